@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// MigrateBoltDBCmd copies the locks and pull statuses in the BoltDB database
+// under --data-dir into a SQL database.
+type MigrateBoltDBCmd struct {
+	// Viper reads the flags and the ATLANTIS_ env vars that can be used
+	// instead of them, same as the server command, so the data source name
+	// doesn't need to be passed on the command line.
+	Viper *viper.Viper
+}
+
+// Init returns the runnable cobra command.
+func (m *MigrateBoltDBCmd) Init() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "migrate-boltdb",
+		Short: "Copy locks and pull request statuses from BoltDB into a SQL database",
+		Long: "Copy the locks, lock queues and pull request statuses from the BoltDB database under --data-dir into the SQL database at --sql-dsn." +
+			" Atlantis must not be running while this is run. Existing data with the same keys in the SQL database is overwritten.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return m.run(cmd)
+		},
+	}
+	m.Viper.SetEnvPrefix("ATLANTIS")
+	m.Viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	m.Viper.AutomaticEnv()
+
+	c.Flags().String(DataDirFlag, DefaultDataDir, "Path to the directory Atlantis stores its data in. The BoltDB database is at atlantis.db inside it.")
+	c.Flags().String(LockingDBTypeFlag, db.SQLiteDriver, "Type of the SQL database to copy into. Either 'sqlite3' or 'postgres'.")
+	c.Flags().String(SQLDSNFlag, "", "Data source name of the SQL database to copy into. Should be specified via the ATLANTIS_SQL_DSN environment variable if it contains a password.")
+	for _, name := range []string{DataDirFlag, LockingDBTypeFlag, SQLDSNFlag} {
+		m.Viper.BindPFlag(name, c.Flags().Lookup(name)) // nolint: errcheck
+	}
+	return c
+}
+
+func (m *MigrateBoltDBCmd) run(cmd *cobra.Command) error {
+	lockingDBType := m.Viper.GetString(LockingDBTypeFlag)
+	sqlDSN := m.Viper.GetString(SQLDSNFlag)
+	if lockingDBType != db.SQLiteDriver && lockingDBType != db.PostgresDriver {
+		return fmt.Errorf("--%s must be one of sqlite3 or postgres", LockingDBTypeFlag)
+	}
+	if sqlDSN == "" {
+		return fmt.Errorf("--%s must be set", SQLDSNFlag)
+	}
+	dataDir, err := homedir.Expand(m.Viper.GetString(DataDirFlag))
+	if err != nil {
+		return errors.Wrap(err, "determining data dir")
+	}
+	src, err := db.New(dataDir)
+	if err != nil {
+		return err
+	}
+	defer src.Close() // nolint: errcheck
+	dst, err := db.NewSQL(lockingDBType, sqlDSN)
+	if err != nil {
+		return err
+	}
+	result, err := db.MigrateBoltDB(src, dst)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "copied %d locks, %d lock queues and %d pull statuses\n", result.Locks, result.Queues, result.Pulls)
+	return nil
+}
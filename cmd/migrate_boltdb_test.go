@@ -0,0 +1,93 @@
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/cmd"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+	"github.com/spf13/viper"
+)
+
+func TestMigrateBoltDB_Validate(t *testing.T) {
+	cases := []struct {
+		args   []string
+		expErr string
+	}{
+		{
+			[]string{"--locking-db-type", "redis", "--sql-dsn", "dsn"},
+			"--locking-db-type must be one of sqlite3 or postgres",
+		},
+		{
+			[]string{"--locking-db-type", "postgres"},
+			"--sql-dsn must be set",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.expErr, func(t *testing.T) {
+			m := &cmd.MigrateBoltDBCmd{Viper: viper.New()}
+			c2 := m.Init()
+			c2.SetArgs(c.args)
+			c2.SetOutput(&bytes.Buffer{})
+			ErrEquals(t, c.expErr, c2.Execute())
+		})
+	}
+}
+
+func TestMigrateBoltDB_Copies(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	lock := models.ProjectLock{
+		Project:   models.NewProject("owner/repo", "."),
+		Workspace: "default",
+		Pull:      models.PullRequest{Num: 1},
+	}
+	_, _, err = boltDB.TryLock(lock)
+	Ok(t, err)
+	// BoltDB only allows one process to open the file at a time.
+	Ok(t, boltDB.Close())
+
+	dsn := filepath.Join(tmp, "atlantis.sqlite")
+	m := &cmd.MigrateBoltDBCmd{Viper: viper.New()}
+	c := m.Init()
+	out := &bytes.Buffer{}
+	c.SetOutput(out)
+	c.SetArgs([]string{"--data-dir", tmp, "--sql-dsn", dsn})
+	Ok(t, c.Execute())
+	Equals(t, "copied 1 locks, 0 lock queues and 0 pull statuses\n", out.String())
+
+	sqlDB, err := db.NewSQL(db.SQLiteDriver, dsn)
+	Ok(t, err)
+	l, err := sqlDB.GetLock(lock.Project, lock.Workspace)
+	Ok(t, err)
+	Assert(t, l != nil, "exp lock to be copied")
+}
+
+// The data source name can be set via ATLANTIS_SQL_DSN so passwords don't end
+// up on the command line.
+func TestMigrateBoltDB_DSNFromEnv(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	Ok(t, boltDB.Close())
+
+	dsn := filepath.Join(tmp, "atlantis.sqlite")
+	Ok(t, os.Setenv("ATLANTIS_SQL_DSN", dsn))
+	defer os.Unsetenv("ATLANTIS_SQL_DSN") // nolint: errcheck
+	m := &cmd.MigrateBoltDBCmd{Viper: viper.New()}
+	c := m.Init()
+	out := &bytes.Buffer{}
+	c.SetOutput(out)
+	c.SetArgs([]string{"--data-dir", tmp})
+	Ok(t, c.Execute())
+	Equals(t, "copied 0 locks, 0 lock queues and 0 pull statuses\n", out.String())
+	_, err = os.Stat(dsn)
+	Ok(t, err)
+}
@@ -32,10 +32,15 @@ import (
 // Used for testing.
 var passedConfig server.UserConfig
 
+// passedServerConfig is set to whatever server.Config ended up being passed
+// to NewServer. Used for testing.
+var passedServerConfig server.Config
+
 type ServerCreatorMock struct{}
 
 func (s *ServerCreatorMock) NewServer(userConfig server.UserConfig, config server.Config) (cmd.ServerStarter, error) {
 	passedConfig = userConfig
+	passedServerConfig = config
 	return &ServerStarterMock{}, nil
 }
 
@@ -127,6 +132,14 @@ func TestExecute_ValidateLogLevel(t *testing.T) {
 	Equals(t, "invalid log level: not one of debug, info, warn, error", err.Error())
 }
 
+func TestExecute_ValidateLogFormat(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.LogFormatFlag: "invalid",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid log format: not one of text or json", err)
+}
+
 func TestExecute_ValidateCheckoutStrategy(t *testing.T) {
 	c := setupWithDefaults(map[string]interface{}{
 		cmd.CheckoutStrategyFlag: "invalid",
@@ -135,6 +148,181 @@ func TestExecute_ValidateCheckoutStrategy(t *testing.T) {
 	ErrEquals(t, "invalid checkout strategy: not one of branch or merge", err)
 }
 
+func TestExecute_ValidateCommentMode(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.CommentModeFlag: "invalid",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid comment mode: not one of new, edit or hide", err)
+}
+
+func TestExecute_ValidateLockTTL(t *testing.T) {
+	cases := map[string]string{
+		"3 days": "invalid --lock-ttl \"3 days\": must be a duration, ex. 72h",
+		"-1h":    "invalid --lock-ttl \"-1h\": must be greater than 0",
+	}
+	for ttl, expErr := range cases {
+		t.Run(ttl, func(t *testing.T) {
+			c := setupWithDefaults(map[string]interface{}{
+				cmd.LockTTLFlag: ttl,
+			})
+			err := c.Execute()
+			ErrEquals(t, expErr, err)
+		})
+	}
+}
+
+func TestExecute_ValidateDriftDetection(t *testing.T) {
+	cases := []struct {
+		interval string
+		repos    string
+		expErr   string
+	}{
+		{
+			"24h",
+			"",
+			"--drift-detection-interval and --drift-detection-repos must both be set or both be empty",
+		},
+		{
+			"",
+			"github.com/owner/repo",
+			"--drift-detection-interval and --drift-detection-repos must both be set or both be empty",
+		},
+		{
+			"1 day",
+			"github.com/owner/repo",
+			"invalid --drift-detection-interval \"1 day\": must be a duration, ex. 24h",
+		},
+		{
+			"-1h",
+			"github.com/owner/repo",
+			"invalid --drift-detection-interval \"-1h\": must be greater than 0",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.interval+" "+c.repos, func(t *testing.T) {
+			cmd := setupWithDefaults(map[string]interface{}{
+				cmd.DriftDetectionIntervalFlag: c.interval,
+				cmd.DriftDetectionReposFlag:    c.repos,
+			})
+			err := cmd.Execute()
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+func TestExecute_ValidateUIAuth(t *testing.T) {
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expErr      string
+	}{
+		{
+			"username without password",
+			map[string]interface{}{cmd.WebUsernameFlag: "user"},
+			"--web-username and --web-password must both be set or both be empty",
+		},
+		{
+			"password without username",
+			map[string]interface{}{cmd.WebPasswordFlag: "pass"},
+			"--web-username and --web-password must both be set or both be empty",
+		},
+		{
+			"issuer without client",
+			map[string]interface{}{cmd.OIDCIssuerURLFlag: "https://accounts.google.com"},
+			"--oidc-issuer-url, --oidc-client-id and --oidc-client-secret must all be set or all be empty",
+		},
+		{
+			"client without secret",
+			map[string]interface{}{cmd.OIDCIssuerURLFlag: "https://accounts.google.com", cmd.OIDCClientIDFlag: "id"},
+			"--oidc-issuer-url, --oidc-client-id and --oidc-client-secret must all be set or all be empty",
+		},
+		{
+			"session secret without issuer",
+			map[string]interface{}{cmd.OIDCSessionSecretFlag: "secret"},
+			"--oidc-session-secret requires --oidc-issuer-url",
+		},
+		{
+			"basic auth and oidc",
+			map[string]interface{}{
+				cmd.WebUsernameFlag:      "user",
+				cmd.WebPasswordFlag:      "pass",
+				cmd.OIDCIssuerURLFlag:    "https://accounts.google.com",
+				cmd.OIDCClientIDFlag:     "id",
+				cmd.OIDCClientSecretFlag: "secret",
+			},
+			"--web-username/--web-password and --oidc-issuer-url cannot be used together",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			cmd := setupWithDefaults(c.flags)
+			err := cmd.Execute()
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+func TestExecute_ValidateLockingDBType(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.LockingDBTypeFlag: "invalid",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid locking db type: not one of boltdb, redis, sqlite3 or postgres", err)
+}
+
+func TestExecute_ValidateRedisHost(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.LockingDBTypeFlag: "redis",
+	})
+	err := c.Execute()
+	ErrEquals(t, "--redis-host must be set if --locking-db-type is redis", err)
+}
+
+func TestExecute_ValidateParallelPoolSize(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.ParallelPoolSizeFlag: -1,
+	})
+	err := c.Execute()
+	ErrEquals(t, "--parallel-pool-size must be greater than 0", err)
+}
+
+func TestExecute_ValidateSQLDSN(t *testing.T) {
+	for _, dbType := range []string{"sqlite3", "postgres"} {
+		t.Run(dbType, func(t *testing.T) {
+			c := setupWithDefaults(map[string]interface{}{
+				cmd.LockingDBTypeFlag: dbType,
+			})
+			err := c.Execute()
+			ErrEquals(t, "--sql-dsn must be set if --locking-db-type is "+dbType, err)
+		})
+	}
+}
+
+func TestExecute_RepoConfig(t *testing.T) {
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmpDir, "repos.yaml")
+
+	t.Log("a missing file should be an error")
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.RepoConfigFlag: path,
+	})
+	err := c.Execute()
+	ErrContains(t, "invalid --repo-config: unable to read "+path, err)
+
+	t.Log("the parsed file should be passed to the server")
+	err = ioutil.WriteFile(path, []byte("repos:\n- id: github.com/owner/repo\n  allow_run_steps: true\n"), 0600)
+	Ok(t, err)
+	c = setupWithDefaults(map[string]interface{}{
+		cmd.RepoConfigFlag: path,
+	})
+	err = c.Execute()
+	Ok(t, err)
+	Equals(t, 1, len(passedServerConfig.RepoConfig.Repos))
+	Equals(t, "github.com/owner/repo", passedServerConfig.RepoConfig.Repos[0].ID)
+}
+
 func TestExecute_ValidateSSLConfig(t *testing.T) {
 	expErr := "--ssl-key-file and --ssl-cert-file are both required for ssl"
 	cases := []struct {
@@ -184,7 +372,7 @@ func TestExecute_ValidateSSLConfig(t *testing.T) {
 }
 
 func TestExecute_ValidateVCSConfig(t *testing.T) {
-	expErr := "--gh-user/--gh-token or --gitlab-user/--gitlab-token or --bitbucket-user/--bitbucket-token must be set"
+	expErr := "--gh-user/--gh-token or --gh-app-id/--gh-app-key-file or --gitlab-user/--gitlab-token or --bitbucket-user/--bitbucket-token or --azuredevops-user/--azuredevops-token or --gitea-user/--gitea-token must be set"
 	cases := []struct {
 		description string
 		flags       map[string]interface{}
@@ -202,6 +390,20 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"just github app id set",
+			map[string]interface{}{
+				cmd.GHAppIDFlag: 1,
+			},
+			true,
+		},
+		{
+			"just github app key file set",
+			map[string]interface{}{
+				cmd.GHAppKeyFileFlag: "key.pem",
+			},
+			true,
+		},
 		{
 			"just gitlab token set",
 			map[string]interface{}{
@@ -216,6 +418,20 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"just azuredevops token set",
+			map[string]interface{}{
+				cmd.AzureDevopsTokenFlag: "token",
+			},
+			true,
+		},
+		{
+			"just gitea token set",
+			map[string]interface{}{
+				cmd.GiteaTokenFlag: "token",
+			},
+			true,
+		},
 		{
 			"just github user set",
 			map[string]interface{}{
@@ -237,6 +453,20 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"just azuredevops user set",
+			map[string]interface{}{
+				cmd.AzureDevopsUserFlag: "user",
+			},
+			true,
+		},
+		{
+			"just gitea user set",
+			map[string]interface{}{
+				cmd.GiteaUserFlag: "user",
+			},
+			true,
+		},
 		{
 			"github user and gitlab token set",
 			map[string]interface{}{
@@ -269,6 +499,14 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"github app id and key file set and should be successful",
+			map[string]interface{}{
+				cmd.GHAppIDFlag:      1,
+				cmd.GHAppKeyFileFlag: "key.pem",
+			},
+			false,
+		},
 		{
 			"gitlab user and gitlab token set and should be successful",
 			map[string]interface{}{
@@ -285,15 +523,35 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"azuredevops user and azuredevops token set and should be successful",
+			map[string]interface{}{
+				cmd.AzureDevopsUserFlag:  "user",
+				cmd.AzureDevopsTokenFlag: "token",
+			},
+			false,
+		},
+		{
+			"gitea user and gitea token set and should be successful",
+			map[string]interface{}{
+				cmd.GiteaUserFlag:  "user",
+				cmd.GiteaTokenFlag: "token",
+			},
+			false,
+		},
 		{
 			"all set should be successful",
 			map[string]interface{}{
-				cmd.GHUserFlag:         "user",
-				cmd.GHTokenFlag:        "token",
-				cmd.GitlabUserFlag:     "user",
-				cmd.GitlabTokenFlag:    "token",
-				cmd.BitbucketUserFlag:  "user",
-				cmd.BitbucketTokenFlag: "token",
+				cmd.GHUserFlag:           "user",
+				cmd.GHTokenFlag:          "token",
+				cmd.GitlabUserFlag:       "user",
+				cmd.GitlabTokenFlag:      "token",
+				cmd.BitbucketUserFlag:    "user",
+				cmd.BitbucketTokenFlag:   "token",
+				cmd.AzureDevopsUserFlag:  "user",
+				cmd.AzureDevopsTokenFlag: "token",
+				cmd.GiteaUserFlag:        "user",
+				cmd.GiteaTokenFlag:       "token",
 			},
 			false,
 		},
@@ -313,6 +571,41 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 	}
 }
 
+func TestExecute_GithubUserAndApp(t *testing.T) {
+	t.Log("Should error if both a GitHub user and a GitHub App are set.")
+	c := setup(map[string]interface{}{
+		cmd.GHUserFlag:        "user",
+		cmd.GHTokenFlag:       "token",
+		cmd.GHAppIDFlag:       1,
+		cmd.GHAppKeyFileFlag:  "key.pem",
+		cmd.RepoWhitelistFlag: "*",
+	})
+	err := c.Execute()
+	ErrEquals(t, "--gh-user/--gh-token and --gh-app-id/--gh-app-key-file cannot be used together", err)
+}
+
+func TestExecute_GithubChecksRequiresApp(t *testing.T) {
+	t.Log("Should error if GitHub checks are enabled without a GitHub App.")
+	c := setup(map[string]interface{}{
+		cmd.GHUserFlag:        "user",
+		cmd.GHTokenFlag:       "token",
+		cmd.GHChecksFlag:      true,
+		cmd.RepoWhitelistFlag: "*",
+	})
+	err := c.Execute()
+	ErrEquals(t, "--gh-checks requires --gh-app-id/--gh-app-key-file because only GitHub Apps can create check runs", err)
+
+	c = setup(map[string]interface{}{
+		cmd.GHAppIDFlag:       1,
+		cmd.GHAppKeyFileFlag:  "key.pem",
+		cmd.GHChecksFlag:      true,
+		cmd.RepoWhitelistFlag: "*",
+	})
+	err = c.Execute()
+	Ok(t, err)
+	Equals(t, true, passedConfig.GithubChecks)
+}
+
 func TestExecute_Defaults(t *testing.T) {
 	t.Log("Should set the defaults for all unspecified flags.")
 	c := setup(map[string]interface{}{
@@ -331,9 +624,15 @@ func TestExecute_Defaults(t *testing.T) {
 	hostname, err := os.Hostname()
 	Ok(t, err)
 	Equals(t, "http://"+hostname+":4141", passedConfig.AtlantisURL)
+	Equals(t, "", passedConfig.APISecret)
 	Equals(t, false, passedConfig.AllowForkPRs)
 	Equals(t, false, passedConfig.AllowRepoConfig)
 	Equals(t, false, passedConfig.Automerge)
+	Equals(t, "dev.azure.com", passedConfig.AzureDevopsHostname)
+	Equals(t, "", passedConfig.AzureDevopsToken)
+	Equals(t, "", passedConfig.AzureDevopsUser)
+	Equals(t, "", passedConfig.AzureDevopsWebhookPassword)
+	Equals(t, "", passedConfig.AzureDevopsWebhookUser)
 
 	// Get our home dir since that's what gets defaulted to
 	dataDir, err := homedir.Expand("~/.atlantis")
@@ -341,7 +640,16 @@ func TestExecute_Defaults(t *testing.T) {
 	Equals(t, dataDir, passedConfig.DataDir)
 
 	Equals(t, "branch", passedConfig.CheckoutStrategy)
+	Equals(t, "new", passedConfig.CommentMode)
 	Equals(t, "", passedConfig.DefaultTFVersion)
+	Equals(t, false, passedConfig.EnableLockQueue)
+	Equals(t, "https://gitea.com", passedConfig.GiteaBaseURL)
+	Equals(t, "", passedConfig.GiteaToken)
+	Equals(t, "", passedConfig.GiteaUser)
+	Equals(t, "", passedConfig.GiteaWebhookSecret)
+	Equals(t, 0, passedConfig.GithubAppID)
+	Equals(t, "", passedConfig.GithubAppKeyFile)
+	Equals(t, false, passedConfig.GithubChecks)
 	Equals(t, "github.com", passedConfig.GithubHostname)
 	Equals(t, "token", passedConfig.GithubToken)
 	Equals(t, "user", passedConfig.GithubUser)
@@ -354,14 +662,30 @@ func TestExecute_Defaults(t *testing.T) {
 	Equals(t, "bitbucket-token", passedConfig.BitbucketToken)
 	Equals(t, "bitbucket-user", passedConfig.BitbucketUser)
 	Equals(t, "", passedConfig.BitbucketWebhookSecret)
+	Equals(t, "", passedConfig.LockTTL)
+	Equals(t, "", passedConfig.DriftDetectionInterval)
+	Equals(t, "boltdb", passedConfig.LockingDBType)
+	Equals(t, "text", passedConfig.LogFormat)
 	Equals(t, "info", passedConfig.LogLevel)
+	Equals(t, "", passedConfig.OIDCClientID)
+	Equals(t, "", passedConfig.OIDCClientSecret)
+	Equals(t, "", passedConfig.OIDCIssuerURL)
+	Equals(t, "", passedConfig.OIDCSessionSecret)
+	Equals(t, 15, passedConfig.ParallelPoolSize)
 	Equals(t, 4141, passedConfig.Port)
+	Equals(t, 0, passedConfig.RedisDB)
+	Equals(t, "", passedConfig.RedisHost)
+	Equals(t, "", passedConfig.RedisPassword)
+	Equals(t, 6379, passedConfig.RedisPort)
 	Equals(t, false, passedConfig.RequireApproval)
 	Equals(t, false, passedConfig.RequireMergeable)
 	Equals(t, "", passedConfig.SlackToken)
+	Equals(t, "", passedConfig.SQLDSN)
 	Equals(t, "", passedConfig.SSLCertFile)
 	Equals(t, "", passedConfig.SSLKeyFile)
 	Equals(t, "", passedConfig.TFEToken)
+	Equals(t, "", passedConfig.WebPassword)
+	Equals(t, "", passedConfig.WebUsername)
 }
 
 func TestExecute_ExpandHomeInDataDir(t *testing.T) {
@@ -434,52 +758,133 @@ func TestExecute_BitbucketUser(t *testing.T) {
 	Equals(t, "user", passedConfig.BitbucketUser)
 }
 
+func TestExecute_AzureDevopsUser(t *testing.T) {
+	t.Log("Should remove the @ from the azure devops username if it's passed.")
+	c := setup(map[string]interface{}{
+		cmd.AzureDevopsUserFlag:  "@user",
+		cmd.AzureDevopsTokenFlag: "token",
+		cmd.RepoWhitelistFlag:    "*",
+	})
+	err := c.Execute()
+	Ok(t, err)
+
+	Equals(t, "user", passedConfig.AzureDevopsUser)
+}
+
+func TestExecute_GiteaUser(t *testing.T) {
+	t.Log("Should remove the @ from the gitea username if it's passed.")
+	c := setup(map[string]interface{}{
+		cmd.GiteaUserFlag:     "@user",
+		cmd.GiteaTokenFlag:    "token",
+		cmd.RepoWhitelistFlag: "*",
+	})
+	err := c.Execute()
+	Ok(t, err)
+
+	Equals(t, "user", passedConfig.GiteaUser)
+}
+
+func TestExecute_ValidateAzureDevopsWebhookAuth(t *testing.T) {
+	expErr := "--azuredevops-webhook-user and --azuredevops-webhook-password must both be set or both be empty"
+	for _, flag := range []string{cmd.AzureDevopsWebhookUserFlag, cmd.AzureDevopsWebhookPasswordFlag} {
+		t.Run(flag, func(t *testing.T) {
+			c := setup(map[string]interface{}{
+				cmd.AzureDevopsUserFlag:  "user",
+				cmd.AzureDevopsTokenFlag: "token",
+				cmd.RepoWhitelistFlag:    "*",
+				flag:                     "value",
+			})
+			err := c.Execute()
+			ErrEquals(t, expErr, err)
+		})
+	}
+}
+
 func TestExecute_Flags(t *testing.T) {
 	t.Log("Should use all flags that are set.")
 	c := setup(map[string]interface{}{
-		cmd.AtlantisURLFlag:            "url",
-		cmd.AllowForkPRsFlag:           true,
-		cmd.AllowRepoConfigFlag:        true,
-		cmd.AutomergeFlag:              true,
-		cmd.BitbucketBaseURLFlag:       "https://bitbucket-base-url.com",
-		cmd.BitbucketTokenFlag:         "bitbucket-token",
-		cmd.BitbucketUserFlag:          "bitbucket-user",
-		cmd.BitbucketWebhookSecretFlag: "bitbucket-secret",
-		cmd.CheckoutStrategyFlag:       "merge",
-		cmd.DataDirFlag:                "/path",
-		cmd.DefaultTFVersionFlag:       "v0.11.0",
-		cmd.GHHostnameFlag:             "ghhostname",
-		cmd.GHTokenFlag:                "token",
-		cmd.GHUserFlag:                 "user",
-		cmd.GHWebhookSecretFlag:        "secret",
-		cmd.GitlabHostnameFlag:         "gitlab-hostname",
-		cmd.GitlabTokenFlag:            "gitlab-token",
-		cmd.GitlabUserFlag:             "gitlab-user",
-		cmd.GitlabWebhookSecretFlag:    "gitlab-secret",
-		cmd.LogLevelFlag:               "debug",
-		cmd.PortFlag:                   8181,
-		cmd.RepoWhitelistFlag:          "github.com/runatlantis/atlantis",
-		cmd.RequireApprovalFlag:        true,
-		cmd.RequireMergeableFlag:       true,
-		cmd.SlackTokenFlag:             "slack-token",
-		cmd.SSLCertFileFlag:            "cert-file",
-		cmd.SSLKeyFileFlag:             "key-file",
-		cmd.TFETokenFlag:               "my-token",
+		cmd.AtlantisURLFlag:                "url",
+		cmd.AllowForkPRsFlag:               true,
+		cmd.APISecretFlag:                  "api-secret",
+		cmd.AllowRepoConfigFlag:            true,
+		cmd.AutomergeFlag:                  true,
+		cmd.AzureDevopsHostnameFlag:        "dev.azure.corp",
+		cmd.AzureDevopsTokenFlag:           "ad-token",
+		cmd.AzureDevopsUserFlag:            "ad-user",
+		cmd.AzureDevopsWebhookPasswordFlag: "ad-wh-pass",
+		cmd.AzureDevopsWebhookUserFlag:     "ad-wh-user",
+		cmd.BitbucketBaseURLFlag:           "https://bitbucket-base-url.com",
+		cmd.BitbucketTokenFlag:             "bitbucket-token",
+		cmd.BitbucketUserFlag:              "bitbucket-user",
+		cmd.BitbucketWebhookSecretFlag:     "bitbucket-secret",
+		cmd.CheckoutStrategyFlag:           "merge",
+		cmd.CommentModeFlag:                "edit",
+		cmd.DataDirFlag:                    "/path",
+		cmd.DefaultTFVersionFlag:           "v0.11.0",
+		cmd.DriftDetectionIntervalFlag:     "24h",
+		cmd.DriftDetectionReposFlag:        "github.com/runatlantis/atlantis",
+		cmd.EnableLockQueueFlag:            true,
+		cmd.GiteaBaseURLFlag:               "https://corp.com/gitea",
+		cmd.GiteaTokenFlag:                 "gitea-token",
+		cmd.GiteaUserFlag:                  "gitea-user",
+		cmd.GiteaWebhookSecretFlag:         "gitea-secret",
+		cmd.GHHostnameFlag:                 "ghhostname",
+		cmd.GHTokenFlag:                    "token",
+		cmd.GHUserFlag:                     "user",
+		cmd.GHWebhookSecretFlag:            "secret",
+		cmd.GitlabHostnameFlag:             "gitlab-hostname",
+		cmd.GitlabTokenFlag:                "gitlab-token",
+		cmd.GitlabUserFlag:                 "gitlab-user",
+		cmd.GitlabWebhookSecretFlag:        "gitlab-secret",
+		cmd.LockTTLFlag:                    "72h",
+		cmd.LockingDBTypeFlag:              "redis",
+		cmd.LogFormatFlag:                  "json",
+		cmd.LogLevelFlag:                   "debug",
+		cmd.ParallelPoolSizeFlag:           5,
+		cmd.PortFlag:                       8181,
+		cmd.RedisDBFlag:                    1,
+		cmd.RedisHostFlag:                  "redis-host",
+		cmd.RedisPasswordFlag:              "redis-password",
+		cmd.RedisPortFlag:                  6380,
+		cmd.RepoWhitelistFlag:              "github.com/runatlantis/atlantis",
+		cmd.RequireApprovalFlag:            true,
+		cmd.RequireMergeableFlag:           true,
+		cmd.SlackTokenFlag:                 "slack-token",
+		cmd.SQLDSNFlag:                     "sql-dsn",
+		cmd.SSLCertFileFlag:                "cert-file",
+		cmd.SSLKeyFileFlag:                 "key-file",
+		cmd.TFETokenFlag:                   "my-token",
+		cmd.WebPasswordFlag:                "web-password",
+		cmd.WebUsernameFlag:                "web-username",
 	})
 	err := c.Execute()
 	Ok(t, err)
 
 	Equals(t, "url", passedConfig.AtlantisURL)
+	Equals(t, "api-secret", passedConfig.APISecret)
 	Equals(t, true, passedConfig.AllowForkPRs)
 	Equals(t, true, passedConfig.AllowRepoConfig)
 	Equals(t, true, passedConfig.Automerge)
+	Equals(t, "dev.azure.corp", passedConfig.AzureDevopsHostname)
+	Equals(t, "ad-token", passedConfig.AzureDevopsToken)
+	Equals(t, "ad-user", passedConfig.AzureDevopsUser)
+	Equals(t, "ad-wh-pass", passedConfig.AzureDevopsWebhookPassword)
+	Equals(t, "ad-wh-user", passedConfig.AzureDevopsWebhookUser)
 	Equals(t, "https://bitbucket-base-url.com", passedConfig.BitbucketBaseURL)
 	Equals(t, "bitbucket-token", passedConfig.BitbucketToken)
 	Equals(t, "bitbucket-user", passedConfig.BitbucketUser)
 	Equals(t, "bitbucket-secret", passedConfig.BitbucketWebhookSecret)
 	Equals(t, "merge", passedConfig.CheckoutStrategy)
+	Equals(t, "edit", passedConfig.CommentMode)
 	Equals(t, "/path", passedConfig.DataDir)
 	Equals(t, "v0.11.0", passedConfig.DefaultTFVersion)
+	Equals(t, "24h", passedConfig.DriftDetectionInterval)
+	Equals(t, "github.com/runatlantis/atlantis", passedConfig.DriftDetectionRepos)
+	Equals(t, true, passedConfig.EnableLockQueue)
+	Equals(t, "https://corp.com/gitea", passedConfig.GiteaBaseURL)
+	Equals(t, "gitea-token", passedConfig.GiteaToken)
+	Equals(t, "gitea-user", passedConfig.GiteaUser)
+	Equals(t, "gitea-secret", passedConfig.GiteaWebhookSecret)
 	Equals(t, "ghhostname", passedConfig.GithubHostname)
 	Equals(t, "token", passedConfig.GithubToken)
 	Equals(t, "user", passedConfig.GithubUser)
@@ -488,15 +893,26 @@ func TestExecute_Flags(t *testing.T) {
 	Equals(t, "gitlab-token", passedConfig.GitlabToken)
 	Equals(t, "gitlab-user", passedConfig.GitlabUser)
 	Equals(t, "gitlab-secret", passedConfig.GitlabWebhookSecret)
+	Equals(t, "72h", passedConfig.LockTTL)
+	Equals(t, "redis", passedConfig.LockingDBType)
+	Equals(t, "json", passedConfig.LogFormat)
 	Equals(t, "debug", passedConfig.LogLevel)
+	Equals(t, 5, passedConfig.ParallelPoolSize)
 	Equals(t, 8181, passedConfig.Port)
+	Equals(t, 1, passedConfig.RedisDB)
+	Equals(t, "redis-host", passedConfig.RedisHost)
+	Equals(t, "redis-password", passedConfig.RedisPassword)
+	Equals(t, 6380, passedConfig.RedisPort)
 	Equals(t, "github.com/runatlantis/atlantis", passedConfig.RepoWhitelist)
 	Equals(t, true, passedConfig.RequireApproval)
 	Equals(t, true, passedConfig.RequireMergeable)
 	Equals(t, "slack-token", passedConfig.SlackToken)
+	Equals(t, "sql-dsn", passedConfig.SQLDSN)
 	Equals(t, "cert-file", passedConfig.SSLCertFile)
 	Equals(t, "key-file", passedConfig.SSLKeyFile)
 	Equals(t, "my-token", passedConfig.TFEToken)
+	Equals(t, "web-password", passedConfig.WebPassword)
+	Equals(t, "web-username", passedConfig.WebUsername)
 }
 
 func TestExecute_ConfigFile(t *testing.T) {
@@ -914,6 +1330,17 @@ func TestExecute_BitbucketServerBaseURLPort(t *testing.T) {
 	Equals(t, "http://mydomain.com:7990", passedConfig.BitbucketBaseURL)
 }
 
+// Gitea base URL must have a scheme.
+func TestExecute_GiteaBaseURLScheme(t *testing.T) {
+	c := setup(map[string]interface{}{
+		cmd.GiteaUserFlag:     "user",
+		cmd.GiteaTokenFlag:    "token",
+		cmd.RepoWhitelistFlag: "*",
+		cmd.GiteaBaseURLFlag:  "gitea.corp",
+	})
+	ErrEquals(t, "--gitea-base-url must have http:// or https://, got \"gitea.corp\"", c.Execute())
+}
+
 func setup(flags map[string]interface{}) *cobra.Command {
 	vipr := viper.New()
 	for k, v := range flags {
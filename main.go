@@ -35,8 +35,10 @@ func main() {
 	}
 	version := &cmd.VersionCmd{AtlantisVersion: atlantisVersion}
 	testdrive := &cmd.TestdriveCmd{}
+	migrateBoltDB := &cmd.MigrateBoltDBCmd{Viper: viper.New()}
 	cmd.RootCmd.AddCommand(server.Init())
 	cmd.RootCmd.AddCommand(version.Init())
 	cmd.RootCmd.AddCommand(testdrive.Init())
+	cmd.RootCmd.AddCommand(migrateBoltDB.Init())
 	cmd.Execute()
 }
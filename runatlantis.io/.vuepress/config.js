@@ -54,14 +54,16 @@ module.exports = {
                         'configuring-webhooks',
                         'server-configuration',
                         'provider-credentials',
-                        'terraform-enterprise'
+                        'terraform-enterprise',
+                        'metrics'
                     ]
                 },
                 {
                     title: 'Using Atlantis',
                     collapsable: true,
                     children: [
-                        ['using-atlantis', 'Overview']
+                        ['using-atlantis', 'Overview'],
+                        'api'
                     ]
                 },
                 {
@@ -70,8 +72,11 @@ module.exports = {
                     children: [
                         ['customizing-atlantis', 'Overview'],
                         'atlantis-yaml-reference',
+                        'server-side-repo-config',
                         'upgrading-atlantis-yaml-to-version-2',
                         'apply-requirements',
+                        'policy-checking',
+                        'drift-detection',
                         'checkout-strategy',
                         'terraform-versions'
                     ]
@@ -84,6 +89,7 @@ module.exports = {
                         'locking',
                         'autoplanning',
                         'automerging',
+                        'streaming-logs',
                         'security'
                     ]
                 }
@@ -9,21 +9,37 @@ XC_EXCLUDE_OSARCH="!darwin/arm !darwin/386"
 echo "-> running clean up...."
 rm -rf output/*
 
-if ! which gox > /dev/null; then
-    echo "-> installing gox..."
-    go get -u github.com/mitchellh/gox
-fi
-
 # build
-# we want to build statically linked binaries
-export CGO_ENABLED=0
+# cgo is needed for the sqlite3 locking database so we build each platform
+# with cgo and link it statically. Building for a platform other than the
+# host's needs a C cross compiler for it, set via CC_<os>_<arch>,
+# ex. CC_linux_arm=arm-linux-gnueabi-gcc.
+HOST_OS=$(go env GOHOSTOS)
+HOST_ARCH=$(go env GOHOSTARCH)
 echo "-> building..."
-gox \
-    -os="${XC_OS}" \
-    -arch="${XC_ARCH}" \
-    -osarch="${XC_EXCLUDE_OSARCH}" \
-    -output "output/{{.OS}}_{{.Arch}}/atlantis" \
-    .
+for OS in ${XC_OS}; do
+    for ARCH in ${XC_ARCH}; do
+        if [[ " ${XC_EXCLUDE_OSARCH} " == *" !${OS}/${ARCH} "* ]]; then
+            continue
+        fi
+        CC_VAR="CC_${OS}_${ARCH}"
+        if [[ "${OS}/${ARCH}" != "${HOST_OS}/${HOST_ARCH}" && -z "${!CC_VAR}" ]]; then
+            echo "no C compiler for ${OS}/${ARCH}, set ${CC_VAR} to build it with cgo"
+            exit 1
+        fi
+        LDFLAGS='-extldflags "-static"'
+        if [[ "${OS}" == "darwin" ]]; then
+            # macOS doesn't support fully static binaries.
+            LDFLAGS=""
+        fi
+        echo "--> ${OS}/${ARCH}"
+        CC="${!CC_VAR:-${CC:-cc}}" CGO_ENABLED=1 GOOS=${OS} GOARCH=${ARCH} go build \
+            -tags "netgo osusergo sqlite_omit_load_extension" \
+            -ldflags "${LDFLAGS}" \
+            -o "output/${OS}_${ARCH}/atlantis" \
+            . || exit 1
+    done
+done
 
 # Zip and copy to the dist dir
 echo ""
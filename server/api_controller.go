@@ -0,0 +1,523 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/recovery"
+)
+
+const (
+	// APITokenHeader is the header that API requests must set to the API
+	// secret.
+	APITokenHeader = "X-Atlantis-Token"
+	// maxAPIJobs is the number of async jobs whose results are kept.
+	maxAPIJobs = 100
+	// maxAPIPageSize is the largest per_page that list requests can set. It's
+	// also the default.
+	maxAPIPageSize = 100
+)
+
+// APIController handles requests to run commands through the JSON API.
+type APIController struct {
+	// APISecret is the token that requests must be authenticated with. If
+	// it's empty, the API is disabled.
+	APISecret            []byte
+	APISecretFlag        string
+	Logger               *logging.SimpleLogger
+	RepoBuilder          events.RepoBuilder
+	RepoWhitelistChecker *events.RepoWhitelistChecker
+	CommandRunner        events.APICommandRunner
+	Locker               locking.Locker
+	LockURLGenerator     events.LockURLGenerator
+	DB                   db.Database
+
+	mutex sync.Mutex
+	jobs  map[string]*APIJob
+	// jobOrder is the IDs of jobs in the order they were created so we know
+	// which to evict first.
+	jobOrder []string
+}
+
+// APIRequest is the JSON body of plan and apply requests.
+type APIRequest struct {
+	// Repo is the repo's ID, ex. github.com/runatlantis/atlantis.
+	Repo string `json:"repo"`
+	// PullNum is the pull request to run the command on. Either it or Ref
+	// must be set.
+	PullNum int `json:"pull_num"`
+	// Ref is the branch or tag to run the command on.
+	Ref      string          `json:"ref"`
+	Projects []APIProjectRef `json:"projects"`
+	// Async is true if the response should be sent before the command is
+	// complete. It contains a job ID to poll for the result.
+	Async bool `json:"async"`
+}
+
+// APIProjectRef selects a project by name or by dir and workspace.
+type APIProjectRef struct {
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+	Name      string `json:"name"`
+}
+
+// APIResult is the result of running a command through the API.
+type APIResult struct {
+	// Error is set if the command couldn't be run.
+	Error    string             `json:"error,omitempty"`
+	Projects []APIProjectResult `json:"projects"`
+}
+
+// APIProjectResult is the result of running a command on a project. It's
+// models.ProjectResult with errors as strings so it can be encoded as JSON.
+type APIProjectResult struct {
+	Dir         string `json:"dir"`
+	Workspace   string `json:"workspace"`
+	ProjectName string `json:"project_name,omitempty"`
+	// Status is "success" or "failed".
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Failure string `json:"failure,omitempty"`
+	// PlanOutput, PlanSummary and LockURL are set if a plan succeeded.
+	PlanOutput  string `json:"plan_output,omitempty"`
+	PlanSummary string `json:"plan_summary,omitempty"`
+	LockURL     string `json:"lock_url,omitempty"`
+	// ApplyOutput is set if an apply succeeded.
+	ApplyOutput string `json:"apply_output,omitempty"`
+}
+
+// APIJob is an async command.
+type APIJob struct {
+	ID string `json:"job_id"`
+	// Status is "running" or "complete".
+	Status string `json:"status"`
+	// Result is set once the job is complete.
+	Result *APIResult `json:"result,omitempty"`
+}
+
+// APILockList is a page of locks.
+type APILockList struct {
+	Locks []APILock `json:"locks"`
+	// Total is the number of locks on every page.
+	Total   int `json:"total"`
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+}
+
+// APILock is a project lock.
+type APILock struct {
+	// ID identifies the lock in GET /api/locks/{id}. It's the lock's Key
+	// encoded so it can be used in a URL path.
+	ID string `json:"id"`
+	// Key is the lock's key in the UI, ex. runatlantis/atlantis/./default.
+	Key string `json:"key"`
+	// Repo is the locked repo's ID, ex. github.com/runatlantis/atlantis.
+	Repo       string    `json:"repo"`
+	Dir        string    `json:"dir"`
+	Workspace  string    `json:"workspace"`
+	PullNum    int       `json:"pull_num"`
+	PullURL    string    `json:"pull_url,omitempty"`
+	PullAuthor string    `json:"pull_author,omitempty"`
+	LockedBy   string    `json:"locked_by"`
+	Time       time.Time `json:"time"`
+	LockURL    string    `json:"lock_url"`
+	// Queue is the pull requests waiting for the lock. It's only set by
+	// GET /api/locks/{id}.
+	Queue []APIQueuedPull `json:"queue,omitempty"`
+}
+
+// APIQueuedPull is a pull request waiting for a lock.
+type APIQueuedPull struct {
+	PullNum  int       `json:"pull_num"`
+	PullURL  string    `json:"pull_url,omitempty"`
+	QueuedBy string    `json:"queued_by"`
+	Time     time.Time `json:"time"`
+}
+
+// APIPullStatus is the status of the projects of a pull request as of its
+// last command.
+type APIPullStatus struct {
+	Repo       string             `json:"repo"`
+	PullNum    int                `json:"pull_num"`
+	PullURL    string             `json:"pull_url,omitempty"`
+	HeadCommit string             `json:"head_commit"`
+	Projects   []APIProjectStatus `json:"projects"`
+}
+
+// APIProjectStatus is the status of a project in a pull request.
+type APIProjectStatus struct {
+	Dir         string `json:"dir"`
+	Workspace   string `json:"workspace"`
+	ProjectName string `json:"project_name,omitempty"`
+	// Status is one of planned, plan_errored, applied, apply_errored or
+	// policy_check_failed.
+	Status string `json:"status"`
+	// PlanSummary is set if the project's last plan succeeded.
+	PlanSummary *APIPlanSummary `json:"plan_summary,omitempty"`
+}
+
+// APIPlanSummary is the number of resources a plan changes.
+type APIPlanSummary struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// Plan is the POST /api/plan route.
+func (a *APIController) Plan(w http.ResponseWriter, r *http.Request) {
+	a.run(w, r, models.PlanCommand)
+}
+
+// Apply is the POST /api/apply route.
+func (a *APIController) Apply(w http.ResponseWriter, r *http.Request) {
+	a.run(w, r, models.ApplyCommand)
+}
+
+// GetJob is the GET /api/jobs/{id} route. It responds with the status of the
+// async job and its result once it's complete.
+func (a *APIController) GetJob(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticate(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	a.mutex.Lock()
+	job, ok := a.jobs[id]
+	var resp APIJob
+	if ok {
+		resp = *job
+	}
+	a.mutex.Unlock()
+	if !ok {
+		a.respondErr(w, logging.Info, http.StatusNotFound, "no job found with id %q", id)
+		return
+	}
+	a.respondJSON(w, http.StatusOK, resp)
+}
+
+// ListLocks is the GET /api/locks route. It responds with a page of the
+// locks sorted by key. The repo query parameter filters them by repo ID and
+// the page and per_page parameters select the page.
+func (a *APIController) ListLocks(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticate(w, r) {
+		return
+	}
+	page, perPage, err := a.parsePage(r)
+	if err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "%s", err)
+		return
+	}
+	locks, err := a.Locker.List()
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "listing locks: %s", err)
+		return
+	}
+	repoFilter := r.URL.Query().Get("repo")
+	var keys []string
+	for key, lock := range locks {
+		if repoFilter == "" || apiRepoID(lock.Pull.BaseRepo.VCSHost.Hostname, lock.Project.RepoFullName) == repoFilter {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	list := APILockList{Locks: []APILock{}, Total: len(keys), Page: page, PerPage: perPage}
+	start := (page - 1) * perPage
+	for i := start; i < len(keys) && i < start+perPage; i++ {
+		list.Locks = append(list.Locks, a.apiLock(keys[i], locks[keys[i]]))
+	}
+	a.respondJSON(w, http.StatusOK, list)
+}
+
+// GetLock is the GET /api/locks/{id} route. It responds with the lock and
+// the pull requests waiting for it.
+func (a *APIController) GetLock(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticate(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	key, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "invalid lock id %q", id)
+		return
+	}
+	lock, err := a.Locker.GetLock(string(key))
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "getting lock: %s", err)
+		return
+	}
+	if lock == nil {
+		a.respondErr(w, logging.Info, http.StatusNotFound, "no lock found with id %q", id)
+		return
+	}
+	queue, err := a.Locker.GetQueue(string(key))
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "getting lock queue: %s", err)
+		return
+	}
+	resp := a.apiLock(string(key), *lock)
+	for _, q := range queue {
+		resp.Queue = append(resp.Queue, APIQueuedPull{
+			PullNum:  q.Pull.Num,
+			PullURL:  q.Pull.URL,
+			QueuedBy: q.User.Username,
+			Time:     q.Time,
+		})
+	}
+	a.respondJSON(w, http.StatusOK, resp)
+}
+
+// GetPullStatus is the GET /api/pulls/{repo}/{num} route. It responds with
+// the status of each project in the pull request as of its last command.
+func (a *APIController) GetPullStatus(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticate(w, r) {
+		return
+	}
+	vars := mux.Vars(r)
+	num, err := strconv.Atoi(vars["num"])
+	if err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "invalid pull request number %q", vars["num"])
+		return
+	}
+	// Statuses are keyed by hostname and full name so there's no need to build
+	// the whole repo, which would fetch a token and only works for some hosts.
+	parts := strings.SplitN(vars["repo"], "/", 2)
+	if len(parts) != 2 || !strings.Contains(parts[1], "/") {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "repo ID %q must be in the format {hostname}/{owner}/{repo}", vars["repo"])
+		return
+	}
+	repo := models.Repo{
+		FullName: parts[1],
+		VCSHost:  models.VCSHost{Hostname: parts[0]},
+	}
+	status, err := a.DB.GetPullStatus(models.PullRequest{BaseRepo: repo, Num: num})
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "getting pull request status: %s", err)
+		return
+	}
+	if status == nil {
+		a.respondErr(w, logging.Info, http.StatusNotFound, "no status found for %s pull request %d", vars["repo"], num)
+		return
+	}
+	resp := APIPullStatus{
+		Repo:       vars["repo"],
+		PullNum:    num,
+		PullURL:    status.Pull.URL,
+		HeadCommit: status.Pull.HeadCommit,
+		Projects:   []APIProjectStatus{},
+	}
+	for _, p := range status.Projects {
+		ps := APIProjectStatus{
+			Dir:         p.RepoRelDir,
+			Workspace:   p.Workspace,
+			ProjectName: p.ProjectName,
+			Status:      p.Status.String(),
+		}
+		if p.PlanSummary != nil {
+			ps.PlanSummary = &APIPlanSummary{Add: p.PlanSummary.Add, Change: p.PlanSummary.Change, Destroy: p.PlanSummary.Destroy}
+		}
+		resp.Projects = append(resp.Projects, ps)
+	}
+	a.respondJSON(w, http.StatusOK, resp)
+}
+
+func (a *APIController) run(w http.ResponseWriter, r *http.Request, name models.CommandName) {
+	if !a.authenticate(w, r) {
+		return
+	}
+	var body APIRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "parsing request: %s", err)
+		return
+	}
+	repo, err := a.RepoBuilder.BuildRepo(body.Repo)
+	if err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "%s", err)
+		return
+	}
+	if !a.RepoWhitelistChecker.IsWhitelisted(repo.FullName, repo.VCSHost.Hostname) {
+		a.respondErr(w, logging.Warn, http.StatusForbidden, "repo %q is not whitelisted", body.Repo)
+		return
+	}
+	req := events.APIRequest{
+		Name:    name,
+		Repo:    repo,
+		PullNum: body.PullNum,
+		Ref:     body.Ref,
+	}
+	for _, p := range body.Projects {
+		req.Projects = append(req.Projects, events.APIProject{Dir: p.Dir, Workspace: p.Workspace, Name: p.Name})
+	}
+
+	reqCtx := events.NewRequestContext("")
+	if !body.Async {
+		code, result := a.runCommand(reqCtx, req)
+		a.respondJSON(w, code, result)
+		return
+	}
+	a.startJob(reqCtx.JobID)
+	go func() {
+		// The server's recovery middleware doesn't catch panics in other
+		// goroutines so without this they would crash the server.
+		defer func() {
+			if err := recover(); err != nil {
+				a.Logger.Err("PANIC: %s\n%s", err, recovery.Stack(3))
+				a.completeJob(reqCtx.JobID, APIResult{Error: fmt.Sprintf("panic: %s", err)})
+			}
+		}()
+		_, result := a.runCommand(reqCtx, req)
+		a.completeJob(reqCtx.JobID, result)
+	}()
+	a.respondJSON(w, http.StatusAccepted, APIJob{ID: reqCtx.JobID, Status: "running"})
+}
+
+// runCommand runs req and returns the response's status code and body.
+func (a *APIController) runCommand(reqCtx events.RequestContext, req events.APIRequest) (int, APIResult) {
+	res, err := a.CommandRunner.RunAPICommand(reqCtx, req)
+	if err != nil {
+		a.Logger.Warn("running %s through the API: %s", req.Name.String(), err)
+		return http.StatusBadRequest, APIResult{Error: err.Error()}
+	}
+	result := APIResult{Projects: []APIProjectResult{}}
+	if res.Error != nil {
+		result.Error = res.Error.Error()
+	} else if res.Failure != "" {
+		result.Error = res.Failure
+	}
+	for _, p := range res.ProjectResults {
+		pr := APIProjectResult{
+			Dir:         p.RepoRelDir,
+			Workspace:   p.Workspace,
+			ProjectName: p.ProjectName,
+			Status:      "success",
+			Failure:     p.Failure,
+			ApplyOutput: p.ApplySuccess,
+		}
+		if !p.IsSuccessful() {
+			pr.Status = "failed"
+		}
+		if p.Error != nil {
+			pr.Error = p.Error.Error()
+		}
+		if p.PlanSuccess != nil {
+			pr.PlanOutput = p.PlanSuccess.TerraformOutput
+			pr.PlanSummary = p.PlanSuccess.Summary()
+			pr.LockURL = p.PlanSuccess.LockURL
+		}
+		result.Projects = append(result.Projects, pr)
+	}
+	if res.HasErrors() {
+		return http.StatusInternalServerError, result
+	}
+	return http.StatusOK, result
+}
+
+func (a *APIController) startJob(id string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.jobs == nil {
+		a.jobs = make(map[string]*APIJob)
+	}
+	if len(a.jobOrder) >= maxAPIJobs {
+		delete(a.jobs, a.jobOrder[0])
+		a.jobOrder = a.jobOrder[1:]
+	}
+	a.jobs[id] = &APIJob{ID: id, Status: "running"}
+	a.jobOrder = append(a.jobOrder, id)
+}
+
+func (a *APIController) completeJob(id string, result APIResult) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	// The job may have been evicted while it was running.
+	if job, ok := a.jobs[id]; ok {
+		job.Status = "complete"
+		job.Result = &result
+	}
+}
+
+func (a *APIController) apiLock(key string, lock models.ProjectLock) APILock {
+	return APILock{
+		ID:         base64.RawURLEncoding.EncodeToString([]byte(key)),
+		Key:        key,
+		Repo:       apiRepoID(lock.Pull.BaseRepo.VCSHost.Hostname, lock.Project.RepoFullName),
+		Dir:        lock.Project.Path,
+		Workspace:  lock.Workspace,
+		PullNum:    lock.Pull.Num,
+		PullURL:    lock.Pull.URL,
+		PullAuthor: lock.Pull.Author,
+		LockedBy:   lock.User.Username,
+		Time:       lock.Time,
+		LockURL:    a.LockURLGenerator.GenerateLockURL(key),
+	}
+}
+
+// parsePage returns the page and per_page query parameters of r. Pages start
+// at 1.
+func (a *APIController) parsePage(r *http.Request) (int, int, error) {
+	page, perPage := 1, maxAPIPageSize
+	var err error
+	if p := r.URL.Query().Get("page"); p != "" {
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("invalid page %q: must be a number greater than 0", p)
+		}
+	}
+	if p := r.URL.Query().Get("per_page"); p != "" {
+		if perPage, err = strconv.Atoi(p); err != nil || perPage < 1 || perPage > maxAPIPageSize {
+			return 0, 0, fmt.Errorf("invalid per_page %q: must be a number from 1 to %d", p, maxAPIPageSize)
+		}
+	}
+	return page, perPage, nil
+}
+
+// apiRepoID returns the ID that the API uses for the repo with full name
+// repoFullName on hostname, ex. github.com/runatlantis/atlantis.
+func apiRepoID(hostname string, repoFullName string) string {
+	return fmt.Sprintf("%s/%s", hostname, repoFullName)
+}
+
+// authenticate responds with an error and returns false if the API is
+// disabled or the request doesn't have the right token.
+func (a *APIController) authenticate(w http.ResponseWriter, r *http.Request) bool {
+	if len(a.APISecret) == 0 {
+		a.respondErr(w, logging.Info, http.StatusNotFound, "the API is disabled, set --%s to enable it", a.APISecretFlag)
+		return false
+	}
+	token := r.Header.Get(APITokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), a.APISecret) != 1 {
+		a.respondErr(w, logging.Warn, http.StatusUnauthorized, "invalid or missing %s header", APITokenHeader)
+		return false
+	}
+	return true
+}
+
+func (a *APIController) respondJSON(w http.ResponseWriter, code int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		a.Logger.Err("encoding API response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data) // nolint: errcheck
+}
+
+func (a *APIController) respondErr(w http.ResponseWriter, lvl logging.LogLevel, code int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	a.Logger.Log(lvl, "%s", response)
+	a.respondJSON(w, code, APIResult{Error: response})
+}
@@ -0,0 +1,487 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func setupAPIController(t *testing.T) (*server.APIController, *mocks.MockAPICommandRunner) {
+	RegisterMockTestingT(t)
+	repoBuilder := mocks.NewMockRepoBuilder()
+	When(repoBuilder.BuildRepo("github.com/runatlantis/atlantis")).ThenReturn(fixtures.GithubRepo, nil)
+	When(repoBuilder.BuildRepo("unknown.com/owner/repo")).ThenReturn(models.Repo{}, errors.New("unknown host"))
+	whitelist, err := events.NewRepoWhitelistChecker("github.com/runatlantis/atlantis")
+	Ok(t, err)
+	runner := mocks.NewMockAPICommandRunner()
+	return &server.APIController{
+		APISecret:            []byte("secret"),
+		APISecretFlag:        "api-secret",
+		Logger:               logging.NewNoopLogger(),
+		RepoBuilder:          repoBuilder,
+		RepoWhitelistChecker: whitelist,
+		CommandRunner:        runner,
+		LockURLGenerator:     &mockLockURLGenerator{},
+	}, runner
+}
+
+func apiRequest(t *testing.T, token string, body interface{}) *http.Request {
+	data, err := json.Marshal(body)
+	Ok(t, err)
+	req, _ := http.NewRequest("POST", "/api/plan", bytes.NewBuffer(data))
+	if token != "" {
+		req.Header.Set(server.APITokenHeader, token)
+	}
+	return req
+}
+
+func TestAPIController_Plan_Auth(t *testing.T) {
+	cases := []struct {
+		description string
+		secret      string
+		token       string
+		expCode     int
+		expErr      string
+	}{
+		{
+			"disabled",
+			"",
+			"secret",
+			http.StatusNotFound,
+			"the API is disabled, set --api-secret to enable it",
+		},
+		{
+			"missing token",
+			"secret",
+			"",
+			http.StatusUnauthorized,
+			"invalid or missing X-Atlantis-Token header",
+		},
+		{
+			"wrong token",
+			"secret",
+			"wrong",
+			http.StatusUnauthorized,
+			"invalid or missing X-Atlantis-Token header",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			ac, runner := setupAPIController(t)
+			ac.APISecret = []byte(c.secret)
+			w := httptest.NewRecorder()
+			ac.Plan(w, apiRequest(t, c.token, server.APIRequest{Repo: "github.com/runatlantis/atlantis", PullNum: 1}))
+			Equals(t, c.expCode, w.Code)
+			var res server.APIResult
+			Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+			Equals(t, c.expErr, res.Error)
+			runner.VerifyWasCalled(Never()).RunAPICommand(matchers.AnyEventsRequestContext(), matchers.AnyEventsAPIRequest())
+		})
+	}
+}
+
+func TestAPIController_Plan_InvalidRepo(t *testing.T) {
+	cases := []struct {
+		repo    string
+		expCode int
+		expErr  string
+	}{
+		{
+			"unknown.com/owner/repo",
+			http.StatusBadRequest,
+			"unknown host",
+		},
+		{
+			"github.com/runatlantis/atlantis",
+			http.StatusForbidden,
+			`repo "github.com/runatlantis/atlantis" is not whitelisted`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.repo, func(t *testing.T) {
+			ac, _ := setupAPIController(t)
+			whitelist, err := events.NewRepoWhitelistChecker("github.com/runatlantis/other")
+			Ok(t, err)
+			ac.RepoWhitelistChecker = whitelist
+			w := httptest.NewRecorder()
+			ac.Plan(w, apiRequest(t, "secret", server.APIRequest{Repo: c.repo, PullNum: 1}))
+			Equals(t, c.expCode, w.Code)
+			var res server.APIResult
+			Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+			Equals(t, c.expErr, res.Error)
+		})
+	}
+}
+
+func TestAPIController_Plan(t *testing.T) {
+	cases := []struct {
+		description string
+		result      events.CommandResult
+		err         error
+		expCode     int
+		expResult   server.APIResult
+	}{
+		{
+			"success",
+			events.CommandResult{ProjectResults: []models.ProjectResult{
+				{
+					RepoRelDir:  "dir",
+					Workspace:   "default",
+					PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy.", LockURL: "lock-url"},
+				},
+			}},
+			nil,
+			http.StatusOK,
+			server.APIResult{Projects: []server.APIProjectResult{
+				{
+					Dir:         "dir",
+					Workspace:   "default",
+					Status:      "success",
+					PlanOutput:  "Plan: 1 to add, 0 to change, 0 to destroy.",
+					PlanSummary: "Plan: 1 to add, 0 to change, 0 to destroy.",
+					LockURL:     "lock-url",
+				},
+			}},
+		},
+		{
+			"project failure",
+			events.CommandResult{ProjectResults: []models.ProjectResult{
+				{
+					RepoRelDir:  "dir",
+					Workspace:   "default",
+					ProjectName: "project",
+					Failure:     "locked",
+				},
+			}},
+			nil,
+			http.StatusInternalServerError,
+			server.APIResult{Projects: []server.APIProjectResult{
+				{
+					Dir:         "dir",
+					Workspace:   "default",
+					ProjectName: "project",
+					Status:      "failed",
+					Failure:     "locked",
+				},
+			}},
+		},
+		{
+			"command error",
+			events.CommandResult{Error: errors.New("parsing atlantis.yaml")},
+			nil,
+			http.StatusInternalServerError,
+			server.APIResult{Error: "parsing atlantis.yaml", Projects: []server.APIProjectResult{}},
+		},
+		{
+			"request error",
+			events.CommandResult{},
+			errors.New("commands can't be run on closed pull requests"),
+			http.StatusBadRequest,
+			server.APIResult{Error: "commands can't be run on closed pull requests"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			ac, runner := setupAPIController(t)
+			When(runner.RunAPICommand(matchers.AnyEventsRequestContext(), matchers.AnyEventsAPIRequest())).ThenReturn(c.result, c.err)
+			w := httptest.NewRecorder()
+			ac.Plan(w, apiRequest(t, "secret", server.APIRequest{
+				Repo:     "github.com/runatlantis/atlantis",
+				PullNum:  1,
+				Projects: []server.APIProjectRef{{Dir: "dir"}, {Name: "project"}},
+			}))
+			Equals(t, c.expCode, w.Code)
+			Equals(t, "application/json", w.Header().Get("Content-Type"))
+			var res server.APIResult
+			Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+			Equals(t, c.expResult, res)
+
+			_, req := runner.VerifyWasCalledOnce().RunAPICommand(matchers.AnyEventsRequestContext(), matchers.AnyEventsAPIRequest()).GetCapturedArguments()
+			Equals(t, events.APIRequest{
+				Name:     models.PlanCommand,
+				Repo:     fixtures.GithubRepo,
+				PullNum:  1,
+				Projects: []events.APIProject{{Dir: "dir"}, {Name: "project"}},
+			}, req)
+		})
+	}
+}
+
+func TestAPIController_ApplyAsync(t *testing.T) {
+	ac, runner := setupAPIController(t)
+	done := make(chan struct{})
+	When(runner.RunAPICommand(matchers.AnyEventsRequestContext(), matchers.AnyEventsAPIRequest())).Then(func(params []Param) ReturnValues {
+		<-done
+		return ReturnValues{events.CommandResult{ProjectResults: []models.ProjectResult{{RepoRelDir: ".", Workspace: "default", ApplySuccess: "applied"}}}, nil}
+	})
+	w := httptest.NewRecorder()
+	ac.Apply(w, apiRequest(t, "secret", server.APIRequest{Repo: "github.com/runatlantis/atlantis", PullNum: 1, Async: true}))
+	Equals(t, http.StatusAccepted, w.Code)
+	var job server.APIJob
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &job))
+	Equals(t, "running", job.Status)
+	Assert(t, job.ID != "", "exp job ID")
+
+	getJob := func(id string) (int, server.APIJob) {
+		req, _ := http.NewRequest("GET", "/api/jobs/"+id, nil)
+		req.Header.Set(server.APITokenHeader, "secret")
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		ac.GetJob(w, req)
+		var job server.APIJob
+		json.Unmarshal(w.Body.Bytes(), &job) // nolint: errcheck
+		return w.Code, job
+	}
+
+	code, _ := getJob("unknown")
+	Equals(t, http.StatusNotFound, code)
+	code, polled := getJob(job.ID)
+	Equals(t, http.StatusOK, code)
+	Equals(t, "running", polled.Status)
+
+	close(done)
+	for i := 0; i < 100 && polled.Status != "complete"; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, polled = getJob(job.ID)
+	}
+	Equals(t, "complete", polled.Status)
+	Equals(t, &server.APIResult{Projects: []server.APIProjectResult{
+		{Dir: ".", Workspace: "default", Status: "success", ApplyOutput: "applied"},
+	}}, polled.Result)
+	_, req := runner.VerifyWasCalledOnce().RunAPICommand(matchers.AnyEventsRequestContext(), matchers.AnyEventsAPIRequest()).GetCapturedArguments()
+	Equals(t, models.ApplyCommand, req.Name)
+}
+
+func apiGet(path string, vars map[string]string) *http.Request {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set(server.APITokenHeader, "secret")
+	return mux.SetURLVars(req, vars)
+}
+
+func TestAPIController_ListLocks(t *testing.T) {
+	ac, _ := setupAPIController(t)
+	locker := lockmocks.NewMockLocker()
+	ac.Locker = locker
+	lockTime := time.Now().UTC()
+	lock := func(repoFullName string, path string, pullNum int) models.ProjectLock {
+		return models.ProjectLock{
+			Project:   models.Project{RepoFullName: repoFullName, Path: path},
+			Pull:      models.PullRequest{Num: pullNum, BaseRepo: models.Repo{VCSHost: models.VCSHost{Hostname: "github.com"}}},
+			User:      models.User{Username: "lkysow"},
+			Workspace: "default",
+			Time:      lockTime,
+		}
+	}
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+		"owner/repo/b/default":  lock("owner/repo", "b", 2),
+		"owner/repo/a/default":  lock("owner/repo", "a", 1),
+		"owner/other/./default": lock("owner/other", ".", 3),
+	}, nil)
+
+	cases := []struct {
+		query   string
+		expCode int
+		expKeys []string
+		expErr  string
+	}{
+		{"", http.StatusOK, []string{"owner/other/./default", "owner/repo/a/default", "owner/repo/b/default"}, ""},
+		{"?repo=github.com/owner/repo", http.StatusOK, []string{"owner/repo/a/default", "owner/repo/b/default"}, ""},
+		{"?repo=gitlab.com/owner/repo", http.StatusOK, []string{}, ""},
+		{"?per_page=2", http.StatusOK, []string{"owner/other/./default", "owner/repo/a/default"}, ""},
+		{"?per_page=2&page=2", http.StatusOK, []string{"owner/repo/b/default"}, ""},
+		{"?page=3", http.StatusOK, []string{}, ""},
+		{"?page=0", http.StatusBadRequest, nil, `invalid page "0": must be a number greater than 0`},
+		{"?per_page=101", http.StatusBadRequest, nil, `invalid per_page "101": must be a number from 1 to 100`},
+	}
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ac.ListLocks(w, apiGet("/api/locks"+c.query, nil))
+			Equals(t, c.expCode, w.Code)
+			if c.expErr != "" {
+				var res server.APIResult
+				Ok(t, json.Unmarshal(w.Body.Bytes(), &res))
+				Equals(t, c.expErr, res.Error)
+				return
+			}
+			var list server.APILockList
+			Ok(t, json.Unmarshal(w.Body.Bytes(), &list))
+			keys := []string{}
+			for _, l := range list.Locks {
+				keys = append(keys, l.Key)
+			}
+			Equals(t, c.expKeys, keys)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	ac.ListLocks(w, apiGet("/api/locks?repo=github.com/owner/other", nil))
+	var list server.APILockList
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &list))
+	Equals(t, server.APILockList{
+		Locks: []server.APILock{
+			{
+				ID:        "b3duZXIvb3RoZXIvLi9kZWZhdWx0",
+				Key:       "owner/other/./default",
+				Repo:      "github.com/owner/other",
+				Dir:       ".",
+				Workspace: "default",
+				PullNum:   3,
+				LockedBy:  "lkysow",
+				Time:      lockTime,
+				LockURL:   "lock-url",
+			},
+		},
+		Total:   1,
+		Page:    1,
+		PerPage: 100,
+	}, list)
+}
+
+func TestAPIController_GetLock(t *testing.T) {
+	ac, _ := setupAPIController(t)
+	locker := lockmocks.NewMockLocker()
+	ac.Locker = locker
+	lock := models.ProjectLock{
+		Project:   models.Project{RepoFullName: "owner/repo", Path: "."},
+		Pull:      models.PullRequest{Num: 1, BaseRepo: models.Repo{VCSHost: models.VCSHost{Hostname: "github.com"}}},
+		User:      models.User{Username: "lkysow"},
+		Workspace: "default",
+	}
+	queued := models.ProjectLock{
+		Pull: models.PullRequest{Num: 2, URL: "pull-url"},
+		User: models.User{Username: "queued"},
+	}
+	When(locker.GetLock("owner/repo/./default")).ThenReturn(&lock, nil)
+	When(locker.GetQueue("owner/repo/./default")).ThenReturn([]models.ProjectLock{queued}, nil)
+
+	id := "b3duZXIvcmVwby8uL2RlZmF1bHQ"
+	w := httptest.NewRecorder()
+	ac.GetLock(w, apiGet("/api/locks/"+id, map[string]string{"id": id}))
+	Equals(t, http.StatusOK, w.Code)
+	var resp server.APILock
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	Equals(t, server.APILock{
+		ID:        id,
+		Key:       "owner/repo/./default",
+		Repo:      "github.com/owner/repo",
+		Dir:       ".",
+		Workspace: "default",
+		PullNum:   1,
+		LockedBy:  "lkysow",
+		LockURL:   "lock-url",
+		Queue:     []server.APIQueuedPull{{PullNum: 2, PullURL: "pull-url", QueuedBy: "queued"}},
+	}, resp)
+
+	t.Run("not found", func(t *testing.T) {
+		id := "b3duZXIvcmVwby9kaXIvZGVmYXVsdA"
+		w := httptest.NewRecorder()
+		ac.GetLock(w, apiGet("/api/locks/"+id, map[string]string{"id": id}))
+		Equals(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ac.GetLock(w, apiGet("/api/locks/owner/repo", map[string]string{"id": "owner/repo"}))
+		Equals(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAPIController_GetPullStatus(t *testing.T) {
+	ac, _ := setupAPIController(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ac.DB = boltDB
+	pull := models.PullRequest{BaseRepo: fixtures.GithubRepo, Num: 1, HeadCommit: "abc123", URL: "pull-url"}
+	_, err = boltDB.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  "staging",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{PlanSummary: &models.PlanSummary{Add: 1, Change: 2, Destroy: 3}},
+		},
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  "production",
+			Workspace:   "default",
+			ProjectName: "production",
+			Error:       errors.New("err"),
+		},
+	})
+	Ok(t, err)
+
+	vars := map[string]string{"repo": "github.com/runatlantis/atlantis", "num": "1"}
+	w := httptest.NewRecorder()
+	ac.GetPullStatus(w, apiGet("/api/pulls/github.com/runatlantis/atlantis/1", vars))
+	Equals(t, http.StatusOK, w.Code)
+	var resp server.APIPullStatus
+	Ok(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	Equals(t, server.APIPullStatus{
+		Repo:       "github.com/runatlantis/atlantis",
+		PullNum:    1,
+		PullURL:    "pull-url",
+		HeadCommit: "abc123",
+		Projects: []server.APIProjectStatus{
+			{
+				Dir:         "staging",
+				Workspace:   "default",
+				Status:      "planned",
+				PlanSummary: &server.APIPlanSummary{Add: 1, Change: 2, Destroy: 3},
+			},
+			{
+				Dir:         "production",
+				Workspace:   "default",
+				ProjectName: "production",
+				Status:      "plan_errored",
+			},
+		},
+	}, resp)
+
+	t.Run("not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ac.GetPullStatus(w, apiGet("/api/pulls/github.com/runatlantis/atlantis/2", map[string]string{"repo": "github.com/runatlantis/atlantis", "num": "2"}))
+		Equals(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid number", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ac.GetPullStatus(w, apiGet("/api/pulls/github.com/runatlantis/atlantis/one", map[string]string{"repo": "github.com/runatlantis/atlantis", "num": "one"}))
+		Equals(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid repo", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ac.GetPullStatus(w, apiGet("/api/pulls/github.com/atlantis/1", map[string]string{"repo": "github.com/atlantis", "num": "1"}))
+		Equals(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unconfigured host", func(t *testing.T) {
+		t.Log("statuses of repos on hosts the API can't run commands on, ex. Bitbucket Server, should still be found")
+		bitbucketServerRepo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "bitbucket.example.com", Type: models.BitbucketServer}}
+		pull := models.PullRequest{BaseRepo: bitbucketServerRepo, Num: 3, HeadCommit: "def456"}
+		_, err := boltDB.UpdatePullWithResults(pull, []models.ProjectResult{{Command: models.PlanCommand, RepoRelDir: ".", Workspace: "default", PlanSuccess: &models.PlanSuccess{}}})
+		Ok(t, err)
+		w := httptest.NewRecorder()
+		ac.GetPullStatus(w, apiGet("/api/pulls/bitbucket.example.com/owner/repo/3", map[string]string{"repo": "bitbucket.example.com/owner/repo", "num": "3"}))
+		Equals(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,50 @@
+// Package auth authenticates users of the Atlantis UI.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Authenticator authenticates requests to the UI.
+type Authenticator interface {
+	// Authenticate returns the username of the user that sent r. If r isn't
+	// authenticated, it responds, ex. with a redirect to the login page, and
+	// returns false.
+	Authenticate(w http.ResponseWriter, r *http.Request) (string, bool)
+}
+
+// usernameKey is the request context key that the authenticated username is
+// stored under.
+type usernameKey struct{}
+
+// Middleware returns middleware for the mux router that authenticates
+// requests with a. Requests to paths that start with one of skipPrefixes
+// aren't authenticated, ex. webhooks which are validated separately.
+func Middleware(a Authenticator, skipPrefixes ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range skipPrefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			username, ok := a.Authenticate(w, r)
+			if !ok {
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), usernameKey{}, username)))
+		})
+	}
+}
+
+// Username returns the username that r was authenticated as or "" if the UI
+// doesn't require authentication.
+func Username(r *http.Request) string {
+	username, _ := r.Context().Value(usernameKey{}).(string)
+	return username
+}
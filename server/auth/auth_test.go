@@ -0,0 +1,56 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/auth"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestMiddleware_BasicAuth(t *testing.T) {
+	var username string
+	handler := auth.Middleware(&auth.BasicAuthenticator{Username: "user", Password: "pass"}, "/events")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username = auth.Username(r)
+		}))
+
+	cases := []struct {
+		description string
+		path        string
+		user        string
+		pass        string
+		expCode     int
+		expUsername string
+	}{
+		{"no credentials", "/", "", "", http.StatusUnauthorized, ""},
+		{"wrong user", "/", "wrong", "pass", http.StatusUnauthorized, ""},
+		{"wrong password", "/", "user", "wrong", http.StatusUnauthorized, ""},
+		{"valid credentials", "/", "user", "pass", http.StatusOK, "user"},
+		{"skipped path", "/events", "", "", http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			username = "not called"
+			req, _ := http.NewRequest("GET", c.path, nil)
+			if c.user != "" {
+				req.SetBasicAuth(c.user, c.pass)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			Equals(t, c.expCode, w.Code)
+			if c.expCode == http.StatusUnauthorized {
+				Equals(t, "not called", username)
+				Equals(t, `Basic realm="Atlantis", charset="UTF-8"`, w.Header().Get("WWW-Authenticate"))
+				return
+			}
+			Equals(t, c.expUsername, username)
+		})
+	}
+}
+
+func TestUsername_NotAuthenticated(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	Equals(t, "", auth.Username(req))
+}
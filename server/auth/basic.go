@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// BasicAuthenticator authenticates users with HTTP basic auth. There's a
+// single user whose credentials are set by flags.
+type BasicAuthenticator struct {
+	Username string
+	Password string
+}
+
+// Authenticate implements Authenticator.
+func (b *BasicAuthenticator) Authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	user, pass, ok := r.BasicAuth()
+	// Compare both so we take the same time whether or not the user matches.
+	userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(b.Username))
+	passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(b.Password))
+	if !ok || userMatch&passMatch != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Atlantis", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	return user, true
+}
@@ -0,0 +1,415 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+	"golang.org/x/oauth2"
+)
+
+// OIDCCallbackPath is the path that the OpenID Connect provider redirects
+// users to once they've logged in. Atlantis' URL with this path must be
+// registered as a redirect URI with the provider.
+const OIDCCallbackPath = "/auth/callback"
+
+const (
+	sessionCookieName = "atlantis_session"
+	stateCookieName   = "atlantis_oidc_state"
+	// sessionDuration is how long users stay logged in.
+	sessionDuration = 24 * time.Hour
+	// loginDuration is how long users have to log in with the provider.
+	loginDuration = 10 * time.Minute
+)
+
+// OIDCAuthenticator authenticates users by logging them in with an OpenID
+// Connect provider, ex. Okta or Google. Once they've logged in, they're
+// identified by a signed session cookie.
+type OIDCAuthenticator struct {
+	Logger *logging.SimpleLogger
+
+	issuer       string
+	oauth2Config oauth2.Config
+	jwksURL      string
+	httpClient   *http.Client
+	// basePath is the path of Atlantis' URL, ex. /basepath. Paths that users
+	// are redirected to after logging in are relative to it.
+	basePath      string
+	secureCookies bool
+	// sessionKey signs session cookies. If it's generated when Atlantis
+	// starts, restarting Atlantis logs everyone out.
+	sessionKey []byte
+
+	keysMutex sync.Mutex
+	// keys are the provider's signing keys by key ID.
+	keys map[string]*rsa.PublicKey
+}
+
+// oidcDiscovery is the provider configuration served at
+// /.well-known/openid-configuration.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims are the claims of an ID token that Atlantis uses.
+type idTokenClaims struct {
+	Issuer   string   `json:"iss"`
+	Subject  string   `json:"sub"`
+	Audience audience `json:"aud"`
+	Expiry   int64    `json:"exp"`
+	Nonce    string   `json:"nonce"`
+	Email    string   `json:"email"`
+}
+
+// audience is the aud claim. It's either a string or an array of strings.
+type audience []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	*a = arr
+	return nil
+}
+
+// NewOIDCAuthenticator fetches the configuration of the provider at
+// issuerURL. redirectURL is Atlantis' URL followed by OIDCCallbackPath.
+// sessionSecret signs session cookies so they stay valid across restarts and
+// across servers. If it's empty, a random key is used instead.
+func NewOIDCAuthenticator(logger *logging.SimpleLogger, issuerURL string, clientID string, clientSecret string, sessionSecret string, redirectURL string) (*OIDCAuthenticator, error) {
+	parsedRedirect, err := url.Parse(redirectURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing redirect URL")
+	}
+	sessionKey := []byte(sessionSecret)
+	if sessionSecret == "" {
+		logger.Warn("no OIDC session secret set so users will be logged out whenever Atlantis restarts and sessions won't be valid on other Atlantis servers")
+		sessionKey = make([]byte, 32)
+		if _, err := rand.Read(sessionKey); err != nil {
+			return nil, errors.Wrap(err, "generating session key")
+		}
+	}
+	o := &OIDCAuthenticator{
+		Logger:        logger,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		basePath:      strings.TrimSuffix(parsedRedirect.Path, OIDCCallbackPath),
+		secureCookies: parsedRedirect.Scheme == "https",
+		sessionKey:    sessionKey,
+	}
+
+	var disc oidcDiscovery
+	discoveryURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	if err := o.getJSON(discoveryURL, &disc); err != nil {
+		return nil, errors.Wrap(err, "getting provider configuration")
+	}
+	if strings.TrimSuffix(disc.Issuer, "/") != strings.TrimSuffix(issuerURL, "/") {
+		return nil, fmt.Errorf("provider's issuer %q doesn't match %q", disc.Issuer, issuerURL)
+	}
+	o.issuer = disc.Issuer
+	o.jwksURL = disc.JWKSURI
+	o.oauth2Config = oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  disc.AuthorizationEndpoint,
+			TokenURL: disc.TokenEndpoint,
+		},
+		RedirectURL: redirectURL,
+		Scopes:      []string{"openid", "email"},
+	}
+	return o, nil
+}
+
+// Authenticate implements Authenticator. Users that aren't logged in are
+// redirected to the provider if they're loading a page. Other requests, ex.
+// deleting a lock, get a 401.
+func (o *OIDCAuthenticator) Authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if username, ok := o.verifySession(cookie.Value); ok {
+			return username, true
+		}
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Unauthorized: log in to Atlantis and try again", http.StatusUnauthorized)
+		return "", false
+	}
+
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		o.Logger.Err("generating OIDC state: %s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return "", false
+	}
+	// The state is also used as the nonce since both are single use and
+	// unguessable.
+	state := hex.EncodeToString(stateBytes)
+	http.SetCookie(w, o.cookie(stateCookieName, state+"|"+r.URL.RequestURI(), loginDuration))
+	http.Redirect(w, r, o.oauth2Config.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", state)), http.StatusFound)
+	return "", false
+}
+
+// Callback is the GET /auth/callback route. The provider redirects users to
+// it once they've logged in. It starts their session and redirects them back
+// to the page they were loading.
+func (o *OIDCAuthenticator) Callback(w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(stateCookieName)
+	if err != nil {
+		o.respond(w, logging.Warn, http.StatusBadRequest, "Login expired, try again")
+		return
+	}
+	parts := strings.SplitN(stateCookie.Value, "|", 2)
+	state := r.URL.Query().Get("state")
+	if len(parts) != 2 || state == "" || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(state)) != 1 {
+		o.respond(w, logging.Warn, http.StatusBadRequest, "Invalid login state, try again")
+		return
+	}
+	returnPath := parts[1]
+	// Only redirect to paths on Atlantis.
+	if !strings.HasPrefix(returnPath, "/") || strings.HasPrefix(returnPath, "//") {
+		returnPath = "/"
+	}
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		o.respond(w, logging.Warn, http.StatusUnauthorized, "Login failed: %s %s", errCode, r.URL.Query().Get("error_description"))
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), oauth2.HTTPClient, o.httpClient)
+	token, err := o.oauth2Config.Exchange(ctx, r.URL.Query().Get("code"))
+	if err != nil {
+		o.respond(w, logging.Warn, http.StatusUnauthorized, "Login failed: exchanging code: %s", err)
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		o.respond(w, logging.Warn, http.StatusUnauthorized, "Login failed: provider didn't return an ID token")
+		return
+	}
+	claims, err := o.verifyIDToken(rawIDToken, state)
+	if err != nil {
+		o.respond(w, logging.Warn, http.StatusUnauthorized, "Login failed: invalid ID token: %s", err)
+		return
+	}
+	username := claims.Email
+	if username == "" {
+		username = claims.Subject
+	}
+	o.Logger.Info("%s logged in to the UI", username)
+
+	http.SetCookie(w, o.cookie(stateCookieName, "", -time.Second))
+	http.SetCookie(w, o.cookie(sessionCookieName, o.signSession(username, time.Now().Add(sessionDuration)), sessionDuration))
+	http.Redirect(w, r, o.basePath+returnPath, http.StatusFound)
+}
+
+// verifyIDToken verifies that rawIDToken was signed by the provider for
+// Atlantis and hasn't expired and returns its claims.
+func (o *OIDCAuthenticator) verifyIDToken(rawIDToken string, nonce string) (idTokenClaims, error) {
+	var claims idTokenClaims
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return claims, errors.Wrap(err, "decoding header")
+	}
+	if header.Alg != "RS256" {
+		return claims, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+	key, err := o.signingKey(header.Kid)
+	if err != nil {
+		return claims, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, errors.Wrap(err, "decoding signature")
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig); err != nil {
+		return claims, errors.New("invalid signature")
+	}
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return claims, errors.Wrap(err, "decoding claims")
+	}
+	if claims.Issuer != o.issuer {
+		return claims, fmt.Errorf("issued by %q, not %q", claims.Issuer, o.issuer)
+	}
+	validAudience := false
+	for _, aud := range claims.Audience {
+		if aud == o.oauth2Config.ClientID {
+			validAudience = true
+		}
+	}
+	if !validAudience {
+		return claims, fmt.Errorf("not issued for client %q", o.oauth2Config.ClientID)
+	}
+	if time.Now().Unix() >= claims.Expiry {
+		return claims, errors.New("expired")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return claims, errors.New("invalid nonce")
+	}
+	return claims, nil
+}
+
+// signingKey returns the provider's key with ID kid. The keys are fetched
+// again if it's not known since providers rotate them.
+func (o *OIDCAuthenticator) signingKey(kid string) (*rsa.PublicKey, error) {
+	o.keysMutex.Lock()
+	defer o.keysMutex.Unlock()
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(o.jwksURL, &jwks); err != nil {
+		return nil, errors.Wrap(err, "getting provider's signing keys")
+	}
+	o.keys = make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding key %q", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding key %q", k.Kid)
+		}
+		o.keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	key, ok := o.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no signing key with id %q", kid)
+	}
+	return key, nil
+}
+
+// signSession returns the value of a session cookie for username that
+// expires at expiry.
+func (o *OIDCAuthenticator) signSession(username string, expiry time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(username)) + "." + strconv.FormatInt(expiry.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(o.mac(payload))
+}
+
+// verifySession returns the username of the session cookie value or false if
+// it wasn't signed by Atlantis or it's expired.
+func (o *OIDCAuthenticator) verifySession(value string) (string, bool) {
+	i := strings.LastIndex(value, ".")
+	if i < 0 {
+		return "", false
+	}
+	payload := value[:i]
+	sig, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil || !hmac.Equal(sig, o.mac(payload)) {
+		return "", false
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 2 {
+		return "", false
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expiry {
+		return "", false
+	}
+	username, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	return string(username), true
+}
+
+func (o *OIDCAuthenticator) mac(payload string) []byte {
+	h := hmac.New(sha256.New, o.sessionKey)
+	h.Write([]byte(payload)) // nolint: errcheck
+	return h.Sum(nil)
+}
+
+// cookie returns a cookie that expires after maxAge. If maxAge is negative,
+// the cookie is deleted.
+func (o *OIDCAuthenticator) cookie(name string, value string, maxAge time.Duration) *http.Cookie {
+	path := o.basePath
+	if path == "" {
+		path = "/"
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   o.secureCookies,
+		HttpOnly: true,
+		// Lax so the cookies are sent when the provider redirects back.
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (o *OIDCAuthenticator) getJSON(u string, v interface{}) error {
+	resp, err := o.httpClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s responded with %d", u, resp.StatusCode)
+	}
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(v), "decoding response from %s", u)
+}
+
+func (o *OIDCAuthenticator) respond(w http.ResponseWriter, lvl logging.LogLevel, code int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	o.Logger.Log(lvl, "%s", response)
+	w.WriteHeader(code)
+	fmt.Fprintln(w, response)
+}
+
+// decodeSegment decodes a base64 encoded JSON segment of a JWT into v.
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
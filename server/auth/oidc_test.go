@@ -0,0 +1,337 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/auth"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const redirectURL = "https://atlantis.example.com/basepath" + auth.OIDCCallbackPath
+
+// fakeIssuer is a local OpenID Connect provider. Its token endpoint returns
+// an ID token with claims for any code.
+type fakeIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	// claims are the claims of the ID tokens it issues. nonce is set to the
+	// nonce of the last authorization request.
+	claims map[string]interface{}
+	// signingKey signs the ID tokens. It's key unless a test changes it.
+	signingKey *rsa.PrivateKey
+	// issuer is the issuer in its configuration. It's its URL unless a test
+	// changes it.
+	issuer string
+	// header is the header of the ID tokens it issues.
+	header map[string]string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Ok(t, err)
+	f := &fakeIssuer{key: key, signingKey: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{ // nolint: errcheck
+			"issuer":                 f.issuer,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{ // nolint: errcheck
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "key-id",
+					"n":   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		if clientID != "client-id" || clientSecret != "client-secret" || r.FormValue("code") != "code" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{ // nolint: errcheck
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"id_token":     f.idToken(t),
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	f.issuer = f.URL
+	f.header = map[string]string{"alg": "RS256", "kid": "key-id"}
+	f.claims = map[string]interface{}{
+		"iss":   f.URL,
+		"sub":   "1234",
+		"aud":   []string{"client-id"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "lkysow@example.com",
+	}
+	return f
+}
+
+func (f *fakeIssuer) idToken(t *testing.T) string {
+	header, err := json.Marshal(f.header)
+	Ok(t, err)
+	claims, err := json.Marshal(f.claims)
+	Ok(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, f.signingKey, crypto.SHA256, hash[:])
+	Ok(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// login starts logging in to o from path and returns the state cookie and
+// the parsed redirect to the issuer.
+func login(t *testing.T, f *fakeIssuer, o *auth.OIDCAuthenticator, path string) (*http.Cookie, *url.URL) {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	_, ok := o.Authenticate(w, req)
+	Assert(t, !ok, "exp not authenticated")
+	Equals(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	Ok(t, err)
+	cookies := w.Result().Cookies()
+	Equals(t, 1, len(cookies))
+	f.claims["nonce"] = location.Query().Get("nonce")
+	return cookies[0], location
+}
+
+// callback calls o's callback with stateCookie and state.
+func callback(o *auth.OIDCAuthenticator, stateCookie *http.Cookie, state string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", auth.OIDCCallbackPath+"?code=code&state="+url.QueryEscape(state), nil)
+	req.AddCookie(stateCookie)
+	w := httptest.NewRecorder()
+	o.Callback(w, req)
+	return w
+}
+
+func TestOIDCAuthenticator_Login(t *testing.T) {
+	f := newFakeIssuer(t)
+	defer f.Close()
+	o, err := auth.NewOIDCAuthenticator(logging.NewNoopLogger(), f.URL, "client-id", "client-secret", "", redirectURL)
+	Ok(t, err)
+
+	stateCookie, location := login(t, f, o, "/lock?id=owner%2Frepo%2F.%2Fdefault")
+	Equals(t, f.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+	Equals(t, "client-id", location.Query().Get("client_id"))
+	Equals(t, redirectURL, location.Query().Get("redirect_uri"))
+	Equals(t, "openid email", location.Query().Get("scope"))
+	Equals(t, "/basepath", stateCookie.Path)
+	Assert(t, stateCookie.Secure, "exp secure cookie since Atlantis uses https")
+
+	w := callback(o, stateCookie, location.Query().Get("state"))
+	Equals(t, http.StatusFound, w.Code)
+	Equals(t, "/basepath/lock?id=owner%2Frepo%2F.%2Fdefault", w.Header().Get("Location"))
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "atlantis_session" {
+			session = c
+		}
+	}
+	Assert(t, session != nil, "exp session cookie")
+
+	req, _ := http.NewRequest("DELETE", "/locks?id=id", nil)
+	req.AddCookie(session)
+	username, ok := o.Authenticate(httptest.NewRecorder(), req)
+	Assert(t, ok, "exp authenticated")
+	Equals(t, "lkysow@example.com", username)
+
+	t.Run("tampered session", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: session.Name, Value: base64.RawURLEncoding.EncodeToString([]byte("admin")) + session.Value[len(base64.RawURLEncoding.EncodeToString([]byte("lkysow@example.com"))):]})
+		w := httptest.NewRecorder()
+		_, ok := o.Authenticate(w, req)
+		Assert(t, !ok, "exp not authenticated")
+		Equals(t, http.StatusFound, w.Code)
+	})
+}
+
+func TestOIDCAuthenticator_NotLoggedIn(t *testing.T) {
+	t.Log("requests other than GETs can't be redirected so they should get a 401")
+	f := newFakeIssuer(t)
+	defer f.Close()
+	o, err := auth.NewOIDCAuthenticator(logging.NewNoopLogger(), f.URL, "client-id", "client-secret", "", redirectURL)
+	Ok(t, err)
+
+	req, _ := http.NewRequest("DELETE", "/locks?id=id", nil)
+	w := httptest.NewRecorder()
+	_, ok := o.Authenticate(w, req)
+	Assert(t, !ok, "exp not authenticated")
+	Equals(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestOIDCAuthenticator_InvalidCallback(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	Ok(t, err)
+	cases := []struct {
+		description string
+		update      func(f *fakeIssuer)
+		wrongState  bool
+		expCode     int
+		expBody     string
+	}{
+		{
+			"wrong state",
+			func(f *fakeIssuer) {},
+			true,
+			http.StatusBadRequest,
+			"Invalid login state, try again",
+		},
+		{
+			"wrong signing key",
+			func(f *fakeIssuer) { f.signingKey = otherKey },
+			false,
+			http.StatusUnauthorized,
+			"Login failed: invalid ID token: invalid signature",
+		},
+		{
+			"unsupported signing algorithm",
+			func(f *fakeIssuer) { f.header["alg"] = "HS256" },
+			false,
+			http.StatusUnauthorized,
+			`Login failed: invalid ID token: unsupported signing algorithm "HS256"`,
+		},
+		{
+			"unknown key id",
+			func(f *fakeIssuer) { f.header["kid"] = "other-key-id" },
+			false,
+			http.StatusUnauthorized,
+			`Login failed: invalid ID token: no signing key with id "other-key-id"`,
+		},
+		{
+			"wrong issuer",
+			func(f *fakeIssuer) { f.claims["iss"] = "https://other.example.com" },
+			false,
+			http.StatusUnauthorized,
+			"Login failed: invalid ID token: issued by \"https://other.example.com\", not ",
+		},
+		{
+			"wrong audience",
+			func(f *fakeIssuer) { f.claims["aud"] = "other-client-id" },
+			false,
+			http.StatusUnauthorized,
+			`Login failed: invalid ID token: not issued for client "client-id"`,
+		},
+		{
+			"wrong audiences",
+			func(f *fakeIssuer) { f.claims["aud"] = []string{"other-client-id", "another-client-id"} },
+			false,
+			http.StatusUnauthorized,
+			`Login failed: invalid ID token: not issued for client "client-id"`,
+		},
+		{
+			"expired",
+			func(f *fakeIssuer) { f.claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+			false,
+			http.StatusUnauthorized,
+			"Login failed: invalid ID token: expired",
+		},
+		{
+			"wrong nonce",
+			func(f *fakeIssuer) { f.claims["nonce"] = "other-nonce" },
+			false,
+			http.StatusUnauthorized,
+			"Login failed: invalid ID token: invalid nonce",
+		},
+		{
+			"no nonce",
+			func(f *fakeIssuer) { delete(f.claims, "nonce") },
+			false,
+			http.StatusUnauthorized,
+			"Login failed: invalid ID token: invalid nonce",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			f := newFakeIssuer(t)
+			defer f.Close()
+			o, err := auth.NewOIDCAuthenticator(logging.NewNoopLogger(), f.URL, "client-id", "client-secret", "", redirectURL)
+			Ok(t, err)
+
+			stateCookie, location := login(t, f, o, "/")
+			c.update(f)
+			state := location.Query().Get("state")
+			if c.wrongState {
+				state = "other-state"
+			}
+			w := callback(o, stateCookie, state)
+			Equals(t, c.expCode, w.Code)
+			Assert(t, strings.HasPrefix(w.Body.String(), c.expBody), "exp body %q to start with %q", w.Body.String(), c.expBody)
+			for _, cookie := range w.Result().Cookies() {
+				Assert(t, cookie.Name != "atlantis_session", "exp no session cookie")
+			}
+		})
+	}
+}
+
+// Test that with a session secret, sessions started on one authenticator are
+// valid on another, ex. after Atlantis restarts.
+func TestOIDCAuthenticator_SessionSecret(t *testing.T) {
+	f := newFakeIssuer(t)
+	defer f.Close()
+	o, err := auth.NewOIDCAuthenticator(logging.NewNoopLogger(), f.URL, "client-id", "client-secret", "session-secret", redirectURL)
+	Ok(t, err)
+	stateCookie, location := login(t, f, o, "/")
+	w := callback(o, stateCookie, location.Query().Get("state"))
+	Equals(t, http.StatusFound, w.Code)
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "atlantis_session" {
+			session = c
+		}
+	}
+	Assert(t, session != nil, "exp session cookie")
+
+	cases := []struct {
+		description   string
+		sessionSecret string
+		expOk         bool
+	}{
+		{"same secret", "session-secret", true},
+		{"other secret", "other-secret", false},
+		{"random secret", "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			restarted, err := auth.NewOIDCAuthenticator(logging.NewNoopLogger(), f.URL, "client-id", "client-secret", c.sessionSecret, redirectURL)
+			Ok(t, err)
+			req, _ := http.NewRequest("DELETE", "/locks?id=id", nil)
+			req.AddCookie(session)
+			username, ok := restarted.Authenticate(httptest.NewRecorder(), req)
+			Equals(t, c.expOk, ok)
+			if c.expOk {
+				Equals(t, "lkysow@example.com", username)
+			}
+		})
+	}
+}
+
+func TestNewOIDCAuthenticator_IssuerMismatch(t *testing.T) {
+	f := newFakeIssuer(t)
+	defer f.Close()
+	f.issuer = "https://other.example.com"
+	_, err := auth.NewOIDCAuthenticator(logging.NewNoopLogger(), f.URL, "client-id", "client-secret", "", redirectURL)
+	ErrEquals(t, `provider's issuer "https://other.example.com" doesn't match "`+f.URL+`"`, err)
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DriftController handles requests for the results of drift detection.
+type DriftController struct {
+	AtlantisVersion string
+	AtlantisURL     *url.URL
+	Logger          *logging.SimpleLogger
+	// DriftDetector is nil if drift detection isn't enabled.
+	DriftDetector *events.DriftDetector
+	DriftTemplate TemplateWriter
+}
+
+// GetDrift is the GET /drift route. It renders the result of the last drift
+// check of each project.
+func (d *DriftController) GetDrift(w http.ResponseWriter, _ *http.Request) {
+	viewData := DriftData{
+		Enabled:         d.DriftDetector != nil,
+		AtlantisVersion: d.AtlantisVersion,
+		CleanedBasePath: d.AtlantisURL.Path,
+	}
+	if d.DriftDetector != nil {
+		for _, r := range d.DriftDetector.Results() {
+			viewData.Projects = append(viewData.Projects, DriftProjectData{
+				RepoID:      r.RepoID,
+				RepoRelDir:  r.RepoRelDir,
+				Workspace:   r.Workspace,
+				ProjectName: r.ProjectName,
+				CheckedAt:   r.CheckedAt,
+				Status:      r.Status.String(),
+				Summary:     r.Summary,
+				Error:       r.Error,
+			})
+		}
+	}
+	if err := d.DriftTemplate.Execute(w, viewData); err != nil {
+		d.Logger.Err("%s", err)
+	}
+}
@@ -0,0 +1,79 @@
+package server_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+)
+
+func TestGetDrift_Disabled(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpl := sMocks.NewMockTemplateWriter()
+	atlantisURL, _ := url.Parse("https://example.com/basepath")
+	dc := server.DriftController{
+		AtlantisVersion: "1.0.0",
+		AtlantisURL:     atlantisURL,
+		Logger:          logging.NewNoopLogger(),
+		DriftTemplate:   tmpl,
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	dc.GetDrift(w, req)
+
+	tmpl.VerifyWasCalledOnce().Execute(w, server.DriftData{
+		Enabled:         false,
+		AtlantisVersion: "1.0.0",
+		CleanedBasePath: "/basepath",
+	})
+}
+
+func TestGetDrift_Renders(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpl := sMocks.NewMockTemplateWriter()
+	repoBuilder := mocks.NewMockRepoBuilder()
+	When(repoBuilder.BuildRepo("github.com/owner/repo")).ThenReturn(models.Repo{}, errors.New("err"))
+	detector := &events.DriftDetector{
+		Repos:       []string{"github.com/owner/repo"},
+		RepoBuilder: repoBuilder,
+		Logger:      logging.NewNoopLogger(),
+	}
+	detector.CheckAll()
+	results := detector.Results()
+
+	atlantisURL, _ := url.Parse("https://example.com")
+	dc := server.DriftController{
+		AtlantisVersion: "1.0.0",
+		AtlantisURL:     atlantisURL,
+		Logger:          logging.NewNoopLogger(),
+		DriftDetector:   detector,
+		DriftTemplate:   tmpl,
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	dc.GetDrift(w, req)
+
+	tmpl.VerifyWasCalledOnce().Execute(w, server.DriftData{
+		Enabled: true,
+		Projects: []server.DriftProjectData{
+			{
+				RepoID:    "github.com/owner/repo",
+				CheckedAt: results[0].CheckedAt,
+				Status:    "error",
+				Error:     "err",
+			},
+		},
+		AtlantisVersion: "1.0.0",
+		CleanedBasePath: "",
+	})
+}
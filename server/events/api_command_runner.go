@@ -0,0 +1,235 @@
+package events
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_api_command_runner.go APICommandRunner
+
+// APICommandRunner runs commands requested through the API instead of pull
+// request comments.
+type APICommandRunner interface {
+	// RunAPICommand runs the command in req and returns its result. An error
+	// is returned if the command couldn't be run at all, ex. because the pull
+	// request is closed.
+	RunAPICommand(reqCtx RequestContext, req APIRequest) (CommandResult, error)
+}
+
+// APIUsername is the user that commands run through the API are run as. It's
+// shown as the owner of the locks they take.
+const APIUsername = "atlantis-api"
+
+// APIRequest is a request to run a command through the API.
+type APIRequest struct {
+	// Name is the command to run. Only plan and apply are supported.
+	Name models.CommandName
+	Repo models.Repo
+	// PullNum is the pull request to run the command on. If it's 0, the
+	// command is run on Ref instead.
+	PullNum int
+	// Ref is the branch or tag to run the command on if PullNum is 0.
+	Ref string
+	// Projects are the projects to run the command on. If empty, the command
+	// is run on the same projects as a comment without any flags would be.
+	// It can't be empty if PullNum is 0.
+	Projects []APIProject
+}
+
+// APIProject selects a project by name or by dir and workspace, the same as
+// the -p, -d and -w flags of comment commands.
+type APIProject struct {
+	Dir       string
+	Workspace string
+	Name      string
+}
+
+// RunAPICommand runs the command in req. On pull requests, it runs the same
+// as a comment command would: the pull request is commented on, its
+// commit statuses are updated and the locks it takes are kept until it's
+// merged. Refs aren't pull requests so their locks are held by pull request
+// number 0 and released once the command finishes. Applying a ref plans it
+// first since there's no earlier plan to apply.
+func (c *DefaultCommandRunner) RunAPICommand(reqCtx RequestContext, req APIRequest) (CommandResult, error) {
+	if req.Name != models.PlanCommand && req.Name != models.ApplyCommand {
+		return CommandResult{}, fmt.Errorf("command %q is not supported by the API", req.Name.String())
+	}
+	cmds, err := c.apiCommentCommands(req)
+	if err != nil {
+		return CommandResult{}, err
+	}
+	log := c.buildLogger(reqCtx, req.Repo.FullName, req.PullNum, req.Name.String())
+	ctx := &CommandContext{
+		BaseRepo: req.Repo,
+		User:     models.User{Username: APIUsername},
+		Log:      log,
+	}
+
+	if req.PullNum == 0 {
+		if req.Ref == "" {
+			return CommandResult{}, errors.New("either a pull request number or a ref must be set")
+		}
+		if len(req.Projects) == 0 {
+			return CommandResult{}, errors.New("projects must be set when running on a ref")
+		}
+		return c.runAPICommandOnRef(ctx, req, cmds)
+	}
+
+	ctx.Pull, ctx.HeadRepo, err = c.getAPIPullData(req.Repo, req.PullNum)
+	if err != nil {
+		return CommandResult{}, err
+	}
+	if !c.AllowForkPRs && ctx.HeadRepo.Owner != ctx.BaseRepo.Owner {
+		return CommandResult{}, fmt.Errorf("commands can't be run on fork pull requests. To enable, set --%s", c.AllowForkPRsFlag)
+	}
+	if ctx.Pull.State != models.OpenPullState {
+		return CommandResult{}, errors.New("commands can't be run on closed pull requests")
+	}
+	if req.Name == models.ApplyCommand {
+		ctx.PullMergeable, err = c.VCSClient.PullIsMergeable(ctx.BaseRepo, ctx.Pull)
+		if err != nil {
+			ctx.PullMergeable = false
+			ctx.Log.Warn("unable to get mergeable status: %s. Continuing with mergeable assumed false", err)
+		}
+	}
+	if err := c.CommitStatusUpdater.UpdateCombined(ctx.BaseRepo, ctx.Pull, models.PendingCommitStatus, req.Name); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+
+	result := c.runAPICommentCommands(ctx, req.Name, cmds)
+	// The comment has the output of all the projects in the request so it
+	// only replaces the previous comment if the request was for all of them.
+	c.updatePullComment(ctx, CommentCommand{Name: req.Name}, result, len(req.Projects) == 0)
+	if result.Error != nil {
+		if statusErr := c.CommitStatusUpdater.UpdateCombined(ctx.BaseRepo, ctx.Pull, models.FailedCommitStatus, req.Name); statusErr != nil {
+			ctx.Log.Warn("unable to update commit status: %s", statusErr)
+		}
+		return result, nil
+	}
+	pullStatus, err := c.updateDB(ctx, ctx.Pull, result.ProjectResults)
+	if err != nil {
+		c.Logger.Err("writing results: %s", err)
+		return result, nil
+	}
+	c.updateCommitStatus(ctx, req.Name, pullStatus)
+	return result, nil
+}
+
+// runAPICommandOnRef runs cmds on the ref in req.
+func (c *DefaultCommandRunner) runAPICommandOnRef(ctx *CommandContext, req APIRequest, cmds []*CommentCommand) (CommandResult, error) {
+	// Every ref's locks are held by pull request number 0 so commands on
+	// refs of the same repo are run one at a time. Otherwise one would
+	// release the other's locks when it finished.
+	unlockRef, err := c.tryLockAPIRef(req.Repo.FullName)
+	if err != nil {
+		return CommandResult{}, err
+	}
+	defer unlockRef()
+
+	ctx.HeadRepo = req.Repo
+	ctx.Pull = models.PullRequest{
+		BaseRepo:   req.Repo,
+		HeadBranch: req.Ref,
+		BaseBranch: req.Ref,
+		HeadCommit: req.Ref,
+		Author:     APIUsername,
+		State:      models.OpenPullState,
+	}
+	defer func() {
+		if err := c.Locker.DequeueByPull(ctx.BaseRepo.FullName, ctx.Pull.Num); err != nil {
+			ctx.Log.Err("leaving lock queues: %s", err)
+		}
+		if _, err := c.Locker.UnlockByPull(ctx.BaseRepo.FullName, ctx.Pull.Num); err != nil {
+			ctx.Log.Err("releasing locks: %s", err)
+		}
+	}()
+
+	if req.Name == models.ApplyCommand {
+		planResult := c.runAPICommentCommands(ctx, models.PlanCommand, cmds)
+		if planResult.HasErrors() {
+			return planResult, nil
+		}
+	}
+	return c.runAPICommentCommands(ctx, req.Name, cmds), nil
+}
+
+// runAPICommentCommands builds and runs the projects selected by cmds.
+func (c *DefaultCommandRunner) runAPICommentCommands(ctx *CommandContext, name models.CommandName, cmds []*CommentCommand) CommandResult {
+	var projectCmds []models.ProjectCommandContext
+	for _, cmd := range cmds {
+		cmd.Name = name
+		var pCmds []models.ProjectCommandContext
+		var err error
+		if name == models.PlanCommand {
+			pCmds, err = c.ProjectCommandBuilder.BuildPlanCommands(ctx, cmd)
+		} else {
+			pCmds, err = c.ProjectCommandBuilder.BuildApplyCommands(ctx, cmd)
+		}
+		if err != nil {
+			return CommandResult{Error: err}
+		}
+		projectCmds = append(projectCmds, pCmds...)
+	}
+	return c.runProjectCmds(projectCmds, name)
+}
+
+// apiCommentCommands converts the projects in req into the comment commands
+// that would select them. They're validated the same as comments.
+func (c *DefaultCommandRunner) apiCommentCommands(req APIRequest) ([]*CommentCommand, error) {
+	if len(req.Projects) == 0 {
+		return []*CommentCommand{{Name: req.Name}}, nil
+	}
+	var cmds []*CommentCommand
+	for _, p := range req.Projects {
+		if p.Name != "" && (p.Dir != "" || p.Workspace != "") {
+			return nil, errors.New("projects can't set a name at the same time as a dir or workspace")
+		}
+		if p.Workspace != url.PathEscape(p.Workspace) || strings.Contains(p.Workspace, "..") {
+			return nil, fmt.Errorf("invalid workspace: %q", p.Workspace)
+		}
+		dir, err := (&CommentParser{}).validateDir(p.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dir: %q", p.Dir)
+		}
+		cmds = append(cmds, NewCommentCommand(dir, nil, req.Name, false, p.Workspace, p.Name))
+	}
+	return cmds, nil
+}
+
+// getAPIPullData gets the pull request with number pullNum. Only hosts whose
+// pull requests can be fetched by number are supported. The others only send
+// enough data to build them in webhooks.
+func (c *DefaultCommandRunner) getAPIPullData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	switch baseRepo.VCSHost.Type {
+	case models.Github:
+		return c.getGithubData(baseRepo, pullNum)
+	case models.Gitea:
+		return c.getGiteaData(baseRepo, pullNum)
+	case models.Gitlab:
+		return c.getGitlabData(baseRepo, nil, pullNum)
+	}
+	return models.PullRequest{}, models.Repo{}, fmt.Errorf("commands can't be run on %s pull requests through the API", baseRepo.VCSHost.Type.String())
+}
+
+// tryLockAPIRef locks the refs of the repo with full name repoFullName. It
+// returns a function that unlocks them or an error if they're already locked.
+func (c *DefaultCommandRunner) tryLockAPIRef(repoFullName string) (func(), error) {
+	c.apiRefMutex.Lock()
+	defer c.apiRefMutex.Unlock()
+	if c.apiRefLocks == nil {
+		c.apiRefLocks = make(map[string]bool)
+	}
+	if c.apiRefLocks[repoFullName] {
+		return nil, fmt.Errorf("another command is running on a ref of %s–wait until it's complete and try again", repoFullName)
+	}
+	c.apiRefLocks[repoFullName] = true
+	return func() {
+		c.apiRefMutex.Lock()
+		defer c.apiRefMutex.Unlock()
+		delete(c.apiRefLocks, repoFullName)
+	}, nil
+}
@@ -0,0 +1,186 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-github/github"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestRunAPICommand_InvalidRequest(t *testing.T) {
+	cases := []struct {
+		description string
+		req         events.APIRequest
+		expErr      string
+	}{
+		{
+			"unsupported command",
+			events.APIRequest{Name: models.UnlockCommand, Repo: fixtures.GithubRepo, PullNum: 1},
+			`command "unlock" is not supported by the API`,
+		},
+		{
+			"no pull or ref",
+			events.APIRequest{Name: models.PlanCommand, Repo: fixtures.GithubRepo},
+			"either a pull request number or a ref must be set",
+		},
+		{
+			"ref without projects",
+			events.APIRequest{Name: models.PlanCommand, Repo: fixtures.GithubRepo, Ref: "main"},
+			"projects must be set when running on a ref",
+		},
+		{
+			"name and dir",
+			events.APIRequest{Name: models.PlanCommand, Repo: fixtures.GithubRepo, PullNum: 1, Projects: []events.APIProject{{Name: "project", Dir: "dir"}}},
+			"projects can't set a name at the same time as a dir or workspace",
+		},
+		{
+			"relative dir",
+			events.APIRequest{Name: models.PlanCommand, Repo: fixtures.GithubRepo, PullNum: 1, Projects: []events.APIProject{{Dir: "../dir"}}},
+			`invalid dir: "../dir"`,
+		},
+		{
+			"invalid workspace",
+			events.APIRequest{Name: models.PlanCommand, Repo: fixtures.GithubRepo, PullNum: 1, Projects: []events.APIProject{{Workspace: "../ws"}}},
+			`invalid workspace: "../ws"`,
+		},
+		{
+			"unsupported vcs host",
+			events.APIRequest{Name: models.PlanCommand, Repo: models.Repo{VCSHost: models.VCSHost{Type: models.BitbucketServer}}, PullNum: 1},
+			"commands can't be run on BitbucketServer pull requests through the API",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			setup(t)
+			ch.Logger = logging.NewNoopLogger()
+			_, err := ch.RunAPICommand(events.RequestContext{}, c.req)
+			ErrEquals(t, c.expErr, err)
+			projectCommandBuilder.VerifyWasCalled(Never()).BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+		})
+	}
+}
+
+func TestRunAPICommand_Pull(t *testing.T) {
+	t.Log("running on a pull request should be the same as commenting")
+	vcsClient := setup(t)
+	ch.Logger = logging.NewNoopLogger()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+
+	pull := &github.PullRequest{State: github.String("open")}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	projCtx := models.ProjectCommandContext{RepoRelDir: "dir", Workspace: "staging"}
+	When(projectCommandBuilder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{projCtx}, nil)
+	When(projectCommandRunner.Plan(projCtx)).ThenReturn(models.ProjectResult{
+		RepoRelDir:  "dir",
+		Workspace:   "staging",
+		PlanSuccess: &models.PlanSuccess{TerraformOutput: "plan"},
+	})
+
+	res, err := ch.RunAPICommand(events.RequestContext{}, events.APIRequest{
+		Name:     models.PlanCommand,
+		Repo:     fixtures.GithubRepo,
+		PullNum:  fixtures.Pull.Num,
+		Projects: []events.APIProject{{Dir: "dir/", Workspace: "staging"}},
+	})
+	Ok(t, err)
+	Equals(t, 1, len(res.ProjectResults))
+	Equals(t, "plan", res.ProjectResults[0].PlanSuccess.TerraformOutput)
+
+	ctx, cmd := projectCommandBuilder.VerifyWasCalledOnce().BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand()).GetCapturedArguments()
+	Equals(t, events.APIUsername, ctx.User.Username)
+	Equals(t, modelPull, ctx.Pull)
+	Equals(t, "dir", cmd.RepoRelDir)
+	Equals(t, "staging", cmd.Workspace)
+	vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	status, err := boltDB.GetPullStatus(modelPull)
+	Ok(t, err)
+	Equals(t, 1, len(status.Projects))
+}
+
+func TestRunAPICommand_ClosedPull(t *testing.T) {
+	setup(t)
+	ch.Logger = logging.NewNoopLogger()
+	pull := &github.PullRequest{State: github.String("closed")}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.ClosedPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	_, err := ch.RunAPICommand(events.RequestContext{}, events.APIRequest{
+		Name:    models.PlanCommand,
+		Repo:    fixtures.GithubRepo,
+		PullNum: fixtures.Pull.Num,
+	})
+	ErrEquals(t, "commands can't be run on closed pull requests", err)
+}
+
+func TestRunAPICommand_RefApply(t *testing.T) {
+	t.Log("applying a ref should plan it first and release its locks afterwards")
+	vcsClient := setup(t)
+	ch.Logger = logging.NewNoopLogger()
+	locker := lockmocks.NewMockLocker()
+	ch.Locker = locker
+	projCtx := models.ProjectCommandContext{RepoRelDir: ".", Workspace: "default"}
+	When(projectCommandBuilder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{projCtx}, nil)
+	When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{projCtx}, nil)
+	When(projectCommandRunner.Plan(projCtx)).ThenReturn(models.ProjectResult{PlanSuccess: &models.PlanSuccess{TerraformOutput: "plan"}})
+	When(projectCommandRunner.Apply(projCtx)).ThenReturn(models.ProjectResult{ApplySuccess: "apply"})
+
+	res, err := ch.RunAPICommand(events.RequestContext{}, events.APIRequest{
+		Name:     models.ApplyCommand,
+		Repo:     fixtures.GithubRepo,
+		Ref:      "main",
+		Projects: []events.APIProject{{Dir: "."}},
+	})
+	Ok(t, err)
+	Equals(t, 1, len(res.ProjectResults))
+	Equals(t, "apply", res.ProjectResults[0].ApplySuccess)
+
+	ctx, _ := projectCommandBuilder.VerifyWasCalledOnce().BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand()).GetCapturedArguments()
+	Equals(t, 0, ctx.Pull.Num)
+	Equals(t, "main", ctx.Pull.HeadBranch)
+	Equals(t, "main", ctx.Pull.BaseBranch)
+	projectCommandRunner.VerifyWasCalledOnce().Apply(projCtx)
+	locker.VerifyWasCalledOnce().UnlockByPull(fixtures.GithubRepo.FullName, 0)
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+}
+
+func TestRunAPICommand_RefApplyPlanFails(t *testing.T) {
+	t.Log("if planning a ref fails it shouldn't be applied")
+	setup(t)
+	ch.Logger = logging.NewNoopLogger()
+	locker := lockmocks.NewMockLocker()
+	ch.Locker = locker
+	projCtx := models.ProjectCommandContext{RepoRelDir: ".", Workspace: "default"}
+	When(projectCommandBuilder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{projCtx}, nil)
+	When(projectCommandRunner.Plan(projCtx)).ThenReturn(models.ProjectResult{Error: errors.New("err")})
+
+	res, err := ch.RunAPICommand(events.RequestContext{}, events.APIRequest{
+		Name:     models.ApplyCommand,
+		Repo:     fixtures.GithubRepo,
+		Ref:      "main",
+		Projects: []events.APIProject{{Dir: "."}},
+	})
+	Ok(t, err)
+	Assert(t, res.HasErrors(), "exp errors")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+	locker.VerifyWasCalledOnce().UnlockByPull(fixtures.GithubRepo.FullName, 0)
+}
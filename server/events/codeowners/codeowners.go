@@ -0,0 +1,169 @@
+// Package codeowners parses CODEOWNERS files.
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Locations are where CODEOWNERS files are looked for, relative to the repo
+// root. GitHub, GitLab and Bitbucket each have their own directory. The first
+// file that exists is used.
+var Locations = []string{
+	".github/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+	".bitbucket/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// Rule is a line of a CODEOWNERS file.
+type Rule struct {
+	Pattern string
+	// Owners are the owners without their leading '@', ex. username or
+	// org/team. Email addresses are kept as is.
+	Owners []string
+	regex  *regexp.Regexp
+}
+
+// File is a parsed CODEOWNERS file.
+type File struct {
+	Rules []Rule
+}
+
+// Find parses the first CODEOWNERS file in Locations that exists. getFile
+// returns the contents of the file at a path relative to the repo root or
+// false if it doesn't exist. Find returns nil if the repo doesn't have a
+// CODEOWNERS file.
+func Find(getFile func(path string) (bool, []byte, error)) (*File, error) {
+	for _, loc := range Locations {
+		exists, content, err := getFile(loc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting %s", loc)
+		}
+		if !exists {
+			continue
+		}
+		file, err := Parse(bytes.NewReader(content))
+		return file, errors.Wrapf(err, "parsing %s", loc)
+	}
+	return nil, nil
+}
+
+// IsLocation returns true if path, relative to the repo root, is one of
+// Locations.
+func IsLocation(path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	for _, loc := range Locations {
+		if path == loc {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses the CODEOWNERS file in r. It only returns an error if r can't
+// be read. GitLab's section headers, ex. [Section], are skipped so the rules
+// in sections are treated like any others.
+func Parse(r io.Reader) (*File, error) {
+	file := &File{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		// Trailing comments are allowed.
+		if i := strings.Index(line, " #"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		rule := Rule{Pattern: fields[0]}
+		for _, owner := range fields[1:] {
+			rule.Owners = append(rule.Owners, strings.TrimPrefix(owner, "@"))
+		}
+		rule.regex = patternToRegex(rule.Pattern)
+		file.Rules = append(file.Rules, rule)
+	}
+	return file, scanner.Err()
+}
+
+// Owners returns the owners of the file at path, relative to the repo root.
+// Like GitHub and GitLab, the last rule that matches is used. It returns nil
+// if no rules match or the last rule that matched has no owners.
+func (f *File) Owners(path string) []string {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	for i := len(f.Rules) - 1; i >= 0; i-- {
+		if f.Rules[i].regex.MatchString(path) {
+			return f.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// AllOwners returns every owner in the file, in the order they're first
+// listed.
+func (f *File) AllOwners() []string {
+	seen := make(map[string]bool)
+	var owners []string
+	for _, rule := range f.Rules {
+		for _, owner := range rule.Owners {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return owners
+}
+
+// patternToRegex converts a CODEOWNERS pattern into a regex. Patterns use the
+// gitignore syntax: patterns that start with or contain a '/' are relative to
+// the repo root and other patterns match at any depth. A pattern that matches
+// a directory matches every file in it, except for dir/* which only matches
+// the files directly in dir.
+// Character classes, ex. [abc], aren't supported and are matched literally.
+func patternToRegex(pattern string) *regexp.Regexp {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case p[i] == '*':
+			re.WriteString("[^/]*")
+		case p[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(p[i])))
+		}
+	}
+	switch {
+	case dirOnly:
+		re.WriteString("/.*$")
+	case strings.HasSuffix(p, "/*"):
+		// Like GitHub, dir/* only matches the files directly in dir.
+		re.WriteString("$")
+	default:
+		re.WriteString("(?:/.*)?$")
+	}
+	// Everything but the wildcards was quoted so it always compiles.
+	return regexp.MustCompile(re.String())
+}
@@ -0,0 +1,92 @@
+package codeowners_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/codeowners"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const codeownersFile = `
+# Default owners.
+*                 @global-owner
+
+*.tf              @tf-owner # Trailing comment.
+/envs/            @org/envs-team
+envs/prod/        @org/prod-team lead@example.com
+modules/*         @module-owner
+**/policies/**    @security
+/docs/unowned.md
+/literal[ab].tf   @literal
+
+[GitLab Section]
+/gitlab/          @gitlab-group/subgroup
+`
+
+func TestFile_Owners(t *testing.T) {
+	file, err := codeowners.Parse(strings.NewReader(codeownersFile))
+	Ok(t, err)
+
+	cases := []struct {
+		path string
+		exp  []string
+	}{
+		{"README.md", []string{"global-owner"}},
+		{"main.tf", []string{"tf-owner"}},
+		{"nested/dir/main.tf", []string{"tf-owner"}},
+		{"envs/staging/main.tf", []string{"org/envs-team"}},
+		{"envs/prod/main.tf", []string{"org/prod-team", "lead@example.com"}},
+		{"other/envs/main.tf", []string{"tf-owner"}},
+		{"modules/vpc.tf", []string{"module-owner"}},
+		{"modules/vpc/main.tf", []string{"tf-owner"}},
+		{"policies/deny.rego", []string{"security"}},
+		{"envs/policies/deny.rego", []string{"security"}},
+		{"docs/unowned.md", nil},
+		{"literal[ab].tf", []string{"literal"}},
+		{"gitlab/main.tf", []string{"gitlab-group/subgroup"}},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			Equals(t, c.exp, file.Owners(c.path))
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	files := make(map[string]string)
+	getFile := func(path string) (bool, []byte, error) {
+		content, ok := files[path]
+		return ok, []byte(content), nil
+	}
+
+	file, err := codeowners.Find(getFile)
+	Ok(t, err)
+	Assert(t, file == nil, "exp nil when there's no CODEOWNERS file")
+
+	files["CODEOWNERS"] = "* @root"
+	files[".github/CODEOWNERS"] = "* @github"
+	file, err = codeowners.Find(getFile)
+	Ok(t, err)
+	Equals(t, []string{"github"}, file.Owners("main.tf"))
+}
+
+func TestFind_Err(t *testing.T) {
+	_, err := codeowners.Find(func(path string) (bool, []byte, error) {
+		return false, nil, errors.New("err")
+	})
+	ErrEquals(t, "getting .github/CODEOWNERS: err", err)
+}
+
+func TestFile_AllOwners(t *testing.T) {
+	file, err := codeowners.Parse(strings.NewReader("* @a @b\n*.tf @b @c\n/docs/\n"))
+	Ok(t, err)
+	Equals(t, []string{"a", "b", "c"}, file.AllOwners())
+}
+
+func TestIsLocation(t *testing.T) {
+	Equals(t, true, codeowners.IsLocation(".github/CODEOWNERS"))
+	Equals(t, true, codeowners.IsLocation("/CODEOWNERS"))
+	Equals(t, false, codeowners.IsLocation("envs/CODEOWNERS"))
+}
@@ -0,0 +1,275 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// DefaultTeamMembershipCacheTTL is how long team memberships are cached for if
+// DefaultCommandAuthorizer.TeamMembershipCacheTTL isn't set.
+const DefaultTeamMembershipCacheTTL = 5 * time.Minute
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_command_authorizer.go CommandAuthorizer
+
+// CommandAuthorizer checks if users are allowed to run comment commands.
+type CommandAuthorizer interface {
+	// Authorize returns nil if user is allowed to run cmdName on every one
+	// of projects in repo. Otherwise it returns the permission they're
+	// missing.
+	Authorize(repo models.Repo, user models.User, cmdName models.CommandName, projects []models.ProjectCommandContext) (*valid.Permission, error)
+	// AuthorizeAny returns nil if user could be allowed to run cmdName on
+	// at least one project in repo. It's for checking commands before we
+	// know which projects they'll run on. Otherwise it returns the
+	// repo-wide permission they're missing.
+	AuthorizeAny(repo models.Repo, user models.User, cmdName models.CommandName) (*valid.Permission, error)
+}
+
+// DefaultCommandAuthorizer authorizes commands using the permissions in the
+// server-side repo config.
+type DefaultCommandAuthorizer struct {
+	VCSClient    vcs.Client
+	ServerConfig valid.ServerConfig
+	// TeamMembershipCacheTTL is how long we remember if a user is a member of
+	// a team so we don't make API calls for every command.
+	TeamMembershipCacheTTL time.Duration
+
+	// cache holds team memberships. It's guarded by cacheMutex.
+	cacheMutex sync.Mutex
+	cache      map[teamMembershipKey]teamMembership
+}
+
+type teamMembershipKey struct {
+	host     models.VCSHost
+	team     string
+	username string
+}
+
+type teamMembership struct {
+	member  bool
+	expires time.Time
+}
+
+// Authorize implements CommandAuthorizer.
+// Commands without any permissions can be run by anyone. For each project,
+// if there are permissions for that project, the user needs one of them.
+// Otherwise they need one of the repo-wide permissions. Projects are matched
+// by both their name and dir so it doesn't matter how the comment targeted
+// them. Projects listed in the same permission, ex. a project's name and
+// dir, are treated as the same project.
+func (a *DefaultCommandAuthorizer) Authorize(repo models.Repo, user models.User, cmdName models.CommandName, projects []models.ProjectCommandContext) (*valid.Permission, error) {
+	repoWide, projectGroups := a.permissionGroups(repo, cmdName)
+
+	// Find the group of permissions for each project. The user needs at
+	// least one permission from each of them.
+	var groups []permissionGroup
+	checked := make(map[int]bool)
+	for _, project := range projects {
+		// -1 is the repo-wide group.
+		i := -1
+		for j, g := range projectGroups {
+			if g.appliesTo(project.GetProjectName(), project.RepoRelDir) {
+				i = j
+				break
+			}
+		}
+		if checked[i] {
+			continue
+		}
+		checked[i] = true
+		if i == -1 {
+			groups = append(groups, repoWide)
+		} else {
+			groups = append(groups, projectGroups[i])
+		}
+	}
+
+	for _, group := range groups {
+		if len(group.permissions) == 0 {
+			continue
+		}
+		allowed, err := a.hasAnyPermission(repo, user, group.permissions)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			missing := group.merge()
+			return &missing, nil
+		}
+	}
+	return nil, nil
+}
+
+// AuthorizeAny implements CommandAuthorizer.
+// Users that have any permission for cmdName, repo-wide or for a project,
+// could be allowed. So could everyone if there aren't repo-wide permissions
+// since projects without permissions can be run by anyone.
+func (a *DefaultCommandAuthorizer) AuthorizeAny(repo models.Repo, user models.User, cmdName models.CommandName) (*valid.Permission, error) {
+	repoWide, projectGroups := a.permissionGroups(repo, cmdName)
+	if len(repoWide.permissions) == 0 {
+		return nil, nil
+	}
+	all := repoWide.permissions
+	for _, g := range projectGroups {
+		all = append(all, g.permissions...)
+	}
+	allowed, err := a.hasAnyPermission(repo, user, all)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		missing := repoWide.merge()
+		return &missing, nil
+	}
+	return nil, nil
+}
+
+// permissionGroups returns the repo-wide group of permissions for cmdName
+// in repo and the groups for each of its projects.
+func (a *DefaultCommandAuthorizer) permissionGroups(repo models.Repo, cmdName models.CommandName) (permissionGroup, []permissionGroup) {
+	repoWide := permissionGroup{}
+	var projectGroups []permissionGroup
+	policy, ok := a.ServerConfig.PolicyForRepo(fmt.Sprintf("%s/%s", repo.VCSHost.Hostname, repo.FullName))
+	if !ok {
+		return repoWide, projectGroups
+	}
+	for _, p := range policy.Permissions {
+		if p.Command != cmdName.String() {
+			continue
+		}
+		if len(p.Projects) == 0 {
+			repoWide.permissions = append(repoWide.permissions, p)
+		} else {
+			projectGroups = addToProjectGroups(projectGroups, p)
+		}
+	}
+	return repoWide, projectGroups
+}
+
+// hasAnyPermission returns true if user has at least one of permissions.
+func (a *DefaultCommandAuthorizer) hasAnyPermission(repo models.Repo, user models.User, permissions []valid.Permission) (bool, error) {
+	// Check the users first since they don't need any API calls.
+	for _, p := range permissions {
+		if p.HasUser(user.Username) {
+			return true, nil
+		}
+	}
+	for _, p := range permissions {
+		for _, team := range p.Teams {
+			member, err := a.isTeamMember(repo, team, user)
+			if err != nil {
+				return false, err
+			}
+			if member {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// isTeamMember returns true if user is a member of team. It uses the cache if
+// it has an unexpired result.
+func (a *DefaultCommandAuthorizer) isTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	key := teamMembershipKey{host: repo.VCSHost, team: team, username: user.Username}
+	now := time.Now()
+	a.cacheMutex.Lock()
+	cached, ok := a.cache[key]
+	a.cacheMutex.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.member, nil
+	}
+
+	member, err := a.VCSClient.IsTeamMember(repo, team, user)
+	if err != nil {
+		return false, errors.Wrapf(err, "checking if %s is a member of %s", user.Username, team)
+	}
+	ttl := a.TeamMembershipCacheTTL
+	if ttl == 0 {
+		ttl = DefaultTeamMembershipCacheTTL
+	}
+	a.cacheMutex.Lock()
+	if a.cache == nil {
+		a.cache = make(map[teamMembershipKey]teamMembership)
+	}
+	a.cache[key] = teamMembership{member: member, expires: now.Add(ttl)}
+	a.cacheMutex.Unlock()
+	return member, nil
+}
+
+// permissionGroup is a set of permissions for the same command where having
+// any one of them is enough.
+type permissionGroup struct {
+	// projects are the names and dirs of the project the permissions are
+	// for. It's empty if they're repo-wide.
+	projects    []string
+	permissions []valid.Permission
+}
+
+// appliesTo returns true if the group is for the project named projectName
+// or the project in repoRelDir.
+func (g permissionGroup) appliesTo(projectName string, repoRelDir string) bool {
+	return valid.Permission{Projects: g.projects}.AppliesTo(projectName, repoRelDir)
+}
+
+// addToProjectGroups adds p to the group for its projects. Projects listed in
+// the same permission are treated as the same project since that's how a
+// project's name and dir are both listed, so p can join groups together.
+func addToProjectGroups(groups []permissionGroup, p valid.Permission) []permissionGroup {
+	var joined permissionGroup
+	var rest []permissionGroup
+	for _, g := range groups {
+		overlaps := false
+		for _, project := range p.Projects {
+			if g.appliesTo(project, project) {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			joined.permissions = append(joined.permissions, g.permissions...)
+		} else {
+			rest = append(rest, g)
+		}
+	}
+	joined.permissions = append(joined.permissions, p)
+	seen := make(map[string]bool)
+	for _, perm := range joined.permissions {
+		for _, project := range perm.Projects {
+			if !seen[project] {
+				seen[project] = true
+				joined.projects = append(joined.projects, project)
+			}
+		}
+	}
+	return append(rest, joined)
+}
+
+// merge combines the group's permissions into one so we can tell users
+// everyone that's allowed.
+func (g permissionGroup) merge() valid.Permission {
+	merged := valid.Permission{Command: g.permissions[0].Command}
+	merged.Projects = g.projects
+	seenUsers := make(map[string]bool)
+	seenTeams := make(map[string]bool)
+	for _, p := range g.permissions {
+		for _, u := range p.Users {
+			if !seenUsers[u] {
+				seenUsers[u] = true
+				merged.Users = append(merged.Users, u)
+			}
+		}
+		for _, t := range p.Teams {
+			if !seenTeams[t] {
+				seenTeams[t] = true
+				merged.Teams = append(merged.Teams, t)
+			}
+		}
+	}
+	return merged
+}
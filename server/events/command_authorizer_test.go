@@ -0,0 +1,310 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// project returns the context of the project named name in dir. name can be
+// empty if the project isn't configured in atlantis.yaml.
+func project(name string, dir string) models.ProjectCommandContext {
+	ctx := models.ProjectCommandContext{RepoRelDir: dir, Workspace: "default"}
+	if name != "" {
+		ctx.ProjectConfig = &valid.Project{Name: &name, Dir: dir, Workspace: "default"}
+	}
+	return ctx
+}
+
+func TestDefaultCommandAuthorizer_Authorize(t *testing.T) {
+	repoWide := valid.Permission{Command: "apply", Users: []string{"alice"}, Teams: []string{"runatlantis/admins"}}
+	prod := valid.Permission{Command: "apply", Projects: []string{"prod", "envs/prod"}, Users: []string{"bob"}}
+	prodAdmins := valid.Permission{Command: "apply", Projects: []string{"prod"}, Teams: []string{"runatlantis/prod-admins"}}
+	byName := valid.Permission{Command: "apply", Projects: []string{"by-name"}, Users: []string{"carol"}}
+	byDir := valid.Permission{Command: "apply", Projects: []string{"envs/by-dir"}, Users: []string{"carol"}}
+	cases := []struct {
+		description string
+		user        string
+		cmdName     models.CommandName
+		projects    []models.ProjectCommandContext
+		expMissing  *valid.Permission
+	}{
+		{
+			"command without permissions",
+			"dave",
+			models.PlanCommand,
+			[]models.ProjectCommandContext{project("", "staging")},
+			nil,
+		},
+		{
+			"no projects",
+			"dave",
+			models.ApplyCommand,
+			nil,
+			nil,
+		},
+		{
+			"user in repo-wide permission",
+			"alice",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("", "staging")},
+			nil,
+		},
+		{
+			"team member in repo-wide permission",
+			"admin",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("", "staging")},
+			nil,
+		},
+		{
+			"not in repo-wide permission",
+			"bob",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("", "staging")},
+			&repoWide,
+		},
+		{
+			"project permission by name",
+			"bob",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("prod", "envs/other")},
+			nil,
+		},
+		{
+			"project permission by dir",
+			"bob",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("", "envs/prod")},
+			nil,
+		},
+		{
+			"team member in one of the project's permissions",
+			"prod-admin",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("prod", "envs/prod")},
+			nil,
+		},
+		{
+			"project's name and dir are the same project",
+			"prod-admin",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("", "envs/prod")},
+			nil,
+		},
+		{
+			"project permissions replace repo-wide permissions",
+			"alice",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("prod", "envs/prod")},
+			&valid.Permission{Command: "apply", Projects: []string{"prod", "envs/prod"}, Users: []string{"bob"}, Teams: []string{"runatlantis/prod-admins"}},
+		},
+		{
+			// atlantis apply -d envs/by-name
+			"permission by name can't be bypassed by dir",
+			"alice",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("by-name", "envs/by-name")},
+			&byName,
+		},
+		{
+			// atlantis apply -p by-dir
+			"permission by dir can't be bypassed by name",
+			"alice",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("by-dir", "envs/by-dir")},
+			&byDir,
+		},
+		{
+			"every project needs its permissions",
+			"alice",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("", "staging"), project("prod", "envs/prod")},
+			&valid.Permission{Command: "apply", Projects: []string{"prod", "envs/prod"}, Users: []string{"bob"}, Teams: []string{"runatlantis/prod-admins"}},
+		},
+		{
+			"every project without permissions needs repo-wide permission",
+			"bob",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("prod", "envs/prod"), project("", "staging")},
+			&repoWide,
+		},
+		{
+			"only projects with their own permissions",
+			"bob",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("prod", "envs/prod")},
+			nil,
+		},
+		{
+			"every project with every permission",
+			"both",
+			models.ApplyCommand,
+			[]models.ProjectCommandContext{project("", "staging"), project("prod", "envs/prod")},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.IsTeamMember(matchers.AnyModelsRepo(), AnyString(), matchers.AnyModelsUser())).ThenReturn(false, nil)
+			When(vcsClient.IsTeamMember(fixtures.GithubRepo, "runatlantis/admins", models.User{Username: "admin"})).ThenReturn(true, nil)
+			When(vcsClient.IsTeamMember(fixtures.GithubRepo, "runatlantis/admins", models.User{Username: "both"})).ThenReturn(true, nil)
+			When(vcsClient.IsTeamMember(fixtures.GithubRepo, "runatlantis/prod-admins", models.User{Username: "prod-admin"})).ThenReturn(true, nil)
+			When(vcsClient.IsTeamMember(fixtures.GithubRepo, "runatlantis/prod-admins", models.User{Username: "both"})).ThenReturn(true, nil)
+			authorizer := &events.DefaultCommandAuthorizer{
+				VCSClient: vcsClient,
+				ServerConfig: valid.ServerConfig{
+					Repos: []valid.ServerRepo{
+						{ID: "github.com/runatlantis/atlantis", Permissions: []valid.Permission{repoWide, prod, prodAdmins, byName, byDir}},
+					},
+				},
+			}
+
+			missing, err := authorizer.Authorize(fixtures.GithubRepo, models.User{Username: c.user}, c.cmdName, c.projects)
+			Ok(t, err)
+			Equals(t, c.expMissing, missing)
+		})
+	}
+}
+
+func TestDefaultCommandAuthorizer_AuthorizeAny(t *testing.T) {
+	repoWide := valid.Permission{Command: "apply", Users: []string{"alice"}, Teams: []string{"runatlantis/admins"}}
+	prod := valid.Permission{Command: "apply", Projects: []string{"prod"}, Users: []string{"bob"}}
+	cases := []struct {
+		description string
+		permissions []valid.Permission
+		user        string
+		expMissing  *valid.Permission
+	}{
+		{
+			"no permissions",
+			nil,
+			"dave",
+			nil,
+		},
+		{
+			"only project permissions",
+			[]valid.Permission{prod},
+			"dave",
+			nil,
+		},
+		{
+			"user in repo-wide permission",
+			[]valid.Permission{repoWide, prod},
+			"alice",
+			nil,
+		},
+		{
+			"team member in repo-wide permission",
+			[]valid.Permission{repoWide, prod},
+			"admin",
+			nil,
+		},
+		{
+			"user in project permission",
+			[]valid.Permission{repoWide, prod},
+			"bob",
+			nil,
+		},
+		{
+			"user in neither",
+			[]valid.Permission{repoWide, prod},
+			"dave",
+			&repoWide,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.IsTeamMember(matchers.AnyModelsRepo(), AnyString(), matchers.AnyModelsUser())).ThenReturn(false, nil)
+			When(vcsClient.IsTeamMember(fixtures.GithubRepo, "runatlantis/admins", models.User{Username: "admin"})).ThenReturn(true, nil)
+			authorizer := &events.DefaultCommandAuthorizer{
+				VCSClient: vcsClient,
+				ServerConfig: valid.ServerConfig{
+					Repos: []valid.ServerRepo{
+						{ID: "github.com/runatlantis/atlantis", Permissions: c.permissions},
+					},
+				},
+			}
+
+			missing, err := authorizer.AuthorizeAny(fixtures.GithubRepo, models.User{Username: c.user}, models.ApplyCommand)
+			Ok(t, err)
+			Equals(t, c.expMissing, missing)
+		})
+	}
+}
+
+func TestDefaultCommandAuthorizer_Authorize_NoMatchingRepo(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClient()
+	authorizer := &events.DefaultCommandAuthorizer{
+		VCSClient: vcsClient,
+		ServerConfig: valid.ServerConfig{
+			Repos: []valid.ServerRepo{
+				{ID: "github.com/runatlantis/other", Permissions: []valid.Permission{{Command: "apply"}}},
+			},
+		},
+	}
+	missing, err := authorizer.Authorize(fixtures.GithubRepo, fixtures.User, models.ApplyCommand, []models.ProjectCommandContext{project("", ".")})
+	Ok(t, err)
+	Assert(t, missing == nil, "exp allowed")
+}
+
+func TestDefaultCommandAuthorizer_Authorize_CachesTeams(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClient()
+	user := models.User{Username: "admin"}
+	When(vcsClient.IsTeamMember(fixtures.GithubRepo, "runatlantis/admins", user)).ThenReturn(true, nil)
+	authorizer := &events.DefaultCommandAuthorizer{
+		VCSClient: vcsClient,
+		ServerConfig: valid.ServerConfig{
+			Repos: []valid.ServerRepo{
+				{ID: "github.com/runatlantis/atlantis", Permissions: []valid.Permission{{Command: "apply", Teams: []string{"runatlantis/admins"}}}},
+			},
+		},
+		TeamMembershipCacheTTL: 50 * time.Millisecond,
+	}
+	projects := []models.ProjectCommandContext{project("", ".")}
+
+	for i := 0; i < 2; i++ {
+		missing, err := authorizer.Authorize(fixtures.GithubRepo, user, models.ApplyCommand, projects)
+		Ok(t, err)
+		Assert(t, missing == nil, "exp allowed")
+	}
+	vcsClient.VerifyWasCalledOnce().IsTeamMember(fixtures.GithubRepo, "runatlantis/admins", user)
+
+	t.Log("after the TTL it should check again")
+	time.Sleep(60 * time.Millisecond)
+	_, err := authorizer.Authorize(fixtures.GithubRepo, user, models.ApplyCommand, projects)
+	Ok(t, err)
+	vcsClient.VerifyWasCalled(Times(2)).IsTeamMember(fixtures.GithubRepo, "runatlantis/admins", user)
+}
+
+func TestDefaultCommandAuthorizer_Authorize_TeamErr(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClient()
+	user := models.User{Username: "admin"}
+	When(vcsClient.IsTeamMember(fixtures.GithubRepo, "runatlantis/admins", user)).ThenReturn(false, errors.New("err"))
+	authorizer := &events.DefaultCommandAuthorizer{
+		VCSClient: vcsClient,
+		ServerConfig: valid.ServerConfig{
+			Repos: []valid.ServerRepo{
+				{ID: "github.com/runatlantis/atlantis", Permissions: []valid.Permission{{Command: "apply", Teams: []string{"runatlantis/admins"}}}},
+			},
+		},
+	}
+	_, err := authorizer.Authorize(fixtures.GithubRepo, user, models.ApplyCommand, []models.ProjectCommandContext{project("", ".")})
+	ErrEquals(t, "checking if admin is a member of runatlantis/admins: err", err)
+}
@@ -14,6 +14,9 @@
 package events
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
 )
@@ -38,3 +41,27 @@ type CommandContext struct {
 	// required the Atlantis status to be successful prior to merging.
 	PullMergeable bool
 }
+
+// RequestContext identifies the webhook request that a command is being run
+// for so that all the log entries for it can be found.
+type RequestContext struct {
+	// VCSRequestID is the ID the VCS host sent with the webhook, ex. the
+	// X-Github-Delivery header. It's empty for hosts that don't send one.
+	VCSRequestID string
+	// JobID is generated by Atlantis for each request. Unlike VCSRequestID,
+	// it's always set.
+	JobID string
+}
+
+// NewRequestContext returns a RequestContext for the webhook request with ID
+// vcsRequestID and a newly generated job ID.
+func NewRequestContext(vcsRequestID string) RequestContext {
+	b := make([]byte, 8)
+	// crypto/rand only errors if the OS's source of randomness is
+	// unavailable in which case the ID is still usable for correlation.
+	rand.Read(b) // nolint: errcheck
+	return RequestContext{
+		VCSRequestID: vcsRequestID,
+		JobID:        hex.EncodeToString(b),
+	}
+}
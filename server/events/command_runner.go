@@ -15,12 +15,20 @@ package events
 
 import (
 	"fmt"
+	"sync"
+
 	"github.com/google/go-github/github"
 	"github.com/lkysow/go-gitlab"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/vcs/azuredevops"
+	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
+	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/runatlantis/atlantis/server/events/vcs/gitea"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/runatlantis/atlantis/server/recovery"
 )
@@ -32,8 +40,10 @@ type CommandRunner interface {
 	// RunCommentCommand is the first step after a command request has been parsed.
 	// It handles gathering additional information needed to execute the command
 	// and then calling the appropriate services to finish executing the command.
-	RunCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand)
-	RunAutoplanCommand(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User)
+	// reqCtx is used to correlate the command's logs with the request that
+	// triggered it.
+	RunCommentCommand(reqCtx RequestContext, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand)
+	RunAutoplanCommand(reqCtx RequestContext, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User)
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_github_pull_getter.go GithubPullGetter
@@ -50,17 +60,56 @@ type GithubPullGetter interface {
 type GitlabMergeRequestGetter interface {
 	// GetMergeRequest gets the pull request with the id pullNum for the repo.
 	GetMergeRequest(repoFullName string, pullNum int) (*gitlab.MergeRequest, error)
+	// GetProject gets the project with id projectID. It's used to look up
+	// the repo a merge request's branch is from.
+	GetProject(projectID int) (*gitlab.Project, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_gitea_pull_getter.go GiteaPullGetter
+
+// GiteaPullGetter makes API calls to get pull requests.
+type GiteaPullGetter interface {
+	// GetPullRequest gets the pull request with id pullNum for the repo.
+	GetPullRequest(repo models.Repo, pullNum int) (*gitea.PullRequest, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_bitbucket_cloud_pull_getter.go BitbucketCloudPullGetter
+
+// BitbucketCloudPullGetter makes API calls to get pull requests.
+type BitbucketCloudPullGetter interface {
+	// GetPullRequest gets the pull request with id pullNum for the repo.
+	GetPullRequest(repo models.Repo, pullNum int) (*bitbucketcloud.PullRequest, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_bitbucket_server_pull_getter.go BitbucketServerPullGetter
+
+// BitbucketServerPullGetter makes API calls to get pull requests.
+type BitbucketServerPullGetter interface {
+	// GetPullRequest gets the pull request with id pullNum for the repo.
+	GetPullRequest(repo models.Repo, pullNum int) (*bitbucketserver.PullRequest, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_azure_devops_pull_getter.go AzureDevopsPullGetter
+
+// AzureDevopsPullGetter makes API calls to get pull requests.
+type AzureDevopsPullGetter interface {
+	// GetPullRequest gets the pull request with id pullNum for the repo.
+	GetPullRequest(repo models.Repo, pullNum int) (*azuredevops.PullRequest, error)
 }
 
 // DefaultCommandRunner is the first step when processing a comment command.
 type DefaultCommandRunner struct {
-	VCSClient                vcs.Client
-	GithubPullGetter         GithubPullGetter
-	GitlabMergeRequestGetter GitlabMergeRequestGetter
-	CommitStatusUpdater      CommitStatusUpdater
-	EventParser              EventParsing
-	MarkdownRenderer         *MarkdownRenderer
-	Logger                   logging.SimpleLogging
+	VCSClient                 vcs.Client
+	GithubPullGetter          GithubPullGetter
+	GitlabMergeRequestGetter  GitlabMergeRequestGetter
+	GiteaPullGetter           GiteaPullGetter
+	BitbucketCloudPullGetter  BitbucketCloudPullGetter
+	BitbucketServerPullGetter BitbucketServerPullGetter
+	AzureDevopsPullGetter     AzureDevopsPullGetter
+	CommitStatusUpdater       CommitStatusUpdater
+	EventParser               EventParsing
+	MarkdownRenderer          *MarkdownRenderer
+	Logger                    logging.SimpleLogging
 	// AllowForkPRs controls whether we operate on pull requests from forks.
 	AllowForkPRs bool
 	// AllowForkPRsFlag is the name of the flag that controls fork PR's. We use
@@ -74,12 +123,49 @@ type DefaultCommandRunner struct {
 	GlobalAutomerge   bool
 	PendingPlanFinder PendingPlanFinder
 	WorkingDir        WorkingDir
-	DB                *db.BoltDB
+	WorkingDirLocker  WorkingDirLocker
+	DB                db.Database
+	// Locker is used to release locks when running the unlock command.
+	Locker locking.Locker
+	// ParallelPoolSize is the max number of projects to run at the same time
+	// when the repo has enabled parallel plans or applies.
+	ParallelPoolSize int
+	// CommandAuthorizer checks if users are allowed to run comment commands.
+	// If it's nil, everyone is.
+	CommandAuthorizer CommandAuthorizer
+	// CommentMode controls what happens to the previous comment with the
+	// output of a command when the command is run again. It's one of
+	// NewCommentMode, EditCommentMode or HideCommentMode.
+	CommentMode string
+
+	// apiRefLocks holds the full names of the repos that API commands are
+	// running on refs of. It's guarded by apiRefMutex.
+	apiRefMutex sync.Mutex
+	apiRefLocks map[string]bool
 }
 
+const (
+	// NewCommentMode posts a new comment each time a command is run.
+	NewCommentMode = "new"
+	// EditCommentMode edits the previous comment for the command instead of
+	// posting a new one.
+	EditCommentMode = "edit"
+	// HideCommentMode posts a new comment and collapses the previous comment
+	// for the command. It's only supported on GitHub, other hosts behave as
+	// if NewCommentMode was set.
+	HideCommentMode = "hide"
+)
+
+// supersededComment replaces the comments that are no longer needed when
+// comments are edited to hold output that used to be split over more
+// comments.
+const supersededComment = "Superseded by a newer comment."
+
 // RunAutoplanCommand runs plan when a pull request is opened or updated.
-func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) {
-	log := c.buildLogger(baseRepo.FullName, pull.Num)
+// It isn't authorized since permissions are for comment commands: nobody ran
+// it and planning can't change any infrastructure.
+func (c *DefaultCommandRunner) RunAutoplanCommand(reqCtx RequestContext, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) {
+	log := c.buildLogger(reqCtx, baseRepo.FullName, pull.Num, models.PlanCommand.String())
 	defer c.logPanics(baseRepo, pull.Num, log)
 	ctx := &CommandContext{
 		User:     user,
@@ -136,8 +222,12 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 // enough data to construct the Repo model and callers might want to wait until
 // the event is further validated before making an additional (potentially
 // wasteful) call to get the necessary data.
-func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
-	log := c.buildLogger(baseRepo.FullName, pullNum)
+func (c *DefaultCommandRunner) RunCommentCommand(reqCtx RequestContext, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
+	var cmdName string
+	if cmd != nil {
+		cmdName = cmd.Name.String()
+	}
+	log := c.buildLogger(reqCtx, baseRepo.FullName, pullNum, cmdName)
 	defer c.logPanics(baseRepo, pullNum, log)
 
 	var headRepo models.Repo
@@ -151,12 +241,25 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 	case models.Github:
 		pull, headRepo, err = c.getGithubData(baseRepo, pullNum)
 	case models.Gitlab:
-		pull, err = c.getGitlabData(baseRepo, pullNum)
-	case models.BitbucketCloud, models.BitbucketServer:
-		if maybePull == nil {
-			err = errors.New("pull request should not be nil–this is a bug")
+		pull, headRepo, err = c.getGitlabData(baseRepo, maybeHeadRepo, pullNum)
+	case models.Gitea:
+		pull, headRepo, err = c.getGiteaData(baseRepo, pullNum)
+	case models.BitbucketCloud, models.BitbucketServer, models.AzureDevops:
+		// Webhooks from these hosts include the pull request. Pull requests
+		// that are handed a lock from the queue don't come from a webhook so
+		// we fetch them to plan their latest commit.
+		if maybePull != nil {
+			pull = *maybePull
+			break
+		}
+		switch baseRepo.VCSHost.Type {
+		case models.BitbucketCloud:
+			pull, headRepo, err = c.getBitbucketCloudData(baseRepo, pullNum)
+		case models.BitbucketServer:
+			pull, headRepo, err = c.getBitbucketServerData(baseRepo, pullNum)
+		case models.AzureDevops:
+			pull, headRepo, err = c.getAzureDevopsData(baseRepo, pullNum)
 		}
-		pull = *maybePull
 	default:
 		err = errors.New("Unknown VCS type–this is a bug")
 	}
@@ -178,6 +281,26 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		return
 	}
 
+	// Unlock doesn't run any Terraform commands so we handle it separately
+	// and don't touch the commit statuses.
+	if cmd.Name == models.UnlockCommand {
+		c.unlock(ctx, cmd)
+		return
+	}
+
+	// Approving policies doesn't run any Terraform commands either. It only
+	// changes the plan commit status.
+	if cmd.Name == models.ApprovePoliciesCommand {
+		c.approvePolicies(ctx, cmd)
+		return
+	}
+
+	// Building the commands clones the repo so we check the user is allowed
+	// to run the command on its target first.
+	if !c.authorizeTarget(ctx, cmd) {
+		return
+	}
+
 	if cmd.CommandName() == models.ApplyCommand {
 		// Get the mergeable status before we set any build statuses of our own.
 		// We do this here because when we set a "Pending" status, if users have
@@ -194,10 +317,6 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		ctx.Log.Info("pull request mergeable status: %t", ctx.PullMergeable)
 	}
 
-	if err = c.CommitStatusUpdater.UpdateCombined(baseRepo, pull, models.PendingCommitStatus, cmd.CommandName()); err != nil {
-		ctx.Log.Warn("unable to update commit status: %s", err)
-	}
-
 	var projectCmds []models.ProjectCommandContext
 	switch cmd.Name {
 	case models.PlanCommand:
@@ -215,6 +334,15 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		c.updatePull(ctx, cmd, CommandResult{Error: err})
 		return
 	}
+	// We authorize the built commands rather than the comment so it doesn't
+	// matter if the comment targeted a project by its name or its dir.
+	if !c.authorize(ctx, cmd, projectCmds) {
+		return
+	}
+
+	if err = c.CommitStatusUpdater.UpdateCombined(baseRepo, pull, models.PendingCommitStatus, cmd.CommandName()); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
 
 	result := c.runProjectCmds(projectCmds, cmd.Name)
 	if cmd.Name == models.PlanCommand && c.automergeEnabled(ctx, projectCmds) && result.HasErrors() {
@@ -240,15 +368,278 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 	}
 }
 
+// authorize returns true if the user is allowed to run cmd on projects. If
+// they aren't, it comments on the pull request to explain why. It always
+// posts a new comment so it doesn't replace the output of previous commands.
+func (c *DefaultCommandRunner) authorize(ctx *CommandContext, cmd *CommentCommand, projects []models.ProjectCommandContext) bool {
+	if c.CommandAuthorizer == nil {
+		return true
+	}
+	missing, err := c.CommandAuthorizer.Authorize(ctx.BaseRepo, ctx.User, cmd.Name, projects)
+	return c.checkPermission(ctx, cmd, missing, err)
+}
+
+// authorizeTarget is like authorize but it checks the project the comment
+// targets without cloning the repo. If the comment doesn't target a specific
+// project we don't know which projects it'll run on until we've cloned, so we
+// only check the user could be allowed to run it on one of them.
+func (c *DefaultCommandRunner) authorizeTarget(ctx *CommandContext, cmd *CommentCommand) bool {
+	if c.CommandAuthorizer == nil {
+		return true
+	}
+	if cmd.IsForSpecificProject() {
+		target := c.ProjectCommandBuilder.BuildCommentTarget(ctx, cmd)
+		return c.authorize(ctx, cmd, []models.ProjectCommandContext{target})
+	}
+	missing, err := c.CommandAuthorizer.AuthorizeAny(ctx.BaseRepo, ctx.User, cmd.Name)
+	return c.checkPermission(ctx, cmd, missing, err)
+}
+
+// checkPermission returns true if the permission check that returned
+// missing and err passed. Otherwise it comments on the pull request with why
+// it didn't.
+func (c *DefaultCommandRunner) checkPermission(ctx *CommandContext, cmd *CommentCommand, missing *valid.Permission, err error) bool {
+	var comment string
+	switch {
+	case err != nil:
+		err = errors.Wrap(err, "checking permissions")
+		ctx.Log.Err("%s", err)
+		comment = c.MarkdownRenderer.Render(CommandResult{Error: err}, cmd.Name, ctx.Log.History.String(), cmd.Verbose, ctx.BaseRepo.VCSHost.Type)
+	case missing != nil:
+		ctx.Log.Info("user %s is not allowed to run %s", ctx.User.Username, cmd.Name.String())
+		comment = c.MarkdownRenderer.RenderPermissionDenied(cmd.Name, ctx.User, *missing)
+	default:
+		return true
+	}
+	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, comment); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+	return false
+}
+
+// unlock releases the locks held by this pull request that match cmd and
+// deletes the plans for those locks. If cmd isn't for a specific project then
+// all the pull request's locks are released.
+func (c *DefaultCommandRunner) unlock(ctx *CommandContext, cmd *CommentCommand) {
+	if c.CommandAuthorizer != nil {
+		projects, err := c.unlockProjects(ctx, cmd)
+		if err != nil {
+			c.updatePull(ctx, cmd, CommandResult{Error: err})
+			return
+		}
+		if !c.authorize(ctx, cmd, projects) {
+			return
+		}
+	}
+
+	var locks []models.ProjectLock
+	var err error
+	if cmd.IsForSpecificProject() {
+		locks, err = c.unlockProject(ctx, cmd)
+	} else {
+		locks, err = c.unlockPull(ctx)
+	}
+	if err != nil {
+		c.updatePull(ctx, cmd, CommandResult{Error: err})
+		return
+	}
+
+	ctx.Log.Info("released %d lock(s)", len(locks))
+	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, c.MarkdownRenderer.RenderUnlock(locks)); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+}
+
+// approvePolicies approves the policy check failures of the pull request's
+// plans that match cmd so they can be applied. The plans then count as
+// successful so the plan commit status is updated.
+func (c *DefaultCommandRunner) approvePolicies(ctx *CommandContext, cmd *CommentCommand) {
+	projectCmds, err := c.ProjectCommandBuilder.BuildApprovePoliciesCommands(ctx, cmd)
+	if err != nil {
+		c.updatePull(ctx, cmd, CommandResult{Error: err})
+		return
+	}
+	if !c.authorize(ctx, cmd, projectCmds) {
+		return
+	}
+
+	result := c.runProjectCmds(projectCmds, cmd.Name)
+	c.updatePull(ctx, cmd, result)
+
+	pullStatus, err := c.updateDB(ctx, ctx.Pull, result.ProjectResults)
+	if err != nil {
+		c.Logger.Err("writing results: %s", err)
+		return
+	}
+	c.updateCommitStatus(ctx, models.PlanCommand, pullStatus)
+}
+
+// unlockProjects returns the projects whose locks cmd would release so we can
+// check the user is allowed to unlock them. Locks only know their project's
+// dir so we look up the names from the last results we stored for this pull.
+func (c *DefaultCommandRunner) unlockProjects(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	pullStatus, err := c.DB.GetPullStatus(ctx.Pull)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting pull status")
+	}
+	allLocks, err := c.Locker.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing locks")
+	}
+	repoRelDir := DefaultRepoRelDir
+	workspace := DefaultWorkspace
+	if cmd.RepoRelDir != "" {
+		repoRelDir = cmd.RepoRelDir
+	}
+	if cmd.Workspace != "" {
+		workspace = cmd.Workspace
+	}
+
+	var projects []models.ProjectCommandContext
+	for _, lock := range allLocks {
+		if lock.Pull.Num != ctx.Pull.Num || lock.Project.RepoFullName != ctx.BaseRepo.FullName {
+			continue
+		}
+		var name string
+		if pullStatus != nil {
+			for _, p := range pullStatus.Projects {
+				if p.RepoRelDir == lock.Project.Path && p.Workspace == lock.Workspace {
+					name = p.ProjectName
+					break
+				}
+			}
+		}
+		switch {
+		case cmd.ProjectName != "" && name != cmd.ProjectName:
+			continue
+		case cmd.ProjectName == "" && cmd.IsForSpecificProject() && (lock.Project.Path != repoRelDir || lock.Workspace != workspace):
+			continue
+		}
+		project := models.ProjectCommandContext{RepoRelDir: lock.Project.Path, Workspace: lock.Workspace}
+		if name != "" {
+			project.ProjectConfig = &valid.Project{Name: &name, Dir: lock.Project.Path, Workspace: lock.Workspace}
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+// unlockPull releases all the locks held by the pull request and deletes all
+// of its plans. It also removes the pull request from any lock queues.
+func (c *DefaultCommandRunner) unlockPull(ctx *CommandContext) ([]models.ProjectLock, error) {
+	if err := c.Locker.DequeueByPull(ctx.BaseRepo.FullName, ctx.Pull.Num); err != nil {
+		return nil, errors.Wrap(err, "leaving lock queues")
+	}
+	locks, err := c.Locker.UnlockByPull(ctx.BaseRepo.FullName, ctx.Pull.Num)
+	if err != nil {
+		return nil, errors.Wrap(err, "releasing locks")
+	}
+	if len(locks) == 0 {
+		return nil, nil
+	}
+
+	unlockFn, err := c.WorkingDirLocker.TryLockPull(ctx.BaseRepo.FullName, ctx.Pull.Num)
+	if err != nil {
+		ctx.Log.Err("unable to obtain working dir lock when trying to delete plans: %s", err)
+	} else {
+		c.deletePlans(ctx)
+		unlockFn()
+	}
+	if err := c.DB.DeletePullStatus(ctx.Pull); err != nil {
+		ctx.Log.Err("unable to delete pull status: %s", err)
+	}
+	return locks, nil
+}
+
+// unlockProject releases the lock held by the pull request for the project
+// specified in cmd and deletes its plan. It also removes the pull request
+// from the project's lock queue. Releasing the lock hands it to the next pull
+// request in the queue.
+func (c *DefaultCommandRunner) unlockProject(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectLock, error) {
+	repoRelDir := DefaultRepoRelDir
+	workspace := DefaultWorkspace
+	if cmd.RepoRelDir != "" {
+		repoRelDir = cmd.RepoRelDir
+	}
+	if cmd.Workspace != "" {
+		workspace = cmd.Workspace
+	}
+
+	// Projects are only known by name from the atlantis.yaml file so we look
+	// up the dir and workspace from the last results we stored for this pull.
+	if cmd.ProjectName != "" {
+		pullStatus, err := c.DB.GetPullStatus(ctx.Pull)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting pull status")
+		}
+		found := false
+		if pullStatus != nil {
+			for _, p := range pullStatus.Projects {
+				if p.ProjectName == cmd.ProjectName {
+					repoRelDir = p.RepoRelDir
+					workspace = p.Workspace
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, nil
+		}
+	}
+
+	if err := c.Locker.Dequeue(models.NewProject(ctx.BaseRepo.FullName, repoRelDir), workspace, ctx.Pull.Num); err != nil {
+		return nil, errors.Wrap(err, "leaving lock queue")
+	}
+	allLocks, err := c.Locker.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing locks")
+	}
+	var locks []models.ProjectLock
+	for key, lock := range allLocks {
+		// Only release locks held by this pull request. Other pull requests'
+		// locks can only be released through the UI.
+		if lock.Pull.Num != ctx.Pull.Num ||
+			lock.Project.RepoFullName != ctx.BaseRepo.FullName ||
+			lock.Project.Path != repoRelDir ||
+			lock.Workspace != workspace {
+			continue
+		}
+		unlocked, err := c.Locker.Unlock(key)
+		if err != nil {
+			return locks, errors.Wrapf(err, "releasing lock %q", key)
+		}
+		if unlocked == nil {
+			continue
+		}
+		locks = append(locks, *unlocked)
+
+		unlockFn, err := c.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, workspace)
+		if err != nil {
+			ctx.Log.Err("unable to obtain working dir lock when trying to delete plan: %s", err)
+		} else {
+			if err := c.WorkingDir.DeleteForWorkspace(ctx.BaseRepo, ctx.Pull, workspace); err != nil {
+				ctx.Log.Err("unable to delete workspace: %s", err)
+			}
+			unlockFn()
+		}
+		if err := c.DB.DeleteProjectStatus(ctx.Pull, workspace, repoRelDir); err != nil {
+			ctx.Log.Err("unable to delete project status: %s", err)
+		}
+	}
+	return locks, nil
+}
+
 func (c *DefaultCommandRunner) updateCommitStatus(ctx *CommandContext, cmd models.CommandName, pullStatus models.PullStatus) {
 	var numSuccess int
 	var status models.CommitStatus
 
 	if cmd == models.PlanCommand {
-		// We consider anything that isn't a plan error as a plan success.
-		// For example, if there is an apply error, that means that at least a
-		// plan was generated successfully.
-		numSuccess = len(pullStatus.Projects) - pullStatus.StatusCount(models.ErroredPlanStatus)
+		// We consider anything that isn't a plan error or an unapproved
+		// policy check failure as a plan success. For example, if there is an
+		// apply error, that means that at least a plan was generated
+		// successfully.
+		numSuccess = len(pullStatus.Projects) - pullStatus.StatusCount(models.ErroredPlanStatus) - pullStatus.StatusCount(models.PolicyCheckFailedPlanStatus)
 		status = models.SuccessCommitStatus
 		if numSuccess != len(pullStatus.Projects) {
 			status = models.FailedCommitStatus
@@ -302,20 +693,239 @@ func (c *DefaultCommandRunner) automerge(ctx *CommandContext, pullStatus models.
 }
 
 func (c *DefaultCommandRunner) runProjectCmds(cmds []models.ProjectCommandContext, cmdName models.CommandName) CommandResult {
+	if cmdName == models.ApplyCommand && hasDependencies(cmds) {
+		return c.runProjectCmdsInDependencyOrder(cmds, cmdName)
+	}
+	return c.runProjectCmdGroup(cmds, cmdName)
+}
+
+// runProjectCmdsInDependencyOrder runs each project after the projects it
+// depends on. If a project fails, the projects that depend on it aren't run
+// and get a failure result instead. The results are in the order the
+// projects were run.
+func (c *DefaultCommandRunner) runProjectCmdsInDependencyOrder(cmds []models.ProjectCommandContext, cmdName models.CommandName) CommandResult {
+	unapplied, err := c.unappliedDependencies(cmds)
+	if err != nil {
+		return CommandResult{Error: errors.Wrap(err, "checking if dependencies have been applied")}
+	}
+
+	var results []models.ProjectResult
+	// failed holds the names of the projects that failed or weren't run.
+	failed := make(map[string]bool)
+	for _, group := range dependencyGroups(cmds) {
+		var toRun []models.ProjectCommandContext
+		for _, i := range group {
+			pCmd := cmds[i]
+			var failure string
+			if dep := failedDependency(pCmd, unapplied); dep != "" {
+				pCmd.Log.Warn("not running %s in dir %q workspace %q because project %q hasn't been applied", cmdName.String(), pCmd.RepoRelDir, pCmd.Workspace, dep)
+				failure = fmt.Sprintf("Not run because project %q that this project depends on hasn't been applied.", dep)
+			} else if dep := failedDependency(pCmd, failed); dep != "" {
+				pCmd.Log.Warn("not running %s in dir %q workspace %q because project %q failed", cmdName.String(), pCmd.RepoRelDir, pCmd.Workspace, dep)
+				failure = fmt.Sprintf("Not run because project %q that this project depends on failed.", dep)
+			}
+			if failure != "" {
+				results = append(results, models.ProjectResult{
+					Command:     cmdName,
+					Failure:     failure,
+					RepoRelDir:  pCmd.RepoRelDir,
+					Workspace:   pCmd.Workspace,
+					ProjectName: pCmd.GetProjectName(),
+				})
+				if name := pCmd.GetProjectName(); name != "" {
+					failed[name] = true
+				}
+				continue
+			}
+			toRun = append(toRun, pCmd)
+		}
+
+		groupResult := c.runProjectCmdGroup(toRun, cmdName)
+		for _, res := range groupResult.ProjectResults {
+			if !res.IsSuccessful() && res.ProjectName != "" {
+				failed[res.ProjectName] = true
+			}
+		}
+		results = append(results, groupResult.ProjectResults...)
+	}
+	return CommandResult{ProjectResults: results}
+}
+
+// unappliedDependencies returns the names of the projects that cmds depend on
+// that aren't in cmds and haven't been applied in the pull request. Projects
+// without a status in the pull request have nothing to apply so they don't
+// count.
+func (c *DefaultCommandRunner) unappliedDependencies(cmds []models.ProjectCommandContext) (map[string]bool, error) {
+	inCmds := make(map[string]bool)
+	for _, cmd := range cmds {
+		if name := cmd.GetProjectName(); name != "" {
+			inCmds[name] = true
+		}
+	}
+	notInCmds := make(map[string]bool)
+	for _, cmd := range cmds {
+		if cmd.ProjectConfig == nil {
+			continue
+		}
+		for _, dep := range cmd.ProjectConfig.DependsOn {
+			if !inCmds[dep] {
+				notInCmds[dep] = true
+			}
+		}
+	}
+	unapplied := make(map[string]bool)
+	if len(notInCmds) == 0 {
+		return unapplied, nil
+	}
+
+	status, err := c.DB.GetPullStatus(cmds[0].Pull)
+	if err != nil || status == nil {
+		return unapplied, err
+	}
+	for _, p := range status.Projects {
+		if notInCmds[p.ProjectName] && p.Status != models.AppliedPlanStatus {
+			unapplied[p.ProjectName] = true
+		}
+	}
+	return unapplied, nil
+}
+
+// runProjectCmdGroup runs cmds, which must not depend on each other.
+func (c *DefaultCommandRunner) runProjectCmdGroup(cmds []models.ProjectCommandContext, cmdName models.CommandName) CommandResult {
+	if c.parallelEnabled(cmds, cmdName) {
+		return c.runProjectCmdsParallel(cmds, cmdName)
+	}
 	var results []models.ProjectResult
 	for _, pCmd := range cmds {
-		var res models.ProjectResult
-		switch cmdName {
-		case models.PlanCommand:
-			res = c.ProjectCommandRunner.Plan(pCmd)
-		case models.ApplyCommand:
-			res = c.ProjectCommandRunner.Apply(pCmd)
+		results = append(results, c.runProjectCmd(pCmd, cmdName))
+	}
+	return CommandResult{ProjectResults: results}
+}
+
+// runProjectCmdsParallel runs cmds concurrently using up to ParallelPoolSize
+// goroutines. Projects in the same workspace share a clone of the repo but
+// each one only locks its own directory in it so they can run at the same
+// time. The results are in the same order as cmds.
+func (c *DefaultCommandRunner) runProjectCmdsParallel(cmds []models.ProjectCommandContext, cmdName models.CommandName) CommandResult {
+	poolSize := c.ParallelPoolSize
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	unlockFns := make([]func(), len(cmds))
+	errs := make([]error, len(cmds))
+	if cmdName == models.PlanCommand {
+		cmds, unlockFns, errs = c.cloneForParallelPlans(cmds)
+	}
+	results := make([]models.ProjectResult, len(cmds))
+	sem := make(chan struct{}, poolSize)
+	var wg sync.WaitGroup
+	for i := range cmds {
+		if errs[i] != nil {
+			results[i] = models.ProjectResult{
+				Command:     cmdName,
+				Error:       errs[i],
+				RepoRelDir:  cmds[i].RepoRelDir,
+				Workspace:   cmds[i].Workspace,
+				ProjectName: cmds[i].GetProjectName(),
+			}
+			continue
 		}
-		results = append(results, res)
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if unlockFns[i] != nil {
+				defer unlockFns[i]()
+			}
+			results[i] = c.runProjectCmdRecoverPanics(cmds[i], cmdName)
+		}(i)
 	}
+	wg.Wait()
 	return CommandResult{ProjectResults: results}
 }
 
+// cloneForParallelPlans clones the repo once for each workspace in cmds while
+// holding the workspace's lock. Cloning can delete the workspace's files so
+// it can't happen while projects in the workspace are planning. Each
+// project's directory is locked along with the workspace and stays locked
+// after the clone so nothing can clone the workspace again until the project
+// is done. It returns cmds marked as cloned, the functions to unlock each
+// project's directory and the errors for the projects that can't be planned.
+func (c *DefaultCommandRunner) cloneForParallelPlans(cmds []models.ProjectCommandContext) ([]models.ProjectCommandContext, []func(), []error) {
+	cloned := make([]models.ProjectCommandContext, len(cmds))
+	copy(cloned, cmds)
+	unlockFns := make([]func(), len(cmds))
+	errs := make([]error, len(cmds))
+	done := make(map[string]bool)
+	for i, pCmd := range cmds {
+		if done[pCmd.Workspace] {
+			continue
+		}
+		done[pCmd.Workspace] = true
+		var inWorkspace []int
+		var paths []string
+		for j := i; j < len(cmds); j++ {
+			if cmds[j].Workspace == pCmd.Workspace {
+				inWorkspace = append(inWorkspace, j)
+				paths = append(paths, cmds[j].RepoRelDir)
+			}
+		}
+
+		unlockWorkspace, unlockPaths, err := c.WorkingDirLocker.TryLockWorkspacePaths(pCmd.BaseRepo.FullName, pCmd.Pull.Num, pCmd.Workspace, paths)
+		if err == nil {
+			_, err = c.WorkingDir.Clone(pCmd.Log, pCmd.BaseRepo, pCmd.HeadRepo, pCmd.Pull, pCmd.Workspace)
+			unlockWorkspace()
+			if err != nil {
+				for _, unlockPath := range unlockPaths {
+					unlockPath()
+				}
+			}
+		}
+		for k, j := range inWorkspace {
+			if err != nil {
+				errs[j] = err
+				continue
+			}
+			unlockFns[j] = unlockPaths[k]
+			cloned[j].Cloned = true
+		}
+	}
+	return cloned, unlockFns, errs
+}
+
+// runProjectCmdRecoverPanics runs pCmd and returns a panic as an error
+// result. Panics in the goroutines running projects in parallel aren't caught
+// by logPanics and would otherwise crash the server.
+func (c *DefaultCommandRunner) runProjectCmdRecoverPanics(pCmd models.ProjectCommandContext, cmdName models.CommandName) (res models.ProjectResult) {
+	defer func() {
+		if err := recover(); err != nil {
+			stack := recovery.Stack(3)
+			pCmd.Log.Err("PANIC: %s\n%s", err, stack)
+			res = models.ProjectResult{
+				Command:     cmdName,
+				Error:       fmt.Errorf("panic: %s", err),
+				RepoRelDir:  pCmd.RepoRelDir,
+				Workspace:   pCmd.Workspace,
+				ProjectName: pCmd.GetProjectName(),
+			}
+		}
+	}()
+	return c.runProjectCmd(pCmd, cmdName)
+}
+
+func (c *DefaultCommandRunner) runProjectCmd(pCmd models.ProjectCommandContext, cmdName models.CommandName) models.ProjectResult {
+	switch cmdName {
+	case models.PlanCommand:
+		return c.ProjectCommandRunner.Plan(pCmd)
+	case models.ApplyCommand:
+		return c.ProjectCommandRunner.Apply(pCmd)
+	case models.ApprovePoliciesCommand:
+		return c.ProjectCommandRunner.ApprovePolicies(pCmd)
+	}
+	return models.ProjectResult{}
+}
+
 func (c *DefaultCommandRunner) getGithubData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
 	if c.GithubPullGetter == nil {
 		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support GitHub")
@@ -331,21 +941,115 @@ func (c *DefaultCommandRunner) getGithubData(baseRepo models.Repo, pullNum int)
 	return pull, headRepo, nil
 }
 
-func (c *DefaultCommandRunner) getGitlabData(baseRepo models.Repo, pullNum int) (models.PullRequest, error) {
+func (c *DefaultCommandRunner) getGiteaData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	if c.GiteaPullGetter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support Gitea")
+	}
+	giteaPull, err := c.GiteaPullGetter.GetPullRequest(baseRepo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to Gitea")
+	}
+	pull, _, headRepo, err := c.EventParser.ParseGiteaPull(giteaPull)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from comment data")
+	}
+	return pull, headRepo, nil
+}
+
+// getGitlabData gets the merge request. GitLab comment events include the repo
+// the merge request's branch is from so we only look it up if maybeHeadRepo
+// is nil.
+func (c *DefaultCommandRunner) getGitlabData(baseRepo models.Repo, maybeHeadRepo *models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
 	if c.GitlabMergeRequestGetter == nil {
-		return models.PullRequest{}, errors.New("Atlantis not configured to support GitLab")
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support GitLab")
 	}
 	mr, err := c.GitlabMergeRequestGetter.GetMergeRequest(baseRepo.FullName, pullNum)
 	if err != nil {
-		return models.PullRequest{}, errors.Wrap(err, "making merge request API call to GitLab")
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making merge request API call to GitLab")
 	}
 	pull := c.EventParser.ParseGitlabMergeRequest(mr, baseRepo)
-	return pull, nil
+	if maybeHeadRepo != nil {
+		return pull, *maybeHeadRepo, nil
+	}
+	if mr.SourceProjectID == mr.TargetProjectID {
+		return pull, baseRepo, nil
+	}
+	project, err := c.GitlabMergeRequestGetter.GetProject(mr.SourceProjectID)
+	if err != nil {
+		return pull, models.Repo{}, errors.Wrap(err, "making project API call to GitLab")
+	}
+	headRepo, err := c.EventParser.ParseGitlabProject(project)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from project data")
+	}
+	return pull, headRepo, nil
+}
+
+func (c *DefaultCommandRunner) getBitbucketCloudData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	if c.BitbucketCloudPullGetter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support Bitbucket Cloud")
+	}
+	bbPull, err := c.BitbucketCloudPullGetter.GetPullRequest(baseRepo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to Bitbucket Cloud")
+	}
+	pull, _, headRepo, err := c.EventParser.ParseBitbucketCloudPull(bbPull)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from pull request data")
+	}
+	return pull, headRepo, nil
+}
+
+func (c *DefaultCommandRunner) getBitbucketServerData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	if c.BitbucketServerPullGetter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support Bitbucket Server")
+	}
+	bbPull, err := c.BitbucketServerPullGetter.GetPullRequest(baseRepo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to Bitbucket Server")
+	}
+	pull, _, headRepo, err := c.EventParser.ParseBitbucketServerPull(bbPull)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from pull request data")
+	}
+	return pull, headRepo, nil
+}
+
+func (c *DefaultCommandRunner) getAzureDevopsData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	if c.AzureDevopsPullGetter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support Azure DevOps")
+	}
+	adPull, err := c.AzureDevopsPullGetter.GetPullRequest(baseRepo, pullNum)
+	if err != nil {
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to Azure DevOps")
+	}
+	pull, _, headRepo, err := c.EventParser.ParseAzureDevopsPull(adPull)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from pull request data")
+	}
+	return pull, headRepo, nil
 }
 
-func (c *DefaultCommandRunner) buildLogger(repoFullName string, pullNum int) *logging.SimpleLogger {
+func (c *DefaultCommandRunner) buildLogger(reqCtx RequestContext, repoFullName string, pullNum int, cmdName string) *logging.SimpleLogger {
 	src := fmt.Sprintf("%s#%d", repoFullName, pullNum)
-	return c.Logger.NewLogger(src, true, c.Logger.GetLevel())
+	log := c.Logger.NewLogger(src, true, c.Logger.GetLevel())
+	if log == nil {
+		return nil
+	}
+	// We set the fields directly rather than using WithFields because the
+	// pull request comment is built from this logger's History.
+	log.Fields = logging.Fields{
+		logging.RepoKey:  repoFullName,
+		logging.PullKey:  pullNum,
+		logging.JobIDKey: reqCtx.JobID,
+	}
+	if cmdName != "" {
+		log.Fields[logging.CommandKey] = cmdName
+	}
+	if reqCtx.VCSRequestID != "" {
+		log.Fields[logging.VCSRequestIDKey] = reqCtx.VCSRequestID
+	}
+	return log
 }
 
 func (c *DefaultCommandRunner) validateCtxAndComment(ctx *CommandContext) bool {
@@ -368,16 +1072,140 @@ func (c *DefaultCommandRunner) validateCtxAndComment(ctx *CommandContext) bool {
 }
 
 func (c *DefaultCommandRunner) updatePull(ctx *CommandContext, command PullCommand, res CommandResult) {
+	specific, ok := command.(interface{ IsForSpecificProject() bool })
+	c.updatePullComment(ctx, command, res, !ok || !specific.IsForSpecificProject())
+}
+
+// updatePullComment comments on the pull request with the output of command.
+// The previous comments for the command are only edited or hidden if
+// forWholePull is true because the output of a command for specific projects
+// doesn't replace the output for the other projects.
+func (c *DefaultCommandRunner) updatePullComment(ctx *CommandContext, command PullCommand, res CommandResult, forWholePull bool) {
 	// Log if we got any errors or failures.
 	if res.Error != nil {
-		ctx.Log.Err(res.Error.Error())
+		ctx.Log.Err("%s", res.Error)
 	} else if res.Failure != "" {
-		ctx.Log.Warn(res.Failure)
+		ctx.Log.Warn("%s", res.Failure)
 	}
 
 	comment := c.MarkdownRenderer.Render(res, command.CommandName(), ctx.Log.History.String(), command.IsVerbose(), ctx.BaseRepo.VCSHost.Type)
-	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, comment); err != nil {
+	switch {
+	case c.CommentMode == EditCommentMode && forWholePull:
+		c.editComment(ctx, command.CommandName(), comment)
+	case c.CommentMode == HideCommentMode && forWholePull && ctx.BaseRepo.VCSHost.Type == models.Github:
+		c.hideAndComment(ctx, command.CommandName(), comment)
+	default:
+		if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, comment); err != nil {
+			ctx.Log.Err("unable to comment: %s", err)
+		}
+	}
+}
+
+// editComment edits the previous comments with the output of cmdName to be
+// comment. comment is split like a new comment would be and each part
+// replaces the next previous comment. Parts without a previous comment are
+// created as new comments and previous comments without a part are marked as
+// superseded. If the previous comments can't be edited, new comments are
+// created.
+func (c *DefaultCommandRunner) editComment(ctx *CommandContext, cmdName models.CommandName, comment string) {
+	prevIDs := c.prevCommentIDs(ctx, cmdName)
+	if len(prevIDs) == 0 {
+		c.createComment(ctx, cmdName, comment)
+		return
+	}
+	parts := c.VCSClient.SplitComment(ctx.BaseRepo, comment)
+	for i := 0; i < len(prevIDs) && i < len(parts); i++ {
+		if err := c.VCSClient.EditComment(ctx.BaseRepo, ctx.Pull.Num, prevIDs[i], parts[i]); err != nil {
+			// The comment may have been deleted so we fall back to creating
+			// new comments. The parts we've already edited are superseded
+			// so the output isn't shown twice.
+			ctx.Log.Warn("unable to edit comment %s, creating a new comment instead: %s", prevIDs[i], err)
+			c.supersedeComments(ctx, prevIDs[:i])
+			c.createComment(ctx, cmdName, comment)
+			return
+		}
+	}
+
+	var ids []string
+	if len(prevIDs) < len(parts) {
+		ids = append(ids, prevIDs...)
+		for _, part := range parts[len(prevIDs):] {
+			newIDs, err := c.VCSClient.CreateCommentWithIDs(ctx.BaseRepo, ctx.Pull.Num, part)
+			ids = append(ids, newIDs...)
+			if err != nil {
+				ctx.Log.Err("unable to comment: %s", err)
+				break
+			}
+		}
+	} else {
+		// The output used to be split over more comments.
+		ids = prevIDs[:len(parts)]
+		c.supersedeComments(ctx, prevIDs[len(parts):])
+	}
+	c.storeCommentIDs(ctx, cmdName, ids)
+}
+
+// createComment creates comment and stores its IDs as the comments with the
+// output of cmdName.
+func (c *DefaultCommandRunner) createComment(ctx *CommandContext, cmdName models.CommandName, comment string) {
+	ids, err := c.VCSClient.CreateCommentWithIDs(ctx.BaseRepo, ctx.Pull.Num, comment)
+	if err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+	c.storeCommentIDs(ctx, cmdName, ids)
+}
+
+// supersedeComments replaces the comments with IDs ids with a note that
+// they're superseded.
+func (c *DefaultCommandRunner) supersedeComments(ctx *CommandContext, ids []string) {
+	for _, id := range ids {
+		if err := c.VCSClient.EditComment(ctx.BaseRepo, ctx.Pull.Num, id, supersededComment); err != nil {
+			ctx.Log.Warn("unable to edit comment %s: %s", id, err)
+		}
+	}
+}
+
+// hideAndComment creates comment and hides the previous comments with the
+// output of cmdName.
+func (c *DefaultCommandRunner) hideAndComment(ctx *CommandContext, cmdName models.CommandName, comment string) {
+	prevIDs := c.prevCommentIDs(ctx, cmdName)
+	ids, err := c.VCSClient.CreateCommentWithIDs(ctx.BaseRepo, ctx.Pull.Num, comment)
+	if err != nil {
+		// We don't hide the previous comments because they'd be the only
+		// output on the pull request.
 		ctx.Log.Err("unable to comment: %s", err)
+		return
+	}
+	for _, id := range prevIDs {
+		if err := c.VCSClient.HideComment(ctx.BaseRepo, ctx.Pull.Num, id); err != nil {
+			ctx.Log.Warn("unable to hide comment %s: %s", id, err)
+		}
+	}
+	c.storeCommentIDs(ctx, cmdName, ids)
+}
+
+// prevCommentIDs returns the IDs of the comments with the last output of
+// cmdName on the pull request.
+func (c *DefaultCommandRunner) prevCommentIDs(ctx *CommandContext, cmdName models.CommandName) []string {
+	status, err := c.DB.GetPullStatus(ctx.Pull)
+	if err != nil {
+		ctx.Log.Warn("unable to get previous comments: %s", err)
+		return nil
+	}
+	if status == nil {
+		return nil
+	}
+	return status.Comments[cmdName.String()]
+}
+
+// storeCommentIDs stores ids as the comments with the last output of cmdName
+// on the pull request.
+func (c *DefaultCommandRunner) storeCommentIDs(ctx *CommandContext, cmdName models.CommandName, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	if err := c.DB.UpdatePullComments(ctx.Pull, cmdName, ids); err != nil {
+		ctx.Log.Warn("unable to store comment ids: %s", err)
 	}
 }
 
@@ -431,6 +1259,21 @@ func (c *DefaultCommandRunner) automergeEnabled(ctx *CommandContext, projectCmds
 		(len(projectCmds) > 0 && projectCmds[0].GlobalConfig != nil && projectCmds[0].GlobalConfig.Automerge)
 }
 
+// parallelEnabled returns true if the repo is configured to run cmdName for
+// its projects concurrently.
+func (c *DefaultCommandRunner) parallelEnabled(projectCmds []models.ProjectCommandContext, cmdName models.CommandName) bool {
+	if len(projectCmds) < 2 || projectCmds[0].GlobalConfig == nil {
+		return false
+	}
+	switch cmdName {
+	case models.PlanCommand:
+		return projectCmds[0].GlobalConfig.ParallelPlan
+	case models.ApplyCommand:
+		return projectCmds[0].GlobalConfig.ParallelApply
+	}
+	return false
+}
+
 // automergeComment is the comment that gets posted when Atlantis automatically
 // merges the PR.
 var automergeComment = `Automatically merging because all plans have been successfully applied.`
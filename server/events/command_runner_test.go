@@ -17,18 +17,25 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/logging"
 
 	"github.com/google/go-github/github"
+	"github.com/lkysow/go-gitlab"
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
 	"github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	logmocks "github.com/runatlantis/atlantis/server/logging/mocks"
 	. "github.com/runatlantis/atlantis/testing"
 )
@@ -38,6 +45,10 @@ var projectCommandRunner *mocks.MockProjectCommandRunner
 var eventParsing *mocks.MockEventParsing
 var githubGetter *mocks.MockGithubPullGetter
 var gitlabGetter *mocks.MockGitlabMergeRequestGetter
+var giteaGetter *mocks.MockGiteaPullGetter
+var bitbucketCloudGetter *mocks.MockBitbucketCloudPullGetter
+var bitbucketServerGetter *mocks.MockBitbucketServerPullGetter
+var azureDevopsGetter *mocks.MockAzureDevopsPullGetter
 var ch events.DefaultCommandRunner
 var pullLogger *logging.SimpleLogger
 var workingDir events.WorkingDir
@@ -50,6 +61,10 @@ func setup(t *testing.T) *vcsmocks.MockClient {
 	vcsClient := vcsmocks.NewMockClient()
 	githubGetter = mocks.NewMockGithubPullGetter()
 	gitlabGetter = mocks.NewMockGitlabMergeRequestGetter()
+	giteaGetter = mocks.NewMockGiteaPullGetter()
+	bitbucketCloudGetter = mocks.NewMockBitbucketCloudPullGetter()
+	bitbucketServerGetter = mocks.NewMockBitbucketServerPullGetter()
+	azureDevopsGetter = mocks.NewMockAzureDevopsPullGetter()
 	logger := logmocks.NewMockSimpleLogging()
 	pullLogger = logging.NewSimpleLogger("runatlantis/atlantis#1", true, logging.Info)
 	projectCommandRunner = mocks.NewMockProjectCommandRunner()
@@ -59,19 +74,24 @@ func setup(t *testing.T) *vcsmocks.MockClient {
 	When(logger.NewLogger("runatlantis/atlantis#1", true, logging.Info)).
 		ThenReturn(pullLogger)
 	ch = events.DefaultCommandRunner{
-		VCSClient:                vcsClient,
-		CommitStatusUpdater:      &events.DefaultCommitStatusUpdater{vcsClient},
-		EventParser:              eventParsing,
-		MarkdownRenderer:         &events.MarkdownRenderer{},
-		GithubPullGetter:         githubGetter,
-		GitlabMergeRequestGetter: gitlabGetter,
-		Logger:                   logger,
-		AllowForkPRs:             false,
-		AllowForkPRsFlag:         "allow-fork-prs-flag",
-		ProjectCommandBuilder:    projectCommandBuilder,
-		ProjectCommandRunner:     projectCommandRunner,
-		PendingPlanFinder:        pendingPlanFinder,
-		WorkingDir:               workingDir,
+		VCSClient:                 vcsClient,
+		CommitStatusUpdater:       &events.DefaultCommitStatusUpdater{vcsClient},
+		EventParser:               eventParsing,
+		MarkdownRenderer:          &events.MarkdownRenderer{},
+		GithubPullGetter:          githubGetter,
+		GitlabMergeRequestGetter:  gitlabGetter,
+		GiteaPullGetter:           giteaGetter,
+		BitbucketCloudPullGetter:  bitbucketCloudGetter,
+		BitbucketServerPullGetter: bitbucketServerGetter,
+		AzureDevopsPullGetter:     azureDevopsGetter,
+		Logger:                    logger,
+		AllowForkPRs:              false,
+		AllowForkPRsFlag:          "allow-fork-prs-flag",
+		ProjectCommandBuilder:     projectCommandBuilder,
+		ProjectCommandRunner:      projectCommandRunner,
+		PendingPlanFinder:         pendingPlanFinder,
+		WorkingDir:                workingDir,
+		WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
 	}
 	return vcsClient
 }
@@ -80,7 +100,7 @@ func TestRunCommentCommand_LogPanics(t *testing.T) {
 	t.Log("if there is a panic it is commented back on the pull request")
 	vcsClient := setup(t)
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenPanic("OMG PANIC!!!")
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, 1, &events.CommentCommand{Name: models.PlanCommand})
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, 1, &events.CommentCommand{Name: models.PlanCommand})
 	_, _, comment := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
 	Assert(t, strings.Contains(comment, "Error: goroutine panic"), fmt.Sprintf("comment should be about a goroutine panic but was %q", comment))
 }
@@ -89,7 +109,7 @@ func TestRunCommentCommand_NoGithubPullGetter(t *testing.T) {
 	t.Log("if DefaultCommandRunner was constructed with a nil GithubPullGetter an error should be logged")
 	setup(t)
 	ch.GithubPullGetter = nil
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, 1, nil)
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, 1, nil)
 	Equals(t, "[EROR] Atlantis not configured to support GitHub\n", pullLogger.History.String())
 }
 
@@ -97,15 +117,31 @@ func TestRunCommentCommand_NoGitlabMergeGetter(t *testing.T) {
 	t.Log("if DefaultCommandRunner was constructed with a nil GitlabMergeRequestGetter an error should be logged")
 	setup(t)
 	ch.GitlabMergeRequestGetter = nil
-	ch.RunCommentCommand(fixtures.GitlabRepo, &fixtures.GitlabRepo, nil, fixtures.User, 1, nil)
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GitlabRepo, &fixtures.GitlabRepo, nil, fixtures.User, 1, nil)
 	Equals(t, "[EROR] Atlantis not configured to support GitLab\n", pullLogger.History.String())
 }
 
+func TestRunCommentCommand_NoGiteaPullGetter(t *testing.T) {
+	t.Log("if DefaultCommandRunner was constructed with a nil GiteaPullGetter an error should be logged")
+	setup(t)
+	ch.GiteaPullGetter = nil
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GiteaRepo, &fixtures.GiteaRepo, nil, fixtures.User, 1, nil)
+	Equals(t, "[EROR] Atlantis not configured to support Gitea\n", pullLogger.History.String())
+}
+
+func TestRunCommentCommand_GiteaPullErr(t *testing.T) {
+	t.Log("if getting the gitea pull request fails an error should be logged")
+	vcsClient := setup(t)
+	When(giteaGetter.GetPullRequest(fixtures.GiteaRepo, fixtures.Pull.Num)).ThenReturn(nil, errors.New("err"))
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GiteaRepo, &fixtures.GiteaRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GiteaRepo, fixtures.Pull.Num, "`Error: making pull request API call to Gitea: err`")
+}
+
 func TestRunCommentCommand_GithubPullErr(t *testing.T) {
 	t.Log("if getting the github pull request fails an error should be logged")
 	vcsClient := setup(t)
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(nil, errors.New("err"))
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "`Error: making pull request API call to GitHub: err`")
 }
 
@@ -113,7 +149,7 @@ func TestRunCommentCommand_GitlabMergeRequestErr(t *testing.T) {
 	t.Log("if getting the gitlab merge request fails an error should be logged")
 	vcsClient := setup(t)
 	When(gitlabGetter.GetMergeRequest(fixtures.GitlabRepo.FullName, fixtures.Pull.Num)).ThenReturn(nil, errors.New("err"))
-	ch.RunCommentCommand(fixtures.GitlabRepo, &fixtures.GitlabRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GitlabRepo, &fixtures.GitlabRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GitlabRepo, fixtures.Pull.Num, "`Error: making merge request API call to GitLab: err`")
 }
 
@@ -124,7 +160,7 @@ func TestRunCommentCommand_GithubPullParseErr(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(fixtures.Pull, fixtures.GithubRepo, fixtures.GitlabRepo, errors.New("err"))
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "`Error: extracting required fields from comment data: err`")
 }
 
@@ -142,7 +178,7 @@ func TestRunCommentCommand_ForkPRDisabled(t *testing.T) {
 	headRepo.Owner = "forkrepo"
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, headRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "Atlantis commands can't be run on fork pull requests. To enable, set --"+ch.AllowForkPRsFlag)
 }
 
@@ -157,10 +193,83 @@ func TestRunCommentCommand_ClosedPull(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
 	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "Atlantis commands can't be run on closed pull requests")
 }
 
+func TestRunCommentCommand_BitbucketCloudPullNil(t *testing.T) {
+	t.Log("if the pull request isn't from a webhook it should be fetched" +
+		" from Bitbucket Cloud")
+	vcsClient := setup(t)
+	repo := models.Repo{
+		FullName: "owner/repo",
+		VCSHost: models.VCSHost{
+			Hostname: "bitbucket.org",
+			Type:     models.BitbucketCloud,
+		},
+	}
+	bbPull := &bitbucketcloud.PullRequest{}
+	modelPull := models.PullRequest{Num: fixtures.Pull.Num, State: models.ClosedPullState}
+	When(bitbucketCloudGetter.GetPullRequest(repo, fixtures.Pull.Num)).ThenReturn(bbPull, nil)
+	When(eventParsing.ParseBitbucketCloudPull(bbPull)).ThenReturn(modelPull, repo, repo, nil)
+
+	ch.RunCommentCommand(events.RequestContext{}, repo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
+	vcsClient.VerifyWasCalledOnce().CreateComment(repo, fixtures.Pull.Num, "Atlantis commands can't be run on closed pull requests")
+}
+
+func TestRunCommentCommand_AzureDevopsPullErr(t *testing.T) {
+	t.Log("if getting the azure devops pull request fails an error should be logged")
+	vcsClient := setup(t)
+	repo := models.Repo{
+		FullName: "org/project/repo",
+		VCSHost: models.VCSHost{
+			Hostname: "dev.azure.com",
+			Type:     models.AzureDevops,
+		},
+	}
+	When(azureDevopsGetter.GetPullRequest(repo, fixtures.Pull.Num)).ThenReturn(nil, errors.New("err"))
+	ch.RunCommentCommand(events.RequestContext{}, repo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
+	vcsClient.VerifyWasCalledOnce().CreateComment(repo, fixtures.Pull.Num, "`Error: making pull request API call to Azure DevOps: err`")
+}
+
+func TestRunCommentCommand_GitlabForkHeadRepo(t *testing.T) {
+	t.Log("if the head repo isn't known and the merge request is from a fork" +
+		" the fork should be fetched from GitLab")
+	vcsClient := setup(t)
+	mr := &gitlab.MergeRequest{SourceProjectID: 2, TargetProjectID: 1}
+	project := &gitlab.Project{}
+	forkRepo := models.Repo{FullName: "fork/atlantis", Owner: "fork"}
+	modelPull := models.PullRequest{Num: fixtures.Pull.Num, State: models.OpenPullState}
+	When(gitlabGetter.GetMergeRequest(fixtures.GitlabRepo.FullName, fixtures.Pull.Num)).ThenReturn(mr, nil)
+	When(eventParsing.ParseGitlabMergeRequest(mr, fixtures.GitlabRepo)).ThenReturn(modelPull)
+	When(gitlabGetter.GetProject(2)).ThenReturn(project, nil)
+	When(eventParsing.ParseGitlabProject(project)).ThenReturn(forkRepo, nil)
+
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GitlabRepo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
+	gitlabGetter.VerifyWasCalledOnce().GetProject(2)
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GitlabRepo, fixtures.Pull.Num, "Atlantis commands can't be run on fork pull requests. To enable, set --allow-fork-prs-flag")
+}
+
+func TestRunCommentCommand_LogFields(t *testing.T) {
+	setup(t)
+	pull := &github.PullRequest{
+		State: github.String("closed"),
+	}
+	modelPull := models.PullRequest{State: models.ClosedPullState}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	reqCtx := events.RequestContext{VCSRequestID: "delivery-id", JobID: "job-id"}
+	ch.RunCommentCommand(reqCtx, fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand})
+	Equals(t, logging.Fields{
+		logging.RepoKey:         fixtures.GithubRepo.FullName,
+		logging.PullKey:         fixtures.Pull.Num,
+		logging.CommandKey:      "plan",
+		logging.VCSRequestIDKey: "delivery-id",
+		logging.JobIDKey:        "job-id",
+	}, pullLogger.Fields)
+}
+
 // Test that if one plan fails and we are using automerge, that
 // we delete the plans.
 func TestRunAutoplanCommand_DeletePlans(t *testing.T) {
@@ -196,6 +305,847 @@ func TestRunAutoplanCommand_DeletePlans(t *testing.T) {
 
 	When(workingDir.GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).
 		ThenReturn(tmp, nil)
-	ch.RunAutoplanCommand(fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
 	pendingPlanFinder.VerifyWasCalledOnce().DeletePlans(tmp)
 }
+
+func TestRunAutoplanCommand_EditCommentMode(t *testing.T) {
+	t.Log("in edit mode the previous comment should be edited instead of creating a new one")
+	vcsClient := setup(t)
+	ch.CommentMode = events.EditCommentMode
+	defer func() { ch.CommentMode = "" }()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+		ThenReturn(nil, errors.New("err"))
+	When(vcsClient.CreateCommentWithIDs(matchers.AnyModelsRepo(), AnyInt(), AnyString())).
+		ThenReturn([]string{"1", "2"}, nil)
+	When(vcsClient.SplitComment(matchers.AnyModelsRepo(), AnyString())).Then(func(params []Param) ReturnValues {
+		return []ReturnValue{[]string{params[1].(string)}}
+	})
+
+	t.Log("with no previous comment, a new comment is created")
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	_, _, comment := vcsClient.VerifyWasCalledOnce().CreateCommentWithIDs(matchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
+	status, err := boltDB.GetPullStatus(fixtures.Pull)
+	Ok(t, err)
+	Equals(t, map[string][]string{"plan": {"1", "2"}}, status.Comments)
+
+	t.Log("the next time, the first comment is edited and the rest are marked as superseded")
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	vcsClient.VerifyWasCalledOnce().EditComment(fixtures.GithubRepo, fixtures.Pull.Num, "1", comment)
+	vcsClient.VerifyWasCalledOnce().EditComment(fixtures.GithubRepo, fixtures.Pull.Num, "2", "Superseded by a newer comment.")
+	vcsClient.VerifyWasCalledOnce().CreateCommentWithIDs(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	status, err = boltDB.GetPullStatus(fixtures.Pull)
+	Ok(t, err)
+	Equals(t, map[string][]string{"plan": {"1"}}, status.Comments)
+}
+
+func TestRunAutoplanCommand_EditCommentModeLongerOutput(t *testing.T) {
+	t.Log("in edit mode output that's split over more comments than before should edit each previous comment in order and create the rest")
+	vcsClient := setup(t)
+	ch.CommentMode = events.EditCommentMode
+	defer func() { ch.CommentMode = "" }()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+	Ok(t, boltDB.UpdatePullComments(fixtures.Pull, models.PlanCommand, []string{"1", "2"}))
+	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+		ThenReturn(nil, errors.New("err"))
+	When(vcsClient.SplitComment(matchers.AnyModelsRepo(), AnyString())).
+		ThenReturn([]string{"part1", "part2", "part3"})
+	When(vcsClient.CreateCommentWithIDs(fixtures.GithubRepo, fixtures.Pull.Num, "part3")).
+		ThenReturn([]string{"3"}, nil)
+
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	vcsClient.VerifyWasCalledOnce().EditComment(fixtures.GithubRepo, fixtures.Pull.Num, "1", "part1")
+	vcsClient.VerifyWasCalledOnce().EditComment(fixtures.GithubRepo, fixtures.Pull.Num, "2", "part2")
+	vcsClient.VerifyWasCalledOnce().CreateCommentWithIDs(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	status, err := boltDB.GetPullStatus(fixtures.Pull)
+	Ok(t, err)
+	Equals(t, map[string][]string{"plan": {"1", "2", "3"}}, status.Comments)
+}
+
+func TestRunAutoplanCommand_EditCommentModeEditFails(t *testing.T) {
+	t.Log("in edit mode if the previous comment can't be edited a new comment should be created")
+	vcsClient := setup(t)
+	ch.CommentMode = events.EditCommentMode
+	defer func() { ch.CommentMode = "" }()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+	Ok(t, boltDB.UpdatePullComments(fixtures.Pull, models.PlanCommand, []string{"1", "2"}))
+	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+		ThenReturn(nil, errors.New("err"))
+	When(vcsClient.SplitComment(matchers.AnyModelsRepo(), AnyString())).
+		ThenReturn([]string{"part1", "part2"})
+	When(vcsClient.EditComment(fixtures.GithubRepo, fixtures.Pull.Num, "2", "part2")).
+		ThenReturn(errors.New("not found"))
+	When(vcsClient.CreateCommentWithIDs(matchers.AnyModelsRepo(), AnyInt(), AnyString())).
+		ThenReturn([]string{"3", "4"}, nil)
+
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	t.Log("the comment that was already edited should be superseded")
+	vcsClient.VerifyWasCalledOnce().EditComment(fixtures.GithubRepo, fixtures.Pull.Num, "1", "Superseded by a newer comment.")
+	vcsClient.VerifyWasCalledOnce().CreateCommentWithIDs(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	status, err := boltDB.GetPullStatus(fixtures.Pull)
+	Ok(t, err)
+	Equals(t, map[string][]string{"plan": {"3", "4"}}, status.Comments)
+}
+
+func TestRunCommentCommand_EditCommentModeSpecificProject(t *testing.T) {
+	t.Log("in edit mode the output of a command for specific projects shouldn't replace the previous comment")
+	vcsClient := setup(t)
+	ch.CommentMode = events.EditCommentMode
+	defer func() { ch.CommentMode = "" }()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+	Ok(t, boltDB.UpdatePullComments(fixtures.Pull, models.PlanCommand, []string{"1"}))
+	pull := &github.PullRequest{State: github.String("open")}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(fixtures.Pull, fixtures.GithubRepo, fixtures.GithubRepo, nil)
+	When(projectCommandBuilder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn(nil, errors.New("err"))
+
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "b"})
+	vcsClient.VerifyWasCalled(Never()).EditComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+	vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	status, err := boltDB.GetPullStatus(fixtures.Pull)
+	Ok(t, err)
+	Equals(t, map[string][]string{"plan": {"1"}}, status.Comments)
+}
+
+func TestRunAutoplanCommand_HideCommentMode(t *testing.T) {
+	t.Log("in hide mode a new comment should be created and the previous one hidden")
+	vcsClient := setup(t)
+	ch.CommentMode = events.HideCommentMode
+	defer func() { ch.CommentMode = "" }()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+	Ok(t, boltDB.UpdatePullComments(fixtures.Pull, models.PlanCommand, []string{"1"}))
+	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+		ThenReturn(nil, errors.New("err"))
+	When(vcsClient.CreateCommentWithIDs(matchers.AnyModelsRepo(), AnyInt(), AnyString())).
+		ThenReturn([]string{"2"}, nil)
+
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	vcsClient.VerifyWasCalledOnce().CreateCommentWithIDs(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	vcsClient.VerifyWasCalledOnce().HideComment(fixtures.GithubRepo, fixtures.Pull.Num, "1")
+	status, err := boltDB.GetPullStatus(fixtures.Pull)
+	Ok(t, err)
+	Equals(t, map[string][]string{"plan": {"2"}}, status.Comments)
+}
+
+func TestRunAutoplanCommand_HideCommentModeNotGithub(t *testing.T) {
+	t.Log("in hide mode, VCS hosts other than GitHub should get a new comment")
+	vcsClient := setup(t)
+	ch.CommentMode = events.HideCommentMode
+	defer func() { ch.CommentMode = "" }()
+	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+		ThenReturn(nil, errors.New("err"))
+
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GitlabRepo, fixtures.GitlabRepo, fixtures.Pull, fixtures.User)
+	vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	vcsClient.VerifyWasCalled(Never()).HideComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+}
+
+func TestRunCommentCommand_UnlockAll(t *testing.T) {
+	t.Log("atlantis unlock should release all the pull request's locks and comment")
+	vcsClient := setup(t)
+	locker := lockmocks.NewMockLocker()
+	ch.Locker = locker
+	ch.WorkingDirLocker = events.NewDefaultWorkingDirLocker()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+
+	pull := &github.PullRequest{State: github.String("open")}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(locker.UnlockByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)).ThenReturn([]models.ProjectLock{
+		{Project: models.NewProject(fixtures.GithubRepo.FullName, "path2"), Workspace: "default"},
+		{Project: models.NewProject(fixtures.GithubRepo.FullName, "path1"), Workspace: "staging"},
+	}, nil)
+	When(workingDir.GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn(tmp, nil)
+
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand})
+	pendingPlanFinder.VerifyWasCalledOnce().DeletePlans(tmp)
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num,
+		"Released the following locks and deleted their plans:\n\n- dir: `path1` workspace: `staging`\n- dir: `path2` workspace: `default`\n\nTo plan again, comment `atlantis plan`.")
+}
+
+func TestRunCommentCommand_UnlockProject(t *testing.T) {
+	t.Log("atlantis unlock -d should only release that project's lock if held by this pull request")
+	vcsClient := setup(t)
+	locker := lockmocks.NewMockLocker()
+	ch.Locker = locker
+	ch.WorkingDirLocker = events.NewDefaultWorkingDirLocker()
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+
+	pull := &github.PullRequest{State: github.String("open")}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	ownLock := models.ProjectLock{
+		Project:   models.NewProject(fixtures.GithubRepo.FullName, "path"),
+		Workspace: "default",
+		Pull:      modelPull,
+	}
+	otherPull := modelPull
+	otherPull.Num = 2
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+		"runatlantis/atlantis/path/default": ownLock,
+		"runatlantis/atlantis/other/default": {
+			Project:   models.NewProject(fixtures.GithubRepo.FullName, "other"),
+			Workspace: "default",
+			Pull:      otherPull,
+		},
+	}, nil)
+	When(locker.Unlock("runatlantis/atlantis/path/default")).ThenReturn(&ownLock, nil)
+
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand, RepoRelDir: "path"})
+	locker.VerifyWasCalledOnce().Dequeue(models.NewProject(fixtures.GithubRepo.FullName, "path"), "default", fixtures.Pull.Num)
+	locker.VerifyWasCalledOnce().Unlock("runatlantis/atlantis/path/default")
+	locker.VerifyWasCalled(Never()).Unlock("runatlantis/atlantis/other/default")
+	workingDir.(*mocks.MockWorkingDir).VerifyWasCalledOnce().DeleteForWorkspace(fixtures.GithubRepo, modelPull, "default")
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num,
+		"Released the following locks and deleted their plans:\n\n- dir: `path` workspace: `default`\n\nTo plan again, comment `atlantis plan`.")
+}
+
+func TestRunCommentCommand_UnlockPermissionDenied(t *testing.T) {
+	t.Log("atlantis unlock -d shouldn't release the lock of a project the user isn't allowed to unlock by name")
+	vcsClient := setup(t)
+	locker := lockmocks.NewMockLocker()
+	ch.Locker = locker
+	ch.WorkingDirLocker = events.NewDefaultWorkingDirLocker()
+	ch.CommandAuthorizer = &events.DefaultCommandAuthorizer{
+		VCSClient: vcsClient,
+		ServerConfig: valid.ServerConfig{
+			Repos: []valid.ServerRepo{
+				{ID: "github.com/runatlantis/atlantis", Permissions: []valid.Permission{
+					{Command: "unlock", Projects: []string{"prod"}, Users: []string{"alice"}},
+				}},
+			},
+		},
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+
+	pull := &github.PullRequest{State: github.String("open")}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	_, err = boltDB.UpdatePullWithResults(modelPull, []models.ProjectResult{
+		{RepoRelDir: "envs/prod", Workspace: "default", ProjectName: "prod", PlanSuccess: &models.PlanSuccess{}},
+	})
+	Ok(t, err)
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+		"runatlantis/atlantis/envs/prod/default": {
+			Project:   models.NewProject(fixtures.GithubRepo.FullName, "envs/prod"),
+			Workspace: "default",
+			Pull:      modelPull,
+		},
+	}, nil)
+
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand, RepoRelDir: "envs/prod"})
+	locker.VerifyWasCalled(Never()).Unlock(AnyString())
+	_, _, comment := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
+	Assert(t, strings.HasPrefix(comment, "**Unlock Denied**"), "exp permission denied comment but got %q", comment)
+}
+
+func TestRunCommentCommand_ApprovePolicies(t *testing.T) {
+	t.Log("atlantis approve_policies should approve the failures and update the plan status")
+	vcsClient := setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+
+	pull := &github.PullRequest{State: github.String("open")}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	_, err = boltDB.UpdatePullWithResults(modelPull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{PolicyCheckFailures: []string{"security: denied"}},
+		},
+	})
+	Ok(t, err)
+	cmd := &events.CommentCommand{Name: models.ApprovePoliciesCommand}
+	projCtx := models.ProjectCommandContext{RepoRelDir: ".", Workspace: "default"}
+	When(projectCommandBuilder.BuildApprovePoliciesCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{projCtx}, nil)
+	When(projectCommandRunner.ApprovePolicies(projCtx)).ThenReturn(models.ProjectResult{
+		Command:                models.ApprovePoliciesCommand,
+		RepoRelDir:             ".",
+		Workspace:              "default",
+		ApprovePoliciesSuccess: "approved",
+	})
+
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, cmd)
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num,
+		"Ran Approve Policies for dir: `.` workspace: `default`\n\napproved\n\n")
+	vcsClient.VerifyWasCalledOnce().UpdateStatus(fixtures.GithubRepo, modelPull, models.SuccessCommitStatus, "atlantis/plan", "1/1 projects planned successfully.", "")
+	status, err := boltDB.GetPullStatus(modelPull)
+	Ok(t, err)
+	Equals(t, models.PlannedPlanStatus, status.Projects[0].Status)
+}
+
+func TestRunAutoplanCommand_Parallel(t *testing.T) {
+	cases := []struct {
+		description    string
+		parallelPlan   bool
+		expConcurrency int32
+	}{
+		{
+			description:    "parallel plan disabled",
+			parallelPlan:   false,
+			expConcurrency: 1,
+		},
+		{
+			description:    "parallel plan enabled",
+			parallelPlan:   true,
+			expConcurrency: 2,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			setup(t)
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			boltDB, err := db.New(tmp)
+			Ok(t, err)
+			ch.DB = boltDB
+			ch.ParallelPoolSize = 2
+			runner := &concurrencyRecordingRunner{}
+			ch.ProjectCommandRunner = runner
+
+			globalCfg := &valid.Config{ParallelPlan: c.parallelPlan}
+			var projectCmds []models.ProjectCommandContext
+			for _, p := range []struct{ dir, workspace string }{
+				{"a", "one"},
+				{"b", "two"},
+				{"c", "one"},
+				{"d", "three"},
+				{"e", "two"},
+			} {
+				projectCmds = append(projectCmds, models.ProjectCommandContext{
+					GlobalConfig: globalCfg,
+					RepoRelDir:   p.dir,
+					Workspace:    p.workspace,
+				})
+			}
+			When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+				ThenReturn(projectCmds, nil)
+
+			ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+
+			Equals(t, c.expConcurrency, runner.maxActive)
+			t.Log("results should be in the same order as the projects")
+			status, err := boltDB.GetPullStatus(fixtures.Pull)
+			Ok(t, err)
+			var dirs []string
+			for _, p := range status.Projects {
+				dirs = append(dirs, p.RepoRelDir)
+			}
+			Equals(t, []string{"a", "b", "c", "d", "e"}, dirs)
+		})
+	}
+}
+
+func TestRunAutoplanCommand_ParallelClonesOnce(t *testing.T) {
+	t.Log("projects planned in parallel should share one clone of their workspace made before they run")
+	setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+	ch.ParallelPoolSize = 2
+
+	t.Log("a workspace that's locked by another command can't be cloned")
+	unlockTwo, err := ch.WorkingDirLocker.TryLock(fixtures.GithubRepo.FullName, fixtures.Pull.Num, "two")
+	Ok(t, err)
+	defer unlockTwo()
+
+	globalCfg := &valid.Config{ParallelPlan: true}
+	var projectCmds []models.ProjectCommandContext
+	for _, p := range []struct{ dir, workspace string }{
+		{"a", "one"},
+		{"b", "two"},
+		{"c", "one"},
+	} {
+		projectCmds = append(projectCmds, models.ProjectCommandContext{
+			BaseRepo:     fixtures.GithubRepo,
+			HeadRepo:     fixtures.GithubRepo,
+			Pull:         fixtures.Pull,
+			GlobalConfig: globalCfg,
+			RepoRelDir:   p.dir,
+			Workspace:    p.workspace,
+		})
+	}
+	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+		ThenReturn(projectCmds, nil)
+
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+
+	workingDir.(*mocks.MockWorkingDir).VerifyWasCalledOnce().Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), EqString("one"))
+	workingDir.(*mocks.MockWorkingDir).VerifyWasCalled(Never()).Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), EqString("two"))
+	planned := projectCommandRunner.VerifyWasCalled(Times(2)).Plan(matchers.AnyModelsProjectCommandContext()).GetAllCapturedArguments()
+	for _, ctx := range planned {
+		Equals(t, "one", ctx.Workspace)
+		Assert(t, ctx.Cloned, "exp project %q to be marked as cloned", ctx.RepoRelDir)
+	}
+	status, err := boltDB.GetPullStatus(fixtures.Pull)
+	Ok(t, err)
+	Equals(t, "b", status.Projects[1].RepoRelDir)
+	Equals(t, models.ErroredPlanStatus, status.Projects[1].Status)
+
+	t.Log("the projects' directories should be unlocked when they're done")
+	unlockOne, err := ch.WorkingDirLocker.TryLock(fixtures.GithubRepo.FullName, fixtures.Pull.Num, "one")
+	Ok(t, err)
+	unlockOne()
+}
+
+// Projects in the same workspace only lock their own directory so they
+// should run at the same time.
+func TestRunAutoplanCommand_ParallelSameWorkspace(t *testing.T) {
+	setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ch.DB = boltDB
+	ch.ParallelPoolSize = 2
+	runner := &concurrencyRecordingRunner{}
+	ch.ProjectCommandRunner = runner
+
+	globalCfg := &valid.Config{ParallelPlan: true}
+	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+		ThenReturn([]models.ProjectCommandContext{
+			{GlobalConfig: globalCfg, RepoRelDir: "a", Workspace: "default"},
+			{GlobalConfig: globalCfg, RepoRelDir: "b", Workspace: "default"},
+		}, nil)
+
+	ch.RunAutoplanCommand(events.RequestContext{}, fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+
+	Equals(t, int32(2), runner.maxActive)
+	Assert(t, runner.sameWorkspaceOverlapped, "exp projects in the same workspace to run at the same time")
+}
+
+// concurrencyRecordingRunner is a ProjectCommandRunner that records how many
+// projects it was running at the same time.
+type concurrencyRecordingRunner struct {
+	mutex                   sync.Mutex
+	active                  int32
+	maxActive               int32
+	activeWorkspaces        map[string]bool
+	sameWorkspaceOverlapped bool
+}
+
+func (r *concurrencyRecordingRunner) Plan(ctx models.ProjectCommandContext) models.ProjectResult {
+	r.mutex.Lock()
+	if r.activeWorkspaces == nil {
+		r.activeWorkspaces = make(map[string]bool)
+	}
+	if r.activeWorkspaces[ctx.Workspace] {
+		r.sameWorkspaceOverlapped = true
+	}
+	r.activeWorkspaces[ctx.Workspace] = true
+	r.active++
+	if r.active > r.maxActive {
+		r.maxActive = r.active
+	}
+	r.mutex.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	r.mutex.Lock()
+	r.active--
+	r.activeWorkspaces[ctx.Workspace] = false
+	r.mutex.Unlock()
+	return models.ProjectResult{
+		Command:     models.PlanCommand,
+		RepoRelDir:  ctx.RepoRelDir,
+		Workspace:   ctx.Workspace,
+		PlanSuccess: &models.PlanSuccess{},
+	}
+}
+
+func (r *concurrencyRecordingRunner) Apply(ctx models.ProjectCommandContext) models.ProjectResult {
+	return models.ProjectResult{}
+}
+
+func (r *concurrencyRecordingRunner) ApprovePolicies(ctx models.ProjectCommandContext) models.ProjectResult {
+	return models.ProjectResult{}
+}
+
+func (r *concurrencyRecordingRunner) PlanDrift(ctx models.ProjectCommandContext) models.ProjectResult {
+	return models.ProjectResult{}
+}
+
+func TestRunCommentCommand_ApplyDependencyOrder(t *testing.T) {
+	cases := []struct {
+		description string
+		failing     string
+		expApplied  []string
+		expStatuses map[string]models.ProjectPlanStatus
+	}{
+		{
+			description: "projects are applied after their dependencies",
+			expApplied:  []string{"network", "other", "cluster", "dns", "app"},
+			expStatuses: map[string]models.ProjectPlanStatus{
+				"network": models.AppliedPlanStatus,
+				"other":   models.AppliedPlanStatus,
+				"cluster": models.AppliedPlanStatus,
+				"dns":     models.AppliedPlanStatus,
+				"app":     models.AppliedPlanStatus,
+			},
+		},
+		{
+			description: "dependents of a failed project aren't applied",
+			failing:     "network",
+			expApplied:  []string{"network", "other", "dns"},
+			expStatuses: map[string]models.ProjectPlanStatus{
+				"network": models.ErroredApplyStatus,
+				"other":   models.AppliedPlanStatus,
+				"cluster": models.ErroredApplyStatus,
+				"dns":     models.AppliedPlanStatus,
+				"app":     models.ErroredApplyStatus,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			setup(t)
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			boltDB, err := db.New(tmp)
+			Ok(t, err)
+			ch.DB = boltDB
+			runner := &orderRecordingRunner{failing: c.failing}
+			ch.ProjectCommandRunner = runner
+
+			pull := &github.PullRequest{State: github.String("open")}
+			modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+			When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+			When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+			var projectCmds []models.ProjectCommandContext
+			for _, p := range []struct {
+				name      string
+				dependsOn []string
+			}{
+				{"app", []string{"cluster", "network"}},
+				{"cluster", []string{"network"}},
+				{"dns", []string{"other"}},
+				{"network", nil},
+				{"other", nil},
+			} {
+				name := p.name
+				projectCmds = append(projectCmds, models.ProjectCommandContext{
+					GlobalConfig: &valid.Config{},
+					ProjectConfig: &valid.Project{
+						Name:      &name,
+						Dir:       name,
+						Workspace: "default",
+						DependsOn: p.dependsOn,
+					},
+					RepoRelDir: name,
+					Workspace:  "default",
+				})
+			}
+			When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+				ThenReturn(projectCmds, nil)
+
+			ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+
+			Equals(t, c.expApplied, runner.applied)
+			status, err := boltDB.GetPullStatus(modelPull)
+			Ok(t, err)
+			statuses := make(map[string]models.ProjectPlanStatus)
+			for _, p := range status.Projects {
+				statuses[p.ProjectName] = p.Status
+			}
+			Equals(t, c.expStatuses, statuses)
+		})
+	}
+}
+
+// orderRecordingRunner is a ProjectCommandRunner that records the order
+// projects were applied in. It fails to apply the project named failing.
+func TestRunCommentCommand_ApplyDependencyNotInCommand(t *testing.T) {
+	t.Log("applying a single project should only run if the projects it depends on have been applied")
+	cases := []struct {
+		description   string
+		networkResult *models.ProjectResult
+		expApplied    []string
+		expStatus     models.ProjectPlanStatus
+	}{
+		{
+			description:   "dependency planned",
+			networkResult: &models.ProjectResult{Command: models.PlanCommand, PlanSuccess: &models.PlanSuccess{}},
+			expApplied:    nil,
+			expStatus:     models.ErroredApplyStatus,
+		},
+		{
+			description:   "dependency failed to apply",
+			networkResult: &models.ProjectResult{Command: models.ApplyCommand, Error: errors.New("err")},
+			expApplied:    nil,
+			expStatus:     models.ErroredApplyStatus,
+		},
+		{
+			description:   "dependency applied",
+			networkResult: &models.ProjectResult{Command: models.ApplyCommand, ApplySuccess: "success"},
+			expApplied:    []string{"app"},
+			expStatus:     models.AppliedPlanStatus,
+		},
+		{
+			description:   "dependency has nothing to apply",
+			networkResult: nil,
+			expApplied:    []string{"app"},
+			expStatus:     models.AppliedPlanStatus,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			setup(t)
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			boltDB, err := db.New(tmp)
+			Ok(t, err)
+			ch.DB = boltDB
+			runner := &orderRecordingRunner{}
+			ch.ProjectCommandRunner = runner
+
+			pull := &github.PullRequest{State: github.String("open")}
+			modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+			When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+			When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+			appResult := models.ProjectResult{Command: models.PlanCommand, PlanSuccess: &models.PlanSuccess{}, RepoRelDir: "app", Workspace: "default", ProjectName: "app"}
+			results := []models.ProjectResult{appResult}
+			if c.networkResult != nil {
+				networkResult := *c.networkResult
+				networkResult.RepoRelDir = "network"
+				networkResult.Workspace = "default"
+				networkResult.ProjectName = "network"
+				results = append(results, networkResult)
+			}
+			_, err = boltDB.UpdatePullWithResults(modelPull, results)
+			Ok(t, err)
+
+			name := "app"
+			When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+				ThenReturn([]models.ProjectCommandContext{{
+					GlobalConfig: &valid.Config{},
+					ProjectConfig: &valid.Project{
+						Name:      &name,
+						Dir:       "app",
+						Workspace: "default",
+						DependsOn: []string{"network"},
+					},
+					Pull:       modelPull,
+					RepoRelDir: "app",
+					Workspace:  "default",
+				}}, nil)
+
+			ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand, ProjectName: "app"})
+
+			Equals(t, c.expApplied, runner.applied)
+			status, err := boltDB.GetPullStatus(modelPull)
+			Ok(t, err)
+			for _, p := range status.Projects {
+				if p.ProjectName == "app" {
+					Equals(t, c.expStatus, p.Status)
+				}
+			}
+		})
+	}
+}
+
+type orderRecordingRunner struct {
+	failing string
+	applied []string
+}
+
+func (r *orderRecordingRunner) Plan(ctx models.ProjectCommandContext) models.ProjectResult {
+	return models.ProjectResult{}
+}
+
+func (r *orderRecordingRunner) ApprovePolicies(ctx models.ProjectCommandContext) models.ProjectResult {
+	return models.ProjectResult{}
+}
+
+func (r *orderRecordingRunner) PlanDrift(ctx models.ProjectCommandContext) models.ProjectResult {
+	return models.ProjectResult{}
+}
+
+func (r *orderRecordingRunner) Apply(ctx models.ProjectCommandContext) models.ProjectResult {
+	name := ctx.GetProjectName()
+	r.applied = append(r.applied, name)
+	res := models.ProjectResult{
+		Command:     models.ApplyCommand,
+		RepoRelDir:  ctx.RepoRelDir,
+		Workspace:   ctx.Workspace,
+		ProjectName: name,
+	}
+	if name == r.failing {
+		res.Error = errors.New("apply failed")
+	} else {
+		res.ApplySuccess = "success"
+	}
+	return res
+}
+
+func TestRunCommentCommand_PermissionDenied(t *testing.T) {
+	t.Log("if the user isn't allowed to run the command we should comment and not run it")
+	vcsClient := setup(t)
+	authorizer := mocks.NewMockCommandAuthorizer()
+	ch.CommandAuthorizer = authorizer
+	pull := &github.PullRequest{State: github.String("open")}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	cmd := &events.CommentCommand{Name: models.ApplyCommand}
+	projectCmds := []models.ProjectCommandContext{{RepoRelDir: ".", Workspace: "default"}}
+	When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn(projectCmds, nil)
+	When(authorizer.Authorize(fixtures.GithubRepo, fixtures.User, models.ApplyCommand, projectCmds)).ThenReturn(&valid.Permission{
+		Command: "apply",
+		Users:   []string{"alice"},
+		Teams:   []string{"runatlantis/admins"},
+	}, nil)
+
+	ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, cmd)
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num,
+		"**Apply Denied**: `lkysow` is not allowed to run `atlantis apply`.\n\nIt can only be run by the users `alice` and members of the teams `runatlantis/admins`.")
+	projectCommandRunner.VerifyWasCalled(Never()).Apply(matchers.AnyModelsProjectCommandContext())
+	vcsClient.VerifyWasCalled(Never()).UpdateStatus(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyModelsCommitStatus(), AnyString(), AnyString(), AnyString())
+}
+
+// Test that a project's permissions can't be bypassed by targeting it with
+// -d when the permission lists its name or with -p when it lists its dir.
+func TestRunCommentCommand_PermissionDeniedBeforeClone(t *testing.T) {
+	t.Log("if the user isn't allowed to run plan on the comment's target we shouldn't clone the repo to build the commands")
+	denied := &valid.Permission{Command: "plan", Users: []string{"alice"}}
+	name := "prod"
+	target := models.ProjectCommandContext{
+		ProjectConfig: &valid.Project{Name: &name, Dir: "envs/prod", Workspace: "default"},
+		RepoRelDir:    "envs/prod",
+		Workspace:     "default",
+	}
+	cases := []struct {
+		description string
+		cmd         *events.CommentCommand
+	}{
+		{
+			"all projects",
+			&events.CommentCommand{Name: models.PlanCommand},
+		},
+		{
+			"specific project",
+			&events.CommentCommand{Name: models.PlanCommand, ProjectName: "prod"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			vcsClient := setup(t)
+			authorizer := mocks.NewMockCommandAuthorizer()
+			ch.CommandAuthorizer = authorizer
+			pull := &github.PullRequest{State: github.String("open")}
+			modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+			When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+			When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+			When(projectCommandBuilder.BuildCommentTarget(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+				ThenReturn(target)
+			When(authorizer.AuthorizeAny(fixtures.GithubRepo, fixtures.User, models.PlanCommand)).ThenReturn(denied, nil)
+			When(authorizer.Authorize(fixtures.GithubRepo, fixtures.User, models.PlanCommand, []models.ProjectCommandContext{target})).ThenReturn(denied, nil)
+
+			ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, c.cmd)
+			vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num,
+				"**Plan Denied**: `lkysow` is not allowed to run `atlantis plan`.\n\nIt can only be run by the users `alice`.")
+			projectCommandBuilder.VerifyWasCalled(Never()).BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+		})
+	}
+}
+
+func TestRunCommentCommand_ProjectPermissionBypass(t *testing.T) {
+	cases := []struct {
+		description string
+		cmd         *events.CommentCommand
+		projects    []string
+	}{
+		{
+			"permission by name targeted by dir",
+			&events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "envs/prod"},
+			[]string{"prod"},
+		},
+		{
+			"permission by dir targeted by name",
+			&events.CommentCommand{Name: models.ApplyCommand, ProjectName: "prod"},
+			[]string{"envs/prod"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			vcsClient := setup(t)
+			ch.CommandAuthorizer = &events.DefaultCommandAuthorizer{
+				VCSClient: vcsClient,
+				ServerConfig: valid.ServerConfig{
+					Repos: []valid.ServerRepo{
+						{ID: "github.com/runatlantis/atlantis", Permissions: []valid.Permission{
+							// Anyone can apply the other projects.
+							{Command: "apply", Users: []string{fixtures.User.Username}},
+							{Command: "apply", Projects: c.projects, Users: []string{"alice"}},
+						}},
+					},
+				},
+			}
+			pull := &github.PullRequest{State: github.String("open")}
+			modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+			When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+			When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+			name := "prod"
+			When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+				ThenReturn([]models.ProjectCommandContext{{
+					ProjectConfig: &valid.Project{Name: &name, Dir: "envs/prod", Workspace: "default"},
+					RepoRelDir:    "envs/prod",
+					Workspace:     "default",
+				}}, nil)
+
+			ch.RunCommentCommand(events.RequestContext{}, fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, c.cmd)
+			_, _, comment := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
+			Assert(t, strings.HasPrefix(comment, "**Apply Denied**"), "exp permission denied comment but got %q", comment)
+			projectCommandRunner.VerifyWasCalled(Never()).Apply(matchers.AnyModelsProjectCommandContext())
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/flynn-archive/go-shlex"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/metrics"
 	"github.com/spf13/pflag"
 	"io/ioutil"
 	"net/url"
@@ -35,6 +36,7 @@ const (
 	projectFlagShort   = "p"
 	verboseFlagLong    = "verbose"
 	verboseFlagShort   = ""
+	allowDestroyFlag   = "allow-destroy"
 	atlantisExecutable = "atlantis"
 )
 
@@ -64,9 +66,11 @@ type CommentBuilder interface {
 
 // CommentParser implements CommentParsing
 type CommentParser struct {
-	GithubUser    string
-	GitlabUser    string
-	BitbucketUser string
+	GithubUser      string
+	GitlabUser      string
+	BitbucketUser   string
+	AzureDevopsUser string
+	GiteaUser       string
 }
 
 // CommentParseResult describes the result of parsing a comment as a command.
@@ -86,7 +90,8 @@ type CommentParseResult struct {
 // Valid commands contain:
 // - The initial "executable" name, 'run' or 'atlantis' or '@GithubUser'
 //   where GithubUser is the API user Atlantis is running as.
-// - Then a command, either 'plan', 'apply', or 'help'.
+// - Then a command, either 'plan', 'apply', 'unlock', 'approve_policies' or
+//   'help'.
 // - Then optional flags, then an optional separator '--' followed by optional
 //   extra flags to be appended to the terraform plan/apply command.
 //
@@ -96,8 +101,21 @@ type CommentParseResult struct {
 // - @GithubUser plan -w staging
 // - atlantis plan -w staging -d dir --verbose
 // - atlantis plan --verbose -- -key=value -key2 value2
+// - atlantis unlock -d dir
+// - atlantis approve_policies
 //
 func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) CommentParseResult {
+	result := e.parse(comment, vcsHost)
+	switch {
+	case result.Command != nil:
+		metrics.CommentCommands.WithLabelValues(result.Command.Name.String()).Inc()
+	case result.CommentResponse != "":
+		metrics.CommentCommands.WithLabelValues("invalid").Inc()
+	}
+	return result
+}
+
+func (e *CommentParser) parse(comment string, vcsHost models.VCSHostType) CommentParseResult {
 	if multiLineRegex.MatchString(comment) {
 		return CommentParseResult{Ignore: true}
 	}
@@ -124,6 +142,10 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		vcsUser = e.GitlabUser
 	case models.BitbucketCloud, models.BitbucketServer:
 		vcsUser = e.BitbucketUser
+	case models.AzureDevops:
+		vcsUser = e.AzureDevopsUser
+	case models.Gitea:
+		vcsUser = e.GiteaUser
 	}
 	executableNames := []string{"run", atlantisExecutable, "@" + vcsUser}
 	if !e.stringInSlice(args[0], executableNames) {
@@ -152,8 +174,8 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		return CommentParseResult{CommentResponse: HelpComment}
 	}
 
-	// Need to have a plan or apply at this point.
-	if !e.stringInSlice(command, []string{models.PlanCommand.String(), models.ApplyCommand.String()}) {
+	// Need to have a plan, apply, unlock or approve_policies at this point.
+	if !e.stringInSlice(command, []string{models.PlanCommand.String(), models.ApplyCommand.String(), models.UnlockCommand.String(), models.ApprovePoliciesCommand.String()}) {
 		return CommentParseResult{CommentResponse: fmt.Sprintf("```\nError: unknown command %q.\nRun 'atlantis --help' for usage.\n```", command)}
 	}
 
@@ -161,6 +183,7 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 	var dir string
 	var project string
 	var verbose bool
+	var allowDestroy bool
 	var extraArgs []string
 	var flagSet *pflag.FlagSet
 	var name models.CommandName
@@ -183,6 +206,21 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Apply the plan for this directory, relative to root of repo, ex. 'child/dir'.")
 		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Apply the plan for this project. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+		flagSet.BoolVar(&allowDestroy, allowDestroyFlag, false, "Apply even if the plan destroys resources protected by the no_destroy apply requirement.")
+	case models.UnlockCommand.String():
+		name = models.UnlockCommand
+		flagSet = pflag.NewFlagSet(models.UnlockCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Release the lock for this Terraform workspace.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Release the lock for this directory, relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Release the lock for this project. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
+	case models.ApprovePoliciesCommand.String():
+		name = models.ApprovePoliciesCommand
+		flagSet = pflag.NewFlagSet(models.ApprovePoliciesCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Approve the policy check failures of the plan for this Terraform workspace.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Approve the policy check failures of the plan for this directory, relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Approve the policy check failures of the plan for this project. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
 	default:
 		return CommentParseResult{CommentResponse: fmt.Sprintf("Error: unknown command %q – this is a bug", command)}
 	}
@@ -208,6 +246,11 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 	}
 
 	if flagSet.ArgsLenAtDash() != -1 {
+		// Unlock and approve_policies don't run Terraform so there's nothing
+		// to pass extra arguments to.
+		if name == models.UnlockCommand || name == models.ApprovePoliciesCommand {
+			return CommentParseResult{CommentResponse: e.errMarkdown("extra arguments after -- are not supported", command, flagSet)}
+		}
 		extraArgsUnsafe := flagSet.Args()[flagSet.ArgsLenAtDash():]
 		// Quote all extra args so there isn't a security issue when we append
 		// them to the terraform commands, ex. "; cat /etc/passwd"
@@ -239,9 +282,9 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		return CommentParseResult{CommentResponse: e.errMarkdown(err, command, flagSet)}
 	}
 
-	return CommentParseResult{
-		Command: NewCommentCommand(dir, extraArgs, name, verbose, workspace, project),
-	}
+	cmd := NewCommentCommand(dir, extraArgs, name, verbose, workspace, project)
+	cmd.AllowDestroy = allowDestroy
+	return CommentParseResult{Command: cmd}
 }
 
 // BuildPlanComment builds a plan comment for the specified args.
@@ -343,12 +386,23 @@ Examples:
   # apply the plan for the root directory and staging workspace
   atlantis apply -d . -w staging
 
+  # release all locks held by this pull request
+  atlantis unlock
+
+  # approve the policy check failures of all plans from this pull request
+  atlantis approve_policies
+
 Commands:
-  plan   Runs 'terraform plan' for the changes in this pull request.
-         To plan a specific project, use the -d, -w and -p flags.
-  apply  Runs 'terraform apply' on all unapplied plans from this pull request.
-         To only apply a specific plan, use the -d, -w and -p flags.
-  help   View help.
+  plan             Runs 'terraform plan' for the changes in this pull request.
+                   To plan a specific project, use the -d, -w and -p flags.
+  apply            Runs 'terraform apply' on all unapplied plans from this pull request.
+                   To only apply a specific plan, use the -d, -w and -p flags.
+  unlock           Releases all locks held by this pull request and deletes its plans.
+                   To only unlock a specific project, use the -d, -w and -p flags.
+  approve_policies Approves the policy check failures of all plans from this pull
+                   request so they can be applied. Only policy approvers can run it.
+                   To only approve a specific plan, use the -d, -w and -p flags.
+  help             View help.
 
 Flags:
   -h, --help   help for atlantis
@@ -18,8 +18,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -161,6 +163,20 @@ func TestParse_InvalidCommand(t *testing.T) {
 	}
 }
 
+func TestParse_RecordsMetrics(t *testing.T) {
+	plans := metrics.CommentCommands.WithLabelValues("plan")
+	invalid := metrics.CommentCommands.WithLabelValues("invalid")
+	plansBefore := testutil.ToFloat64(plans)
+	invalidBefore := testutil.ToFloat64(invalid)
+
+	commentParser.Parse("atlantis plan", models.Github)
+	commentParser.Parse("atlantis paln", models.Github)
+	commentParser.Parse("not for atlantis", models.Github)
+
+	Equals(t, plansBefore+1, testutil.ToFloat64(plans))
+	Equals(t, invalidBefore+1, testutil.ToFloat64(invalid))
+}
+
 func TestParse_SubcommandUsage(t *testing.T) {
 	t.Log("given a comment asking for the usage of a subcommand should " +
 		"return help")
@@ -169,6 +185,10 @@ func TestParse_SubcommandUsage(t *testing.T) {
 		"atlantis plan --help",
 		"atlantis apply -h",
 		"atlantis apply --help",
+		"atlantis unlock -h",
+		"atlantis unlock --help",
+		"atlantis approve_policies -h",
+		"atlantis approve_policies --help",
 	}
 	for _, c := range comments {
 		r := commentParser.Parse(c, models.Github)
@@ -203,6 +223,10 @@ func TestParse_InvalidFlags(t *testing.T) {
 			"atlantis apply --abc",
 			"Error: unknown flag: --abc",
 		},
+		{
+			"atlantis unlock --verbose",
+			"Error: unknown flag: --verbose",
+		},
 	}
 	for _, c := range cases {
 		r := commentParser.Parse(c.comment, models.Github)
@@ -213,6 +237,73 @@ func TestParse_InvalidFlags(t *testing.T) {
 	}
 }
 
+func TestParse_UnlockExtraArgs(t *testing.T) {
+	t.Log("unlock doesn't run terraform so extra args should be rejected")
+	r := commentParser.Parse("atlantis unlock -- -target=resource", models.Github)
+	Assert(t, r.Command == nil, "expected command to be nil")
+	Assert(t, strings.Contains(r.CommentResponse, "Error: extra arguments after -- are not supported"),
+		"expected CommentResponse %q to contain error", r.CommentResponse)
+}
+
+func TestParse_Unlock(t *testing.T) {
+	cases := []struct {
+		comment string
+		exp     *events.CommentCommand
+	}{
+		{
+			"atlantis unlock",
+			&events.CommentCommand{Name: models.UnlockCommand},
+		},
+		{
+			"atlantis unlock -d dir",
+			&events.CommentCommand{Name: models.UnlockCommand, RepoRelDir: "dir"},
+		},
+		{
+			"atlantis unlock -d dir -w staging",
+			&events.CommentCommand{Name: models.UnlockCommand, RepoRelDir: "dir", Workspace: "staging"},
+		},
+		{
+			"atlantis unlock --project myproject",
+			&events.CommentCommand{Name: models.UnlockCommand, ProjectName: "myproject"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, c.exp, r.Command)
+		})
+	}
+}
+
+func TestParse_AllowDestroy(t *testing.T) {
+	r := commentParser.Parse("atlantis apply -d dir --allow-destroy", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir", AllowDestroy: true}, r.Command)
+
+	t.Log("--allow-destroy is only supported by apply")
+	r = commentParser.Parse("atlantis plan --allow-destroy", models.Github)
+	Assert(t, r.Command == nil, "expected command to be nil")
+	Assert(t, strings.Contains(r.CommentResponse, "Error: unknown flag: --allow-destroy"),
+		"expected CommentResponse %q to contain error", r.CommentResponse)
+}
+
+func TestParse_ApprovePolicies(t *testing.T) {
+	r := commentParser.Parse("atlantis approve_policies", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: models.ApprovePoliciesCommand}, r.Command)
+
+	r = commentParser.Parse("atlantis approve_policies -p project", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: models.ApprovePoliciesCommand, ProjectName: "project"}, r.Command)
+
+	t.Log("approve_policies doesn't run terraform so extra args should be rejected")
+	r = commentParser.Parse("atlantis approve_policies -- -target=resource", models.Github)
+	Assert(t, r.Command == nil, "expected command to be nil")
+	Assert(t, strings.Contains(r.CommentResponse, "Error: extra arguments after -- are not supported"),
+		"expected CommentResponse %q to contain error", r.CommentResponse)
+}
+
 func TestParse_RelativeDirPath(t *testing.T) {
 	t.Log("if -d is used with a relative path, should return an error")
 	comments := []string{
@@ -284,6 +375,7 @@ func TestParse_UsingProjectAtSameTimeAsWorkspaceOrDir(t *testing.T) {
 		"atlantis plan -w workspace -p project",
 		"atlantis plan -d dir -p project",
 		"atlantis plan -d dir -w workspace -p project",
+		"atlantis unlock -d dir -p project",
 	}
 	for _, c := range cases {
 		t.Run(c, func(t *testing.T) {
@@ -644,9 +736,11 @@ func TestBuildPlanApplyComment(t *testing.T) {
 
 func TestParse_VCSUsername(t *testing.T) {
 	cp := events.CommentParser{
-		GithubUser:    "gh",
-		GitlabUser:    "gl",
-		BitbucketUser: "bb",
+		GithubUser:      "gh",
+		GitlabUser:      "gl",
+		BitbucketUser:   "bb",
+		AzureDevopsUser: "ad",
+		GiteaUser:       "gt",
 	}
 	cases := []struct {
 		vcs  models.VCSHostType
@@ -668,6 +762,14 @@ func TestParse_VCSUsername(t *testing.T) {
 			vcs:  models.BitbucketCloud,
 			user: "bb",
 		},
+		{
+			vcs:  models.AzureDevops,
+			user: "ad",
+		},
+		{
+			vcs:  models.Gitea,
+			user: "gt",
+		},
 	}
 
 	for _, c := range cases {
@@ -689,6 +791,8 @@ var PlanUsage = `Usage of plan:
 `
 
 var ApplyUsage = `Usage of apply:
+      --allow-destroy      Apply even if the plan destroys resources protected by
+                           the no_destroy apply requirement.
   -d, --dir string         Apply the plan for this directory, relative to root of
                            repo, ex. 'child/dir'.
   -p, --project string     Apply the plan for this project. Refers to the name of
@@ -67,11 +67,7 @@ func (d *DefaultCommitStatusUpdater) UpdateCombinedCount(repo models.Repo, pull
 }
 
 func (d *DefaultCommitStatusUpdater) UpdateProject(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus, url string) error {
-	projectID := ctx.GetProjectName()
-	if projectID == "" {
-		projectID = fmt.Sprintf("%s/%s", ctx.RepoRelDir, ctx.Workspace)
-	}
-	src := fmt.Sprintf("atlantis/%s: %s", cmdName.String(), projectID)
+	src := projectStatusSrc(ctx, cmdName)
 	var descripWords string
 	switch status {
 	case models.PendingCommitStatus:
@@ -84,3 +80,13 @@ func (d *DefaultCommitStatusUpdater) UpdateProject(ctx models.ProjectCommandCont
 	descrip := fmt.Sprintf("%s %s", strings.Title(cmdName.String()), descripWords)
 	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, src, descrip, url)
 }
+
+// projectStatusSrc returns the name of the status for running cmdName on the
+// project represented by ctx, ex. "atlantis/plan: dir/default".
+func projectStatusSrc(ctx models.ProjectCommandContext, cmdName models.CommandName) string {
+	projectID := ctx.GetProjectName()
+	if projectID == "" {
+		projectID = fmt.Sprintf("%s/%s", ctx.RepoRelDir, ctx.Workspace)
+	}
+	return fmt.Sprintf("atlantis/%s: %s", cmdName.String(), projectID)
+}
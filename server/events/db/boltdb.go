@@ -10,7 +10,6 @@ import (
 	"github.com/runatlantis/atlantis/server/events/models"
 	"os"
 	"path"
-	"strings"
 	"time"
 )
 
@@ -19,12 +18,13 @@ type BoltDB struct {
 	db              *bolt.DB
 	locksBucketName []byte
 	pullsBucketName []byte
+	queueBucketName []byte
 }
 
 const (
-	locksBucketName  = "runLocks"
-	pullsBucketName  = "pulls"
-	pullKeySeparator = "::"
+	locksBucketName = "runLocks"
+	pullsBucketName = "pulls"
+	queueBucketName = "lockQueue"
 )
 
 // New returns a valid locker. We need to be able to write to dataDir
@@ -49,35 +49,63 @@ func New(dataDir string) (*BoltDB, error) {
 		if _, err = tx.CreateBucketIfNotExists([]byte(pullsBucketName)); err != nil {
 			return errors.Wrapf(err, "creating bucket %q", pullsBucketName)
 		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(queueBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", queueBucketName)
+		}
 		return nil
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "starting BoltDB")
 	}
 	// todo: close BoltDB when server is sigtermed
-	return &BoltDB{db: db, locksBucketName: []byte(locksBucketName), pullsBucketName: []byte(pullsBucketName)}, nil
+	return &BoltDB{db: db, locksBucketName: []byte(locksBucketName), pullsBucketName: []byte(pullsBucketName), queueBucketName: []byte(queueBucketName)}, nil
 }
 
 // NewWithDB is used for testing.
 func NewWithDB(db *bolt.DB, bucket string) (*BoltDB, error) {
-	return &BoltDB{db: db, locksBucketName: []byte(bucket), pullsBucketName: []byte(pullsBucketName)}, nil
+	return &BoltDB{db: db, locksBucketName: []byte(bucket), pullsBucketName: []byte(pullsBucketName), queueBucketName: []byte(queueBucketName)}, nil
+}
+
+// Close closes the database file so another process can open it.
+func (b *BoltDB) Close() error {
+	return b.db.Close()
 }
 
 // TryLock attempts to create a new lock. If the lock is
 // acquired, it will return true and the lock returned will be newLock.
 // If the lock is not acquired, it will return false and the current
-// lock that is preventing this lock from being acquired.
+// lock that is preventing this lock from being acquired. If the lock isn't
+// held but other pull requests are queued for it, the lock returned is the
+// first of them.
 func (b *BoltDB) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error) {
 	var lockAcquired bool
 	var currLock models.ProjectLock
-	key := b.lockKey(newLock.Project, newLock.Workspace)
+	key := lockKey(newLock.Project, newLock.Workspace)
 	newLockSerialized, _ := json.Marshal(newLock)
 	transactionErr := b.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(b.locksBucketName)
 
 		// if there is no run at that key then we're free to create the lock
+		// unless someone else is queued for it
 		currLockSerialized := bucket.Get([]byte(key))
 		if currLockSerialized == nil {
+			queueBucket, err := tx.CreateBucketIfNotExists(b.queueBucketName)
+			if err != nil {
+				return err
+			}
+			queue, err := b.getQueueFromBucket(queueBucket, []byte(key))
+			if err != nil {
+				return err
+			}
+			acquired, newQueue := acquireFreeLock(queue, newLock)
+			if !acquired {
+				lockAcquired = false
+				currLock = queue[0]
+				return nil
+			}
+			if err := b.writeQueueToBucket(queueBucket, []byte(key), newQueue); err != nil {
+				return err
+			}
 			// This will only error on readonly buckets, it's okay to ignore.
 			bucket.Put([]byte(key), newLockSerialized) // nolint: errcheck
 			lockAcquired = true
@@ -107,7 +135,7 @@ func (b *BoltDB) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock,
 func (b *BoltDB) Unlock(p models.Project, workspace string) (*models.ProjectLock, error) {
 	var lock models.ProjectLock
 	foundLock := false
-	key := b.lockKey(p, workspace)
+	key := lockKey(p, workspace)
 	err := b.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(b.locksBucketName)
 		serialized := bucket.Get([]byte(key))
@@ -126,6 +154,32 @@ func (b *BoltDB) Unlock(p models.Project, workspace string) (*models.ProjectLock
 	return nil, err
 }
 
+// UnlockIfUnchanged deletes the lock for lock's project and workspace only if
+// it's still the same hold as lock, i.e. it wasn't released and taken again
+// since lock was read. It returns the deleted lock or a nil pointer if
+// nothing was deleted.
+func (b *BoltDB) UnlockIfUnchanged(lock models.ProjectLock) (*models.ProjectLock, error) {
+	var deleted *models.ProjectLock
+	key := lockKey(lock.Project, lock.Workspace)
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.locksBucketName)
+		serialized := bucket.Get([]byte(key))
+		if serialized == nil {
+			return nil
+		}
+		var currLock models.ProjectLock
+		if err := json.Unmarshal(serialized, &currLock); err != nil {
+			return errors.Wrap(err, "failed to deserialize lock")
+		}
+		if !sameLock(currLock, lock) {
+			return nil
+		}
+		deleted = &currLock
+		return bucket.Delete([]byte(key))
+	})
+	return deleted, errors.Wrap(err, "DB transaction failed")
+}
+
 // List lists all current locks.
 func (b *BoltDB) List() ([]models.ProjectLock, error) {
 	var locks []models.ProjectLock
@@ -188,7 +242,7 @@ func (b *BoltDB) UnlockByPull(repoFullName string, pullNum int) ([]models.Projec
 // GetLock returns a pointer to the lock for that project and workspace.
 // If there is no lock, it returns a nil pointer.
 func (b *BoltDB) GetLock(p models.Project, workspace string) (*models.ProjectLock, error) {
-	key := b.lockKey(p, workspace)
+	key := lockKey(p, workspace)
 	var lockBytes []byte
 	err := b.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket(b.locksBucketName)
@@ -213,6 +267,167 @@ func (b *BoltDB) GetLock(p models.Project, workspace string) (*models.ProjectLoc
 	return &lock, nil
 }
 
+// Enqueue adds newLock to the end of the queue of pull requests waiting for
+// the lock on its project and workspace. It returns the 1-based position of
+// newLock's pull request in the queue. If the pull request is already queued,
+// its existing position is returned and the queue is left unchanged. If the
+// lock was released since TryLock failed and newLock's pull request is next
+// in line, newLock acquires the lock instead of being queued and true is
+// returned.
+func (b *BoltDB) Enqueue(newLock models.ProjectLock) (bool, int, error) {
+	var lockAcquired bool
+	var position int
+	key := []byte(lockKey(newLock.Project, newLock.Workspace))
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		locksBucket := tx.Bucket(b.locksBucketName)
+		bucket, err := tx.CreateBucketIfNotExists(b.queueBucketName)
+		if err != nil {
+			return err
+		}
+		queue, err := b.getQueueFromBucket(bucket, key)
+		if err != nil {
+			return err
+		}
+		if locksBucket.Get(key) == nil {
+			var newQueue []models.ProjectLock
+			lockAcquired, newQueue = acquireFreeLock(queue, newLock)
+			if lockAcquired {
+				serialized, err := json.Marshal(newLock)
+				if err != nil {
+					return errors.Wrap(err, "serializing")
+				}
+				if err := locksBucket.Put(key, serialized); err != nil {
+					return err
+				}
+				return b.writeQueueToBucket(bucket, key, newQueue)
+			}
+		}
+		newQueue, pos := enqueue(queue, newLock)
+		position = pos
+		if len(newQueue) == len(queue) {
+			return nil
+		}
+		return b.writeQueueToBucket(bucket, key, newQueue)
+	})
+	return lockAcquired, position, errors.Wrap(err, "DB transaction failed")
+}
+
+// GetQueue returns the pull requests waiting for the lock on project and
+// workspace, in the order they will acquire it.
+func (b *BoltDB) GetQueue(p models.Project, workspace string) ([]models.ProjectLock, error) {
+	var queue []models.ProjectLock
+	key := []byte(lockKey(p, workspace))
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.queueBucketName)
+		if bucket == nil {
+			return nil
+		}
+		var txErr error
+		queue, txErr = b.getQueueFromBucket(bucket, key)
+		return txErr
+	})
+	return queue, errors.Wrap(err, "DB transaction failed")
+}
+
+// DequeueByPull removes the pull request from every queue it's waiting in
+// for repoFullName.
+func (b *BoltDB) DequeueByPull(repoFullName string, pullNum int) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(b.queueBucketName)
+		if err != nil {
+			return err
+		}
+		updated := make(map[string][]models.ProjectLock)
+		c := bucket.Cursor()
+
+		// The repoFullName is the first part of the key so we can use it as
+		// a prefix search.
+		for k, v := c.Seek([]byte(repoFullName)); k != nil && bytes.HasPrefix(k, []byte(repoFullName)); k, v = c.Next() {
+			var queue []models.ProjectLock
+			if err := json.Unmarshal(v, &queue); err != nil {
+				return errors.Wrapf(err, "deserializing queue at key %q", string(k))
+			}
+			if newQueue := dequeuePull(queue, repoFullName, pullNum); len(newQueue) != len(queue) {
+				updated[string(k)] = newQueue
+			}
+		}
+
+		// We can't modify the bucket while iterating over it so we write
+		// the changes afterwards.
+		for k, q := range updated {
+			if err := b.writeQueueToBucket(bucket, []byte(k), q); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// Dequeue removes the pull request from the queue for the lock on project and
+// workspace.
+func (b *BoltDB) Dequeue(p models.Project, workspace string, pullNum int) error {
+	key := []byte(lockKey(p, workspace))
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.queueBucketName)
+		if bucket == nil {
+			return nil
+		}
+		queue, err := b.getQueueFromBucket(bucket, key)
+		if err != nil {
+			return err
+		}
+		newQueue := dequeuePull(queue, p.RepoFullName, pullNum)
+		if len(newQueue) == len(queue) {
+			return nil
+		}
+		return b.writeQueueToBucket(bucket, key, newQueue)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// PromoteNext gives the lock on project and workspace to the first pull
+// request in its queue. It only does so if the lock isn't currently held.
+// It returns a pointer to the new lock or a nil pointer if there was no lock
+// to promote.
+func (b *BoltDB) PromoteNext(p models.Project, workspace string) (*models.ProjectLock, error) {
+	var promoted *models.ProjectLock
+	key := []byte(lockKey(p, workspace))
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		locksBucket := tx.Bucket(b.locksBucketName)
+		if locksBucket.Get(key) != nil {
+			return nil
+		}
+		queueBucket, err := tx.CreateBucketIfNotExists(b.queueBucketName)
+		if err != nil {
+			return err
+		}
+		queue, err := b.getQueueFromBucket(queueBucket, key)
+		if err != nil {
+			return err
+		}
+		if len(queue) == 0 {
+			return nil
+		}
+
+		next := queue[0]
+		next.Time = time.Now().Local()
+		serialized, err := json.Marshal(next)
+		if err != nil {
+			return errors.Wrap(err, "serializing")
+		}
+		if err := locksBucket.Put(key, serialized); err != nil {
+			return err
+		}
+		if err := b.writeQueueToBucket(queueBucket, key, queue[1:]); err != nil {
+			return err
+		}
+		promoted = &next
+		return nil
+	})
+	return promoted, errors.Wrap(err, "DB transaction failed")
+}
+
 // UpdatePullWithResults updates pull's status with the latest project results.
 // It returns the new PullStatus object.
 func (b *BoltDB) UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error) {
@@ -229,48 +444,7 @@ func (b *BoltDB) UpdatePullWithResults(pull models.PullRequest, newResults []mod
 			return err
 		}
 
-		// If there is no pull OR if the pull we have is out of date, we
-		// just write a new pull.
-		if currStatus == nil || currStatus.Pull.HeadCommit != pull.HeadCommit {
-			var statuses []models.ProjectStatus
-			for _, r := range newResults {
-				statuses = append(statuses, b.projectResultToProject(r))
-			}
-			newStatus = models.PullStatus{
-				Pull:     pull,
-				Projects: statuses,
-			}
-		} else {
-			// If there's an existing pull at the right commit then we have to
-			// merge our project results with the existing ones. We do a merge
-			// because it's possible a user is just applying a single project
-			// in this command and so we don't want to delete our data about
-			// other projects that aren't affected by this command.
-			newStatus = *currStatus
-			for _, res := range newResults {
-				// First, check if we should update any existing projects.
-				updatedExisting := false
-				for i := range newStatus.Projects {
-					// NOTE: We're using a reference here because we are
-					// in-place updating its Status field.
-					proj := &newStatus.Projects[i]
-					if res.Workspace == proj.Workspace &&
-						res.RepoRelDir == proj.RepoRelDir &&
-						res.ProjectName == proj.ProjectName {
-
-						proj.Status = res.PlanStatus()
-						updatedExisting = true
-						break
-					}
-				}
-
-				if !updatedExisting {
-					// If we didn't update an existing project, then we need to
-					// add this because it's a new one.
-					newStatus.Projects = append(newStatus.Projects, b.projectResultToProject(res))
-				}
-			}
-		}
+		newStatus = mergePullResults(currStatus, pull, newResults)
 
 		// Now, we overwrite the key with our new status.
 		return b.writePullToBucket(bucket, key, newStatus)
@@ -324,41 +498,33 @@ func (b *BoltDB) DeleteProjectStatus(pull models.PullRequest, workspace string,
 		if currStatusPtr == nil {
 			return nil
 		}
-		currStatus := *currStatusPtr
-
-		// Create a new projectStatuses array without the ones we want to
-		// delete.
-		var newProjects []models.ProjectStatus
-		for _, p := range currStatus.Projects {
-			if p.Workspace == workspace && p.RepoRelDir == repoRelDir {
-				continue
-			}
-			newProjects = append(newProjects, p)
-		}
-
 		// Overwrite the old pull status.
-		currStatus.Projects = newProjects
-		return b.writePullToBucket(bucket, key, currStatus)
+		return b.writePullToBucket(bucket, key, deleteProjectStatus(*currStatusPtr, workspace, repoRelDir))
 	})
 	return errors.Wrap(err, "DB transaction failed")
 }
 
-func (b *BoltDB) pullKey(pull models.PullRequest) ([]byte, error) {
-	hostname := pull.BaseRepo.VCSHost.Hostname
-	if strings.Contains(hostname, pullKeySeparator) {
-		return nil, fmt.Errorf("vcs hostname %q contains illegal string %q", hostname, pullKeySeparator)
-	}
-	repo := pull.BaseRepo.FullName
-	if strings.Contains(repo, pullKeySeparator) {
-		return nil, fmt.Errorf("repo name %q contains illegal string %q", hostname, pullKeySeparator)
+// UpdatePullComments records commentIDs as the IDs of the comments that hold
+// the latest output of command on pull.
+func (b *BoltDB) UpdatePullComments(pull models.PullRequest, command models.CommandName, commentIDs []string) error {
+	key, err := b.pullKey(pull)
+	if err != nil {
+		return err
 	}
-
-	return []byte(fmt.Sprintf("%s::%s::%d", hostname, repo, pull.Num)),
-		nil
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.pullsBucketName)
+		currStatus, err := b.getPullFromBucket(bucket, key)
+		if err != nil {
+			return err
+		}
+		return b.writePullToBucket(bucket, key, setPullComments(currStatus, pull, command, commentIDs))
+	})
+	return errors.Wrap(err, "DB transaction failed")
 }
 
-func (b *BoltDB) lockKey(p models.Project, workspace string) string {
-	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+func (b *BoltDB) pullKey(pull models.PullRequest) ([]byte, error) {
+	key, err := pullKey(pull)
+	return []byte(key), err
 }
 
 func (b *BoltDB) getPullFromBucket(bucket *bolt.Bucket, key []byte) (*models.PullStatus, error) {
@@ -374,19 +540,34 @@ func (b *BoltDB) getPullFromBucket(bucket *bolt.Bucket, key []byte) (*models.Pul
 	return &p, nil
 }
 
-func (b *BoltDB) writePullToBucket(bucket *bolt.Bucket, key []byte, pull models.PullStatus) error {
-	serialized, err := json.Marshal(pull)
+func (b *BoltDB) getQueueFromBucket(bucket *bolt.Bucket, key []byte) ([]models.ProjectLock, error) {
+	serialized := bucket.Get(key)
+	if serialized == nil {
+		return nil, nil
+	}
+
+	var queue []models.ProjectLock
+	if err := json.Unmarshal(serialized, &queue); err != nil {
+		return nil, errors.Wrapf(err, "deserializing queue at %q", key)
+	}
+	return queue, nil
+}
+
+func (b *BoltDB) writeQueueToBucket(bucket *bolt.Bucket, key []byte, queue []models.ProjectLock) error {
+	if len(queue) == 0 {
+		return bucket.Delete(key)
+	}
+	serialized, err := json.Marshal(queue)
 	if err != nil {
 		return errors.Wrap(err, "serializing")
 	}
 	return bucket.Put(key, serialized)
 }
 
-func (b *BoltDB) projectResultToProject(p models.ProjectResult) models.ProjectStatus {
-	return models.ProjectStatus{
-		Workspace:   p.Workspace,
-		RepoRelDir:  p.RepoRelDir,
-		ProjectName: p.ProjectName,
-		Status:      p.PlanStatus(),
+func (b *BoltDB) writePullToBucket(bucket *bolt.Bucket, key []byte, pull models.PullStatus) error {
+	serialized, err := json.Marshal(pull)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
 	}
+	return bucket.Put(key, serialized)
 }
@@ -239,6 +239,37 @@ func TestUnlockingMultiple(t *testing.T) {
 	Equals(t, 0, len(ls))
 }
 
+func TestUnlockIfUnchanged(t *testing.T) {
+	db, b := newTestDB()
+	defer cleanupDB(db)
+
+	_, _, err := b.TryLock(lock)
+	Ok(t, err)
+
+	t.Log("a lock held by another pull shouldn't be unlocked")
+	otherPull := lock
+	otherPull.Pull.Num = pullNum + 1
+	deleted, err := b.UnlockIfUnchanged(otherPull)
+	Ok(t, err)
+	Assert(t, deleted == nil, "exp lock not to be deleted")
+
+	t.Log("a lock the same pull took at another time shouldn't be unlocked")
+	otherTime := lock
+	otherTime.Time = lock.Time.Add(-time.Hour)
+	deleted, err = b.UnlockIfUnchanged(otherTime)
+	Ok(t, err)
+	Assert(t, deleted == nil, "exp lock not to be deleted")
+
+	t.Log("the same lock should be unlocked")
+	deleted, err = b.UnlockIfUnchanged(lock)
+	Ok(t, err)
+	Assert(t, deleted != nil, "exp lock to be deleted")
+	Equals(t, pullNum, deleted.Pull.Num)
+	ls, err := b.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+}
+
 func TestUnlockByPullNone(t *testing.T) {
 	t.Log("UnlockByPull should be successful when there are no locks")
 	db, b := newTestDB()
@@ -352,7 +383,229 @@ func TestGetLock(t *testing.T) {
 	Equals(t, lock.User, l.User)
 }
 
-// Test we can create a status and then get it.
+func TestEnqueue(t *testing.T) {
+	t.Log("enqueuing should return each pull's position in the queue")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+
+	_, _, err := b.TryLock(lock)
+	Ok(t, err)
+	for i := 1; i <= 3; i++ {
+		queued := lock
+		queued.Pull.Num = pullNum + i
+		acquired, pos, err := b.Enqueue(queued)
+		Ok(t, err)
+		Equals(t, false, acquired)
+		Equals(t, i, pos)
+	}
+
+	t.Log("enqueuing the same pull again should not change its position")
+	queued := lock
+	queued.Pull.Num = pullNum + 2
+	acquired, pos, err := b.Enqueue(queued)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, 2, pos)
+
+	queue, err := b.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 3, len(queue))
+	Equals(t, pullNum+1, queue[0].Pull.Num)
+	Equals(t, pullNum+2, queue[1].Pull.Num)
+	Equals(t, pullNum+3, queue[2].Pull.Num)
+}
+
+func TestGetQueueEmpty(t *testing.T) {
+	t.Log("getting a queue that doesn't exist should return nothing")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+	queue, err := b.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 0, len(queue))
+}
+
+func TestDequeueByPull(t *testing.T) {
+	t.Log("DequeueByPull should only remove the matching pull from its repo's queues")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+
+	queued := lock
+	queued.Pull.Num = pullNum + 1
+	otherWorkspace := queued
+	otherWorkspace.Workspace = "staging"
+	otherPull := lock
+	otherPull.Pull.Num = pullNum + 2
+	otherRepo := queued
+	otherRepo.Project = models.NewProject("owner/repo2", "parent/child")
+	for _, l := range []models.ProjectLock{queued, otherWorkspace, otherPull, otherRepo} {
+		held := l
+		held.Pull.Num = pullNum
+		_, _, err := b.TryLock(held)
+		Ok(t, err)
+		_, _, err = b.Enqueue(l)
+		Ok(t, err)
+	}
+
+	Ok(t, b.DequeueByPull(project.RepoFullName, pullNum+1))
+
+	queue, err := b.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+	Equals(t, pullNum+2, queue[0].Pull.Num)
+	queue, err = b.GetQueue(project, "staging")
+	Ok(t, err)
+	Equals(t, 0, len(queue))
+	queue, err = b.GetQueue(otherRepo.Project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+}
+
+func TestDequeue(t *testing.T) {
+	t.Log("Dequeue should only remove the pull from the project and workspace's queue")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+
+	queued := lock
+	queued.Pull.Num = pullNum + 1
+	otherWorkspace := queued
+	otherWorkspace.Workspace = "staging"
+	otherPull := lock
+	otherPull.Pull.Num = pullNum + 2
+	for _, l := range []models.ProjectLock{queued, otherWorkspace, otherPull} {
+		held := l
+		held.Pull.Num = pullNum
+		_, _, err := b.TryLock(held)
+		Ok(t, err)
+		_, _, err = b.Enqueue(l)
+		Ok(t, err)
+	}
+
+	Ok(t, b.Dequeue(project, workspace, pullNum+1))
+
+	queue, err := b.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+	Equals(t, pullNum+2, queue[0].Pull.Num)
+	queue, err = b.GetQueue(project, "staging")
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+
+	t.Log("dequeuing a pull that isn't queued should do nothing")
+	Ok(t, b.Dequeue(project, workspace, pullNum+3))
+	queue, err = b.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+}
+
+func TestPromoteNext(t *testing.T) {
+	t.Log("PromoteNext should hand the lock to the first queued pull once it's free")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+
+	_, _, err := b.TryLock(lock)
+	Ok(t, err)
+	queued := lock
+	queued.Pull.Num = pullNum + 1
+	_, _, err = b.Enqueue(queued)
+	Ok(t, err)
+
+	t.Log("...nothing is promoted while the lock is held")
+	promoted, err := b.PromoteNext(project, workspace)
+	Ok(t, err)
+	Assert(t, promoted == nil, "exp nothing to be promoted")
+
+	t.Log("...the queued pull gets the lock after it's released")
+	_, err = b.Unlock(project, workspace)
+	Ok(t, err)
+	promoted, err = b.PromoteNext(project, workspace)
+	Ok(t, err)
+	Assert(t, promoted != nil, "exp lock to be promoted")
+	Equals(t, pullNum+1, promoted.Pull.Num)
+
+	curr, err := b.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, curr != nil, "exp lock to be held")
+	Equals(t, pullNum+1, curr.Pull.Num)
+	queue, err := b.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 0, len(queue))
+
+	t.Log("...nothing is promoted when the queue is empty")
+	_, err = b.Unlock(project, workspace)
+	Ok(t, err)
+	promoted, err = b.PromoteNext(project, workspace)
+	Ok(t, err)
+	Assert(t, promoted == nil, "exp nothing to be promoted")
+}
+
+func TestEnqueueFreeLock(t *testing.T) {
+	t.Log("enqueuing should take the lock if it was released and nobody is queued")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+
+	acquired, pos, err := b.Enqueue(lock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	Equals(t, 0, pos)
+	curr, err := b.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, curr != nil, "exp lock to be held")
+	Equals(t, pullNum, curr.Pull.Num)
+
+	t.Log("...but not if another pull is ahead in the queue")
+	queued := lock
+	queued.Pull.Num = pullNum + 1
+	_, _, err = b.Enqueue(queued)
+	Ok(t, err)
+	_, err = b.Unlock(project, workspace)
+	Ok(t, err)
+	behind := lock
+	behind.Pull.Num = pullNum + 2
+	acquired, pos, err = b.Enqueue(behind)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, 2, pos)
+
+	t.Log("...unless it's the first pull in the queue")
+	acquired, _, err = b.Enqueue(queued)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	queue, err := b.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+	Equals(t, pullNum+2, queue[0].Pull.Num)
+}
+
+func TestTryLockQueued(t *testing.T) {
+	t.Log("TryLock should not take a free lock while other pulls are queued")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+
+	_, _, err := b.TryLock(lock)
+	Ok(t, err)
+	queued := lock
+	queued.Pull.Num = pullNum + 1
+	_, _, err = b.Enqueue(queued)
+	Ok(t, err)
+	_, err = b.Unlock(project, workspace)
+	Ok(t, err)
+
+	other := lock
+	other.Pull.Num = pullNum + 2
+	acquired, curr, err := b.TryLock(other)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, pullNum+1, curr.Pull.Num)
+
+	t.Log("...but the first queued pull can take it")
+	acquired, _, err = b.TryLock(queued)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	queue, err := b.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 0, len(queue))
+}
+
 func TestPullStatus_UpdateGet(t *testing.T) {
 	b, cleanup := newTestDB2(t)
 	defer cleanup()
@@ -571,6 +824,86 @@ func TestPullStatus_UpdateNewCommit(t *testing.T) {
 	}, maybeStatus.Projects)
 }
 
+// Test that plan summaries are stored and replaced by later plans.
+func TestPullStatus_PlanSummary(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			VCSHost: models.VCSHost{
+				Hostname: "github.com",
+				Type:     models.Github,
+			},
+		},
+	}
+	summary := &models.PlanSummary{Add: 1, Creates: []string{"null_resource.test"}}
+	_, err := b.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{PlanSummary: summary},
+		},
+	})
+	Ok(t, err)
+	status, err := b.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, summary, status.Projects[0].PlanSummary)
+
+	status2, err := b.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:    models.PlanCommand,
+			RepoRelDir: ".",
+			Workspace:  "default",
+			Error:      errors.New("err"),
+		},
+	})
+	Ok(t, err)
+	Assert(t, status2.Projects[0].PlanSummary == nil, "exp summary to be cleared by failed plan")
+}
+
+// Test that the IDs of the comments are stored and kept when the pull request
+// gets a new commit.
+func TestPullStatus_UpdateComments(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			VCSHost: models.VCSHost{
+				Hostname: "github.com",
+				Type:     models.Github,
+			},
+		},
+	}
+
+	Ok(t, b.UpdatePullComments(pull, models.PlanCommand, []string{"1", "2"}))
+	status, err := b.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, pull, status.Pull)
+	Equals(t, map[string][]string{"plan": {"1", "2"}}, status.Comments)
+
+	Ok(t, b.UpdatePullComments(pull, models.ApplyCommand, []string{"3"}))
+	pull.HeadCommit = "newsha"
+	_, err = b.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			RepoRelDir: ".",
+			Workspace:  "default",
+			Failure:    "failure",
+		},
+	})
+	Ok(t, err)
+	status, err = b.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, 1, len(status.Projects))
+	Equals(t, map[string][]string{"plan": {"1", "2"}, "apply": {"3"}}, status.Comments)
+}
+
 // Test that if we update an existing pull status and our new status is for a
 // the same commit, that we merge the statuses.
 func TestPullStatus_UpdateMerge(t *testing.T) {
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// Database stores Atlantis' state: the project locks, the queues of pull
+// requests waiting for those locks and the status of each pull request.
+type Database interface {
+	locking.Backend
+
+	// UpdatePullWithResults updates pull's status with the latest project
+	// results. It returns the new PullStatus object.
+	UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error)
+	// GetPullStatus returns the status for pull.
+	// If there is no status, returns a nil pointer.
+	GetPullStatus(pull models.PullRequest) (*models.PullStatus, error)
+	// DeletePullStatus deletes the status for pull.
+	DeletePullStatus(pull models.PullRequest) error
+	// DeleteProjectStatus deletes all project statuses under pull that match
+	// workspace and repoRelDir.
+	DeleteProjectStatus(pull models.PullRequest, workspace string, repoRelDir string) error
+	// UpdatePullComments records commentIDs as the IDs of the comments that
+	// hold the latest output of command on pull.
+	UpdatePullComments(pull models.PullRequest, command models.CommandName, commentIDs []string) error
+}
+
+const pullKeySeparator = "::"
+
+func lockKey(p models.Project, workspace string) string {
+	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+}
+
+func pullKey(pull models.PullRequest) (string, error) {
+	hostname := pull.BaseRepo.VCSHost.Hostname
+	if strings.Contains(hostname, pullKeySeparator) {
+		return "", fmt.Errorf("vcs hostname %q contains illegal string %q", hostname, pullKeySeparator)
+	}
+	repo := pull.BaseRepo.FullName
+	if strings.Contains(repo, pullKeySeparator) {
+		return "", fmt.Errorf("repo name %q contains illegal string %q", repo, pullKeySeparator)
+	}
+
+	return fmt.Sprintf("%s::%s::%d", hostname, repo, pull.Num), nil
+}
+
+// mergePullResults returns the status of pull after newResults are applied
+// to its current status currStatus. currStatus can be nil if there is no
+// status yet.
+func mergePullResults(currStatus *models.PullStatus, pull models.PullRequest, newResults []models.ProjectResult) models.PullStatus {
+	// If there is no pull OR if the pull we have is out of date, we
+	// just write a new pull.
+	if currStatus == nil || currStatus.Pull.HeadCommit != pull.HeadCommit {
+		var statuses []models.ProjectStatus
+		for _, r := range newResults {
+			statuses = append(statuses, projectResultToProject(r))
+		}
+		newStatus := models.PullStatus{
+			Pull:     pull,
+			Projects: statuses,
+		}
+		// The comments are still on the pull request so we keep track of
+		// them even though the commit changed.
+		if currStatus != nil {
+			newStatus.Comments = currStatus.Comments
+		}
+		return newStatus
+	}
+
+	// If there's an existing pull at the right commit then we have to
+	// merge our project results with the existing ones. We do a merge
+	// because it's possible a user is just applying a single project
+	// in this command and so we don't want to delete our data about
+	// other projects that aren't affected by this command.
+	newStatus := *currStatus
+	for _, res := range newResults {
+		// First, check if we should update any existing projects.
+		updatedExisting := false
+		for i := range newStatus.Projects {
+			// NOTE: We're using a reference here because we are
+			// in-place updating its Status field.
+			proj := &newStatus.Projects[i]
+			if res.Workspace == proj.Workspace &&
+				res.RepoRelDir == proj.RepoRelDir &&
+				res.ProjectName == proj.ProjectName {
+
+				proj.Status = res.PlanStatus()
+				// Applies and policy approvals keep the summary of the
+				// plan they're for.
+				if res.Command == models.PlanCommand {
+					proj.PlanSummary = resultPlanSummary(res)
+				}
+				updatedExisting = true
+				break
+			}
+		}
+
+		if !updatedExisting {
+			// If we didn't update an existing project, then we need to
+			// add this because it's a new one.
+			newStatus.Projects = append(newStatus.Projects, projectResultToProject(res))
+		}
+	}
+	return newStatus
+}
+
+// deleteProjectStatus returns currStatus without the project statuses that
+// match workspace and repoRelDir.
+func deleteProjectStatus(currStatus models.PullStatus, workspace string, repoRelDir string) models.PullStatus {
+	var newProjects []models.ProjectStatus
+	for _, p := range currStatus.Projects {
+		if p.Workspace == workspace && p.RepoRelDir == repoRelDir {
+			continue
+		}
+		newProjects = append(newProjects, p)
+	}
+	currStatus.Projects = newProjects
+	return currStatus
+}
+
+// setPullComments returns currStatus with the comments of command set to
+// commentIDs. currStatus can be nil if there is no status yet.
+func setPullComments(currStatus *models.PullStatus, pull models.PullRequest, command models.CommandName, commentIDs []string) models.PullStatus {
+	newStatus := models.PullStatus{Pull: pull}
+	if currStatus != nil {
+		newStatus = *currStatus
+	}
+	comments := make(map[string][]string)
+	for k, v := range newStatus.Comments {
+		comments[k] = v
+	}
+	comments[command.String()] = commentIDs
+	newStatus.Comments = comments
+	return newStatus
+}
+
+// sameLock returns true if a and b are the same hold of a lock: the same pull
+// request took it at the same time.
+func sameLock(a models.ProjectLock, b models.ProjectLock) bool {
+	return a.Pull.Num == b.Pull.Num && a.Time.Equal(b.Time)
+}
+
+// acquireFreeLock returns true if newLock can take a lock that isn't held,
+// given the queue of pull requests waiting for it. Only the pull request at
+// the front of the queue can take it so that nobody jumps the queue. It also
+// returns the queue with newLock's pull request removed if it was acquired.
+func acquireFreeLock(queue []models.ProjectLock, newLock models.ProjectLock) (bool, []models.ProjectLock) {
+	if len(queue) == 0 {
+		return true, queue
+	}
+	if queue[0].Pull.Num == newLock.Pull.Num {
+		return true, queue[1:]
+	}
+	return false, queue
+}
+
+// enqueue appends newLock to queue unless its pull request is already
+// queued. It returns the new queue and the 1-based position of newLock's
+// pull request in it.
+func enqueue(queue []models.ProjectLock, newLock models.ProjectLock) ([]models.ProjectLock, int) {
+	for i, q := range queue {
+		if q.Pull.Num == newLock.Pull.Num {
+			return queue, i + 1
+		}
+	}
+	queue = append(queue, newLock)
+	return queue, len(queue)
+}
+
+// dequeuePull returns queue without the entries for pullNum in repoFullName.
+func dequeuePull(queue []models.ProjectLock, repoFullName string, pullNum int) []models.ProjectLock {
+	var newQueue []models.ProjectLock
+	for _, q := range queue {
+		if q.Project.RepoFullName == repoFullName && q.Pull.Num == pullNum {
+			continue
+		}
+		newQueue = append(newQueue, q)
+	}
+	return newQueue
+}
+
+func projectResultToProject(p models.ProjectResult) models.ProjectStatus {
+	return models.ProjectStatus{
+		Workspace:   p.Workspace,
+		RepoRelDir:  p.RepoRelDir,
+		ProjectName: p.ProjectName,
+		Status:      p.PlanStatus(),
+		PlanSummary: resultPlanSummary(p),
+	}
+}
+
+// resultPlanSummary returns the plan summary of p or nil if p isn't a
+// successful plan.
+func resultPlanSummary(p models.ProjectResult) *models.PlanSummary {
+	if p.PlanSuccess == nil {
+		return nil
+	}
+	return p.PlanSuccess.PlanSummary
+}
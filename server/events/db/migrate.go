@@ -0,0 +1,113 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// MigrateBoltDBResult is what was copied by MigrateBoltDB.
+type MigrateBoltDBResult struct {
+	Locks  int
+	Pulls  int
+	Queues int
+}
+
+// MigrateBoltDB copies the locks, pull statuses and lock queues in src into
+// dst. Anything in dst with the same keys is overwritten. Everything is
+// copied in a single transaction so if it fails, dst is left unchanged.
+// Atlantis must not be running while this is called.
+func MigrateBoltDB(src *BoltDB, dst *SQLDB) (MigrateBoltDBResult, error) {
+	var result MigrateBoltDBResult
+	locks := make(map[string]models.ProjectLock)
+	pulls := make(map[string]models.PullStatus)
+	queues := make(map[string][]models.ProjectLock)
+
+	err := src.db.View(func(tx *bolt.Tx) error {
+		if err := forEachInBucket(tx, src.locksBucketName, func(k string, v []byte) error {
+			var lock models.ProjectLock
+			if err := json.Unmarshal(v, &lock); err != nil {
+				return errors.Wrapf(err, "deserializing lock at key %q", k)
+			}
+			locks[k] = lock
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := forEachInBucket(tx, src.pullsBucketName, func(k string, v []byte) error {
+			var pull models.PullStatus
+			if err := json.Unmarshal(v, &pull); err != nil {
+				return errors.Wrapf(err, "deserializing pull at key %q", k)
+			}
+			pulls[k] = pull
+			return nil
+		}); err != nil {
+			return err
+		}
+		return forEachInBucket(tx, src.queueBucketName, func(k string, v []byte) error {
+			var queue []models.ProjectLock
+			if err := json.Unmarshal(v, &queue); err != nil {
+				return errors.Wrapf(err, "deserializing queue at key %q", k)
+			}
+			queues[k] = queue
+			return nil
+		})
+	})
+	if err != nil {
+		return result, errors.Wrap(err, "reading BoltDB")
+	}
+
+	err = dst.transaction(func(tx *sql.Tx) error {
+		for k, lock := range locks {
+			if _, err := tx.Exec(`DELETE FROM locks WHERE lock_key = $1`, k); err != nil {
+				return err
+			}
+			if _, err := dst.insertLock(tx, lock); err != nil {
+				return errors.Wrapf(err, "copying lock at key %q", k)
+			}
+		}
+		for k, pull := range pulls {
+			if err := dst.writePullStatus(tx, k, pull); err != nil {
+				return errors.Wrapf(err, "copying pull at key %q", k)
+			}
+		}
+		for k, queue := range queues {
+			if _, err := tx.Exec(`DELETE FROM lock_queue WHERE lock_key = $1`, k); err != nil {
+				return err
+			}
+			for i, q := range queue {
+				serialized, err := json.Marshal(q)
+				if err != nil {
+					return errors.Wrap(err, "serializing")
+				}
+				if _, err := tx.Exec(`INSERT INTO lock_queue (lock_key, position, repo_full_name, pull_num, data) VALUES ($1, $2, $3, $4, $5)`,
+					k, i+1, q.Project.RepoFullName, q.Pull.Num, string(serialized)); err != nil {
+					return errors.Wrapf(err, "copying queue at key %q", k)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, errors.Wrap(err, "writing SQL database")
+	}
+	result.Locks = len(locks)
+	result.Pulls = len(pulls)
+	result.Queues = len(queues)
+	return result, nil
+}
+
+// forEachInBucket calls fn with each key and value in the bucket. It's a
+// no-op if the bucket doesn't exist.
+func forEachInBucket(tx *bolt.Tx, bucketName []byte, fn func(k string, v []byte) error) error {
+	bucket := tx.Bucket(bucketName)
+	if bucket == nil {
+		return nil
+	}
+	return bucket.ForEach(func(k, v []byte) error {
+		return fn(string(k), v)
+	})
+}
@@ -0,0 +1,602 @@
+package db
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// RedisDB is a database using Redis. Unlike BoltDB, it can be shared by
+// multiple Atlantis servers.
+type RedisDB struct {
+	pool *redis.Pool
+}
+
+const (
+	redisLockPrefix  = "lock/"
+	redisQueuePrefix = "queue/"
+	redisPullPrefix  = "pull/"
+
+	// redisMaxTxAttempts is how many times we try a transaction before giving
+	// up because other clients keep modifying the keys it depends on.
+	redisMaxTxAttempts = 10
+)
+
+// NewRedis returns a RedisDB that uses the Redis server at hostname:port.
+// password can be empty if the server doesn't require auth. database is the
+// number of the Redis database to use. It returns an error if the server
+// can't be reached.
+func NewRedis(hostname string, port int, password string, database int) (*RedisDB, error) {
+	addr := net.JoinHostPort(hostname, strconv.Itoa(port))
+	pool := &redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 4 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr,
+				redis.DialPassword(password),
+				redis.DialDatabase(database),
+				redis.DialConnectTimeout(5*time.Second))
+		},
+	}
+
+	// Check the connection now so that a misconfiguration is caught at
+	// startup rather than on the first pull request.
+	conn := pool.Get()
+	defer conn.Close() // nolint: errcheck
+	if _, err := conn.Do("PING"); err != nil {
+		return nil, errors.Wrapf(err, "connecting to Redis at %s", addr)
+	}
+	return &RedisDB{pool: pool}, nil
+}
+
+// redisCmd is a command to be run in a transaction.
+type redisCmd struct {
+	name string
+	args []interface{}
+}
+
+// TryLock attempts to create a new lock. If the lock is
+// acquired, it will return true and the lock returned will be newLock.
+// If the lock is not acquired, it will return false and the current
+// lock that is preventing this lock from being acquired. If the lock isn't
+// held but other pull requests are queued for it, the lock returned is the
+// first of them.
+func (r *RedisDB) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error) {
+	var lockAcquired bool
+	var currLock models.ProjectLock
+	key := redisLockPrefix + lockKey(newLock.Project, newLock.Workspace)
+	queueKey := redisQueuePrefix + lockKey(newLock.Project, newLock.Workspace)
+	newLockSerialized, err := json.Marshal(newLock)
+	if err != nil {
+		return false, currLock, errors.Wrap(err, "serializing")
+	}
+	err = r.transaction([]string{key, queueKey}, func(values [][]byte) ([]redisCmd, error) {
+		// if there is no run at that key then we're free to create the lock
+		// unless someone else is queued for it
+		if values[0] == nil {
+			queue, err := r.deserializeQueue(queueKey, values[1])
+			if err != nil {
+				return nil, err
+			}
+			acquired, newQueue := acquireFreeLock(queue, newLock)
+			lockAcquired = acquired
+			if !acquired {
+				currLock = queue[0]
+				return nil, nil
+			}
+			currLock = newLock
+			cmds := []redisCmd{{"SET", []interface{}{key, newLockSerialized}}}
+			if len(newQueue) != len(queue) {
+				queueCmd, err := r.writeQueueCmd(queueKey, newQueue)
+				if err != nil {
+					return nil, err
+				}
+				cmds = append(cmds, queueCmd)
+			}
+			return cmds, nil
+		}
+
+		// otherwise the lock fails, return to caller the run that's holding the lock
+		lockAcquired = false
+		return nil, errors.Wrap(json.Unmarshal(values[0], &currLock), "failed to deserialize current lock")
+	})
+	if err != nil {
+		return false, currLock, errors.Wrap(err, "DB transaction failed")
+	}
+	return lockAcquired, currLock, nil
+}
+
+// Unlock attempts to unlock the project and workspace.
+// If there is no lock, then it will return a nil pointer.
+// If there is a lock, then it will delete it, and then return a pointer
+// to the deleted lock.
+func (r *RedisDB) Unlock(p models.Project, workspace string) (*models.ProjectLock, error) {
+	return r.unlockIf(redisLockPrefix+lockKey(p, workspace), func(models.ProjectLock) bool { return true })
+}
+
+// UnlockIfUnchanged deletes the lock for lock's project and workspace only if
+// it's still the same hold as lock, i.e. it wasn't released and taken again
+// since lock was read. It returns the deleted lock or a nil pointer if
+// nothing was deleted.
+func (r *RedisDB) UnlockIfUnchanged(lock models.ProjectLock) (*models.ProjectLock, error) {
+	return r.unlockIf(redisLockPrefix+lockKey(lock.Project, lock.Workspace), func(l models.ProjectLock) bool {
+		return sameLock(l, lock)
+	})
+}
+
+// List lists all current locks.
+func (r *RedisDB) List() ([]models.ProjectLock, error) {
+	var locks []models.ProjectLock
+	keys, err := r.scan(redisLockPrefix + "*")
+	if err != nil {
+		return locks, errors.Wrap(err, "DB transaction failed")
+	}
+	values, err := r.getAll(keys)
+	if err != nil {
+		return locks, errors.Wrap(err, "DB transaction failed")
+	}
+	for i, v := range values {
+		// The lock might have been deleted since we listed the keys.
+		if v == nil {
+			continue
+		}
+		var lock models.ProjectLock
+		if err := json.Unmarshal(v, &lock); err != nil {
+			return locks, errors.Wrapf(err, "failed to deserialize lock at key %q", keys[i])
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// UnlockByPull deletes all locks associated with that pull request and returns them.
+func (r *RedisDB) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	var locks []models.ProjectLock
+	// we can use the repoFullName as a prefix search since that's the first part of the key
+	keys, err := r.scan(redisLockPrefix + redisEscapePattern(repoFullName) + "/*")
+	if err != nil {
+		return locks, err
+	}
+	for _, key := range keys {
+		lock, err := r.unlockIf(key, func(l models.ProjectLock) bool {
+			return l.Project.RepoFullName == repoFullName && l.Pull.Num == pullNum
+		})
+		if err != nil {
+			return locks, errors.Wrapf(err, "unlocking %s", strings.TrimPrefix(key, redisLockPrefix))
+		}
+		if lock != nil {
+			locks = append(locks, *lock)
+		}
+	}
+	return locks, nil
+}
+
+// GetLock returns a pointer to the lock for that project and workspace.
+// If there is no lock, it returns a nil pointer.
+func (r *RedisDB) GetLock(p models.Project, workspace string) (*models.ProjectLock, error) {
+	key := redisLockPrefix + lockKey(p, workspace)
+	values, err := r.getAll([]string{key})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting lock data")
+	}
+	if values[0] == nil {
+		return nil, nil
+	}
+
+	var lock models.ProjectLock
+	if err := json.Unmarshal(values[0], &lock); err != nil {
+		return nil, errors.Wrapf(err, "deserializing lock at key %q", key)
+	}
+
+	// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+	lock.Time = lock.Time.Local()
+	return &lock, nil
+}
+
+// Enqueue adds newLock to the end of the queue of pull requests waiting for
+// the lock on its project and workspace. It returns the 1-based position of
+// newLock's pull request in the queue. If the pull request is already queued,
+// its existing position is returned and the queue is left unchanged. If the
+// lock was released since TryLock failed and newLock's pull request is next
+// in line, newLock acquires the lock instead of being queued and true is
+// returned.
+func (r *RedisDB) Enqueue(newLock models.ProjectLock) (bool, int, error) {
+	var lockAcquired bool
+	var position int
+	lockRedisKey := redisLockPrefix + lockKey(newLock.Project, newLock.Workspace)
+	key := redisQueuePrefix + lockKey(newLock.Project, newLock.Workspace)
+	err := r.transaction([]string{lockRedisKey, key}, func(values [][]byte) ([]redisCmd, error) {
+		lockAcquired = false
+		position = 0
+		queue, err := r.deserializeQueue(key, values[1])
+		if err != nil {
+			return nil, err
+		}
+		var newQueue []models.ProjectLock
+		if values[0] == nil {
+			lockAcquired, newQueue = acquireFreeLock(queue, newLock)
+			if lockAcquired {
+				serialized, err := json.Marshal(newLock)
+				if err != nil {
+					return nil, errors.Wrap(err, "serializing")
+				}
+				cmds := []redisCmd{{"SET", []interface{}{lockRedisKey, serialized}}}
+				if len(newQueue) != len(queue) {
+					queueCmd, err := r.writeQueueCmd(key, newQueue)
+					if err != nil {
+						return nil, err
+					}
+					cmds = append(cmds, queueCmd)
+				}
+				return cmds, nil
+			}
+		}
+		newQueue, position = enqueue(queue, newLock)
+		if len(newQueue) == len(queue) {
+			return nil, nil
+		}
+		cmd, err := r.writeQueueCmd(key, newQueue)
+		return []redisCmd{cmd}, err
+	})
+	return lockAcquired, position, errors.Wrap(err, "DB transaction failed")
+}
+
+// GetQueue returns the pull requests waiting for the lock on project and
+// workspace, in the order they will acquire it.
+func (r *RedisDB) GetQueue(p models.Project, workspace string) ([]models.ProjectLock, error) {
+	key := redisQueuePrefix + lockKey(p, workspace)
+	values, err := r.getAll([]string{key})
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	return r.deserializeQueue(key, values[0])
+}
+
+// DequeueByPull removes the pull request from every queue it's waiting in
+// for repoFullName.
+func (r *RedisDB) DequeueByPull(repoFullName string, pullNum int) error {
+	// The repoFullName is the first part of the key so we can use it as
+	// a prefix search.
+	keys, err := r.scan(redisQueuePrefix + redisEscapePattern(repoFullName) + "/*")
+	if err != nil {
+		return errors.Wrap(err, "DB transaction failed")
+	}
+	for _, key := range keys {
+		err := r.transaction([]string{key}, func(values [][]byte) ([]redisCmd, error) {
+			queue, err := r.deserializeQueue(key, values[0])
+			if err != nil {
+				return nil, err
+			}
+			newQueue := dequeuePull(queue, repoFullName, pullNum)
+			if len(newQueue) == len(queue) {
+				return nil, nil
+			}
+			cmd, err := r.writeQueueCmd(key, newQueue)
+			return []redisCmd{cmd}, err
+		})
+		if err != nil {
+			return errors.Wrap(err, "DB transaction failed")
+		}
+	}
+	return nil
+}
+
+// Dequeue removes the pull request from the queue for the lock on project and
+// workspace.
+func (r *RedisDB) Dequeue(p models.Project, workspace string, pullNum int) error {
+	key := redisQueuePrefix + lockKey(p, workspace)
+	err := r.transaction([]string{key}, func(values [][]byte) ([]redisCmd, error) {
+		queue, err := r.deserializeQueue(key, values[0])
+		if err != nil {
+			return nil, err
+		}
+		newQueue := dequeuePull(queue, p.RepoFullName, pullNum)
+		if len(newQueue) == len(queue) {
+			return nil, nil
+		}
+		cmd, err := r.writeQueueCmd(key, newQueue)
+		return []redisCmd{cmd}, err
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// PromoteNext gives the lock on project and workspace to the first pull
+// request in its queue. It only does so if the lock isn't currently held.
+// It returns a pointer to the new lock or a nil pointer if there was no lock
+// to promote.
+func (r *RedisDB) PromoteNext(p models.Project, workspace string) (*models.ProjectLock, error) {
+	var promoted *models.ProjectLock
+	key := lockKey(p, workspace)
+	lockRedisKey := redisLockPrefix + key
+	queueRedisKey := redisQueuePrefix + key
+	err := r.transaction([]string{lockRedisKey, queueRedisKey}, func(values [][]byte) ([]redisCmd, error) {
+		promoted = nil
+		if values[0] != nil {
+			return nil, nil
+		}
+		queue, err := r.deserializeQueue(queueRedisKey, values[1])
+		if err != nil {
+			return nil, err
+		}
+		if len(queue) == 0 {
+			return nil, nil
+		}
+
+		next := queue[0]
+		next.Time = time.Now().Local()
+		serialized, err := json.Marshal(next)
+		if err != nil {
+			return nil, errors.Wrap(err, "serializing")
+		}
+		queueCmd, err := r.writeQueueCmd(queueRedisKey, queue[1:])
+		if err != nil {
+			return nil, err
+		}
+		promoted = &next
+		return []redisCmd{{"SET", []interface{}{lockRedisKey, serialized}}, queueCmd}, nil
+	})
+	return promoted, errors.Wrap(err, "DB transaction failed")
+}
+
+// UpdatePullWithResults updates pull's status with the latest project results.
+// It returns the new PullStatus object.
+func (r *RedisDB) UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error) {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return models.PullStatus{}, err
+	}
+
+	var newStatus models.PullStatus
+	err = r.transaction([]string{key}, func(values [][]byte) ([]redisCmd, error) {
+		currStatus, err := r.deserializePull(key, values[0])
+		if err != nil {
+			return nil, err
+		}
+		newStatus = mergePullResults(currStatus, pull, newResults)
+
+		// Now, we overwrite the key with our new status.
+		cmd, err := r.writePullCmd(key, newStatus)
+		return []redisCmd{cmd}, err
+	})
+	return newStatus, errors.Wrap(err, "DB transaction failed")
+}
+
+// GetPullStatus returns the status for pull.
+// If there is no status, returns a nil pointer.
+func (r *RedisDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, error) {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return nil, err
+	}
+	values, err := r.getAll([]string{key})
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	return r.deserializePull(key, values[0])
+}
+
+// DeletePullStatus deletes the status for pull.
+func (r *RedisDB) DeletePullStatus(pull models.PullRequest) error {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return err
+	}
+	conn := r.pool.Get()
+	defer conn.Close() // nolint: errcheck
+	_, err = conn.Do("DEL", key)
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteProjectStatus deletes all project statuses under pull that match
+// workspace and repoRelDir.
+func (r *RedisDB) DeleteProjectStatus(pull models.PullRequest, workspace string, repoRelDir string) error {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return err
+	}
+	err = r.transaction([]string{key}, func(values [][]byte) ([]redisCmd, error) {
+		currStatus, err := r.deserializePull(key, values[0])
+		if err != nil {
+			return nil, err
+		}
+		if currStatus == nil {
+			return nil, nil
+		}
+
+		// Overwrite the old pull status.
+		cmd, err := r.writePullCmd(key, deleteProjectStatus(*currStatus, workspace, repoRelDir))
+		return []redisCmd{cmd}, err
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// UpdatePullComments records commentIDs as the IDs of the comments that hold
+// the latest output of command on pull.
+func (r *RedisDB) UpdatePullComments(pull models.PullRequest, command models.CommandName, commentIDs []string) error {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return err
+	}
+	err = r.transaction([]string{key}, func(values [][]byte) ([]redisCmd, error) {
+		currStatus, err := r.deserializePull(key, values[0])
+		if err != nil {
+			return nil, err
+		}
+		cmd, err := r.writePullCmd(key, setPullComments(currStatus, pull, command, commentIDs))
+		return []redisCmd{cmd}, err
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// transaction runs the commands returned by fn atomically. fn is called with
+// the current values of keys, or nil for keys that aren't set. If any of keys
+// are modified by another client before the commands are run, fn is called
+// again with the new values. If fn returns no commands, nothing is run.
+func (r *RedisDB) transaction(keys []string, fn func(values [][]byte) ([]redisCmd, error)) error {
+	conn := r.pool.Get()
+	defer conn.Close() // nolint: errcheck
+
+	for i := 0; i < redisMaxTxAttempts; i++ {
+		if _, err := conn.Do("WATCH", redis.Args{}.AddFlat(keys)...); err != nil {
+			return err
+		}
+		values, err := redis.ByteSlices(conn.Do("MGET", redis.Args{}.AddFlat(keys)...))
+		if err != nil {
+			conn.Do("UNWATCH") // nolint: errcheck
+			return err
+		}
+		cmds, err := fn(values)
+		if err != nil || len(cmds) == 0 {
+			conn.Do("UNWATCH") // nolint: errcheck
+			return err
+		}
+
+		if err := conn.Send("MULTI"); err != nil {
+			return err
+		}
+		for _, c := range cmds {
+			if err := conn.Send(c.name, c.args...); err != nil {
+				return err
+			}
+		}
+		replies, err := redis.Values(conn.Do("EXEC"))
+		// If one of the watched keys was modified then none of the commands
+		// were run. Redis replies with nil in this case but some
+		// implementations reply with an empty array.
+		if err == redis.ErrNil || (err == nil && len(replies) == 0) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, reply := range replies {
+			if replyErr, ok := reply.(redis.Error); ok {
+				return replyErr
+			}
+		}
+		return nil
+	}
+	return errors.Errorf("keys %s were modified concurrently %d times in a row", strings.Join(keys, ", "), redisMaxTxAttempts)
+}
+
+// unlockIf deletes the lock at key if shouldUnlock returns true for it and
+// returns the deleted lock. If there is no lock or it wasn't deleted, it
+// returns a nil pointer.
+func (r *RedisDB) unlockIf(key string, shouldUnlock func(lock models.ProjectLock) bool) (*models.ProjectLock, error) {
+	var deleted *models.ProjectLock
+	err := r.transaction([]string{key}, func(values [][]byte) ([]redisCmd, error) {
+		deleted = nil
+		if values[0] == nil {
+			return nil, nil
+		}
+		var lock models.ProjectLock
+		if err := json.Unmarshal(values[0], &lock); err != nil {
+			return nil, errors.Wrap(err, "failed to deserialize lock")
+		}
+		if !shouldUnlock(lock) {
+			return nil, nil
+		}
+		deleted = &lock
+		return []redisCmd{{"DEL", []interface{}{key}}}, nil
+	})
+	return deleted, errors.Wrap(err, "DB transaction failed")
+}
+
+// scan returns all keys matching pattern.
+func (r *RedisDB) scan(pattern string) ([]string, error) {
+	conn := r.pool.Get()
+	defer conn.Close() // nolint: errcheck
+
+	var keys []string
+	cursor := 0
+	for {
+		reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 100))
+		if err != nil {
+			return nil, err
+		}
+		var page []string
+		if _, err := redis.Scan(reply, &cursor, &page); err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// getAll returns the values of keys, or nil for keys that aren't set.
+func (r *RedisDB) getAll(keys []string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	conn := r.pool.Get()
+	defer conn.Close() // nolint: errcheck
+	return redis.ByteSlices(conn.Do("MGET", redis.Args{}.AddFlat(keys)...))
+}
+
+func (r *RedisDB) pullKey(pull models.PullRequest) (string, error) {
+	key, err := pullKey(pull)
+	return redisPullPrefix + key, err
+}
+
+func (r *RedisDB) deserializePull(key string, serialized []byte) (*models.PullStatus, error) {
+	if serialized == nil {
+		return nil, nil
+	}
+	var p models.PullStatus
+	if err := json.Unmarshal(serialized, &p); err != nil {
+		return nil, errors.Wrapf(err, "deserializing pull at %q with contents %q", key, serialized)
+	}
+	return &p, nil
+}
+
+func (r *RedisDB) deserializeQueue(key string, serialized []byte) ([]models.ProjectLock, error) {
+	if serialized == nil {
+		return nil, nil
+	}
+	var queue []models.ProjectLock
+	if err := json.Unmarshal(serialized, &queue); err != nil {
+		return nil, errors.Wrapf(err, "deserializing queue at %q", key)
+	}
+	return queue, nil
+}
+
+func (r *RedisDB) writeQueueCmd(key string, queue []models.ProjectLock) (redisCmd, error) {
+	if len(queue) == 0 {
+		return redisCmd{"DEL", []interface{}{key}}, nil
+	}
+	serialized, err := json.Marshal(queue)
+	if err != nil {
+		return redisCmd{}, errors.Wrap(err, "serializing")
+	}
+	return redisCmd{"SET", []interface{}{key, serialized}}, nil
+}
+
+func (r *RedisDB) writePullCmd(key string, pull models.PullStatus) (redisCmd, error) {
+	serialized, err := json.Marshal(pull)
+	if err != nil {
+		return redisCmd{}, errors.Wrap(err, "serializing")
+	}
+	return redisCmd{"SET", []interface{}{key, serialized}}, nil
+}
+
+// redisEscapePattern escapes the characters in s that have special meaning
+// in a SCAN MATCH pattern.
+func redisEscapePattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,421 @@
+package db_test
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestNewRedis_CannotConnect(t *testing.T) {
+	t.Log("should error at startup if the server can't be reached")
+	s, err := miniredis.Run()
+	Ok(t, err)
+	host := s.Host()
+	port, err := strconv.Atoi(s.Port())
+	Ok(t, err)
+	s.Close()
+
+	_, err = db.NewRedis(host, port, "", 0)
+	Assert(t, err != nil, "exp error")
+}
+
+func TestNewRedis_Password(t *testing.T) {
+	s, err := miniredis.Run()
+	Ok(t, err)
+	defer s.Close()
+	s.RequireAuth("secret")
+	port, err := strconv.Atoi(s.Port())
+	Ok(t, err)
+
+	_, err = db.NewRedis(s.Host(), port, "wrong", 0)
+	Assert(t, err != nil, "exp error with wrong password")
+	_, err = db.NewRedis(s.Host(), port, "secret", 0)
+	Ok(t, err)
+}
+
+func TestRedis_Locking(t *testing.T) {
+	r, cleanup := newTestRedis(t)
+	defer cleanup()
+
+	t.Log("listing locks when there are none should return an empty list")
+	ls, err := r.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+
+	t.Log("the first lock should be acquired")
+	acquired, currLock, err := r.TryLock(lock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	Equals(t, lock, currLock)
+
+	t.Log("a lock held by another pull should not be acquired")
+	newLock := lock
+	newLock.Pull.Num = pullNum + 1
+	acquired, currLock, err = r.TryLock(newLock)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, pullNum, currLock.Pull.Num)
+
+	t.Log("the same project in a different workspace should be acquired")
+	otherWorkspace := newLock
+	otherWorkspace.Workspace = "staging"
+	acquired, _, err = r.TryLock(otherWorkspace)
+	Ok(t, err)
+	Equals(t, true, acquired)
+
+	ls, err = r.List()
+	Ok(t, err)
+	Equals(t, 2, len(ls))
+
+	l, err := r.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, l != nil, "exp lock")
+	Equals(t, pullNum, l.Pull.Num)
+	Equals(t, lock.User, l.User)
+
+	t.Log("unlocking should return the deleted lock")
+	deleted, err := r.Unlock(project, workspace)
+	Ok(t, err)
+	Assert(t, deleted != nil, "exp deleted lock")
+	Equals(t, pullNum, deleted.Pull.Num)
+	l, err = r.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, l == nil, "exp lock to be deleted")
+
+	t.Log("unlocking when there is no lock should return nil")
+	deleted, err = r.Unlock(project, workspace)
+	Ok(t, err)
+	Assert(t, deleted == nil, "exp nil")
+
+	t.Log("the lock should now be acquired by the other pull")
+	acquired, _, err = r.TryLock(newLock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+}
+
+func TestRedis_TryLockConcurrent(t *testing.T) {
+	t.Log("only one of many concurrent attempts should acquire the lock")
+	r, cleanup := newTestRedis(t)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	var acquiredCount int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(num int) {
+			defer wg.Done()
+			newLock := lock
+			newLock.Pull.Num = num
+			acquired, _, err := r.TryLock(newLock)
+			Ok(t, err)
+			if acquired {
+				atomic.AddInt32(&acquiredCount, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	Equals(t, int32(1), acquiredCount)
+}
+
+func TestRedis_UnlockIfUnchanged(t *testing.T) {
+	r, cleanup := newTestRedis(t)
+	defer cleanup()
+
+	_, _, err := r.TryLock(lock)
+	Ok(t, err)
+
+	t.Log("a lock held by another pull shouldn't be unlocked")
+	otherPull := lock
+	otherPull.Pull.Num = pullNum + 1
+	deleted, err := r.UnlockIfUnchanged(otherPull)
+	Ok(t, err)
+	Assert(t, deleted == nil, "exp lock not to be deleted")
+
+	t.Log("a lock the same pull took at another time shouldn't be unlocked")
+	otherTime := lock
+	otherTime.Time = lock.Time.Add(-time.Hour)
+	deleted, err = r.UnlockIfUnchanged(otherTime)
+	Ok(t, err)
+	Assert(t, deleted == nil, "exp lock not to be deleted")
+
+	t.Log("the same lock should be unlocked")
+	deleted, err = r.UnlockIfUnchanged(lock)
+	Ok(t, err)
+	Assert(t, deleted != nil, "exp lock to be deleted")
+	Equals(t, pullNum, deleted.Pull.Num)
+	ls, err := r.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+}
+
+func TestRedis_UnlockByPull(t *testing.T) {
+	r, cleanup := newTestRedis(t)
+	defer cleanup()
+
+	_, _, err := r.TryLock(lock)
+	Ok(t, err)
+	otherPath := lock
+	otherPath.Project = models.NewProject(project.RepoFullName, "other/path")
+	_, _, err = r.TryLock(otherPath)
+	Ok(t, err)
+	otherPull := lock
+	otherPull.Workspace = "staging"
+	otherPull.Pull.Num = pullNum + 1
+	_, _, err = r.TryLock(otherPull)
+	Ok(t, err)
+	otherRepo := lock
+	otherRepo.Project = models.NewProject("owner/repo2", "parent/child")
+	_, _, err = r.TryLock(otherRepo)
+	Ok(t, err)
+	t.Log("a repo that has the same prefix should not be matched")
+	prefixRepo := lock
+	prefixRepo.Project = models.NewProject(project.RepoFullName+"-other", "parent/child")
+	_, _, err = r.TryLock(prefixRepo)
+	Ok(t, err)
+
+	deleted, err := r.UnlockByPull(project.RepoFullName, pullNum)
+	Ok(t, err)
+	Equals(t, 2, len(deleted))
+
+	ls, err := r.List()
+	Ok(t, err)
+	Equals(t, 3, len(ls))
+	for _, l := range ls {
+		Assert(t, l.Project.RepoFullName != project.RepoFullName || l.Pull.Num != pullNum, "exp lock %v to be deleted", l)
+	}
+}
+
+func TestRedis_Queue(t *testing.T) {
+	r, cleanup := newTestRedis(t)
+	defer cleanup()
+
+	_, _, err := r.TryLock(lock)
+	Ok(t, err)
+
+	t.Log("enqueuing should return each pull's position in the queue")
+	for i := 1; i <= 3; i++ {
+		queued := lock
+		queued.Pull.Num = pullNum + i
+		acquired, pos, err := r.Enqueue(queued)
+		Ok(t, err)
+		Equals(t, false, acquired)
+		Equals(t, i, pos)
+	}
+	queued := lock
+	queued.Pull.Num = pullNum + 2
+	_, pos, err := r.Enqueue(queued)
+	Ok(t, err)
+	Equals(t, 2, pos)
+	otherRepo := queued
+	otherRepo.Project = models.NewProject("owner/repo2", "parent/child")
+	otherRepoLock := lock
+	otherRepoLock.Project = otherRepo.Project
+	_, _, err = r.TryLock(otherRepoLock)
+	Ok(t, err)
+	_, _, err = r.Enqueue(otherRepo)
+	Ok(t, err)
+
+	t.Log("dequeuing should only remove the matching pull from its repo's queues")
+	Ok(t, r.DequeueByPull(project.RepoFullName, pullNum+2))
+	queue, err := r.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 2, len(queue))
+	Equals(t, pullNum+1, queue[0].Pull.Num)
+	Equals(t, pullNum+3, queue[1].Pull.Num)
+	queue, err = r.GetQueue(otherRepo.Project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+
+	t.Log("dequeuing from one project should leave the pull's other queues")
+	Ok(t, r.Dequeue(otherRepo.Project, workspace, pullNum+2))
+	queue, err = r.GetQueue(otherRepo.Project, workspace)
+	Ok(t, err)
+	Equals(t, 0, len(queue))
+	queue, err = r.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 2, len(queue))
+
+	t.Log("nothing is promoted while the lock is held")
+	promoted, err := r.PromoteNext(project, workspace)
+	Ok(t, err)
+	Assert(t, promoted == nil, "exp nothing to be promoted")
+
+	t.Log("a free lock can't be taken by a pull that isn't first in the queue")
+	_, err = r.Unlock(project, workspace)
+	Ok(t, err)
+	other := lock
+	other.Pull.Num = pullNum + 4
+	acquired, curr, err := r.TryLock(other)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, pullNum+1, curr.Pull.Num)
+	acquired, pos, err = r.Enqueue(other)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, 3, pos)
+	Ok(t, r.Dequeue(project, workspace, pullNum+4))
+
+	t.Log("the first queued pull gets the lock after it's released")
+	promoted, err = r.PromoteNext(project, workspace)
+	Ok(t, err)
+	Assert(t, promoted != nil, "exp lock to be promoted")
+	Equals(t, pullNum+1, promoted.Pull.Num)
+	held, err := r.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, held != nil, "exp lock to be held")
+	Equals(t, pullNum+1, held.Pull.Num)
+	queue, err = r.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+	Equals(t, pullNum+3, queue[0].Pull.Num)
+
+	t.Log("enqueuing takes the lock if it's free and nobody is queued")
+	_, err = r.Unlock(project, workspace)
+	Ok(t, err)
+	Ok(t, r.Dequeue(project, workspace, pullNum+3))
+	acquired, _, err = r.Enqueue(queued)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	held, err = r.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, held != nil, "exp lock to be held")
+	Equals(t, pullNum+2, held.Pull.Num)
+}
+
+func TestRedis_PullStatus(t *testing.T) {
+	r, cleanup := newTestRedis(t)
+	defer cleanup()
+
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		URL:        "url",
+		HeadBranch: "head",
+		BaseBranch: "base",
+		Author:     "lkysow",
+		State:      models.OpenPullState,
+		BaseRepo: models.Repo{
+			FullName:          "runatlantis/atlantis",
+			Owner:             "runatlantis",
+			Name:              "atlantis",
+			CloneURL:          "clone-url",
+			SanitizedCloneURL: "clone-url",
+			VCSHost: models.VCSHost{
+				Hostname: "github.com",
+				Type:     models.Github,
+			},
+		},
+	}
+
+	t.Log("there should be no status before it's updated")
+	status, err := r.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status == nil, "exp nil")
+
+	_, err = r.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+		{
+			Command:    models.PlanCommand,
+			RepoRelDir: "other",
+			Workspace:  "default",
+			Error:      errors.New("err"),
+		},
+	})
+	Ok(t, err)
+
+	t.Log("results at the same commit should be merged")
+	updated, err := r.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:      models.ApplyCommand,
+			RepoRelDir:   ".",
+			Workspace:    "default",
+			ApplySuccess: "applied",
+		},
+	})
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{
+			Workspace:  "default",
+			RepoRelDir: ".",
+			Status:     models.AppliedPlanStatus,
+		},
+		{
+			Workspace:  "default",
+			RepoRelDir: "other",
+			Status:     models.ErroredPlanStatus,
+		},
+	}, updated.Projects)
+
+	status, err = r.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status != nil, "exp status")
+	Equals(t, updated, *status)
+
+	t.Log("deleting a project status should leave the others")
+	Ok(t, r.DeleteProjectStatus(pull, "default", "."))
+	status, err = r.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, 1, len(status.Projects))
+	Equals(t, "other", status.Projects[0].RepoRelDir)
+
+	t.Log("deleting the pull status should delete it all")
+	Ok(t, r.DeletePullStatus(pull))
+	status, err = r.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status == nil, "exp nil")
+}
+
+func TestRedis_PullComments(t *testing.T) {
+	r, cleanup := newTestRedis(t)
+	defer cleanup()
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			VCSHost: models.VCSHost{
+				Hostname: "github.com",
+				Type:     models.Github,
+			},
+		},
+	}
+
+	Ok(t, r.UpdatePullComments(pull, models.PlanCommand, []string{"1", "2"}))
+	pull.HeadCommit = "newsha"
+	_, err := r.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+	})
+	Ok(t, err)
+	status, err := r.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, 1, len(status.Projects))
+	Equals(t, map[string][]string{"plan": {"1", "2"}}, status.Comments)
+}
+
+func newTestRedis(t *testing.T) (*db.RedisDB, func()) {
+	s, err := miniredis.Run()
+	Ok(t, err)
+	port, err := strconv.Atoi(s.Port())
+	Ok(t, err)
+	r, err := db.NewRedis(s.Host(), port, "", 0)
+	Ok(t, err)
+	return r, s.Close
+}
@@ -0,0 +1,739 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	// Register the drivers for the SQL databases we support.
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// SQLDB is a database using SQLite or Postgres. Besides what the other
+// databases store, it also keeps a history of every project run so it can be
+// queried.
+type SQLDB struct {
+	db     *sql.DB
+	driver string
+}
+
+const (
+	// SQLiteDriver is the driver name for SQLite databases.
+	SQLiteDriver = "sqlite3"
+	// PostgresDriver is the driver name for Postgres databases.
+	PostgresDriver = "postgres"
+
+	// sqlMaxLockAttempts is how many times TryLock retries if the lock is
+	// deleted between failing to create it and reading the current lock. It's
+	// also how many times Enqueue retries if another server adds to the same
+	// queue at the same time.
+	sqlMaxLockAttempts = 10
+
+	// sqlMigrationLockID is the key of the Postgres advisory lock held while
+	// migrating so that servers starting at the same time don't run the same
+	// migrations.
+	sqlMigrationLockID = 7461746
+)
+
+// sqlMigrations are the statements that create and update the schema. Each
+// element is one version of the schema and is applied in a transaction.
+// Released migrations must never be modified, only appended to.
+var sqlMigrations = [][]string{
+	{
+		`CREATE TABLE locks (
+			lock_key TEXT PRIMARY KEY,
+			repo_full_name TEXT NOT NULL,
+			path TEXT NOT NULL,
+			workspace TEXT NOT NULL,
+			pull_num INTEGER NOT NULL,
+			username TEXT NOT NULL,
+			locked_at TIMESTAMP NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE INDEX locks_repo_pull ON locks (repo_full_name, pull_num)`,
+		`CREATE TABLE lock_queue (
+			lock_key TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			repo_full_name TEXT NOT NULL,
+			pull_num INTEGER NOT NULL,
+			data TEXT NOT NULL,
+			PRIMARY KEY (lock_key, pull_num),
+			UNIQUE (lock_key, position)
+		)`,
+		`CREATE INDEX lock_queue_repo_pull ON lock_queue (repo_full_name, pull_num)`,
+		`CREATE TABLE pull_statuses (
+			pull_key TEXT PRIMARY KEY,
+			repo_full_name TEXT NOT NULL,
+			pull_num INTEGER NOT NULL,
+			head_commit TEXT NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE TABLE project_statuses (
+			pull_key TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			workspace TEXT NOT NULL,
+			repo_rel_dir TEXT NOT NULL,
+			project_name TEXT NOT NULL,
+			status TEXT NOT NULL,
+			PRIMARY KEY (pull_key, position)
+		)`,
+		`CREATE TABLE project_runs (
+			repo_full_name TEXT NOT NULL,
+			pull_num INTEGER NOT NULL,
+			head_commit TEXT NOT NULL,
+			workspace TEXT NOT NULL,
+			repo_rel_dir TEXT NOT NULL,
+			project_name TEXT NOT NULL,
+			command TEXT NOT NULL,
+			status TEXT NOT NULL,
+			ran_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX project_runs_repo_pull ON project_runs (repo_full_name, pull_num)`,
+	},
+	{
+		`CREATE TABLE pull_comments (
+			pull_key TEXT NOT NULL,
+			command TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			comment_id TEXT NOT NULL,
+			PRIMARY KEY (pull_key, command, position)
+		)`,
+	},
+	{
+		`ALTER TABLE project_statuses ADD COLUMN plan_summary TEXT`,
+	},
+}
+
+// NewSQL returns a SQLDB using the database at dataSourceName. driver must
+// be SQLiteDriver or PostgresDriver. Any schema migrations that haven't been
+// run yet are run before it returns.
+func NewSQL(driver string, dataSourceName string) (*SQLDB, error) {
+	if driver != SQLiteDriver && driver != PostgresDriver {
+		return nil, fmt.Errorf("unsupported SQL driver %q", driver)
+	}
+	db, err := sql.Open(driver, dataSourceName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening %s database", driver)
+	}
+	if driver == SQLiteDriver {
+		// SQLite only supports one writer at a time so we serialize our
+		// connections rather than erroring with "database is locked".
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, errors.Wrapf(err, "connecting to %s database", driver)
+	}
+	s := &SQLDB{db: db, driver: driver}
+	if err := s.migrate(); err != nil {
+		return nil, errors.Wrap(err, "migrating database schema")
+	}
+	return s, nil
+}
+
+// TryLock attempts to create a new lock. If the lock is
+// acquired, it will return true and the lock returned will be newLock.
+// If the lock is not acquired, it will return false and the current
+// lock that is preventing this lock from being acquired. If the lock isn't
+// held but other pull requests are queued for it, the lock returned is the
+// first of them.
+func (s *SQLDB) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error) {
+	key := lockKey(newLock.Project, newLock.Workspace)
+	for i := 0; i < sqlMaxLockAttempts; i++ {
+		var lockAcquired bool
+		var currLock *models.ProjectLock
+		err := s.transaction(func(tx *sql.Tx) error {
+			// if the lock is held, return to caller the run that's holding the lock
+			var err error
+			currLock, err = s.getLock(tx, key)
+			if err != nil || currLock != nil {
+				return err
+			}
+			lockAcquired, currLock, err = s.acquireFreeLock(tx, newLock)
+			return err
+		})
+		if err != nil {
+			return false, models.ProjectLock{}, errors.Wrap(err, "DB transaction failed")
+		}
+		if lockAcquired {
+			return true, newLock, nil
+		}
+		// If the lock was created after we checked for it, we try again.
+		if currLock != nil {
+			return false, *currLock, nil
+		}
+	}
+	return false, models.ProjectLock{}, fmt.Errorf("lock %q was modified concurrently %d times in a row", key, sqlMaxLockAttempts)
+}
+
+// Unlock attempts to unlock the project and workspace.
+// If there is no lock, then it will return a nil pointer.
+// If there is a lock, then it will delete it, and then return a pointer
+// to the deleted lock.
+func (s *SQLDB) Unlock(p models.Project, workspace string) (*models.ProjectLock, error) {
+	key := lockKey(p, workspace)
+	var lock *models.ProjectLock
+	err := s.transaction(func(tx *sql.Tx) error {
+		var err error
+		lock, err = s.getLock(tx, key)
+		if err != nil || lock == nil {
+			return err
+		}
+		res, err := tx.Exec(`DELETE FROM locks WHERE lock_key = $1`, key)
+		if err != nil {
+			return err
+		}
+		// Another server might have deleted it since we read it.
+		if deleted, err := res.RowsAffected(); err != nil || deleted == 0 {
+			lock = nil
+			return err
+		}
+		return nil
+	})
+	return lock, errors.Wrap(err, "DB transaction failed")
+}
+
+// UnlockIfUnchanged deletes the lock for lock's project and workspace only if
+// it's still the same hold as lock, i.e. it wasn't released and taken again
+// since lock was read. It returns the deleted lock or a nil pointer if
+// nothing was deleted.
+func (s *SQLDB) UnlockIfUnchanged(lock models.ProjectLock) (*models.ProjectLock, error) {
+	key := lockKey(lock.Project, lock.Workspace)
+	// On Postgres, we lock the row so that it can't be replaced between
+	// comparing it and deleting it.
+	currLockQuery := `SELECT lock_key, data FROM locks WHERE lock_key = $1`
+	if s.driver == PostgresDriver {
+		currLockQuery += ` FOR UPDATE`
+	}
+	var deleted *models.ProjectLock
+	err := s.transaction(func(tx *sql.Tx) error {
+		deleted = nil
+		currLock, err := s.queryLocks(tx, currLockQuery, key)
+		if err != nil || len(currLock) == 0 || !sameLock(currLock[0], lock) {
+			return err
+		}
+		res, err := tx.Exec(`DELETE FROM locks WHERE lock_key = $1`, key)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		deleted = &currLock[0]
+		return nil
+	})
+	return deleted, errors.Wrap(err, "DB transaction failed")
+}
+
+// List lists all current locks.
+func (s *SQLDB) List() ([]models.ProjectLock, error) {
+	locks, err := s.queryLocks(s.db, `SELECT lock_key, data FROM locks ORDER BY lock_key`)
+	return locks, errors.Wrap(err, "DB transaction failed")
+}
+
+// UnlockByPull deletes all locks associated with that pull request and returns them.
+func (s *SQLDB) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	var locks []models.ProjectLock
+	err := s.transaction(func(tx *sql.Tx) error {
+		var err error
+		locks, err = s.queryLocks(tx, `SELECT lock_key, data FROM locks WHERE repo_full_name = $1 AND pull_num = $2 ORDER BY lock_key`, repoFullName, pullNum)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM locks WHERE repo_full_name = $1 AND pull_num = $2`, repoFullName, pullNum)
+		return err
+	})
+	return locks, errors.Wrap(err, "DB transaction failed")
+}
+
+// GetLock returns a pointer to the lock for that project and workspace.
+// If there is no lock, it returns a nil pointer.
+func (s *SQLDB) GetLock(p models.Project, workspace string) (*models.ProjectLock, error) {
+	lock, err := s.getLock(s.db, lockKey(p, workspace))
+	if err != nil {
+		return nil, errors.Wrap(err, "getting lock data")
+	}
+	if lock != nil {
+		// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+		lock.Time = lock.Time.Local()
+	}
+	return lock, nil
+}
+
+// Enqueue adds newLock to the end of the queue of pull requests waiting for
+// the lock on its project and workspace. It returns the 1-based position of
+// newLock's pull request in the queue. If the pull request is already queued,
+// its existing position is returned and the queue is left unchanged. If the
+// lock was released since TryLock failed and newLock's pull request is next
+// in line, newLock acquires the lock instead of being queued and true is
+// returned.
+func (s *SQLDB) Enqueue(newLock models.ProjectLock) (bool, int, error) {
+	key := lockKey(newLock.Project, newLock.Workspace)
+	serialized, err := json.Marshal(newLock)
+	if err != nil {
+		return false, 0, errors.Wrap(err, "serializing")
+	}
+	// On Postgres, we lock the row of the current lock so that it can't be
+	// released, and the queue checked for the next pull request, until this
+	// pull request is queued. SQLite only has one connection so transactions
+	// can't interleave.
+	currLockQuery := `SELECT lock_key, data FROM locks WHERE lock_key = $1`
+	if s.driver == PostgresDriver {
+		currLockQuery += ` FOR UPDATE`
+	}
+	for i := 0; i < sqlMaxLockAttempts; i++ {
+		var lockAcquired bool
+		var position int
+		err := s.transaction(func(tx *sql.Tx) error {
+			currLock, err := s.queryLocks(tx, currLockQuery, key)
+			if err != nil {
+				return err
+			}
+			if len(currLock) == 0 {
+				var nextLock *models.ProjectLock
+				lockAcquired, nextLock, err = s.acquireFreeLock(tx, newLock)
+				// If another pull request took the lock at the same time, we
+				// try again so that we lock its row.
+				if err != nil || lockAcquired || nextLock == nil {
+					return err
+				}
+			}
+
+			var queuePos int
+			err = tx.QueryRow(`SELECT position FROM lock_queue WHERE lock_key = $1 AND pull_num = $2`, key, newLock.Pull.Num).Scan(&queuePos)
+			if err == sql.ErrNoRows {
+				if err := tx.QueryRow(`SELECT COALESCE(MAX(position), 0) + 1 FROM lock_queue WHERE lock_key = $1`, key).Scan(&queuePos); err != nil {
+					return err
+				}
+				// If another server queued a pull request at the same time,
+				// it might have taken this position, or queued this pull
+				// request, so we insert nothing and try again.
+				res, err := tx.Exec(`INSERT INTO lock_queue (lock_key, position, repo_full_name, pull_num, data) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+					key, queuePos, newLock.Project.RepoFullName, newLock.Pull.Num, string(serialized))
+				if err != nil {
+					return err
+				}
+				if inserted, err := res.RowsAffected(); err != nil || inserted == 0 {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+
+			// Positions have gaps once pull requests leave the queue so we
+			// count the pull requests ahead of this one.
+			return tx.QueryRow(`SELECT COUNT(*) FROM lock_queue WHERE lock_key = $1 AND position <= $2`, key, queuePos).Scan(&position)
+		})
+		if err != nil {
+			return false, 0, errors.Wrap(err, "DB transaction failed")
+		}
+		if lockAcquired || position > 0 {
+			return lockAcquired, position, nil
+		}
+	}
+	return false, 0, fmt.Errorf("queue %q was modified concurrently %d times in a row", key, sqlMaxLockAttempts)
+}
+
+// GetQueue returns the pull requests waiting for the lock on project and
+// workspace, in the order they will acquire it.
+func (s *SQLDB) GetQueue(p models.Project, workspace string) ([]models.ProjectLock, error) {
+	queue, err := s.queryLocks(s.db, `SELECT lock_key, data FROM lock_queue WHERE lock_key = $1 ORDER BY position`, lockKey(p, workspace))
+	return queue, errors.Wrap(err, "DB transaction failed")
+}
+
+// DequeueByPull removes the pull request from every queue it's waiting in
+// for repoFullName.
+func (s *SQLDB) DequeueByPull(repoFullName string, pullNum int) error {
+	_, err := s.db.Exec(`DELETE FROM lock_queue WHERE repo_full_name = $1 AND pull_num = $2`, repoFullName, pullNum)
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// Dequeue removes the pull request from the queue for the lock on project and
+// workspace.
+func (s *SQLDB) Dequeue(p models.Project, workspace string, pullNum int) error {
+	_, err := s.db.Exec(`DELETE FROM lock_queue WHERE lock_key = $1 AND pull_num = $2`, lockKey(p, workspace), pullNum)
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// PromoteNext gives the lock on project and workspace to the first pull
+// request in its queue. It only does so if the lock isn't currently held.
+// It returns a pointer to the new lock or a nil pointer if there was no lock
+// to promote.
+func (s *SQLDB) PromoteNext(p models.Project, workspace string) (*models.ProjectLock, error) {
+	key := lockKey(p, workspace)
+	var promoted *models.ProjectLock
+	err := s.transaction(func(tx *sql.Tx) error {
+		queue, err := s.queryLocks(tx, `SELECT lock_key, data FROM lock_queue WHERE lock_key = $1 ORDER BY position LIMIT 1`, key)
+		if err != nil || len(queue) == 0 {
+			return err
+		}
+
+		next := queue[0]
+		next.Time = time.Now().Local()
+		// If the lock is held, this does nothing.
+		acquired, err := s.insertLock(tx, next)
+		if err != nil || !acquired {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM lock_queue WHERE lock_key = $1 AND pull_num = $2`, key, next.Pull.Num); err != nil {
+			return err
+		}
+		promoted = &next
+		return nil
+	})
+	return promoted, errors.Wrap(err, "DB transaction failed")
+}
+
+// UpdatePullWithResults updates pull's status with the latest project results.
+// It returns the new PullStatus object. Each result is also recorded in the
+// project_runs table.
+func (s *SQLDB) UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error) {
+	key, err := pullKey(pull)
+	if err != nil {
+		return models.PullStatus{}, err
+	}
+
+	var newStatus models.PullStatus
+	err = s.transaction(func(tx *sql.Tx) error {
+		currStatus, err := s.getPullStatus(tx, key)
+		if err != nil {
+			return err
+		}
+		newStatus = mergePullResults(currStatus, pull, newResults)
+		if err := s.writePullStatus(tx, key, newStatus); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, r := range newResults {
+			if _, err := tx.Exec(`INSERT INTO project_runs (repo_full_name, pull_num, head_commit, workspace, repo_rel_dir, project_name, command, status, ran_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+				pull.BaseRepo.FullName, pull.Num, pull.HeadCommit, r.Workspace, r.RepoRelDir, r.ProjectName, r.Command.String(), r.PlanStatus().String(), now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return newStatus, errors.Wrap(err, "DB transaction failed")
+}
+
+// GetPullStatus returns the status for pull.
+// If there is no status, returns a nil pointer.
+func (s *SQLDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, error) {
+	key, err := pullKey(pull)
+	if err != nil {
+		return nil, err
+	}
+	var status *models.PullStatus
+	err = s.transaction(func(tx *sql.Tx) error {
+		var txErr error
+		status, txErr = s.getPullStatus(tx, key)
+		return txErr
+	})
+	return status, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeletePullStatus deletes the status for pull.
+func (s *SQLDB) DeletePullStatus(pull models.PullRequest) error {
+	key, err := pullKey(pull)
+	if err != nil {
+		return err
+	}
+	err = s.transaction(func(tx *sql.Tx) error {
+		return s.deletePullStatus(tx, key)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteProjectStatus deletes all project statuses under pull that match
+// workspace and repoRelDir.
+func (s *SQLDB) DeleteProjectStatus(pull models.PullRequest, workspace string, repoRelDir string) error {
+	key, err := pullKey(pull)
+	if err != nil {
+		return err
+	}
+	err = s.transaction(func(tx *sql.Tx) error {
+		currStatus, err := s.getPullStatus(tx, key)
+		if err != nil || currStatus == nil {
+			return err
+		}
+		return s.writePullStatus(tx, key, deleteProjectStatus(*currStatus, workspace, repoRelDir))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// UpdatePullComments records commentIDs as the IDs of the comments that hold
+// the latest output of command on pull.
+func (s *SQLDB) UpdatePullComments(pull models.PullRequest, command models.CommandName, commentIDs []string) error {
+	key, err := pullKey(pull)
+	if err != nil {
+		return err
+	}
+	err = s.transaction(func(tx *sql.Tx) error {
+		currStatus, err := s.getPullStatus(tx, key)
+		if err != nil {
+			return err
+		}
+		return s.writePullStatus(tx, key, setPullComments(currStatus, pull, command, commentIDs))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// sqlQuerier is implemented by both *sql.DB and *sql.Tx.
+type sqlQuerier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// migrate runs the migrations in sqlMigrations that haven't been run yet.
+func (s *SQLDB) migrate() error {
+	if s.driver == PostgresDriver {
+		// Advisory locks are held by the session so we keep one connection
+		// for the lock while the migrations use the others.
+		ctx := context.Background()
+		conn, err := s.db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close() // nolint: errcheck
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, sqlMigrationLockID); err != nil {
+			return errors.Wrap(err, "locking migrations")
+		}
+		defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, sqlMigrationLockID) // nolint: errcheck
+	}
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+	var version int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(sqlMigrations); i++ {
+		err := s.transaction(func(tx *sql.Tx) error {
+			for _, stmt := range sqlMigrations[i] {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, i+1)
+			return err
+		})
+		if err != nil {
+			return errors.Wrapf(err, "running migration %d", i+1)
+		}
+	}
+	return nil
+}
+
+// transaction runs fn in a transaction. The transaction is committed if fn
+// returns nil and rolled back otherwise.
+func (s *SQLDB) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertLock creates lock if there is no lock for its project and workspace.
+// It returns true if lock was created.
+func (s *SQLDB) insertLock(tx *sql.Tx, lock models.ProjectLock) (bool, error) {
+	serialized, err := json.Marshal(lock)
+	if err != nil {
+		return false, errors.Wrap(err, "serializing")
+	}
+	res, err := tx.Exec(`INSERT INTO locks (lock_key, repo_full_name, path, workspace, pull_num, username, locked_at, data) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (lock_key) DO NOTHING`,
+		lockKey(lock.Project, lock.Workspace), lock.Project.RepoFullName, lock.Project.Path, lock.Workspace, lock.Pull.Num, lock.User.Username, lock.Time, string(serialized))
+	if err != nil {
+		return false, err
+	}
+	inserted, err := res.RowsAffected()
+	return inserted == 1, err
+}
+
+// acquireFreeLock creates lock if there is no lock for its project and
+// workspace and no other pull request is ahead of it in the lock's queue. It
+// returns true if lock was created. Otherwise, if other pull requests are
+// queued, it returns the first of them. If lock's pull request was first in
+// the queue, it's removed from the queue.
+func (s *SQLDB) acquireFreeLock(tx *sql.Tx, lock models.ProjectLock) (bool, *models.ProjectLock, error) {
+	key := lockKey(lock.Project, lock.Workspace)
+	queue, err := s.queryLocks(tx, `SELECT lock_key, data FROM lock_queue WHERE lock_key = $1 ORDER BY position LIMIT 1`, key)
+	if err != nil {
+		return false, nil, err
+	}
+	if len(queue) > 0 && queue[0].Pull.Num != lock.Pull.Num {
+		return false, &queue[0], nil
+	}
+	acquired, err := s.insertLock(tx, lock)
+	if err != nil || !acquired || len(queue) == 0 {
+		return acquired, nil, err
+	}
+	_, err = tx.Exec(`DELETE FROM lock_queue WHERE lock_key = $1 AND pull_num = $2`, key, lock.Pull.Num)
+	return true, nil, err
+}
+
+// getLock returns the lock at key or a nil pointer if there is no lock.
+func (s *SQLDB) getLock(q sqlQuerier, key string) (*models.ProjectLock, error) {
+	locks, err := s.queryLocks(q, `SELECT lock_key, data FROM locks WHERE lock_key = $1`, key)
+	if err != nil || len(locks) == 0 {
+		return nil, err
+	}
+	return &locks[0], nil
+}
+
+// queryLocks runs query, which must select the lock_key and data columns,
+// and returns the deserialized locks.
+func (s *SQLDB) queryLocks(q sqlQuerier, query string, args ...interface{}) ([]models.ProjectLock, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+
+	var locks []models.ProjectLock
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			return nil, err
+		}
+		var lock models.ProjectLock
+		if err := json.Unmarshal([]byte(data), &lock); err != nil {
+			return nil, errors.Wrapf(err, "deserializing lock at key %q", key)
+		}
+		locks = append(locks, lock)
+	}
+	return locks, rows.Err()
+}
+
+// getPullStatus returns the status of the pull at key or a nil pointer if
+// there is no status.
+func (s *SQLDB) getPullStatus(tx *sql.Tx, key string) (*models.PullStatus, error) {
+	var data string
+	err := tx.QueryRow(`SELECT data FROM pull_statuses WHERE pull_key = $1`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status models.PullStatus
+	if err := json.Unmarshal([]byte(data), &status.Pull); err != nil {
+		return nil, errors.Wrapf(err, "deserializing pull at %q with contents %q", key, data)
+	}
+
+	rows, err := tx.Query(`SELECT workspace, repo_rel_dir, project_name, status, plan_summary FROM project_statuses WHERE pull_key = $1 ORDER BY position`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	for rows.Next() {
+		var p models.ProjectStatus
+		var planStatus string
+		var planSummary sql.NullString
+		if err := rows.Scan(&p.Workspace, &p.RepoRelDir, &p.ProjectName, &planStatus, &planSummary); err != nil {
+			return nil, err
+		}
+		if p.Status, err = parsePlanStatus(planStatus); err != nil {
+			return nil, errors.Wrapf(err, "deserializing pull at %q", key)
+		}
+		if planSummary.Valid {
+			p.PlanSummary = &models.PlanSummary{}
+			if err := json.Unmarshal([]byte(planSummary.String), p.PlanSummary); err != nil {
+				return nil, errors.Wrapf(err, "deserializing plan summary of pull at %q", key)
+			}
+		}
+		status.Projects = append(status.Projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	commentRows, err := tx.Query(`SELECT command, comment_id FROM pull_comments WHERE pull_key = $1 ORDER BY command, position`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer commentRows.Close() // nolint: errcheck
+	for commentRows.Next() {
+		var command, commentID string
+		if err := commentRows.Scan(&command, &commentID); err != nil {
+			return nil, err
+		}
+		if status.Comments == nil {
+			status.Comments = make(map[string][]string)
+		}
+		status.Comments[command] = append(status.Comments[command], commentID)
+	}
+	return &status, commentRows.Err()
+}
+
+// writePullStatus overwrites the status of the pull at key with status.
+func (s *SQLDB) writePullStatus(tx *sql.Tx, key string, status models.PullStatus) error {
+	if err := s.deletePullStatus(tx, key); err != nil {
+		return err
+	}
+	serialized, err := json.Marshal(status.Pull)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	if _, err := tx.Exec(`INSERT INTO pull_statuses (pull_key, repo_full_name, pull_num, head_commit, data) VALUES ($1, $2, $3, $4, $5)`,
+		key, status.Pull.BaseRepo.FullName, status.Pull.Num, status.Pull.HeadCommit, string(serialized)); err != nil {
+		return err
+	}
+	for i, p := range status.Projects {
+		var planSummary sql.NullString
+		if p.PlanSummary != nil {
+			serialized, err := json.Marshal(p.PlanSummary)
+			if err != nil {
+				return errors.Wrap(err, "serializing plan summary")
+			}
+			planSummary = sql.NullString{String: string(serialized), Valid: true}
+		}
+		if _, err := tx.Exec(`INSERT INTO project_statuses (pull_key, position, workspace, repo_rel_dir, project_name, status, plan_summary) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			key, i, p.Workspace, p.RepoRelDir, p.ProjectName, p.Status.String(), planSummary); err != nil {
+			return err
+		}
+	}
+	for command, ids := range status.Comments {
+		for i, id := range ids {
+			if _, err := tx.Exec(`INSERT INTO pull_comments (pull_key, command, position, comment_id) VALUES ($1, $2, $3, $4)`,
+				key, command, i, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SQLDB) deletePullStatus(tx *sql.Tx, key string) error {
+	if _, err := tx.Exec(`DELETE FROM project_statuses WHERE pull_key = $1`, key); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM pull_comments WHERE pull_key = $1`, key); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM pull_statuses WHERE pull_key = $1`, key)
+	return err
+}
+
+// parsePlanStatus is the inverse of models.ProjectPlanStatus.String().
+func parsePlanStatus(s string) (models.ProjectPlanStatus, error) {
+	for _, status := range []models.ProjectPlanStatus{
+		models.ErroredPlanStatus,
+		models.PlannedPlanStatus,
+		models.ErroredApplyStatus,
+		models.AppliedPlanStatus,
+		models.PolicyCheckFailedPlanStatus,
+	} {
+		if status.String() == s {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown plan status %q", s)
+}
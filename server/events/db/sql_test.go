@@ -0,0 +1,605 @@
+package db_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestNewSQL_UnsupportedDriver(t *testing.T) {
+	_, err := db.NewSQL("mysql", "dsn")
+	ErrEquals(t, `unsupported SQL driver "mysql"`, err)
+}
+
+func TestNewSQL_MigratesOnce(t *testing.T) {
+	t.Log("reopening a database should keep its data")
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	dsn := filepath.Join(tmp, "atlantis.db")
+	s, err := db.NewSQL(db.SQLiteDriver, dsn)
+	Ok(t, err)
+	_, _, err = s.TryLock(lock)
+	Ok(t, err)
+
+	s, err = db.NewSQL(db.SQLiteDriver, dsn)
+	Ok(t, err)
+	ls, err := s.List()
+	Ok(t, err)
+	Equals(t, 1, len(ls))
+}
+
+func TestSQL_Locking(t *testing.T) {
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+
+	t.Log("listing locks when there are none should return an empty list")
+	ls, err := s.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+
+	t.Log("the first lock should be acquired")
+	acquired, currLock, err := s.TryLock(lock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	Equals(t, lock, currLock)
+
+	t.Log("a lock held by another pull should not be acquired")
+	newLock := lock
+	newLock.Pull.Num = pullNum + 1
+	acquired, currLock, err = s.TryLock(newLock)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, pullNum, currLock.Pull.Num)
+
+	t.Log("the same project in a different workspace should be acquired")
+	otherWorkspace := newLock
+	otherWorkspace.Workspace = "staging"
+	acquired, _, err = s.TryLock(otherWorkspace)
+	Ok(t, err)
+	Equals(t, true, acquired)
+
+	ls, err = s.List()
+	Ok(t, err)
+	Equals(t, 2, len(ls))
+
+	l, err := s.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, l != nil, "exp lock")
+	Equals(t, pullNum, l.Pull.Num)
+	Equals(t, lock.User, l.User)
+
+	t.Log("unlocking should return the deleted lock")
+	deleted, err := s.Unlock(project, workspace)
+	Ok(t, err)
+	Assert(t, deleted != nil, "exp deleted lock")
+	Equals(t, pullNum, deleted.Pull.Num)
+	l, err = s.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, l == nil, "exp lock to be deleted")
+
+	t.Log("unlocking when there is no lock should return nil")
+	deleted, err = s.Unlock(project, workspace)
+	Ok(t, err)
+	Assert(t, deleted == nil, "exp nil")
+
+	t.Log("the lock should now be acquired by the other pull")
+	acquired, _, err = s.TryLock(newLock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+}
+
+func TestSQL_TryLockConcurrent(t *testing.T) {
+	t.Log("only one of many concurrent attempts should acquire the lock")
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	var acquiredCount int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(num int) {
+			defer wg.Done()
+			newLock := lock
+			newLock.Pull.Num = num
+			acquired, _, err := s.TryLock(newLock)
+			Ok(t, err)
+			if acquired {
+				atomic.AddInt32(&acquiredCount, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	Equals(t, int32(1), acquiredCount)
+}
+
+func TestSQL_EnqueueConcurrent(t *testing.T) {
+	t.Log("concurrent pull requests should each get their own place in the queue")
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+
+	held := lock
+	held.Pull.Num = 100
+	_, _, err := s.TryLock(held)
+	Ok(t, err)
+	var wg sync.WaitGroup
+	positions := make([]int, 10)
+	for i := range positions {
+		wg.Add(1)
+		go func(num int) {
+			defer wg.Done()
+			newLock := lock
+			newLock.Pull.Num = num
+			_, pos, err := s.Enqueue(newLock)
+			Ok(t, err)
+			positions[num] = pos
+		}(i)
+	}
+	wg.Wait()
+	sort.Ints(positions)
+	Equals(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, positions)
+	queue, err := s.GetQueue(lock.Project, lock.Workspace)
+	Ok(t, err)
+	Equals(t, 10, len(queue))
+}
+
+func TestSQL_UnlockIfUnchanged(t *testing.T) {
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+
+	_, _, err := s.TryLock(lock)
+	Ok(t, err)
+
+	t.Log("a lock held by another pull shouldn't be unlocked")
+	otherPull := lock
+	otherPull.Pull.Num = pullNum + 1
+	deleted, err := s.UnlockIfUnchanged(otherPull)
+	Ok(t, err)
+	Assert(t, deleted == nil, "exp lock not to be deleted")
+
+	t.Log("a lock the same pull took at another time shouldn't be unlocked")
+	otherTime := lock
+	otherTime.Time = lock.Time.Add(-time.Hour)
+	deleted, err = s.UnlockIfUnchanged(otherTime)
+	Ok(t, err)
+	Assert(t, deleted == nil, "exp lock not to be deleted")
+
+	t.Log("the same lock should be unlocked")
+	deleted, err = s.UnlockIfUnchanged(lock)
+	Ok(t, err)
+	Assert(t, deleted != nil, "exp lock to be deleted")
+	Equals(t, pullNum, deleted.Pull.Num)
+	ls, err := s.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+}
+
+func TestSQL_UnlockByPull(t *testing.T) {
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+
+	_, _, err := s.TryLock(lock)
+	Ok(t, err)
+	otherPath := lock
+	otherPath.Project = models.NewProject(project.RepoFullName, "other/path")
+	_, _, err = s.TryLock(otherPath)
+	Ok(t, err)
+	otherPull := lock
+	otherPull.Workspace = "staging"
+	otherPull.Pull.Num = pullNum + 1
+	_, _, err = s.TryLock(otherPull)
+	Ok(t, err)
+	otherRepo := lock
+	otherRepo.Project = models.NewProject("owner/repo2", "parent/child")
+	_, _, err = s.TryLock(otherRepo)
+	Ok(t, err)
+
+	deleted, err := s.UnlockByPull(project.RepoFullName, pullNum)
+	Ok(t, err)
+	Equals(t, 2, len(deleted))
+
+	ls, err := s.List()
+	Ok(t, err)
+	Equals(t, 2, len(ls))
+	for _, l := range ls {
+		Assert(t, l.Project.RepoFullName != project.RepoFullName || l.Pull.Num != pullNum, "exp lock %v to be deleted", l)
+	}
+}
+
+func TestSQL_Queue(t *testing.T) {
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+
+	_, _, err := s.TryLock(lock)
+	Ok(t, err)
+
+	t.Log("enqueuing should return each pull's position in the queue")
+	for i := 1; i <= 3; i++ {
+		queued := lock
+		queued.Pull.Num = pullNum + i
+		acquired, pos, err := s.Enqueue(queued)
+		Ok(t, err)
+		Equals(t, false, acquired)
+		Equals(t, i, pos)
+	}
+	queued := lock
+	queued.Pull.Num = pullNum + 2
+	_, pos, err := s.Enqueue(queued)
+	Ok(t, err)
+	Equals(t, 2, pos)
+	otherRepo := queued
+	otherRepo.Project = models.NewProject("owner/repo2", "parent/child")
+	otherRepoLock := lock
+	otherRepoLock.Project = otherRepo.Project
+	_, _, err = s.TryLock(otherRepoLock)
+	Ok(t, err)
+	_, _, err = s.Enqueue(otherRepo)
+	Ok(t, err)
+
+	t.Log("dequeuing should only remove the matching pull from its repo's queues")
+	Ok(t, s.DequeueByPull(project.RepoFullName, pullNum+2))
+	queue, err := s.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 2, len(queue))
+	Equals(t, pullNum+1, queue[0].Pull.Num)
+	Equals(t, pullNum+3, queue[1].Pull.Num)
+	queue, err = s.GetQueue(otherRepo.Project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+
+	t.Log("dequeuing from one project should leave the pull's other queues")
+	Ok(t, s.Dequeue(otherRepo.Project, workspace, pullNum+2))
+	queue, err = s.GetQueue(otherRepo.Project, workspace)
+	Ok(t, err)
+	Equals(t, 0, len(queue))
+	queue, err = s.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 2, len(queue))
+
+	t.Log("positions should be counted without the dequeued pull")
+	queued.Pull.Num = pullNum + 3
+	_, pos, err = s.Enqueue(queued)
+	Ok(t, err)
+	Equals(t, 2, pos)
+
+	t.Log("nothing is promoted while the lock is held")
+	promoted, err := s.PromoteNext(project, workspace)
+	Ok(t, err)
+	Assert(t, promoted == nil, "exp nothing to be promoted")
+
+	t.Log("a free lock can't be taken by a pull that isn't first in the queue")
+	_, err = s.Unlock(project, workspace)
+	Ok(t, err)
+	other := lock
+	other.Pull.Num = pullNum + 4
+	acquired, curr, err := s.TryLock(other)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, pullNum+1, curr.Pull.Num)
+	acquired, pos, err = s.Enqueue(other)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, 3, pos)
+	Ok(t, s.Dequeue(project, workspace, pullNum+4))
+
+	t.Log("the first queued pull gets the lock after it's released")
+	promoted, err = s.PromoteNext(project, workspace)
+	Ok(t, err)
+	Assert(t, promoted != nil, "exp lock to be promoted")
+	Equals(t, pullNum+1, promoted.Pull.Num)
+	held, err := s.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, held != nil, "exp lock to be held")
+	Equals(t, pullNum+1, held.Pull.Num)
+	queue, err = s.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+	Equals(t, pullNum+3, queue[0].Pull.Num)
+
+	t.Log("enqueuing takes the lock if it's free and nobody is queued")
+	_, err = s.Unlock(project, workspace)
+	Ok(t, err)
+	Ok(t, s.Dequeue(project, workspace, pullNum+3))
+	queued.Pull.Num = pullNum + 2
+	acquired, _, err = s.Enqueue(queued)
+	Ok(t, err)
+	Equals(t, true, acquired)
+	held, err = s.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, held != nil, "exp lock to be held")
+	Equals(t, pullNum+2, held.Pull.Num)
+}
+
+func TestSQL_PullStatus(t *testing.T) {
+	s, dsn, cleanup := newTestSQL(t)
+	defer cleanup()
+	pull := testPull()
+
+	t.Log("there should be no status before it's updated")
+	status, err := s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status == nil, "exp nil")
+
+	_, err = s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+		{
+			Command:    models.PlanCommand,
+			RepoRelDir: "other",
+			Workspace:  "default",
+			Error:      errors.New("err"),
+		},
+	})
+	Ok(t, err)
+
+	t.Log("results at the same commit should be merged")
+	updated, err := s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:      models.ApplyCommand,
+			RepoRelDir:   ".",
+			Workspace:    "default",
+			ApplySuccess: "applied",
+		},
+	})
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{
+			Workspace:  "default",
+			RepoRelDir: ".",
+			Status:     models.AppliedPlanStatus,
+		},
+		{
+			Workspace:  "default",
+			RepoRelDir: "other",
+			Status:     models.ErroredPlanStatus,
+		},
+	}, updated.Projects)
+
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status != nil, "exp status")
+	Equals(t, updated, *status)
+
+	t.Log("every result should be kept in the run history")
+	conn, err := sql.Open(db.SQLiteDriver, dsn)
+	Ok(t, err)
+	defer conn.Close() // nolint: errcheck
+	rows, err := conn.Query(`SELECT repo_rel_dir, command, status FROM project_runs WHERE repo_full_name = $1 AND pull_num = $2 ORDER BY rowid`, pull.BaseRepo.FullName, pull.Num)
+	Ok(t, err)
+	var runs []string
+	for rows.Next() {
+		var dir, command, runStatus string
+		Ok(t, rows.Scan(&dir, &command, &runStatus))
+		runs = append(runs, dir+" "+command+" "+runStatus)
+	}
+	Ok(t, rows.Err())
+	Ok(t, rows.Close())
+	Equals(t, []string{
+		". plan planned",
+		"other plan plan_errored",
+		". apply applied",
+	}, runs)
+
+	t.Log("deleting a project status should leave the others")
+	Ok(t, s.DeleteProjectStatus(pull, "default", "."))
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, 1, len(status.Projects))
+	Equals(t, "other", status.Projects[0].RepoRelDir)
+
+	t.Log("deleting the pull status should delete it all")
+	Ok(t, s.DeletePullStatus(pull))
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status == nil, "exp nil")
+}
+
+func TestSQL_PullStatusNewCommit(t *testing.T) {
+	t.Log("results at a new commit should replace the old status")
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+	pull := testPull()
+
+	_, err := s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+	})
+	Ok(t, err)
+
+	pull.HeadCommit = "newsha"
+	updated, err := s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  "other",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+	})
+	Ok(t, err)
+	status, err := s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, updated, *status)
+	Equals(t, "newsha", status.Pull.HeadCommit)
+	Equals(t, 1, len(status.Projects))
+	Equals(t, "other", status.Projects[0].RepoRelDir)
+}
+
+func TestSQL_PlanSummary(t *testing.T) {
+	t.Log("plan summaries should be stored and kept when the plan is applied")
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+	pull := testPull()
+	summary := &models.PlanSummary{Add: 1, Creates: []string{"null_resource.test"}}
+
+	_, err := s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{PlanSummary: summary},
+		},
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  "other",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+	})
+	Ok(t, err)
+	_, err = s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:      models.ApplyCommand,
+			RepoRelDir:   ".",
+			Workspace:    "default",
+			ApplySuccess: "applied",
+		},
+	})
+	Ok(t, err)
+	status, err := s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, summary, status.Projects[0].PlanSummary)
+	Equals(t, models.AppliedPlanStatus, status.Projects[0].Status)
+	Assert(t, status.Projects[1].PlanSummary == nil, "exp nil summary")
+}
+
+func TestSQL_PullComments(t *testing.T) {
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+	pull := testPull()
+
+	t.Log("comments can be stored before there are any results")
+	Ok(t, s.UpdatePullComments(pull, models.PlanCommand, []string{"1", "2"}))
+	status, err := s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, pull, status.Pull)
+	Equals(t, map[string][]string{"plan": {"1", "2"}}, status.Comments)
+
+	t.Log("comments should be kept when the pull gets a new commit")
+	Ok(t, s.UpdatePullComments(pull, models.ApplyCommand, []string{"3"}))
+	pull.HeadCommit = "newsha"
+	_, err = s.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+	})
+	Ok(t, err)
+	Ok(t, s.UpdatePullComments(pull, models.PlanCommand, []string{"4"}))
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, 1, len(status.Projects))
+	Equals(t, map[string][]string{"plan": {"4"}, "apply": {"3"}}, status.Comments)
+
+	t.Log("deleting the pull status should delete the comments")
+	Ok(t, s.DeletePullStatus(pull))
+	Ok(t, s.UpdatePullComments(pull, models.PlanCommand, []string{"5"}))
+	status, err = s.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, map[string][]string{"plan": {"5"}}, status.Comments)
+}
+
+func TestMigrateBoltDB(t *testing.T) {
+	b, cleanupBolt := newTestDB2(t)
+	defer cleanupBolt()
+	s, _, cleanup := newTestSQL(t)
+	defer cleanup()
+	pull := testPull()
+
+	_, _, err := b.TryLock(lock)
+	Ok(t, err)
+	queued := lock
+	queued.Pull.Num = pullNum + 1
+	_, _, err = b.Enqueue(queued)
+	Ok(t, err)
+	boltStatus, err := b.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+	})
+	Ok(t, err)
+
+	t.Log("a lock already in the SQL database should be overwritten")
+	otherLock := lock
+	otherLock.Pull.Num = pullNum + 2
+	_, _, err = s.TryLock(otherLock)
+	Ok(t, err)
+
+	result, err := db.MigrateBoltDB(b, s)
+	Ok(t, err)
+	Equals(t, db.MigrateBoltDBResult{Locks: 1, Pulls: 1, Queues: 1}, result)
+
+	l, err := s.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, l != nil, "exp lock")
+	Equals(t, pullNum, l.Pull.Num)
+	queue, err := s.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+	Equals(t, pullNum+1, queue[0].Pull.Num)
+	status, err := s.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, status != nil, "exp status")
+	Equals(t, boltStatus, *status)
+
+	t.Log("migrating again should not duplicate anything")
+	_, err = db.MigrateBoltDB(b, s)
+	Ok(t, err)
+	ls, err := s.List()
+	Ok(t, err)
+	Equals(t, 1, len(ls))
+	queue, err = s.GetQueue(project, workspace)
+	Ok(t, err)
+	Equals(t, 1, len(queue))
+}
+
+func newTestSQL(t *testing.T) (*db.SQLDB, string, func()) {
+	tmp, cleanup := TempDir(t)
+	dsn := filepath.Join(tmp, "atlantis.db")
+	s, err := db.NewSQL(db.SQLiteDriver, dsn)
+	Ok(t, err)
+	return s, dsn, cleanup
+}
+
+func testPull() models.PullRequest {
+	return models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		URL:        "url",
+		HeadBranch: "head",
+		BaseBranch: "base",
+		Author:     "lkysow",
+		State:      models.OpenPullState,
+		BaseRepo: models.Repo{
+			FullName:          "runatlantis/atlantis",
+			Owner:             "runatlantis",
+			Name:              "atlantis",
+			CloneURL:          "clone-url",
+			SanitizedCloneURL: "clone-url",
+			VCSHost: models.VCSHost{
+				Hostname: "github.com",
+				Type:     models.Github,
+			},
+		},
+	}
+}
@@ -0,0 +1,204 @@
+package events
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_drift_webhooks_sender.go DriftWebhooksSender
+
+// DriftWebhooksSender sends webhooks for projects that have drifted.
+type DriftWebhooksSender interface {
+	// SendDrift sends the webhook.
+	SendDrift(log *logging.SimpleLogger, res webhooks.DriftResult) error
+}
+
+// DriftStatus is the result of checking a project for drift.
+type DriftStatus int
+
+const (
+	// NoDriftStatus means planning the project made no changes.
+	NoDriftStatus DriftStatus = iota
+	// DriftedStatus means planning the project made changes so its
+	// infrastructure no longer matches the code on the default branch.
+	DriftedStatus
+	// DriftErrorStatus means the project couldn't be checked.
+	DriftErrorStatus
+)
+
+// String returns the string representation of the status.
+func (d DriftStatus) String() string {
+	switch d {
+	case NoDriftStatus:
+		return "no drift"
+	case DriftedStatus:
+		return "drifted"
+	case DriftErrorStatus:
+		return "error"
+	}
+	return ""
+}
+
+// ProjectDrift is the result of the last drift check of a project.
+type ProjectDrift struct {
+	// RepoID is the ID of the project's repo, ex. github.com/owner/repo.
+	RepoID      string
+	RepoRelDir  string
+	Workspace   string
+	ProjectName string
+	CheckedAt   time.Time
+	Status      DriftStatus
+	// Summary summarizes the planned changes if the project has drifted.
+	Summary string
+	// Error is why the project couldn't be checked if Status is
+	// DriftErrorStatus. If the repo itself couldn't be checked, it's the only
+	// result for the repo and has no RepoRelDir.
+	Error string
+}
+
+// DriftDetector plans the default branch of each of Repos on a schedule to
+// detect drift, i.e. when the infrastructure no longer matches the code. It
+// keeps the result of the last check of each project and sends webhooks for
+// projects that have drifted.
+type DriftDetector struct {
+	// Repos are the IDs of the repos to check, ex. github.com/owner/repo.
+	Repos                 []string
+	RepoBuilder           RepoBuilder
+	ProjectCommandBuilder ProjectCommandBuilder
+	ProjectCommandRunner  ProjectCommandRunner
+	Webhooks              DriftWebhooksSender
+	Logger                *logging.SimpleLogger
+	// DriftURL is the URL of the page that lists the results. It's linked to
+	// from webhooks.
+	DriftURL string
+
+	mutex   sync.RWMutex
+	results map[string][]ProjectDrift
+}
+
+// Run checks every repo for drift immediately and then every interval until
+// stop is closed.
+func (d *DriftDetector) Run(interval time.Duration, stop <-chan struct{}) {
+	d.CheckAll()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.CheckAll()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// CheckAll checks every repo for drift. Repos are checked one at a time so
+// drift detection doesn't compete with pull requests for resources.
+func (d *DriftDetector) CheckAll() {
+	for _, id := range d.Repos {
+		results := d.checkRepo(id)
+		d.mutex.Lock()
+		if d.results == nil {
+			d.results = make(map[string][]ProjectDrift)
+		}
+		d.results[id] = results
+		d.mutex.Unlock()
+	}
+}
+
+// Results returns the result of the last check of each project, sorted by
+// repo and then project.
+func (d *DriftDetector) Results() []ProjectDrift {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	var all []ProjectDrift
+	for _, results := range d.results {
+		all = append(all, results...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].RepoID != all[j].RepoID {
+			return all[i].RepoID < all[j].RepoID
+		}
+		if all[i].RepoRelDir != all[j].RepoRelDir {
+			return all[i].RepoRelDir < all[j].RepoRelDir
+		}
+		return all[i].Workspace < all[j].Workspace
+	})
+	return all
+}
+
+func (d *DriftDetector) checkRepo(id string) []ProjectDrift {
+	log := d.Logger.WithFields(logging.Fields{logging.RepoKey: id})
+	log.Info("checking repo for drift")
+	repoErr := func(err error) []ProjectDrift {
+		log.Err("checking repo for drift: %s", err)
+		return []ProjectDrift{{RepoID: id, CheckedAt: time.Now(), Status: DriftErrorStatus, Error: err.Error()}}
+	}
+
+	repo, err := d.RepoBuilder.BuildRepo(id)
+	if err != nil {
+		return repoErr(err)
+	}
+	// The default branch has no pull request so Pull is empty.
+	ctx := &CommandContext{
+		BaseRepo: repo,
+		HeadRepo: repo,
+		Log:      log,
+	}
+	projCtxs, err := d.ProjectCommandBuilder.BuildDriftCommands(ctx)
+	if err != nil {
+		return repoErr(err)
+	}
+
+	var results []ProjectDrift
+	for _, projCtx := range projCtxs {
+		res := d.ProjectCommandRunner.PlanDrift(projCtx)
+		drift := ProjectDrift{
+			RepoID:      id,
+			RepoRelDir:  res.RepoRelDir,
+			Workspace:   res.Workspace,
+			ProjectName: res.ProjectName,
+			CheckedAt:   time.Now(),
+		}
+		switch {
+		case res.Error != nil:
+			drift.Status = DriftErrorStatus
+			drift.Error = res.Error.Error()
+		case res.Failure != "":
+			drift.Status = DriftErrorStatus
+			drift.Error = res.Failure
+		case res.PlanSuccess.Summary() == "No changes.":
+			drift.Status = NoDriftStatus
+		case res.PlanSuccess.Summary() == "":
+			// Without a summary we can't tell whether it drifted so we don't
+			// alert on it.
+			drift.Status = DriftErrorStatus
+			drift.Error = "unable to find the plan's summary in its output"
+		default:
+			drift.Status = DriftedStatus
+			drift.Summary = res.PlanSuccess.Summary()
+		}
+		results = append(results, drift)
+
+		if drift.Status != DriftedStatus {
+			continue
+		}
+		projCtx.Log.Warn("project has drifted: %s", drift.Summary)
+		err := d.Webhooks.SendDrift(projCtx.Log, webhooks.DriftResult{
+			Repo:        repo,
+			RepoRelDir:  drift.RepoRelDir,
+			Workspace:   drift.Workspace,
+			ProjectName: drift.ProjectName,
+			Summary:     drift.Summary,
+			URL:         d.DriftURL,
+		})
+		if err != nil {
+			projCtx.Log.Warn("unable to send drift webhook: %s", err)
+		}
+	}
+	return results
+}
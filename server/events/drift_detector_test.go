@@ -0,0 +1,154 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestDriftDetector_CheckAll(t *testing.T) {
+	cases := []struct {
+		description string
+		result      models.ProjectResult
+		expStatus   events.DriftStatus
+		expSummary  string
+		expError    string
+	}{
+		{
+			"no changes",
+			models.ProjectResult{
+				PlanSuccess: &models.PlanSuccess{TerraformOutput: "No changes. Infrastructure is up-to-date."},
+			},
+			events.NoDriftStatus,
+			"",
+			"",
+		},
+		{
+			"changes",
+			models.ProjectResult{
+				PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+			},
+			events.DriftedStatus,
+			"Plan: 1 to add, 0 to change, 0 to destroy.",
+			"",
+		},
+		{
+			"no summary",
+			models.ProjectResult{
+				PlanSuccess: &models.PlanSuccess{TerraformOutput: "Error: unexpected output"},
+			},
+			events.DriftErrorStatus,
+			"",
+			"unable to find the plan's summary in its output",
+		},
+		{
+			"error",
+			models.ProjectResult{
+				Error: errors.New("err"),
+			},
+			events.DriftErrorStatus,
+			"",
+			"err",
+		},
+		{
+			"failure",
+			models.ProjectResult{
+				Failure: "failure",
+			},
+			events.DriftErrorStatus,
+			"",
+			"failure",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			repoBuilder := mocks.NewMockRepoBuilder()
+			builder := mocks.NewMockProjectCommandBuilder()
+			runner := mocks.NewMockProjectCommandRunner()
+			sender := mocks.NewMockDriftWebhooksSender()
+			logger := logging.NewNoopLogger()
+
+			projCtx := models.ProjectCommandContext{
+				Log:        logger,
+				RepoRelDir: "path",
+				Workspace:  "default",
+			}
+			c.result.RepoRelDir = "path"
+			c.result.Workspace = "default"
+			When(repoBuilder.BuildRepo("github.com/runatlantis/atlantis")).ThenReturn(fixtures.GithubRepo, nil)
+			When(builder.BuildDriftCommands(matchers.AnyPtrToEventsCommandContext())).ThenReturn([]models.ProjectCommandContext{projCtx}, nil)
+			When(runner.PlanDrift(matchers.AnyModelsProjectCommandContext())).ThenReturn(c.result)
+
+			detector := events.DriftDetector{
+				Repos:                 []string{"github.com/runatlantis/atlantis"},
+				RepoBuilder:           repoBuilder,
+				ProjectCommandBuilder: builder,
+				ProjectCommandRunner:  runner,
+				Webhooks:              sender,
+				Logger:                logger,
+				DriftURL:              "https://atlantis/drift",
+			}
+			detector.CheckAll()
+
+			results := detector.Results()
+			Equals(t, 1, len(results))
+			Equals(t, "github.com/runatlantis/atlantis", results[0].RepoID)
+			Equals(t, "path", results[0].RepoRelDir)
+			Equals(t, "default", results[0].Workspace)
+			Equals(t, c.expStatus, results[0].Status)
+			Equals(t, c.expSummary, results[0].Summary)
+			Equals(t, c.expError, results[0].Error)
+			Assert(t, !results[0].CheckedAt.IsZero(), "exp CheckedAt to be set")
+
+			if c.expStatus != events.DriftedStatus {
+				sender.VerifyWasCalled(Never()).SendDrift(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyWebhooksDriftResult())
+				return
+			}
+			sender.VerifyWasCalledOnce().SendDrift(logger, webhooks.DriftResult{
+				Repo:       fixtures.GithubRepo,
+				RepoRelDir: "path",
+				Workspace:  "default",
+				Summary:    c.expSummary,
+				URL:        "https://atlantis/drift",
+			})
+		})
+	}
+}
+
+func TestDriftDetector_CheckAllRepoError(t *testing.T) {
+	t.Log("if the repo can't be checked its only result should be the error")
+	RegisterMockTestingT(t)
+	repoBuilder := mocks.NewMockRepoBuilder()
+	builder := mocks.NewMockProjectCommandBuilder()
+	When(repoBuilder.BuildRepo("github.com/runatlantis/atlantis")).ThenReturn(fixtures.GithubRepo, nil)
+	When(builder.BuildDriftCommands(matchers.AnyPtrToEventsCommandContext())).ThenReturn(nil, errors.New("cloning failed"))
+
+	detector := events.DriftDetector{
+		Repos:                 []string{"github.com/runatlantis/atlantis", "unknown.com/owner/repo"},
+		RepoBuilder:           repoBuilder,
+		ProjectCommandBuilder: builder,
+		Logger:                logging.NewNoopLogger(),
+	}
+	When(repoBuilder.BuildRepo("unknown.com/owner/repo")).ThenReturn(models.Repo{}, errors.New("unknown host"))
+	detector.CheckAll()
+
+	results := detector.Results()
+	Equals(t, 2, len(results))
+	Equals(t, "github.com/runatlantis/atlantis", results[0].RepoID)
+	Equals(t, events.DriftErrorStatus, results[0].Status)
+	Equals(t, "cloning failed", results[0].Error)
+	Equals(t, "unknown.com/owner/repo", results[1].RepoID)
+	Equals(t, events.DriftErrorStatus, results[1].Status)
+	Equals(t, "unknown host", results[1].Error)
+}
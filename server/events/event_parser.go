@@ -16,6 +16,7 @@ package events
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"path"
 	"strings"
 
@@ -23,8 +24,11 @@ import (
 	"github.com/lkysow/go-gitlab"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/vcs/azuredevops"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/runatlantis/atlantis/server/events/vcs/gitea"
 	"gopkg.in/go-playground/validator.v9"
 )
 
@@ -79,6 +83,10 @@ type CommentCommand struct {
 	// project specified in an atlantis.yaml file.
 	// If empty then the comment specified no project.
 	ProjectName string
+	// AllowDestroy is true if the comment acknowledged that applying will
+	// destroy resources protected by the no_destroy apply requirement,
+	// ex. atlantis apply --allow-destroy
+	AllowDestroy bool
 }
 
 // IsForSpecificProject returns true if the command is for a specific dir, workspace
@@ -141,6 +149,14 @@ type EventParsing interface {
 	ParseGithubIssueCommentEvent(comment *github.IssueCommentEvent) (
 		baseRepo models.Repo, user models.User, pullNum int, err error)
 
+	// ParseGithubCheckRunEvent parses GitHub check run events. These are sent
+	// when a button on one of our check runs is clicked.
+	// baseRepo is the repo that the pull request will be merged into.
+	// user is the user that clicked the button.
+	// pullNum is the number of the pull request the check run is for.
+	ParseGithubCheckRunEvent(event *github.CheckRunEvent) (
+		baseRepo models.Repo, user models.User, pullNum int, err error)
+
 	// ParseGithubPull parses the response from the GitHub API endpoint (not
 	// from a webhook) that returns a pull request.
 	// pull is the parsed pull request.
@@ -185,6 +201,10 @@ type EventParsing interface {
 	// that returns a merge request.
 	ParseGitlabMergeRequest(mr *gitlab.MergeRequest, baseRepo models.Repo) models.PullRequest
 
+	// ParseGitlabProject parses the response from the GitLab API endpoint
+	// (not from a webhook) that returns a project.
+	ParseGitlabProject(project *gitlab.Project) (models.Repo, error)
+
 	// ParseBitbucketCloudPullEvent parses a pull request event from Bitbucket
 	// Cloud (bitbucket.org).
 	// pull is the parsed pull request.
@@ -234,17 +254,92 @@ type EventParsing interface {
 	// GetBitbucketServerPullEventType returns the type of the pull request
 	// event given the Bitbucket Server header.
 	GetBitbucketServerPullEventType(eventTypeHeader string) models.PullRequestEventType
+
+	// ParseAzureDevopsPullEvent parses a pull request event from Azure
+	// DevOps.
+	// pull is the parsed pull request.
+	// pullEventType is the type of event, for example opened/closed.
+	// baseRepo is the repo the pull request will be merged into.
+	// headRepo is the repo the pull request branch is from.
+	// user is the pull request author.
+	ParseAzureDevopsPullEvent(body []byte) (
+		pull models.PullRequest, pullEventType models.PullRequestEventType,
+		baseRepo models.Repo, headRepo models.Repo, user models.User, err error)
+
+	// ParseAzureDevopsPullCommentEvent parses a pull request comment event
+	// from Azure DevOps.
+	// pull is the parsed pull request.
+	// baseRepo is the repo the pull request will be merged into.
+	// headRepo is the repo the pull request branch is from.
+	// user is the comment author.
+	// comment is the comment that triggered the event.
+	ParseAzureDevopsPullCommentEvent(body []byte) (
+		pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo,
+		user models.User, comment string, err error)
+
+	// ParseGiteaPullEvent parses Gitea pull request events.
+	// pull is the parsed pull request.
+	// pullEventType is the type of event, for example opened/closed.
+	// baseRepo is the repo the pull request will be merged into.
+	// headRepo is the repo the pull request branch is from.
+	// user is the pull request author.
+	ParseGiteaPullEvent(event gitea.PullRequestEvent) (
+		pull models.PullRequest, pullEventType models.PullRequestEventType,
+		baseRepo models.Repo, headRepo models.Repo, user models.User, err error)
+
+	// ParseGiteaIssueCommentEvent parses Gitea pull request comment events.
+	// baseRepo is the repo that the pull request will be merged into.
+	// user is the comment author.
+	// pullNum is the number of the pull request that triggered the webhook.
+	ParseGiteaIssueCommentEvent(event gitea.IssueCommentEvent) (
+		baseRepo models.Repo, user models.User, pullNum int, err error)
+
+	// ParseGiteaPull parses the response from the Gitea API endpoint (not
+	// from a webhook) that returns a pull request.
+	// pull is the parsed pull request.
+	// baseRepo is the repo the pull request will be merged into.
+	// headRepo is the repo the pull request branch is from.
+	ParseGiteaPull(giteaPull *gitea.PullRequest) (
+		pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+
+	// ParseBitbucketCloudPull parses the response from the Bitbucket Cloud API
+	// endpoint (not from a webhook) that returns a pull request.
+	// pull is the parsed pull request.
+	// baseRepo is the repo the pull request will be merged into.
+	// headRepo is the repo the pull request branch is from.
+	ParseBitbucketCloudPull(bbPull *bitbucketcloud.PullRequest) (
+		pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+
+	// ParseBitbucketServerPull parses the response from the Bitbucket Server API
+	// endpoint (not from a webhook) that returns a pull request.
+	// pull is the parsed pull request.
+	// baseRepo is the repo the pull request will be merged into.
+	// headRepo is the repo the pull request branch is from.
+	ParseBitbucketServerPull(bbPull *bitbucketserver.PullRequest) (
+		pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
+
+	// ParseAzureDevopsPull parses the response from the Azure DevOps API
+	// endpoint (not from a webhook) that returns a pull request.
+	// pull is the parsed pull request.
+	// baseRepo is the repo the pull request will be merged into.
+	// headRepo is the repo the pull request branch is from.
+	ParseAzureDevopsPull(adPull *azuredevops.PullRequest) (
+		pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error)
 }
 
 // EventParser parses VCS events.
 type EventParser struct {
-	GithubUser         string
-	GithubToken        string
+	// GithubCredentials are used to build the clone URLs of GitHub repos.
+	GithubCredentials  vcs.GithubCredentials
 	GitlabUser         string
 	GitlabToken        string
 	BitbucketUser      string
 	BitbucketToken     string
 	BitbucketServerURL string
+	AzureDevopsUser    string
+	AzureDevopsToken   string
+	GiteaUser          string
+	GiteaToken         string
 }
 
 // GetBitbucketCloudPullEventType returns the type of the pull request
@@ -280,8 +375,23 @@ func (e *EventParser) ParseBitbucketCloudPullCommentEvent(body []byte) (pull mod
 }
 
 func (e *EventParser) parseCommonBitbucketCloudEventData(event bitbucketcloud.CommonEventData) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, err error) {
+	pull, baseRepo, headRepo, err = e.ParseBitbucketCloudPull(event.PullRequest)
+	if err != nil {
+		return
+	}
+	pull.Author = *event.Actor.Nickname
+	user = models.User{
+		Username: *event.Actor.Nickname,
+	}
+	return
+}
+
+// ParseBitbucketCloudPull parses the response from the Bitbucket Cloud API
+// endpoint (not from a webhook) that returns a pull request.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseBitbucketCloudPull(bbPull *bitbucketcloud.PullRequest) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
 	var prState models.PullRequestState
-	switch *event.PullRequest.State {
+	switch *bbPull.State {
 	case "OPEN":
 		prState = models.OpenPullState
 	case "MERGED":
@@ -291,14 +401,14 @@ func (e *EventParser) parseCommonBitbucketCloudEventData(event bitbucketcloud.Co
 	case "DECLINE":
 		prState = models.ClosedPullState
 	default:
-		err = fmt.Errorf("unable to determine pull request state from %q–this is a bug", *event.PullRequest.State)
+		err = fmt.Errorf("unable to determine pull request state from %q–this is a bug", *bbPull.State)
 		return
 	}
 
 	headRepo, err = models.NewRepo(
 		models.BitbucketCloud,
-		*event.PullRequest.Source.Repository.FullName,
-		*event.PullRequest.Source.Repository.Links.HTML.HREF,
+		*bbPull.Source.Repository.FullName,
+		*bbPull.Source.Repository.Links.HTML.HREF,
 		e.BitbucketUser,
 		e.BitbucketToken)
 	if err != nil {
@@ -306,8 +416,8 @@ func (e *EventParser) parseCommonBitbucketCloudEventData(event bitbucketcloud.Co
 	}
 	baseRepo, err = models.NewRepo(
 		models.BitbucketCloud,
-		*event.Repository.FullName,
-		*event.Repository.Links.HTML.HREF,
+		*bbPull.Destination.Repository.FullName,
+		*bbPull.Destination.Repository.Links.HTML.HREF,
 		e.BitbucketUser,
 		e.BitbucketToken)
 	if err != nil {
@@ -315,17 +425,16 @@ func (e *EventParser) parseCommonBitbucketCloudEventData(event bitbucketcloud.Co
 	}
 
 	pull = models.PullRequest{
-		Num:        *event.PullRequest.ID,
-		HeadCommit: *event.PullRequest.Source.Commit.Hash,
-		URL:        *event.PullRequest.Links.HTML.HREF,
-		HeadBranch: *event.PullRequest.Source.Branch.Name,
-		BaseBranch: *event.PullRequest.Destination.Branch.Name,
-		Author:     *event.Actor.Nickname,
+		Num:        *bbPull.ID,
+		HeadCommit: *bbPull.Source.Commit.Hash,
+		URL:        *bbPull.Links.HTML.HREF,
+		HeadBranch: *bbPull.Source.Branch.Name,
+		BaseBranch: *bbPull.Destination.Branch.Name,
 		State:      prState,
 		BaseRepo:   baseRepo,
 	}
-	user = models.User{
-		Username: *event.Actor.Nickname,
+	if bbPull.Author != nil && bbPull.Author.Nickname != nil {
+		pull.Author = *bbPull.Author.Nickname
 	}
 	return
 }
@@ -370,6 +479,38 @@ func (e *EventParser) ParseGithubIssueCommentEvent(comment *github.IssueCommentE
 	return
 }
 
+// ParseGithubCheckRunEvent parses GitHub check run events.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseGithubCheckRunEvent(event *github.CheckRunEvent) (baseRepo models.Repo, user models.User, pullNum int, err error) {
+	baseRepo, err = e.ParseGithubRepo(event.Repo)
+	if err != nil {
+		return
+	}
+	if event.Sender.GetLogin() == "" {
+		err = errors.New("sender.login is null")
+		return
+	}
+	user = models.User{
+		Username: event.Sender.GetLogin(),
+	}
+	if event.CheckRun == nil {
+		err = errors.New("check_run is null")
+		return
+	}
+	// GitHub only includes pull requests from the same repo so check runs on
+	// pull requests from forks won't have any.
+	if len(event.CheckRun.PullRequests) == 0 {
+		err = errors.New("check_run.pull_requests is empty")
+		return
+	}
+	pullNum = event.CheckRun.PullRequests[0].GetNumber()
+	if pullNum == 0 {
+		err = errors.New("check_run.pull_requests[0].number is null")
+		return
+	}
+	return
+}
+
 // ParseGithubPullEvent parses GitHub pull request events.
 // See EventParsing for return value docs.
 func (e *EventParser) ParseGithubPullEvent(pullEvent *github.PullRequestEvent) (pull models.PullRequest, pullEventType models.PullRequestEventType, baseRepo models.Repo, headRepo models.Repo, user models.User, err error) {
@@ -471,7 +612,16 @@ func (e *EventParser) ParseGithubPull(pull *github.PullRequest) (pullModel model
 // returns a repo into the Atlantis model.
 // See EventParsing for return value docs.
 func (e *EventParser) ParseGithubRepo(ghRepo *github.Repository) (models.Repo, error) {
-	return models.NewRepo(models.Github, ghRepo.GetFullName(), ghRepo.GetCloneURL(), e.GithubUser, e.GithubToken)
+	var user, token string
+	if e.GithubCredentials != nil {
+		var err error
+		user = e.GithubCredentials.GetUser()
+		token, err = e.GithubCredentials.GetToken(ghRepo.GetOwner().GetLogin(), ghRepo.GetName())
+		if err != nil {
+			return models.Repo{}, errors.Wrapf(err, "getting credentials for %s", ghRepo.GetFullName())
+		}
+	}
+	return models.NewRepo(models.Github, ghRepo.GetFullName(), ghRepo.GetCloneURL(), user, token)
 }
 
 // ParseGitlabMergeRequestEvent parses GitLab merge request events.
@@ -569,6 +719,12 @@ func (e *EventParser) ParseGitlabMergeRequest(mr *gitlab.MergeRequest, baseRepo
 	}
 }
 
+// ParseGitlabProject parses the response from the GitLab API endpoint (not
+// from a webhook) that returns a project.
+func (e *EventParser) ParseGitlabProject(project *gitlab.Project) (models.Repo, error) {
+	return models.NewRepo(models.Gitlab, project.PathWithNamespace, project.HTTPURLToRepo, e.GitlabUser, e.GitlabToken)
+}
+
 // GetBitbucketServerPullEventType returns the type of the pull request
 // event given the Bitbucket Server header.
 func (e *EventParser) GetBitbucketServerPullEventType(eventTypeHeader string) models.PullRequestEventType {
@@ -600,8 +756,23 @@ func (e *EventParser) ParseBitbucketServerPullCommentEvent(body []byte) (pull mo
 }
 
 func (e *EventParser) parseCommonBitbucketServerEventData(event bitbucketserver.CommonEventData) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, err error) {
+	pull, baseRepo, headRepo, err = e.ParseBitbucketServerPull(event.PullRequest)
+	if err != nil {
+		return
+	}
+	pull.Author = *event.Actor.Username
+	user = models.User{
+		Username: *event.Actor.Username,
+	}
+	return
+}
+
+// ParseBitbucketServerPull parses the response from the Bitbucket Server API
+// endpoint (not from a webhook) that returns a pull request.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseBitbucketServerPull(bbPull *bitbucketserver.PullRequest) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
 	var prState models.PullRequestState
-	switch *event.PullRequest.State {
+	switch *bbPull.State {
 	case "OPEN":
 		prState = models.OpenPullState
 	case "MERGED":
@@ -609,13 +780,13 @@ func (e *EventParser) parseCommonBitbucketServerEventData(event bitbucketserver.
 	case "DECLINED":
 		prState = models.ClosedPullState
 	default:
-		err = fmt.Errorf("unable to determine pull request state from %q–this is a bug", *event.PullRequest.State)
+		err = fmt.Errorf("unable to determine pull request state from %q–this is a bug", *bbPull.State)
 		return
 	}
 
-	headRepoSlug := *event.PullRequest.FromRef.Repository.Slug
-	headRepoFullname := fmt.Sprintf("%s/%s", *event.PullRequest.FromRef.Repository.Project.Name, headRepoSlug)
-	headRepoCloneURL := fmt.Sprintf("%s/scm/%s/%s.git", e.BitbucketServerURL, strings.ToLower(*event.PullRequest.FromRef.Repository.Project.Key), headRepoSlug)
+	headRepoSlug := *bbPull.FromRef.Repository.Slug
+	headRepoFullname := fmt.Sprintf("%s/%s", *bbPull.FromRef.Repository.Project.Name, headRepoSlug)
+	headRepoCloneURL := fmt.Sprintf("%s/scm/%s/%s.git", e.BitbucketServerURL, strings.ToLower(*bbPull.FromRef.Repository.Project.Key), headRepoSlug)
 	headRepo, err = models.NewRepo(
 		models.BitbucketServer,
 		headRepoFullname,
@@ -626,9 +797,9 @@ func (e *EventParser) parseCommonBitbucketServerEventData(event bitbucketserver.
 		return
 	}
 
-	baseRepoSlug := *event.PullRequest.ToRef.Repository.Slug
-	baseRepoFullname := fmt.Sprintf("%s/%s", *event.PullRequest.ToRef.Repository.Project.Name, baseRepoSlug)
-	baseRepoCloneURL := fmt.Sprintf("%s/scm/%s/%s.git", e.BitbucketServerURL, strings.ToLower(*event.PullRequest.ToRef.Repository.Project.Key), baseRepoSlug)
+	baseRepoSlug := *bbPull.ToRef.Repository.Slug
+	baseRepoFullname := fmt.Sprintf("%s/%s", *bbPull.ToRef.Repository.Project.Name, baseRepoSlug)
+	baseRepoCloneURL := fmt.Sprintf("%s/scm/%s/%s.git", e.BitbucketServerURL, strings.ToLower(*bbPull.ToRef.Repository.Project.Key), baseRepoSlug)
 	baseRepo, err = models.NewRepo(
 		models.BitbucketServer,
 		baseRepoFullname,
@@ -640,17 +811,16 @@ func (e *EventParser) parseCommonBitbucketServerEventData(event bitbucketserver.
 	}
 
 	pull = models.PullRequest{
-		Num:        *event.PullRequest.ID,
-		HeadCommit: *event.PullRequest.FromRef.LatestCommit,
-		URL:        fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d", e.BitbucketServerURL, *event.PullRequest.ToRef.Repository.Project.Key, *event.PullRequest.ToRef.Repository.Slug, *event.PullRequest.ID),
-		HeadBranch: *event.PullRequest.FromRef.DisplayID,
-		BaseBranch: *event.PullRequest.ToRef.DisplayID,
-		Author:     *event.Actor.Username,
+		Num:        *bbPull.ID,
+		HeadCommit: *bbPull.FromRef.LatestCommit,
+		URL:        fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d", e.BitbucketServerURL, *bbPull.ToRef.Repository.Project.Key, *bbPull.ToRef.Repository.Slug, *bbPull.ID),
+		HeadBranch: *bbPull.FromRef.DisplayID,
+		BaseBranch: *bbPull.ToRef.DisplayID,
 		State:      prState,
 		BaseRepo:   baseRepo,
 	}
-	user = models.User{
-		Username: *event.Actor.Username,
+	if bbPull.Author != nil && bbPull.Author.User != nil && bbPull.Author.User.Name != nil {
+		pull.Author = *bbPull.Author.User.Name
 	}
 	return
 }
@@ -671,3 +841,210 @@ func (e *EventParser) ParseBitbucketServerPullEvent(body []byte) (pull models.Pu
 	pull, baseRepo, headRepo, user, err = e.parseCommonBitbucketServerEventData(event.CommonEventData)
 	return
 }
+
+// ParseAzureDevopsPullEvent parses a pull request event from Azure DevOps.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseAzureDevopsPullEvent(body []byte) (pull models.PullRequest, pullEventType models.PullRequestEventType, baseRepo models.Repo, headRepo models.Repo, user models.User, err error) {
+	var event azuredevops.PullRequestEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = errors.Wrap(err, "parsing json")
+		return
+	}
+	if err = validator.New().Struct(event); err != nil {
+		err = errors.Wrapf(err, "API response %q was missing fields", string(body))
+		return
+	}
+	pull, baseRepo, headRepo, err = e.ParseAzureDevopsPull(event.Resource)
+	if err != nil {
+		return
+	}
+	user = models.User{
+		Username: *event.Resource.CreatedBy.UniqueName,
+	}
+
+	// Azure DevOps sends the same event type for every update to a pull
+	// request, ex. adding a reviewer, so we look at the pull request status
+	// and whether the event lists pushed commits to figure out what happened.
+	switch {
+	case *event.EventType == azuredevops.PullCreatedEvent:
+		pullEventType = models.OpenedPullEvent
+	case *event.EventType != azuredevops.PullUpdatedEvent:
+		pullEventType = models.OtherPullEvent
+	case pull.State == models.ClosedPullState:
+		pullEventType = models.ClosedPullEvent
+	case len(event.Resource.Commits) > 0:
+		pullEventType = models.UpdatedPullEvent
+	default:
+		pullEventType = models.OtherPullEvent
+	}
+	return
+}
+
+// ParseAzureDevopsPullCommentEvent parses a pull request comment event from
+// Azure DevOps.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseAzureDevopsPullCommentEvent(body []byte) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, user models.User, comment string, err error) {
+	var event azuredevops.CommentEvent
+	if err = json.Unmarshal(body, &event); err != nil {
+		err = errors.Wrap(err, "parsing json")
+		return
+	}
+	if err = validator.New().Struct(event); err != nil {
+		err = errors.Wrapf(err, "API response %q was missing fields", string(body))
+		return
+	}
+	pull, baseRepo, headRepo, err = e.ParseAzureDevopsPull(event.Resource.PullRequest)
+	if err != nil {
+		return
+	}
+	user = models.User{
+		Username: *event.Resource.Comment.Author.UniqueName,
+	}
+	comment = *event.Resource.Comment.Content
+	return
+}
+
+// ParseAzureDevopsPull parses the response from the Azure DevOps API endpoint
+// (not from a webhook) that returns a pull request.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseAzureDevopsPull(adPull *azuredevops.PullRequest) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	var prState models.PullRequestState
+	switch *adPull.Status {
+	case azuredevops.PullActiveStatus:
+		prState = models.OpenPullState
+	case azuredevops.PullCompletedStatus, azuredevops.PullAbandonedStatus:
+		prState = models.ClosedPullState
+	default:
+		err = fmt.Errorf("unable to determine pull request state from %q–this is a bug", *adPull.Status)
+		return
+	}
+
+	baseRepo, err = e.parseAzureDevopsRepo(adPull.Repository)
+	if err != nil {
+		return
+	}
+	headRepo = baseRepo
+	if adPull.ForkSource != nil {
+		headRepo, err = e.parseAzureDevopsRepo(adPull.ForkSource.Repository)
+		if err != nil {
+			return
+		}
+	}
+
+	pull = models.PullRequest{
+		Num:        *adPull.PullRequestID,
+		HeadCommit: *adPull.LastMergeSourceCommit.CommitID,
+		URL:        fmt.Sprintf("%s/pullrequest/%d", baseRepo.SanitizedCloneURL, *adPull.PullRequestID),
+		HeadBranch: strings.TrimPrefix(*adPull.SourceRefName, "refs/heads/"),
+		BaseBranch: strings.TrimPrefix(*adPull.TargetRefName, "refs/heads/"),
+		Author:     *adPull.CreatedBy.UniqueName,
+		State:      prState,
+		BaseRepo:   baseRepo,
+	}
+	return
+}
+
+// parseAzureDevopsRepo parses the repo. Its full name is the organization,
+// project and repo name, ex. org/project/repo. The organization isn't part of
+// the payload so it's taken from the remote URL, ex.
+// https://org@dev.azure.com/org/project/_git/repo.
+func (e *EventParser) parseAzureDevopsRepo(adRepo *azuredevops.Repository) (models.Repo, error) {
+	remoteURL, err := url.Parse(*adRepo.RemoteURL)
+	if err != nil {
+		return models.Repo{}, errors.Wrapf(err, "parsing remote url %q", *adRepo.RemoteURL)
+	}
+	pathParts := strings.Split(strings.TrimPrefix(remoteURL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[2] != "_git" {
+		return models.Repo{}, fmt.Errorf("unable to determine organization from remote url %q", *adRepo.RemoteURL)
+	}
+	// The remote URL includes the organization as the user which we don't
+	// want since we add our own credentials.
+	remoteURL.User = nil
+	fullName := fmt.Sprintf("%s/%s/%s", pathParts[0], *adRepo.Project.Name, *adRepo.Name)
+	return models.NewRepo(models.AzureDevops, fullName, remoteURL.String(), e.AzureDevopsUser, e.AzureDevopsToken)
+}
+
+// ParseGiteaPullEvent parses Gitea pull request events.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseGiteaPullEvent(event gitea.PullRequestEvent) (pull models.PullRequest, pullEventType models.PullRequestEventType, baseRepo models.Repo, headRepo models.Repo, user models.User, err error) {
+	if err = validator.New().Struct(event); err != nil {
+		err = errors.Wrap(err, "event was missing fields")
+		return
+	}
+	pull, baseRepo, headRepo, err = e.ParseGiteaPull(event.PullRequest)
+	if err != nil {
+		return
+	}
+	switch *event.Action {
+	case gitea.PullOpenedAction, gitea.PullReopenedAction:
+		pullEventType = models.OpenedPullEvent
+	case gitea.PullSynchronizedAction:
+		pullEventType = models.UpdatedPullEvent
+	case gitea.PullClosedAction:
+		pullEventType = models.ClosedPullEvent
+	default:
+		pullEventType = models.OtherPullEvent
+	}
+	user = models.User{Username: *event.Sender.Login}
+	return
+}
+
+// ParseGiteaIssueCommentEvent parses Gitea pull request comment events.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseGiteaIssueCommentEvent(event gitea.IssueCommentEvent) (baseRepo models.Repo, user models.User, pullNum int, err error) {
+	if err = validator.New().Struct(event); err != nil {
+		err = errors.Wrap(err, "event was missing fields")
+		return
+	}
+	baseRepo, err = e.parseGiteaRepo(event.Repository)
+	if err != nil {
+		return
+	}
+	user = models.User{Username: *event.Comment.User.Login}
+	pullNum = *event.Issue.Number
+	return
+}
+
+// ParseGiteaPull parses the response from the Gitea API endpoint (not
+// from a webhook) that returns a pull request.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseGiteaPull(giteaPull *gitea.PullRequest) (pull models.PullRequest, baseRepo models.Repo, headRepo models.Repo, err error) {
+	if giteaPull == nil {
+		err = errors.New("pull_request is null")
+		return
+	}
+	if err = validator.New().Struct(giteaPull); err != nil {
+		err = errors.Wrap(err, "pull request was missing fields")
+		return
+	}
+	baseRepo, err = e.parseGiteaRepo(giteaPull.Base.Repo)
+	if err != nil {
+		return
+	}
+	headRepo, err = e.parseGiteaRepo(giteaPull.Head.Repo)
+	if err != nil {
+		return
+	}
+
+	// Gitea's states are open and closed. Merged pull requests are closed.
+	pullState := models.ClosedPullState
+	if *giteaPull.State == "open" {
+		pullState = models.OpenPullState
+	}
+
+	pull = models.PullRequest{
+		Num:        *giteaPull.Number,
+		HeadCommit: *giteaPull.Head.SHA,
+		URL:        *giteaPull.HTMLURL,
+		HeadBranch: *giteaPull.Head.Ref,
+		BaseBranch: *giteaPull.Base.Ref,
+		Author:     *giteaPull.User.Login,
+		State:      pullState,
+		BaseRepo:   baseRepo,
+	}
+	return
+}
+
+func (e *EventParser) parseGiteaRepo(giteaRepo *gitea.Repository) (models.Repo, error) {
+	return models.NewRepo(models.Gitea, *giteaRepo.FullName, *giteaRepo.CloneURL, e.GiteaUser, e.GiteaToken)
+}
@@ -0,0 +1,236 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// ApplyCheckRunAction is the identifier of the check run action that applies
+// the project's plan.
+const ApplyCheckRunAction = "apply"
+
+// maxCheckRunTextLength is the maximum number of chars GitHub allows in the
+// summary and text of a check run's output.
+const maxCheckRunTextLength = 65535
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_github_check_run_updater.go GithubCheckRunUpdater
+
+// GithubCheckRunUpdater creates and updates GitHub check runs.
+type GithubCheckRunUpdater interface {
+	// UpsertCheckRun updates the check run called opts.Name on the head
+	// commit of pull or creates it if it doesn't exist.
+	UpsertCheckRun(repo models.Repo, pull models.PullRequest, opts github.UpdateCheckRunOptions) error
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_project_result_updater.go ProjectResultUpdater
+
+// ProjectResultUpdater reports the result of running a command on a project
+// to the VCS host.
+type ProjectResultUpdater interface {
+	// UpdateProjectResult reports projectResult which is the result of running a
+	// command on the project represented by ctx. url links to the output of
+	// the command.
+	UpdateProjectResult(ctx models.ProjectCommandContext, projectResult models.ProjectResult, url string) error
+}
+
+// CheckRunProject identifies the project a check run is for. It's stored as
+// the check run's external ID so we know which project to apply when the
+// check run's Apply button is clicked.
+type CheckRunProject struct {
+	RepoRelDir  string `json:"dir"`
+	Workspace   string `json:"workspace"`
+	ProjectName string `json:"project,omitempty"`
+}
+
+// NewCheckRunApplyCommand returns the command to run when the Apply button of
+// the check run with externalID is clicked.
+func NewCheckRunApplyCommand(externalID string) (*CommentCommand, error) {
+	var project CheckRunProject
+	if err := json.Unmarshal([]byte(externalID), &project); err != nil {
+		return nil, errors.Wrapf(err, "parsing check run external id %q", externalID)
+	}
+	if project.RepoRelDir == "" && project.ProjectName == "" {
+		return nil, fmt.Errorf("check run external id %q was missing dir", externalID)
+	}
+	// Named projects are applied the same way as "atlantis apply -p name"
+	// which doesn't allow a dir or workspace.
+	if project.ProjectName != "" {
+		return NewCommentCommand("", nil, models.ApplyCommand, false, "", project.ProjectName), nil
+	}
+	return NewCommentCommand(project.RepoRelDir, nil, models.ApplyCommand, false, project.Workspace, ""), nil
+}
+
+// GithubChecksUpdater reports the status and result of each project as a
+// GitHub check run instead of a commit status. The output of the command is
+// in the check run's details so it isn't limited by the comment size. The
+// combined statuses and the statuses of repos on other VCS hosts are still
+// updated with CommitStatusUpdater.
+type GithubChecksUpdater struct {
+	Client              GithubCheckRunUpdater
+	CommitStatusUpdater CommitStatusUpdater
+}
+
+// UpdateCombined updates the combined commit status.
+func (g *GithubChecksUpdater) UpdateCombined(repo models.Repo, pull models.PullRequest, status models.CommitStatus, command models.CommandName) error {
+	return g.CommitStatusUpdater.UpdateCombined(repo, pull, status, command)
+}
+
+// UpdateCombinedCount updates the combined commit status.
+func (g *GithubChecksUpdater) UpdateCombinedCount(repo models.Repo, pull models.PullRequest, status models.CommitStatus, command models.CommandName, numSuccess int, numTotal int) error {
+	return g.CommitStatusUpdater.UpdateCombinedCount(repo, pull, status, command, numSuccess, numTotal)
+}
+
+// UpdateProject updates the status of the project's check run.
+func (g *GithubChecksUpdater) UpdateProject(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus, url string) error {
+	if ctx.BaseRepo.VCSHost.Type != models.Github {
+		return g.CommitStatusUpdater.UpdateProject(ctx, cmdName, status, url)
+	}
+	opts := g.checkRunOptions(ctx, cmdName, url)
+	title := strings.Title(cmdName.String())
+	switch status {
+	case models.PendingCommitStatus:
+		opts.Status = github.String("in_progress")
+		title += " in progress..."
+	case models.FailedCommitStatus:
+		g.complete(&opts, false)
+		title += " failed."
+	case models.SuccessCommitStatus:
+		g.complete(&opts, true)
+		title += " succeeded."
+	}
+	opts.Output = &github.CheckRunOutput{
+		Title:   github.String(title),
+		Summary: github.String(title),
+	}
+	return g.Client.UpsertCheckRun(ctx.BaseRepo, ctx.Pull, opts)
+}
+
+// UpdateProjectResult completes the project's check run with the output of
+// result. Successful plans get an Apply button.
+func (g *GithubChecksUpdater) UpdateProjectResult(ctx models.ProjectCommandContext, result models.ProjectResult, url string) error {
+	if ctx.BaseRepo.VCSHost.Type != models.Github {
+		return g.CommitStatusUpdater.UpdateProject(ctx, result.Command, result.CommitStatus(), url)
+	}
+	opts := g.checkRunOptions(ctx, result.Command, url)
+	g.complete(&opts, result.CommitStatus() == models.SuccessCommitStatus)
+
+	cmdTitle := strings.Title(result.Command.String())
+	var title, summary, text string
+	switch {
+	case result.Error != nil:
+		title = fmt.Sprintf("%s Error", cmdTitle)
+		summary = fmt.Sprintf("**%s Error**", cmdTitle)
+		text = result.Error.Error()
+	case result.Failure != "":
+		title = fmt.Sprintf("%s Failed", cmdTitle)
+		summary = fmt.Sprintf("**%s Failed**: %s", cmdTitle, result.Failure)
+	case result.PlanSuccess != nil:
+		title = result.PlanSuccess.Summary()
+		if title == "" {
+			title = "Plan succeeded."
+		}
+		applyStep := fmt.Sprintf("* :arrow_forward: To **apply** this plan, click **Apply** above or comment:\n"+
+			"    * `%s`\n", result.PlanSuccess.ApplyCmd)
+		// The Apply button can't pass --allow-destroy or approve policy
+		// check failures so we don't show it for plans that need them.
+		if failures := result.PlanSuccess.PolicyCheckFailures; len(failures) > 0 {
+			applyStep = fmt.Sprintf("* :no_entry: This plan failed its policy checks:\n    * %s\n\n"+
+				"  It can't be **applied** until a policy approver reviews the failures and comments:\n"+
+				"    * `%s %s`\n", strings.Join(failures, "\n    * "), atlantisExecutable, models.ApprovePoliciesCommand.String())
+		} else if len(result.PlanSuccess.ProtectedDestroys) > 0 {
+			applyStep = fmt.Sprintf("* :no_entry: This plan destroys resources protected by the `no_destroy` apply requirement. To **apply** it anyway, comment:\n"+
+				"    * `%s --%s`\n", result.PlanSuccess.ApplyCmd, allowDestroyFlag)
+		} else {
+			opts.Actions = []*github.CheckRunAction{
+				{
+					Label:       "Apply",
+					Description: "Apply this plan.",
+					Identifier:  ApplyCheckRunAction,
+				},
+			}
+		}
+		summary = fmt.Sprintf("%s\n\n%s"+
+			"* :put_litter_in_its_place: To **delete** this plan click [here](%s)\n"+
+			"* :repeat: To **plan** this project again, comment:\n"+
+			"    * `%s`",
+			title, applyStep, result.PlanSuccess.LockURL, result.PlanSuccess.RePlanCmd)
+		text = result.PlanSuccess.TerraformOutput
+	default:
+		title = "Apply succeeded."
+		summary = title
+		text = result.ApplySuccess
+	}
+	summary = truncateUTF8(summary, maxCheckRunTextLength)
+	opts.Output = &github.CheckRunOutput{
+		Title:   github.String(title),
+		Summary: github.String(summary),
+	}
+	if text != "" {
+		opts.Output.Text = github.String(checkRunOutputText(text, url))
+	}
+	return g.Client.UpsertCheckRun(ctx.BaseRepo, ctx.Pull, opts)
+}
+
+// checkRunOptions returns the options common to all updates of the check run
+// for running cmdName on the project represented by ctx.
+func (g *GithubChecksUpdater) checkRunOptions(ctx models.ProjectCommandContext, cmdName models.CommandName, url string) github.UpdateCheckRunOptions {
+	// Marshalling a struct of strings can't fail.
+	externalID, _ := json.Marshal(CheckRunProject{
+		RepoRelDir:  ctx.RepoRelDir,
+		Workspace:   ctx.Workspace,
+		ProjectName: ctx.GetProjectName(),
+	})
+	opts := github.UpdateCheckRunOptions{
+		Name:       projectStatusSrc(ctx, cmdName),
+		ExternalID: github.String(string(externalID)),
+	}
+	if url != "" {
+		opts.DetailsURL = github.String(url)
+	}
+	return opts
+}
+
+// complete marks opts as completed.
+func (g *GithubChecksUpdater) complete(opts *github.UpdateCheckRunOptions, success bool) {
+	conclusion := "failure"
+	if success {
+		conclusion = "success"
+	}
+	opts.Status = github.String("completed")
+	opts.Conclusion = github.String(conclusion)
+	opts.CompletedAt = &github.Timestamp{Time: time.Now()}
+}
+
+// checkRunOutputText wraps output in a code block and truncates it to fit in
+// a check run's output. url links to the full output.
+func checkRunOutputText(output string, url string) string {
+	start := "```diff\n"
+	end := "\n```"
+	if len(start)+len(output)+len(end) <= maxCheckRunTextLength {
+		return start + output + end
+	}
+	end += "\n\n**Warning**: Output truncated."
+	if url != "" {
+		end += fmt.Sprintf(" See the [full output](%s).", url)
+	}
+	return start + truncateUTF8(output, maxCheckRunTextLength-len(start)-len(end)) + end
+}
+
+// truncateUTF8 truncates s to at most max bytes without splitting a multibyte
+// character, which would make the check run's output invalid UTF-8.
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
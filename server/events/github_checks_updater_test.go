@@ -0,0 +1,274 @@
+package events_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/go-github/github"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var checksCtx = models.ProjectCommandContext{
+	BaseRepo:   models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: models.Github}},
+	Pull:       models.PullRequest{Num: 1, HeadCommit: "sha"},
+	RepoRelDir: "dir",
+	Workspace:  "default",
+}
+
+func TestGithubChecksUpdater_UpdateProject(t *testing.T) {
+	cases := []struct {
+		status        models.CommitStatus
+		expStatus     string
+		expConclusion string
+		expTitle      string
+	}{
+		{
+			status:    models.PendingCommitStatus,
+			expStatus: "in_progress",
+			expTitle:  "Plan in progress...",
+		},
+		{
+			status:        models.FailedCommitStatus,
+			expStatus:     "completed",
+			expConclusion: "failure",
+			expTitle:      "Plan failed.",
+		},
+		{
+			status:        models.SuccessCommitStatus,
+			expStatus:     "completed",
+			expConclusion: "success",
+			expTitle:      "Plan succeeded.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.expTitle, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			client := mocks.NewMockGithubCheckRunUpdater()
+			u := events.GithubChecksUpdater{Client: client}
+			Ok(t, u.UpdateProject(checksCtx, models.PlanCommand, c.status, "https://job"))
+
+			_, _, opts := client.VerifyWasCalledOnce().UpsertCheckRun(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyGithubUpdateCheckRunOptions()).GetCapturedArguments()
+			Equals(t, "atlantis/plan: dir/default", opts.Name)
+			Equals(t, `{"dir":"dir","workspace":"default"}`, opts.GetExternalID())
+			Equals(t, "https://job", opts.GetDetailsURL())
+			Equals(t, c.expStatus, opts.GetStatus())
+			Equals(t, c.expConclusion, opts.GetConclusion())
+			Equals(t, c.expTitle, opts.Output.GetTitle())
+		})
+	}
+}
+
+// Repos not on GitHub should still get commit statuses.
+func TestGithubChecksUpdater_UpdateProjectNotGithub(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockGithubCheckRunUpdater()
+	statusUpdater := mocks.NewMockCommitStatusUpdater()
+	u := events.GithubChecksUpdater{Client: client, CommitStatusUpdater: statusUpdater}
+	ctx := checksCtx
+	ctx.BaseRepo.VCSHost.Type = models.Gitlab
+
+	Ok(t, u.UpdateProject(ctx, models.PlanCommand, models.PendingCommitStatus, "https://job"))
+	statusUpdater.VerifyWasCalledOnce().UpdateProject(ctx, models.PlanCommand, models.PendingCommitStatus, "https://job")
+	Ok(t, u.UpdateProjectResult(ctx, models.ProjectResult{Command: models.PlanCommand, Failure: "failure"}, "https://job"))
+	statusUpdater.VerifyWasCalledOnce().UpdateProject(ctx, models.PlanCommand, models.FailedCommitStatus, "https://job")
+	client.VerifyWasCalled(Never()).UpsertCheckRun(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyGithubUpdateCheckRunOptions())
+}
+
+func TestGithubChecksUpdater_UpdateProjectResult(t *testing.T) {
+	cases := []struct {
+		description   string
+		result        models.ProjectResult
+		expConclusion string
+		expTitle      string
+		expSummary    string
+		expText       string
+		expActions    bool
+	}{
+		{
+			description: "plan success",
+			result: models.ProjectResult{
+				Command: models.PlanCommand,
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "+ null_resource.test\nPlan: 1 to add, 0 to change, 0 to destroy.",
+					LockURL:         "https://lock",
+					RePlanCmd:       "atlantis plan -d dir",
+					ApplyCmd:        "atlantis apply -d dir",
+				},
+			},
+			expConclusion: "success",
+			expTitle:      "Plan: 1 to add, 0 to change, 0 to destroy.",
+			expSummary:    "Plan: 1 to add, 0 to change, 0 to destroy.\n\n* :arrow_forward: To **apply** this plan, click **Apply** above or comment:\n    * `atlantis apply -d dir`\n* :put_litter_in_its_place: To **delete** this plan click [here](https://lock)\n* :repeat: To **plan** this project again, comment:\n    * `atlantis plan -d dir`",
+			expText:       "```diff\n+ null_resource.test\nPlan: 1 to add, 0 to change, 0 to destroy.\n```",
+			expActions:    true,
+		},
+		{
+			description: "plan with protected destroys",
+			result: models.ProjectResult{
+				Command: models.PlanCommand,
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput:   "- aws_db_instance.main",
+					LockURL:           "https://lock",
+					RePlanCmd:         "atlantis plan -d dir",
+					ApplyCmd:          "atlantis apply -d dir",
+					PlanSummary:       &models.PlanSummary{Destroy: 1, Deletes: []string{"aws_db_instance.main"}},
+					ProtectedDestroys: []string{"aws_db_instance.main"},
+				},
+			},
+			expConclusion: "success",
+			expTitle:      "Plan: 0 to add, 0 to change, 1 to destroy.",
+			expSummary:    "Plan: 0 to add, 0 to change, 1 to destroy.\n\n* :no_entry: This plan destroys resources protected by the `no_destroy` apply requirement. To **apply** it anyway, comment:\n    * `atlantis apply -d dir --allow-destroy`\n* :put_litter_in_its_place: To **delete** this plan click [here](https://lock)\n* :repeat: To **plan** this project again, comment:\n    * `atlantis plan -d dir`",
+			expText:       "```diff\n- aws_db_instance.main\n```",
+		},
+		{
+			description: "plan with policy check failures",
+			result: models.ProjectResult{
+				Command: models.PlanCommand,
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput:     "+ aws_s3_bucket.logs",
+					LockURL:             "https://lock",
+					RePlanCmd:           "atlantis plan -d dir",
+					ApplyCmd:            "atlantis apply -d dir",
+					PolicyCheckFailures: []string{"security: aws_s3_bucket.logs must not be public"},
+				},
+			},
+			expConclusion: "failure",
+			expTitle:      "Plan succeeded.",
+			expSummary:    "Plan succeeded.\n\n* :no_entry: This plan failed its policy checks:\n    * security: aws_s3_bucket.logs must not be public\n\n  It can't be **applied** until a policy approver reviews the failures and comments:\n    * `atlantis approve_policies`\n* :put_litter_in_its_place: To **delete** this plan click [here](https://lock)\n* :repeat: To **plan** this project again, comment:\n    * `atlantis plan -d dir`",
+			expText:       "```diff\n+ aws_s3_bucket.logs\n```",
+		},
+		{
+			description: "plan failure",
+			result: models.ProjectResult{
+				Command: models.PlanCommand,
+				Failure: "Pull request must be approved before running apply.",
+			},
+			expConclusion: "failure",
+			expTitle:      "Plan Failed",
+			expSummary:    "**Plan Failed**: Pull request must be approved before running apply.",
+		},
+		{
+			description: "apply error",
+			result: models.ProjectResult{
+				Command: models.ApplyCommand,
+				Error:   errors.New("error"),
+			},
+			expConclusion: "failure",
+			expTitle:      "Apply Error",
+			expSummary:    "**Apply Error**",
+			expText:       "```diff\nerror\n```",
+		},
+		{
+			description: "apply success",
+			result: models.ProjectResult{
+				Command:      models.ApplyCommand,
+				ApplySuccess: "Apply complete!",
+			},
+			expConclusion: "success",
+			expTitle:      "Apply succeeded.",
+			expSummary:    "Apply succeeded.",
+			expText:       "```diff\nApply complete!\n```",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			client := mocks.NewMockGithubCheckRunUpdater()
+			u := events.GithubChecksUpdater{Client: client}
+			Ok(t, u.UpdateProjectResult(checksCtx, c.result, "https://job"))
+
+			_, _, opts := client.VerifyWasCalledOnce().UpsertCheckRun(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyGithubUpdateCheckRunOptions()).GetCapturedArguments()
+			Equals(t, "atlantis/"+c.result.Command.String()+": dir/default", opts.Name)
+			Equals(t, "completed", opts.GetStatus())
+			Equals(t, c.expConclusion, opts.GetConclusion())
+			Assert(t, opts.CompletedAt != nil, "exp completed at to be set")
+			Equals(t, c.expTitle, opts.Output.GetTitle())
+			Equals(t, c.expSummary, opts.Output.GetSummary())
+			Equals(t, c.expText, opts.Output.GetText())
+			if c.expActions {
+				Equals(t, []*github.CheckRunAction{{Label: "Apply", Description: "Apply this plan.", Identifier: events.ApplyCheckRunAction}}, opts.Actions)
+			} else {
+				Equals(t, 0, len(opts.Actions))
+			}
+		})
+	}
+}
+
+// Output that's too long for the check run should be truncated with a link to
+// the full output.
+func TestGithubChecksUpdater_UpdateProjectResultTruncates(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockGithubCheckRunUpdater()
+	u := events.GithubChecksUpdater{Client: client}
+	result := models.ProjectResult{
+		Command:      models.ApplyCommand,
+		ApplySuccess: strings.Repeat("a", 70000),
+	}
+	Ok(t, u.UpdateProjectResult(checksCtx, result, "https://job"))
+
+	_, _, opts := client.VerifyWasCalledOnce().UpsertCheckRun(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyGithubUpdateCheckRunOptions()).GetCapturedArguments()
+	text := opts.Output.GetText()
+	Equals(t, 65535, len(text))
+	Assert(t, strings.HasSuffix(text, "\n```\n\n**Warning**: Output truncated. See the [full output](https://job)."), "exp truncation warning, got %q", text[len(text)-100:])
+}
+
+// Truncated output shouldn't end in part of a multibyte character since
+// GitHub rejects invalid UTF-8.
+func TestGithubChecksUpdater_UpdateProjectResultTruncatesMultibyte(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockGithubCheckRunUpdater()
+	u := events.GithubChecksUpdater{Client: client}
+	result := models.ProjectResult{
+		Command: models.PlanCommand,
+		Failure: "a" + strings.Repeat("é", 40000),
+	}
+	Ok(t, u.UpdateProjectResult(checksCtx, result, ""))
+
+	_, _, opts := client.VerifyWasCalledOnce().UpsertCheckRun(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyGithubUpdateCheckRunOptions()).GetCapturedArguments()
+	summary := opts.Output.GetSummary()
+	Assert(t, len(summary) <= 65535, "exp summary to be truncated, got %d bytes", len(summary))
+	Assert(t, len(summary) > 65530, "exp summary to only be truncated as much as needed, got %d bytes", len(summary))
+	Assert(t, utf8.ValidString(summary), "exp summary to be valid UTF-8")
+}
+
+func TestNewCheckRunApplyCommand(t *testing.T) {
+	cases := []struct {
+		externalID string
+		exp        *events.CommentCommand
+		expErr     string
+	}{
+		{
+			externalID: `{"dir":"dir","workspace":"staging"}`,
+			exp:        &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir", Workspace: "staging"},
+		},
+		{
+			externalID: `{"dir":"dir","workspace":"staging","project":"proj"}`,
+			exp:        &events.CommentCommand{Name: models.ApplyCommand, ProjectName: "proj"},
+		},
+		{
+			externalID: `{"workspace":"staging"}`,
+			expErr:     `check run external id "{\"workspace\":\"staging\"}" was missing dir`,
+		},
+		{
+			externalID: `not json`,
+			expErr:     `parsing check run external id "not json": invalid character 'o' in literal null (expecting 'u')`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.externalID, func(t *testing.T) {
+			cmd, err := events.NewCheckRunApplyCommand(c.externalID)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, cmd)
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Package jobs holds the output of plans and applies while they run so that
+// it can be streamed to users' browsers.
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+const (
+	// DefaultMaxJobs is the default number of jobs kept in memory.
+	DefaultMaxJobs = 100
+	// DefaultMaxLines is the default number of lines of output kept in memory
+	// for each job.
+	DefaultMaxLines = 10000
+	// subscriberBufferSize is how many lines can be waiting to be read by a
+	// subscriber before it's considered too slow and is dropped.
+	subscriberBufferSize = 1000
+)
+
+// Info describes a job.
+type Info struct {
+	ID          string
+	Command     models.CommandName
+	Repo        models.Repo
+	Pull        models.PullRequest
+	RepoRelDir  string
+	Workspace   string
+	ProjectName string
+	StartTime   time.Time
+	// Complete is true once the command has finished running.
+	Complete bool
+}
+
+// Line is a line of a job's output.
+type Line struct {
+	// Num is the number of the line in the job's output, starting at 0.
+	Num  int
+	Text string
+}
+
+// Store keeps the output of the most recent jobs in memory and fans out new
+// output to subscribers. It's safe for concurrent use.
+type Store struct {
+	mu       sync.Mutex
+	maxJobs  int
+	maxLines int
+	jobs     map[string]*job
+	// order is the IDs of jobs in the order they were created so we know
+	// which to evict first.
+	order []string
+}
+
+type job struct {
+	info Info
+	// lines are the most recent lines of output. Older lines are dropped
+	// once there are more than maxLines.
+	lines []Line
+	// nextNum is the Num of the next line to be written.
+	nextNum     int
+	subscribers map[chan Line]struct{}
+}
+
+// NewStore returns a Store that keeps up to maxJobs jobs with up to maxLines
+// lines of output each.
+func NewStore(maxJobs int, maxLines int) *Store {
+	return &Store{
+		maxJobs:  maxJobs,
+		maxLines: maxLines,
+		jobs:     make(map[string]*job),
+	}
+}
+
+// NewJob creates a job for running cmdName on the project in ctx and returns
+// its ID. If the store is full, the oldest job is evicted, preferring jobs
+// that are complete.
+func (s *Store) NewJob(ctx models.ProjectCommandContext, cmdName models.CommandName) string {
+	id := newID()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.order) >= s.maxJobs {
+		s.evict()
+	}
+	s.jobs[id] = &job{
+		info: Info{
+			ID:          id,
+			Command:     cmdName,
+			Repo:        ctx.BaseRepo,
+			Pull:        ctx.Pull,
+			RepoRelDir:  ctx.RepoRelDir,
+			Workspace:   ctx.Workspace,
+			ProjectName: ctx.GetProjectName(),
+			StartTime:   time.Now(),
+		},
+		subscribers: make(map[chan Line]struct{}),
+	}
+	s.order = append(s.order, id)
+	return id
+}
+
+// WriteLine appends line to the output of the job with id jobID and sends it
+// to the job's subscribers. It's a no-op if the job doesn't exist.
+func (s *Store) WriteLine(jobID string, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[jobID]
+	if !ok || j.info.Complete {
+		return
+	}
+
+	l := Line{Num: j.nextNum, Text: line}
+	j.nextNum++
+	j.lines = append(j.lines, l)
+	if len(j.lines) > s.maxLines {
+		j.lines = j.lines[len(j.lines)-s.maxLines:]
+	}
+	for ch := range j.subscribers {
+		select {
+		case ch <- l:
+		default:
+			// We never block the command on a slow subscriber. Instead we
+			// drop it and it can resubscribe from the last line it got.
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Complete marks the job with id jobID as complete and closes its
+// subscribers' channels. It's a no-op if the job doesn't exist.
+func (s *Store) Complete(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[jobID]
+	if !ok {
+		return
+	}
+	j.info.Complete = true
+	j.closeSubscribers()
+}
+
+// Get returns the info for the job with id jobID. It returns false if there's
+// no such job.
+func (s *Store) Get(jobID string) (Info, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[jobID]
+	if !ok {
+		return Info{}, false
+	}
+	return j.info, true
+}
+
+// Subscribe returns the lines of output of the job with id jobID, starting at
+// line number from, that are still in memory. New lines are sent on the
+// returned channel. The channel is closed once the job completes or if the
+// subscriber doesn't keep up, in which case it can subscribe again from the
+// line after the last one it got. unsubscribe must be called once the caller
+// is done with the channel. It returns false if there's no such job.
+func (s *Store) Subscribe(jobID string, from int) (lines []Line, ch <-chan Line, unsubscribe func(), ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[jobID]
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	for _, l := range j.lines {
+		if l.Num >= from {
+			lines = append(lines, l)
+		}
+	}
+	subCh := make(chan Line, subscriberBufferSize)
+	if j.info.Complete {
+		close(subCh)
+		return lines, subCh, func() {}, true
+	}
+	j.subscribers[subCh] = struct{}{}
+	unsubscribe = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := j.subscribers[subCh]; ok {
+			delete(j.subscribers, subCh)
+			close(subCh)
+		}
+	}
+	return lines, subCh, unsubscribe, true
+}
+
+// evict deletes the oldest complete job or if they're all running, the
+// oldest job. s.mu must be held.
+func (s *Store) evict() {
+	idx := 0
+	for i, id := range s.order {
+		if s.jobs[id].info.Complete {
+			idx = i
+			break
+		}
+	}
+	id := s.order[idx]
+	s.jobs[id].closeSubscribers()
+	delete(s.jobs, id)
+	s.order = append(s.order[:idx], s.order[idx+1:]...)
+}
+
+func (j *job) closeSubscribers() {
+	for ch := range j.subscribers {
+		close(ch)
+	}
+	j.subscribers = make(map[chan Line]struct{})
+}
+
+// newID returns a random job ID. IDs are random so they can't be guessed.
+func newID() string {
+	b := make([]byte, 16)
+	// crypto/rand only errors if the OS's source of randomness is
+	// unavailable in which case we have bigger problems.
+	rand.Read(b) // nolint: errcheck
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,168 @@
+package jobs_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/jobs"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var ctx = models.ProjectCommandContext{
+	BaseRepo:   models.Repo{FullName: "owner/repo"},
+	Pull:       models.PullRequest{Num: 1},
+	RepoRelDir: "dir",
+	Workspace:  "default",
+}
+
+func TestNewJob(t *testing.T) {
+	s := jobs.NewStore(10, 10)
+	id := s.NewJob(ctx, models.PlanCommand)
+	Assert(t, len(id) == 32, "exp id to be 32 chars, got %q", id)
+	Assert(t, s.NewJob(ctx, models.PlanCommand) != id, "exp ids to be unique")
+
+	info, ok := s.Get(id)
+	Assert(t, ok, "exp job to exist")
+	Equals(t, id, info.ID)
+	Equals(t, models.PlanCommand, info.Command)
+	Equals(t, "owner/repo", info.Repo.FullName)
+	Equals(t, 1, info.Pull.Num)
+	Equals(t, "dir", info.RepoRelDir)
+	Equals(t, "default", info.Workspace)
+	Equals(t, false, info.Complete)
+}
+
+func TestGet_NoJob(t *testing.T) {
+	s := jobs.NewStore(10, 10)
+	_, ok := s.Get("nope")
+	Equals(t, false, ok)
+	_, _, _, ok = s.Subscribe("nope", 0)
+	Equals(t, false, ok)
+}
+
+func TestSubscribe_Backlog(t *testing.T) {
+	s := jobs.NewStore(10, 10)
+	id := s.NewJob(ctx, models.PlanCommand)
+	s.WriteLine(id, "a")
+	s.WriteLine(id, "b")
+	s.WriteLine(id, "c")
+
+	lines, _, unsubscribe, ok := s.Subscribe(id, 1)
+	defer unsubscribe()
+	Assert(t, ok, "exp job to exist")
+	Equals(t, []jobs.Line{{Num: 1, Text: "b"}, {Num: 2, Text: "c"}}, lines)
+}
+
+func TestSubscribe_NewLinesAndComplete(t *testing.T) {
+	s := jobs.NewStore(10, 10)
+	id := s.NewJob(ctx, models.PlanCommand)
+	s.WriteLine(id, "a")
+
+	lines, ch, unsubscribe, ok := s.Subscribe(id, 0)
+	defer unsubscribe()
+	Assert(t, ok, "exp job to exist")
+	Equals(t, []jobs.Line{{Num: 0, Text: "a"}}, lines)
+
+	s.WriteLine(id, "b")
+	s.Complete(id)
+	// Lines written after the job completes are ignored.
+	s.WriteLine(id, "c")
+
+	var got []jobs.Line
+	for l := range ch {
+		got = append(got, l)
+	}
+	Equals(t, []jobs.Line{{Num: 1, Text: "b"}}, got)
+	info, _ := s.Get(id)
+	Equals(t, true, info.Complete)
+}
+
+func TestSubscribe_AlreadyComplete(t *testing.T) {
+	s := jobs.NewStore(10, 10)
+	id := s.NewJob(ctx, models.PlanCommand)
+	s.WriteLine(id, "a")
+	s.Complete(id)
+
+	lines, ch, unsubscribe, ok := s.Subscribe(id, 0)
+	defer unsubscribe()
+	Assert(t, ok, "exp job to exist")
+	Equals(t, []jobs.Line{{Num: 0, Text: "a"}}, lines)
+	_, open := <-ch
+	Equals(t, false, open)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	s := jobs.NewStore(10, 10)
+	id := s.NewJob(ctx, models.PlanCommand)
+	_, ch, unsubscribe, _ := s.Subscribe(id, 0)
+	unsubscribe()
+	_, open := <-ch
+	Equals(t, false, open)
+
+	// Writing and completing after unsubscribing shouldn't panic.
+	s.WriteLine(id, "a")
+	s.Complete(id)
+	unsubscribe()
+}
+
+func TestWriteLine_MaxLines(t *testing.T) {
+	s := jobs.NewStore(10, 2)
+	id := s.NewJob(ctx, models.PlanCommand)
+	s.WriteLine(id, "a")
+	s.WriteLine(id, "b")
+	s.WriteLine(id, "c")
+
+	lines, _, unsubscribe, _ := s.Subscribe(id, 0)
+	defer unsubscribe()
+	Equals(t, []jobs.Line{{Num: 1, Text: "b"}, {Num: 2, Text: "c"}}, lines)
+}
+
+func TestWriteLine_SlowSubscriberDropped(t *testing.T) {
+	s := jobs.NewStore(10, 5000)
+	id := s.NewJob(ctx, models.PlanCommand)
+	_, ch, unsubscribe, _ := s.Subscribe(id, 0)
+	defer unsubscribe()
+
+	// Write more lines than the subscriber's buffer without reading any.
+	for i := 0; i < 2000; i++ {
+		s.WriteLine(id, fmt.Sprintf("%d", i))
+	}
+	count := 0
+	for range ch {
+		count++
+	}
+	Assert(t, count > 0 && count < 2000, "exp subscriber to get some lines before being dropped, got %d", count)
+}
+
+func TestNewJob_EvictsOldestComplete(t *testing.T) {
+	s := jobs.NewStore(2, 10)
+	running := s.NewJob(ctx, models.PlanCommand)
+	complete := s.NewJob(ctx, models.PlanCommand)
+	s.Complete(complete)
+
+	newest := s.NewJob(ctx, models.ApplyCommand)
+	_, ok := s.Get(complete)
+	Equals(t, false, ok)
+	_, ok = s.Get(running)
+	Equals(t, true, ok)
+	_, ok = s.Get(newest)
+	Equals(t, true, ok)
+}
+
+func TestNewJob_EvictsOldestRunning(t *testing.T) {
+	s := jobs.NewStore(2, 10)
+	oldest := s.NewJob(ctx, models.PlanCommand)
+	_, ch, unsubscribe, _ := s.Subscribe(oldest, 0)
+	defer unsubscribe()
+	second := s.NewJob(ctx, models.PlanCommand)
+
+	s.NewJob(ctx, models.PlanCommand)
+	_, ok := s.Get(oldest)
+	Equals(t, false, ok)
+	_, ok = s.Get(second)
+	Equals(t, true, ok)
+	// The evicted job's subscribers should be closed.
+	_, open := <-ch
+	Equals(t, false, open)
+}
@@ -0,0 +1,107 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/db"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// LockReaperInterval is how often LockReaper checks for expired locks.
+const LockReaperInterval = 1 * time.Minute
+
+// LockReaper releases locks that have been held for longer than their TTL.
+type LockReaper struct {
+	Locker           locking.Locker
+	VCSClient        vcs.Client
+	WorkingDir       WorkingDir
+	WorkingDirLocker WorkingDirLocker
+	DB               db.Database
+	Logger           logging.SimpleLogging
+	// DefaultTTL is the TTL used for locks that don't set their own. If 0,
+	// those locks never expire.
+	DefaultTTL time.Duration
+}
+
+// Run checks for expired locks every interval until stop is closed.
+func (r *LockReaper) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.ReapExpired(time.Now())
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ReapExpired releases all locks that have expired as of now, deletes their
+// plans and comments on their pull requests to explain why.
+func (r *LockReaper) ReapExpired(now time.Time) {
+	locks, err := r.Locker.List()
+	if err != nil {
+		r.Logger.Err("listing locks to check for expiry: %s", err)
+		return
+	}
+	for key, lock := range locks {
+		ttl := r.ttl(lock)
+		if ttl == 0 || now.Sub(lock.Time) < ttl {
+			continue
+		}
+		r.Logger.Info("lock %q has been held since %s which is longer than its TTL of %s, releasing", key, lock.Time, ttl)
+		if err := r.release(key, lock, ttl); err != nil {
+			r.Logger.Err("releasing expired lock %q: %s", key, err)
+		}
+	}
+}
+
+func (r *LockReaper) release(key string, lock models.ProjectLock, ttl time.Duration) error {
+	deleted, err := r.Locker.UnlockIfUnchanged(lock)
+	if err != nil {
+		return err
+	}
+	// The lock might have been released, and maybe taken by another pull
+	// request, since we listed it.
+	if deleted == nil {
+		r.Logger.Debug("lock %q changed since it was listed, skipping", key)
+		return nil
+	}
+
+	// NOTE: Because BaseRepo was added to the PullRequest model later, previous
+	// installations of Atlantis will have locks in their DB that do not have
+	// this field on PullRequest. We skip commenting and deleting the working dir in this case.
+	if lock.Pull.BaseRepo == (models.Repo{}) {
+		r.Logger.Debug("skipping commenting on pull request and deleting workspace because BaseRepo field is empty")
+		return nil
+	}
+	unlock, err := r.WorkingDirLocker.TryLock(lock.Pull.BaseRepo.FullName, lock.Pull.Num, lock.Workspace)
+	if err != nil {
+		r.Logger.Err("unable to obtain working dir lock when trying to delete old plans: %s", err)
+	} else {
+		defer unlock()
+		if err := r.WorkingDir.DeleteForWorkspace(lock.Pull.BaseRepo, lock.Pull, lock.Workspace); err != nil {
+			r.Logger.Err("unable to delete workspace: %s", err)
+		}
+	}
+	if err := r.DB.DeleteProjectStatus(lock.Pull, lock.Workspace, lock.Project.Path); err != nil {
+		r.Logger.Err("unable to delete project status: %s", err)
+	}
+
+	comment := fmt.Sprintf("**Warning**: The lock for dir: `%s` workspace: `%s` was held for longer than its TTL of `%s` so it was **released** and its plan was **discarded**.\n\n"+
+		"To `apply` this plan you must run `plan` again.", lock.Project.Path, lock.Workspace, ttl)
+	return r.VCSClient.CreateComment(lock.Pull.BaseRepo, lock.Pull.Num, comment)
+}
+
+// ttl returns the TTL that applies to lock.
+func (r *LockReaper) ttl(lock models.ProjectLock) time.Duration {
+	if lock.TTL > 0 {
+		return lock.TTL
+	}
+	return r.DefaultTTL
+}
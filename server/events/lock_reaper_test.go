@@ -0,0 +1,134 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/db"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestLockReaper_ReapExpired(t *testing.T) {
+	now := time.Now()
+	lockedAt := now.Add(-2 * time.Hour)
+	cases := []struct {
+		description string
+		defaultTTL  time.Duration
+		lockTTL     time.Duration
+		expReaped   bool
+	}{
+		{
+			"no ttl",
+			0,
+			0,
+			false,
+		},
+		{
+			"default ttl not expired",
+			3 * time.Hour,
+			0,
+			false,
+		},
+		{
+			"default ttl expired",
+			1 * time.Hour,
+			0,
+			true,
+		},
+		{
+			"lock ttl overrides default",
+			1 * time.Hour,
+			3 * time.Hour,
+			false,
+		},
+		{
+			"lock ttl expired without default",
+			0,
+			1 * time.Hour,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			locker := lockmocks.NewMockLocker()
+			vcsClient := vcsmocks.NewMockClient()
+			workingDir := mocks.NewMockWorkingDir()
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			boltDB, err := db.New(tmp)
+			Ok(t, err)
+
+			pull := fixtures.Pull
+			pull.BaseRepo = fixtures.GithubRepo
+			lock := models.ProjectLock{
+				Project:   models.NewProject(fixtures.GithubRepo.FullName, "path"),
+				Workspace: "default",
+				Pull:      pull,
+				Time:      lockedAt,
+				TTL:       c.lockTTL,
+			}
+			key := "runatlantis/atlantis/path/default"
+			When(locker.List()).ThenReturn(map[string]models.ProjectLock{key: lock}, nil)
+			When(locker.UnlockIfUnchanged(lock)).ThenReturn(&lock, nil)
+
+			reaper := events.LockReaper{
+				Locker:           locker,
+				VCSClient:        vcsClient,
+				WorkingDir:       workingDir,
+				WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+				DB:               boltDB,
+				Logger:           logging.NewNoopLogger(),
+				DefaultTTL:       c.defaultTTL,
+			}
+			reaper.ReapExpired(now)
+
+			if !c.expReaped {
+				locker.VerifyWasCalled(Never()).UnlockIfUnchanged(lock)
+				return
+			}
+			locker.VerifyWasCalledOnce().UnlockIfUnchanged(lock)
+			workingDir.VerifyWasCalledOnce().DeleteForWorkspace(fixtures.GithubRepo, pull, "default")
+			_, _, comment := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
+			Equals(t, "**Warning**: The lock for dir: `path` workspace: `default` was held for longer than its TTL of `1h0m0s` so it was **released** and its plan was **discarded**.\n\nTo `apply` this plan you must run `plan` again.", comment)
+		})
+	}
+}
+
+func TestLockReaper_ReapExpiredAlreadyUnlocked(t *testing.T) {
+	t.Log("if the lock was deleted or taken again after we listed it we" +
+		" shouldn't comment")
+	RegisterMockTestingT(t)
+	locker := lockmocks.NewMockLocker()
+	vcsClient := vcsmocks.NewMockClient()
+	key := "runatlantis/atlantis/path/default"
+	lock := models.ProjectLock{
+		Project:   models.NewProject(fixtures.GithubRepo.FullName, "path"),
+		Workspace: "default",
+		Pull:      fixtures.Pull,
+		Time:      time.Now().Add(-2 * time.Hour),
+	}
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{key: lock}, nil)
+	When(locker.UnlockIfUnchanged(lock)).ThenReturn(nil, nil)
+
+	reaper := events.LockReaper{
+		Locker:     locker,
+		VCSClient:  vcsClient,
+		Logger:     logging.NewNoopLogger(),
+		DefaultTTL: time.Hour,
+	}
+	reaper.ReapExpired(time.Now())
+	locker.VerifyWasCalledOnce().UnlockIfUnchanged(lock)
+	locker.VerifyWasCalled(Never()).Unlock(key)
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+}
@@ -29,16 +29,24 @@ import (
 type Backend interface {
 	TryLock(lock models.ProjectLock) (bool, models.ProjectLock, error)
 	Unlock(project models.Project, workspace string) (*models.ProjectLock, error)
+	UnlockIfUnchanged(lock models.ProjectLock) (*models.ProjectLock, error)
 	List() ([]models.ProjectLock, error)
 	GetLock(project models.Project, workspace string) (*models.ProjectLock, error)
 	UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error)
+
+	Enqueue(lock models.ProjectLock) (bool, int, error)
+	GetQueue(project models.Project, workspace string) ([]models.ProjectLock, error)
+	DequeueByPull(repoFullName string, pullNum int) error
+	Dequeue(project models.Project, workspace string, pullNum int) error
+	PromoteNext(project models.Project, workspace string) (*models.ProjectLock, error)
 }
 
 // TryLockResponse results from an attempted lock.
 type TryLockResponse struct {
 	// LockAcquired is true if the lock was acquired from this call.
 	LockAcquired bool
-	// CurrLock is what project is currently holding the lock.
+	// CurrLock is what project is currently holding the lock. If the lock is
+	// free but other pull requests are queued for it, it's the first of them.
 	CurrLock models.ProjectLock
 	// LockKey is an identified by which to lookup and delete this lock.
 	LockKey string
@@ -47,16 +55,25 @@ type TryLockResponse struct {
 // Client is used to perform locking actions.
 type Client struct {
 	backend Backend
+	// onPromote is called with the new lock when a lock is handed to the
+	// next pull request in its queue. If nil, queued pull requests are never
+	// given the lock.
+	onPromote func(lock models.ProjectLock)
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_locker.go Locker
 
 type Locker interface {
-	TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (TryLockResponse, error)
+	TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, ttl time.Duration) (TryLockResponse, error)
 	Unlock(key string) (*models.ProjectLock, error)
+	UnlockIfUnchanged(lock models.ProjectLock) (*models.ProjectLock, error)
 	List() (map[string]models.ProjectLock, error)
 	UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error)
 	GetLock(key string) (*models.ProjectLock, error)
+	Enqueue(p models.Project, workspace string, pull models.PullRequest, user models.User, ttl time.Duration) (bool, int, error)
+	GetQueue(key string) ([]models.ProjectLock, error)
+	DequeueByPull(repoFullName string, pullNum int) error
+	Dequeue(p models.Project, workspace string, pullNum int) error
 }
 
 // NewClient returns a new locking client.
@@ -66,17 +83,32 @@ func NewClient(backend Backend) *Client {
 	}
 }
 
+// NewQueueingClient returns a new locking client that hands released locks to
+// the next pull request waiting in that lock's queue. onPromote is called
+// with each lock that is handed over.
+func NewQueueingClient(backend Backend, onPromote func(lock models.ProjectLock)) *Client {
+	return &Client{
+		backend:   backend,
+		onPromote: onPromote,
+	}
+}
+
 // keyRegex matches and captures {repoFullName}/{path}/{workspace} where path can have multiple /'s in it.
 var keyRegex = regexp.MustCompile(`^(.*?\/.*?)\/(.*)\/(.*)$`)
 
-// TryLock attempts to acquire a lock to a project and workspace.
-func (c *Client) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (TryLockResponse, error) {
+// TryLock attempts to acquire a lock to a project and workspace. The lock
+// isn't acquired while other pull requests are queued for it, even if it's
+// free, so that nobody jumps the queue.
+// ttl is how long the lock can be held for. If 0, the server's default is
+// used.
+func (c *Client) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, ttl time.Duration) (TryLockResponse, error) {
 	lock := models.ProjectLock{
 		Workspace: workspace,
 		Time:      time.Now().Local(),
 		Project:   p,
 		User:      user,
 		Pull:      pull,
+		TTL:       ttl,
 	}
 	lockAcquired, currLock, err := c.backend.TryLock(lock)
 	if err != nil {
@@ -94,7 +126,30 @@ func (c *Client) Unlock(key string) (*models.ProjectLock, error) {
 	if err != nil {
 		return nil, err
 	}
-	return c.backend.Unlock(project, workspace)
+	lock, err := c.backend.Unlock(project, workspace)
+	if err != nil {
+		return nil, err
+	}
+	if lock != nil {
+		err = c.promoteNext(project, workspace)
+	}
+	return lock, err
+}
+
+// UnlockIfUnchanged unlocks lock's project and workspace only if the lock is
+// still held by lock's pull request since lock.Time. This way a lock that
+// was read, ex. to check if it expired, isn't deleted if it was released and
+// taken again since. If successful, a pointer to the now deleted lock will be
+// returned. Else, that pointer will be nil.
+func (c *Client) UnlockIfUnchanged(lock models.ProjectLock) (*models.ProjectLock, error) {
+	deleted, err := c.backend.UnlockIfUnchanged(lock)
+	if err != nil {
+		return nil, err
+	}
+	if deleted != nil {
+		err = c.promoteNext(lock.Project, lock.Workspace)
+	}
+	return deleted, err
 }
 
 // List returns a map of all locks with their lock key as the map key.
@@ -113,7 +168,16 @@ func (c *Client) List() (map[string]models.ProjectLock, error) {
 
 // UnlockByPull deletes all locks associated with that pull request.
 func (c *Client) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
-	return c.backend.UnlockByPull(repoFullName, pullNum)
+	locks, err := c.backend.UnlockByPull(repoFullName, pullNum)
+	if err != nil {
+		return locks, err
+	}
+	for _, lock := range locks {
+		if err := c.promoteNext(lock.Project, lock.Workspace); err != nil {
+			return locks, err
+		}
+	}
+	return locks, nil
 }
 
 // GetLock attempts to get the lock stored at key. If successful,
@@ -134,6 +198,61 @@ func (c *Client) GetLock(key string) (*models.ProjectLock, error) {
 	return projectLock, nil
 }
 
+// Enqueue adds the pull request to the queue of pull requests waiting for the
+// lock on project p and workspace. It returns the pull request's 1-based
+// position in the queue. ttl is used for the lock once the pull request
+// acquires it. If the lock was released since TryLock failed and nobody is
+// ahead of the pull request, it acquires the lock instead and true is
+// returned.
+func (c *Client) Enqueue(p models.Project, workspace string, pull models.PullRequest, user models.User, ttl time.Duration) (bool, int, error) {
+	lock := models.ProjectLock{
+		Workspace: workspace,
+		Time:      time.Now().Local(),
+		Project:   p,
+		User:      user,
+		Pull:      pull,
+		TTL:       ttl,
+	}
+	return c.backend.Enqueue(lock)
+}
+
+// GetQueue returns the pull requests waiting for the lock stored at key in
+// the order they will acquire it.
+func (c *Client) GetQueue(key string) ([]models.ProjectLock, error) {
+	project, workspace, err := c.lockKeyToProjectWorkspace(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.backend.GetQueue(project, workspace)
+}
+
+// DequeueByPull removes the pull request from all the queues it's waiting in.
+func (c *Client) DequeueByPull(repoFullName string, pullNum int) error {
+	return c.backend.DequeueByPull(repoFullName, pullNum)
+}
+
+// Dequeue removes the pull request from the queue for the lock on project p
+// and workspace.
+func (c *Client) Dequeue(p models.Project, workspace string, pullNum int) error {
+	return c.backend.Dequeue(p, workspace, pullNum)
+}
+
+// promoteNext hands the lock for project p and workspace to the next pull
+// request in its queue, if queueing is enabled.
+func (c *Client) promoteNext(p models.Project, workspace string) error {
+	if c.onPromote == nil {
+		return nil
+	}
+	promoted, err := c.backend.PromoteNext(p, workspace)
+	if err != nil {
+		return err
+	}
+	if promoted != nil {
+		c.onPromote(*promoted)
+	}
+	return nil
+}
+
 func (c *Client) key(p models.Project, workspace string) string {
 	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
 }
@@ -42,7 +42,7 @@ func TestTryLock_Err(t *testing.T) {
 	When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(false, models.ProjectLock{}, errExpected)
 	t.Log("when the backend returns an error, TryLock should return that error")
 	l := locking.NewClient(backend)
-	_, err := l.TryLock(project, workspace, pull, user)
+	_, err := l.TryLock(project, workspace, pull, user, 0)
 	Equals(t, err, err)
 }
 
@@ -52,7 +52,7 @@ func TestTryLock_Success(t *testing.T) {
 	backend := mocks.NewMockBackend()
 	When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(true, currLock, nil)
 	l := locking.NewClient(backend)
-	r, err := l.TryLock(project, workspace, pull, user)
+	r, err := l.TryLock(project, workspace, pull, user, 0)
 	Ok(t, err)
 	Equals(t, locking.TryLockResponse{LockAcquired: true, CurrLock: currLock, LockKey: "owner/repo/path/workspace"}, r)
 }
@@ -87,6 +87,86 @@ func TestUnlock(t *testing.T) {
 	Equals(t, &pl, lock)
 }
 
+func TestUnlock_PromotesQueued(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.Unlock(matchers.AnyModelsProject(), AnyString())).ThenReturn(&pl, nil)
+	queued := pl
+	queued.Pull.Num = 2
+	When(backend.PromoteNext(project, workspace)).ThenReturn(&queued, nil)
+
+	var promoted []models.ProjectLock
+	l := locking.NewQueueingClient(backend, func(lock models.ProjectLock) {
+		promoted = append(promoted, lock)
+	})
+	lock, err := l.Unlock("owner/repo/path/workspace")
+	Ok(t, err)
+	Equals(t, &pl, lock)
+	Equals(t, []models.ProjectLock{queued}, promoted)
+}
+
+func TestUnlockIfUnchanged_PromotesQueued(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.UnlockIfUnchanged(pl)).ThenReturn(&pl, nil)
+	queued := pl
+	queued.Pull.Num = 2
+	When(backend.PromoteNext(pl.Project, pl.Workspace)).ThenReturn(&queued, nil)
+
+	var promoted []models.ProjectLock
+	l := locking.NewQueueingClient(backend, func(lock models.ProjectLock) {
+		promoted = append(promoted, lock)
+	})
+	lock, err := l.UnlockIfUnchanged(pl)
+	Ok(t, err)
+	Equals(t, &pl, lock)
+	Equals(t, []models.ProjectLock{queued}, promoted)
+}
+
+func TestUnlockIfUnchanged_Changed(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.UnlockIfUnchanged(pl)).ThenReturn(nil, nil)
+	l := locking.NewQueueingClient(backend, func(models.ProjectLock) {})
+	lock, err := l.UnlockIfUnchanged(pl)
+	Ok(t, err)
+	Assert(t, lock == nil, "exp nil lock")
+	backend.VerifyWasCalled(Never()).PromoteNext(matchers.AnyModelsProject(), AnyString())
+}
+
+func TestEnqueue(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.Enqueue(matchers.AnyModelsProjectLock())).ThenReturn(false, 2, nil)
+	l := locking.NewQueueingClient(backend, nil)
+	acquired, pos, err := l.Enqueue(project, workspace, pull, user, time.Hour)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, 2, pos)
+
+	queued := backend.VerifyWasCalledOnce().Enqueue(matchers.AnyModelsProjectLock()).GetCapturedArguments()
+	Equals(t, project, queued.Project)
+	Equals(t, time.Hour, queued.TTL)
+}
+
+func TestDequeue(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	l := locking.NewClient(backend)
+	Ok(t, l.Dequeue(project, workspace, 2))
+	backend.VerifyWasCalledOnce().Dequeue(project, workspace, 2)
+}
+
+func TestUnlock_NoQueueing(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.Unlock(matchers.AnyModelsProject(), AnyString())).ThenReturn(&pl, nil)
+	l := locking.NewClient(backend)
+	_, err := l.Unlock("owner/repo/path/workspace")
+	Ok(t, err)
+	backend.VerifyWasCalled(Never()).PromoteNext(matchers.AnyModelsProject(), AnyString())
+}
+
 func TestList_Err(t *testing.T) {
 	RegisterMockTestingT(t)
 	backend := mocks.NewMockBackend()
@@ -117,6 +197,42 @@ func TestUnlockByPull(t *testing.T) {
 	Equals(t, errExpected, err)
 }
 
+func TestUnlockByPull_PromotesQueued(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.UnlockByPull("owner/repo", 1)).ThenReturn([]models.ProjectLock{pl}, nil)
+	queued := pl
+	queued.Pull.Num = 2
+	When(backend.PromoteNext(project, workspace)).ThenReturn(&queued, nil)
+
+	var promoted []models.ProjectLock
+	l := locking.NewQueueingClient(backend, func(lock models.ProjectLock) {
+		promoted = append(promoted, lock)
+	})
+	_, err := l.UnlockByPull("owner/repo", 1)
+	Ok(t, err)
+	Equals(t, []models.ProjectLock{queued}, promoted)
+}
+
+func TestGetQueue_BadKey(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	l := locking.NewClient(backend)
+	_, err := l.GetQueue("invalidkey")
+	Assert(t, err != nil, "err should not be nil")
+	Assert(t, strings.Contains(err.Error(), "invalid key format"), "expected different err")
+}
+
+func TestGetQueue(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.GetQueue(project, workspace)).ThenReturn([]models.ProjectLock{pl}, nil)
+	l := locking.NewClient(backend)
+	queue, err := l.GetQueue("owner/repo/path/workspace")
+	Ok(t, err)
+	Equals(t, []models.ProjectLock{pl}, queue)
+}
+
 func TestGetLock_BadKey(t *testing.T) {
 	RegisterMockTestingT(t)
 	backend := mocks.NewMockBackend()
@@ -26,11 +26,11 @@ func NewMockLocker(options ...pegomock.Option) *MockLocker {
 func (mock *MockLocker) SetFailHandler(fh pegomock.FailHandler) { mock.fail = fh }
 func (mock *MockLocker) FailHandler() pegomock.FailHandler      { return mock.fail }
 
-func (mock *MockLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (locking.TryLockResponse, error) {
+func (mock *MockLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, ttl time.Duration) (locking.TryLockResponse, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockLocker().")
 	}
-	params := []pegomock.Param{p, workspace, pull, user}
+	params := []pegomock.Param{p, workspace, pull, user, ttl}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("TryLock", params, []reflect.Type{reflect.TypeOf((*locking.TryLockResponse)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 locking.TryLockResponse
 	var ret1 error
@@ -64,6 +64,25 @@ func (mock *MockLocker) Unlock(key string) (*models.ProjectLock, error) {
 	return ret0, ret1
 }
 
+func (mock *MockLocker) UnlockIfUnchanged(lock models.ProjectLock) (*models.ProjectLock, error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockLocker().")
+	}
+	params := []pegomock.Param{lock}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("UnlockIfUnchanged", params, []reflect.Type{reflect.TypeOf((**models.ProjectLock)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 *models.ProjectLock
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(*models.ProjectLock)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
 func (mock *MockLocker) List() (map[string]models.ProjectLock, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockLocker().")
@@ -121,6 +140,78 @@ func (mock *MockLocker) GetLock(key string) (*models.ProjectLock, error) {
 	return ret0, ret1
 }
 
+func (mock *MockLocker) Enqueue(p models.Project, workspace string, pull models.PullRequest, user models.User, ttl time.Duration) (bool, int, error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockLocker().")
+	}
+	params := []pegomock.Param{p, workspace, pull, user, ttl}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("Enqueue", params, []reflect.Type{reflect.TypeOf((*bool)(nil)).Elem(), reflect.TypeOf((*int)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 bool
+	var ret1 int
+	var ret2 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(bool)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(int)
+		}
+		if result[2] != nil {
+			ret2 = result[2].(error)
+		}
+	}
+	return ret0, ret1, ret2
+}
+
+func (mock *MockLocker) GetQueue(key string) ([]models.ProjectLock, error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockLocker().")
+	}
+	params := []pegomock.Param{key}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("GetQueue", params, []reflect.Type{reflect.TypeOf((*[]models.ProjectLock)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 []models.ProjectLock
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].([]models.ProjectLock)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
+func (mock *MockLocker) DequeueByPull(repoFullName string, pullNum int) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockLocker().")
+	}
+	params := []pegomock.Param{repoFullName, pullNum}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("DequeueByPull", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockLocker) Dequeue(p models.Project, workspace string, pullNum int) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockLocker().")
+	}
+	params := []pegomock.Param{p, workspace, pullNum}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("Dequeue", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockLocker) VerifyWasCalledOnce() *VerifierLocker {
 	return &VerifierLocker{
 		mock:                   mock,
@@ -158,8 +249,8 @@ type VerifierLocker struct {
 	timeout                time.Duration
 }
 
-func (verifier *VerifierLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) *Locker_TryLock_OngoingVerification {
-	params := []pegomock.Param{p, workspace, pull, user}
+func (verifier *VerifierLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, ttl time.Duration) *Locker_TryLock_OngoingVerification {
+	params := []pegomock.Param{p, workspace, pull, user, ttl}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "TryLock", params, verifier.timeout)
 	return &Locker_TryLock_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -169,12 +260,12 @@ type Locker_TryLock_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *Locker_TryLock_OngoingVerification) GetCapturedArguments() (models.Project, string, models.PullRequest, models.User) {
-	p, workspace, pull, user := c.GetAllCapturedArguments()
-	return p[len(p)-1], workspace[len(workspace)-1], pull[len(pull)-1], user[len(user)-1]
+func (c *Locker_TryLock_OngoingVerification) GetCapturedArguments() (models.Project, string, models.PullRequest, models.User, time.Duration) {
+	p, workspace, pull, user, ttl := c.GetAllCapturedArguments()
+	return p[len(p)-1], workspace[len(workspace)-1], pull[len(pull)-1], user[len(user)-1], ttl[len(ttl)-1]
 }
 
-func (c *Locker_TryLock_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Project, _param1 []string, _param2 []models.PullRequest, _param3 []models.User) {
+func (c *Locker_TryLock_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Project, _param1 []string, _param2 []models.PullRequest, _param3 []models.User, _param4 []time.Duration) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
 		_param0 = make([]models.Project, len(params[0]))
@@ -193,6 +284,10 @@ func (c *Locker_TryLock_OngoingVerification) GetAllCapturedArguments() (_param0
 		for u, param := range params[3] {
 			_param3[u] = param.(models.User)
 		}
+		_param4 = make([]time.Duration, len(params[4]))
+		for u, param := range params[4] {
+			_param4[u] = param.(time.Duration)
+		}
 	}
 	return
 }
@@ -224,6 +319,33 @@ func (c *Locker_Unlock_OngoingVerification) GetAllCapturedArguments() (_param0 [
 	return
 }
 
+func (verifier *VerifierLocker) UnlockIfUnchanged(lock models.ProjectLock) *Locker_UnlockIfUnchanged_OngoingVerification {
+	params := []pegomock.Param{lock}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UnlockIfUnchanged", params, verifier.timeout)
+	return &Locker_UnlockIfUnchanged_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type Locker_UnlockIfUnchanged_OngoingVerification struct {
+	mock              *MockLocker
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *Locker_UnlockIfUnchanged_OngoingVerification) GetCapturedArguments() models.ProjectLock {
+	lock := c.GetAllCapturedArguments()
+	return lock[len(lock)-1]
+}
+
+func (c *Locker_UnlockIfUnchanged_OngoingVerification) GetAllCapturedArguments() (_param0 []models.ProjectLock) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.ProjectLock, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.ProjectLock)
+		}
+	}
+	return
+}
+
 func (verifier *VerifierLocker) List() *Locker_List_OngoingVerification {
 	params := []pegomock.Param{}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "List", params, verifier.timeout)
@@ -298,3 +420,139 @@ func (c *Locker_GetLock_OngoingVerification) GetAllCapturedArguments() (_param0
 	}
 	return
 }
+
+func (verifier *VerifierLocker) Enqueue(p models.Project, workspace string, pull models.PullRequest, user models.User, ttl time.Duration) *Locker_Enqueue_OngoingVerification {
+	params := []pegomock.Param{p, workspace, pull, user, ttl}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "Enqueue", params, verifier.timeout)
+	return &Locker_Enqueue_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type Locker_Enqueue_OngoingVerification struct {
+	mock              *MockLocker
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *Locker_Enqueue_OngoingVerification) GetCapturedArguments() (models.Project, string, models.PullRequest, models.User, time.Duration) {
+	p, workspace, pull, user, ttl := c.GetAllCapturedArguments()
+	return p[len(p)-1], workspace[len(workspace)-1], pull[len(pull)-1], user[len(user)-1], ttl[len(ttl)-1]
+}
+
+func (c *Locker_Enqueue_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Project, _param1 []string, _param2 []models.PullRequest, _param3 []models.User, _param4 []time.Duration) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Project, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Project)
+		}
+		_param1 = make([]string, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(string)
+		}
+		_param2 = make([]models.PullRequest, len(params[2]))
+		for u, param := range params[2] {
+			_param2[u] = param.(models.PullRequest)
+		}
+		_param3 = make([]models.User, len(params[3]))
+		for u, param := range params[3] {
+			_param3[u] = param.(models.User)
+		}
+		_param4 = make([]time.Duration, len(params[4]))
+		for u, param := range params[4] {
+			_param4[u] = param.(time.Duration)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierLocker) GetQueue(key string) *Locker_GetQueue_OngoingVerification {
+	params := []pegomock.Param{key}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetQueue", params, verifier.timeout)
+	return &Locker_GetQueue_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type Locker_GetQueue_OngoingVerification struct {
+	mock              *MockLocker
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *Locker_GetQueue_OngoingVerification) GetCapturedArguments() string {
+	key := c.GetAllCapturedArguments()
+	return key[len(key)-1]
+}
+
+func (c *Locker_GetQueue_OngoingVerification) GetAllCapturedArguments() (_param0 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierLocker) DequeueByPull(repoFullName string, pullNum int) *Locker_DequeueByPull_OngoingVerification {
+	params := []pegomock.Param{repoFullName, pullNum}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "DequeueByPull", params, verifier.timeout)
+	return &Locker_DequeueByPull_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type Locker_DequeueByPull_OngoingVerification struct {
+	mock              *MockLocker
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *Locker_DequeueByPull_OngoingVerification) GetCapturedArguments() (string, int) {
+	repoFullName, pullNum := c.GetAllCapturedArguments()
+	return repoFullName[len(repoFullName)-1], pullNum[len(pullNum)-1]
+}
+
+func (c *Locker_DequeueByPull_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]int, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(int)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierLocker) Dequeue(p models.Project, workspace string, pullNum int) *Locker_Dequeue_OngoingVerification {
+	params := []pegomock.Param{p, workspace, pullNum}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "Dequeue", params, verifier.timeout)
+	return &Locker_Dequeue_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type Locker_Dequeue_OngoingVerification struct {
+	mock              *MockLocker
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *Locker_Dequeue_OngoingVerification) GetCapturedArguments() (models.Project, string, int) {
+	p, workspace, pullNum := c.GetAllCapturedArguments()
+	return p[len(p)-1], workspace[len(workspace)-1], pullNum[len(pullNum)-1]
+}
+
+func (c *Locker_Dequeue_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Project, _param1 []string, _param2 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Project, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Project)
+		}
+		_param1 = make([]string, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(string)
+		}
+		_param2 = make([]int, len(params[2]))
+		for u, param := range params[2] {
+			_param2[u] = param.(int)
+		}
+	}
+	return
+}
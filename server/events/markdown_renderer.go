@@ -16,16 +16,19 @@ package events
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
 
 const (
-	planCommandTitle  = "Plan"
-	applyCommandTitle = "Apply"
+	planCommandTitle            = "Plan"
+	applyCommandTitle           = "Apply"
+	approvePoliciesCommandTitle = "Approve Policies"
 	// maxUnwrappedLines is the maximum number of lines the Terraform output
 	// can be before we wrap it in an expandable template.
 	maxUnwrappedLines = 12
@@ -70,17 +73,39 @@ type planSuccessData struct {
 	PlanWasDeleted bool
 }
 
+// HasPlanSummaries returns true if any of the results have a plan summary.
+func (r resultData) HasPlanSummaries() bool {
+	for _, result := range r.Results {
+		if result.PlanSummary != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionDeniedData is data about a command the user isn't allowed to run.
+type permissionDeniedData struct {
+	Command     string
+	CommandName string
+	Username    string
+	// Projects, Users and Teams are comma-separated lists of code spans.
+	Projects string
+	Users    string
+	Teams    string
+}
+
 type projectResultTmplData struct {
 	Workspace   string
 	RepoRelDir  string
 	ProjectName string
 	Rendered    string
+	PlanSummary *models.PlanSummary
 }
 
 // Render formats the data into a markdown string.
 // nolint: interfacer
 func (m *MarkdownRenderer) Render(res CommandResult, cmdName models.CommandName, log string, verbose bool, vcsHost models.VCSHostType) string {
-	commandStr := strings.Title(cmdName.String())
+	commandStr := strings.Title(strings.Replace(cmdName.String(), "_", " ", -1))
 	common := commonData{
 		Command:      commandStr,
 		Verbose:      verbose,
@@ -132,6 +157,7 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 			} else {
 				resultData.Rendered = m.renderTemplate(planSuccessUnwrappedTmpl, planSuccessData{PlanSuccess: *result.PlanSuccess, PlanWasDeleted: common.PlansDeleted})
 			}
+			resultData.PlanSummary = result.PlanSuccess.PlanSummary
 			numPlanSuccesses++
 		} else if result.ApplySuccess != "" {
 			if m.shouldUseWrappedTmpl(vcsHost, result.ApplySuccess) {
@@ -140,6 +166,8 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 				resultData.Rendered = m.renderTemplate(applyUnwrappedSuccessTmpl, struct{ Output string }{result.ApplySuccess})
 			}
 
+		} else if result.ApprovePoliciesSuccess != "" {
+			resultData.Rendered = result.ApprovePoliciesSuccess
 		} else {
 			resultData.Rendered = "Found no template. This is a bug!"
 		}
@@ -152,11 +180,11 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 		tmpl = singleProjectPlanSuccessTmpl
 	case len(resultsTmplData) == 1 && common.Command == planCommandTitle && numPlanSuccesses == 0:
 		tmpl = singleProjectPlanUnsuccessfulTmpl
-	case len(resultsTmplData) == 1 && common.Command == applyCommandTitle:
+	case len(resultsTmplData) == 1 && (common.Command == applyCommandTitle || common.Command == approvePoliciesCommandTitle):
 		tmpl = singleProjectApplyTmpl
 	case common.Command == planCommandTitle:
 		tmpl = multiProjectPlanTmpl
-	case common.Command == applyCommandTitle:
+	case common.Command == applyCommandTitle || common.Command == approvePoliciesCommandTitle:
 		tmpl = multiProjectApplyTmpl
 	default:
 		return "no template matched–this is a bug"
@@ -164,13 +192,54 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 	return m.renderTemplate(tmpl, resultData{resultsTmplData, common})
 }
 
+// RenderUnlock formats the locks released by an unlock command into a
+// markdown string.
+func (m *MarkdownRenderer) RenderUnlock(locks []models.ProjectLock) string {
+	if len(locks) == 0 {
+		return noLocksUnlockedTmpl
+	}
+	// Sort so the comment is deterministic.
+	sorted := make([]models.ProjectLock, len(locks))
+	copy(sorted, locks)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Project.Path == sorted[j].Project.Path {
+			return sorted[i].Workspace < sorted[j].Workspace
+		}
+		return sorted[i].Project.Path < sorted[j].Project.Path
+	})
+	return m.renderTemplate(unlockSuccessTmpl, sorted)
+}
+
+// RenderPermissionDenied formats the comment explaining that user isn't
+// allowed to run cmdName because they don't have the missing permission.
+func (m *MarkdownRenderer) RenderPermissionDenied(cmdName models.CommandName, user models.User, missing valid.Permission) string {
+	return m.renderTemplate(permissionDeniedTmpl, permissionDeniedData{
+		Command:     strings.Title(strings.Replace(cmdName.String(), "_", " ", -1)),
+		CommandName: cmdName.String(),
+		Username:    user.Username,
+		Projects:    codeList(missing.Projects),
+		Users:       codeList(missing.Users),
+		Teams:       codeList(missing.Teams),
+	})
+}
+
+// codeList formats items as a comma-separated list of code spans.
+func codeList(items []string) string {
+	var quoted []string
+	for _, i := range items {
+		quoted = append(quoted, "`"+i+"`")
+	}
+	return strings.Join(quoted, ", ")
+}
+
 // shouldUseWrappedTmpl returns true if we should use the wrapped markdown
 // templates that collapse the output to make the comment smaller on initial
 // load. Some VCS providers or versions of VCS providers don't support this
 // syntax.
 func (m *MarkdownRenderer) shouldUseWrappedTmpl(vcsHost models.VCSHostType, output string) bool {
-	// Bitbucket Cloud and Server don't support the folding markdown syntax.
-	if vcsHost == models.BitbucketServer || vcsHost == models.BitbucketCloud {
+	// Bitbucket Cloud and Server and Azure DevOps don't support the folding
+	// markdown syntax.
+	if vcsHost == models.BitbucketServer || vcsHost == models.BitbucketCloud || vcsHost == models.AzureDevops {
 		return false
 	}
 
@@ -206,6 +275,7 @@ var multiProjectPlanTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMap
 		"{{ range $result := .Results }}" +
 		"1. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
 		"{{end}}\n" +
+		planSummaryTableTmpl +
 		"{{ range $i, $result := .Results }}" +
 		"### {{add $i 1}}. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
 		"{{$result.Rendered}}\n\n" +
@@ -222,12 +292,39 @@ var multiProjectApplyTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMa
 		"{{$result.Rendered}}\n\n" +
 		"---\n{{end}}" +
 		logTmpl))
+
+// planSummaryTableTmpl is a table of the changes in each project's plan. It's
+// only shown if at least one of the plans could be summarized.
+var planSummaryTableTmpl = "{{ if .HasPlanSummaries }}" +
+	"| Project | To Add | To Change | To Destroy |\n" +
+	"|---------|--------|-----------|------------|\n" +
+	"{{ range $result := .Results }}" +
+	"| {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}` | " +
+	"{{ with $result.PlanSummary }}{{.Add}} | {{.Change}} | {{.Destroy}}{{ else }}- | - | -{{ end }} |\n" +
+	"{{end}}\n{{end}}"
+
+// planSummaryLineTmpl is the summary of the plan shown above its output.
+var planSummaryLineTmpl = "{{ if .PlanSummary }}**{{ .PlanSummary }}**\n\n{{ end }}"
+
+// planDestroysTmpl calls out the resources the plan destroys or replaces so
+// they aren't missed in a long plan output.
+var planDestroysTmpl = "{{ with .PlanSummary }}{{ if or .Deletes .Replaces }}:warning: **This plan will destroy or replace resources:**\n" +
+	"{{ range .Deletes }}* `{{.}}` will be destroyed\n{{ end }}" +
+	"{{ range .Replaces }}* `{{.}}` will be replaced\n{{ end }}" +
+	"\n{{ end }}{{ end }}"
+
+// planPolicyFailuresTmpl lists the policies the plan violates.
+var planPolicyFailuresTmpl = "{{ if .PolicyCheckFailures }}:no_entry: **This plan failed its policy checks:**\n" +
+	"{{ range .PolicyCheckFailures }}* {{.}}\n{{ end }}" +
+	"\n{{ end }}"
 var planSuccessUnwrappedTmpl = template.Must(template.New("").Parse(
-	"```diff\n" +
+	planSummaryLineTmpl + planPolicyFailuresTmpl + planDestroysTmpl +
+		"```diff\n" +
 		"{{.TerraformOutput}}\n" +
 		"```\n\n" + planNextSteps))
 var planSuccessWrappedTmpl = template.Must(template.New("").Parse(
-	"<details><summary>Show Output</summary>\n\n" +
+	planSummaryLineTmpl + planPolicyFailuresTmpl + planDestroysTmpl +
+		"<details><summary>Show Output</summary>\n\n" +
 		"```diff\n" +
 		"{{.TerraformOutput}}\n" +
 		"```\n\n" +
@@ -236,8 +333,13 @@ var planSuccessWrappedTmpl = template.Must(template.New("").Parse(
 
 // planNextSteps are instructions appended after successful plans as to what
 // to do next.
-var planNextSteps = "{{ if .PlanWasDeleted }}This plan was not saved because one or more projects failed and automerge requires all plans pass.{{ else }}* :arrow_forward: To **apply** this plan, comment:\n" +
-	"    * `{{.ApplyCmd}}`\n" +
+var planNextSteps = "{{ if .PlanWasDeleted }}This plan was not saved because one or more projects failed and automerge requires all plans pass.{{ else }}" +
+	"{{ if .PolicyCheckFailures }}* :no_entry: This plan can't be **applied** until a policy approver reviews the failures and comments:\n" +
+	"    * `atlantis approve_policies`\n" +
+	"{{ else if .ProtectedDestroys }}* :no_entry: This plan destroys resources protected by the `no_destroy` apply requirement. To **apply** it anyway, comment:\n" +
+	"    * `{{.ApplyCmd}} --allow-destroy`\n" +
+	"{{ else }}* :arrow_forward: To **apply** this plan, comment:\n" +
+	"    * `{{.ApplyCmd}}`\n{{ end }}" +
 	"* :put_litter_in_its_place: To **delete** this plan click [here]({{.LockURL}})\n" +
 	"* :repeat: To **plan** this project again, comment:\n" +
 	"    * `{{.RePlanCmd}}`{{end}}"
@@ -267,3 +369,13 @@ var failureTmplText = "**{{.Command}} Failed**: {{.Failure}}"
 var failureTmpl = template.Must(template.New("").Parse(failureTmplText))
 var failureWithLogTmpl = template.Must(template.New("").Parse(failureTmplText + logTmpl))
 var logTmpl = "{{if .Verbose}}\n<details><summary>Log</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n"
+var unlockSuccessTmpl = template.Must(template.New("").Parse(
+	"Released the following locks and deleted their plans:\n" +
+		"{{ range . }}\n" +
+		"- dir: `{{ .Project.Path }}` workspace: `{{ .Workspace }}`{{ end }}\n\n" +
+		"To plan again, comment `atlantis plan`."))
+var permissionDeniedTmpl = template.Must(template.New("").Parse(
+	"**{{.Command}} Denied**: `{{.Username}}` is not allowed to run `atlantis {{.CommandName}}`{{ if .Projects }} on {{.Projects}}{{ end }}.\n\n" +
+		"{{ if or .Users .Teams }}It can only be run by{{ if .Users }} the users {{.Users}}{{ end }}{{ if and .Users .Teams }} and{{ end }}{{ if .Teams }} members of the teams {{.Teams}}{{ end }}." +
+		"{{ else }}No one is allowed to run it.{{ end }}"))
+var noLocksUnlockedTmpl = "There were no locks held by this pull request to release."
@@ -21,6 +21,7 @@ import (
 
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -603,6 +604,11 @@ func TestRenderProjectResults_WrappedErr(t *testing.T) {
 			Output:     strings.Repeat("line\n", 13),
 			ShouldWrap: false,
 		},
+		{
+			VCSHost:    models.AzureDevops,
+			Output:     strings.Repeat("line\n", 13),
+			ShouldWrap: false,
+		},
 	}
 
 	for _, c := range cases {
@@ -932,6 +938,193 @@ $$$
 	Equals(t, expWithBackticks, rendered)
 }
 
+// Test that plan summaries are shown above each plan and in a table of all
+// the projects.
+func TestRenderProjectResults_PlanSummaries(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir:  ".",
+				Workspace:   "staging",
+				ProjectName: "app",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "terraform-output",
+					LockURL:         "staging-lock-url",
+					ApplyCmd:        "staging-apply-cmd",
+					RePlanCmd:       "staging-replan-cmd",
+					PlanSummary:     &models.PlanSummary{Add: 1, Destroy: 2},
+				},
+			},
+			{
+				RepoRelDir: ".",
+				Workspace:  "production",
+				Error:      errors.New("error"),
+			},
+		},
+	}, models.PlanCommand, "log", false, models.Github)
+	exp := `Ran Plan for 2 projects:
+1. project: $app$ dir: $.$ workspace: $staging$
+1. dir: $.$ workspace: $production$
+
+| Project | To Add | To Change | To Destroy |
+|---------|--------|-----------|------------|
+| project: $app$ dir: $.$ workspace: $staging$ | 1 | 0 | 2 |
+| dir: $.$ workspace: $production$ | - | - | - |
+
+### 1. project: $app$ dir: $.$ workspace: $staging$
+**Plan: 1 to add, 0 to change, 2 to destroy.**
+
+$$$diff
+terraform-output
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $staging-apply-cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](staging-lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $staging-replan-cmd$
+
+---
+### 2. dir: $.$ workspace: $production$
+**Plan Error**
+$$$
+error
+$$$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`
+	expWithBackticks := strings.Replace(exp, "$", "`", -1)
+	Equals(t, expWithBackticks, rendered)
+}
+
+// Test that destroyed and replaced resources are called out and that plans
+// with protected destroys tell users to apply with --allow-destroy.
+func TestRenderProjectResults_PlanDestroys(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "terraform-output",
+					LockURL:         "lock-url",
+					ApplyCmd:        "atlantis apply -d .",
+					RePlanCmd:       "atlantis plan -d .",
+					PlanSummary: &models.PlanSummary{
+						Add:      1,
+						Destroy:  2,
+						Deletes:  []string{"aws_db_instance.main"},
+						Replaces: []string{"aws_instance.web"},
+					},
+					ProtectedDestroys: []string{"aws_db_instance.main"},
+				},
+			},
+		},
+	}, models.PlanCommand, "log", false, models.Github)
+	exp := `Ran Plan for dir: $.$ workspace: $default$
+
+**Plan: 1 to add, 0 to change, 2 to destroy.**
+
+:warning: **This plan will destroy or replace resources:**
+* $aws_db_instance.main$ will be destroyed
+* $aws_instance.web$ will be replaced
+
+$$$diff
+terraform-output
+$$$
+
+* :no_entry: This plan destroys resources protected by the $no_destroy$ apply requirement. To **apply** it anyway, comment:
+    * $atlantis apply -d . --allow-destroy$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d .$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`
+	expWithBackticks := strings.Replace(exp, "$", "`", -1)
+	Equals(t, expWithBackticks, rendered)
+}
+
+func TestRenderProjectResults_PlanPolicyFailures(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput:     "terraform-output",
+					LockURL:             "lock-url",
+					ApplyCmd:            "atlantis apply -d .",
+					RePlanCmd:           "atlantis plan -d .",
+					PolicyCheckFailures: []string{"security: aws_s3_bucket.logs must not be public"},
+				},
+			},
+		},
+	}, models.PlanCommand, "log", false, models.Github)
+	exp := `Ran Plan for dir: $.$ workspace: $default$
+
+:no_entry: **This plan failed its policy checks:**
+* security: aws_s3_bucket.logs must not be public
+
+$$$diff
+terraform-output
+$$$
+
+* :no_entry: This plan can't be **applied** until a policy approver reviews the failures and comments:
+    * $atlantis approve_policies$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d .$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`
+	expWithBackticks := strings.Replace(exp, "$", "`", -1)
+	Equals(t, expWithBackticks, rendered)
+}
+
+func TestRenderProjectResults_ApprovePolicies(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir:             ".",
+				Workspace:              "default",
+				ApprovePoliciesSuccess: "Policy check failures approved by @alice. The plan can now be applied.",
+			},
+			{
+				RepoRelDir: "dir",
+				Workspace:  "default",
+				Failure:    "failure",
+			},
+		},
+	}, models.ApprovePoliciesCommand, "log", false, models.Github)
+	exp := `Ran Approve Policies for 2 projects:
+1. dir: $.$ workspace: $default$
+1. dir: $dir$ workspace: $default$
+
+### 1. dir: $.$ workspace: $default$
+Policy check failures approved by @alice. The plan can now be applied.
+
+---
+### 2. dir: $dir$ workspace: $default$
+**Approve Policies Failed**: failure
+
+---
+
+`
+	expWithBackticks := strings.Replace(exp, "$", "`", -1)
+	Equals(t, expWithBackticks, rendered)
+}
+
 // Test rendering when there was an error in one of the plans and we deleted
 // all the plans as a result.
 func TestRenderProjectResults_PlansDeleted(t *testing.T) {
@@ -1038,3 +1231,33 @@ This plan was not saved because one or more projects failed and automerge requir
 		})
 	}
 }
+
+func TestRenderPermissionDenied(t *testing.T) {
+	cases := map[string]struct {
+		missing valid.Permission
+		exp     string
+	}{
+		"users": {
+			missing: valid.Permission{Command: "apply", Users: []string{"alice", "bob"}},
+			exp:     "**Apply Denied**: `lkysow` is not allowed to run `atlantis apply`.\n\nIt can only be run by the users `alice`, `bob`.",
+		},
+		"teams for a project": {
+			missing: valid.Permission{Command: "apply", Projects: []string{"prod", "envs/prod"}, Teams: []string{"runatlantis/admins"}},
+			exp:     "**Apply Denied**: `lkysow` is not allowed to run `atlantis apply` on `prod`, `envs/prod`.\n\nIt can only be run by members of the teams `runatlantis/admins`.",
+		},
+		"no one": {
+			missing: valid.Permission{Command: "approve_policies"},
+			exp:     "**Approve Policies Denied**: `lkysow` is not allowed to run `atlantis approve_policies`.\n\nNo one is allowed to run it.",
+		},
+	}
+	r := events.MarkdownRenderer{}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cmdName := models.ApplyCommand
+			if c.missing.Command == "approve_policies" {
+				cmdName = models.ApprovePoliciesCommand
+			}
+			Equals(t, c.exp, r.RenderPermissionDenied(cmdName, models.User{Username: "lkysow"}, c.missing))
+		})
+	}
+}
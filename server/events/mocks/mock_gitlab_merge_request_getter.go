@@ -44,6 +44,25 @@ func (mock *MockGitlabMergeRequestGetter) GetMergeRequest(repoFullName string, p
 	return ret0, ret1
 }
 
+func (mock *MockGitlabMergeRequestGetter) GetProject(projectID int) (*go_gitlab.Project, error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockGitlabMergeRequestGetter().")
+	}
+	params := []pegomock.Param{projectID}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("GetProject", params, []reflect.Type{reflect.TypeOf((**go_gitlab.Project)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 *go_gitlab.Project
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(*go_gitlab.Project)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
 func (mock *MockGitlabMergeRequestGetter) VerifyWasCalledOnce() *VerifierGitlabMergeRequestGetter {
 	return &VerifierGitlabMergeRequestGetter{
 		mock:                   mock,
@@ -111,3 +130,30 @@ func (c *GitlabMergeRequestGetter_GetMergeRequest_OngoingVerification) GetAllCap
 	}
 	return
 }
+
+func (verifier *VerifierGitlabMergeRequestGetter) GetProject(projectID int) *GitlabMergeRequestGetter_GetProject_OngoingVerification {
+	params := []pegomock.Param{projectID}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetProject", params, verifier.timeout)
+	return &GitlabMergeRequestGetter_GetProject_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type GitlabMergeRequestGetter_GetProject_OngoingVerification struct {
+	mock              *MockGitlabMergeRequestGetter
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *GitlabMergeRequestGetter_GetProject_OngoingVerification) GetCapturedArguments() int {
+	projectID := c.GetAllCapturedArguments()
+	return projectID[len(projectID)-1]
+}
+
+func (c *GitlabMergeRequestGetter_GetProject_OngoingVerification) GetAllCapturedArguments() (_param0 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]int, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(int)
+		}
+	}
+	return
+}
@@ -113,6 +113,25 @@ func (mock *MockWorkingDir) DeleteForWorkspace(r models.Repo, p models.PullReque
 	return ret0
 }
 
+func (mock *MockWorkingDir) CloneDefaultBranch(log *logging.SimpleLogger, repo models.Repo, workspace string) (string, error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockWorkingDir().")
+	}
+	params := []pegomock.Param{log, repo, workspace}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("CloneDefaultBranch", params, []reflect.Type{reflect.TypeOf((*string)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 string
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(string)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
 func (mock *MockWorkingDir) VerifyWasCalledOnce() *VerifierWorkingDir {
 	return &VerifierWorkingDir{
 		mock:                   mock,
@@ -324,3 +343,38 @@ func (c *WorkingDir_DeleteForWorkspace_OngoingVerification) GetAllCapturedArgume
 	}
 	return
 }
+
+func (verifier *VerifierWorkingDir) CloneDefaultBranch(log *logging.SimpleLogger, repo models.Repo, workspace string) *WorkingDir_CloneDefaultBranch_OngoingVerification {
+	params := []pegomock.Param{log, repo, workspace}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "CloneDefaultBranch", params, verifier.timeout)
+	return &WorkingDir_CloneDefaultBranch_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type WorkingDir_CloneDefaultBranch_OngoingVerification struct {
+	mock              *MockWorkingDir
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *WorkingDir_CloneDefaultBranch_OngoingVerification) GetCapturedArguments() (*logging.SimpleLogger, models.Repo, string) {
+	log, repo, workspace := c.GetAllCapturedArguments()
+	return log[len(log)-1], repo[len(repo)-1], workspace[len(workspace)-1]
+}
+
+func (c *WorkingDir_CloneDefaultBranch_OngoingVerification) GetAllCapturedArguments() (_param0 []*logging.SimpleLogger, _param1 []models.Repo, _param2 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]*logging.SimpleLogger, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(*logging.SimpleLogger)
+		}
+		_param1 = make([]models.Repo, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(models.Repo)
+		}
+		_param2 = make([]string, len(params[2]))
+		for u, param := range params[2] {
+			_param2[u] = param.(string)
+		}
+	}
+	return
+}
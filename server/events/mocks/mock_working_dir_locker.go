@@ -43,6 +43,48 @@ func (mock *MockWorkingDirLocker) TryLock(repoFullName string, pullNum int, work
 	return ret0, ret1
 }
 
+func (mock *MockWorkingDirLocker) TryLockPath(repoFullName string, pullNum int, workspace string, path string) (func(), error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockWorkingDirLocker().")
+	}
+	params := []pegomock.Param{repoFullName, pullNum, workspace, path}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("TryLockPath", params, []reflect.Type{reflect.TypeOf((*func())(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 func()
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(func())
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
+func (mock *MockWorkingDirLocker) TryLockWorkspacePaths(repoFullName string, pullNum int, workspace string, paths []string) (func(), []func(), error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockWorkingDirLocker().")
+	}
+	params := []pegomock.Param{repoFullName, pullNum, workspace, paths}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("TryLockWorkspacePaths", params, []reflect.Type{reflect.TypeOf((*func())(nil)).Elem(), reflect.TypeOf((*[]func())(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 func()
+	var ret1 []func()
+	var ret2 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(func())
+		}
+		if result[1] != nil {
+			ret1 = result[1].([]func())
+		}
+		if result[2] != nil {
+			ret2 = result[2].(error)
+		}
+	}
+	return ret0, ret1, ret2
+}
+
 func (mock *MockWorkingDirLocker) TryLockPull(repoFullName string, pullNum int) (func(), error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockWorkingDirLocker().")
@@ -134,6 +176,84 @@ func (c *WorkingDirLocker_TryLock_OngoingVerification) GetAllCapturedArguments()
 	return
 }
 
+func (verifier *VerifierWorkingDirLocker) TryLockPath(repoFullName string, pullNum int, workspace string, path string) *WorkingDirLocker_TryLockPath_OngoingVerification {
+	params := []pegomock.Param{repoFullName, pullNum, workspace, path}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "TryLockPath", params, verifier.timeout)
+	return &WorkingDirLocker_TryLockPath_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type WorkingDirLocker_TryLockPath_OngoingVerification struct {
+	mock              *MockWorkingDirLocker
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *WorkingDirLocker_TryLockPath_OngoingVerification) GetCapturedArguments() (string, int, string, string) {
+	repoFullName, pullNum, workspace, path := c.GetAllCapturedArguments()
+	return repoFullName[len(repoFullName)-1], pullNum[len(pullNum)-1], workspace[len(workspace)-1], path[len(path)-1]
+}
+
+func (c *WorkingDirLocker_TryLockPath_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []int, _param2 []string, _param3 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]int, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(int)
+		}
+		_param2 = make([]string, len(params[2]))
+		for u, param := range params[2] {
+			_param2[u] = param.(string)
+		}
+		_param3 = make([]string, len(params[3]))
+		for u, param := range params[3] {
+			_param3[u] = param.(string)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierWorkingDirLocker) TryLockWorkspacePaths(repoFullName string, pullNum int, workspace string, paths []string) *WorkingDirLocker_TryLockWorkspacePaths_OngoingVerification {
+	params := []pegomock.Param{repoFullName, pullNum, workspace, paths}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "TryLockWorkspacePaths", params, verifier.timeout)
+	return &WorkingDirLocker_TryLockWorkspacePaths_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type WorkingDirLocker_TryLockWorkspacePaths_OngoingVerification struct {
+	mock              *MockWorkingDirLocker
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *WorkingDirLocker_TryLockWorkspacePaths_OngoingVerification) GetCapturedArguments() (string, int, string, []string) {
+	repoFullName, pullNum, workspace, paths := c.GetAllCapturedArguments()
+	return repoFullName[len(repoFullName)-1], pullNum[len(pullNum)-1], workspace[len(workspace)-1], paths[len(paths)-1]
+}
+
+func (c *WorkingDirLocker_TryLockWorkspacePaths_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []int, _param2 []string, _param3 [][]string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]int, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(int)
+		}
+		_param2 = make([]string, len(params[2]))
+		for u, param := range params[2] {
+			_param2[u] = param.(string)
+		}
+		_param3 = make([][]string, len(params[3]))
+		for u, param := range params[3] {
+			_param3[u] = param.([]string)
+		}
+	}
+	return
+}
+
 func (verifier *VerifierWorkingDirLocker) TryLockPull(repoFullName string, pullNum int) *WorkingDirLocker_TryLockPull_OngoingVerification {
 	params := []pegomock.Param{repoFullName, pullNum}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "TryLockPull", params, verifier.timeout)
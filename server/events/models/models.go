@@ -33,11 +33,13 @@ import (
 // Repo is a VCS repository.
 type Repo struct {
 	// FullName is the owner and repo name separated
-	// by a "/", ex. "runatlantis/atlantis", "gitlab/subgroup/atlantis", "Bitbucket Server/atlantis".
+	// by a "/", ex. "runatlantis/atlantis", "gitlab/subgroup/atlantis",
+	// "Bitbucket Server/atlantis", "azuredevops-org/project/atlantis".
 	FullName string
 	// Owner is just the repo owner, ex. "runatlantis" or "gitlab/subgroup".
-	// This may contain /'s in the case of GitLab subgroups.
-	// This may contain spaces in the case of Bitbucket Server.
+	// This may contain /'s in the case of GitLab subgroups and Azure DevOps
+	// where it's the organization and project, ex. "org/project".
+	// This may contain spaces in the case of Bitbucket Server and Azure DevOps.
 	Owner string
 	// Name is just the repo name, ex. "atlantis". This will never have
 	// /'s in it.
@@ -56,6 +58,11 @@ type Repo struct {
 // cloneURL can be with or without .git at the end
 // ex. https://github.com/runatlantis/atlantis.git OR
 //     https://github.com/runatlantis/atlantis
+// For Azure DevOps, repoFullName is the org/project/repo form and cloneURL
+// is the repo's remote URL which doesn't end in .git
+// ex. https://dev.azure.com/org/project/_git/repo
+// For Gitea, cloneURL can include the sub path Gitea is installed under
+// ex. https://corp.com/gitea/owner/repo.git
 func NewRepo(vcsHostType VCSHostType, repoFullName string, cloneURL string, vcsUser string, vcsToken string) (Repo, error) {
 	if repoFullName == "" {
 		return Repo{}, errors.New("repoFullName can't be empty")
@@ -64,7 +71,8 @@ func NewRepo(vcsHostType VCSHostType, repoFullName string, cloneURL string, vcsU
 		return Repo{}, errors.New("cloneURL can't be empty")
 	}
 
-	if !strings.HasSuffix(cloneURL, ".git") {
+	// Azure DevOps clone URLs don't end in .git.
+	if !strings.HasSuffix(cloneURL, ".git") && vcsHostType != AzureDevops {
 		cloneURL += ".git"
 	}
 
@@ -79,6 +87,14 @@ func NewRepo(vcsHostType VCSHostType, repoFullName string, cloneURL string, vcsU
 	// from the repo name and so there's no point checking if they match.
 	if vcsHostType != BitbucketServer {
 		expClonePath := fmt.Sprintf("/%s.git", repoFullName)
+		if vcsHostType == AzureDevops {
+			expClonePath = azureDevopsClonePath(repoFullName)
+		}
+		// Gitea can be installed under a sub path, ex.
+		// https://corp.com/gitea/owner/repo.git.
+		if vcsHostType == Gitea && strings.HasSuffix(cloneURLParsed.Path, expClonePath) {
+			expClonePath = cloneURLParsed.Path
+		}
 		if expClonePath != cloneURLParsed.Path {
 			return Repo{}, fmt.Errorf("expected clone url to have path %q but had %q", expClonePath, cloneURLParsed.Path)
 		}
@@ -101,8 +117,13 @@ func NewRepo(vcsHostType VCSHostType, repoFullName string, cloneURL string, vcsU
 		return Repo{}, fmt.Errorf("invalid repo format %q, owner %q or repo %q was empty", repoFullName, owner, repo)
 	}
 	// Only GitLab repos can have /'s in their owners. This is for GitLab
-	// subgroups.
-	if strings.Contains(owner, "/") && vcsHostType != Gitlab {
+	// subgroups. Azure DevOps owners are always the org and project.
+	if vcsHostType == AzureDevops {
+		orgProject := strings.Split(owner, "/")
+		if len(orgProject) != 2 || orgProject[0] == "" || orgProject[1] == "" {
+			return Repo{}, fmt.Errorf("invalid repo format %q, owner %q should be the organization and project separated by a /", repoFullName, owner)
+		}
+	} else if strings.Contains(owner, "/") && vcsHostType != Gitlab {
 		return Repo{}, fmt.Errorf("invalid repo format %q, owner %q should not contain any /'s", repoFullName, owner)
 	}
 	if strings.Contains(repo, "/") {
@@ -122,6 +143,13 @@ func NewRepo(vcsHostType VCSHostType, repoFullName string, cloneURL string, vcsU
 	}, nil
 }
 
+// azureDevopsClonePath returns the path of the clone URL for the Azure DevOps
+// repo with repoFullName, ex. org/project/repo => /org/project/_git/repo.
+func azureDevopsClonePath(repoFullName string) string {
+	owner, repo := SplitRepoFullName(repoFullName)
+	return fmt.Sprintf("/%s/_git/%s", owner, repo)
+}
+
 // PullRequest is a VCS pull request.
 // GitLab calls these Merge Requests.
 type PullRequest struct {
@@ -202,6 +230,9 @@ type ProjectLock struct {
 	Workspace string
 	// Time is the time at which the lock was first created.
 	Time time.Time
+	// TTL is how long the lock can be held for before it's released
+	// automatically. If 0, the server's default lock TTL is used.
+	TTL time.Duration
 }
 
 // Project represents a Terraform project. Since there may be multiple
@@ -263,6 +294,8 @@ const (
 	Gitlab
 	BitbucketCloud
 	BitbucketServer
+	AzureDevops
+	Gitea
 )
 
 func (h VCSHostType) String() string {
@@ -275,16 +308,28 @@ func (h VCSHostType) String() string {
 		return "BitbucketCloud"
 	case BitbucketServer:
 		return "BitbucketServer"
+	case AzureDevops:
+		return "AzureDevops"
+	case Gitea:
+		return "Gitea"
 	}
 	return "<missing String() implementation>"
 }
 
 type ProjectCommandContext struct {
+	// AllowDestroy is true if the user acknowledged that applying will
+	// destroy resources protected by the no_destroy apply requirement.
+	AllowDestroy bool
 	// ApplyCmd is the command that users should run to apply this plan. If
 	// this is an apply then this will be empty.
 	ApplyCmd string
 	// BaseRepo is the repository that the pull request will be merged into.
 	BaseRepo Repo
+	// Cloned is true if the repo has already been cloned at the pull
+	// request's commit for this project's workspace and its directory has
+	// been locked for it. Projects that plan in parallel share the clone so
+	// they mustn't clone it again.
+	Cloned bool
 	// CommentArgs are the extra arguments appended to comment,
 	// ex. atlantis plan -- -target=resource
 	CommentArgs  []string
@@ -294,7 +339,10 @@ type ProjectCommandContext struct {
 	// be the same as BaseRepo.
 	// See https://help.github.com/articles/about-pull-request-merges/.
 	HeadRepo Repo
-	Log      *logging.SimpleLogger
+	// JobID is the ID of the job that this command's output is streamed to.
+	// It's empty if the output isn't being streamed.
+	JobID string
+	Log   *logging.SimpleLogger
 	// PullMergeable is true if the pull request for this project is able to be merged.
 	PullMergeable bool
 	Pull          PullRequest
@@ -342,7 +390,10 @@ type ProjectResult struct {
 	Failure      string
 	PlanSuccess  *PlanSuccess
 	ApplySuccess string
-	ProjectName  string
+	// ApprovePoliciesSuccess is the output of approving the project's policy
+	// check failures.
+	ApprovePoliciesSuccess string
+	ProjectName            string
 }
 
 // CommitStatus returns the vcs commit status of this project result.
@@ -353,6 +404,9 @@ func (p ProjectResult) CommitStatus() CommitStatus {
 	if p.Failure != "" {
 		return FailedCommitStatus
 	}
+	if p.PlanSuccess != nil && len(p.PlanSuccess.PolicyCheckFailures) > 0 {
+		return FailedCommitStatus
+	}
 	return SuccessCommitStatus
 }
 
@@ -365,6 +419,8 @@ func (p ProjectResult) PlanStatus() ProjectPlanStatus {
 			return ErroredPlanStatus
 		} else if p.Failure != "" {
 			return ErroredPlanStatus
+		} else if p.PlanSuccess != nil && len(p.PlanSuccess.PolicyCheckFailures) > 0 {
+			return PolicyCheckFailedPlanStatus
 		}
 		return PlannedPlanStatus
 
@@ -375,14 +431,24 @@ func (p ProjectResult) PlanStatus() ProjectPlanStatus {
 			return ErroredApplyStatus
 		}
 		return AppliedPlanStatus
+
+	case ApprovePoliciesCommand:
+		if p.Error != nil || p.Failure != "" {
+			return PolicyCheckFailedPlanStatus
+		}
+		return PlannedPlanStatus
 	}
 
 	panic("PlanStatus() missing a combination")
 }
 
-// IsSuccessful returns true if this project result had no errors.
+// IsSuccessful returns true if this project result had no errors and its
+// plan, if any, passed its policy checks.
 func (p ProjectResult) IsSuccessful() bool {
-	return p.PlanSuccess != nil || p.ApplySuccess != ""
+	if p.PlanSuccess != nil {
+		return len(p.PlanSuccess.PolicyCheckFailures) == 0
+	}
+	return p.ApplySuccess != "" || p.ApprovePoliciesSuccess != ""
 }
 
 // PlanSuccess is the result of a successful plan.
@@ -395,6 +461,76 @@ type PlanSuccess struct {
 	RePlanCmd string
 	// ApplyCmd is the command that users should run to apply this plan.
 	ApplyCmd string
+	// PlanSummary is the summary of the changes in the plan. It's nil if the
+	// plan couldn't be summarized, ex. because the Terraform version doesn't
+	// support showing plans as JSON.
+	PlanSummary *PlanSummary
+	// ProtectedDestroys are the addresses of the resources the plan destroys
+	// or replaces that are protected by the no_destroy apply requirement. If
+	// there are any, the plan can only be applied with --allow-destroy.
+	ProtectedDestroys []string
+	// PolicyCheckFailures are the policy violations found by the plan's
+	// policy_check steps. If there are any, the plan can't be applied until
+	// a policy approver runs approve_policies.
+	PolicyCheckFailures []string
+}
+
+// Summary returns the line of the plan output that summarizes the changes,
+// ex. "Plan: 1 to add, 0 to change, 0 to destroy.". If there are no changes
+// it returns "No changes." and if neither is found it returns "".
+func (p PlanSuccess) Summary() string {
+	if p.PlanSummary != nil {
+		return p.PlanSummary.String()
+	}
+	for _, line := range strings.Split(p.TerraformOutput, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Plan: ") {
+			return line
+		}
+		if strings.HasPrefix(line, "No changes.") {
+			return "No changes."
+		}
+	}
+	return ""
+}
+
+// PlanSummary summarizes the changes a plan will make. It's parsed from the
+// output of terraform show -json.
+type PlanSummary struct {
+	// Add, Change and Destroy are the number of resources that will be
+	// created, updated and destroyed. Resources that are replaced count as
+	// both an add and a destroy, the same as in Terraform's output.
+	Add     int
+	Change  int
+	Destroy int
+	// Creates, Updates, Deletes and Replaces are the addresses of the
+	// resources for each action, ex. "aws_instance.web".
+	Creates  []string `json:",omitempty"`
+	Updates  []string `json:",omitempty"`
+	Deletes  []string `json:",omitempty"`
+	Replaces []string `json:",omitempty"`
+}
+
+// HasChanges returns true if the plan will change any resources.
+func (p PlanSummary) HasChanges() bool {
+	return p.Add+p.Change+p.Destroy > 0
+}
+
+// String returns the summary in the same format as Terraform's output, ex.
+// "Plan: 1 to add, 0 to change, 0 to destroy." or "No changes.".
+func (p PlanSummary) String() string {
+	if !p.HasChanges() {
+		return "No changes."
+	}
+	return fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy.", p.Add, p.Change, p.Destroy)
+}
+
+// Destroys returns the addresses of the resources that will be destroyed,
+// including the ones that will be replaced.
+func (p PlanSummary) Destroys() []string {
+	var destroys []string
+	destroys = append(destroys, p.Deletes...)
+	return append(destroys, p.Replaces...)
 }
 
 // PullStatus is the current status of a pull request that is in progress.
@@ -403,6 +539,11 @@ type PullStatus struct {
 	Projects []ProjectStatus
 	// Pull is the original pull request model.
 	Pull PullRequest
+	// Comments are the IDs of the comments Atlantis last posted with the
+	// output of each command, keyed by the command's name, ex. "plan". They're
+	// kept when the pull request gets new commits so the comments can be
+	// updated.
+	Comments map[string][]string `json:",omitempty"`
 }
 
 // StatusCount returns the number of projects that have status.
@@ -423,6 +564,9 @@ type ProjectStatus struct {
 	ProjectName string
 	// Status is the status of where this project is at in the planning cycle.
 	Status ProjectPlanStatus
+	// PlanSummary is the summary of the project's last plan. It's nil if the
+	// plan failed or couldn't be summarized.
+	PlanSummary *PlanSummary `json:",omitempty"`
 }
 
 // ProjectPlanStatus is the status of where this project is at in the planning
@@ -442,6 +586,9 @@ const (
 	// AppliedPlanStatus means that a plan has been generated and applied
 	// successfully.
 	AppliedPlanStatus
+	// PolicyCheckFailedPlanStatus means that a plan has been generated but it
+	// failed its policy checks and they haven't been approved yet.
+	PolicyCheckFailedPlanStatus
 )
 
 // String returns a string representation of the status.
@@ -455,6 +602,8 @@ func (p ProjectPlanStatus) String() string {
 		return "apply_errored"
 	case AppliedPlanStatus:
 		return "applied"
+	case PolicyCheckFailedPlanStatus:
+		return "policy_check_failed"
 	default:
 		panic("missing String() impl for ProjectPlanStatus")
 	}
@@ -468,6 +617,11 @@ const (
 	ApplyCommand CommandName = iota
 	// PlanCommand is a command to run terraform plan.
 	PlanCommand
+	// UnlockCommand is a command to release the locks held by a pull request.
+	UnlockCommand
+	// ApprovePoliciesCommand is a command to approve the policy check
+	// failures of a pull request's plans so they can be applied.
+	ApprovePoliciesCommand
 	// Adding more? Don't forget to update String() below
 )
 
@@ -478,6 +632,10 @@ func (c CommandName) String() string {
 		return "apply"
 	case PlanCommand:
 		return "plan"
+	case UnlockCommand:
+		return "unlock"
+	case ApprovePoliciesCommand:
+		return "approve_policies"
 	}
 	return ""
 }
@@ -60,6 +60,79 @@ func TestNewRepo_CloneURLBitbucketServer(t *testing.T) {
 	}, repo)
 }
 
+func TestNewRepo_AzureDevops(t *testing.T) {
+	repo, err := models.NewRepo(models.AzureDevops, "org/my project/repo", "https://dev.azure.com/org/my%20project/_git/repo", "u", "p")
+	Ok(t, err)
+	Equals(t, models.Repo{
+		FullName:          "org/my project/repo",
+		Owner:             "org/my project",
+		Name:              "repo",
+		CloneURL:          "https://u:p@dev.azure.com/org/my%20project/_git/repo",
+		SanitizedCloneURL: "https://dev.azure.com/org/my%20project/_git/repo",
+		VCSHost: models.VCSHost{
+			Hostname: "dev.azure.com",
+			Type:     models.AzureDevops,
+		},
+	}, repo)
+}
+
+func TestNewRepo_AzureDevopsWrongFormat(t *testing.T) {
+	cases := []struct {
+		repoFullName string
+		cloneURL     string
+		expErr       string
+	}{
+		{
+			"org/project/repo",
+			"https://dev.azure.com/org/project/_git/other",
+			`expected clone url to have path "/org/project/_git/repo" but had "/org/project/_git/other"`,
+		},
+		{
+			"project/repo",
+			"https://dev.azure.com/project/_git/repo",
+			`invalid repo format "project/repo", owner "project" should be the organization and project separated by a /`,
+		},
+		{
+			"org/project/extra/repo",
+			"https://dev.azure.com/org/project/extra/_git/repo",
+			`invalid repo format "org/project/extra/repo", owner "org/project/extra" should be the organization and project separated by a /`,
+		},
+		{
+			"org//repo",
+			"https://dev.azure.com/org//_git/repo",
+			`invalid repo format "org//repo", owner "org/" should be the organization and project separated by a /`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.repoFullName, func(t *testing.T) {
+			_, err := models.NewRepo(models.AzureDevops, c.repoFullName, c.cloneURL, "u", "p")
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+// Gitea can be installed under a sub path.
+func TestNewRepo_GiteaSubPath(t *testing.T) {
+	repo, err := models.NewRepo(models.Gitea, "owner/repo", "https://corp.com/gitea/owner/repo.git", "u", "p")
+	Ok(t, err)
+	Equals(t, models.Repo{
+		FullName:          "owner/repo",
+		Owner:             "owner",
+		Name:              "repo",
+		CloneURL:          "https://u:p@corp.com/gitea/owner/repo.git",
+		SanitizedCloneURL: "https://corp.com/gitea/owner/repo.git",
+		VCSHost: models.VCSHost{
+			Hostname: "corp.com",
+			Type:     models.Gitea,
+		},
+	}, repo)
+}
+
+func TestNewRepo_GiteaWrongClonePath(t *testing.T) {
+	_, err := models.NewRepo(models.Gitea, "owner/repo", "https://corp.com/gitea/owner/other.git", "u", "p")
+	ErrEquals(t, `expected clone url to have path "/owner/repo.git" but had "/gitea/owner/other.git"`, err)
+}
+
 func TestNewRepo_FullNameWrongFormat(t *testing.T) {
 	cases := []struct {
 		repoFullName string
@@ -351,6 +424,27 @@ func TestProjectResult_PlanStatus(t *testing.T) {
 	}
 }
 
+func TestPlanSuccess_Summary(t *testing.T) {
+	cases := map[string]string{
+		"":                    "",
+		"Refreshing state...": "",
+		"  + null_resource.test\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n": "Plan: 1 to add, 0 to change, 0 to destroy.",
+		"No changes. Infrastructure is up-to-date.\n\nThis means that...":        "No changes.",
+	}
+	for output, exp := range cases {
+		t.Run(output, func(t *testing.T) {
+			Equals(t, exp, models.PlanSuccess{TerraformOutput: output}.Summary())
+		})
+	}
+
+	t.Log("the plan summary should be used if it's set")
+	Equals(t, "Plan: 1 to add, 2 to change, 3 to destroy.", models.PlanSuccess{
+		TerraformOutput: "No changes.",
+		PlanSummary:     &models.PlanSummary{Add: 1, Change: 2, Destroy: 3},
+	}.Summary())
+	Equals(t, "No changes.", models.PlanSuccess{PlanSummary: &models.PlanSummary{}}.Summary())
+}
+
 func TestPullStatus_StatusCount(t *testing.T) {
 	ps := models.PullStatus{
 		Projects: []models.ProjectStatus{
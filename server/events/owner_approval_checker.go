@@ -0,0 +1,163 @@
+package events
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/pkg/fileutils"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/codeowners"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_owner_approval_checker.go OwnerApprovalChecker
+
+// OwnerApprovalChecker checks the approved_by_owners apply requirement.
+type OwnerApprovalChecker interface {
+	// UnapprovedFiles returns the modified files of the project described by
+	// ctx that haven't been approved by one of their owners.
+	UnapprovedFiles(ctx models.ProjectCommandContext) ([]string, error)
+}
+
+// DefaultOwnerApprovalChecker finds the owners of files in the CODEOWNERS
+// file on the pull request's base branch. The pull request's own CODEOWNERS
+// file isn't used since its author could change who owns their files.
+type DefaultOwnerApprovalChecker struct {
+	VCSClient vcs.Client
+}
+
+// UnapprovedFiles implements OwnerApprovalChecker.
+// A project's modified files are the files modified by the pull request that
+// match its autoplan when_modified patterns, the same files that decide if
+// it's planned automatically. Files without owners don't need approval.
+// Modified CODEOWNERS files always need approval, from their owners or if
+// they don't have any, from any owner.
+// Owners that contain a '/' are GitHub teams or GitLab groups, email
+// addresses are skipped since they can't be matched to approvers and other
+// owners are usernames.
+func (c *DefaultOwnerApprovalChecker) UnapprovedFiles(ctx models.ProjectCommandContext) ([]string, error) {
+	owners, err := codeowners.Find(func(path string) (bool, []byte, error) {
+		return c.VCSClient.GetFileContent(ctx.BaseRepo, ctx.Pull.BaseBranch, path)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading CODEOWNERS file from %s", ctx.Pull.BaseBranch)
+	}
+	if owners == nil {
+		return nil, fmt.Errorf("the %s apply requirement needs a CODEOWNERS file on the %s branch at one of %s", raw.ApprovedByOwnersApplyRequirement, ctx.Pull.BaseBranch, strings.Join(codeowners.Locations, ", "))
+	}
+
+	modifiedFiles, err := c.VCSClient.GetModifiedFiles(ctx.BaseRepo, ctx.Pull)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting modified files")
+	}
+	files, err := c.projectFiles(ctx, modifiedFiles)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	approvers, err := c.VCSClient.GetApprovers(ctx.BaseRepo, ctx.Pull)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting approvers")
+	}
+
+	// Files usually share owners so we only check each set of owners once.
+	approvedOwners := make(map[string]bool)
+	var unapproved []string
+	for _, file := range files {
+		fileOwners := owners.Owners(file)
+		if len(fileOwners) == 0 && codeowners.IsLocation(file) {
+			fileOwners = owners.AllOwners()
+		}
+		if len(fileOwners) == 0 {
+			continue
+		}
+		key := strings.Join(fileOwners, " ")
+		approved, ok := approvedOwners[key]
+		if !ok {
+			approved, err = c.approvedByAnOwner(ctx.BaseRepo, fileOwners, approvers)
+			if err != nil {
+				return nil, err
+			}
+			approvedOwners[key] = approved
+		}
+		if !approved {
+			unapproved = append(unapproved, file)
+		}
+	}
+	return unapproved, nil
+}
+
+// projectFiles returns the files in modifiedFiles that belong to the project
+// in ctx.
+func (c *DefaultOwnerApprovalChecker) projectFiles(ctx models.ProjectCommandContext, modifiedFiles []string) ([]string, error) {
+	whenModified := raw.DefaultAutoPlan().WhenModified
+	if ctx.ProjectConfig != nil {
+		whenModified = ctx.ProjectConfig.Autoplan.WhenModified
+	}
+	// The patterns are relative to the project dir but the modified files are
+	// relative to the repo root.
+	var patterns []string
+	for _, wm := range whenModified {
+		patterns = append(patterns, filepath.Join(ctx.RepoRelDir, wm))
+	}
+	pm, err := fileutils.NewPatternMatcher(patterns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "matching modified files with patterns: %v", whenModified)
+	}
+	var files []string
+	for _, file := range modifiedFiles {
+		// Changing a CODEOWNERS file changes who owns the project's files so
+		// it's part of every project.
+		if codeowners.IsLocation(file) {
+			files = append(files, file)
+			continue
+		}
+		match, err := pm.Matches(file)
+		if err != nil {
+			ctx.Log.Debug("match err for file %q: %s", file, err)
+			continue
+		}
+		if match {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// approvedByAnOwner returns true if one of approvers is one of owners or a
+// member of one of them.
+func (c *DefaultOwnerApprovalChecker) approvedByAnOwner(repo models.Repo, owners []string, approvers []string) (bool, error) {
+	// Check the usernames first since they don't need any API calls.
+	var teams []string
+	for _, owner := range owners {
+		switch {
+		case strings.Contains(owner, "@"):
+			continue
+		case strings.Contains(owner, "/"):
+			teams = append(teams, owner)
+		default:
+			for _, approver := range approvers {
+				if strings.EqualFold(owner, approver) {
+					return true, nil
+				}
+			}
+		}
+	}
+	for _, team := range teams {
+		for _, approver := range approvers {
+			member, err := c.VCSClient.IsTeamMember(repo, team, models.User{Username: approver})
+			if err != nil {
+				return false, errors.Wrapf(err, "checking if %s is a member of %s", approver, team)
+			}
+			if member {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
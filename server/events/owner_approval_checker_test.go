@@ -0,0 +1,189 @@
+package events_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// ownersPull is fixtures.Pull with a base branch so we can tell which branch
+// CODEOWNERS is read from.
+var ownersPull = models.PullRequest{
+	Num:        fixtures.Pull.Num,
+	HeadBranch: "branch",
+	BaseBranch: "master",
+}
+
+// newOwnersVCSClient returns a mock VCS client with no CODEOWNERS files on
+// any branch except for the files in baseFiles on the base branch.
+func newOwnersVCSClient(baseFiles map[string]string, modifiedFiles []string, approvers []string) *vcsmocks.MockClient {
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.GetFileContent(matchers.AnyModelsRepo(), AnyString(), AnyString())).ThenReturn(false, nil, nil)
+	for path, content := range baseFiles {
+		When(vcsClient.GetFileContent(fixtures.GithubRepo, "master", path)).ThenReturn(true, []byte(content), nil)
+	}
+	When(vcsClient.GetModifiedFiles(fixtures.GithubRepo, ownersPull)).ThenReturn(modifiedFiles, nil)
+	When(vcsClient.GetApprovers(fixtures.GithubRepo, ownersPull)).ThenReturn(approvers, nil)
+	When(vcsClient.IsTeamMember(matchers.AnyModelsRepo(), AnyString(), matchers.AnyModelsUser())).ThenReturn(false, nil)
+	return vcsClient
+}
+
+func TestDefaultOwnerApprovalChecker_UnapprovedFiles(t *testing.T) {
+	codeowners := `
+*.tf @alice
+/envs/prod/ @runatlantis/prod-admins
+/envs/staging/
+docs/ ops@example.com
+`
+	modifiedFiles := []string{
+		"main.tf",
+		"envs/prod/main.tf",
+		"envs/staging/main.tf",
+		"docs/main.tf",
+	}
+	cases := []struct {
+		description   string
+		repoRelDir    string
+		approvers     []string
+		expUnapproved []string
+	}{
+		{
+			"no approvals",
+			".",
+			nil,
+			[]string{"main.tf", "envs/prod/main.tf", "docs/main.tf"},
+		},
+		{
+			"approved by user owner",
+			".",
+			[]string{"bob", "Alice"},
+			[]string{"envs/prod/main.tf", "docs/main.tf"},
+		},
+		{
+			"approved by team member",
+			"envs/prod",
+			[]string{"prod-admin"},
+			nil,
+		},
+		{
+			"approved by owner of other files",
+			"envs/prod",
+			[]string{"alice"},
+			[]string{"envs/prod/main.tf"},
+		},
+		{
+			"files without owners",
+			"envs/staging",
+			nil,
+			nil,
+		},
+		{
+			"email owners are skipped",
+			"docs",
+			[]string{"ops"},
+			[]string{"docs/main.tf"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			vcsClient := newOwnersVCSClient(map[string]string{".github/CODEOWNERS": codeowners}, modifiedFiles, c.approvers)
+			When(vcsClient.IsTeamMember(fixtures.GithubRepo, "runatlantis/prod-admins", models.User{Username: "prod-admin"})).ThenReturn(true, nil)
+			checker := &events.DefaultOwnerApprovalChecker{VCSClient: vcsClient}
+
+			ctx := models.ProjectCommandContext{
+				Log:        logging.NewNoopLogger(),
+				BaseRepo:   fixtures.GithubRepo,
+				Pull:       ownersPull,
+				RepoRelDir: c.repoRelDir,
+			}
+			unapproved, err := checker.UnapprovedFiles(ctx)
+			Ok(t, err)
+			Equals(t, c.expUnapproved, unapproved)
+		})
+	}
+}
+
+// Test that the project's when_modified patterns decide which files need
+// approval.
+func TestDefaultOwnerApprovalChecker_UnapprovedFiles_WhenModified(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := newOwnersVCSClient(map[string]string{"CODEOWNERS": "* @alice\n"}, []string{"project/main.tf", "project/vars.yaml", "modules/db/main.tf"}, nil)
+	checker := &events.DefaultOwnerApprovalChecker{VCSClient: vcsClient}
+
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(),
+		BaseRepo:   fixtures.GithubRepo,
+		Pull:       ownersPull,
+		RepoRelDir: "project",
+		ProjectConfig: &valid.Project{
+			Dir: "project",
+			Autoplan: valid.Autoplan{
+				WhenModified: []string{"*.tf*", "../modules/**/*.tf"},
+				Enabled:      true,
+			},
+		},
+	}
+	unapproved, err := checker.UnapprovedFiles(ctx)
+	Ok(t, err)
+	Equals(t, []string{"project/main.tf", "modules/db/main.tf"}, unapproved)
+}
+
+// Test that a pull request can't remove the owners of its files by changing
+// CODEOWNERS since it's read from the base branch, and that the change to
+// CODEOWNERS itself needs an owner's approval.
+func TestDefaultOwnerApprovalChecker_UnapprovedFiles_ChangedCodeowners(t *testing.T) {
+	cases := []struct {
+		description   string
+		codeowners    string
+		expUnapproved []string
+	}{
+		{
+			"CODEOWNERS with owners",
+			"*.tf @alice\n/.github/ @bob\n",
+			[]string{"main.tf", ".github/CODEOWNERS"},
+		},
+		{
+			"CODEOWNERS without owners needs any owner",
+			"*.tf @alice\n",
+			[]string{"main.tf", ".github/CODEOWNERS"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			vcsClient := newOwnersVCSClient(map[string]string{".github/CODEOWNERS": c.codeowners}, []string{"main.tf", ".github/CODEOWNERS"}, []string{"carol"})
+			// The pull request's CODEOWNERS doesn't have the rule for .tf files.
+			When(vcsClient.GetFileContent(fixtures.GithubRepo, "branch", ".github/CODEOWNERS")).ThenReturn(true, []byte("/.github/ @bob\n"), nil)
+			checker := &events.DefaultOwnerApprovalChecker{VCSClient: vcsClient}
+
+			ctx := models.ProjectCommandContext{
+				Log:        logging.NewNoopLogger(),
+				BaseRepo:   fixtures.GithubRepo,
+				Pull:       ownersPull,
+				RepoRelDir: ".",
+			}
+			unapproved, err := checker.UnapprovedFiles(ctx)
+			Ok(t, err)
+			Equals(t, c.expUnapproved, unapproved)
+			vcsClient.VerifyWasCalled(Never()).GetFileContent(fixtures.GithubRepo, "branch", ".github/CODEOWNERS")
+		})
+	}
+}
+
+func TestDefaultOwnerApprovalChecker_UnapprovedFiles_NoCodeowners(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := newOwnersVCSClient(nil, nil, nil)
+	checker := &events.DefaultOwnerApprovalChecker{VCSClient: vcsClient}
+
+	_, err := checker.UnapprovedFiles(models.ProjectCommandContext{BaseRepo: fixtures.GithubRepo, Pull: ownersPull, RepoRelDir: "."})
+	ErrEquals(t, "the approved_by_owners apply requirement needs a CODEOWNERS file on the master branch at one of .github/CODEOWNERS, .gitlab/CODEOWNERS, .bitbucket/CODEOWNERS, CODEOWNERS, docs/CODEOWNERS", err)
+}
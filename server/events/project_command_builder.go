@@ -2,6 +2,8 @@ package events
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/go-version"
@@ -9,6 +11,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 )
@@ -37,6 +40,18 @@ type ProjectCommandBuilder interface {
 	// comment doesn't specify one project then there may be multiple commands
 	// to be run.
 	BuildApplyCommands(ctx *CommandContext, commentCommand *CommentCommand) ([]models.ProjectCommandContext, error)
+	// BuildApprovePoliciesCommands builds project approve_policies commands
+	// for this comment. It returns an error if the user isn't a policy
+	// approver.
+	BuildApprovePoliciesCommands(ctx *CommandContext, commentCommand *CommentCommand) ([]models.ProjectCommandContext, error)
+	// BuildDriftCommands builds project commands that will plan every
+	// project on the default branch of ctx.BaseRepo to check it for drift.
+	BuildDriftCommands(ctx *CommandContext) ([]models.ProjectCommandContext, error)
+	// BuildCommentTarget returns the project that a comment targeting a
+	// specific project is for without cloning the repo. It's only used to
+	// check permissions before anything is cloned so it may be missing
+	// config that BuildPlanCommands and BuildApplyCommands would find.
+	BuildCommentTarget(ctx *CommandContext, commentCommand *CommentCommand) models.ProjectCommandContext
 }
 
 // DefaultProjectCommandBuilder implements ProjectCommandBuilder.
@@ -52,6 +67,10 @@ type DefaultProjectCommandBuilder struct {
 	AllowRepoConfigFlag string
 	PendingPlanFinder   *DefaultPendingPlanFinder
 	CommentBuilder      CommentBuilder
+	// ServerConfig is the server-side repo config. Repos that match it can
+	// use atlantis.yaml files even if AllowRepoConfig is false but their
+	// files are restricted to what it allows.
+	ServerConfig valid.ServerConfig
 }
 
 // TFCommandRunner runs Terraform commands.
@@ -97,15 +116,13 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 
 	// Parse config file if it exists.
 	var config valid.Config
+	policy, hasPolicy := p.repoPolicy(ctx.BaseRepo)
 	hasConfigFile, err := p.ParserValidator.HasConfigFile(repoDir)
 	if err != nil {
 		return nil, errors.Wrapf(err, "looking for %s file in %q", yaml.AtlantisYAMLFilename, repoDir)
 	}
 	if hasConfigFile {
-		if !p.AllowRepoConfig {
-			return nil, fmt.Errorf("%s files not allowed because Atlantis is not running with --%s", yaml.AtlantisYAMLFilename, p.AllowRepoConfigFlag)
-		}
-		config, err = p.ParserValidator.ReadConfig(repoDir)
+		config, err = p.readConfig(repoDir, policy, hasPolicy)
 		if err != nil {
 			return nil, err
 		}
@@ -130,15 +147,16 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 		modifiedProjects := p.ProjectFinder.DetermineProjects(ctx.Log, modifiedFiles, ctx.BaseRepo.FullName, repoDir)
 		ctx.Log.Info("automatically determined that there were %d projects modified in this pull request: %s", len(modifiedProjects), modifiedProjects)
 		for _, mp := range modifiedProjects {
+			projCfg, globalCfg := p.serverDefaultCfg(policy, hasPolicy, mp.Path, DefaultWorkspace)
 			projCtxs = append(projCtxs, models.ProjectCommandContext{
 				BaseRepo:      ctx.BaseRepo,
 				HeadRepo:      ctx.HeadRepo,
 				Pull:          ctx.Pull,
 				User:          ctx.User,
-				Log:           ctx.Log,
+				Log:           projectLogger(ctx.Log, mp.Path, DefaultWorkspace, ""),
 				RepoRelDir:    mp.Path,
-				ProjectConfig: nil,
-				GlobalConfig:  nil,
+				ProjectConfig: projCfg,
+				GlobalConfig:  globalCfg,
 				CommentArgs:   commentFlags,
 				Workspace:     DefaultWorkspace,
 				Verbose:       verbose,
@@ -165,7 +183,7 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 				HeadRepo:      ctx.HeadRepo,
 				Pull:          ctx.Pull,
 				User:          ctx.User,
-				Log:           ctx.Log,
+				Log:           projectLogger(ctx.Log, mp.Dir, mp.Workspace, mp.GetName()),
 				CommentArgs:   commentFlags,
 				Workspace:     mp.Workspace,
 				RepoRelDir:    mp.Dir,
@@ -247,6 +265,7 @@ func (p *DefaultProjectCommandBuilder) buildApplyAllCommands(ctx *CommandContext
 		if err != nil {
 			return nil, errors.Wrapf(err, "building command for dir %q", plan.RepoRelDir)
 		}
+		cmd.AllowDestroy = commentCmd.AllowDestroy
 		cmds = append(cmds, cmd)
 	}
 	return cmds, nil
@@ -266,6 +285,162 @@ func (p *DefaultProjectCommandBuilder) BuildApplyCommands(ctx *CommandContext, c
 	return []models.ProjectCommandContext{pac}, nil
 }
 
+// BuildApprovePoliciesCommands builds project approve_policies commands for
+// this comment. Like apply, if the comment doesn't specify one project then
+// it's run for every plan in the pull request. It returns an error if the user
+// isn't one of the server-side config's policy approvers.
+func (p *DefaultProjectCommandBuilder) BuildApprovePoliciesCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	if !p.ServerConfig.Policies.IsApprover(ctx.User.Username) {
+		return nil, fmt.Errorf("user %q is not a policy approver", ctx.User.Username)
+	}
+	return p.BuildApplyCommands(ctx, cmd)
+}
+
+// BuildDriftCommands builds project commands that will plan every project on
+// the default branch of ctx.BaseRepo to check it for drift. If the repo has
+// an atlantis.yaml file, those are its projects. Otherwise they're found the
+// same way as for autoplanning except every file in the repo counts as
+// modified.
+func (p *DefaultProjectCommandBuilder) BuildDriftCommands(ctx *CommandContext) ([]models.ProjectCommandContext, error) {
+	workspace := DefaultWorkspace
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, DriftPullNum, workspace)
+	if err != nil {
+		ctx.Log.Warn("workspace was locked")
+		return nil, err
+	}
+	defer unlockFn()
+
+	repoDir, err := p.WorkingDir.CloneDefaultBranch(ctx.Log, ctx.BaseRepo, workspace)
+	if err != nil {
+		return nil, err
+	}
+	policy, hasPolicy := p.repoPolicy(ctx.BaseRepo)
+	hasConfigFile, err := p.ParserValidator.HasConfigFile(repoDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "looking for %s file in %q", yaml.AtlantisYAMLFilename, repoDir)
+	}
+	var projCtxs []models.ProjectCommandContext
+	if !hasConfigFile {
+		repoFiles, err := listRepoFiles(repoDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "listing files in repo")
+		}
+		projects := p.ProjectFinder.DetermineProjects(ctx.Log, repoFiles, ctx.BaseRepo.FullName, repoDir)
+		for _, mp := range projects {
+			projCfg, globalCfg := p.serverDefaultCfg(policy, hasPolicy, mp.Path, DefaultWorkspace)
+			projCtxs = append(projCtxs, models.ProjectCommandContext{
+				BaseRepo:      ctx.BaseRepo,
+				HeadRepo:      ctx.HeadRepo,
+				Pull:          ctx.Pull,
+				User:          ctx.User,
+				Log:           projectLogger(ctx.Log, mp.Path, DefaultWorkspace, ""),
+				RepoRelDir:    mp.Path,
+				ProjectConfig: projCfg,
+				GlobalConfig:  globalCfg,
+				Workspace:     DefaultWorkspace,
+			})
+		}
+		return projCtxs, nil
+	}
+
+	config, err := p.readConfig(repoDir, policy, hasPolicy)
+	if err != nil {
+		return nil, err
+	}
+	// Use for i instead of range because need to get the pointer to the
+	// project config.
+	for i := 0; i < len(config.Projects); i++ {
+		mp := config.Projects[i]
+		if _, err := os.Stat(filepath.Join(repoDir, mp.Dir)); err != nil {
+			ctx.Log.Warn("skipping project at dir %q because it doesn't exist", mp.Dir)
+			continue
+		}
+		projCtxs = append(projCtxs, models.ProjectCommandContext{
+			BaseRepo:      ctx.BaseRepo,
+			HeadRepo:      ctx.HeadRepo,
+			Pull:          ctx.Pull,
+			User:          ctx.User,
+			Log:           projectLogger(ctx.Log, mp.Dir, mp.Workspace, mp.GetName()),
+			RepoRelDir:    mp.Dir,
+			ProjectConfig: &mp,
+			GlobalConfig:  &config,
+			Workspace:     mp.Workspace,
+		})
+	}
+	return projCtxs, nil
+}
+
+// listRepoFiles returns the paths of all the files in the repo cloned to
+// repoDir, relative to repoDir.
+func listRepoFiles(repoDir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// BuildCommentTarget implements ProjectCommandBuilder.
+// If the comment targets a project by name we look it up in the
+// atlantis.yaml file on the head branch through the VCS API. If we can't read
+// the file, the project only has the name the comment used.
+func (p *DefaultProjectCommandBuilder) BuildCommentTarget(ctx *CommandContext, cmd *CommentCommand) models.ProjectCommandContext {
+	target := models.ProjectCommandContext{
+		BaseRepo:   ctx.BaseRepo,
+		HeadRepo:   ctx.HeadRepo,
+		Log:        ctx.Log,
+		Pull:       ctx.Pull,
+		User:       ctx.User,
+		RepoRelDir: DefaultRepoRelDir,
+		Workspace:  DefaultWorkspace,
+	}
+	if cmd.RepoRelDir != "" {
+		target.RepoRelDir = cmd.RepoRelDir
+	}
+	if cmd.Workspace != "" {
+		target.Workspace = cmd.Workspace
+	}
+	if cmd.ProjectName == "" {
+		return target
+	}
+
+	name := cmd.ProjectName
+	target.ProjectConfig = &valid.Project{Name: &name, Dir: target.RepoRelDir, Workspace: target.Workspace}
+	exists, content, err := p.VCSClient.GetFileContent(ctx.HeadRepo, ctx.Pull.HeadBranch, yaml.AtlantisYAMLFilename)
+	if err != nil {
+		ctx.Log.Debug("unable to read %s to find project %q: %s", yaml.AtlantisYAMLFilename, name, err)
+		return target
+	}
+	if !exists {
+		return target
+	}
+	config, err := p.ParserValidator.ParseConfig(content, p.ServerConfig.Workflows)
+	if err != nil {
+		ctx.Log.Debug("unable to find project %q: %s", name, err)
+		return target
+	}
+	if proj := config.FindProjectByName(name); proj != nil {
+		target.ProjectConfig = proj
+		target.RepoRelDir = proj.Dir
+		target.Workspace = proj.Workspace
+	}
+	return target
+}
+
 func (p *DefaultProjectCommandBuilder) buildProjectApplyCommand(ctx *CommandContext, cmd *CommentCommand) (models.ProjectCommandContext, error) {
 	workspace := DefaultWorkspace
 	if cmd.Workspace != "" {
@@ -289,11 +464,13 @@ func (p *DefaultProjectCommandBuilder) buildProjectApplyCommand(ctx *CommandCont
 		repoRelDir = cmd.RepoRelDir
 	}
 
-	return p.buildProjectCommandCtx(ctx, cmd.ProjectName, cmd.Flags, repoDir, repoRelDir, workspace)
+	projCtx, err = p.buildProjectCommandCtx(ctx, cmd.ProjectName, cmd.Flags, repoDir, repoRelDir, workspace)
+	projCtx.AllowDestroy = cmd.AllowDestroy
+	return projCtx, err
 }
 
 func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *CommandContext, projectName string, commentFlags []string, repoDir string, repoRelDir string, workspace string) (models.ProjectCommandContext, error) {
-	projCfg, globalCfg, err := p.getCfg(projectName, repoRelDir, workspace, repoDir)
+	projCfg, globalCfg, err := p.getCfg(ctx.BaseRepo, projectName, repoRelDir, workspace, repoDir)
 	if err != nil {
 		return models.ProjectCommandContext{}, err
 	}
@@ -315,7 +492,7 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *CommandContex
 		HeadRepo:      ctx.HeadRepo,
 		Pull:          ctx.Pull,
 		User:          ctx.User,
-		Log:           ctx.Log,
+		Log:           projectLogger(ctx.Log, repoRelDir, workspace, projectName),
 		CommentArgs:   commentFlags,
 		Workspace:     workspace,
 		RepoRelDir:    repoRelDir,
@@ -327,7 +504,21 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *CommandContex
 	}, nil
 }
 
-func (p *DefaultProjectCommandBuilder) getCfg(projectName string, dir string, workspace string, repoDir string) (projectCfg *valid.Project, globalCfg *valid.Config, err error) {
+// projectLogger returns a logger that adds the project's dir, workspace and
+// name to the fields of log's entries.
+func projectLogger(log *logging.SimpleLogger, repoRelDir string, workspace string, projectName string) *logging.SimpleLogger {
+	fields := logging.Fields{
+		logging.DirKey:       repoRelDir,
+		logging.WorkspaceKey: workspace,
+	}
+	if projectName != "" {
+		fields[logging.ProjectKey] = projectName
+	}
+	return log.WithFields(fields)
+}
+
+func (p *DefaultProjectCommandBuilder) getCfg(repo models.Repo, projectName string, dir string, workspace string, repoDir string) (projectCfg *valid.Project, globalCfg *valid.Config, err error) {
+	policy, hasPolicy := p.repoPolicy(repo)
 	hasConfigFile, err := p.ParserValidator.HasConfigFile(repoDir)
 	if err != nil {
 		err = errors.Wrapf(err, "looking for %s file in %q", yaml.AtlantisYAMLFilename, repoDir)
@@ -338,15 +529,11 @@ func (p *DefaultProjectCommandBuilder) getCfg(projectName string, dir string, wo
 			err = fmt.Errorf("cannot specify a project name unless an %s file exists to configure projects", yaml.AtlantisYAMLFilename)
 			return
 		}
+		projectCfg, globalCfg = p.serverDefaultCfg(policy, hasPolicy, dir, workspace)
 		return
 	}
 
-	if !p.AllowRepoConfig {
-		err = fmt.Errorf("%s files not allowed because Atlantis is not running with --%s", yaml.AtlantisYAMLFilename, p.AllowRepoConfigFlag)
-		return
-	}
-
-	globalCfgStruct, err := p.ParserValidator.ReadConfig(repoDir)
+	globalCfgStruct, err := p.readConfig(repoDir, policy, hasPolicy)
 	if err != nil {
 		return
 	}
@@ -365,6 +552,9 @@ func (p *DefaultProjectCommandBuilder) getCfg(projectName string, dir string, wo
 
 	projCfgs := globalCfg.FindProjectsByDirWorkspace(dir, workspace)
 	if len(projCfgs) == 0 {
+		// The project isn't configured in atlantis.yaml but the server-side
+		// defaults still apply.
+		projectCfg, _ = p.serverDefaultCfg(policy, hasPolicy, dir, workspace)
 		return
 	}
 	if len(projCfgs) > 1 {
@@ -375,6 +565,111 @@ func (p *DefaultProjectCommandBuilder) getCfg(projectName string, dir string, wo
 	return
 }
 
+// repoPolicy returns the server-side repo config for repo and whether any of
+// it matched the repo.
+func (p *DefaultProjectCommandBuilder) repoPolicy(repo models.Repo) (valid.RepoPolicy, bool) {
+	return p.ServerConfig.PolicyForRepo(fmt.Sprintf("%s/%s", repo.VCSHost.Hostname, repo.FullName))
+}
+
+// readConfig reads the atlantis.yaml file in repoDir and merges the
+// server-side repo config into it. If hasPolicy is true, it returns an error
+// if the file sets anything that policy doesn't allow.
+// Keys set in atlantis.yaml take precedence over the server-side repo config,
+// which takes precedence over Atlantis' defaults. The --require-approval and
+// --require-mergeable flags still override all of these when applying.
+func (p *DefaultProjectCommandBuilder) readConfig(repoDir string, policy valid.RepoPolicy, hasPolicy bool) (valid.Config, error) {
+	if !p.AllowRepoConfig && !hasPolicy {
+		return valid.Config{}, fmt.Errorf("%s files not allowed because Atlantis is not running with --%s", yaml.AtlantisYAMLFilename, p.AllowRepoConfigFlag)
+	}
+	config, err := p.ParserValidator.ReadConfigWithServerWorkflows(repoDir, p.ServerConfig.Workflows)
+	if err != nil {
+		return valid.Config{}, err
+	}
+	if hasPolicy {
+		if err := p.validatePolicy(config, policy); err != nil {
+			return valid.Config{}, err
+		}
+	}
+
+	// The server's workflows can be used by any repo but the repo's own
+	// workflows take precedence.
+	workflows := make(map[string]valid.Workflow)
+	for name, w := range p.ServerConfig.Workflows {
+		workflows[name] = w
+	}
+	for name, w := range config.Workflows {
+		workflows[name] = w
+	}
+	config.Workflows = workflows
+	for i := range config.Projects {
+		config.Projects[i] = p.applyPolicyDefaults(config.Projects[i], policy)
+	}
+	return config, nil
+}
+
+// validatePolicy returns an error if config sets anything that policy
+// doesn't allow.
+func (p *DefaultProjectCommandBuilder) validatePolicy(config valid.Config, policy valid.RepoPolicy) error {
+	if len(config.Workflows) > 0 && !policy.AllowCustomWorkflows {
+		return fmt.Errorf("%s cannot define workflows because the server-side repo config does not set allow_custom_workflows: true", yaml.AtlantisYAMLFilename)
+	}
+	if !policy.AllowRunSteps {
+		for name, w := range config.Workflows {
+			for _, stage := range []*valid.Stage{w.Plan, w.Apply} {
+				if stage == nil {
+					continue
+				}
+				for _, step := range stage.Steps {
+					if step.StepName == raw.RunStepName {
+						return fmt.Errorf("workflow %q cannot use run steps because the server-side repo config does not set allow_run_steps: true", name)
+					}
+				}
+			}
+		}
+	}
+	for _, proj := range config.Projects {
+		if proj.Workflow != nil && !policy.IsOverrideAllowed(raw.WorkflowKey) {
+			return fmt.Errorf("project at dir: %q workspace: %q cannot set %s because the server-side repo config does not include it in allowed_overrides", proj.Dir, proj.Workspace, raw.WorkflowKey)
+		}
+		// no_destroy_resources changes what the no_destroy apply requirement
+		// protects so it's part of the apply_requirements override.
+		if (len(proj.ApplyRequirements) > 0 || len(proj.NoDestroyResources) > 0) && !policy.IsOverrideAllowed(raw.ApplyRequirementsKey) {
+			return fmt.Errorf("project at dir: %q workspace: %q cannot set %s because the server-side repo config does not include it in allowed_overrides", proj.Dir, proj.Workspace, raw.ApplyRequirementsKey)
+		}
+	}
+	return nil
+}
+
+// applyPolicyDefaults returns proj with policy's defaults for the keys that
+// proj doesn't set.
+func (p *DefaultProjectCommandBuilder) applyPolicyDefaults(proj valid.Project, policy valid.RepoPolicy) valid.Project {
+	if proj.Workflow == nil {
+		proj.Workflow = policy.Workflow
+	}
+	if len(proj.ApplyRequirements) == 0 {
+		proj.ApplyRequirements = policy.ApplyRequirements
+	}
+	return proj
+}
+
+// serverDefaultCfg returns the config for the project at dir and workspace
+// when it isn't configured in an atlantis.yaml file. If hasPolicy is false
+// there is no config so it returns nils.
+func (p *DefaultProjectCommandBuilder) serverDefaultCfg(policy valid.RepoPolicy, hasPolicy bool, dir string, workspace string) (*valid.Project, *valid.Config) {
+	if !hasPolicy {
+		return nil, nil
+	}
+	proj := p.applyPolicyDefaults(valid.Project{
+		Dir:       dir,
+		Workspace: workspace,
+		Autoplan:  raw.DefaultAutoPlan(),
+	}, policy)
+	return &proj, &valid.Config{
+		Version:   2,
+		Workflows: p.ServerConfig.Workflows,
+	}
+}
+
 // validateWorkspaceAllowed returns an error if there are projects configured
 // in globalCfg for repoRelDir and none of those projects use workspace.
 func (p *DefaultProjectCommandBuilder) validateWorkspaceAllowed(globalCfg *valid.Config, repoRelDir string, workspace string) error {
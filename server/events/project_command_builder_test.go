@@ -1,10 +1,13 @@
 package events_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"path/filepath"
+	"regexp"
 	"testing"
 
+	"github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/mocks"
@@ -218,7 +221,8 @@ projects:
 				Equals(t, baseRepo, actCtx.HeadRepo)
 				Equals(t, pull, actCtx.Pull)
 				Equals(t, models.User{}, actCtx.User)
-				Equals(t, logger, actCtx.Log)
+				Equals(t, expCtx.dir, actCtx.Log.Fields[logging.DirKey])
+				Equals(t, expCtx.workspace, actCtx.Log.Fields[logging.WorkspaceKey])
 				Equals(t, 0, len(actCtx.CommentArgs))
 
 				Equals(t, expCtx.projectConfig, actCtx.ProjectConfig)
@@ -507,7 +511,8 @@ projects:
 				Equals(t, baseRepo, actCtx.HeadRepo)
 				Equals(t, pull, actCtx.Pull)
 				Equals(t, models.User{}, actCtx.User)
-				Equals(t, logger, actCtx.Log)
+				Equals(t, c.ExpDir, actCtx.Log.Fields[logging.DirKey])
+				Equals(t, c.ExpWorkspace, actCtx.Log.Fields[logging.WorkspaceKey])
 
 				Equals(t, c.ExpProjectConfig, actCtx.ProjectConfig)
 				Equals(t, c.ExpDir, actCtx.RepoRelDir)
@@ -820,15 +825,19 @@ func TestDefaultProjectCommandBuilder_BuildMultiApply(t *testing.T) {
 		User:     models.User{},
 		Log:      logging.NewNoopLogger(),
 	}, &events.CommentCommand{
-		RepoRelDir:  "",
-		Flags:       nil,
-		Name:        models.ApplyCommand,
-		Verbose:     false,
-		Workspace:   "",
-		ProjectName: "",
+		RepoRelDir:   "",
+		Flags:        nil,
+		Name:         models.ApplyCommand,
+		Verbose:      false,
+		Workspace:    "",
+		ProjectName:  "",
+		AllowDestroy: true,
 	})
 	Ok(t, err)
 	Equals(t, 4, len(ctxs))
+	for _, ctx := range ctxs {
+		Assert(t, ctx.AllowDestroy, "exp --allow-destroy to be passed to %s", ctx.RepoRelDir)
+	}
 	Equals(t, "project1", ctxs[0].RepoRelDir)
 	Equals(t, "workspace1", ctxs[0].Workspace)
 	Equals(t, "project2", ctxs[1].RepoRelDir)
@@ -839,6 +848,257 @@ func TestDefaultProjectCommandBuilder_BuildMultiApply(t *testing.T) {
 	Equals(t, "workspace2", ctxs[3].Workspace)
 }
 
+// Only policy approvers should be able to approve policy check failures.
+func TestDefaultProjectCommandBuilder_BuildApprovePoliciesCommands(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"main.tf": nil,
+	})
+	defer cleanup()
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.GetWorkingDir(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString())).
+		ThenReturn(tmpDir, nil)
+
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+		WorkingDir:        workingDir,
+		ParserValidator:   &yaml.ParserValidator{},
+		ProjectFinder:     &events.DefaultProjectFinder{},
+		PendingPlanFinder: &events.DefaultPendingPlanFinder{},
+		CommentBuilder:    &events.CommentParser{},
+		ServerConfig: valid.ServerConfig{
+			Policies: valid.PolicySets{Approvers: []string{"alice"}},
+		},
+	}
+	cmd := &events.CommentCommand{Name: models.ApprovePoliciesCommand, RepoRelDir: "."}
+
+	_, err := builder.BuildApprovePoliciesCommands(&events.CommandContext{
+		User: models.User{Username: "mallory"},
+		Log:  logging.NewNoopLogger(),
+	}, cmd)
+	ErrEquals(t, `user "mallory" is not a policy approver`, err)
+
+	ctxs, err := builder.BuildApprovePoliciesCommands(&events.CommandContext{
+		User: models.User{Username: "alice"},
+		Log:  logging.NewNoopLogger(),
+	}, cmd)
+	Ok(t, err)
+	Equals(t, 1, len(ctxs))
+	Equals(t, ".", ctxs[0].RepoRelDir)
+	Equals(t, "default", ctxs[0].Workspace)
+}
+
+func TestDefaultProjectCommandBuilder_BuildCommentTarget(t *testing.T) {
+	atlantisYAML := []byte(`
+version: 2
+projects:
+- name: prod
+  dir: envs/prod
+  workspace: production
+`)
+	cases := []struct {
+		description  string
+		cmd          events.CommentCommand
+		fileErr      error
+		expName      string
+		expDir       string
+		expWorkspace string
+	}{
+		{
+			"defaults",
+			events.CommentCommand{},
+			nil,
+			"",
+			".",
+			"default",
+		},
+		{
+			"dir and workspace",
+			events.CommentCommand{RepoRelDir: "envs/staging", Workspace: "staging"},
+			nil,
+			"",
+			"envs/staging",
+			"staging",
+		},
+		{
+			"name in atlantis.yaml",
+			events.CommentCommand{ProjectName: "prod"},
+			nil,
+			"prod",
+			"envs/prod",
+			"production",
+		},
+		{
+			"name not in atlantis.yaml",
+			events.CommentCommand{ProjectName: "other"},
+			nil,
+			"other",
+			".",
+			"default",
+		},
+		{
+			"can't read atlantis.yaml",
+			events.CommentCommand{ProjectName: "prod"},
+			errors.New("err"),
+			"prod",
+			".",
+			"default",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetFileContent(matchers.AnyModelsRepo(), EqString("branch"), EqString(yaml.AtlantisYAMLFilename))).
+				ThenReturn(c.fileErr == nil, atlantisYAML, c.fileErr)
+			workingDir := mocks.NewMockWorkingDir()
+			builder := &events.DefaultProjectCommandBuilder{
+				WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+				WorkingDir:       workingDir,
+				ParserValidator:  &yaml.ParserValidator{},
+				VCSClient:        vcsClient,
+			}
+			cmd := c.cmd
+
+			target := builder.BuildCommentTarget(&events.CommandContext{
+				Pull: models.PullRequest{HeadBranch: "branch"},
+				Log:  logging.NewNoopLogger(),
+			}, &cmd)
+			Equals(t, c.expName, target.GetProjectName())
+			Equals(t, c.expDir, target.RepoRelDir)
+			Equals(t, c.expWorkspace, target.Workspace)
+			workingDir.VerifyWasCalled(Never()).Clone(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString())
+		})
+	}
+}
+
+func TestDefaultProjectCommandBuilder_BuildDriftCommands(t *testing.T) {
+	cases := []struct {
+		description  string
+		atlantisYAML string
+		expDirs      []string
+		expWorkspace []string
+	}{
+		{
+			description:  "no atlantis.yaml",
+			expDirs:      []string{"project1", "project2"},
+			expWorkspace: []string{"default", "default"},
+		},
+		{
+			description: "atlantis.yaml",
+			atlantisYAML: `version: 2
+projects:
+- dir: project1
+  workspace: staging
+  autoplan:
+    enabled: false
+- dir: project2
+- dir: missing
+`,
+			expDirs:      []string{"project1", "project2"},
+			expWorkspace: []string{"staging", "default"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+				".git": map[string]interface{}{
+					"main.tf": nil,
+				},
+				"project1": map[string]interface{}{
+					"main.tf": nil,
+				},
+				"project2": map[string]interface{}{
+					"main.tf": nil,
+				},
+			})
+			defer cleanup()
+			if c.atlantisYAML != "" {
+				err := ioutil.WriteFile(filepath.Join(tmpDir, yaml.AtlantisYAMLFilename), []byte(c.atlantisYAML), 0600)
+				Ok(t, err)
+			}
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.CloneDefaultBranch(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsRepo(),
+				AnyString())).ThenReturn(tmpDir, nil)
+
+			builder := &events.DefaultProjectCommandBuilder{
+				WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+				WorkingDir:          workingDir,
+				ParserValidator:     &yaml.ParserValidator{},
+				ProjectFinder:       &events.DefaultProjectFinder{},
+				AllowRepoConfig:     true,
+				AllowRepoConfigFlag: "allow-repo-config",
+				CommentBuilder:      &events.CommentParser{},
+			}
+			ctxs, err := builder.BuildDriftCommands(&events.CommandContext{
+				Log: logging.NewNoopLogger(),
+			})
+			Ok(t, err)
+			Equals(t, len(c.expDirs), len(ctxs))
+			for i, ctx := range ctxs {
+				Equals(t, c.expDirs[i], ctx.RepoRelDir)
+				Equals(t, c.expWorkspace[i], ctx.Workspace)
+			}
+		})
+	}
+}
+
+// Test that drift commands lock the drift working dir, not pull 0's which
+// API commands on a branch use.
+func TestDefaultProjectCommandBuilder_BuildDriftCommands_Locking(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"main.tf": nil,
+	})
+	defer cleanup()
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.CloneDefaultBranch(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		AnyString())).ThenReturn(tmpDir, nil)
+	locker := events.NewDefaultWorkingDirLocker()
+	builder := &events.DefaultProjectCommandBuilder{
+		WorkingDirLocker:    locker,
+		WorkingDir:          workingDir,
+		ParserValidator:     &yaml.ParserValidator{},
+		ProjectFinder:       &events.DefaultProjectFinder{},
+		AllowRepoConfig:     true,
+		AllowRepoConfigFlag: "allow-repo-config",
+		CommentBuilder:      &events.CommentParser{},
+	}
+	ctx := &events.CommandContext{
+		BaseRepo: models.Repo{FullName: "owner/repo"},
+		Log:      logging.NewNoopLogger(),
+	}
+
+	unlockRef, err := locker.TryLock("owner/repo", 0, events.DefaultWorkspace)
+	Ok(t, err)
+	_, err = builder.BuildDriftCommands(ctx)
+	Ok(t, err)
+	unlockRef()
+
+	unlockDrift, err := locker.TryLock("owner/repo", events.DriftPullNum, events.DefaultWorkspace)
+	Ok(t, err)
+	defer unlockDrift()
+	_, err = builder.BuildDriftCommands(ctx)
+	Assert(t, err != nil, "expected an error since the drift working dir is locked")
+	workingDir.VerifyWasCalledOnce().CloneDefaultBranch(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		AnyString())
+}
+
 // Test that if repo config is disabled we error out if there's an atlantis.yaml
 // file.
 func TestDefaultProjectCommandBuilder_RepoConfigDisabled(t *testing.T) {
@@ -967,3 +1227,275 @@ projects:
 }
 
 func String(v string) *string { return &v }
+
+func Bool(v bool) *bool { return &v }
+
+// Test that the server-side repo config is merged with atlantis.yaml.
+func TestDefaultProjectCommandBuilder_ServerConfig(t *testing.T) {
+	prodWorkflow := valid.Workflow{
+		Plan: &valid.Stage{
+			Steps: []valid.Step{{StepName: "plan", ExtraArgs: []string{"-var-file=prod.tfvars"}}},
+		},
+	}
+	serverCfg := valid.ServerConfig{
+		Repos: []valid.ServerRepo{
+			{
+				IDRegex:           regexp.MustCompile("github.com/owner/.*"),
+				ApplyRequirements: []string{"approved"},
+				Workflow:          String("prod"),
+			},
+			{
+				ID:               "github.com/owner/overrides",
+				AllowedOverrides: []string{"workflow", "apply_requirements"},
+			},
+			{
+				ID:                   "github.com/owner/custom",
+				AllowCustomWorkflows: Bool(true),
+			},
+		},
+		Workflows: map[string]valid.Workflow{
+			"prod": prodWorkflow,
+		},
+	}
+	defaultAutoplan := valid.Autoplan{
+		WhenModified: []string{"**/*.tf*"},
+		Enabled:      true,
+	}
+
+	cases := []struct {
+		Description      string
+		RepoFullName     string
+		AllowRepoConfig  bool
+		AtlantisYAML     string
+		ExpProjectConfig *valid.Project
+		ExpWorkflows     map[string]valid.Workflow
+		ExpErr           string
+	}{
+		{
+			Description:      "repo not in server config",
+			RepoFullName:     "other/repo",
+			ExpProjectConfig: nil,
+		},
+		{
+			Description:  "repo not in server config with atlantis.yaml",
+			RepoFullName: "other/repo",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .`,
+			ExpErr: "atlantis.yaml files not allowed because Atlantis is not running with --allow-repo-config",
+		},
+		{
+			Description:  "no atlantis.yaml",
+			RepoFullName: "owner/repo",
+			ExpProjectConfig: &valid.Project{
+				Dir:               ".",
+				Workspace:         "default",
+				Workflow:          String("prod"),
+				Autoplan:          defaultAutoplan,
+				ApplyRequirements: []string{"approved"},
+			},
+			ExpWorkflows: serverCfg.Workflows,
+		},
+		{
+			Description:  "atlantis.yaml allowed without --allow-repo-config",
+			RepoFullName: "owner/repo",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .
+  terraform_version: v0.11.0`,
+			ExpProjectConfig: &valid.Project{
+				Dir:               ".",
+				Workspace:         "default",
+				Workflow:          String("prod"),
+				TerraformVersion:  version.Must(version.NewVersion("v0.11.0")),
+				Autoplan:          defaultAutoplan,
+				ApplyRequirements: []string{"approved"},
+			},
+			ExpWorkflows: serverCfg.Workflows,
+		},
+		{
+			Description:  "workflow override not allowed",
+			RepoFullName: "owner/repo",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .
+  workflow: prod`,
+			ExpErr: "project at dir: \".\" workspace: \"default\" cannot set workflow because the server-side repo config does not include it in allowed_overrides",
+		},
+		{
+			Description:  "apply_requirements override not allowed",
+			RepoFullName: "owner/repo",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .
+  apply_requirements: [mergeable]`,
+			ExpErr: "project at dir: \".\" workspace: \"default\" cannot set apply_requirements because the server-side repo config does not include it in allowed_overrides",
+		},
+		{
+			Description:  "no_destroy_resources override not allowed",
+			RepoFullName: "owner/repo",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .
+  no_destroy_resources: [aws_db_*]`,
+			ExpErr: "project at dir: \".\" workspace: \"default\" cannot set apply_requirements because the server-side repo config does not include it in allowed_overrides",
+		},
+		{
+			Description:  "overrides allowed",
+			RepoFullName: "owner/overrides",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .
+  workflow: prod
+  apply_requirements: [mergeable]`,
+			ExpProjectConfig: &valid.Project{
+				Dir:               ".",
+				Workspace:         "default",
+				Workflow:          String("prod"),
+				Autoplan:          defaultAutoplan,
+				ApplyRequirements: []string{"mergeable"},
+			},
+			ExpWorkflows: serverCfg.Workflows,
+		},
+		{
+			Description:  "custom workflows not allowed",
+			RepoFullName: "owner/repo",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .
+workflows:
+  custom:
+    plan:
+      steps: [init, plan]`,
+			ExpErr: "atlantis.yaml cannot define workflows because the server-side repo config does not set allow_custom_workflows: true",
+		},
+		{
+			Description:  "run steps not allowed",
+			RepoFullName: "owner/custom",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .
+workflows:
+  custom:
+    plan:
+      steps: [init, run: echo hi, plan]`,
+			ExpErr: "workflow \"custom\" cannot use run steps because the server-side repo config does not set allow_run_steps: true",
+		},
+		{
+			Description:  "custom workflows allowed",
+			RepoFullName: "owner/custom",
+			AtlantisYAML: `
+version: 2
+projects:
+- dir: .
+workflows:
+  custom:
+    plan:
+      steps: [init, plan]`,
+			ExpProjectConfig: &valid.Project{
+				Dir:               ".",
+				Workspace:         "default",
+				Workflow:          String("prod"),
+				Autoplan:          defaultAutoplan,
+				ApplyRequirements: []string{"approved"},
+			},
+			ExpWorkflows: map[string]valid.Workflow{
+				"prod": prodWorkflow,
+				"custom": {
+					Plan: &valid.Stage{
+						Steps: []valid.Step{{StepName: "init"}, {StepName: "plan"}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir, cleanup := TempDir(t)
+			defer cleanup()
+			if c.AtlantisYAML != "" {
+				err := ioutil.WriteFile(filepath.Join(tmpDir, yaml.AtlantisYAMLFilename), []byte(c.AtlantisYAML), 0600)
+				Ok(t, err)
+			}
+			err := ioutil.WriteFile(filepath.Join(tmpDir, "main.tf"), nil, 0600)
+			Ok(t, err)
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString())).ThenReturn(tmpDir, nil)
+			When(workingDir.GetWorkingDir(
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString())).ThenReturn(tmpDir, nil)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"main.tf"}, nil)
+
+			builder := &events.DefaultProjectCommandBuilder{
+				WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+				WorkingDir:          workingDir,
+				ParserValidator:     &yaml.ParserValidator{},
+				VCSClient:           vcsClient,
+				ProjectFinder:       &events.DefaultProjectFinder{},
+				AllowRepoConfig:     c.AllowRepoConfig,
+				AllowRepoConfigFlag: "allow-repo-config",
+				PendingPlanFinder:   &events.DefaultPendingPlanFinder{},
+				CommentBuilder:      &events.CommentParser{},
+				ServerConfig:        serverCfg,
+			}
+			repo := models.Repo{
+				FullName: c.RepoFullName,
+				VCSHost: models.VCSHost{
+					Hostname: "github.com",
+				},
+			}
+			ctx := &events.CommandContext{
+				BaseRepo: repo,
+				HeadRepo: repo,
+				Pull:     models.PullRequest{},
+				User:     models.User{},
+				Log:      logging.NewNoopLogger(),
+			}
+
+			t.Log("autoplan and comments for a specific project should use the same config")
+			autoplanCtxs, autoplanErr := builder.BuildAutoplanCommands(ctx)
+			applyCtxs, applyErr := builder.BuildApplyCommands(ctx, &events.CommentCommand{
+				RepoRelDir: ".",
+				Name:       models.ApplyCommand,
+			})
+			for _, err := range []error{autoplanErr, applyErr} {
+				if c.ExpErr != "" {
+					ErrEquals(t, c.ExpErr, err)
+				} else {
+					Ok(t, err)
+				}
+			}
+			if c.ExpErr != "" {
+				return
+			}
+			Equals(t, 1, len(autoplanCtxs))
+			Equals(t, 1, len(applyCtxs))
+			for _, actCtx := range []models.ProjectCommandContext{autoplanCtxs[0], applyCtxs[0]} {
+				Equals(t, c.ExpProjectConfig, actCtx.ProjectConfig)
+				if c.ExpWorkflows == nil {
+					Assert(t, actCtx.GlobalConfig == nil, "exp nil global config")
+				} else {
+					Equals(t, c.ExpWorkflows, actCtx.GlobalConfig.Workflows)
+				}
+			}
+		})
+	}
+}
@@ -15,17 +15,22 @@ package events
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/jobs"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/runtime"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/yaml/raw"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 )
 
 // DirNotExistErr is an error caused by the directory not existing.
@@ -46,6 +51,14 @@ type LockURLGenerator interface {
 	GenerateLockURL(lockID string) string
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_job_url_generator.go JobURLGenerator
+
+// JobURLGenerator generates urls to jobs.
+type JobURLGenerator interface {
+	// GenerateJobURL returns the full URL to the job at jobID.
+	GenerateJobURL(jobID string) string
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_step_runner.go StepRunner
 
 // StepRunner runs steps. Steps are individual pieces of execution like
@@ -55,6 +68,24 @@ type StepRunner interface {
 	Run(ctx models.ProjectCommandContext, extraArgs []string, path string) (string, error)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_plan_summary_runner.go PlanSummaryRunner
+
+// PlanSummaryRunner summarizes the changes in a project's plan.
+type PlanSummaryRunner interface {
+	// Run returns the summary of the plan for the project described by ctx
+	// at path or nil if the plan can't be summarized.
+	Run(ctx models.ProjectCommandContext, path string) (*models.PlanSummary, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_policy_check_step_runner.go PolicyCheckStepRunner
+
+// PolicyCheckStepRunner runs policy_check steps.
+type PolicyCheckStepRunner interface {
+	// Run returns the policy violations of the plan for the project
+	// described by ctx at path.
+	Run(ctx models.ProjectCommandContext, path string) ([]string, error)
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_webhooks_sender.go WebhooksSender
 
 // WebhooksSender sends webhook.
@@ -72,6 +103,12 @@ type ProjectCommandRunner interface {
 	Plan(ctx models.ProjectCommandContext) models.ProjectResult
 	// Apply runs terraform apply for the project described by ctx.
 	Apply(ctx models.ProjectCommandContext) models.ProjectResult
+	// ApprovePolicies approves the policy check failures of the plan for the
+	// project described by ctx so it can be applied.
+	ApprovePolicies(ctx models.ProjectCommandContext) models.ProjectResult
+	// PlanDrift runs terraform plan for the project described by ctx on its
+	// repo's default branch to check it for drift.
+	PlanDrift(ctx models.ProjectCommandContext) models.ProjectResult
 }
 
 // DefaultProjectCommandRunner implements ProjectCommandRunner.
@@ -82,18 +119,31 @@ type DefaultProjectCommandRunner struct {
 	PlanStepRunner           StepRunner
 	ApplyStepRunner          StepRunner
 	RunStepRunner            StepRunner
+	PolicyCheckStepRunner    PolicyCheckStepRunner
+	PlanSummaryRunner        PlanSummaryRunner
 	PullApprovedChecker      runtime.PullApprovedChecker
+	OwnerApprovalChecker     OwnerApprovalChecker
 	WorkingDir               WorkingDir
 	Webhooks                 WebhooksSender
 	WorkingDirLocker         WorkingDirLocker
 	RequireApprovalOverride  bool
 	RequireMergeableOverride bool
+	// Jobs stores the output of each plan and apply so it can be streamed to
+	// the job page. If nil, output isn't streamed.
+	Jobs                *jobs.Store
+	JobURLGenerator     JobURLGenerator
+	CommitStatusUpdater CommitStatusUpdater
+	// ResultUpdater, if set, reports the result of each project once its
+	// command has finished instead of only updating its commit status.
+	ResultUpdater ProjectResultUpdater
 }
 
 // Plan runs terraform plan for the project described by ctx.
 func (p *DefaultProjectCommandRunner) Plan(ctx models.ProjectCommandContext) models.ProjectResult {
+	start := time.Now()
+	ctx, completeJob := p.startJob(ctx, models.PlanCommand)
 	planSuccess, failure, err := p.doPlan(ctx)
-	return models.ProjectResult{
+	result := models.ProjectResult{
 		Command:     models.PlanCommand,
 		PlanSuccess: planSuccess,
 		Error:       err,
@@ -102,12 +152,18 @@ func (p *DefaultProjectCommandRunner) Plan(ctx models.ProjectCommandContext) mod
 		Workspace:   ctx.Workspace,
 		ProjectName: ctx.GetProjectName(),
 	}
+	completeJob(result)
+	metrics.ProjectCommandDuration.WithLabelValues(models.PlanCommand.String(), ctx.BaseRepo.FullName, metrics.Result(err, failure)).
+		Observe(time.Since(start).Seconds())
+	return result
 }
 
 // Apply runs terraform apply for the project described by ctx.
 func (p *DefaultProjectCommandRunner) Apply(ctx models.ProjectCommandContext) models.ProjectResult {
+	start := time.Now()
+	ctx, completeJob := p.startJob(ctx, models.ApplyCommand)
 	applyOut, failure, err := p.doApply(ctx)
-	return models.ProjectResult{
+	result := models.ProjectResult{
 		Command:      models.ApplyCommand,
 		Failure:      failure,
 		Error:        err,
@@ -116,11 +172,61 @@ func (p *DefaultProjectCommandRunner) Apply(ctx models.ProjectCommandContext) mo
 		Workspace:    ctx.Workspace,
 		ProjectName:  ctx.GetProjectName(),
 	}
+	completeJob(result)
+	metrics.ProjectCommandDuration.WithLabelValues(models.ApplyCommand.String(), ctx.BaseRepo.FullName, metrics.Result(err, failure)).
+		Observe(time.Since(start).Seconds())
+	return result
+}
+
+// ApprovePolicies approves the policy check failures of the plan for the
+// project described by ctx so it can be applied.
+func (p *DefaultProjectCommandRunner) ApprovePolicies(ctx models.ProjectCommandContext) models.ProjectResult {
+	out, failure, err := p.doApprovePolicies(ctx)
+	return models.ProjectResult{
+		Command:                models.ApprovePoliciesCommand,
+		Failure:                failure,
+		Error:                  err,
+		ApprovePoliciesSuccess: out,
+		RepoRelDir:             ctx.RepoRelDir,
+		Workspace:              ctx.Workspace,
+		ProjectName:            ctx.GetProjectName(),
+	}
+}
+
+// startJob creates the job that the output of running cmdName for the project
+// is streamed to and points the project's commit status at the job's page.
+// It returns ctx with its JobID set and a function to call once the command
+// has finished with its result. If p.Jobs is nil, it returns ctx unchanged.
+func (p *DefaultProjectCommandRunner) startJob(ctx models.ProjectCommandContext, cmdName models.CommandName) (models.ProjectCommandContext, func(result models.ProjectResult)) {
+	if p.Jobs == nil {
+		return ctx, func(models.ProjectResult) {}
+	}
+	ctx.JobID = p.Jobs.NewJob(ctx, cmdName)
+	jobURL := p.JobURLGenerator.GenerateJobURL(ctx.JobID)
+	if err := p.CommitStatusUpdater.UpdateProject(ctx, cmdName, models.PendingCommitStatus, jobURL); err != nil {
+		ctx.Log.Warn("unable to update project status: %s", err)
+	}
+	return ctx, func(result models.ProjectResult) {
+		p.Jobs.Complete(ctx.JobID)
+		var err error
+		if p.ResultUpdater != nil {
+			err = p.ResultUpdater.UpdateProjectResult(ctx, result, jobURL)
+		} else {
+			err = p.CommitStatusUpdater.UpdateProject(ctx, cmdName, result.CommitStatus(), jobURL)
+		}
+		if err != nil {
+			ctx.Log.Warn("unable to update project status: %s", err)
+		}
+	}
 }
 
 func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (*models.PlanSuccess, string, error) {
 	// Acquire Atlantis lock for this repo/dir/workspace.
-	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, models.NewProject(ctx.BaseRepo.FullName, ctx.RepoRelDir))
+	var lockTTL time.Duration
+	if ctx.ProjectConfig != nil && ctx.ProjectConfig.LockTTL != nil {
+		lockTTL = *ctx.ProjectConfig.LockTTL
+	}
+	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, models.NewProject(ctx.BaseRepo.FullName, ctx.RepoRelDir), lockTTL)
 	if err != nil {
 		return nil, "", errors.Wrap(err, "acquiring lock")
 	}
@@ -129,15 +235,26 @@ func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (
 	}
 	ctx.Log.Debug("acquired lock for project")
 
-	// Acquire internal lock for the directory we're going to operate in.
-	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
-	if err != nil {
-		return nil, "", err
-	}
-	defer unlockFn()
+	var repoDir string
+	var cloneErr error
+	if ctx.Cloned {
+		// The command runner has cloned the workspace and locked our
+		// directory in it. Cloning again could delete the files other
+		// projects in the workspace are running in.
+		repoDir, cloneErr = p.WorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)
+	} else {
+		// Acquire internal lock for the workspace we're going to clone to.
+		// Cloning can delete the whole workspace so we can't just lock our
+		// directory.
+		unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+		if err != nil {
+			return nil, "", err
+		}
+		defer unlockFn()
 
-	// Clone is idempotent so okay to run even if the repo was already cloned.
-	repoDir, cloneErr := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.Workspace)
+		// Clone is idempotent so okay to run even if the repo was already cloned.
+		repoDir, cloneErr = p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.Workspace)
+	}
 	if cloneErr != nil {
 		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
@@ -149,17 +266,25 @@ func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (
 		return nil, "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
 	}
 
-	// Use default stage unless another workflow is defined in config
-	stage := p.defaultPlanStage()
-	if ctx.ProjectConfig != nil && ctx.ProjectConfig.Workflow != nil {
-		ctx.Log.Debug("project configured to use workflow %q", *ctx.ProjectConfig.Workflow)
-		configuredStage := ctx.GlobalConfig.GetPlanStage(*ctx.ProjectConfig.Workflow)
-		if configuredStage != nil {
-			ctx.Log.Debug("project will use the configured stage for that workflow")
-			stage = *configuredStage
+	stage := p.planStage(ctx)
+
+	// The policy check failures of the previous plan don't apply to the new
+	// one.
+	policyCheckFile := filepath.Join(projAbsPath, runtime.GetPolicyCheckFilename(ctx.Workspace, ctx.ProjectConfig))
+	if err = os.Remove(policyCheckFile); err != nil && !os.IsNotExist(err) {
+		return nil, "", errors.Wrap(err, "removing policy check failures of previous plan")
+	}
+	outputs, policyFailures, err := p.runSteps(stage.Steps, ctx, projAbsPath)
+	// If a policy check failed, or errored so the plan wasn't checked, we
+	// block applying the plan until the failures are approved.
+	if len(policyFailures) > 0 || (err != nil && p.hasPolicyCheck(stage)) {
+		if writeErr := ioutil.WriteFile(policyCheckFile, []byte(strings.Join(policyFailures, "\n")), 0600); writeErr != nil {
+			ctx.Log.Err("unable to record policy check failures: %s", writeErr)
+			if err == nil {
+				err = errors.Wrap(writeErr, "recording policy check failures")
+			}
 		}
 	}
-	outputs, err := p.runSteps(stage.Steps, ctx, projAbsPath)
 	if err != nil {
 		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
@@ -167,16 +292,105 @@ func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (
 		return nil, "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
 	}
 
+	summary := p.planSummary(ctx, projAbsPath)
+	var destroys []string
+	if summary != nil && p.hasRequirement(p.applyRequirements(ctx), raw.NoDestroyApplyRequirement) {
+		destroys = protectedDestroys(ctx.ProjectConfig, summary)
+	}
 	return &models.PlanSuccess{
-		LockURL:         p.LockURLGenerator.GenerateLockURL(lockAttempt.LockKey),
-		TerraformOutput: strings.Join(outputs, "\n"),
-		RePlanCmd:       ctx.RePlanCmd,
-		ApplyCmd:        ctx.ApplyCmd,
+		LockURL:             p.LockURLGenerator.GenerateLockURL(lockAttempt.LockKey),
+		TerraformOutput:     strings.Join(outputs, "\n"),
+		RePlanCmd:           ctx.RePlanCmd,
+		ApplyCmd:            ctx.ApplyCmd,
+		PlanSummary:         summary,
+		ProtectedDestroys:   destroys,
+		PolicyCheckFailures: policyFailures,
 	}, "", nil
 }
 
-func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx models.ProjectCommandContext, absPath string) ([]string, error) {
+// PlanDrift runs terraform plan for the project described by ctx on its
+// repo's default branch to check it for drift. Nothing can be applied from
+// the default branch so unlike Plan, it doesn't lock the project or create a
+// job.
+func (p *DefaultProjectCommandRunner) PlanDrift(ctx models.ProjectCommandContext) models.ProjectResult {
+	planSuccess, err := p.doPlanDrift(ctx)
+	return models.ProjectResult{
+		Command:     models.PlanCommand,
+		PlanSuccess: planSuccess,
+		Error:       err,
+		RepoRelDir:  ctx.RepoRelDir,
+		Workspace:   ctx.Workspace,
+		ProjectName: ctx.GetProjectName(),
+	}
+}
+
+func (p *DefaultProjectCommandRunner) doPlanDrift(ctx models.ProjectCommandContext) (*models.PlanSuccess, error) {
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, DriftPullNum, ctx.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockFn()
+
+	repoDir, err := p.WorkingDir.CloneDefaultBranch(ctx.Log, ctx.BaseRepo, ctx.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
+	if _, err = os.Stat(projAbsPath); os.IsNotExist(err) {
+		return nil, DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+	}
+
+	// Policy checks only matter for plans that will be applied.
+	var steps []valid.Step
+	for _, step := range p.planStage(ctx).Steps {
+		if step.StepName != raw.PolicyCheckStepName {
+			steps = append(steps, step)
+		}
+	}
+	outputs, _, err := p.runSteps(steps, ctx, projAbsPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	}
+	return &models.PlanSuccess{
+		TerraformOutput: strings.Join(outputs, "\n"),
+		PlanSummary:     p.planSummary(ctx, projAbsPath),
+	}, nil
+}
+
+// planStage returns the plan stage of the project's workflow. It's the
+// default stage unless another workflow is defined in config.
+func (p *DefaultProjectCommandRunner) planStage(ctx models.ProjectCommandContext) valid.Stage {
+	stage := p.defaultPlanStage()
+	if ctx.ProjectConfig != nil && ctx.ProjectConfig.Workflow != nil {
+		ctx.Log.Debug("project configured to use workflow %q", *ctx.ProjectConfig.Workflow)
+		configuredStage := ctx.GlobalConfig.GetPlanStage(*ctx.ProjectConfig.Workflow)
+		if configuredStage != nil {
+			ctx.Log.Debug("project will use the configured stage for that workflow")
+			stage = *configuredStage
+		}
+	}
+	return stage
+}
+
+// planSummary returns the summary of the plan at projAbsPath or nil if it
+// can't be summarized. The summary is optional so errors are only logged.
+func (p *DefaultProjectCommandRunner) planSummary(ctx models.ProjectCommandContext, projAbsPath string) *models.PlanSummary {
+	if p.PlanSummaryRunner == nil {
+		return nil
+	}
+	summary, err := p.PlanSummaryRunner.Run(ctx, projAbsPath)
+	if err != nil {
+		ctx.Log.Warn("unable to summarize plan: %s", err)
+		return nil
+	}
+	return summary
+}
+
+// runSteps runs steps and returns their output and the policy violations
+// found by any policy_check steps.
+func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx models.ProjectCommandContext, absPath string) ([]string, []string, error) {
 	var outputs []string
+	var policyFailures []string
 	for _, step := range steps {
 		var out string
 		var err error
@@ -189,16 +403,30 @@ func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx models.Pr
 			out, err = p.ApplyStepRunner.Run(ctx, step.ExtraArgs, absPath)
 		case "run":
 			out, err = p.RunStepRunner.Run(ctx, step.RunCommand, absPath)
+		case "policy_check":
+			var failures []string
+			failures, err = p.PolicyCheckStepRunner.Run(ctx, absPath)
+			policyFailures = append(policyFailures, failures...)
 		}
 
 		if out != "" {
 			outputs = append(outputs, out)
 		}
 		if err != nil {
-			return outputs, err
+			return outputs, policyFailures, err
 		}
 	}
-	return outputs, nil
+	return outputs, policyFailures, nil
+}
+
+// hasPolicyCheck returns true if stage has a policy_check step.
+func (p *DefaultProjectCommandRunner) hasPolicyCheck(stage valid.Stage) bool {
+	for _, step := range stage.Steps {
+		if step.StepName == raw.PolicyCheckStepName {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext) (applyOut string, failure string, err error) {
@@ -214,21 +442,8 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 		return "", "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
 	}
 
-	// Figure out what our apply requirements are.
-	var applyRequirements []string
-	if p.RequireApprovalOverride || p.RequireMergeableOverride {
-		// If any server flags are set, they override project config.
-		if p.RequireMergeableOverride {
-			applyRequirements = append(applyRequirements, raw.MergeableApplyRequirement)
-		}
-		if p.RequireApprovalOverride {
-			applyRequirements = append(applyRequirements, raw.ApprovedApplyRequirement)
-		}
-	} else if ctx.ProjectConfig != nil {
-		// Else we use the project config if it's set.
-		applyRequirements = ctx.ProjectConfig.ApplyRequirements
-	}
-	for _, req := range applyRequirements {
+	var noDestroy bool
+	for _, req := range p.applyRequirements(ctx) {
 		switch req {
 		case raw.ApprovedApplyRequirement:
 			approved, err := p.PullApprovedChecker.PullIsApproved(ctx.BaseRepo, ctx.Pull) // nolint: vetshadow
@@ -238,19 +453,44 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 			if !approved {
 				return "", "Pull request must be approved before running apply.", nil
 			}
+		case raw.ApprovedByOwnersApplyRequirement:
+			unapproved, err := p.OwnerApprovalChecker.UnapprovedFiles(ctx) // nolint: vetshadow
+			if err != nil {
+				return "", "", errors.Wrap(err, "checking if owners approved pull request")
+			}
+			if len(unapproved) > 0 {
+				return "", fmt.Sprintf("Pull request must be approved by an owner of each modified file before running apply. Missing approval for: `%s`.", strings.Join(unapproved, "`, `")), nil
+			}
 		case raw.MergeableApplyRequirement:
 			if !ctx.PullMergeable {
 				return "", "Pull request must be mergeable before running apply.", nil
 			}
+		case raw.NoDestroyApplyRequirement:
+			noDestroy = true
 		}
 	}
 	// Acquire internal lock for the directory we're going to operate in.
-	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+	unlockFn, err := p.WorkingDirLocker.TryLockPath(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace, ctx.RepoRelDir)
 	if err != nil {
 		return "", "", err
 	}
 	defer unlockFn()
 
+	// We check the plan's policy check failures and destroys once we hold
+	// the lock so it can't be replaced by a new plan in the meantime.
+	policyCheckFile := filepath.Join(absPath, runtime.GetPolicyCheckFilename(ctx.Workspace, ctx.ProjectConfig))
+	if _, err = os.Stat(policyCheckFile); err == nil {
+		return "", fmt.Sprintf("Plan failed its policy checks. A policy approver must comment `%s %s` before it can be applied.", atlantisExecutable, models.ApprovePoliciesCommand.String()), nil
+	} else if !os.IsNotExist(err) {
+		return "", "", errors.Wrap(err, "checking for policy check failures")
+	}
+	if noDestroy && !ctx.AllowDestroy {
+		failure, err := p.destroyFailure(ctx, absPath) // nolint: vetshadow
+		if err != nil || failure != "" {
+			return "", failure, err
+		}
+	}
+
 	// Use default stage unless another workflow is defined in config
 	stage := p.defaultApplyStage()
 	if ctx.ProjectConfig != nil && ctx.ProjectConfig.Workflow != nil {
@@ -259,7 +499,7 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 			stage = *configuredStage
 		}
 	}
-	outputs, err := p.runSteps(stage.Steps, ctx, absPath)
+	outputs, _, err := p.runSteps(stage.Steps, ctx, absPath)
 	p.Webhooks.Send(ctx.Log, webhooks.ApplyResult{ // nolint: errcheck
 		Workspace: ctx.Workspace,
 		User:      ctx.User,
@@ -273,6 +513,113 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 	return strings.Join(outputs, "\n"), "", nil
 }
 
+func (p *DefaultProjectCommandRunner) doApprovePolicies(ctx models.ProjectCommandContext) (out string, failure string, err error) {
+	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", errors.New("project has not been cloned–did you run plan?")
+		}
+		return "", "", err
+	}
+	absPath := filepath.Join(repoDir, ctx.RepoRelDir)
+
+	// Acquire internal lock so we don't approve the failures of a plan
+	// that's being replaced.
+	unlockFn, err := p.WorkingDirLocker.TryLockPath(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace, ctx.RepoRelDir)
+	if err != nil {
+		return "", "", err
+	}
+	defer unlockFn()
+
+	err = os.Remove(filepath.Join(absPath, runtime.GetPolicyCheckFilename(ctx.Workspace, ctx.ProjectConfig)))
+	if os.IsNotExist(err) {
+		return "Plan has no policy check failures to approve.", "", nil
+	}
+	if err != nil {
+		return "", "", errors.Wrap(err, "removing policy check failures")
+	}
+	ctx.Log.Info("policy check failures approved by %s", ctx.User.Username)
+	return fmt.Sprintf("Policy check failures approved by @%s. The plan can now be applied.", ctx.User.Username), "", nil
+}
+
+// applyRequirements returns the requirements that must be satisfied before
+// the project in ctx can be applied.
+func (p *DefaultProjectCommandRunner) applyRequirements(ctx models.ProjectCommandContext) []string {
+	var applyRequirements []string
+	if p.RequireApprovalOverride || p.RequireMergeableOverride {
+		// If any server flags are set, they override project config.
+		if p.RequireMergeableOverride {
+			applyRequirements = append(applyRequirements, raw.MergeableApplyRequirement)
+		}
+		if p.RequireApprovalOverride {
+			applyRequirements = append(applyRequirements, raw.ApprovedApplyRequirement)
+		}
+		// There are no server flags for no_destroy or approved_by_owners so
+		// we don't let the flags turn them off.
+		for _, req := range []string{raw.ApprovedByOwnersApplyRequirement, raw.NoDestroyApplyRequirement} {
+			if ctx.ProjectConfig != nil && p.hasRequirement(ctx.ProjectConfig.ApplyRequirements, req) {
+				applyRequirements = append(applyRequirements, req)
+			}
+		}
+	} else if ctx.ProjectConfig != nil {
+		// Else we use the project config if it's set. It already has the
+		// server-side repo config's defaults merged in.
+		applyRequirements = ctx.ProjectConfig.ApplyRequirements
+	}
+	return applyRequirements
+}
+
+func (p *DefaultProjectCommandRunner) hasRequirement(reqs []string, req string) bool {
+	for _, r := range reqs {
+		if r == req {
+			return true
+		}
+	}
+	return false
+}
+
+// destroyFailure returns the failure message if the plan at absPath destroys
+// or replaces resources protected by the no_destroy apply requirement. Plans
+// that can't be summarized are refused since we can't tell what they destroy.
+func (p *DefaultProjectCommandRunner) destroyFailure(ctx models.ProjectCommandContext, absPath string) (string, error) {
+	var summary *models.PlanSummary
+	if p.PlanSummaryRunner != nil {
+		var err error
+		summary, err = p.PlanSummaryRunner.Run(ctx, absPath)
+		if err != nil {
+			return "", errors.Wrap(err, "checking plan for destroyed resources")
+		}
+	}
+	applyCmd := ctx.ApplyCmd
+	if applyCmd == "" {
+		applyCmd = fmt.Sprintf("%s %s", atlantisExecutable, models.ApplyCommand.String())
+	}
+	if summary == nil {
+		return fmt.Sprintf("Unable to check if the plan destroys resources protected by the %s apply requirement. This requires Terraform >= 0.12. To apply it anyway, comment `%s --%s`.", raw.NoDestroyApplyRequirement, applyCmd, allowDestroyFlag), nil
+	}
+	if destroys := protectedDestroys(ctx.ProjectConfig, summary); len(destroys) > 0 {
+		return fmt.Sprintf("Plan destroys resources protected by the %s apply requirement: `%s`. To apply it anyway, comment `%s --%s`.", raw.NoDestroyApplyRequirement, strings.Join(destroys, "`, `"), applyCmd, allowDestroyFlag), nil
+	}
+	return "", nil
+}
+
+// resourceAddressPrefix matches the module path and data source prefix of a
+// resource address, ex. module.db[0]. in module.db[0].aws_db_instance.main.
+var resourceAddressPrefix = regexp.MustCompile(`^(module\.[^.\[]+(\[[^\]]*\])?\.)*(data\.)?`)
+
+// protectedDestroys returns the addresses of the resources that summary
+// destroys or replaces and that the no_destroy apply requirement protects.
+func protectedDestroys(projCfg *valid.Project, summary *models.PlanSummary) []string {
+	var protected []string
+	for _, address := range summary.Destroys() {
+		resourceType := strings.SplitN(resourceAddressPrefix.ReplaceAllString(address, ""), ".", 2)[0]
+		if projCfg == nil || projCfg.IsDestroyProtected(resourceType) {
+			protected = append(protected, address)
+		}
+	}
+	return protected
+}
+
 func (p DefaultProjectCommandRunner) defaultPlanStage() valid.Stage {
 	return valid.Stage{
 		Steps: []valid.Step{
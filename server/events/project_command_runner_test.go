@@ -14,15 +14,18 @@
 package events_test
 
 import (
+	"errors"
 	"os"
 	"strings"
 	"testing"
 
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/jobs"
 	"github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
 	mocks2 "github.com/runatlantis/atlantis/server/events/runtime/mocks"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
@@ -161,6 +164,7 @@ func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 				matchers.AnyModelsUser(),
 				AnyString(),
 				matchers.AnyModelsProject(),
+				matchers.AnyTimeDuration(),
 			)).ThenReturn(&events.TryLockResponse{
 				LockAcquired: true,
 				LockKey:      "lock-key",
@@ -200,6 +204,129 @@ func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 	}
 }
 
+// The plan's summary should be set if it can be summarized. Failing to
+// summarize the plan shouldn't fail the plan.
+func TestDefaultProjectCommandRunner_PlanSummary(t *testing.T) {
+	cases := []struct {
+		description string
+		summary     *models.PlanSummary
+		err         error
+	}{
+		{
+			description: "summarized",
+			summary:     &models.PlanSummary{Add: 1, Creates: []string{"null_resource.test"}},
+		},
+		{
+			description: "error",
+			err:         errors.New("error"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockInit := mocks.NewMockStepRunner()
+			mockPlan := mocks.NewMockStepRunner()
+			mockSummary := mocks.NewMockPlanSummaryRunner()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			mockLocker := mocks.NewMockProjectLocker()
+			runner := events.DefaultProjectCommandRunner{
+				Locker:            mockLocker,
+				LockURLGenerator:  mockURLGenerator{},
+				InitStepRunner:    mockInit,
+				PlanStepRunner:    mockPlan,
+				PlanSummaryRunner: mockSummary,
+				WorkingDir:        mockWorkingDir,
+				WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+			}
+			repoDir, cleanup := TempDir(t)
+			defer cleanup()
+			When(mockWorkingDir.Clone(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString(),
+			)).ThenReturn(repoDir, nil)
+			When(mockLocker.TryLock(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsPullRequest(),
+				matchers.AnyModelsUser(),
+				AnyString(),
+				matchers.AnyModelsProject(),
+				matchers.AnyTimeDuration(),
+			)).ThenReturn(&events.TryLockResponse{
+				LockAcquired: true,
+				LockKey:      "lock-key",
+			}, nil)
+			ctx := models.ProjectCommandContext{
+				Log:        logging.NewNoopLogger(),
+				Workspace:  "default",
+				RepoRelDir: ".",
+			}
+			When(mockPlan.Run(ctx, nil, repoDir)).ThenReturn("plan", nil)
+			When(mockSummary.Run(ctx, repoDir)).ThenReturn(c.summary, c.err)
+
+			res := runner.Plan(ctx)
+			Assert(t, res.PlanSuccess != nil, "exp plan success")
+			Equals(t, c.summary, res.PlanSuccess.PlanSummary)
+		})
+	}
+}
+
+// Projects planned in parallel have been cloned and had their directory
+// locked by the command runner so they shouldn't clone or lock again.
+func TestDefaultProjectCommandRunner_PlanCloned(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockPlan := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	workingDirLocker := events.NewDefaultWorkingDirLocker()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:            mockLocker,
+		LockURLGenerator:  mockURLGenerator{},
+		InitStepRunner:    mockInit,
+		PlanStepRunner:    mockPlan,
+		PlanSummaryRunner: mocks.NewMockPlanSummaryRunner(),
+		WorkingDir:        mockWorkingDir,
+		WorkingDirLocker:  workingDirLocker,
+	}
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		matchers.AnyTimeDuration(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+	}, nil)
+	ctx := models.ProjectCommandContext{
+		Cloned:     true,
+		Log:        logging.NewNoopLogger(),
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(repoDir, nil)
+	When(mockPlan.Run(ctx, nil, repoDir)).ThenReturn("plan", nil)
+	unlockFn, err := workingDirLocker.TryLockPath(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace, ctx.RepoRelDir)
+	Ok(t, err)
+	defer unlockFn()
+
+	res := runner.Plan(ctx)
+	Assert(t, res.PlanSuccess != nil, "exp plan success but got %q %s", res.Failure, res.Error)
+	mockWorkingDir.VerifyWasCalled(Never()).Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)
+}
+
 func TestDefaultProjectCommandRunner_ApplyNotCloned(t *testing.T) {
 	mockWorkingDir := mocks.NewMockWorkingDir()
 	runner := &events.DefaultProjectCommandRunner{
@@ -249,6 +376,362 @@ func TestDefaultProjectCommandRunner_ApplyNotMergeable(t *testing.T) {
 	Equals(t, "Pull request must be mergeable before running apply.", res.Failure)
 }
 
+// Test that approved_by_owners is still required when the server flags
+// override the project's apply requirements.
+func TestDefaultProjectCommandRunner_ApplyNotApprovedByOwners(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockOwners := mocks.NewMockOwnerApprovalChecker()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:               mockWorkingDir,
+		OwnerApprovalChecker:     mockOwners,
+		WorkingDirLocker:         events.NewDefaultWorkingDirLocker(),
+		RequireMergeableOverride: true,
+	}
+	ctx := models.ProjectCommandContext{
+		ProjectConfig: &valid.Project{
+			Dir:               ".",
+			ApplyRequirements: []string{"approved_by_owners"},
+		},
+		PullMergeable: true,
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+	When(mockOwners.UnapprovedFiles(ctx)).ThenReturn([]string{"main.tf", "modules/db/main.tf"}, nil)
+
+	res := runner.Apply(ctx)
+	Equals(t, "Pull request must be approved by an owner of each modified file before running apply. Missing approval for: `main.tf`, `modules/db/main.tf`.", res.Failure)
+}
+
+func TestDefaultProjectCommandRunner_ApplyNoDestroy(t *testing.T) {
+	dbDestroy := &models.PlanSummary{Destroy: 1, Deletes: []string{"module.db[0].aws_db_instance.main"}}
+	cases := []struct {
+		description  string
+		noDestroy    []string
+		allowDestroy bool
+		summary      *models.PlanSummary
+		expFailure   string
+	}{
+		{
+			description: "protected destroy",
+			summary:     dbDestroy,
+			expFailure:  "Plan destroys resources protected by the no_destroy apply requirement: `module.db[0].aws_db_instance.main`. To apply it anyway, comment `atlantis apply -d . --allow-destroy`.",
+		},
+		{
+			description: "protected replace matching pattern",
+			noDestroy:   []string{"aws_db_*"},
+			summary:     &models.PlanSummary{Add: 1, Destroy: 1, Replaces: []string{"aws_db_instance.main"}},
+			expFailure:  "Plan destroys resources protected by the no_destroy apply requirement: `aws_db_instance.main`. To apply it anyway, comment `atlantis apply -d . --allow-destroy`.",
+		},
+		{
+			description: "destroy not matching pattern",
+			noDestroy:   []string{"aws_rds_*"},
+			summary:     dbDestroy,
+		},
+		{
+			description:  "allow destroy",
+			allowDestroy: true,
+			summary:      dbDestroy,
+		},
+		{
+			description: "no destroys",
+			summary:     &models.PlanSummary{Add: 1, Creates: []string{"aws_db_instance.main"}},
+		},
+		{
+			description: "plan can't be summarized",
+			expFailure:  "Unable to check if the plan destroys resources protected by the no_destroy apply requirement. This requires Terraform >= 0.12. To apply it anyway, comment `atlantis apply -d . --allow-destroy`.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockApply := mocks.NewMockStepRunner()
+			mockSummary := mocks.NewMockPlanSummaryRunner()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			runner := events.DefaultProjectCommandRunner{
+				ApplyStepRunner:   mockApply,
+				PlanSummaryRunner: mockSummary,
+				WorkingDir:        mockWorkingDir,
+				Webhooks:          mocks.NewMockWebhooksSender(),
+				WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+			}
+			repoDir, cleanup := TempDir(t)
+			defer cleanup()
+			When(mockWorkingDir.GetWorkingDir(
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString(),
+			)).ThenReturn(repoDir, nil)
+
+			ctx := models.ProjectCommandContext{
+				Log: logging.NewNoopLogger(),
+				ProjectConfig: &valid.Project{
+					Dir:                ".",
+					Workspace:          "default",
+					ApplyRequirements:  []string{"no_destroy"},
+					NoDestroyResources: c.noDestroy,
+				},
+				Workspace:    "default",
+				RepoRelDir:   ".",
+				ApplyCmd:     "atlantis apply -d .",
+				AllowDestroy: c.allowDestroy,
+			}
+			When(mockSummary.Run(ctx, repoDir)).ThenReturn(c.summary, nil)
+			When(mockApply.Run(ctx, nil, repoDir)).ThenReturn("apply", nil)
+
+			res := runner.Apply(ctx)
+			Equals(t, c.expFailure, res.Failure)
+			if c.expFailure != "" {
+				mockApply.VerifyWasCalled(Never()).Run(ctx, nil, repoDir)
+				return
+			}
+			Equals(t, "apply", res.ApplySuccess)
+			if c.allowDestroy {
+				mockSummary.VerifyWasCalled(Never()).Run(ctx, repoDir)
+			}
+		})
+	}
+}
+
+// Test that plans call out the destroys that will need --allow-destroy.
+func TestDefaultProjectCommandRunner_PlanProtectedDestroys(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockPlan := mocks.NewMockStepRunner()
+	mockSummary := mocks.NewMockPlanSummaryRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:            mockLocker,
+		LockURLGenerator:  mockURLGenerator{},
+		InitStepRunner:    mockInit,
+		PlanStepRunner:    mockPlan,
+		PlanSummaryRunner: mockSummary,
+		WorkingDir:        mockWorkingDir,
+		WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+	}
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		matchers.AnyTimeDuration(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+	}, nil)
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(),
+		ProjectConfig: &valid.Project{
+			Dir:                ".",
+			Workspace:          "default",
+			ApplyRequirements:  []string{"no_destroy"},
+			NoDestroyResources: []string{"aws_db_instance"},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	When(mockPlan.Run(ctx, nil, repoDir)).ThenReturn("plan", nil)
+	When(mockSummary.Run(ctx, repoDir)).ThenReturn(&models.PlanSummary{
+		Destroy:  2,
+		Add:      1,
+		Deletes:  []string{"aws_db_instance.main"},
+		Replaces: []string{"aws_instance.web"},
+	}, nil)
+
+	res := runner.Plan(ctx)
+	Assert(t, res.PlanSuccess != nil, "exp plan success")
+	Equals(t, []string{"aws_db_instance.main"}, res.PlanSuccess.ProtectedDestroys)
+}
+
+// Test that plans that fail their policy checks can't be applied until the
+// failures are approved.
+func TestDefaultProjectCommandRunner_PolicyCheck(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockPlan := mocks.NewMockStepRunner()
+	mockPolicyCheck := mocks.NewMockPolicyCheckStepRunner()
+	mockApply := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:                mockLocker,
+		LockURLGenerator:      mockURLGenerator{},
+		PlanStepRunner:        mockPlan,
+		PolicyCheckStepRunner: mockPolicyCheck,
+		ApplyStepRunner:       mockApply,
+		WorkingDir:            mockWorkingDir,
+		Webhooks:              mocks.NewMockWebhooksSender(),
+		WorkingDirLocker:      events.NewDefaultWorkingDirLocker(),
+	}
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, nil)
+	When(mockWorkingDir.GetWorkingDir(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		matchers.AnyTimeDuration(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+		UnlockFn:     func() error { return nil },
+	}, nil)
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(),
+		ProjectConfig: &valid.Project{
+			Dir:       ".",
+			Workspace: "default",
+			Workflow:  String("checked"),
+		},
+		GlobalConfig: &valid.Config{
+			Version: 2,
+			Workflows: map[string]valid.Workflow{
+				"checked": {
+					Plan: &valid.Stage{
+						Steps: []valid.Step{{StepName: "plan"}, {StepName: "policy_check"}},
+					},
+				},
+			},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		User:       models.User{Username: "alice"},
+	}
+	When(mockPlan.Run(ctx, nil, repoDir)).ThenReturn("plan", nil)
+	When(mockPolicyCheck.Run(ctx, repoDir)).ThenReturn([]string{"security: denied"}, nil)
+	When(mockApply.Run(ctx, nil, repoDir)).ThenReturn("apply", nil)
+
+	res := runner.Plan(ctx)
+	Assert(t, res.PlanSuccess != nil, "exp plan success")
+	Equals(t, []string{"security: denied"}, res.PlanSuccess.PolicyCheckFailures)
+	Equals(t, models.FailedCommitStatus, res.CommitStatus())
+	Equals(t, models.PolicyCheckFailedPlanStatus, res.PlanStatus())
+
+	res = runner.Apply(ctx)
+	Equals(t, "Plan failed its policy checks. A policy approver must comment `atlantis approve_policies` before it can be applied.", res.Failure)
+	mockApply.VerifyWasCalled(Never()).Run(ctx, nil, repoDir)
+
+	res = runner.ApprovePolicies(ctx)
+	Equals(t, "Policy check failures approved by @alice. The plan can now be applied.", res.ApprovePoliciesSuccess)
+	Equals(t, models.PlannedPlanStatus, res.PlanStatus())
+	res = runner.ApprovePolicies(ctx)
+	Equals(t, "Plan has no policy check failures to approve.", res.ApprovePoliciesSuccess)
+
+	res = runner.Apply(ctx)
+	Equals(t, "", res.Failure)
+	Equals(t, "apply", res.ApplySuccess)
+
+	t.Log("failures should be cleared when the project is planned again")
+	res = runner.Plan(ctx)
+	Equals(t, 1, len(res.PlanSuccess.PolicyCheckFailures))
+	When(mockPolicyCheck.Run(ctx, repoDir)).ThenReturn(nil, nil)
+	res = runner.Plan(ctx)
+	Equals(t, 0, len(res.PlanSuccess.PolicyCheckFailures))
+	res = runner.Apply(ctx)
+	Equals(t, "apply", res.ApplySuccess)
+
+	t.Log("plans whose policy checks errored should be blocked too")
+	When(mockPolicyCheck.Run(ctx, repoDir)).ThenReturn(nil, errors.New("policy error"))
+	res = runner.Plan(ctx)
+	ErrContains(t, "policy error", res.Error)
+	res = runner.Apply(ctx)
+	Equals(t, "Plan failed its policy checks. A policy approver must comment `atlantis approve_policies` before it can be applied.", res.Failure)
+}
+
+func TestDefaultProjectCommandRunner_PlanDrift(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockPlan := mocks.NewMockStepRunner()
+	mockPolicyCheck := mocks.NewMockPolicyCheckStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	workingDirLocker := events.NewDefaultWorkingDirLocker()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:                mockLocker,
+		PlanStepRunner:        mockPlan,
+		PolicyCheckStepRunner: mockPolicyCheck,
+		WorkingDir:            mockWorkingDir,
+		WorkingDirLocker:      workingDirLocker,
+	}
+	t.Log("drift plans have their own clone so an API command running on pull 0 shouldn't block them")
+	unlockAPI, err := workingDirLocker.TryLock(fixtures.GithubRepo.FullName, 0, "default")
+	Ok(t, err)
+	defer unlockAPI()
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.CloneDefaultBranch(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		AnyString(),
+	)).ThenReturn(repoDir, nil)
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(),
+		ProjectConfig: &valid.Project{
+			Dir:       ".",
+			Workspace: "default",
+			Workflow:  String("checked"),
+		},
+		GlobalConfig: &valid.Config{
+			Version: 2,
+			Workflows: map[string]valid.Workflow{
+				"checked": {
+					Plan: &valid.Stage{
+						Steps: []valid.Step{{StepName: "plan"}, {StepName: "policy_check"}},
+					},
+				},
+			},
+		},
+		BaseRepo:   fixtures.GithubRepo,
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	When(mockPlan.Run(ctx, nil, repoDir)).ThenReturn("Plan: 1 to add, 0 to change, 0 to destroy.", nil)
+
+	res := runner.PlanDrift(ctx)
+	Assert(t, res.PlanSuccess != nil, "exp plan success")
+	Equals(t, "Plan: 1 to add, 0 to change, 0 to destroy.", res.PlanSuccess.Summary())
+	t.Log("drift plans should skip policy checks and not lock the project")
+	mockPolicyCheck.VerifyWasCalled(Never()).Run(ctx, repoDir)
+	mockLocker.VerifyWasCalled(Never()).TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		matchers.AnyTimeDuration(),
+	)
+	mockWorkingDir.VerifyWasCalled(Never()).Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)
+}
+
 func TestDefaultProjectCommandRunner_Apply(t *testing.T) {
 	cases := []struct {
 		description   string
@@ -494,6 +977,110 @@ func TestDefaultProjectCommandRunner_Apply(t *testing.T) {
 	}
 }
 
+// Test that the apply's output is streamed to a job and that the project's
+// commit status links to the job.
+func TestDefaultProjectCommandRunner_ApplyJob(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockApply := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockURLs := mocks.NewMockJobURLGenerator()
+	mockUpdater := mocks.NewMockCommitStatusUpdater()
+	store := jobs.NewStore(10, 10)
+	runner := events.DefaultProjectCommandRunner{
+		ApplyStepRunner:     mockApply,
+		WorkingDir:          mockWorkingDir,
+		Webhooks:            mocks.NewMockWebhooksSender(),
+		WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+		Jobs:                store,
+		JobURLGenerator:     mockURLs,
+		CommitStatusUpdater: mockUpdater,
+	}
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, nil)
+	When(mockURLs.GenerateJobURL(AnyString())).ThenReturn("https://job")
+	When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString())).ThenReturn("apply", nil)
+
+	res := runner.Apply(models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(),
+		Workspace:  "default",
+		RepoRelDir: ".",
+	})
+	Equals(t, "apply", res.ApplySuccess)
+
+	// The step should have been run with the job's ID.
+	ctx, _, _ := mockApply.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString()).GetCapturedArguments()
+	Assert(t, ctx.JobID != "", "exp job id to be set")
+	info, ok := store.Get(ctx.JobID)
+	Assert(t, ok, "exp job to exist")
+	Equals(t, models.ApplyCommand, info.Command)
+	Equals(t, true, info.Complete)
+	mockURLs.VerifyWasCalledOnce().GenerateJobURL(ctx.JobID)
+
+	_, cmds, statuses, urls := mockUpdater.VerifyWasCalled(Twice()).UpdateProject(
+		matchers.AnyModelsProjectCommandContext(),
+		matchers.AnyModelsCommandName(),
+		matchers.AnyModelsCommitStatus(),
+		AnyString()).GetAllCapturedArguments()
+	Equals(t, []models.CommandName{models.ApplyCommand, models.ApplyCommand}, cmds)
+	Equals(t, []models.CommitStatus{models.PendingCommitStatus, models.SuccessCommitStatus}, statuses)
+	Equals(t, []string{"https://job", "https://job"}, urls)
+}
+
+// Test that if ResultUpdater is set, it's given the project's result instead
+// of only updating the project's commit status.
+func TestDefaultProjectCommandRunner_ApplyJobResultUpdater(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockApply := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockURLs := mocks.NewMockJobURLGenerator()
+	mockUpdater := mocks.NewMockCommitStatusUpdater()
+	mockResultUpdater := mocks.NewMockProjectResultUpdater()
+	runner := events.DefaultProjectCommandRunner{
+		ApplyStepRunner:     mockApply,
+		WorkingDir:          mockWorkingDir,
+		Webhooks:            mocks.NewMockWebhooksSender(),
+		WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+		Jobs:                jobs.NewStore(10, 10),
+		JobURLGenerator:     mockURLs,
+		CommitStatusUpdater: mockUpdater,
+		ResultUpdater:       mockResultUpdater,
+	}
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, nil)
+	When(mockURLs.GenerateJobURL(AnyString())).ThenReturn("https://job")
+	When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString())).ThenReturn("apply", nil)
+
+	res := runner.Apply(models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(),
+		Workspace:  "default",
+		RepoRelDir: ".",
+	})
+	Equals(t, "apply", res.ApplySuccess)
+
+	// The pending status is still set with the commit status updater.
+	mockUpdater.VerifyWasCalledOnce().UpdateProject(
+		matchers.AnyModelsProjectCommandContext(),
+		matchers.EqModelsCommandName(models.ApplyCommand),
+		matchers.EqModelsCommitStatus(models.PendingCommitStatus),
+		EqString("https://job"))
+	_, result, url := mockResultUpdater.VerifyWasCalledOnce().UpdateProjectResult(
+		matchers.AnyModelsProjectCommandContext(),
+		matchers.AnyModelsProjectResult(),
+		AnyString()).GetCapturedArguments()
+	Equals(t, res, result)
+	Equals(t, "https://job", url)
+}
+
 type mockURLGenerator struct{}
 
 func (m mockURLGenerator) GenerateLockURL(lockID string) string {
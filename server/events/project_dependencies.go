@@ -0,0 +1,88 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// hasDependencies returns true if any of cmds' projects depend on another
+// project.
+func hasDependencies(cmds []models.ProjectCommandContext) bool {
+	for _, cmd := range cmds {
+		if cmd.ProjectConfig != nil && len(cmd.ProjectConfig.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyGroups splits cmds into groups that should be run one after
+// another so that each project runs after the projects it depends on. The
+// groups contain the indices of the commands in cmds. Projects in the same
+// group don't depend on each other so they can be run at the same time.
+// Dependencies on projects that aren't in cmds don't affect the order since
+// there's nothing to wait for. Within a group, the commands stay in the same order as
+// in cmds.
+func dependencyGroups(cmds []models.ProjectCommandContext) [][]int {
+	nameToIdx := make(map[string]int)
+	for i, cmd := range cmds {
+		if name := cmd.GetProjectName(); name != "" {
+			nameToIdx[name] = i
+		}
+	}
+
+	// depth[i] is the length of the longest chain of dependencies of cmds[i]
+	// in cmds, which is the group it goes in. Cycles are rejected when the
+	// config is parsed but we guard against them anyway so we can't loop
+	// forever.
+	depth := make([]int, len(cmds))
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(cmds))
+	var visit func(i int) int
+	visit = func(i int) int {
+		if state[i] != unvisited {
+			return depth[i]
+		}
+		state[i] = visiting
+		if cfg := cmds[i].ProjectConfig; cfg != nil {
+			for _, dep := range cfg.DependsOn {
+				j, ok := nameToIdx[dep]
+				if !ok || state[j] == visiting {
+					continue
+				}
+				if d := visit(j) + 1; d > depth[i] {
+					depth[i] = d
+				}
+			}
+		}
+		state[i] = visited
+		return depth[i]
+	}
+
+	var groups [][]int
+	for i := range cmds {
+		d := visit(i)
+		for len(groups) <= d {
+			groups = append(groups, nil)
+		}
+		groups[d] = append(groups[d], i)
+	}
+	return groups
+}
+
+// failedDependency returns the name of a project that cmd depends on and that
+// is in failed or an empty string if there is none.
+func failedDependency(cmd models.ProjectCommandContext, failed map[string]bool) string {
+	if cmd.ProjectConfig == nil {
+		return ""
+	}
+	for _, dep := range cmd.ProjectConfig.DependsOn {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
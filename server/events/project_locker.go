@@ -15,10 +15,12 @@ package events
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 )
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_project_lock.go ProjectLocker
@@ -31,13 +33,18 @@ type ProjectLocker interface {
 	// return value will be a string describing why the lock was not acquired.
 	// The third return value is a function that can be called to unlock the
 	// lock. It will only be set if the lock was acquired. Any errors will set
-	// error.
-	TryLock(log *logging.SimpleLogger, pull models.PullRequest, user models.User, workspace string, project models.Project) (*TryLockResponse, error)
+	// error. lockTTL is how long the lock can be held for before it's
+	// released automatically. If 0, the server's default is used.
+	TryLock(log *logging.SimpleLogger, pull models.PullRequest, user models.User, workspace string, project models.Project, lockTTL time.Duration) (*TryLockResponse, error)
 }
 
 // DefaultProjectLocker implements ProjectLocker.
 type DefaultProjectLocker struct {
 	Locker locking.Locker
+	// QueueEnabled is true if pull requests that fail to get the lock should
+	// wait in the lock's queue. Locker is then responsible for handing the
+	// lock over once it's released.
+	QueueEnabled bool
 }
 
 // TryLockResponse is the result of trying to lock a project.
@@ -56,12 +63,33 @@ type TryLockResponse struct {
 }
 
 // TryLock implements ProjectLocker.TryLock.
-func (p *DefaultProjectLocker) TryLock(log *logging.SimpleLogger, pull models.PullRequest, user models.User, workspace string, project models.Project) (*TryLockResponse, error) {
-	lockAttempt, err := p.Locker.TryLock(project, workspace, pull, user)
+func (p *DefaultProjectLocker) TryLock(log *logging.SimpleLogger, pull models.PullRequest, user models.User, workspace string, project models.Project, lockTTL time.Duration) (*TryLockResponse, error) {
+	lockAttempt, err := p.Locker.TryLock(project, workspace, pull, user, lockTTL)
 	if err != nil {
+		metrics.LockAttempts.WithLabelValues(metrics.ResultError).Inc()
 		return nil, err
 	}
 	if !lockAttempt.LockAcquired && lockAttempt.CurrLock.Pull.Num != pull.Num {
+		metrics.LockAttempts.WithLabelValues(metrics.LockContended).Inc()
+		if p.QueueEnabled {
+			acquired, position, err := p.Locker.Enqueue(project, workspace, pull, user, lockTTL)
+			if err != nil {
+				return nil, err
+			}
+			// The lock might have been released since we tried to get it.
+			if acquired {
+				return p.lockAcquired(log, lockAttempt.LockKey), nil
+			}
+			log.Info("lock %q is held by pull #%d, queued at position %d", lockAttempt.LockKey, lockAttempt.CurrLock.Pull.Num, position)
+			failureMsg := fmt.Sprintf(
+				"This project is currently locked by an unapplied plan from pull #%d. This pull request is at position **%d** in the queue for the lock.\n\nOnce the lock is released, this pull request will get the lock and `atlantis plan` will be run here automatically. To leave the queue, comment `atlantis unlock`.",
+				lockAttempt.CurrLock.Pull.Num,
+				position)
+			return &TryLockResponse{
+				LockAcquired:      false,
+				LockFailureReason: failureMsg,
+			}, nil
+		}
 		failureMsg := fmt.Sprintf(
 			"This project is currently locked by an unapplied plan from pull #%d. To continue, delete the lock from #%d or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.",
 			lockAttempt.CurrLock.Pull.Num,
@@ -71,13 +99,18 @@ func (p *DefaultProjectLocker) TryLock(log *logging.SimpleLogger, pull models.Pu
 			LockFailureReason: failureMsg,
 		}, nil
 	}
-	log.Info("acquired lock with id %q", lockAttempt.LockKey)
+	return p.lockAcquired(log, lockAttempt.LockKey), nil
+}
+
+func (p *DefaultProjectLocker) lockAcquired(log *logging.SimpleLogger, lockKey string) *TryLockResponse {
+	metrics.LockAttempts.WithLabelValues(metrics.LockAcquired).Inc()
+	log.Info("acquired lock with id %q", lockKey)
 	return &TryLockResponse{
 		LockAcquired: true,
 		UnlockFn: func() error {
-			_, err := p.Locker.Unlock(lockAttempt.LockKey)
+			_, err := p.Locker.Unlock(lockKey)
 			return err
 		},
-		LockKey: lockAttempt.LockKey,
-	}, nil
+		LockKey: lockKey,
+	}
 }
@@ -15,13 +15,16 @@ package events_test
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/locking/mocks"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -38,7 +41,7 @@ func TestDefaultProjectLocker_TryLockWhenLocked(t *testing.T) {
 	lockingPull := models.PullRequest{
 		Num: 2,
 	}
-	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser)).ThenReturn(
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser, time.Duration(0))).ThenReturn(
 		locking.TryLockResponse{
 			LockAcquired: false,
 			CurrLock: models.ProjectLock{
@@ -48,12 +51,85 @@ func TestDefaultProjectLocker_TryLockWhenLocked(t *testing.T) {
 		},
 		nil,
 	)
-	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject)
+	contended := testutil.ToFloat64(metrics.LockAttempts.WithLabelValues(metrics.LockContended))
+	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject, 0)
 	Ok(t, err)
 	Equals(t, &events.TryLockResponse{
 		LockAcquired:      false,
 		LockFailureReason: "This project is currently locked by an unapplied plan from pull #2. To continue, delete the lock from #2 or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.",
 	}, res)
+	Equals(t, contended+1, testutil.ToFloat64(metrics.LockAttempts.WithLabelValues(metrics.LockContended)))
+}
+
+func TestDefaultProjectLocker_TryLockWhenLockedQueueEnabled(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockLocker := mocks.NewMockLocker()
+	locker := events.DefaultProjectLocker{
+		Locker:       mockLocker,
+		QueueEnabled: true,
+	}
+	expProject := models.Project{}
+	expWorkspace := "default"
+	expPull := models.PullRequest{}
+	expUser := models.User{}
+
+	lockingPull := models.PullRequest{
+		Num: 2,
+	}
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser, time.Duration(0))).ThenReturn(
+		locking.TryLockResponse{
+			LockAcquired: false,
+			CurrLock: models.ProjectLock{
+				Pull: lockingPull,
+			},
+			LockKey: "",
+		},
+		nil,
+	)
+	When(mockLocker.Enqueue(expProject, expWorkspace, expPull, expUser, time.Duration(0))).ThenReturn(false, 3, nil)
+	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject, 0)
+	Ok(t, err)
+	Equals(t, &events.TryLockResponse{
+		LockAcquired:      false,
+		LockFailureReason: "This project is currently locked by an unapplied plan from pull #2. This pull request is at position **3** in the queue for the lock.\n\nOnce the lock is released, this pull request will get the lock and `atlantis plan` will be run here automatically. To leave the queue, comment `atlantis unlock`.",
+	}, res)
+	mockLocker.VerifyWasCalledOnce().Enqueue(expProject, expWorkspace, expPull, expUser, time.Duration(0))
+}
+
+// Test that if the lock is released between trying to get it and queueing for
+// it, the pull request gets the lock rather than waiting in the queue.
+func TestDefaultProjectLocker_TryLockWhenReleasedBeforeQueueing(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockLocker := mocks.NewMockLocker()
+	locker := events.DefaultProjectLocker{
+		Locker:       mockLocker,
+		QueueEnabled: true,
+	}
+	expProject := models.Project{}
+	expWorkspace := "default"
+	expPull := models.PullRequest{}
+	expUser := models.User{}
+	lockKey := "key"
+
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser, time.Duration(0))).ThenReturn(
+		locking.TryLockResponse{
+			LockAcquired: false,
+			CurrLock: models.ProjectLock{
+				Pull: models.PullRequest{Num: 2},
+			},
+			LockKey: lockKey,
+		},
+		nil,
+	)
+	When(mockLocker.Enqueue(expProject, expWorkspace, expPull, expUser, time.Duration(0))).ThenReturn(true, 0, nil)
+	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject, 0)
+	Ok(t, err)
+	Equals(t, true, res.LockAcquired)
+	Equals(t, lockKey, res.LockKey)
+
+	err = res.UnlockFn()
+	Ok(t, err)
+	mockLocker.VerifyWasCalledOnce().Unlock(lockKey)
 }
 
 func TestDefaultProjectLocker_TryLockWhenLockedSamePull(t *testing.T) {
@@ -71,7 +147,7 @@ func TestDefaultProjectLocker_TryLockWhenLockedSamePull(t *testing.T) {
 		Num: 2,
 	}
 	lockKey := "key"
-	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser)).ThenReturn(
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser, time.Duration(0))).ThenReturn(
 		locking.TryLockResponse{
 			LockAcquired: false,
 			CurrLock: models.ProjectLock{
@@ -81,7 +157,7 @@ func TestDefaultProjectLocker_TryLockWhenLockedSamePull(t *testing.T) {
 		},
 		nil,
 	)
-	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject)
+	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject, 0)
 	Ok(t, err)
 	Equals(t, true, res.LockAcquired)
 
@@ -107,7 +183,7 @@ func TestDefaultProjectLocker_TryLockUnlocked(t *testing.T) {
 		Num: 2,
 	}
 	lockKey := "key"
-	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser)).ThenReturn(
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser, time.Duration(0))).ThenReturn(
 		locking.TryLockResponse{
 			LockAcquired: true,
 			CurrLock: models.ProjectLock{
@@ -117,9 +193,11 @@ func TestDefaultProjectLocker_TryLockUnlocked(t *testing.T) {
 		},
 		nil,
 	)
-	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject)
+	acquired := testutil.ToFloat64(metrics.LockAttempts.WithLabelValues(metrics.LockAcquired))
+	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject, 0)
 	Ok(t, err)
 	Equals(t, true, res.LockAcquired)
+	Equals(t, acquired+1, testutil.ToFloat64(metrics.LockAttempts.WithLabelValues(metrics.LockAcquired)))
 
 	// UnlockFn should work.
 	mockLocker.VerifyWasCalled(Never()).Unlock(lockKey)
@@ -45,7 +45,7 @@ type PullClosedExecutor struct {
 	VCSClient  vcs.Client
 	WorkingDir WorkingDir
 	Logger     logging.SimpleLogging
-	DB         *db.BoltDB
+	DB         db.Database
 }
 
 type templatedProject struct {
@@ -64,6 +64,12 @@ func (p *PullClosedExecutor) CleanUpPull(repo models.Repo, pull models.PullReque
 		return errors.Wrap(err, "cleaning workspace")
 	}
 
+	// This pull request will never be planned again so it shouldn't be
+	// handed any locks it's waiting on.
+	if err := p.Locker.DequeueByPull(repo.FullName, pull.Num); err != nil {
+		return errors.Wrap(err, "leaving lock queues")
+	}
+
 	// Finally, delete locks. We do this last because when someone
 	// unlocks a project, right now we don't actually delete the plan
 	// so we might have plans laying around but no locks.
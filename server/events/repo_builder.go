@@ -0,0 +1,75 @@
+package events
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_repo_builder.go RepoBuilder
+
+// RepoBuilder builds repos from their IDs for features that aren't triggered
+// by webhooks, ex. drift detection.
+type RepoBuilder interface {
+	// BuildRepo returns the repo with ID id. IDs are in the same format as
+	// --repo-whitelist, {hostname}/{owner}/{repo}.
+	BuildRepo(id string) (models.Repo, error)
+}
+
+// DefaultRepoBuilder implements RepoBuilder. There's no webhook
+// to build the repo from so its clone URL is built from its ID and the
+// credentials of the VCS host with the ID's hostname.
+type DefaultRepoBuilder struct {
+	GithubHostname    string
+	GithubCredentials vcs.GithubCredentials
+	GitlabHostname    string
+	GitlabUser        string
+	GitlabToken       string
+	// BitbucketUser and BitbucketToken are only set for Bitbucket Cloud.
+	// Bitbucket Server clone URLs can't be built from the repo's ID.
+	BitbucketUser  string
+	BitbucketToken string
+	GiteaBaseURL   string
+	GiteaUser      string
+	GiteaToken     string
+}
+
+// BuildRepo returns the repo with ID id. It builds the clone URL each time
+// because GitHub App tokens expire.
+func (b *DefaultRepoBuilder) BuildRepo(id string) (models.Repo, error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 || !strings.Contains(parts[1], "/") {
+		return models.Repo{}, fmt.Errorf("repo ID %q must be in the format {hostname}/{owner}/{repo}", id)
+	}
+	hostname, fullName := parts[0], parts[1]
+
+	switch {
+	case b.GithubCredentials != nil && hostname == b.GithubHostname:
+		owner := fullName[:strings.Index(fullName, "/")]
+		name := fullName[strings.Index(fullName, "/")+1:]
+		token, err := b.GithubCredentials.GetToken(owner, name)
+		if err != nil {
+			return models.Repo{}, err
+		}
+		return models.NewRepo(models.Github, fullName, fmt.Sprintf("https://%s/%s", hostname, fullName), b.GithubCredentials.GetUser(), token)
+	case b.GitlabUser != "" && hostname == b.GitlabHostname:
+		return models.NewRepo(models.Gitlab, fullName, fmt.Sprintf("https://%s/%s", hostname, fullName), b.GitlabUser, b.GitlabToken)
+	case b.BitbucketUser != "" && hostname == "bitbucket.org":
+		return models.NewRepo(models.BitbucketCloud, fullName, fmt.Sprintf("https://%s/%s", hostname, fullName), b.BitbucketUser, b.BitbucketToken)
+	case b.GiteaUser != "" && hostname == b.giteaHostname():
+		return models.NewRepo(models.Gitea, fullName, fmt.Sprintf("%s/%s", strings.TrimSuffix(b.GiteaBaseURL, "/"), fullName), b.GiteaUser, b.GiteaToken)
+	}
+	return models.Repo{}, fmt.Errorf("repo %q isn't on a GitHub, GitLab, Bitbucket Cloud or Gitea host that Atlantis is configured for", id)
+}
+
+// giteaHostname returns the hostname of GiteaBaseURL or "" if it's invalid.
+func (b *DefaultRepoBuilder) giteaHostname() string {
+	parsed, err := url.Parse(b.GiteaBaseURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
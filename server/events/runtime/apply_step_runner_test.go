@@ -24,9 +24,7 @@ import (
 )
 
 func TestRun_NoDir(t *testing.T) {
-	o := runtime.ApplyStepRunner{
-		TerraformExecutor: nil,
-	}
+	o := runtime.ApplyStepRunner{}
 	_, err := o.Run(models.ProjectCommandContext{
 		RepoRelDir: ".",
 		Workspace:  "workspace",
@@ -37,9 +35,7 @@ func TestRun_NoDir(t *testing.T) {
 func TestRun_NoPlanFile(t *testing.T) {
 	tmpDir, cleanup := TempDir(t)
 	defer cleanup()
-	o := runtime.ApplyStepRunner{
-		TerraformExecutor: nil,
-	}
+	o := runtime.ApplyStepRunner{}
 	_, err := o.Run(models.ProjectCommandContext{
 		RepoRelDir: ".",
 		Workspace:  "workspace",
@@ -57,7 +53,7 @@ func TestRun_Success(t *testing.T) {
 	RegisterMockTestingT(t)
 	terraform := mocks.NewMockClient()
 	o := runtime.ApplyStepRunner{
-		TerraformExecutor: terraform,
+		AsyncTFExec: &asyncTFExec{terraform},
 	}
 
 	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
@@ -68,7 +64,7 @@ func TestRun_Success(t *testing.T) {
 		CommentArgs: []string{"comment", "args"},
 	}, []string{"extra", "args"}, tmpDir)
 	Ok(t, err)
-	Equals(t, "output", output)
+	Equals(t, "output\n", output)
 	terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, tmpDir, []string{"apply", "-input=false", "-no-color", "extra", "args", "comment", "args", fmt.Sprintf("%q", planPath)}, nil, "workspace")
 	_, err = os.Stat(planPath)
 	Assert(t, os.IsNotExist(err), "planfile should be deleted")
@@ -85,7 +81,7 @@ func TestRun_AppliesCorrectProjectPlan(t *testing.T) {
 	RegisterMockTestingT(t)
 	terraform := mocks.NewMockClient()
 	o := runtime.ApplyStepRunner{
-		TerraformExecutor: terraform,
+		AsyncTFExec: &asyncTFExec{terraform},
 	}
 
 	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
@@ -100,7 +96,7 @@ func TestRun_AppliesCorrectProjectPlan(t *testing.T) {
 		CommentArgs: []string{"comment", "args"},
 	}, []string{"extra", "args"}, tmpDir)
 	Ok(t, err)
-	Equals(t, "output", output)
+	Equals(t, "output\n", output)
 	terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, tmpDir, []string{"apply", "-input=false", "-no-color", "extra", "args", "comment", "args", fmt.Sprintf("%q", planPath)}, nil, "default")
 	_, err = os.Stat(planPath)
 	Assert(t, os.IsNotExist(err), "planfile should be deleted")
@@ -116,7 +112,7 @@ func TestRun_UsesConfiguredTFVersion(t *testing.T) {
 	RegisterMockTestingT(t)
 	terraform := mocks.NewMockClient()
 	o := runtime.ApplyStepRunner{
-		TerraformExecutor: terraform,
+		AsyncTFExec: &asyncTFExec{terraform},
 	}
 	tfVersion, _ := version.NewVersion("0.11.0")
 
@@ -131,7 +127,7 @@ func TestRun_UsesConfiguredTFVersion(t *testing.T) {
 		},
 	}, []string{"extra", "args"}, tmpDir)
 	Ok(t, err)
-	Equals(t, "output", output)
+	Equals(t, "output\n", output)
 	terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, tmpDir, []string{"apply", "-input=false", "-no-color", "extra", "args", "comment", "args", fmt.Sprintf("%q", planPath)}, tfVersion, "workspace")
 	_, err = os.Stat(planPath)
 	Assert(t, os.IsNotExist(err), "planfile should be deleted")
@@ -198,7 +194,7 @@ func TestRun_UsingTarget(t *testing.T) {
 			Ok(t, err)
 			terraform := mocks.NewMockClient()
 			step := runtime.ApplyStepRunner{
-				TerraformExecutor: terraform,
+				AsyncTFExec: &asyncTFExec{terraform},
 			}
 
 			output, err := step.Run(models.ProjectCommandContext{
@@ -30,6 +30,8 @@ type PlanStepRunner struct {
 	DefaultTFVersion    *version.Version
 	CommitStatusUpdater StatusUpdater
 	AsyncTFExec         AsyncTFExec
+	// JobOutput is where the plan's output is streamed to as it runs.
+	JobOutput JobOutputWriter
 }
 
 func (p *PlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string) (string, error) {
@@ -46,7 +48,7 @@ func (p *PlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []strin
 
 	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectConfig))
 	planCmd := p.buildPlanCmd(ctx, extraArgs, path, tfVersion, planFile)
-	output, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, filepath.Clean(path), planCmd, tfVersion, ctx.Workspace)
+	output, err := runAndStream(p.AsyncTFExec, p.JobOutput, ctx, filepath.Clean(path), planCmd, tfVersion)
 	if p.isRemoteOpsErr(output, err) {
 		ctx.Log.Debug("detected that this project is using TFE remote ops")
 		return p.remotePlan(ctx, extraArgs, path, tfVersion, planFile)
@@ -272,6 +274,7 @@ func (p *PlanStepRunner) runRemotePlan(
 			break
 		}
 		lines = append(lines, line.Line)
+		writeJobLine(p.JobOutput, ctx, line.Line)
 
 		// Here we're checking for the run url and updating the status
 		// if found.
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/jobs"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/runtime"
@@ -34,6 +35,7 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 	s := runtime.PlanStepRunner{
 		DefaultTFVersion:  tfVersion,
 		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
 	}
 
 	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
@@ -55,7 +57,7 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 	}, []string{"extra", "args"}, "/path")
 	Ok(t, err)
 
-	Equals(t, "output", output)
+	Equals(t, "output\n", output)
 	terraform.VerifyWasCalledOnce().RunCommandWithVersion(
 		logger,
 		"/path",
@@ -112,6 +114,7 @@ func TestRun_ErrWorkspaceIn08(t *testing.T) {
 	workspace := "notdefault"
 	s := runtime.PlanStepRunner{
 		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
 		DefaultTFVersion:  tfVersion,
 	}
 
@@ -160,6 +163,7 @@ func TestRun_SwitchesWorkspace(t *testing.T) {
 
 			s := runtime.PlanStepRunner{
 				TerraformExecutor: terraform,
+				AsyncTFExec:       &asyncTFExec{terraform},
 				DefaultTFVersion:  tfVersion,
 			}
 
@@ -182,7 +186,7 @@ func TestRun_SwitchesWorkspace(t *testing.T) {
 			}, []string{"extra", "args"}, "/path")
 			Ok(t, err)
 
-			Equals(t, "output", output)
+			Equals(t, "output\n", output)
 			// Verify that env select was called as well as plan.
 			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger,
 				"/path",
@@ -253,6 +257,7 @@ func TestRun_CreatesWorkspace(t *testing.T) {
 			logger := logging.NewNoopLogger()
 			s := runtime.PlanStepRunner{
 				TerraformExecutor: terraform,
+				AsyncTFExec:       &asyncTFExec{terraform},
 				DefaultTFVersion:  tfVersion,
 			}
 
@@ -302,7 +307,7 @@ func TestRun_CreatesWorkspace(t *testing.T) {
 			}, []string{"extra", "args"}, "/path")
 			Ok(t, err)
 
-			Equals(t, "output", output)
+			Equals(t, "output\n", output)
 			// Verify that env select was called as well as plan.
 			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expWorkspaceArgs, tfVersion, "workspace")
 			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expPlanArgs, tfVersion, "workspace")
@@ -319,6 +324,7 @@ func TestRun_NoWorkspaceSwitchIfNotNecessary(t *testing.T) {
 	logger := logging.NewNoopLogger()
 	s := runtime.PlanStepRunner{
 		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
 		DefaultTFVersion:  tfVersion,
 	}
 	When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "show"}, tfVersion, "workspace")).ThenReturn("workspace\n", nil)
@@ -362,7 +368,7 @@ func TestRun_NoWorkspaceSwitchIfNotNecessary(t *testing.T) {
 	}, []string{"extra", "args"}, "/path")
 	Ok(t, err)
 
-	Equals(t, "output", output)
+	Equals(t, "output\n", output)
 	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expPlanArgs, tfVersion, "workspace")
 
 	// Verify that workspace select was never called.
@@ -388,6 +394,7 @@ func TestRun_AddsEnvVarFile(t *testing.T) {
 	logger := logging.NewNoopLogger()
 	s := runtime.PlanStepRunner{
 		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
 		DefaultTFVersion:  tfVersion,
 	}
 
@@ -436,7 +443,7 @@ func TestRun_AddsEnvVarFile(t *testing.T) {
 	// Verify that env select was never called since we're in version >= 0.10
 	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger, tmpDir, []string{"env", "select", "-no-color", "workspace"}, tfVersion, "workspace")
 	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, tmpDir, expPlanArgs, tfVersion, "workspace")
-	Equals(t, "output", output)
+	Equals(t, "output\n", output)
 }
 
 func TestRun_UsesDiffPathForProject(t *testing.T) {
@@ -448,6 +455,7 @@ func TestRun_UsesDiffPathForProject(t *testing.T) {
 	logger := logging.NewNoopLogger()
 	s := runtime.PlanStepRunner{
 		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
 		DefaultTFVersion:  tfVersion,
 	}
 	When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "show"}, tfVersion, "workspace")).ThenReturn("workspace\n", nil)
@@ -495,7 +503,7 @@ func TestRun_UsesDiffPathForProject(t *testing.T) {
 		},
 	}, []string{"extra", "args"}, "/path")
 	Ok(t, err)
-	Equals(t, "output", output)
+	Equals(t, "output\n", output)
 }
 
 // Test that we format the plan output for better rendering.
@@ -531,6 +539,7 @@ Terraform will perform the following actions:
 	tfVersion, _ := version.NewVersion("0.10.0")
 	s := runtime.PlanStepRunner{
 		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
 		DefaultTFVersion:  tfVersion,
 	}
 	When(terraform.RunCommandWithVersion(
@@ -582,9 +591,10 @@ func TestRun_OutputOnErr(t *testing.T) {
 	tfVersion, _ := version.NewVersion("0.10.0")
 	s := runtime.PlanStepRunner{
 		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
 		DefaultTFVersion:  tfVersion,
 	}
-	expOutput := "expected output"
+	expOutput := "expected output\n"
 	expErrMsg := "error!"
 	When(terraform.RunCommandWithVersion(
 		matchers.AnyPtrToLoggingSimpleLogger(),
@@ -612,6 +622,37 @@ func TestRun_OutputOnErr(t *testing.T) {
 // Test that if we're using 0.12, we don't set the optional -var atlantis_repo_name
 // flags because in >= 0.12 you can't set -var flags if those variables aren't
 // being used.
+// Test that the plan's output is streamed to the job if there is one.
+func TestRun_StreamsOutputToJob(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	store := jobs.NewStore(1, 10)
+	tfVersion, _ := version.NewVersion("0.12.0")
+	s := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
+		DefaultTFVersion:  tfVersion,
+		JobOutput:         store,
+	}
+	When(terraform.RunCommandWithVersion(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		AnyString(),
+		AnyStringSlice(),
+		matchers2.AnyPtrToGoVersionVersion(),
+		AnyString())).ThenReturn("line1\nline2\n", nil)
+
+	ctx := models.ProjectCommandContext{Workspace: "default"}
+	ctx.JobID = store.NewJob(ctx, models.PlanCommand)
+	output, err := s.Run(ctx, nil, "/path")
+	Ok(t, err)
+	Equals(t, "line1\nline2\n", output)
+
+	lines, _, unsubscribe, ok := store.Subscribe(ctx.JobID, 0)
+	defer unsubscribe()
+	Assert(t, ok, "exp job to exist")
+	Equals(t, []jobs.Line{{Num: 0, Text: "line1"}, {Num: 1, Text: "line2"}}, lines)
+}
+
 func TestRun_NoOptionalVarsIn012(t *testing.T) {
 	RegisterMockTestingT(t)
 	terraform := mocks.NewMockClient()
@@ -619,6 +660,7 @@ func TestRun_NoOptionalVarsIn012(t *testing.T) {
 	tfVersion, _ := version.NewVersion("0.12.0")
 	s := runtime.PlanStepRunner{
 		TerraformExecutor: terraform,
+		AsyncTFExec:       &asyncTFExec{terraform},
 		DefaultTFVersion:  tfVersion,
 	}
 
@@ -644,7 +686,7 @@ func TestRun_NoOptionalVarsIn012(t *testing.T) {
 		},
 	}, []string{"extra", "args"}, "/path")
 	Ok(t, err)
-	Equals(t, "output", output)
+	Equals(t, "output\n", output)
 
 	expPlanArgs := []string{"plan",
 		"-input=false",
@@ -664,14 +706,13 @@ func TestRun_NoOptionalVarsIn012(t *testing.T) {
 func TestRun_RemoteOps(t *testing.T) {
 	RegisterMockTestingT(t)
 	terraform := mocks.NewMockClient()
-	asyncTf := &remotePlanMock{}
 
 	tfVersion, _ := version.NewVersion("0.11.12")
 	updater := mocks2.NewMockCommitStatusUpdater()
 	s := runtime.PlanStepRunner{
 		TerraformExecutor:   terraform,
+		AsyncTFExec:         &asyncTFExec{terraform},
 		DefaultTFVersion:    tfVersion,
-		AsyncTFExec:         asyncTf,
 		CommitStatusUpdater: updater,
 	}
 	absProjectPath, cleanup := TempDir(t)
@@ -716,10 +757,14 @@ The "remote" backend does not support saving the generated execution
 plan locally at this time.
 
 `
-	asyncTf.LinesToSend = remotePlanOutput
 	When(terraform.RunCommandWithVersion(nil, absProjectPath, expPlanArgs, tfVersion, "default")).
 		ThenReturn(planOutput, planErr)
 
+	// Then the plan is run again without saving the plan file.
+	expRemotePlanArgs := []string{"plan", "-input=false", "-refresh", "-no-color", "extra", "args", "comment", "args"}
+	When(terraform.RunCommandWithVersion(nil, absProjectPath, expRemotePlanArgs, tfVersion, "default")).
+		ThenReturn(remotePlanOutput, nil)
+
 	// Now that mocking is set up, we're ready to run the plan.
 	ctx := models.ProjectCommandContext{
 		Workspace:   "default",
@@ -749,8 +794,7 @@ Terraform will perform the following actions:
 
 Plan: 0 to add, 0 to change, 1 to destroy.`, output)
 
-	expRemotePlanArgs := []string{"plan", "-input=false", "-refresh", "-no-color", "extra", "args", "comment", "args"}
-	Equals(t, expRemotePlanArgs, asyncTf.CalledArgs)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, absProjectPath, expRemotePlanArgs, tfVersion, "default")
 
 	// Verify that the fake plan file we write has the correct contents.
 	bytes, err := ioutil.ReadFile(filepath.Join(absProjectPath, "default.tfplan"))
@@ -774,20 +818,25 @@ Plan: 0 to add, 0 to change, 1 to destroy.`, string(bytes))
 	updater.VerifyWasCalledOnce().UpdateProject(ctx, models.PlanCommand, models.SuccessCommitStatus, runURL)
 }
 
-type remotePlanMock struct {
-	// LinesToSend will be sent on the channel.
-	LinesToSend string
-	// CalledArgs is what args we were called with.
-	CalledArgs []string
+// asyncTFExec implements AsyncTFExec by calling RunCommandWithVersion on a
+// TerraformExec mock and sending its output line by line. This lets tests
+// stub and verify async commands the same way as sync ones.
+type asyncTFExec struct {
+	tf runtime.TerraformExec
 }
 
-func (r *remotePlanMock) RunCommandAsync(log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (chan<- string, <-chan terraform.Line) {
-	r.CalledArgs = args
+func (a *asyncTFExec) RunCommandAsync(log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (chan<- string, <-chan terraform.Line) {
 	in := make(chan string)
 	out := make(chan terraform.Line)
+	output, err := a.tf.RunCommandWithVersion(log, path, args, v, workspace)
 	go func() {
-		for _, line := range strings.Split(r.LinesToSend, "\n") {
-			out <- terraform.Line{Line: line}
+		if output != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+				out <- terraform.Line{Line: line}
+			}
+		}
+		if err != nil {
+			out <- terraform.Line{Err: err}
 		}
 		close(out)
 		close(in)
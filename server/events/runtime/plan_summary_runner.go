@@ -0,0 +1,136 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// PlanSummaryRunner summarizes the plans created by PlanStepRunner from the
+// output of terraform show -json.
+type PlanSummaryRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// planJSON is the part of the output of terraform show -json we use.
+// See https://www.terraform.io/docs/internals/json-format.html.
+type planJSON struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// Run returns the summary of the plan for the project described by ctx at
+// path. It returns nil if the plan can't be summarized because the Terraform
+// version doesn't support showing plans as JSON, there is no plan file or the
+// plan was created by TFE remote operations.
+func (p *PlanSummaryRunner) Run(ctx models.ProjectCommandContext, path string) (*models.PlanSummary, error) {
+	output, ok, err := showPlanJSON(p.TerraformExecutor, p.DefaultTFVersion, ctx, path)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return ParsePlanJSON(output)
+}
+
+// showPlanJSON returns the output of terraform show -json for the plan of the
+// project described by ctx at path. It returns false if the plan can't be
+// shown because the Terraform version doesn't support it, there is no plan
+// file or the plan was created by TFE remote operations.
+func showPlanJSON(tf TerraformExec, defaultVersion *version.Version, ctx models.ProjectCommandContext, path string) (string, bool, error) {
+	tfVersion := defaultVersion
+	if ctx.ProjectConfig != nil && ctx.ProjectConfig.TerraformVersion != nil {
+		tfVersion = ctx.ProjectConfig.TerraformVersion
+	}
+	if !vTwelveAndUp.Check(tfVersion) {
+		return "", false, nil
+	}
+
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectConfig))
+	isRemote, err := isRemotePlan(planFile)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.Wrap(err, "reading plan file")
+	}
+	if isRemote {
+		return "", false, nil
+	}
+
+	// NOTE: we need to quote the plan filename because Bitbucket Server can
+	// have spaces in its repo owner names.
+	output, err := tf.RunCommandWithVersion(ctx.Log, filepath.Clean(path), []string{"show", "-json", fmt.Sprintf("%q", planFile)}, tfVersion, ctx.Workspace)
+	if err != nil {
+		return "", false, err
+	}
+	return output, true, nil
+}
+
+// isRemotePlan returns true if planFile is the fake plan file we create for
+// TFE remote operations. Those can't be shown as JSON.
+func isRemotePlan(planFile string) (bool, error) {
+	f, err := os.Open(planFile) // nolint: gosec
+	if err != nil {
+		return false, err
+	}
+	defer f.Close() // nolint: errcheck
+	header := make([]byte, len(remoteOpsHeader))
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	return bytes.Equal(header[:n], []byte(remoteOpsHeader)), nil
+}
+
+// ParsePlanJSON parses output, the output of terraform show -json, into a
+// summary of the plan's changes.
+func ParsePlanJSON(output string) (*models.PlanSummary, error) {
+	var plan planJSON
+	if err := json.Unmarshal([]byte(planJSONLine(output)), &plan); err != nil {
+		return nil, errors.Wrap(err, "parsing output of terraform show")
+	}
+
+	summary := &models.PlanSummary{}
+	for _, rc := range plan.ResourceChanges {
+		switch strings.Join(rc.Change.Actions, ",") {
+		case "create":
+			summary.Add++
+			summary.Creates = append(summary.Creates, rc.Address)
+		case "update":
+			summary.Change++
+			summary.Updates = append(summary.Updates, rc.Address)
+		case "delete":
+			summary.Destroy++
+			summary.Deletes = append(summary.Deletes, rc.Address)
+		case "delete,create", "create,delete":
+			summary.Add++
+			summary.Destroy++
+			summary.Replaces = append(summary.Replaces, rc.Address)
+		}
+	}
+	return summary, nil
+}
+
+// planJSONLine returns the line of output, the output of terraform show
+// -json, that holds the JSON. The output is combined with stderr so we look
+// for the line in case Terraform printed any warnings.
+func planJSONLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "{") {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package runtime_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const showJSON = `{"format_version":"0.1","resource_changes":[` +
+	`{"address":"null_resource.new","change":{"actions":["create"]}},` +
+	`{"address":"null_resource.same","change":{"actions":["no-op"]}},` +
+	`{"address":"null_resource.updated","change":{"actions":["update"]}},` +
+	`{"address":"null_resource.old","change":{"actions":["delete"]}},` +
+	`{"address":"null_resource.replaced","change":{"actions":["delete","create"]}},` +
+	`{"address":"data.null_data_source.read","change":{"actions":["read"]}}` +
+	`]}`
+
+func TestPlanSummaryRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "default.tfplan"), []byte("plan"), 0600))
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.0")
+	r := runtime.PlanSummaryRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	logger := logging.NewNoopLogger()
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn(showJSON+"\n", nil)
+
+	summary, err := r.Run(models.ProjectCommandContext{Log: logger, Workspace: "default"}, tmp)
+	Ok(t, err)
+	Equals(t, &models.PlanSummary{
+		Add:      2,
+		Change:   1,
+		Destroy:  2,
+		Creates:  []string{"null_resource.new"},
+		Updates:  []string{"null_resource.updated"},
+		Deletes:  []string{"null_resource.old"},
+		Replaces: []string{"null_resource.replaced"},
+	}, summary)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, tmp, []string{"show", "-json", `"` + filepath.Join(tmp, "default.tfplan") + `"`}, tfVersion, "default")
+}
+
+// Plans can't be summarized if terraform doesn't support show -json or if
+// there's no plan we can show.
+func TestPlanSummaryRunner_RunNoSummary(t *testing.T) {
+	cases := []struct {
+		description string
+		tfVersion   string
+		planFile    string
+	}{
+		{
+			description: "terraform < 0.12",
+			tfVersion:   "0.11.14",
+			planFile:    "plan",
+		},
+		{
+			description: "no plan file",
+			tfVersion:   "0.12.0",
+		},
+		{
+			description: "remote ops",
+			tfVersion:   "0.12.0",
+			planFile:    "Atlantis: this plan was created by remote ops\nplan output",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			if c.planFile != "" {
+				Ok(t, ioutil.WriteFile(filepath.Join(tmp, "default.tfplan"), []byte(c.planFile), 0600))
+			}
+			terraform := mocks.NewMockClient()
+			tfVersion, _ := version.NewVersion(c.tfVersion)
+			r := runtime.PlanSummaryRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+			}
+
+			summary, err := r.Run(models.ProjectCommandContext{Log: logging.NewNoopLogger(), Workspace: "default"}, tmp)
+			Ok(t, err)
+			Assert(t, summary == nil, "exp nil summary, got %v", summary)
+			terraform.VerifyWasCalled(Never()).RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())
+		})
+	}
+}
+
+func TestParsePlanJSON(t *testing.T) {
+	t.Log("warnings printed before the JSON should be ignored")
+	summary, err := runtime.ParsePlanJSON("Warning: something\n\n" + `{"resource_changes":[{"address":"null_resource.new","change":{"actions":["create"]}}]}` + "\n")
+	Ok(t, err)
+	Equals(t, &models.PlanSummary{Add: 1, Creates: []string{"null_resource.new"}}, summary)
+
+	summary, err = runtime.ParsePlanJSON(`{"format_version":"0.1"}`)
+	Ok(t, err)
+	Equals(t, "No changes.", summary.String())
+
+	_, err = runtime.ParsePlanJSON("Error: no plan")
+	ErrEquals(t, "parsing output of terraform show: unexpected end of JSON input", err)
+}
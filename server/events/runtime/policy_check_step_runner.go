@@ -0,0 +1,155 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// policyQuery is the query that policies are evaluated with. Policies
+// report violations by adding messages to the deny set of the atlantis
+// package with rules like deny[msg] { ... }.
+const policyQuery = "data.atlantis.deny"
+
+// PolicyCheckStepRunner runs policy_check steps. It evaluates the plan
+// created by the plan step, as output by terraform show -json, against the
+// Rego policies configured server-side. Policies are evaluated in-process.
+type PolicyCheckStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+	PolicySets        []valid.PolicySet
+}
+
+// Run returns the violations of the policies by the plan for the project
+// described by ctx at path, each prefixed with the name of its policy set. It
+// returns an error if the plan can't be checked.
+func (p *PolicyCheckStepRunner) Run(ctx models.ProjectCommandContext, path string) ([]string, error) {
+	output, ok, err := showPlanJSON(p.TerraformExecutor, p.DefaultTFVersion, ctx, path)
+	if err != nil {
+		return nil, errors.Wrap(err, "showing plan")
+	}
+	if !ok {
+		return nil, errors.New("unable to check policies: plans can only be checked if they were created by Terraform >= 0.12 without remote operations")
+	}
+
+	// We decode numbers as json.Number so large numbers in the plan aren't
+	// rounded.
+	var input interface{}
+	decoder := json.NewDecoder(strings.NewReader(planJSONLine(output)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&input); err != nil {
+		return nil, errors.Wrap(err, "parsing output of terraform show")
+	}
+
+	var failures []string
+	for _, ps := range p.PolicySets {
+		violations, err := p.evalPolicySet(ps, input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking policy set %q", ps.Name)
+		}
+		for _, v := range violations {
+			failures = append(failures, fmt.Sprintf("%s: %s", ps.Name, v))
+		}
+		ctx.Log.Debug("plan has %d violation(s) of policy set %q", len(violations), ps.Name)
+	}
+	return failures, nil
+}
+
+// evalPolicySet returns the violations of the policies in ps by input,
+// sorted so the output is deterministic.
+func (p *PolicyCheckStepRunner) evalPolicySet(ps valid.PolicySet, input interface{}) ([]string, error) {
+	files, err := regoFiles(ps.Path)
+	if err != nil {
+		return nil, err
+	}
+	opts := []func(*rego.Rego){
+		rego.Query(policyQuery),
+		rego.Input(input),
+	}
+	for _, f := range files {
+		src, err := ioutil.ReadFile(f) // nolint: gosec
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rego.Module(f, string(src)))
+	}
+
+	rs, err := rego.New(opts...).Eval(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	// If no policy defines deny, a typo would make every plan pass so we
+	// treat it as an error.
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("no policies define %s", policyQuery)
+	}
+	var violations []string
+	for _, r := range rs {
+		for _, expr := range r.Expressions {
+			msgs, ok := expr.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected %s to be a set, got %v", policyQuery, expr.Value)
+			}
+			for _, m := range msgs {
+				violations = append(violations, violationString(m))
+			}
+		}
+	}
+	sort.Strings(violations)
+	return violations, nil
+}
+
+// violationString returns m as a string. Violations are usually strings but
+// policies can use any value.
+func violationString(m interface{}) string {
+	if s, ok := m.(string); ok {
+		return s
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(m); err != nil {
+		return fmt.Sprintf("%v", m)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// regoFiles returns the .rego files at path, which is either a .rego file or
+// a directory. Directories are searched recursively and Rego test files are
+// skipped.
+func regoFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(p, ".rego") && !strings.HasSuffix(p, "_test.rego") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .rego files found in %s", path)
+	}
+	return files, nil
+}
@@ -0,0 +1,122 @@
+package runtime_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const noDeletesPolicy = `package atlantis
+
+deny[msg] {
+	rc := input.resource_changes[_]
+	rc.change.actions[_] == "delete"
+	msg := sprintf("%s must not be deleted", [rc.address])
+}
+`
+
+const noDataSourcesPolicy = `package atlantis
+
+deny[msg] {
+	startswith(input.resource_changes[_].address, "data.")
+	msg := "data sources are not allowed"
+}
+`
+
+func TestPolicyCheckStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "default.tfplan"), []byte("plan"), 0600))
+	policies := filepath.Join(tmp, "policies")
+	Ok(t, os.MkdirAll(filepath.Join(policies, "nested"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(policies, "deletes.rego"), []byte(noDeletesPolicy), 0600))
+	Ok(t, ioutil.WriteFile(filepath.Join(policies, "nested", "data.rego"), []byte(noDataSourcesPolicy), 0600))
+	// Rego test files should be skipped.
+	Ok(t, ioutil.WriteFile(filepath.Join(policies, "deletes_test.rego"), []byte("not rego"), 0600))
+	permissive := filepath.Join(tmp, "permissive.rego")
+	Ok(t, ioutil.WriteFile(permissive, []byte("package atlantis\n\ndeny[msg] {\n\tfalse\n\tmsg := \"never\"\n}\n"), 0600))
+
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.0")
+	r := runtime.PolicyCheckStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+		PolicySets: []valid.PolicySet{
+			{Name: "strict", Path: policies},
+			{Name: "permissive", Path: permissive},
+		},
+	}
+	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("Warning: something\n\n"+showJSON+"\n", nil)
+
+	failures, err := r.Run(models.ProjectCommandContext{Log: logging.NewNoopLogger(), Workspace: "default"}, tmp)
+	Ok(t, err)
+	Equals(t, []string{
+		"strict: data sources are not allowed",
+		"strict: null_resource.old must not be deleted",
+		"strict: null_resource.replaced must not be deleted",
+	}, failures)
+}
+
+func TestPolicyCheckStepRunner_RunErrors(t *testing.T) {
+	cases := []struct {
+		description string
+		tfVersion   string
+		policy      string
+		expErr      string
+	}{
+		{
+			description: "terraform < 0.12",
+			tfVersion:   "0.11.14",
+			policy:      noDeletesPolicy,
+			expErr:      "unable to check policies: plans can only be checked if they were created by Terraform >= 0.12 without remote operations",
+		},
+		{
+			description: "no deny rules",
+			tfVersion:   "0.12.0",
+			policy:      "package other\n\ndeny[msg] {\n\tmsg := \"denied\"\n}\n",
+			expErr:      `checking policy set "policies": no policies define data.atlantis.deny`,
+		},
+		{
+			description: "invalid policy",
+			tfVersion:   "0.12.0",
+			policy:      "package atlantis\n\ndeny[msg {\n",
+			expErr:      `checking policy set "policies": 1 error occurred: `,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			Ok(t, ioutil.WriteFile(filepath.Join(tmp, "default.tfplan"), []byte("plan"), 0600))
+			policy := filepath.Join(tmp, "policy.rego")
+			Ok(t, ioutil.WriteFile(policy, []byte(c.policy), 0600))
+			terraform := mocks.NewMockClient()
+			tfVersion, _ := version.NewVersion(c.tfVersion)
+			r := runtime.PolicyCheckStepRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+				PolicySets:        []valid.PolicySet{{Name: "policies", Path: policy}},
+			}
+			When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+				ThenReturn(showJSON+"\n", nil)
+
+			_, err := r.Run(models.ProjectCommandContext{Log: logging.NewNoopLogger(), Workspace: "default"}, tmp)
+			ErrContains(t, c.expErr, err)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	"regexp"
+	"strings"
 )
 
 // lineBeforeRunURL is the line output during a remote operation right before
@@ -42,6 +43,41 @@ type StatusUpdater interface {
 	UpdateProject(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus, url string) error
 }
 
+// JobOutputWriter brings the interface from jobs.Store into this package
+// without causing circular imports.
+type JobOutputWriter interface {
+	// WriteLine appends line to the output of the job with id jobID.
+	WriteLine(jobID string, line string)
+}
+
+// runAndStream runs terraform with args and writes each line of its output to
+// ctx's job as soon as it's output. It returns all the output once terraform
+// exits.
+func runAndStream(tf AsyncTFExec, jobOutput JobOutputWriter, ctx models.ProjectCommandContext, path string, args []string, v *version.Version) (string, error) {
+	_, outCh := tf.RunCommandAsync(ctx.Log, path, args, v, ctx.Workspace)
+	var output strings.Builder
+	var err error
+	for line := range outCh {
+		if line.Err != nil {
+			err = line.Err
+			break
+		}
+		// The lines don't include their newlines so we add them back to
+		// match the output of running the command synchronously.
+		output.WriteString(line.Line + "\n")
+		writeJobLine(jobOutput, ctx, line.Line)
+	}
+	return output.String(), err
+}
+
+// writeJobLine writes line to ctx's job. It's a no-op if there is no job.
+func writeJobLine(jobOutput JobOutputWriter, ctx models.ProjectCommandContext, line string) {
+	if jobOutput == nil || ctx.JobID == "" {
+		return
+	}
+	jobOutput.WriteLine(ctx.JobID, line)
+}
+
 // MustConstraint returns a constraint. It panics on error.
 func MustConstraint(constraint string) version.Constraints {
 	c, err := version.NewConstraint(constraint)
@@ -67,3 +103,10 @@ func GetPlanFilename(workspace string, maybeCfg *valid.Project) string {
 	}
 	return invalidFilenameChars.ReplaceAllLiteralString(unescapedFilename, "-")
 }
+
+// GetPolicyCheckFilename returns the filename (not the path) of the file that
+// records the policy check failures of the plan named by GetPlanFilename. The
+// plan can't be applied while the file exists.
+func GetPolicyCheckFilename(workspace string, maybeCfg *valid.Project) string {
+	return strings.TrimSuffix(GetPlanFilename(workspace, maybeCfg), ".tfplan") + ".policy-check-failures"
+}
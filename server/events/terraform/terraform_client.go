@@ -301,17 +301,11 @@ func (c *DefaultClient) RunCommandAsync(log *logging.SimpleLogger, path string,
 
 		// Asynchronously copy from stdout/err to outCh.
 		go func() {
-			s := bufio.NewScanner(stdout)
-			for s.Scan() {
-				outCh <- Line{Line: s.Text()}
-			}
+			scanLines(stdout, outCh)
 			wg.Done()
 		}()
 		go func() {
-			s := bufio.NewScanner(stderr)
-			for s.Scan() {
-				outCh <- Line{Line: s.Text()}
-			}
+			scanLines(stderr, outCh)
 			wg.Done()
 		}()
 
@@ -335,6 +329,29 @@ func (c *DefaultClient) RunCommandAsync(log *logging.SimpleLogger, path string,
 	return inCh, outCh
 }
 
+// scanLines sends each line read from r to outCh. Unlike bufio.Scanner, it
+// doesn't limit how long lines can be since Terraform can output very long
+// lines, ex. for large resource attributes. If reading fails, it sends the
+// error and discards the rest of r so that the command doesn't block writing
+// to a pipe nobody is reading.
+func scanLines(r io.Reader, outCh chan<- Line) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			outCh <- Line{Line: strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")}
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			outCh <- Line{Err: errors.Wrap(err, "reading output")}
+			io.Copy(ioutil.Discard, r) // nolint: errcheck
+			return
+		}
+	}
+}
+
 // MustConstraint will parse one or more constraints from the given
 // constraint string. The string must be a comma-separated list of
 // constraints. It panics if there is an error.
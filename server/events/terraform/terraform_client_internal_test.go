@@ -188,6 +188,27 @@ func TestDefaultClient_RunCommandAsync_BigOutput(t *testing.T) {
 	Equals(t, strings.TrimRight(exp, "\n"), out)
 }
 
+// Test that lines longer than bufio.Scanner's max token size aren't dropped.
+func TestDefaultClient_RunCommandAsync_LongLine(t *testing.T) {
+	v, err := version.NewVersion("0.11.11")
+	Ok(t, err)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: tmp,
+		overrideTF:              "cat",
+	}
+	filename := filepath.Join(tmp, "data")
+	longLine := strings.Repeat("0", 2*1024*1024)
+	Ok(t, ioutil.WriteFile(filename, []byte("first\n"+longLine+"\nlast"), 0600))
+	_, outCh := client.RunCommandAsync(nil, tmp, []string{filename}, nil, "workspace")
+
+	out, err := waitCh(outCh)
+	Ok(t, err)
+	Equals(t, "first\n"+longLine+"\nlast", out)
+}
+
 func TestDefaultClient_RunCommandAsync_StderrOutput(t *testing.T) {
 	v, err := version.NewVersion("0.11.11")
 	Ok(t, err)
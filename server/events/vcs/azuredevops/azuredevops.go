@@ -0,0 +1,22 @@
+// Package azuredevops holds code for Azure DevOps Repos, ex. dev.azure.com.
+package azuredevops
+
+// DefaultHostname is the hostname of Azure DevOps Services.
+const DefaultHostname = "dev.azure.com"
+
+// apiVersion is the version of the REST API that we use.
+const apiVersion = "5.1"
+
+// Event types sent by Azure DevOps service hooks.
+const (
+	PullCreatedEvent        = "git.pullrequest.created"
+	PullUpdatedEvent        = "git.pullrequest.updated"
+	PullCommentCreatedEvent = "ms.vss-code.git-pullrequest-comment-event"
+)
+
+// Pull request statuses.
+const (
+	PullActiveStatus    = "active"
+	PullAbandonedStatus = "abandoned"
+	PullCompletedStatus = "completed"
+)
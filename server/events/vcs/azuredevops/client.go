@@ -0,0 +1,401 @@
+package azuredevops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs/common"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// maxCommentLength is the maximum number of chars allowed by Azure DevOps in
+// a single comment.
+const maxCommentLength = 150000
+
+// changesPageSize is the number of changed files we request at a time.
+const changesPageSize = 100
+
+type Client struct {
+	HTTPClient *http.Client
+	Username   string
+	Token      string
+	// BaseURL is the scheme and host of the API, ex. https://dev.azure.com.
+	BaseURL string
+}
+
+// NewClient builds an Azure DevOps client. hostname is the Azure DevOps
+// hostname, ex. dev.azure.com. username and token are used as basic auth in
+// the requests. token is a personal access token and username can be any
+// value, including empty.
+func NewClient(httpClient *http.Client, username string, token string, hostname string) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		HTTPClient: httpClient,
+		Username:   username,
+		Token:      token,
+		BaseURL:    "https://" + hostname,
+	}
+}
+
+// GetModifiedFiles returns the names of files that were modified in the pull request.
+// The names include the path to the file from the repo root, ex. parent/child/file.txt.
+func (c *Client) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	pullURL, err := c.pullURL(repo, pull.Num)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each push to the pull request creates an iteration. The changes of the
+	// latest iteration are the changes of the whole pull request.
+	resp, err := c.makeRequest("GET", fmt.Sprintf("%s/iterations?api-version=%s", pullURL, apiVersion), nil)
+	if err != nil {
+		return nil, err
+	}
+	var iterations Iterations
+	if err := json.Unmarshal(resp, &iterations); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(iterations); err != nil {
+		return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+	latest := 0
+	for _, i := range iterations.Value {
+		if *i.ID > latest {
+			latest = *i.ID
+		}
+	}
+	if latest == 0 {
+		return nil, fmt.Errorf("pull request %d has no iterations", pull.Num)
+	}
+
+	var files []string
+	skip := 0
+	// We'll only loop 1000 times as a safety measure.
+	maxLoops := 1000
+	for i := 0; i < maxLoops; i++ {
+		changesURL := fmt.Sprintf("%s/iterations/%d/changes?api-version=%s&$top=%d&$skip=%d", pullURL, latest, apiVersion, changesPageSize, skip)
+		resp, err := c.makeRequest("GET", changesURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		var changes IterationChanges
+		if err := json.Unmarshal(resp, &changes); err != nil {
+			return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+		}
+		if err := validator.New().Struct(changes); err != nil {
+			return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+		}
+		for _, change := range changes.ChangeEntries {
+			// Paths are absolute from the repo root, ex. /dir/main.tf.
+			files = append(files, strings.TrimPrefix(*change.Item.Path, "/"))
+
+			// If the file was renamed, we'll want to run plan in the directory
+			// it was moved from as well.
+			if change.OriginalPath != nil {
+				files = append(files, strings.TrimPrefix(*change.OriginalPath, "/"))
+			}
+		}
+		if changes.NextSkip == nil || *changes.NextSkip == 0 {
+			break
+		}
+		skip = *changes.NextSkip
+	}
+
+	// Now ensure all files are unique.
+	hash := make(map[string]bool)
+	var unique []string
+	for _, f := range files {
+		if !hash[f] {
+			unique = append(unique, f)
+			hash[f] = true
+		}
+	}
+	return unique, nil
+}
+
+// CreateComment creates a comment on the pull request. It will write multiple
+// comments if a single comment is too long.
+func (c *Client) CreateComment(repo models.Repo, pullNum int, comment string) error {
+	_, err := c.CreateCommentWithIDs(repo, pullNum, comment)
+	return err
+}
+
+// CreateCommentWithIDs creates comment like CreateComment and returns the IDs
+// of the comments created. Comments live in threads so each ID is of the form
+// "<thread id>/<comment id>".
+func (c *Client) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
+	comments := c.SplitComment(repo, comment)
+	var ids []string
+	for _, comm := range comments {
+		id, err := c.postComment(repo, pullNum, comm)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SplitComment splits comment into parts that are under the max comment
+// length.
+func (c *Client) SplitComment(repo models.Repo, comment string) []string {
+	sepEnd := "\n```\n**Warning**: Output length greater than max comment size. Continued in next comment."
+	sepStart := "Continued from previous comment.\n```diff\n"
+	return common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
+}
+
+// postComment actually posts the comment and returns its ID. It's a helper
+// for CreateComment(). Each comment is posted as a new thread.
+func (c *Client) postComment(repo models.Repo, pullNum int, comment string) (string, error) {
+	pullURL, err := c.pullURL(repo, pullNum)
+	if err != nil {
+		return "", err
+	}
+	// We create the thread as closed so that our comments don't block the
+	// pull request if the branch policy requires all comments be resolved.
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"comments": []map[string]interface{}{
+			{
+				"parentCommentId": 0,
+				"content":         comment,
+				"commentType":     "text",
+			},
+		},
+		"status": "closed",
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/threads?api-version=%s", pullURL, apiVersion)
+	resp, err := c.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return "", err
+	}
+	var thread Thread
+	if err := json.Unmarshal(resp, &thread); err != nil {
+		return "", errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if thread.ID == nil || len(thread.Comments) == 0 || thread.Comments[0].ID == nil {
+		return "", fmt.Errorf("API response %q was missing thread or comment id", string(resp))
+	}
+	return fmt.Sprintf("%d/%d", *thread.ID, *thread.Comments[0].ID), nil
+}
+
+// EditComment replaces the body of the comment with ID commentID which must
+// be of the form "<thread id>/<comment id>".
+func (c *Client) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	ids := strings.Split(commentID, "/")
+	if len(ids) != 2 {
+		return fmt.Errorf("invalid comment id %q: expected <thread id>/<comment id>", commentID)
+	}
+	pullURL, err := c.pullURL(repo, pullNum)
+	if err != nil {
+		return err
+	}
+	sepEnd := "\n```\n**Warning**: Output length greater than max comment size. Output truncated."
+	bodyBytes, err := json.Marshal(map[string]string{
+		"content": common.TruncateComment(comment, maxCommentLength, sepEnd),
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/threads/%s/comments/%s?api-version=%s", pullURL, url.PathEscape(ids[0]), url.PathEscape(ids[1]), apiVersion)
+	_, err = c.makeRequest("PATCH", path, bytes.NewBuffer(bodyBytes))
+	return err
+}
+
+// HideComment is not supported by Azure DevOps.
+func (c *Client) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	return errors.New("hiding comments is not supported by Azure DevOps")
+}
+
+// IsTeamMember is not supported by Azure DevOps.
+func (c *Client) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	return false, errors.New("checking team membership is not supported by Azure DevOps")
+}
+
+// GetApprovers is not supported by Azure DevOps.
+func (c *Client) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, errors.New("listing approvers is not supported by Azure DevOps")
+}
+
+// GetFileContent is not supported by Azure DevOps.
+func (c *Client) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	return false, nil, errors.New("getting file contents is not supported by Azure DevOps")
+}
+
+// PullIsApproved returns true if the pull request was approved.
+func (c *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
+	pullResp, err := c.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, err
+	}
+	for _, reviewer := range pullResp.Reviewers {
+		// Votes of 5 or more are approvals. Azure DevOps can be configured to
+		// let authors approve their own pull requests. This defeats the
+		// purpose of approvals so we don't count that approval.
+		if *reviewer.Vote >= 5 && *reviewer.UniqueName != pull.Author {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PullIsMergeable returns true if the pull request has no conflicts and can be merged.
+func (c *Client) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
+	pullResp, err := c.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, err
+	}
+	return pullResp.MergeStatus != nil && *pullResp.MergeStatus == "succeeded", nil
+}
+
+// UpdateStatus updates the status of the pull request. The status is shown
+// on the pull request and can be required by branch policies. src is split on
+// its first / into the status's genre and name, ex. atlantis/plan.
+func (c *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, src string, description string, url string) error {
+	adState := "failed"
+	switch status {
+	case models.PendingCommitStatus:
+		adState = "pending"
+	case models.SuccessCommitStatus:
+		adState = "succeeded"
+	case models.FailedCommitStatus:
+		adState = "failed"
+	}
+
+	genre, name := "", src
+	if parts := strings.SplitN(src, "/", 2); len(parts) == 2 {
+		genre, name = parts[0], parts[1]
+	}
+	body := map[string]interface{}{
+		"state":       adState,
+		"description": description,
+		"context": map[string]string{
+			"genre": genre,
+			"name":  name,
+		},
+	}
+	if url != "" {
+		body["targetUrl"] = url
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	pullURL, err := c.pullURL(repo, pull.Num)
+	if err != nil {
+		return err
+	}
+	// Pull request statuses are still in preview.
+	path := fmt.Sprintf("%s/statuses?api-version=%s-preview.1", pullURL, apiVersion)
+	_, err = c.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
+	return err
+}
+
+// MergePull merges the pull request by completing it.
+func (c *Client) MergePull(pull models.PullRequest) error {
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"status": PullCompletedStatus,
+		// Azure DevOps requires the latest commit so it doesn't merge commits
+		// that were pushed after we applied.
+		"lastMergeSourceCommit": map[string]string{
+			"commitId": pull.HeadCommit,
+		},
+		"completionOptions": map[string]string{
+			"mergeCommitMessage": common.AutomergeCommitMsg,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	pullURL, err := c.pullURL(pull.BaseRepo, pull.Num)
+	if err != nil {
+		return err
+	}
+	_, err = c.makeRequest("PATCH", fmt.Sprintf("%s?api-version=%s", pullURL, apiVersion), bytes.NewBuffer(bodyBytes))
+	return err
+}
+
+// GetPullRequest gets the pull request with id pullNum for the repo.
+func (c *Client) GetPullRequest(repo models.Repo, pullNum int) (*PullRequest, error) {
+	pullURL, err := c.pullURL(repo, pullNum)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.makeRequest("GET", fmt.Sprintf("%s?api-version=%s", pullURL, apiVersion), nil)
+	if err != nil {
+		return nil, err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(pullResp); err != nil {
+		return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+	return &pullResp, nil
+}
+
+// pullURL returns the API URL of the pull request, ex.
+// https://dev.azure.com/org/project/_apis/git/repositories/repo/pullRequests/1.
+func (c *Client) pullURL(repo models.Repo, pullNum int) (string, error) {
+	orgProject := strings.Split(repo.Owner, "/")
+	if len(orgProject) != 2 {
+		return "", fmt.Errorf("could not extract organization and project from %q", repo.FullName)
+	}
+	return fmt.Sprintf("%s/%s/%s/_apis/git/repositories/%s/pullRequests/%d",
+		c.BaseURL,
+		url.PathEscape(orgProject[0]),
+		url.PathEscape(orgProject[1]),
+		url.PathEscape(repo.Name),
+		pullNum), nil
+}
+
+// prepRequest adds auth and necessary headers.
+func (c *Client) prepRequest(method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.Username, c.Token)
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+func (c *Client) makeRequest(method string, path string, reqBody io.Reader) ([]byte, error) {
+	req, err := c.prepRequest(method, path, reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	requestStr := fmt.Sprintf("%s %s", method, path)
+
+	// Azure DevOps responds to requests with invalid credentials with a 203
+	// and a sign in page so we only accept the codes we expect.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("making request %q unexpected status code: %d, body: %s", requestStr, resp.StatusCode, string(respBody))
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading response from request %q", requestStr)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,326 @@
+package azuredevops_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs/azuredevops"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const pullPath = "/owner/my%20project/_apis/git/repositories/repo/pullRequests/1"
+
+var repo = models.Repo{
+	FullName: "owner/my project/repo",
+	Owner:    "owner/my project",
+	Name:     "repo",
+	VCSHost: models.VCSHost{
+		Type:     models.AzureDevops,
+		Hostname: "dev.azure.com",
+	},
+}
+
+func newClient(url string) *azuredevops.Client {
+	client := azuredevops.NewClient(http.DefaultClient, "user", "token", "dev.azure.com")
+	client.BaseURL = url
+	return client
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	bytes, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	Ok(t, err)
+	return bytes
+}
+
+func TestNewClient(t *testing.T) {
+	client := azuredevops.NewClient(nil, "user", "token", "dev.azure.com")
+	Equals(t, "https://dev.azure.com", client.BaseURL)
+	Equals(t, http.DefaultClient, client.HTTPClient)
+}
+
+// Should use the latest iteration and include the original paths of renamed
+// files.
+func TestClient_GetModifiedFiles(t *testing.T) {
+	iterations := readFixture(t, "iterations.json")
+	changes := readFixture(t, "changes.json")
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		Assert(t, ok, "exp basic auth")
+		Equals(t, "user", user)
+		Equals(t, "token", pass)
+		switch r.RequestURI {
+		case pullPath + "/iterations?api-version=5.1":
+			w.Write(iterations) // nolint: errcheck
+		case pullPath + "/iterations/2/changes?api-version=5.1&$top=100&$skip=0":
+			w.Write(changes) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	files, err := newClient(testServer.URL).GetModifiedFiles(repo, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, []string{"main.tf", "dir/new.tf", "dir/old.tf"}, files)
+}
+
+// Should follow pagination properly.
+func TestClient_GetModifiedFilesPagination(t *testing.T) {
+	firstResp := `{"changeEntries":[{"item":{"path":"/file1.txt"}},{"item":{"path":"/file2.txt"}}],"nextSkip":100,"nextTop":100}`
+	secondResp := `{"changeEntries":[{"item":{"path":"/file2.txt"}},{"item":{"path":"/file3.txt"}}]}`
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case pullPath + "/iterations?api-version=5.1":
+			w.Write([]byte(`{"value":[{"id":1}]}`)) // nolint: errcheck
+		case pullPath + "/iterations/1/changes?api-version=5.1&$top=100&$skip=0":
+			w.Write([]byte(firstResp)) // nolint: errcheck
+		case pullPath + "/iterations/1/changes?api-version=5.1&$top=100&$skip=100":
+			w.Write([]byte(secondResp)) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	files, err := newClient(testServer.URL).GetModifiedFiles(repo, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, []string{"file1.txt", "file2.txt", "file3.txt"}, files)
+}
+
+func TestClient_CreateComment(t *testing.T) {
+	var body map[string]interface{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case pullPath + "/threads?api-version=5.1":
+			Equals(t, "POST", r.Method)
+			Ok(t, json.NewDecoder(r.Body).Decode(&body))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id": 7, "comments": [{"id": 1, "content": "comment"}]}`)) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	ids, err := newClient(testServer.URL).CreateCommentWithIDs(repo, 1, "comment")
+	Ok(t, err)
+	Equals(t, []string{"7/1"}, ids)
+	Equals(t, "closed", body["status"])
+	comment := body["comments"].([]interface{})[0].(map[string]interface{})
+	Equals(t, "comment", comment["content"])
+	Equals(t, "text", comment["commentType"])
+}
+
+func TestClient_EditComment(t *testing.T) {
+	var body map[string]interface{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case pullPath + "/threads/7/comments/1?api-version=5.1":
+			Equals(t, "PATCH", r.Method)
+			Ok(t, json.NewDecoder(r.Body).Decode(&body))
+			w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	client := newClient(testServer.URL)
+	Ok(t, client.EditComment(repo, 1, "7/1", "edited"))
+	Equals(t, map[string]interface{}{"content": "edited"}, body)
+	ErrEquals(t, `invalid comment id "7": expected <thread id>/<comment id>`, client.EditComment(repo, 1, "7", "edited"))
+	ErrEquals(t, "hiding comments is not supported by Azure DevOps", client.HideComment(repo, 1, "7/1"))
+}
+
+func TestClient_PullIsApproved(t *testing.T) {
+	pullRequest := string(readFixture(t, "pull-request.json"))
+	cases := []struct {
+		description string
+		reviewers   string
+		exp         bool
+	}{
+		{
+			"no reviewers",
+			`[]`,
+			false,
+		},
+		{
+			"no vote",
+			`[{"uniqueName":"reviewer@example.com","vote":0}]`,
+			false,
+		},
+		{
+			"rejected",
+			`[{"uniqueName":"reviewer@example.com","vote":-10}]`,
+			false,
+		},
+		{
+			"approved",
+			`[{"uniqueName":"reviewer@example.com","vote":10}]`,
+			true,
+		},
+		{
+			"approved with suggestions",
+			`[{"uniqueName":"reviewer@example.com","vote":5}]`,
+			true,
+		},
+		{
+			"approved by author",
+			`[{"uniqueName":"author@example.com","vote":10}]`,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			var pullResp map[string]interface{}
+			Ok(t, json.Unmarshal([]byte(pullRequest), &pullResp))
+			var reviewers []interface{}
+			Ok(t, json.Unmarshal([]byte(c.reviewers), &reviewers))
+			pullResp["reviewers"] = reviewers
+			resp, err := json.Marshal(pullResp)
+			Ok(t, err)
+
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.RequestURI {
+				case pullPath + "?api-version=5.1":
+					w.Write(resp) // nolint: errcheck
+				default:
+					t.Errorf("got unexpected request at %q", r.RequestURI)
+					http.Error(w, "not found", http.StatusNotFound)
+				}
+			}))
+			defer testServer.Close()
+
+			approved, err := newClient(testServer.URL).PullIsApproved(repo, models.PullRequest{
+				Num:    1,
+				Author: "author@example.com",
+			})
+			Ok(t, err)
+			Equals(t, c.exp, approved)
+		})
+	}
+}
+
+func TestClient_PullIsMergeable(t *testing.T) {
+	pullRequest := string(readFixture(t, "pull-request.json"))
+	cases := []struct {
+		mergeStatus string
+		exp         bool
+	}{
+		{"succeeded", true},
+		{"conflicts", false},
+		{"queued", false},
+		{"rejectedByPolicy", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.mergeStatus, func(t *testing.T) {
+			resp := strings.Replace(pullRequest, `"mergeStatus": "succeeded"`, `"mergeStatus": "`+c.mergeStatus+`"`, 1)
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.RequestURI {
+				case pullPath + "?api-version=5.1":
+					w.Write([]byte(resp)) // nolint: errcheck
+				default:
+					t.Errorf("got unexpected request at %q", r.RequestURI)
+					http.Error(w, "not found", http.StatusNotFound)
+				}
+			}))
+			defer testServer.Close()
+
+			mergeable, err := newClient(testServer.URL).PullIsMergeable(repo, models.PullRequest{Num: 1})
+			Ok(t, err)
+			Equals(t, c.exp, mergeable)
+		})
+	}
+}
+
+func TestClient_UpdateStatus(t *testing.T) {
+	cases := []struct {
+		status   models.CommitStatus
+		expState string
+	}{
+		{models.PendingCommitStatus, "pending"},
+		{models.SuccessCommitStatus, "succeeded"},
+		{models.FailedCommitStatus, "failed"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expState, func(t *testing.T) {
+			var body map[string]interface{}
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.RequestURI {
+				case pullPath + "/statuses?api-version=5.1-preview.1":
+					Equals(t, "POST", r.Method)
+					Ok(t, json.NewDecoder(r.Body).Decode(&body))
+					w.WriteHeader(http.StatusOK)
+				default:
+					t.Errorf("got unexpected request at %q", r.RequestURI)
+					http.Error(w, "not found", http.StatusNotFound)
+				}
+			}))
+			defer testServer.Close()
+
+			err := newClient(testServer.URL).UpdateStatus(repo, models.PullRequest{Num: 1}, c.status, "atlantis/plan", "Plan succeeded", "https://atlantis/jobs/1")
+			Ok(t, err)
+			Equals(t, map[string]interface{}{
+				"state":       c.expState,
+				"description": "Plan succeeded",
+				"targetUrl":   "https://atlantis/jobs/1",
+				"context": map[string]interface{}{
+					"genre": "atlantis",
+					"name":  "plan",
+				},
+			}, body)
+		})
+	}
+}
+
+// Should complete the pull request at the commit we applied.
+func TestClient_MergePull(t *testing.T) {
+	var body map[string]interface{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case pullPath + "?api-version=5.1":
+			Equals(t, "PATCH", r.Method)
+			Ok(t, json.NewDecoder(r.Body).Decode(&body))
+			w.Write(readFixture(t, "pull-request.json")) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	err := newClient(testServer.URL).MergePull(models.PullRequest{
+		Num:        1,
+		HeadCommit: "b60280bc6e62e2f880f1b63c1e24987664d3bda3",
+		BaseRepo:   repo,
+	})
+	Ok(t, err)
+	Equals(t, "completed", body["status"])
+	Equals(t, map[string]interface{}{"commitId": "b60280bc6e62e2f880f1b63c1e24987664d3bda3"}, body["lastMergeSourceCommit"])
+}
+
+// Azure DevOps responds with a 203 and a sign in page when the credentials
+// are invalid so we should error.
+func TestClient_InvalidCredentials(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+		w.Write([]byte("<html>sign in</html>")) // nolint: errcheck
+	}))
+	defer testServer.Close()
+
+	_, err := newClient(testServer.URL).PullIsMergeable(repo, models.PullRequest{Num: 1})
+	ErrContains(t, "unexpected status code: 203", err)
+}
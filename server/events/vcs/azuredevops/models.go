@@ -0,0 +1,95 @@
+package azuredevops
+
+// Event is the body of the webhooks sent by Azure DevOps service hooks.
+type Event struct {
+	EventType *string `json:"eventType,omitempty" validate:"required"`
+}
+
+type PullRequestEvent struct {
+	Event
+	Resource *PullRequest `json:"resource,omitempty" validate:"required"`
+}
+
+type CommentEvent struct {
+	Event
+	Resource *struct {
+		Comment     *Comment     `json:"comment,omitempty" validate:"required"`
+		PullRequest *PullRequest `json:"pullRequest,omitempty" validate:"required"`
+	} `json:"resource,omitempty" validate:"required"`
+}
+
+type PullRequest struct {
+	PullRequestID         *int        `json:"pullRequestId,omitempty" validate:"required"`
+	Status                *string     `json:"status,omitempty" validate:"required"`
+	CreatedBy             *Identity   `json:"createdBy,omitempty" validate:"required"`
+	SourceRefName         *string     `json:"sourceRefName,omitempty" validate:"required"`
+	TargetRefName         *string     `json:"targetRefName,omitempty" validate:"required"`
+	MergeStatus           *string     `json:"mergeStatus,omitempty"`
+	LastMergeSourceCommit *Commit     `json:"lastMergeSourceCommit,omitempty" validate:"required"`
+	Repository            *Repository `json:"repository,omitempty" validate:"required"`
+	// ForkSource is set if the pull request's source branch is in a fork.
+	ForkSource *struct {
+		Repository *Repository `json:"repository,omitempty" validate:"required"`
+	} `json:"forkSource,omitempty"`
+	Reviewers []Reviewer `json:"reviewers,omitempty" validate:"dive"`
+	// Commits is only set on git.pullrequest.updated events when commits
+	// were pushed to the pull request's source branch.
+	Commits []Commit `json:"commits,omitempty" validate:"dive"`
+}
+
+type Repository struct {
+	Name      *string  `json:"name,omitempty" validate:"required"`
+	RemoteURL *string  `json:"remoteUrl,omitempty" validate:"required"`
+	Project   *Project `json:"project,omitempty" validate:"required"`
+}
+
+type Project struct {
+	Name *string `json:"name,omitempty" validate:"required"`
+}
+
+type Commit struct {
+	CommitID *string `json:"commitId,omitempty" validate:"required"`
+}
+
+type Identity struct {
+	ID         *string `json:"id,omitempty"`
+	UniqueName *string `json:"uniqueName,omitempty" validate:"required"`
+}
+
+type Reviewer struct {
+	Identity
+	// Vote is 10 for approved, 5 for approved with suggestions, 0 for no
+	// vote, -5 for waiting for author and -10 for rejected.
+	Vote *int `json:"vote,omitempty" validate:"required"`
+}
+
+type Comment struct {
+	ID      *int      `json:"id,omitempty"`
+	Content *string   `json:"content,omitempty" validate:"required"`
+	Author  *Identity `json:"author,omitempty" validate:"required"`
+}
+
+// Thread is a thread of comments on a pull request. Each of our comments is
+// posted in its own thread.
+type Thread struct {
+	ID       *int      `json:"id,omitempty" validate:"required"`
+	Comments []Comment `json:"comments,omitempty" validate:"required,min=1"`
+}
+
+type Iterations struct {
+	Value []struct {
+		ID *int `json:"id,omitempty" validate:"required"`
+	} `json:"value,omitempty" validate:"required,dive"`
+}
+
+type IterationChanges struct {
+	ChangeEntries []struct {
+		Item *struct {
+			Path *string `json:"path,omitempty" validate:"required"`
+		} `json:"item,omitempty" validate:"required"`
+		// OriginalPath is set if the file was renamed.
+		OriginalPath *string `json:"originalPath,omitempty"`
+	} `json:"changeEntries,omitempty" validate:"required,dive"`
+	NextSkip *int `json:"nextSkip,omitempty"`
+	NextTop  *int `json:"nextTop,omitempty"`
+}
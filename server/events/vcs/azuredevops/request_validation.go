@@ -0,0 +1,25 @@
+package azuredevops
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateBasicAuth returns an error if the request doesn't have the expected
+// basic auth credentials. Azure DevOps service hooks don't sign their
+// payloads so basic auth is the only way to authenticate them.
+func ValidateBasicAuth(r *http.Request, user []byte, pass []byte) error {
+	reqUser, reqPass, ok := r.BasicAuth()
+	if !ok {
+		return errors.New("request is missing basic auth credentials")
+	}
+	// Compare both so we take the same time whether or not the user matches.
+	userMatch := subtle.ConstantTimeCompare([]byte(reqUser), user)
+	passMatch := subtle.ConstantTimeCompare([]byte(reqPass), pass)
+	if userMatch&passMatch != 1 {
+		return errors.New("basic auth credentials did not match")
+	}
+	return nil
+}
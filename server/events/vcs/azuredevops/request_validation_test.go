@@ -0,0 +1,40 @@
+package azuredevops_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/vcs/azuredevops"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestValidateBasicAuth(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/events", nil)
+	req.SetBasicAuth("user", "pass")
+	Ok(t, azuredevops.ValidateBasicAuth(req, []byte("user"), []byte("pass")))
+}
+
+func TestValidateBasicAuth_Missing(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/events", nil)
+	err := azuredevops.ValidateBasicAuth(req, []byte("user"), []byte("pass"))
+	ErrEquals(t, "request is missing basic auth credentials", err)
+}
+
+func TestValidateBasicAuth_Invalid(t *testing.T) {
+	cases := []struct {
+		user string
+		pass string
+	}{
+		{"user", "wrong"},
+		{"wrong", "pass"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.user+":"+c.pass, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/events", nil)
+			req.SetBasicAuth(c.user, c.pass)
+			err := azuredevops.ValidateBasicAuth(req, []byte("user"), []byte("pass"))
+			ErrEquals(t, "basic auth credentials did not match", err)
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -86,42 +88,117 @@ func (b *Client) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]
 
 // CreateComment creates a comment on the merge request.
 func (b *Client) CreateComment(repo models.Repo, pullNum int, comment string) error {
+	_, err := b.CreateCommentWithIDs(repo, pullNum, comment)
+	return err
+}
+
+// CreateCommentWithIDs creates a comment on the merge request and returns its
+// ID.
+func (b *Client) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
 	// NOTE: I tried to find the maximum size of a comment for bitbucket.org but
 	// I got up to 200k chars without issue so for now I'm not going to bother
 	// to detect this.
-	bodyBytes, err := json.Marshal(map[string]map[string]string{"content": {
-		"raw": comment,
-	}})
+	bodyBytes, err := b.commentBody(comment)
 	if err != nil {
-		return errors.Wrap(err, "json encoding")
+		return nil, err
 	}
 	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/comments", b.BaseURL, repo.FullName, pullNum)
-	_, err = b.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
+	resp, err := b.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	var created Comment
+	if err := json.Unmarshal(resp, &created); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if created.ID == nil {
+		return nil, fmt.Errorf("API response %q was missing id", string(resp))
+	}
+	return []string{strconv.Itoa(*created.ID)}, nil
+}
+
+// SplitComment never splits comment because CreateCommentWithIDs always posts
+// a single comment.
+func (b *Client) SplitComment(repo models.Repo, comment string) []string {
+	return []string{comment}
+}
+
+// EditComment replaces the body of the comment with ID commentID.
+func (b *Client) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	bodyBytes, err := b.commentBody(comment)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/comments/%s", b.BaseURL, repo.FullName, pullNum, url.PathEscape(commentID))
+	_, err = b.makeRequest("PUT", path, bytes.NewBuffer(bodyBytes))
 	return err
 }
 
+// HideComment is not supported by Bitbucket.
+func (b *Client) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	return errors.New("hiding comments is not supported by Bitbucket")
+}
+
+// IsTeamMember is not supported by Bitbucket.
+func (b *Client) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	return false, errors.New("checking team membership is not supported by Bitbucket")
+}
+
+// commentBody returns the JSON request body for a comment.
+func (b *Client) commentBody(comment string) ([]byte, error) {
+	bodyBytes, err := json.Marshal(map[string]map[string]string{"content": {
+		"raw": comment,
+	}})
+	return bodyBytes, errors.Wrap(err, "json encoding")
+}
+
 // PullIsApproved returns true if the merge request was approved.
 func (b *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
-	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d", b.BaseURL, repo.FullName, pull.Num)
-	resp, err := b.makeRequest("GET", path, nil)
+	approvers, err := b.GetApprovers(repo, pull)
 	if err != nil {
 		return false, err
 	}
-	var pullResp PullRequest
-	if err := json.Unmarshal(resp, &pullResp); err != nil {
-		return false, errors.Wrapf(err, "Could not parse response %q", string(resp))
-	}
-	if err := validator.New().Struct(pullResp); err != nil {
-		return false, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	return len(approvers) > 0, nil
+}
+
+// GetApprovers returns the usernames of the users that approved the pull
+// request.
+func (b *Client) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	pullResp, err := b.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return nil, err
 	}
+	var approvers []string
 	for _, participant := range pullResp.Participants {
 		// Bitbucket allows the author to approve their own pull request. This
 		// defeats the purpose of approvals so we don't count that approval.
 		if *participant.Approved && *participant.User.Username != pull.Author {
-			return true, nil
+			approvers = append(approvers, *participant.User.Username)
 		}
 	}
-	return false, nil
+	return approvers, nil
+}
+
+// GetPullRequest gets the pull request with id pullNum for the repo.
+func (b *Client) GetPullRequest(repo models.Repo, pullNum int) (*PullRequest, error) {
+	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d", b.BaseURL, repo.FullName, pullNum)
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(pullResp); err != nil {
+		return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+	return &pullResp, nil
+}
+
+// GetFileContent returns the contents of the file at path on the branch ref.
+func (b *Client) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	return b.getFile(fmt.Sprintf("%s/2.0/repositories/%s/src/%s/%s", b.BaseURL, repo.FullName, url.PathEscape(ref), path))
 }
 
 // PullIsMergeable returns true if the merge request has no conflicts and can be merged.
@@ -185,6 +262,31 @@ func (b *Client) MergePull(pull models.PullRequest) error {
 	return err
 }
 
+// getFile gets the raw file at path. It returns false if the file doesn't
+// exist.
+func (b *Client) getFile(path string) (bool, []byte, error) {
+	req, err := b.prepRequest("GET", path, nil)
+	if err != nil {
+		return false, nil, errors.Wrap(err, "constructing request")
+	}
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return false, nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil, nil
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "reading response from request \"GET %s\"", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, fmt.Errorf("making request \"GET %s\" unexpected status code: %d, body: %s", path, resp.StatusCode, string(respBody))
+	}
+	return true, respBody, nil
+}
+
 // prepRequest adds auth and necessary headers.
 func (b *Client) prepRequest(method string, path string, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequest(method, path, body)
@@ -153,29 +153,34 @@ func TestClient_GetModifiedFilesOldNil(t *testing.T) {
 
 func TestClient_PullIsApproved(t *testing.T) {
 	cases := []struct {
-		description string
-		testdata    string
-		exp         bool
+		description  string
+		testdata     string
+		exp          bool
+		expApprovers []string
 	}{
 		{
 			"no approvers",
 			"pull-unapproved.json",
 			false,
+			nil,
 		},
 		{
 			"approver is the author",
 			"pull-approved-by-author.json",
 			false,
+			nil,
 		},
 		{
 			"single approver",
 			"pull-approved.json",
 			true,
+			[]string{"approver"},
 		},
 		{
 			"two approvers one author",
 			"pull-approved-multiple.json",
 			true,
+			[]string{"approver"},
 		},
 	}
 
@@ -202,14 +207,51 @@ func TestClient_PullIsApproved(t *testing.T) {
 
 			repo, err := models.NewRepo(models.BitbucketServer, "owner/repo", "https://bitbucket.org/owner/repo.git", "user", "token")
 			Ok(t, err)
-			approved, err := client.PullIsApproved(repo, models.PullRequest{
+			pull := models.PullRequest{
 				Num:        1,
 				HeadBranch: "branch",
 				Author:     "author",
 				BaseRepo:   repo,
-			})
+			}
+			approved, err := client.PullIsApproved(repo, pull)
 			Ok(t, err)
 			Equals(t, c.exp, approved)
+
+			approvers, err := client.GetApprovers(repo, pull)
+			Ok(t, err)
+			Equals(t, c.expApprovers, approvers)
 		})
 	}
 }
+
+func TestClient_GetFileContent(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/2.0/repositories/owner/repo/src/master/.bitbucket/CODEOWNERS":
+			w.Write([]byte("* @alice\n")) // nolint: errcheck
+			return
+		case "/2.0/repositories/owner/repo/src/master/CODEOWNERS":
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}))
+	defer testServer.Close()
+
+	client := bitbucketcloud.NewClient(http.DefaultClient, "user", "pass", "runatlantis.io")
+	client.BaseURL = testServer.URL
+	repo, err := models.NewRepo(models.BitbucketCloud, "owner/repo", "https://bitbucket.org/owner/repo.git", "user", "token")
+	Ok(t, err)
+
+	exists, content, err := client.GetFileContent(repo, "master", ".bitbucket/CODEOWNERS")
+	Ok(t, err)
+	Equals(t, true, exists)
+	Equals(t, "* @alice\n", string(content))
+
+	exists, _, err = client.GetFileContent(repo, "master", "CODEOWNERS")
+	Ok(t, err)
+	Equals(t, false, exists)
+}
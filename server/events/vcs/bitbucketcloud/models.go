@@ -51,6 +51,10 @@ type PullRequest struct {
 	Participants []Participant `json:"participants,omitempty" validate:"required"`
 	Links        *Links        `json:"links,omitempty" validate:"required"`
 	State        *string       `json:"state,omitempty" validate:"required"`
+	// Author is only set when the pull request comes from the API.
+	Author *struct {
+		Nickname *string `json:"nickname,omitempty"`
+	} `json:"author,omitempty"`
 }
 type Links struct {
 	HTML *Link `json:"html,omitempty" validate:"required"`
@@ -76,6 +80,7 @@ type Commit struct {
 	Hash *string `json:"hash,omitempty" validate:"required"`
 }
 type Comment struct {
+	ID      *int            `json:"id,omitempty"`
 	Content *CommentContent `json:"content,omitempty" validate:"required"`
 }
 type CommentContent struct {
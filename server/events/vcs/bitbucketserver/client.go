@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/runatlantis/atlantis/server/events/vcs/common"
@@ -130,56 +131,167 @@ func (b *Client) GetProjectKey(repoName string, cloneURL string) (string, error)
 // CreateComment creates a comment on the merge request. It will write multiple
 // comments if a single comment is too long.
 func (b *Client) CreateComment(repo models.Repo, pullNum int, comment string) error {
-	sepEnd := "\n```\n**Warning**: Output length greater than max comment size. Continued in next comment."
-	sepStart := "Continued from previous comment.\n```diff\n"
-	comments := common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
+	_, err := b.CreateCommentWithIDs(repo, pullNum, comment)
+	return err
+}
+
+// CreateCommentWithIDs creates comment like CreateComment and returns the IDs
+// of the comments created.
+func (b *Client) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
+	comments := b.SplitComment(repo, comment)
+	var ids []string
 	for _, c := range comments {
-		if err := b.postComment(repo, pullNum, c); err != nil {
-			return err
+		id, err := b.postComment(repo, pullNum, c)
+		if err != nil {
+			return ids, err
 		}
+		ids = append(ids, id)
 	}
-	return nil
+	return ids, nil
 }
 
-// postComment actually posts the comment. It's a helper for CreateComment().
-func (b *Client) postComment(repo models.Repo, pullNum int, comment string) error {
+// SplitComment splits comment into parts that are under the max comment
+// length.
+func (b *Client) SplitComment(repo models.Repo, comment string) []string {
+	sepEnd := "\n```\n**Warning**: Output length greater than max comment size. Continued in next comment."
+	sepStart := "Continued from previous comment.\n```diff\n"
+	return common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
+}
+
+// postComment actually posts the comment and returns its ID. It's a helper
+// for CreateComment().
+func (b *Client) postComment(repo models.Repo, pullNum int, comment string) (string, error) {
 	bodyBytes, err := json.Marshal(map[string]string{"text": comment})
 	if err != nil {
-		return errors.Wrap(err, "json encoding")
+		return "", errors.Wrap(err, "json encoding")
 	}
-	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	commentsURL, err := b.commentsURL(repo, pullNum)
+	if err != nil {
+		return "", err
+	}
+	resp, err := b.makeRequest("POST", commentsURL, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return "", err
+	}
+	var created Comment
+	if err := json.Unmarshal(resp, &created); err != nil {
+		return "", errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if created.ID == nil {
+		return "", fmt.Errorf("API response %q was missing id", string(resp))
+	}
+	return strconv.Itoa(*created.ID), nil
+}
+
+// EditComment replaces the body of the comment with ID commentID. Bitbucket
+// requires the comment's current version so we look it up first.
+func (b *Client) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	commentsURL, err := b.commentsURL(repo, pullNum)
 	if err != nil {
 		return err
 	}
-	path := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/comments", b.BaseURL, projectKey, repo.Name, pullNum)
-	_, err = b.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
+	path := fmt.Sprintf("%s/%s", commentsURL, url.PathEscape(commentID))
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	var existing Comment
+	if err := json.Unmarshal(resp, &existing); err != nil {
+		return errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if existing.Version == nil {
+		return fmt.Errorf("API response %q was missing version", string(resp))
+	}
+
+	sepEnd := "\n```\n**Warning**: Output length greater than max comment size. Output truncated."
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"text":    common.TruncateComment(comment, maxCommentLength, sepEnd),
+		"version": *existing.Version,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	_, err = b.makeRequest("PUT", path, bytes.NewBuffer(bodyBytes))
 	return err
 }
 
+// HideComment is not supported by Bitbucket.
+func (b *Client) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	return errors.New("hiding comments is not supported by Bitbucket")
+}
+
+// IsTeamMember is not supported by Bitbucket.
+func (b *Client) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	return false, errors.New("checking team membership is not supported by Bitbucket")
+}
+
+// commentsURL returns the URL of the comments of the pull request.
+func (b *Client) commentsURL(repo models.Repo, pullNum int) (string, error) {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/comments", b.BaseURL, projectKey, repo.Name, pullNum), nil
+}
+
 // PullIsApproved returns true if the merge request was approved.
 func (b *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
-	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	pullResp, err := b.GetPullRequest(repo, pull.Num)
 	if err != nil {
 		return false, err
 	}
-	path := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, projectKey, repo.Name, pull.Num)
+	for _, reviewer := range pullResp.Reviewers {
+		if *reviewer.Approved {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetApprovers returns the usernames of the reviewers that approved the pull
+// request.
+func (b *Client) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	pullResp, err := b.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return nil, err
+	}
+	var approvers []string
+	for _, reviewer := range pullResp.Reviewers {
+		if *reviewer.Approved && reviewer.User != nil && reviewer.User.Name != nil {
+			approvers = append(approvers, *reviewer.User.Name)
+		}
+	}
+	return approvers, nil
+}
+
+// GetPullRequest gets the pull request with id pullNum for the repo.
+func (b *Client) GetPullRequest(repo models.Repo, pullNum int) (*PullRequest, error) {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, projectKey, repo.Name, pullNum)
 	resp, err := b.makeRequest("GET", path, nil)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	var pullResp PullRequest
 	if err := json.Unmarshal(resp, &pullResp); err != nil {
-		return false, errors.Wrapf(err, "Could not parse response %q", string(resp))
+		return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
 	}
 	if err := validator.New().Struct(pullResp); err != nil {
-		return false, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+		return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
 	}
-	for _, reviewer := range pullResp.Reviewers {
-		if *reviewer.Approved {
-			return true, nil
-		}
+	return &pullResp, nil
+}
+
+// GetFileContent returns the contents of the file at path on the branch ref.
+func (b *Client) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return false, nil, err
 	}
-	return false, nil
+	return b.getFile(fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/raw/%s?at=%s", b.BaseURL, projectKey, repo.Name, path, url.QueryEscape("refs/heads/"+ref)))
 }
 
 // PullIsMergeable returns true if the merge request has no conflicts and can be merged.
@@ -264,6 +376,31 @@ func (b *Client) MergePull(pull models.PullRequest) error {
 	return err
 }
 
+// getFile gets the raw file at path. It returns false if the file doesn't
+// exist.
+func (b *Client) getFile(path string) (bool, []byte, error) {
+	req, err := b.prepRequest("GET", path, nil)
+	if err != nil {
+		return false, nil, errors.Wrap(err, "constructing request")
+	}
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return false, nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil, nil
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "reading response from request \"GET %s\"", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, fmt.Errorf("making request \"GET %s\" unexpected status code: %d, body: %s", path, resp.StatusCode, string(respBody))
+	}
+	return true, respBody, nil
+}
+
 // prepRequest adds auth and necessary headers.
 func (b *Client) prepRequest(method string, path string, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequest(method, path, body)
@@ -180,3 +180,77 @@ func TestClient_MergePull(t *testing.T) {
 	})
 	Ok(t, err)
 }
+
+func TestClient_GetApprovers(t *testing.T) {
+	pullRequest, err := ioutil.ReadFile(filepath.Join("testdata", "pull-request-approved.json"))
+	Ok(t, err)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1":
+			w.Write(pullRequest) // nolint: errcheck
+			return
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+	repo := models.Repo{
+		FullName:          "owner/repo",
+		Owner:             "owner",
+		Name:              "repo",
+		SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+		VCSHost: models.VCSHost{
+			Type:     models.BitbucketServer,
+			Hostname: "bitbucket.example.com",
+		},
+	}
+
+	approvers, err := client.GetApprovers(repo, models.PullRequest{Num: 1, BaseRepo: repo})
+	Ok(t, err)
+	Equals(t, []string{"approver"}, approvers)
+}
+
+func TestClient_GetFileContent(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/rest/api/1.0/projects/ow/repos/repo/raw/.bitbucket/CODEOWNERS?at=refs%2Fheads%2Fmaster":
+			w.Write([]byte("* @alice\n")) // nolint: errcheck
+			return
+		case "/rest/api/1.0/projects/ow/repos/repo/raw/CODEOWNERS?at=refs%2Fheads%2Fmaster":
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+	repo := models.Repo{
+		FullName:          "owner/repo",
+		Owner:             "owner",
+		Name:              "repo",
+		SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+		VCSHost: models.VCSHost{
+			Type:     models.BitbucketServer,
+			Hostname: "bitbucket.example.com",
+		},
+	}
+
+	exists, content, err := client.GetFileContent(repo, "master", ".bitbucket/CODEOWNERS")
+	Ok(t, err)
+	Equals(t, true, exists)
+	Equals(t, "* @alice\n", string(content))
+
+	exists, _, err = client.GetFileContent(repo, "master", "CODEOWNERS")
+	Ok(t, err)
+	Equals(t, false, exists)
+}
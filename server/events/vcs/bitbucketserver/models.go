@@ -31,7 +31,16 @@ type PullRequest struct {
 	State     *string `json:"state,omitempty" validate:"required"`
 	Reviewers []struct {
 		Approved *bool `json:"approved,omitempty" validate:"required"`
+		User     *struct {
+			Name *string `json:"name,omitempty"`
+		} `json:"user,omitempty"`
 	} `json:"reviewers,omitempty" validate:"required"`
+	// Author is only set when the pull request comes from the API.
+	Author *struct {
+		User *struct {
+			Name *string `json:"name,omitempty"`
+		} `json:"user,omitempty"`
+	} `json:"author,omitempty"`
 }
 
 type Ref struct {
@@ -55,7 +64,10 @@ type Actor struct {
 }
 
 type Comment struct {
-	Text *string `json:"text,omitempty" validate:"required"`
+	ID *int `json:"id,omitempty"`
+	// Version must be sent when editing a comment so edits aren't lost.
+	Version *int    `json:"version,omitempty"`
+	Text    *string `json:"text,omitempty" validate:"required"`
 }
 
 type Changes struct {
@@ -23,8 +23,36 @@ import (
 type Client interface {
 	GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error)
 	CreateComment(repo models.Repo, pullNum int, comment string) error
+	// CreateCommentWithIDs creates comment like CreateComment and returns the
+	// IDs of the comments it created, in order. There's more than one ID if
+	// comment was too long and had to be split.
+	CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error)
+	// SplitComment splits comment into the parts CreateCommentWithIDs would
+	// post as separate comments because it's too long for a single comment.
+	// Each part can be passed to EditComment without being truncated.
+	SplitComment(repo models.Repo, comment string) []string
+	// EditComment replaces the body of the comment with ID commentID with
+	// comment. If comment is too long for a single comment, it's truncated.
+	EditComment(repo models.Repo, pullNum int, commentID string, comment string) error
+	// HideComment hides the comment with ID commentID so it's not shown on
+	// the pull request by default. Only GitHub supports this, other hosts
+	// return an error.
+	HideComment(repo models.Repo, pullNum int, commentID string) error
 	PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error)
+	// GetApprovers returns the usernames of the users that approved pull.
+	// Only GitHub, GitLab and Bitbucket support this, other hosts return an
+	// error.
+	GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error)
+	// GetFileContent returns the contents of the file at path, relative to
+	// the repo root, on the branch ref of repo. It returns false if the file
+	// doesn't exist. Only GitHub, GitLab and Bitbucket support this, other
+	// hosts return an error.
+	GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error)
 	PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error)
+	// IsTeamMember returns true if user is a member of team. On GitHub team
+	// is {org}/{team slug} and on GitLab it's the full path of a group. Other
+	// hosts return an error.
+	IsTeamMember(repo models.Repo, team string, user models.User) (bool, error)
 	// UpdateStatus updates the commit status to state for pull. src is the
 	// source of this status. This should be relatively static across runs,
 	// ex. atlantis/plan or atlantis/apply.
@@ -35,6 +35,16 @@ func SplitComment(comment string, maxSize int, sepEnd string, sepStart string) [
 	return comments
 }
 
+// TruncateComment returns comment cut down to maxSize chars with sepEnd
+// appended if it's longer than maxSize. It's used when the comment must fit
+// in a single comment, ex. when editing an existing comment.
+func TruncateComment(comment string, maxSize int, sepEnd string) string {
+	if len(comment) <= maxSize {
+		return comment
+	}
+	return comment[:maxSize-len(sepEnd)] + sepEnd
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
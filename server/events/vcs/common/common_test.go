@@ -61,3 +61,13 @@ func TestSplitComment_FourComments(t *testing.T) {
 		sepStart + comment[expMax*2:expMax*3] + sepEnd,
 		sepStart + comment[expMax*3:]}, split)
 }
+
+func TestTruncateComment(t *testing.T) {
+	comment := "comment under max size"
+	Equals(t, comment, common.TruncateComment(comment, len(comment), "-sepEnd"))
+
+	comment = strings.Repeat("a", 100)
+	truncated := common.TruncateComment(comment, 50, "-sepEnd")
+	Equals(t, 50, len(truncated))
+	Equals(t, strings.Repeat("a", 43)+"-sepEnd", truncated)
+}
@@ -0,0 +1,337 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs/common"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// maxCommentLength is the maximum number of chars we put in a single comment.
+// Gitea doesn't have a limit but very long comments make the pull request page
+// slow to load.
+const maxCommentLength = 65536
+
+// pageSize is the number of items we request at a time from paginated APIs.
+const pageSize = 50
+
+type Client struct {
+	HTTPClient *http.Client
+	Token      string
+	// BaseURL is the URL of the Gitea installation, ex. https://gitea.com.
+	BaseURL string
+}
+
+// NewClient builds a Gitea client. Returns an error if the baseURL is
+// malformed. httpClient is the client to use to make the requests, token is
+// an access token of the API user and baseURL is the URL of the Gitea
+// installation including any sub path, ex. https://corp.com/gitea. Don't
+// include the API path, ex. '/api/v1'.
+func NewClient(httpClient *http.Client, token string, baseURL string) (*Client, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", baseURL)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("must have 'http://' or 'https://' in base url %q", baseURL)
+	}
+	return &Client{
+		HTTPClient: httpClient,
+		Token:      token,
+		BaseURL:    strings.TrimRight(parsedURL.String(), "/"),
+	}, nil
+}
+
+// GetModifiedFiles returns the names of files that were modified in the pull request.
+// The names include the path to the file from the repo root, ex. parent/child/file.txt.
+func (c *Client) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	var files []string
+	// We'll only loop 1000 times as a safety measure.
+	maxLoops := 1000
+	for page := 1; page <= maxLoops; page++ {
+		path := fmt.Sprintf("%s/files?page=%d&limit=%d", c.repoURL(repo, "pulls", pull.Num), page, pageSize)
+		resp, err := c.makeRequest("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var changedFiles []ChangedFile
+		if err := json.Unmarshal(resp, &changedFiles); err != nil {
+			return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+		}
+		for _, f := range changedFiles {
+			if err := validator.New().Struct(f); err != nil {
+				return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+			}
+			files = append(files, *f.Filename)
+
+			// If the file was renamed, we'll want to run plan in the directory
+			// it was moved from as well.
+			if f.PreviousFilename != nil && *f.PreviousFilename != "" {
+				files = append(files, *f.PreviousFilename)
+			}
+		}
+		if len(changedFiles) < pageSize {
+			break
+		}
+	}
+
+	// Now ensure all files are unique.
+	hash := make(map[string]bool)
+	var unique []string
+	for _, f := range files {
+		if !hash[f] {
+			unique = append(unique, f)
+			hash[f] = true
+		}
+	}
+	return unique, nil
+}
+
+// CreateComment creates a comment on the pull request. It will write multiple
+// comments if a single comment is too long.
+func (c *Client) CreateComment(repo models.Repo, pullNum int, comment string) error {
+	_, err := c.CreateCommentWithIDs(repo, pullNum, comment)
+	return err
+}
+
+// CreateCommentWithIDs creates comment like CreateComment and returns the IDs
+// of the comments created.
+func (c *Client) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
+	comments := c.SplitComment(repo, comment)
+	var ids []string
+	for _, comm := range comments {
+		id, err := c.postComment(repo, pullNum, comm)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SplitComment splits comment into parts that are under the max comment
+// length.
+func (c *Client) SplitComment(repo models.Repo, comment string) []string {
+	sepEnd := "\n```\n</details>" +
+		"\n<br>\n\n**Warning**: Output length greater than max comment size. Continued in next comment."
+	sepStart := "Continued from previous comment.\n<details><summary>Show Output</summary>\n\n" +
+		"```diff\n"
+	return common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
+}
+
+// postComment actually posts the comment and returns its ID. It's a helper
+// for CreateComment().
+func (c *Client) postComment(repo models.Repo, pullNum int, comment string) (string, error) {
+	bodyBytes, err := json.Marshal(map[string]string{"body": comment})
+	if err != nil {
+		return "", errors.Wrap(err, "json encoding")
+	}
+	// Pull requests are issues in Gitea so we comment on the issue.
+	path := fmt.Sprintf("%s/comments", c.repoURL(repo, "issues", pullNum))
+	resp, err := c.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return "", err
+	}
+	var created Comment
+	if err := json.Unmarshal(resp, &created); err != nil {
+		return "", errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if created.ID == nil {
+		return "", fmt.Errorf("API response %q was missing id", string(resp))
+	}
+	return strconv.Itoa(*created.ID), nil
+}
+
+// EditComment replaces the body of the comment with ID commentID.
+func (c *Client) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	sepEnd := "\n```\n</details>" +
+		"\n<br>\n\n**Warning**: Output length greater than max comment size. Output truncated."
+	bodyBytes, err := json.Marshal(map[string]string{
+		"body": common.TruncateComment(comment, maxCommentLength, sepEnd),
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	// Comments are addressed by their ID alone, not by the issue they're on.
+	path := fmt.Sprintf("%s/api/v1/repos/%s/issues/comments/%s", c.BaseURL, repo.FullName, url.PathEscape(commentID))
+	_, err = c.makeRequest("PATCH", path, bytes.NewBuffer(bodyBytes))
+	return err
+}
+
+// HideComment is not supported by Gitea.
+func (c *Client) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	return errors.New("hiding comments is not supported by Gitea")
+}
+
+// IsTeamMember is not supported by Gitea.
+func (c *Client) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	return false, errors.New("checking team membership is not supported by Gitea")
+}
+
+// GetApprovers is not supported by Gitea.
+func (c *Client) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, errors.New("listing approvers is not supported by Gitea")
+}
+
+// GetFileContent is not supported by Gitea.
+func (c *Client) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	return false, nil, errors.New("getting file contents is not supported by Gitea")
+}
+
+// PullIsApproved returns true if the pull request was approved.
+func (c *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
+	// We'll only loop 1000 times as a safety measure.
+	maxLoops := 1000
+	for page := 1; page <= maxLoops; page++ {
+		path := fmt.Sprintf("%s/reviews?page=%d&limit=%d", c.repoURL(repo, "pulls", pull.Num), page, pageSize)
+		resp, err := c.makeRequest("GET", path, nil)
+		if err != nil {
+			return false, err
+		}
+		var reviews []Review
+		if err := json.Unmarshal(resp, &reviews); err != nil {
+			return false, errors.Wrapf(err, "Could not parse response %q", string(resp))
+		}
+		for _, review := range reviews {
+			if err := validator.New().Struct(review); err != nil {
+				return false, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+			}
+			if *review.State != ApprovedReviewState || review.Dismissed {
+				continue
+			}
+			// Gitea doesn't let authors approve their own pull requests but
+			// we check anyway in case that changes.
+			if review.User != nil && review.User.Login != nil && *review.User.Login == pull.Author {
+				continue
+			}
+			return true, nil
+		}
+		if len(reviews) < pageSize {
+			break
+		}
+	}
+	return false, nil
+}
+
+// PullIsMergeable returns true if the pull request has no conflicts and can be merged.
+func (c *Client) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
+	pullResp, err := c.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, err
+	}
+	return pullResp.Mergeable != nil && *pullResp.Mergeable, nil
+}
+
+// GetPullRequest gets the pull request with id pullNum for the repo.
+func (c *Client) GetPullRequest(repo models.Repo, pullNum int) (*PullRequest, error) {
+	resp, err := c.makeRequest("GET", c.repoURL(repo, "pulls", pullNum), nil)
+	if err != nil {
+		return nil, err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(pullResp); err != nil {
+		return nil, errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+	return &pullResp, nil
+}
+
+// UpdateStatus updates the commit status of the pull request's head commit.
+func (c *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, src string, description string, url string) error {
+	giteaState := "failure"
+	switch status {
+	case models.PendingCommitStatus:
+		giteaState = "pending"
+	case models.SuccessCommitStatus:
+		giteaState = "success"
+	case models.FailedCommitStatus:
+		giteaState = "failure"
+	}
+	bodyBytes, err := json.Marshal(map[string]string{
+		"state":       giteaState,
+		"context":     src,
+		"description": description,
+		"target_url":  url,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/api/v1/repos/%s/statuses/%s", c.BaseURL, repo.FullName, pull.HeadCommit)
+	_, err = c.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
+	return err
+}
+
+// MergePull merges the pull request.
+func (c *Client) MergePull(pull models.PullRequest) error {
+	bodyBytes, err := json.Marshal(map[string]string{
+		"Do":                "merge",
+		"MergeTitleField":   common.AutomergeCommitMsg,
+		"MergeMessageField": "",
+		// Gitea will refuse to merge if commits were pushed after we applied.
+		"head_commit_id": pull.HeadCommit,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/merge", c.repoURL(pull.BaseRepo, "pulls", pull.Num))
+	_, err = c.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
+	return err
+}
+
+// repoURL returns the API URL of the pull request or issue, ex.
+// https://gitea.com/api/v1/repos/owner/repo/pulls/1.
+func (c *Client) repoURL(repo models.Repo, kind string, num int) string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s/%d", c.BaseURL, repo.FullName, kind, num)
+}
+
+// prepRequest adds auth and necessary headers.
+func (c *Client) prepRequest(method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "token "+c.Token)
+	req.Header.Add("Accept", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+func (c *Client) makeRequest(method string, path string, reqBody io.Reader) ([]byte, error) {
+	req, err := c.prepRequest(method, path, reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	requestStr := fmt.Sprintf("%s %s", method, path)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("making request %q unexpected status code: %d, body: %s", requestStr, resp.StatusCode, string(respBody))
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading response from request %q", requestStr)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,325 @@
+package gitea_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs/gitea"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const pullPath = "/api/v1/repos/owner/repo/pulls/1"
+
+var repo = models.Repo{
+	FullName: "owner/repo",
+	Owner:    "owner",
+	Name:     "repo",
+	VCSHost: models.VCSHost{
+		Type:     models.Gitea,
+		Hostname: "gitea.com",
+	},
+}
+
+func newClient(t *testing.T, url string) *gitea.Client {
+	client, err := gitea.NewClient(http.DefaultClient, "token", url)
+	Ok(t, err)
+	return client
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	bytes, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	Ok(t, err)
+	return bytes
+}
+
+func TestNewClient(t *testing.T) {
+	cases := []struct {
+		baseURL string
+		expURL  string
+		expErr  string
+	}{
+		{"https://gitea.com", "https://gitea.com", ""},
+		{"https://corp.com/gitea/", "https://corp.com/gitea", ""},
+		{"http://localhost:3000", "http://localhost:3000", ""},
+		{"gitea.com", "", `must have 'http://' or 'https://' in base url "gitea.com"`},
+	}
+	for _, c := range cases {
+		t.Run(c.baseURL, func(t *testing.T) {
+			client, err := gitea.NewClient(nil, "token", c.baseURL)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expURL, client.BaseURL)
+			Equals(t, http.DefaultClient, client.HTTPClient)
+		})
+	}
+}
+
+// Should follow pagination and include the previous names of renamed files.
+func TestClient_GetModifiedFiles(t *testing.T) {
+	var firstPage []map[string]string
+	for i := 0; i < 50; i++ {
+		firstPage = append(firstPage, map[string]string{"filename": fmt.Sprintf("file%d.txt", i)})
+	}
+	firstResp, err := json.Marshal(firstPage)
+	Ok(t, err)
+	secondResp := `[{"filename":"file0.txt"},{"filename":"dir/new.tf","previous_filename":"dir/old.tf"}]`
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "token token", r.Header.Get("Authorization"))
+		switch r.RequestURI {
+		case pullPath + "/files?page=1&limit=50":
+			w.Write(firstResp) // nolint: errcheck
+		case pullPath + "/files?page=2&limit=50":
+			w.Write([]byte(secondResp)) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	files, err := newClient(t, testServer.URL).GetModifiedFiles(repo, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, 52, len(files))
+	Equals(t, "file0.txt", files[0])
+	Equals(t, []string{"dir/new.tf", "dir/old.tf"}, files[50:])
+}
+
+// Should work with Gitea installed under a sub path.
+func TestClient_CreateComment(t *testing.T) {
+	var body map[string]interface{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/gitea/api/v1/repos/owner/repo/issues/1/comments":
+			Equals(t, "POST", r.Method)
+			Ok(t, json.NewDecoder(r.Body).Decode(&body))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 5, "body": "comment"}`)) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	ids, err := newClient(t, testServer.URL+"/gitea").CreateCommentWithIDs(repo, 1, "comment")
+	Ok(t, err)
+	Equals(t, []string{"5"}, ids)
+	Equals(t, map[string]interface{}{"body": "comment"}, body)
+}
+
+func TestClient_EditComment(t *testing.T) {
+	var body map[string]interface{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/api/v1/repos/owner/repo/issues/comments/5":
+			Equals(t, "PATCH", r.Method)
+			Ok(t, json.NewDecoder(r.Body).Decode(&body))
+			w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	client := newClient(t, testServer.URL)
+	Ok(t, client.EditComment(repo, 1, "5", "edited"))
+	Equals(t, map[string]interface{}{"body": "edited"}, body)
+	ErrEquals(t, "hiding comments is not supported by Gitea", client.HideComment(repo, 1, "5"))
+}
+
+func TestClient_PullIsApproved(t *testing.T) {
+	cases := []struct {
+		description string
+		reviews     string
+		exp         bool
+	}{
+		{
+			"no reviews",
+			`[]`,
+			false,
+		},
+		{
+			"commented",
+			`[{"state":"COMMENT","user":{"login":"reviewer"}}]`,
+			false,
+		},
+		{
+			"changes requested",
+			`[{"state":"REQUEST_CHANGES","user":{"login":"reviewer"}}]`,
+			false,
+		},
+		{
+			"approved",
+			`[{"state":"COMMENT","user":{"login":"reviewer"}},{"state":"APPROVED","user":{"login":"reviewer"}}]`,
+			true,
+		},
+		{
+			"approval dismissed",
+			`[{"state":"APPROVED","user":{"login":"reviewer"},"dismissed":true}]`,
+			false,
+		},
+		{
+			"approved by author",
+			`[{"state":"APPROVED","user":{"login":"author"}}]`,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.RequestURI {
+				case pullPath + "/reviews?page=1&limit=50":
+					w.Write([]byte(c.reviews)) // nolint: errcheck
+				default:
+					t.Errorf("got unexpected request at %q", r.RequestURI)
+					http.Error(w, "not found", http.StatusNotFound)
+				}
+			}))
+			defer testServer.Close()
+
+			approved, err := newClient(t, testServer.URL).PullIsApproved(repo, models.PullRequest{
+				Num:    1,
+				Author: "author",
+			})
+			Ok(t, err)
+			Equals(t, c.exp, approved)
+		})
+	}
+}
+
+func TestClient_PullIsMergeable(t *testing.T) {
+	pullRequest := string(readFixture(t, "pull-request.json"))
+	cases := []struct {
+		mergeable string
+		exp       bool
+	}{
+		{"true", true},
+		{"false", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.mergeable, func(t *testing.T) {
+			resp := strings.Replace(pullRequest, `"mergeable": true`, `"mergeable": `+c.mergeable, 1)
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.RequestURI {
+				case pullPath:
+					w.Write([]byte(resp)) // nolint: errcheck
+				default:
+					t.Errorf("got unexpected request at %q", r.RequestURI)
+					http.Error(w, "not found", http.StatusNotFound)
+				}
+			}))
+			defer testServer.Close()
+
+			mergeable, err := newClient(t, testServer.URL).PullIsMergeable(repo, models.PullRequest{Num: 1})
+			Ok(t, err)
+			Equals(t, c.exp, mergeable)
+		})
+	}
+}
+
+func TestClient_GetPullRequest(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case pullPath:
+			w.Write(readFixture(t, "pull-request.json")) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	pull, err := newClient(t, testServer.URL).GetPullRequest(repo, 1)
+	Ok(t, err)
+	Equals(t, 1, *pull.Number)
+	Equals(t, "author", *pull.User.Login)
+	Equals(t, "b60280bc6e62e2f880f1b63c1e24987664d3bda3", *pull.Head.SHA)
+}
+
+func TestClient_UpdateStatus(t *testing.T) {
+	cases := []struct {
+		status   models.CommitStatus
+		expState string
+	}{
+		{models.PendingCommitStatus, "pending"},
+		{models.SuccessCommitStatus, "success"},
+		{models.FailedCommitStatus, "failure"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expState, func(t *testing.T) {
+			var body map[string]interface{}
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.RequestURI {
+				case "/api/v1/repos/owner/repo/statuses/sha":
+					Equals(t, "POST", r.Method)
+					Ok(t, json.NewDecoder(r.Body).Decode(&body))
+					w.WriteHeader(http.StatusCreated)
+				default:
+					t.Errorf("got unexpected request at %q", r.RequestURI)
+					http.Error(w, "not found", http.StatusNotFound)
+				}
+			}))
+			defer testServer.Close()
+
+			err := newClient(t, testServer.URL).UpdateStatus(repo, models.PullRequest{Num: 1, HeadCommit: "sha"}, c.status, "atlantis/plan", "Plan succeeded", "https://atlantis/jobs/1")
+			Ok(t, err)
+			Equals(t, map[string]interface{}{
+				"state":       c.expState,
+				"context":     "atlantis/plan",
+				"description": "Plan succeeded",
+				"target_url":  "https://atlantis/jobs/1",
+			}, body)
+		})
+	}
+}
+
+// Should merge the pull request at the commit we applied.
+func TestClient_MergePull(t *testing.T) {
+	var body map[string]interface{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case pullPath + "/merge":
+			Equals(t, "POST", r.Method)
+			Ok(t, json.NewDecoder(r.Body).Decode(&body))
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	err := newClient(t, testServer.URL).MergePull(models.PullRequest{
+		Num:        1,
+		HeadCommit: "b60280bc6e62e2f880f1b63c1e24987664d3bda3",
+		BaseRepo:   repo,
+	})
+	Ok(t, err)
+	Equals(t, "merge", body["Do"])
+	Equals(t, "b60280bc6e62e2f880f1b63c1e24987664d3bda3", body["head_commit_id"])
+}
+
+func TestClient_Unauthorized(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"token is required"}`)) // nolint: errcheck
+	}))
+	defer testServer.Close()
+
+	_, err := newClient(t, testServer.URL).PullIsMergeable(repo, models.PullRequest{Num: 1})
+	ErrContains(t, "unexpected status code: 401", err)
+}
@@ -0,0 +1,27 @@
+// Package gitea holds code for Gitea and Forgejo, ex. gitea.com.
+package gitea
+
+// DefaultBaseURL is the base URL of gitea.com.
+const DefaultBaseURL = "https://gitea.com"
+
+// Event types sent in the X-Gitea-Event header. Forgejo sends the same
+// headers.
+const (
+	PullRequestHeader  = "pull_request"
+	IssueCommentHeader = "issue_comment"
+)
+
+// Actions sent with pull request events.
+const (
+	PullOpenedAction       = "opened"
+	PullReopenedAction     = "reopened"
+	PullSynchronizedAction = "synchronized"
+	PullClosedAction       = "closed"
+)
+
+// CommentCreatedAction is the action sent with issue comment events when the
+// comment was created.
+const CommentCreatedAction = "created"
+
+// ApprovedReviewState is the state of reviews that approve the pull request.
+const ApprovedReviewState = "APPROVED"
@@ -0,0 +1,63 @@
+package gitea
+
+type PullRequestEvent struct {
+	Action      *string      `json:"action,omitempty" validate:"required"`
+	PullRequest *PullRequest `json:"pull_request,omitempty" validate:"required"`
+	Sender      *User        `json:"sender,omitempty" validate:"required"`
+}
+
+type IssueCommentEvent struct {
+	Action     *string     `json:"action,omitempty" validate:"required"`
+	Issue      *Issue      `json:"issue,omitempty" validate:"required"`
+	Comment    *Comment    `json:"comment,omitempty" validate:"required"`
+	Repository *Repository `json:"repository,omitempty" validate:"required"`
+	// IsPull is true if the comment is on a pull request rather than an issue.
+	IsPull bool `json:"is_pull"`
+}
+
+type Issue struct {
+	Number *int `json:"number,omitempty" validate:"required"`
+}
+
+type Comment struct {
+	ID   *int    `json:"id,omitempty"`
+	Body *string `json:"body,omitempty" validate:"required"`
+	User *User   `json:"user,omitempty" validate:"required"`
+}
+
+type PullRequest struct {
+	Number    *int    `json:"number,omitempty" validate:"required"`
+	State     *string `json:"state,omitempty" validate:"required"`
+	HTMLURL   *string `json:"html_url,omitempty" validate:"required"`
+	User      *User   `json:"user,omitempty" validate:"required"`
+	Head      *Branch `json:"head,omitempty" validate:"required"`
+	Base      *Branch `json:"base,omitempty" validate:"required"`
+	Mergeable *bool   `json:"mergeable,omitempty"`
+}
+
+type Branch struct {
+	Ref  *string     `json:"ref,omitempty" validate:"required"`
+	SHA  *string     `json:"sha,omitempty" validate:"required"`
+	Repo *Repository `json:"repo,omitempty" validate:"required"`
+}
+
+type Repository struct {
+	FullName *string `json:"full_name,omitempty" validate:"required"`
+	CloneURL *string `json:"clone_url,omitempty" validate:"required"`
+}
+
+type User struct {
+	Login *string `json:"login,omitempty" validate:"required"`
+}
+
+type ChangedFile struct {
+	Filename *string `json:"filename,omitempty" validate:"required"`
+	// PreviousFilename is set if the file was renamed.
+	PreviousFilename *string `json:"previous_filename,omitempty"`
+}
+
+type Review struct {
+	State     *string `json:"state,omitempty" validate:"required"`
+	User      *User   `json:"user,omitempty"`
+	Dismissed bool    `json:"dismissed"`
+}
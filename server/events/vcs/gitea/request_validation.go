@@ -0,0 +1,28 @@
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateSignature returns an error if signature isn't the hex encoded
+// HMAC-SHA256 of payload using secretKey. Gitea sends this in the
+// X-Gitea-Signature header.
+func ValidateSignature(payload []byte, signature string, secretKey []byte) error {
+	if signature == "" {
+		return errors.New("missing signature")
+	}
+	messageMAC, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.Wrapf(err, "decoding signature %q", signature)
+	}
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write(payload) // nolint: errcheck
+	if !hmac.Equal(messageMAC, mac.Sum(nil)) {
+		return errors.New("payload signature check failed")
+	}
+	return nil
+}
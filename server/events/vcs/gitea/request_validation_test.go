@@ -0,0 +1,36 @@
+package gitea_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/vcs/gitea"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// The signature is the hex encoded HMAC-SHA256 of "payload" with key "secret".
+const validSignature = "b82fcb791acec57859b989b430a826488ce2e479fdf92326bd0a2e8375a42ba4"
+
+func TestValidateSignature(t *testing.T) {
+	Ok(t, gitea.ValidateSignature([]byte("payload"), validSignature, []byte("secret")))
+}
+
+func TestValidateSignature_Invalid(t *testing.T) {
+	cases := []struct {
+		description string
+		payload     string
+		signature   string
+		secret      string
+		expErr      string
+	}{
+		{"missing", "payload", "", "secret", "missing signature"},
+		{"wrong secret", "payload", validSignature, "wrong", "payload signature check failed"},
+		{"wrong payload", "wrong", validSignature, "secret", "payload signature check failed"},
+		{"not hex", "payload", "zz", "secret", `decoding signature "zz": encoding/hex: invalid byte: U+007A 'z'`},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := gitea.ValidateSignature([]byte(c.payload), c.signature, []byte(c.secret))
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
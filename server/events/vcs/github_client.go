@@ -16,7 +16,9 @@ package vcs
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/runatlantis/atlantis/server/events/vcs/common"
@@ -30,19 +32,24 @@ import (
 // by GitHub.
 const maxCommentLength = 65536
 
+// outdatedCommentPrefix is prepended to comments that have been hidden to
+// collapse them.
+const outdatedCommentPrefix = "<details><summary>Outdated: superseded by a newer comment.</summary>\n\n"
+
 // GithubClient is used to perform GitHub actions.
 type GithubClient struct {
 	client *github.Client
 	ctx    context.Context
 }
 
-// NewGithubClient returns a valid GitHub client.
-func NewGithubClient(hostname string, user string, pass string) (*GithubClient, error) {
-	tp := github.BasicAuthTransport{
-		Username: strings.TrimSpace(user),
-		Password: strings.TrimSpace(pass),
+// NewGithubClient returns a valid GitHub client that authenticates with
+// credentials.
+func NewGithubClient(hostname string, credentials GithubCredentials) (*GithubClient, error) {
+	transport, err := credentials.Client()
+	if err != nil {
+		return nil, errors.Wrap(err, "building GitHub HTTP client")
 	}
-	client := github.NewClient(tp.Client())
+	client := github.NewClient(transport)
 	// If we're using github.com then we don't need to do any additional configuration
 	// for the client. It we're using Github Enterprise, then we need to manually
 	// set the base url for the API.
@@ -98,19 +105,69 @@ func (g *GithubClient) GetModifiedFiles(repo models.Repo, pull models.PullReques
 // If comment length is greater than the max comment length we split into
 // multiple comments.
 func (g *GithubClient) CreateComment(repo models.Repo, pullNum int, comment string) error {
+	_, err := g.CreateCommentWithIDs(repo, pullNum, comment)
+	return err
+}
+
+// CreateCommentWithIDs creates comment like CreateComment and returns the IDs
+// of the comments created.
+func (g *GithubClient) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
+	comments := g.SplitComment(repo, comment)
+	var ids []string
+	for _, c := range comments {
+		created, _, err := g.client.Issues.CreateComment(g.ctx, repo.Owner, repo.Name, pullNum, &github.IssueComment{Body: &c})
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, strconv.FormatInt(created.GetID(), 10))
+	}
+	return ids, nil
+}
+
+// SplitComment splits comment into parts that are under the max comment
+// length.
+func (g *GithubClient) SplitComment(repo models.Repo, comment string) []string {
 	sepEnd := "\n```\n</details>" +
 		"\n<br>\n\n**Warning**: Output length greater than max comment size. Continued in next comment."
 	sepStart := "Continued from previous comment.\n<details><summary>Show Output</summary>\n\n" +
 		"```diff\n"
+	return common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
+}
 
-	comments := common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
-	for _, c := range comments {
-		_, _, err := g.client.Issues.CreateComment(g.ctx, repo.Owner, repo.Name, pullNum, &github.IssueComment{Body: &c})
-		if err != nil {
-			return err
-		}
+// EditComment replaces the body of the comment with ID commentID.
+func (g *GithubClient) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	id, err := strconv.ParseInt(commentID, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "parsing comment id %q", commentID)
 	}
-	return nil
+	sepEnd := "\n```\n</details>" +
+		"\n<br>\n\n**Warning**: Output length greater than max comment size. Output truncated."
+	body := common.TruncateComment(comment, maxCommentLength, sepEnd)
+	_, _, err = g.client.Issues.EditComment(g.ctx, repo.Owner, repo.Name, id, &github.IssueComment{Body: &body})
+	return err
+}
+
+// HideComment collapses the comment with ID commentID so only a note that
+// it's outdated is shown. The original comment can still be expanded.
+func (g *GithubClient) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	id, err := strconv.ParseInt(commentID, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "parsing comment id %q", commentID)
+	}
+	existing, _, err := g.client.Issues.GetComment(g.ctx, repo.Owner, repo.Name, id)
+	if err != nil {
+		return errors.Wrapf(err, "getting comment %d", id)
+	}
+	if strings.HasPrefix(existing.GetBody(), outdatedCommentPrefix) {
+		return nil
+	}
+	body := outdatedCommentPrefix + existing.GetBody() + "\n</details>"
+	if len(body) > maxCommentLength {
+		// There's no room to collapse the comment so we drop its body.
+		body = outdatedCommentPrefix + "Output too long to keep.\n</details>"
+	}
+	_, _, err = g.client.Issues.EditComment(g.ctx, repo.Owner, repo.Name, id, &github.IssueComment{Body: &body})
+	return err
 }
 
 // PullIsApproved returns true if the pull request was approved.
@@ -127,6 +184,64 @@ func (g *GithubClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	return false, nil
 }
 
+// GetApprovers returns the usernames of the users whose latest review of the
+// pull request is an approval.
+func (g *GithubClient) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	latestStates := make(map[string]string)
+	var reviewers []string
+	opts := github.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := g.client.PullRequests.ListReviews(g.ctx, repo.Owner, repo.Name, pull.Num, &opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting reviews")
+		}
+		for _, review := range reviews {
+			// Comments don't change whether a user approved or requested
+			// changes so we skip them.
+			state := review.GetState()
+			if state == "COMMENTED" || state == "PENDING" {
+				continue
+			}
+			login := review.GetUser().GetLogin()
+			if _, ok := latestStates[login]; !ok {
+				reviewers = append(reviewers, login)
+			}
+			latestStates[login] = state
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	var approvers []string
+	for _, login := range reviewers {
+		if latestStates[login] == "APPROVED" {
+			approvers = append(approvers, login)
+		}
+	}
+	return approvers, nil
+}
+
+// GetFileContent returns the contents of the file at path on the branch ref.
+func (g *GithubClient) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	file, _, resp, err := g.client.Repositories.GetContents(g.ctx, repo.Owner, repo.Name, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "getting %s", path)
+	}
+	// If path is a directory then file is nil.
+	if file == nil {
+		return false, nil, nil
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "decoding %s", path)
+	}
+	return true, []byte(content), nil
+}
+
 // PullIsMergeable returns true if the pull request is mergeable.
 func (g *GithubClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	githubPR, err := g.GetPullRequest(repo, pull.Num)
@@ -149,6 +264,55 @@ func (g *GithubClient) PullIsMergeable(repo models.Repo, pull models.PullRequest
 	return true, nil
 }
 
+// IsTeamMember returns true if user is an active member of team. team is
+// {org}/{team slug}, ex. runatlantis/maintainers.
+func (g *GithubClient) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	split := strings.SplitN(team, "/", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return false, fmt.Errorf("invalid team %q, must be in the format {org}/{team slug}", team)
+	}
+	org, slug := split[0], split[1]
+
+	// The team endpoints aren't under the repo so with GitHub App credentials
+	// we need to say which installation to use.
+	ctx := withGithubAppRepo(g.ctx, repo.Owner, repo.Name)
+
+	// There's no endpoint to get a team by its slug so we have to find its ID
+	// by listing the org's teams.
+	var teamID int64
+	found := false
+	opts := github.ListOptions{PerPage: 100}
+	for !found {
+		teams, resp, err := g.client.Teams.ListTeams(ctx, org, &opts)
+		if err != nil {
+			return false, errors.Wrapf(err, "listing teams in %s", org)
+		}
+		for _, t := range teams {
+			if strings.EqualFold(t.GetSlug(), slug) {
+				teamID = t.GetID()
+				found = true
+				break
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if !found {
+		return false, fmt.Errorf("team %q not found", team)
+	}
+
+	membership, resp, err := g.client.Teams.GetTeamMembership(ctx, teamID, user.Username)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting membership of %s in %s", user.Username, team)
+	}
+	return membership.GetState() == "active", nil
+}
+
 // GetPullRequest returns the pull request.
 func (g *GithubClient) GetPullRequest(repo models.Repo, num int) (*github.PullRequest, error) {
 	pull, _, err := g.client.PullRequests.Get(g.ctx, repo.Owner, repo.Name, num)
@@ -178,6 +342,36 @@ func (g *GithubClient) UpdateStatus(repo models.Repo, pull models.PullRequest, s
 	return err
 }
 
+// UpsertCheckRun updates the check run called opts.Name on the head commit of
+// pull or creates it if it doesn't exist. Only GitHub Apps can create check
+// runs.
+// See https://developer.github.com/v3/checks/runs/.
+func (g *GithubClient) UpsertCheckRun(repo models.Repo, pull models.PullRequest, opts github.UpdateCheckRunOptions) error {
+	existing, _, err := g.client.Checks.ListCheckRunsForRef(g.ctx, repo.Owner, repo.Name, pull.HeadCommit, &github.ListCheckRunsOptions{
+		CheckName: github.String(opts.Name),
+	})
+	if err != nil {
+		return errors.Wrap(err, "listing check runs")
+	}
+	if len(existing.CheckRuns) > 0 {
+		_, _, err = g.client.Checks.UpdateCheckRun(g.ctx, repo.Owner, repo.Name, existing.CheckRuns[0].GetID(), opts)
+		return errors.Wrap(err, "updating check run")
+	}
+	_, _, err = g.client.Checks.CreateCheckRun(g.ctx, repo.Owner, repo.Name, github.CreateCheckRunOptions{
+		Name:        opts.Name,
+		HeadBranch:  pull.HeadBranch,
+		HeadSHA:     pull.HeadCommit,
+		DetailsURL:  opts.DetailsURL,
+		ExternalID:  opts.ExternalID,
+		Status:      opts.Status,
+		Conclusion:  opts.Conclusion,
+		CompletedAt: opts.CompletedAt,
+		Output:      opts.Output,
+		Actions:     opts.Actions,
+	})
+	return errors.Wrap(err, "creating check run")
+}
+
 // MergePull merges the pull request.
 func (g *GithubClient) MergePull(pull models.PullRequest) error {
 	// Users can set their repo to disallow certain types of merging.
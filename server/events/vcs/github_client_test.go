@@ -1,6 +1,8 @@
 package vcs_test
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -10,7 +12,9 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-github/github"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	. "github.com/runatlantis/atlantis/testing"
@@ -57,7 +61,7 @@ func TestGithubClient_GetModifiedFiles(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -112,7 +116,7 @@ func TestGithubClient_GetModifiedFilesMovedFile(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -173,7 +177,7 @@ func TestGithubClient_UpdateStatus(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -195,6 +199,116 @@ func TestGithubClient_UpdateStatus(t *testing.T) {
 	}
 }
 
+// HideComment should collapse the comment's body unless it's already hidden.
+func TestGithubClient_HideComment(t *testing.T) {
+	body := "plan output"
+	var edits []string
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "GET" && r.RequestURI == "/api/v3/repos/owner/repo/issues/comments/5":
+				resp, err := json.Marshal(map[string]interface{}{"id": 5, "body": body})
+				Ok(t, err)
+				w.Write(resp) // nolint: errcheck
+			case r.Method == "PATCH" && r.RequestURI == "/api/v3/repos/owner/repo/issues/comments/5":
+				var comment github.IssueComment
+				Ok(t, json.NewDecoder(r.Body).Decode(&comment))
+				body = comment.GetBody()
+				edits = append(edits, body)
+				w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	Ok(t, client.HideComment(repo, 1, "5"))
+	Ok(t, client.HideComment(repo, 1, "5"))
+	Equals(t, []string{"<details><summary>Outdated: superseded by a newer comment.</summary>\n\nplan output\n</details>"}, edits)
+
+	Ok(t, client.EditComment(repo, 1, "5", "new output"))
+	Equals(t, "new output", body)
+	ErrContains(t, `parsing comment id "abc"`, client.EditComment(repo, 1, "abc", "new output"))
+}
+
+// UpsertCheckRun should update the check run if it exists and create it
+// otherwise.
+func TestGithubClient_UpsertCheckRun(t *testing.T) {
+	cases := []struct {
+		description string
+		listResp    string
+		expMethod   string
+		expURI      string
+		expBody     string
+	}{
+		{
+			"exists",
+			`{"total_count": 1, "check_runs": [{"id": 4}]}`,
+			"PATCH",
+			"/api/v3/repos/owner/repo/check-runs/4",
+			`{"name":"atlantis/plan: dir/default","status":"in_progress"}`,
+		},
+		{
+			"does not exist",
+			`{"total_count": 0, "check_runs": []}`,
+			"POST",
+			"/api/v3/repos/owner/repo/check-runs",
+			`{"name":"atlantis/plan: dir/default","head_branch":"branch","head_sha":"sha","status":"in_progress"}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			called := false
+			testServer := httptest.NewTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.RequestURI {
+					case "/api/v3/repos/owner/repo/commits/sha/check-runs?check_name=atlantis%2Fplan%3A+dir%2Fdefault":
+						w.Write([]byte(c.listResp)) // nolint: errcheck
+					case c.expURI:
+						Equals(t, c.expMethod, r.Method)
+						body, err := ioutil.ReadAll(r.Body)
+						Ok(t, err)
+						Equals(t, c.expBody+"\n", string(body))
+						called = true
+						w.Write([]byte(`{"id": 4}`)) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request at %q", r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+
+			testServerURL, err := url.Parse(testServer.URL)
+			Ok(t, err)
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
+			Ok(t, err)
+			defer disableSSLVerification()()
+
+			err = client.UpsertCheckRun(models.Repo{
+				FullName: "owner/repo",
+				Owner:    "owner",
+				Name:     "repo",
+			}, models.PullRequest{
+				Num:        1,
+				HeadBranch: "branch",
+				HeadCommit: "sha",
+			}, github.UpdateCheckRunOptions{
+				Name:   "atlantis/plan: dir/default",
+				Status: github.String("in_progress"),
+			})
+			Ok(t, err)
+			Assert(t, called, "exp check run to be upserted")
+		})
+	}
+}
+
 func TestGithubClient_PullIsMergeable(t *testing.T) {
 	cases := []struct {
 		state        string
@@ -265,7 +379,7 @@ func TestGithubClient_PullIsMergeable(t *testing.T) {
 				}))
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -347,7 +461,7 @@ func TestGithubClient_MergePullHandlesError(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -470,7 +584,7 @@ func TestGithubClient_MergePullCorrectMethod(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -504,3 +618,158 @@ func disableSSLVerification() func() {
 		http.DefaultTransport.(*http.Transport).TLSClientConfig = orig
 	}
 }
+
+func TestGithubClient_IsTeamMember(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/orgs/owner/teams?per_page=100":
+				w.Write([]byte(`[{"id": 1, "slug": "devs"}, {"id": 2, "slug": "admins"}]`)) // nolint: errcheck
+			case "/api/v3/teams/2/memberships/alice":
+				w.Write([]byte(`{"state": "active"}`)) // nolint: errcheck
+			case "/api/v3/teams/2/memberships/bob":
+				w.Write([]byte(`{"state": "pending"}`)) // nolint: errcheck
+			case "/api/v3/teams/2/memberships/carol":
+				http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	cases := []struct {
+		team      string
+		user      string
+		expMember bool
+		expErr    string
+	}{
+		{"owner/admins", "alice", true, ""},
+		{"owner/admins", "bob", false, ""},
+		{"owner/admins", "carol", false, ""},
+		{"owner/other", "alice", false, `team "owner/other" not found`},
+		{"admins", "alice", false, `invalid team "admins", must be in the format {org}/{team slug}`},
+	}
+	for _, c := range cases {
+		t.Run(c.team+" "+c.user, func(t *testing.T) {
+			member, err := client.IsTeamMember(repo, c.team, models.User{Username: c.user})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expMember, member)
+		})
+	}
+}
+
+// With GitHub App credentials the team endpoints should use the token of the
+// installation that owns the repo even though they aren't under /repos.
+func TestGithubClient_IsTeamMemberGithubApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Ok(t, err)
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/installation":
+				w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+			case "/api/v3/app/installations/5/access_tokens":
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"token": "installation-token", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+			case "/api/v3/orgs/owner/teams?per_page=100":
+				Equals(t, "token installation-token", r.Header.Get("Authorization"))
+				w.Write([]byte(`[{"id": 2, "slug": "admins"}]`)) // nolint: errcheck
+			case "/api/v3/teams/2/memberships/alice":
+				Equals(t, "token installation-token", r.Header.Get("Authorization"))
+				w.Write([]byte(`{"state": "active"}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	creds := &vcs.GithubAppCredentials{AppID: 1, Key: key, APIURL: testServer.URL + "/api/v3"}
+	client, err := vcs.NewGithubClient(testServerURL.Host, creds)
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	member, err := client.IsTeamMember(models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}, "owner/admins", models.User{Username: "alice"})
+	Ok(t, err)
+	Equals(t, true, member)
+}
+
+func TestGithubClient_GetApprovers(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/pulls/1/reviews?per_page=100":
+				w.Header().Set("Link", `<https://api.github.com/repos/owner/repo/pulls/1/reviews?page=2&per_page=100>; rel="next"`)
+				w.Write([]byte(`[
+					{"user": {"login": "alice"}, "state": "APPROVED"},
+					{"user": {"login": "bob"}, "state": "APPROVED"},
+					{"user": {"login": "carol"}, "state": "CHANGES_REQUESTED"}
+				]`)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/pulls/1/reviews?page=2&per_page=100":
+				w.Write([]byte(`[
+					{"user": {"login": "alice"}, "state": "COMMENTED"},
+					{"user": {"login": "bob"}, "state": "CHANGES_REQUESTED"},
+					{"user": {"login": "carol"}, "state": "APPROVED"},
+					{"user": {"login": "dave"}, "state": "DISMISSED"}
+				]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	approvers, err := client.GetApprovers(repo, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, []string{"alice", "carol"}, approvers)
+}
+
+func TestGithubClient_GetFileContent(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/contents/.github/CODEOWNERS?ref=master":
+				// "* @alice\n" base64 encoded.
+				w.Write([]byte(`{"type": "file", "encoding": "base64", "content": "KiBAYWxpY2UK"}`)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/contents/CODEOWNERS?ref=master":
+				http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"})
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	exists, content, err := client.GetFileContent(repo, "master", ".github/CODEOWNERS")
+	Ok(t, err)
+	Equals(t, true, exists)
+	Equals(t, "* @alice\n", string(content))
+
+	exists, _, err = client.GetFileContent(repo, "master", "CODEOWNERS")
+	Ok(t, err)
+	Equals(t, false, exists)
+}
@@ -0,0 +1,350 @@
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// githubAppAccept is the Accept header required by the GitHub App endpoints
+// while they're in preview.
+const githubAppAccept = "application/vnd.github.machine-man-preview+json"
+
+// githubAppTokenUser is the username used with installation tokens when
+// cloning over HTTPS.
+const githubAppTokenUser = "x-access-token"
+
+// installationTokenRefreshWindow is how long before an installation token
+// expires that we'll request a new one. Tokens are valid for an hour and
+// we don't want a token to expire in the middle of a clone.
+const installationTokenRefreshWindow = 10 * time.Minute
+
+// GithubCredentials provides the credentials used to talk to GitHub, both
+// for API calls and when cloning repos.
+type GithubCredentials interface {
+	// Client returns an HTTP client that authenticates its requests.
+	Client() (*http.Client, error)
+	// GetUser returns the username to use when cloning.
+	GetUser() string
+	// GetToken returns the token to use when cloning owner/repo.
+	GetToken(owner string, repo string) (string, error)
+}
+
+// GithubUserCredentials authenticates as a GitHub user with a personal
+// access token.
+type GithubUserCredentials struct {
+	User  string
+	Token string
+}
+
+// Client returns a client that uses basic auth.
+func (c *GithubUserCredentials) Client() (*http.Client, error) {
+	tr := &github.BasicAuthTransport{
+		Username: strings.TrimSpace(c.User),
+		Password: strings.TrimSpace(c.Token),
+	}
+	return tr.Client(), nil
+}
+
+// GetUser returns the username.
+func (c *GithubUserCredentials) GetUser() string {
+	return c.User
+}
+
+// GetToken returns the token. It's the same for every repo.
+func (c *GithubUserCredentials) GetToken(owner string, repo string) (string, error) {
+	return c.Token, nil
+}
+
+// GithubAppCredentials authenticates as a GitHub App. It mints JWTs signed
+// with the app's private key and exchanges them for installation tokens
+// which are refreshed before they expire.
+type GithubAppCredentials struct {
+	AppID int64
+	Key   *rsa.PrivateKey
+	// APIURL is the base URL of the GitHub API without a trailing slash,
+	// ex. https://api.github.com.
+	APIURL string
+	// HTTPClient is used to make the JWT authenticated requests. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	mutex sync.Mutex
+	// installations maps repo owners to the ID of the app's installation
+	// for that owner.
+	installations map[string]int64
+	// tokens maps installation IDs to their most recent token.
+	tokens map[int64]installationToken
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewGithubAppCredentials reads the PEM encoded private key at keyFile and
+// returns credentials for the app with id appID on hostname, ex. github.com.
+func NewGithubAppCredentials(appID int64, keyFile string, hostname string) (*GithubAppCredentials, error) {
+	keyBytes, err := ioutil.ReadFile(keyFile) // nolint: gosec
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading GitHub App private key %q", keyFile)
+	}
+	key, err := parseGithubAppKey(keyBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing GitHub App private key %q", keyFile)
+	}
+	return &GithubAppCredentials{
+		AppID:  appID,
+		Key:    key,
+		APIURL: GithubAPIURL(hostname),
+	}, nil
+}
+
+// GithubAPIURL returns the base URL of the API for hostname without a
+// trailing slash. GitHub Enterprise serves its API under /api/v3.
+func GithubAPIURL(hostname string) string {
+	if hostname == "github.com" {
+		return "https://api.github.com"
+	}
+	return fmt.Sprintf("https://%s/api/v3", hostname)
+}
+
+// Client returns a client whose requests use the token of the installation
+// for the repo being requested.
+func (c *GithubAppCredentials) Client() (*http.Client, error) {
+	return &http.Client{
+		Transport: &githubAppTransport{
+			creds:     c,
+			transport: http.DefaultTransport,
+		},
+	}, nil
+}
+
+// GetUser returns the username GitHub expects with installation tokens.
+func (c *GithubAppCredentials) GetUser() string {
+	return githubAppTokenUser
+}
+
+// GetToken returns an installation token that has access to owner/repo.
+func (c *GithubAppCredentials) GetToken(owner string, repo string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	installationID, err := c.installationID(owner, repo)
+	if err != nil {
+		return "", err
+	}
+	if tok, ok := c.tokens[installationID]; ok && time.Until(tok.ExpiresAt) > installationTokenRefreshWindow {
+		return tok.Token, nil
+	}
+
+	resp, err := c.appRequest("POST", fmt.Sprintf("/app/installations/%d/access_tokens", installationID))
+	if err != nil {
+		return "", errors.Wrapf(err, "creating token for installation %d", installationID)
+	}
+	var tok installationToken
+	if err := json.Unmarshal(resp, &tok); err != nil {
+		return "", errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	if tok.Token == "" {
+		return "", fmt.Errorf("response %q was missing token", string(resp))
+	}
+	if c.tokens == nil {
+		c.tokens = make(map[int64]installationToken)
+	}
+	c.tokens[installationID] = tok
+	return tok.Token, nil
+}
+
+// Slug returns the app's slug. Comments are made by the user "<slug>[bot]".
+func (c *GithubAppCredentials) Slug() (string, error) {
+	resp, err := c.appRequest("GET", "/app")
+	if err != nil {
+		return "", errors.Wrap(err, "getting app")
+	}
+	var app struct {
+		Slug string `json:"slug"`
+	}
+	if err := json.Unmarshal(resp, &app); err != nil {
+		return "", errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	if app.Slug == "" {
+		return "", fmt.Errorf("response %q was missing slug", string(resp))
+	}
+	return app.Slug, nil
+}
+
+// installationID returns the ID of the app's installation for owner. It must
+// be called with the mutex held.
+func (c *GithubAppCredentials) installationID(owner string, repo string) (int64, error) {
+	if id, ok := c.installations[owner]; ok {
+		return id, nil
+	}
+	resp, err := c.appRequest("GET", fmt.Sprintf("/repos/%s/%s/installation", owner, repo))
+	if err != nil {
+		return 0, errors.Wrapf(err, "getting installation for %s/%s", owner, repo)
+	}
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &installation); err != nil {
+		return 0, errors.Wrapf(err, "parsing response %q", string(resp))
+	}
+	if installation.ID == 0 {
+		return 0, fmt.Errorf("response %q was missing id", string(resp))
+	}
+	if c.installations == nil {
+		c.installations = make(map[string]int64)
+	}
+	c.installations[owner] = installation.ID
+	return installation.ID, nil
+}
+
+// appRequest makes a request authenticated as the app itself.
+func (c *GithubAppCredentials) appRequest(method string, path string) ([]byte, error) {
+	jwt, err := c.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, c.APIURL+path, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing request")
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", githubAppAccept)
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading response from request %q", method+" "+path)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("making request %q unexpected status code: %d, body: %s", method+" "+path, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// jwt returns a JWT identifying the app signed with RS256. GitHub rejects
+// JWTs valid for more than ten minutes so we stay under that and backdate
+// the issued at time to allow for clock drift.
+func (c *GithubAppCredentials) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": c.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.Key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", errors.Wrap(err, "signing JWT")
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parseGithubAppKey parses a PEM encoded RSA private key. GitHub generates
+// PKCS1 keys but we also accept PKCS8 in case the key was converted.
+func parseGithubAppKey(keyBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(keyBytes))
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.New("key is not a PKCS1 or PKCS8 private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("key is not an RSA private key")
+	}
+	return key, nil
+}
+
+// githubAppTransport adds the token of the installation that owns the repo
+// being requested to each request.
+type githubAppTransport struct {
+	creds     *GithubAppCredentials
+	transport http.RoundTripper
+}
+
+// githubAppRepoKey is the context key for the repo whose installation should
+// be used for requests outside of /repos/{owner}/{repo}.
+type githubAppRepoKey struct{}
+
+// withGithubAppRepo returns a context that makes requests to endpoints that
+// aren't scoped to a repo, ex. /orgs/{org}/teams, use the token of the
+// installation that owns owner/repo.
+func withGithubAppRepo(ctx context.Context, owner string, repo string) context.Context {
+	return context.WithValue(ctx, githubAppRepoKey{}, [2]string{owner, repo})
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *githubAppTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	owner, repo, ok := repoFromPath(req.URL.Path)
+	if !ok {
+		if r, isSet := req.Context().Value(githubAppRepoKey{}).([2]string); isSet {
+			owner, repo, ok = r[0], r[1], true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("could not determine the repo of request %q to find its GitHub App installation", req.URL.Path)
+	}
+	token, err := t.creds.GetToken(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	// RoundTrippers must not modify the request.
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		req2.Header[k] = append([]string(nil), v...)
+	}
+	req2.Header.Set("Authorization", "token "+token)
+	return t.transport.RoundTrip(req2)
+}
+
+// repoFromPath returns the owner and repo from an API path containing
+// /repos/{owner}/{repo}.
+func repoFromPath(path string) (string, string, bool) {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if p == "repos" && i+2 < len(parts) && parts[i+1] != "" && parts[i+2] != "" {
+			return parts[i+1], parts[i+2], true
+		}
+	}
+	return "", "", false
+}
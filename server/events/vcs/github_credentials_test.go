@@ -0,0 +1,203 @@
+package vcs_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGithubUserCredentials(t *testing.T) {
+	creds := &vcs.GithubUserCredentials{User: "user", Token: "token"}
+	Equals(t, "user", creds.GetUser())
+	token, err := creds.GetToken("owner", "repo")
+	Ok(t, err)
+	Equals(t, "token", token)
+}
+
+func TestNewGithubAppCredentials(t *testing.T) {
+	key := githubAppKey(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	keyFile := filepath.Join(tmp, "key.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	Ok(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+
+	creds, err := vcs.NewGithubAppCredentials(1, keyFile, "github.com")
+	Ok(t, err)
+	Equals(t, int64(1), creds.AppID)
+	Equals(t, "https://api.github.com", creds.APIURL)
+	Equals(t, key.D, creds.Key.D)
+	Equals(t, "x-access-token", creds.GetUser())
+
+	creds, err = vcs.NewGithubAppCredentials(1, keyFile, "ghe.corp")
+	Ok(t, err)
+	Equals(t, "https://ghe.corp/api/v3", creds.APIURL)
+}
+
+func TestNewGithubAppCredentials_InvalidKey(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	keyFile := filepath.Join(tmp, "key.pem")
+	Ok(t, ioutil.WriteFile(keyFile, []byte("not a key"), 0600))
+
+	_, err := vcs.NewGithubAppCredentials(1, keyFile, "github.com")
+	ErrEquals(t, fmt.Sprintf("parsing GitHub App private key %q: no PEM data found", keyFile), err)
+
+	_, err = vcs.NewGithubAppCredentials(1, filepath.Join(tmp, "missing.pem"), "github.com")
+	Assert(t, err != nil, "exp error")
+	Assert(t, os.IsNotExist(errors.Cause(err)), "exp not exist error, got %s", err)
+}
+
+// Tokens should be cached until they're about to expire.
+func TestGithubAppCredentials_GetToken(t *testing.T) {
+	key := githubAppKey(t)
+	fake := newFakeGithubApp(t, key, time.Hour)
+	defer fake.server.Close()
+	creds := &vcs.GithubAppCredentials{AppID: 1, Key: key, APIURL: fake.server.URL}
+
+	token, err := creds.GetToken("owner", "repo")
+	Ok(t, err)
+	Equals(t, "token-1", token)
+	token, err = creds.GetToken("owner", "other-repo")
+	Ok(t, err)
+	Equals(t, "token-1", token)
+	Equals(t, 1, fake.installationCalls)
+	Equals(t, 1, fake.tokenCalls)
+}
+
+func TestGithubAppCredentials_GetTokenRefreshes(t *testing.T) {
+	key := githubAppKey(t)
+	fake := newFakeGithubApp(t, key, 5*time.Minute)
+	defer fake.server.Close()
+	creds := &vcs.GithubAppCredentials{AppID: 1, Key: key, APIURL: fake.server.URL}
+
+	token, err := creds.GetToken("owner", "repo")
+	Ok(t, err)
+	Equals(t, "token-1", token)
+	token, err = creds.GetToken("owner", "repo")
+	Ok(t, err)
+	Equals(t, "token-2", token)
+	Equals(t, 1, fake.installationCalls)
+	Equals(t, 2, fake.tokenCalls)
+}
+
+func TestGithubAppCredentials_GetTokenNotInstalled(t *testing.T) {
+	key := githubAppKey(t)
+	fake := newFakeGithubApp(t, key, time.Hour)
+	defer fake.server.Close()
+	creds := &vcs.GithubAppCredentials{AppID: 1, Key: key, APIURL: fake.server.URL}
+
+	_, err := creds.GetToken("other-owner", "repo")
+	ErrContains(t, `getting installation for other-owner/repo: making request "GET /repos/other-owner/repo/installation" unexpected status code: 404`, err)
+}
+
+func TestGithubAppCredentials_Client(t *testing.T) {
+	key := githubAppKey(t)
+	fake := newFakeGithubApp(t, key, time.Hour)
+	defer fake.server.Close()
+	creds := &vcs.GithubAppCredentials{AppID: 1, Key: key, APIURL: fake.server.URL}
+
+	client, err := creds.Client()
+	Ok(t, err)
+	resp, err := client.Get(fake.server.URL + "/repos/owner/repo/pulls/1")
+	Ok(t, err)
+	defer resp.Body.Close() // nolint: errcheck
+	Equals(t, http.StatusOK, resp.StatusCode)
+	Equals(t, "token token-1", fake.lastAuth)
+
+	_, err = client.Get(fake.server.URL + "/user")
+	ErrContains(t, `could not determine the repo of request "/user"`, err)
+}
+
+func TestGithubAppCredentials_Slug(t *testing.T) {
+	key := githubAppKey(t)
+	fake := newFakeGithubApp(t, key, time.Hour)
+	defer fake.server.Close()
+	creds := &vcs.GithubAppCredentials{AppID: 1, Key: key, APIURL: fake.server.URL}
+
+	slug, err := creds.Slug()
+	Ok(t, err)
+	Equals(t, "atlantis-app", slug)
+}
+
+// fakeGithubApp fakes the GitHub API endpoints used by GitHub Apps. The app is
+// only installed on the "owner" account.
+type fakeGithubApp struct {
+	server            *httptest.Server
+	installationCalls int
+	tokenCalls        int
+	lastAuth          string
+}
+
+func newFakeGithubApp(t *testing.T, key *rsa.PrivateKey, tokenTTL time.Duration) *fakeGithubApp {
+	f := &fakeGithubApp{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.RequestURI == "/repos/owner/repo/installation":
+			verifyJWT(t, key, r)
+			f.installationCalls++
+			w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+		case r.Method == "POST" && r.RequestURI == "/app/installations/5/access_tokens":
+			verifyJWT(t, key, r)
+			f.tokenCalls++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": "token-%d", "expires_at": %q}`, f.tokenCalls, time.Now().Add(tokenTTL).Format(time.RFC3339))
+		case r.Method == "GET" && r.RequestURI == "/app":
+			verifyJWT(t, key, r)
+			w.Write([]byte(`{"id": 1, "slug": "atlantis-app"}`)) // nolint: errcheck
+		case r.Method == "GET" && r.RequestURI == "/repos/owner/repo/pulls/1":
+			f.lastAuth = r.Header.Get("Authorization")
+			w.Write([]byte(`{}`)) // nolint: errcheck
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	return f
+}
+
+// verifyJWT checks that the request is authenticated with a JWT for app 1
+// signed by key.
+func verifyJWT(t *testing.T, key *rsa.PrivateKey, r *http.Request) {
+	t.Helper()
+	Equals(t, "application/vnd.github.machine-man-preview+json", r.Header.Get("Accept"))
+	auth := r.Header.Get("Authorization")
+	Assert(t, strings.HasPrefix(auth, "Bearer "), "exp Bearer auth, got %q", auth)
+	parts := strings.Split(strings.TrimPrefix(auth, "Bearer "), ".")
+	Equals(t, 3, len(parts))
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	Ok(t, err)
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	Ok(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], sig))
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	Ok(t, err)
+	var claims map[string]int64
+	Ok(t, json.Unmarshal(claimsJSON, &claims))
+	Equals(t, int64(1), claims["iss"])
+	Assert(t, claims["exp"]-claims["iat"] <= 10*60, "exp JWT to be valid for at most 10 minutes")
+}
+
+func githubAppKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Ok(t, err)
+	return key
+}
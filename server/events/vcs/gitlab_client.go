@@ -16,7 +16,9 @@ package vcs
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/runatlantis/atlantis/server/events/vcs/common"
@@ -135,10 +137,41 @@ func (g *GitlabClient) GetModifiedFiles(repo models.Repo, pull models.PullReques
 
 // CreateComment creates a comment on the merge request.
 func (g *GitlabClient) CreateComment(repo models.Repo, pullNum int, comment string) error {
-	_, _, err := g.Client.Notes.CreateMergeRequestNote(repo.FullName, pullNum, &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.String(comment)})
+	_, err := g.CreateCommentWithIDs(repo, pullNum, comment)
 	return err
 }
 
+// CreateCommentWithIDs creates a comment on the merge request and returns its
+// ID. GitLab doesn't limit the length of comments so we never split them.
+func (g *GitlabClient) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
+	note, _, err := g.Client.Notes.CreateMergeRequestNote(repo.FullName, pullNum, &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.String(comment)})
+	if err != nil {
+		return nil, err
+	}
+	return []string{strconv.Itoa(note.ID)}, nil
+}
+
+// SplitComment never splits comment because GitLab doesn't limit the length
+// of comments.
+func (g *GitlabClient) SplitComment(repo models.Repo, comment string) []string {
+	return []string{comment}
+}
+
+// EditComment replaces the body of the comment with ID commentID.
+func (g *GitlabClient) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	id, err := strconv.Atoi(commentID)
+	if err != nil {
+		return errors.Wrapf(err, "parsing comment id %q", commentID)
+	}
+	_, _, err = g.Client.Notes.UpdateMergeRequestNote(repo.FullName, pullNum, id, &gitlab.UpdateMergeRequestNoteOptions{Body: gitlab.String(comment)})
+	return err
+}
+
+// HideComment is not supported by GitLab.
+func (g *GitlabClient) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	return errors.New("hiding comments is not supported by GitLab")
+}
+
 // PullIsApproved returns true if the merge request was approved.
 func (g *GitlabClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
 	approvals, _, err := g.Client.MergeRequests.GetMergeRequestApprovals(repo.FullName, pull.Num)
@@ -151,6 +184,32 @@ func (g *GitlabClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	return true, nil
 }
 
+// GetApprovers returns the usernames of the users that approved the merge
+// request.
+func (g *GitlabClient) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	approvals, _, err := g.Client.MergeRequests.GetMergeRequestApprovals(repo.FullName, pull.Num)
+	if err != nil {
+		return nil, err
+	}
+	var approvers []string
+	for _, a := range approvals.ApprovedBy {
+		approvers = append(approvers, a.User.Username)
+	}
+	return approvers, nil
+}
+
+// GetFileContent returns the contents of the file at path on the branch ref.
+func (g *GitlabClient) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	content, resp, err := g.Client.RepositoryFiles.GetRawFile(repo.FullName, path, &gitlab.GetRawFileOptions{Ref: gitlab.String(ref)})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	return true, content, nil
+}
+
 // PullIsMergeable returns true if the merge request can be merged.
 // In GitLab, there isn't a single field that tells us if the pull request is
 // mergeable so for now we check the merge_status and approvals_before_merge
@@ -175,6 +234,37 @@ func (g *GitlabClient) PullIsMergeable(repo models.Repo, pull models.PullRequest
 	return false, nil
 }
 
+// IsTeamMember returns true if user is a direct member of the group whose
+// full path is team, ex. owner/subgroup.
+func (g *GitlabClient) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	const maxPerPage = 100
+	nextPage := 1
+	for {
+		members, resp, err := g.Client.Groups.ListGroupMembers(team, &gitlab.ListGroupMembersOptions{
+			ListOptions: gitlab.ListOptions{
+				Page:    nextPage,
+				PerPage: maxPerPage,
+			},
+			Query: gitlab.String(user.Username),
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "listing members of group %s", team)
+		}
+		for _, m := range members {
+			// The query also matches partial usernames and names so we
+			// need to check for an exact match.
+			if m.Username == user.Username {
+				return true, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return false, nil
+}
+
 // UpdateStatus updates the build status of a commit.
 func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
 	gitlabState := gitlab.Failed
@@ -200,6 +290,12 @@ func (g *GitlabClient) GetMergeRequest(repoFullName string, pullNum int) (*gitla
 	return mr, err
 }
 
+// GetProject gets the project with id projectID.
+func (g *GitlabClient) GetProject(projectID int) (*gitlab.Project, error) {
+	project, _, err := g.Client.Projects.GetProject(projectID)
+	return project, err
+}
+
 // MergePull merges the merge request.
 func (g *GitlabClient) MergePull(pull models.PullRequest) error {
 	commitMsg := common.AutomergeCommitMsg
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/lkysow/go-gitlab"
@@ -233,3 +234,67 @@ func TestGitlabClient_UpdateStatus(t *testing.T) {
 }
 
 var mergeSuccess = `{"id":22461274,"iid":13,"project_id":4580910,"title":"Update main.tf","description":"","state":"merged","created_at":"2019-01-15T18:27:29.375Z","updated_at":"2019-01-25T17:28:01.437Z","merged_by":{"id":1755902,"name":"Luke Kysow","username":"lkysow","state":"active","avatar_url":"https://secure.gravatar.com/avatar/25fd57e71590fe28736624ff24d41c5f?s=80\u0026d=identicon","web_url":"https://gitlab.com/lkysow"},"merged_at":"2019-01-25T17:28:01.459Z","closed_by":null,"closed_at":null,"target_branch":"patch-1","source_branch":"patch-1-merger","upvotes":0,"downvotes":0,"author":{"id":1755902,"name":"Luke Kysow","username":"lkysow","state":"active","avatar_url":"https://secure.gravatar.com/avatar/25fd57e71590fe28736624ff24d41c5f?s=80\u0026d=identicon","web_url":"https://gitlab.com/lkysow"},"assignee":null,"source_project_id":4580910,"target_project_id":4580910,"labels":[],"work_in_progress":false,"milestone":null,"merge_when_pipeline_succeeds":false,"merge_status":"can_be_merged","sha":"cb86d70f464632bdfbe1bb9bc0f2f9d847a774a0","merge_commit_sha":"c9b336f1c71d3e64810b8cfa2abcfab232d6bff6","user_notes_count":0,"discussion_locked":null,"should_remove_source_branch":null,"force_remove_source_branch":false,"web_url":"https://gitlab.com/lkysow/atlantis-example/merge_requests/13","time_stats":{"time_estimate":0,"total_time_spent":0,"human_time_estimate":null,"human_total_time_spent":null},"squash":false,"subscribed":true,"changes_count":"1","latest_build_started_at":null,"latest_build_finished_at":null,"first_deployed_to_production_at":null,"pipeline":null,"diff_refs":{"base_sha":"67cb91d3f6198189f433c045154a885784ba6977","head_sha":"cb86d70f464632bdfbe1bb9bc0f2f9d847a774a0","start_sha":"67cb91d3f6198189f433c045154a885784ba6977"},"merge_error":null,"approvals_before_merge":null}`
+
+func TestGitlabClient_IsTeamMember(t *testing.T) {
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.EscapedPath() {
+			case "/api/v4/groups/owner%2Fadmins/members":
+				Equals(t, "1", r.URL.Query().Get("page"))
+				// The query matches partial usernames.
+				if strings.HasPrefix("alice2", r.URL.Query().Get("query")) {
+					w.Write([]byte(`[{"id": 2, "username": "alice2"}]`)) // nolint: errcheck
+					return
+				}
+				w.Write([]byte(`[]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	internalClient := gitlab.NewClient(nil, "token")
+	Ok(t, internalClient.SetBaseURL(testServer.URL))
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: nil,
+	}
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	member, err := client.IsTeamMember(repo, "owner/admins", models.User{Username: "alice2"})
+	Ok(t, err)
+	Equals(t, true, member)
+
+	member, err = client.IsTeamMember(repo, "owner/admins", models.User{Username: "alice"})
+	Ok(t, err)
+	Equals(t, false, member)
+
+	member, err = client.IsTeamMember(repo, "owner/admins", models.User{Username: "bob"})
+	Ok(t, err)
+	Equals(t, false, member)
+}
+
+func TestGitlabClient_GetApprovers(t *testing.T) {
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v4/projects/owner%2Frepo/merge_requests/1/approvals":
+				w.Write([]byte(`{"approved_by": [{"user": {"username": "alice"}}, {"user": {"username": "bob"}}]}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	internalClient := gitlab.NewClient(nil, "token")
+	Ok(t, internalClient.SetBaseURL(testServer.URL))
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: nil,
+	}
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	approvers, err := client.GetApprovers(repo, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, []string{"alice", "bob"}, approvers)
+}
@@ -0,0 +1,113 @@
+package vcs
+
+import (
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/metrics"
+)
+
+// InstrumentedClient records the latency and errors of each call to Client
+// as metrics.
+type InstrumentedClient struct {
+	Client Client
+	// Host is the type of VCS host that Client makes API calls to.
+	Host models.VCSHostType
+}
+
+func (i *InstrumentedClient) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	start := time.Now()
+	files, err := i.Client.GetModifiedFiles(repo, pull)
+	i.observe("GetModifiedFiles", start, err)
+	return files, err
+}
+
+func (i *InstrumentedClient) CreateComment(repo models.Repo, pullNum int, comment string) error {
+	start := time.Now()
+	err := i.Client.CreateComment(repo, pullNum, comment)
+	i.observe("CreateComment", start, err)
+	return err
+}
+
+func (i *InstrumentedClient) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
+	start := time.Now()
+	ids, err := i.Client.CreateCommentWithIDs(repo, pullNum, comment)
+	i.observe("CreateCommentWithIDs", start, err)
+	return ids, err
+}
+
+// SplitComment doesn't make any API calls so it isn't instrumented.
+func (i *InstrumentedClient) SplitComment(repo models.Repo, comment string) []string {
+	return i.Client.SplitComment(repo, comment)
+}
+
+func (i *InstrumentedClient) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	start := time.Now()
+	err := i.Client.EditComment(repo, pullNum, commentID, comment)
+	i.observe("EditComment", start, err)
+	return err
+}
+
+func (i *InstrumentedClient) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	start := time.Now()
+	err := i.Client.HideComment(repo, pullNum, commentID)
+	i.observe("HideComment", start, err)
+	return err
+}
+
+func (i *InstrumentedClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
+	start := time.Now()
+	approved, err := i.Client.PullIsApproved(repo, pull)
+	i.observe("PullIsApproved", start, err)
+	return approved, err
+}
+
+func (i *InstrumentedClient) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	start := time.Now()
+	approvers, err := i.Client.GetApprovers(repo, pull)
+	i.observe("GetApprovers", start, err)
+	return approvers, err
+}
+
+func (i *InstrumentedClient) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	start := time.Now()
+	exists, content, err := i.Client.GetFileContent(repo, ref, path)
+	i.observe("GetFileContent", start, err)
+	return exists, content, err
+}
+
+func (i *InstrumentedClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
+	start := time.Now()
+	mergeable, err := i.Client.PullIsMergeable(repo, pull)
+	i.observe("PullIsMergeable", start, err)
+	return mergeable, err
+}
+
+func (i *InstrumentedClient) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	start := time.Now()
+	member, err := i.Client.IsTeamMember(repo, team, user)
+	i.observe("IsTeamMember", start, err)
+	return member, err
+}
+
+func (i *InstrumentedClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
+	start := time.Now()
+	err := i.Client.UpdateStatus(repo, pull, state, src, description, url)
+	i.observe("UpdateStatus", start, err)
+	return err
+}
+
+func (i *InstrumentedClient) MergePull(pull models.PullRequest) error {
+	start := time.Now()
+	err := i.Client.MergePull(pull)
+	i.observe("MergePull", start, err)
+	return err
+}
+
+func (i *InstrumentedClient) observe(method string, start time.Time, err error) {
+	host := i.Host.String()
+	metrics.VCSRequestDuration.WithLabelValues(host, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.VCSRequestErrors.WithLabelValues(host, method).Inc()
+	}
+}
@@ -0,0 +1,58 @@
+package vcs_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestInstrumentedClient_RecordsErrors(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClient()
+	instrumented := &vcs.InstrumentedClient{Client: client, Host: models.Gitlab}
+	repo := models.Repo{FullName: "owner/repo"}
+	errCounter := metrics.VCSRequestErrors.WithLabelValues("Gitlab", "CreateComment")
+	before := testutil.ToFloat64(errCounter)
+
+	When(client.CreateComment(repo, 1, "ok")).ThenReturn(nil)
+	When(client.CreateComment(repo, 1, "fail")).ThenReturn(errors.New("err"))
+
+	Ok(t, instrumented.CreateComment(repo, 1, "ok"))
+	Equals(t, before, testutil.ToFloat64(errCounter))
+	ErrEquals(t, "err", instrumented.CreateComment(repo, 1, "fail"))
+	Equals(t, before+1, testutil.ToFloat64(errCounter))
+	client.VerifyWasCalledOnce().CreateComment(repo, 1, "fail")
+}
+
+func TestInstrumentedClient_PassesThroughResults(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClient()
+	instrumented := &vcs.InstrumentedClient{Client: client, Host: models.Github}
+	repo := models.Repo{FullName: "owner/repo"}
+	pull := models.PullRequest{Num: 1}
+
+	When(client.GetModifiedFiles(repo, pull)).ThenReturn([]string{"main.tf"}, nil)
+	When(client.PullIsApproved(repo, pull)).ThenReturn(true, nil)
+	When(client.PullIsMergeable(repo, pull)).ThenReturn(true, nil)
+
+	files, err := instrumented.GetModifiedFiles(repo, pull)
+	Ok(t, err)
+	Equals(t, []string{"main.tf"}, files)
+	approved, err := instrumented.PullIsApproved(repo, pull)
+	Ok(t, err)
+	Equals(t, true, approved)
+	mergeable, err := instrumented.PullIsMergeable(repo, pull)
+	Ok(t, err)
+	Equals(t, true, mergeable)
+	Ok(t, instrumented.UpdateStatus(repo, pull, models.SuccessCommitStatus, "src", "descrip", "url"))
+	Ok(t, instrumented.MergePull(pull))
+	client.VerifyWasCalledOnce().UpdateStatus(repo, pull, models.SuccessCommitStatus, "src", "descrip", "url")
+	client.VerifyWasCalledOnce().MergePull(pull)
+}
@@ -32,12 +32,33 @@ func (a *NotConfiguredVCSClient) GetModifiedFiles(repo models.Repo, pull models.
 func (a *NotConfiguredVCSClient) CreateComment(repo models.Repo, pullNum int, comment string) error {
 	return a.err()
 }
+func (a *NotConfiguredVCSClient) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
+	return nil, a.err()
+}
+func (a *NotConfiguredVCSClient) SplitComment(repo models.Repo, comment string) []string {
+	return []string{comment}
+}
+func (a *NotConfiguredVCSClient) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	return a.err()
+}
+func (a *NotConfiguredVCSClient) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	return a.err()
+}
 func (a *NotConfiguredVCSClient) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
 	return false, a.err()
 }
+func (a *NotConfiguredVCSClient) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, a.err()
+}
+func (a *NotConfiguredVCSClient) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	return false, nil, a.err()
+}
 func (a *NotConfiguredVCSClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	return false, a.err()
 }
+func (a *NotConfiguredVCSClient) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	return false, a.err()
+}
 func (a *NotConfiguredVCSClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
 	return a.err()
 }
@@ -25,7 +25,7 @@ type ClientProxy struct {
 	clients map[models.VCSHostType]Client
 }
 
-func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClient Client, bitbucketServerClient Client) *ClientProxy {
+func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClient Client, bitbucketServerClient Client, azureDevopsClient Client, giteaClient Client) *ClientProxy {
 	if githubClient == nil {
 		githubClient = &NotConfiguredVCSClient{}
 	}
@@ -38,12 +38,21 @@ func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClie
 	if bitbucketServerClient == nil {
 		bitbucketServerClient = &NotConfiguredVCSClient{}
 	}
+	if azureDevopsClient == nil {
+		azureDevopsClient = &NotConfiguredVCSClient{}
+	}
+	if giteaClient == nil {
+		giteaClient = &NotConfiguredVCSClient{}
+	}
+	// We wrap each client so that the calls to every host are instrumented.
 	return &ClientProxy{
 		clients: map[models.VCSHostType]Client{
-			models.Github:          githubClient,
-			models.Gitlab:          gitlabClient,
-			models.BitbucketCloud:  bitbucketCloudClient,
-			models.BitbucketServer: bitbucketServerClient,
+			models.Github:          &InstrumentedClient{Client: githubClient, Host: models.Github},
+			models.Gitlab:          &InstrumentedClient{Client: gitlabClient, Host: models.Gitlab},
+			models.BitbucketCloud:  &InstrumentedClient{Client: bitbucketCloudClient, Host: models.BitbucketCloud},
+			models.BitbucketServer: &InstrumentedClient{Client: bitbucketServerClient, Host: models.BitbucketServer},
+			models.AzureDevops:     &InstrumentedClient{Client: azureDevopsClient, Host: models.AzureDevops},
+			models.Gitea:           &InstrumentedClient{Client: giteaClient, Host: models.Gitea},
 		},
 	}
 }
@@ -56,14 +65,42 @@ func (d *ClientProxy) CreateComment(repo models.Repo, pullNum int, comment strin
 	return d.clients[repo.VCSHost.Type].CreateComment(repo, pullNum, comment)
 }
 
+func (d *ClientProxy) CreateCommentWithIDs(repo models.Repo, pullNum int, comment string) ([]string, error) {
+	return d.clients[repo.VCSHost.Type].CreateCommentWithIDs(repo, pullNum, comment)
+}
+
+func (d *ClientProxy) SplitComment(repo models.Repo, comment string) []string {
+	return d.clients[repo.VCSHost.Type].SplitComment(repo, comment)
+}
+
+func (d *ClientProxy) EditComment(repo models.Repo, pullNum int, commentID string, comment string) error {
+	return d.clients[repo.VCSHost.Type].EditComment(repo, pullNum, commentID, comment)
+}
+
+func (d *ClientProxy) HideComment(repo models.Repo, pullNum int, commentID string) error {
+	return d.clients[repo.VCSHost.Type].HideComment(repo, pullNum, commentID)
+}
+
 func (d *ClientProxy) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
 	return d.clients[repo.VCSHost.Type].PullIsApproved(repo, pull)
 }
 
+func (d *ClientProxy) GetApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return d.clients[repo.VCSHost.Type].GetApprovers(repo, pull)
+}
+
+func (d *ClientProxy) GetFileContent(repo models.Repo, ref string, path string) (bool, []byte, error) {
+	return d.clients[repo.VCSHost.Type].GetFileContent(repo, ref, path)
+}
+
 func (d *ClientProxy) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	return d.clients[repo.VCSHost.Type].PullIsMergeable(repo, pull)
 }
 
+func (d *ClientProxy) IsTeamMember(repo models.Repo, team string, user models.User) (bool, error) {
+	return d.clients[repo.VCSHost.Type].IsTeamMember(repo, team, user)
+}
+
 func (d *ClientProxy) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
 	return d.clients[repo.VCSHost.Type].UpdateStatus(repo, pull, state, src, description, url)
 }
@@ -24,12 +24,15 @@ import (
 
 // SlackWebhook sends webhooks to Slack.
 type SlackWebhook struct {
-	Client         SlackClient
+	Client SlackClient
+	// Event is the event the webhook is sent for, either ApplyEvent or
+	// DriftEvent.
+	Event          string
 	WorkspaceRegex *regexp.Regexp
 	Channel        string
 }
 
-func NewSlack(r *regexp.Regexp, channel string, client SlackClient) (*SlackWebhook, error) {
+func NewSlack(event string, r *regexp.Regexp, channel string, client SlackClient) (*SlackWebhook, error) {
 	if err := client.AuthTest(); err != nil {
 		return nil, fmt.Errorf("testing slack authentication: %s. Verify your slack-token is valid", err)
 	}
@@ -44,15 +47,26 @@ func NewSlack(r *regexp.Regexp, channel string, client SlackClient) (*SlackWebho
 
 	return &SlackWebhook{
 		Client:         client,
+		Event:          event,
 		WorkspaceRegex: r,
 		Channel:        channel,
 	}, nil
 }
 
-// Send sends the webhook to Slack if the workspace matches the regex.
+// Send sends the webhook to Slack if it's for applies and the workspace
+// matches the regex.
 func (s *SlackWebhook) Send(log *logging.SimpleLogger, applyResult ApplyResult) error {
-	if !s.WorkspaceRegex.MatchString(applyResult.Workspace) {
+	if s.Event != ApplyEvent || !s.WorkspaceRegex.MatchString(applyResult.Workspace) {
 		return nil
 	}
 	return s.Client.PostMessage(s.Channel, applyResult)
 }
+
+// SendDrift sends the webhook to Slack if it's for drift and the workspace
+// matches the regex.
+func (s *SlackWebhook) SendDrift(log *logging.SimpleLogger, driftResult DriftResult) error {
+	if s.Event != DriftEvent || !s.WorkspaceRegex.MatchString(driftResult.Workspace) {
+		return nil
+	}
+	return s.Client.PostDriftMessage(s.Channel, driftResult)
+}
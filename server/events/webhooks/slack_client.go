@@ -32,6 +32,7 @@ type SlackClient interface {
 	TokenIsSet() bool
 	ChannelExists(channelName string) (bool, error)
 	PostMessage(channel string, applyResult ApplyResult) error
+	PostDriftMessage(channel string, driftResult DriftResult) error
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_underlying_slack_client.go UnderlyingSlackClient
@@ -86,6 +87,14 @@ func (d *DefaultSlackClient) PostMessage(channel string, applyResult ApplyResult
 	return err
 }
 
+func (d *DefaultSlackClient) PostDriftMessage(channel string, driftResult DriftResult) error {
+	params := slack.NewPostMessageParameters()
+	params.Attachments = d.createDriftAttachments(driftResult)
+	params.EscapeText = false
+	_, _, err := d.Slack.PostMessage(channel, "", params)
+	return err
+}
+
 func (d *DefaultSlackClient) createAttachments(applyResult ApplyResult) []slack.Attachment {
 	var colour string
 	var successWord string
@@ -116,3 +125,33 @@ func (d *DefaultSlackClient) createAttachments(applyResult ApplyResult) []slack.
 	}
 	return []slack.Attachment{attachment}
 }
+
+func (d *DefaultSlackClient) createDriftAttachments(driftResult DriftResult) []slack.Attachment {
+	project := driftResult.RepoRelDir
+	if driftResult.ProjectName != "" {
+		project = driftResult.ProjectName
+	}
+	text := fmt.Sprintf("Drift detected in <%s|%s>", driftResult.URL, driftResult.Repo.FullName)
+	attachment := slack.Attachment{
+		Color: slackFailureColour,
+		Text:  text,
+		Fields: []slack.AttachmentField{
+			{
+				Title: "Project",
+				Value: project,
+				Short: true,
+			},
+			{
+				Title: "Workspace",
+				Value: driftResult.Workspace,
+				Short: true,
+			},
+			{
+				Title: "Changes",
+				Value: driftResult.Summary,
+				Short: false,
+			},
+		},
+	}
+	return []slack.Attachment{attachment}
+}
@@ -160,6 +160,47 @@ func TestPostMessage_Error(t *testing.T) {
 	Assert(t, err != nil, "expected error")
 }
 
+func TestPostDriftMessage_Success(t *testing.T) {
+	t.Log("When drift is detected, the message should link to the drift page")
+	setup(t)
+
+	expParams := slack.NewPostMessageParameters()
+	expParams.Attachments = []slack.Attachment{{
+		Color: "danger",
+		Text:  "Drift detected in <https://atlantis/drift|runatlantis/atlantis>",
+		Fields: []slack.AttachmentField{
+			{
+				Title: "Project",
+				Value: "infra",
+				Short: true,
+			},
+			{
+				Title: "Workspace",
+				Value: "production",
+				Short: true,
+			},
+			{
+				Title: "Changes",
+				Value: "Plan: 1 to add, 0 to change, 0 to destroy.",
+				Short: false,
+			},
+		},
+	}}
+	expParams.AsUser = false
+	expParams.EscapeText = false
+
+	channel := "somechannel"
+	err := client.PostDriftMessage(channel, webhooks.DriftResult{
+		Repo:        models.Repo{FullName: "runatlantis/atlantis"},
+		RepoRelDir:  "infra",
+		Workspace:   "production",
+		Summary:     "Plan: 1 to add, 0 to change, 0 to destroy.",
+		URL:         "https://atlantis/drift",
+	})
+	Ok(t, err)
+	underlying.VerifyWasCalledOnce().PostMessage(channel, "", expParams)
+}
+
 func setup(t *testing.T) {
 	RegisterMockTestingT(t)
 	underlying = mocks.NewMockUnderlyingSlackClient()
@@ -34,6 +34,7 @@ func TestSend_PostMessage(t *testing.T) {
 	channel := "somechannel"
 	hook := webhooks.SlackWebhook{
 		Client:         client,
+		Event:          webhooks.ApplyEvent,
 		WorkspaceRegex: regex,
 		Channel:        channel,
 	}
@@ -56,6 +57,7 @@ func TestSend_NoopSuccess(t *testing.T) {
 	channel := "somechannel"
 	hook := webhooks.SlackWebhook{
 		Client:         client,
+		Event:          webhooks.ApplyEvent,
 		WorkspaceRegex: regex,
 		Channel:        channel,
 	}
@@ -66,3 +68,30 @@ func TestSend_NoopSuccess(t *testing.T) {
 	Ok(t, err)
 	client.VerifyWasCalled(Never()).PostMessage(channel, result)
 }
+
+func TestSendDrift_PostDriftMessage(t *testing.T) {
+	t.Log("Sending a drift hook should only call PostDriftMessage if the hook is for drift")
+	RegisterMockTestingT(t)
+	client := mocks.NewMockSlackClient()
+	regex, err := regexp.Compile(".*")
+	Ok(t, err)
+
+	channel := "somechannel"
+	hook := webhooks.SlackWebhook{
+		Client:         client,
+		Event:          webhooks.ApplyEvent,
+		WorkspaceRegex: regex,
+		Channel:        channel,
+	}
+	result := webhooks.DriftResult{
+		Workspace: "production",
+	}
+	Ok(t, hook.SendDrift(logging.NewNoopLogger(), result))
+	client.VerifyWasCalled(Never()).PostDriftMessage(channel, result)
+
+	hook.Event = webhooks.DriftEvent
+	Ok(t, hook.SendDrift(logging.NewNoopLogger(), result))
+	client.VerifyWasCalledOnce().PostDriftMessage(channel, result)
+	Ok(t, hook.Send(logging.NewNoopLogger(), webhooks.ApplyResult{Workspace: "production"}))
+	client.VerifyWasCalled(Never()).PostMessage(channel, webhooks.ApplyResult{Workspace: "production"})
+}
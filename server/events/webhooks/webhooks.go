@@ -25,6 +25,7 @@ import (
 
 const SlackKind = "slack"
 const ApplyEvent = "apply"
+const DriftEvent = "drift"
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_sender.go Sender
 
@@ -32,6 +33,9 @@ const ApplyEvent = "apply"
 type Sender interface {
 	// Send sends the webhook (if the implementation thinks it should).
 	Send(log *logging.SimpleLogger, applyResult ApplyResult) error
+	// SendDrift sends the webhook for a project that has drifted (if the
+	// implementation thinks it should).
+	SendDrift(log *logging.SimpleLogger, driftResult DriftResult) error
 }
 
 // ApplyResult is the result of a terraform apply.
@@ -43,6 +47,20 @@ type ApplyResult struct {
 	Success   bool
 }
 
+// DriftResult describes a project whose infrastructure has drifted from the
+// code on its repo's default branch.
+type DriftResult struct {
+	Repo        models.Repo
+	RepoRelDir  string
+	Workspace   string
+	ProjectName string
+	// Summary summarizes the changes planned to undo the drift, ex.
+	// "Plan: 1 to add, 0 to change, 0 to destroy.".
+	Summary string
+	// URL is the page listing the drift status of each project.
+	URL string
+}
+
 // MultiWebhookSender sends multiple webhooks for each one it's configured for.
 type MultiWebhookSender struct {
 	Webhooks []Sender
@@ -65,8 +83,8 @@ func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookS
 		if c.Kind == "" || c.Event == "" {
 			return nil, errors.New("must specify \"kind\" and \"event\" keys for webhooks")
 		}
-		if c.Event != ApplyEvent {
-			return nil, fmt.Errorf("\"event: %s\" not supported. Only \"event: %s\" is supported right now", c.Event, ApplyEvent)
+		if c.Event != ApplyEvent && c.Event != DriftEvent {
+			return nil, fmt.Errorf("\"event: %s\" not supported. Only \"event: %s\" and \"event: %s\" are supported right now", c.Event, ApplyEvent, DriftEvent)
 		}
 		switch c.Kind {
 		case SlackKind:
@@ -76,7 +94,7 @@ func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookS
 			if c.Channel == "" {
 				return nil, errors.New("must specify \"channel\" if using a webhook of \"kind: slack\"")
 			}
-			slack, err := NewSlack(r, c.Channel, client)
+			slack, err := NewSlack(c.Event, r, c.Channel, client)
 			if err != nil {
 				return nil, err
 			}
@@ -100,3 +118,13 @@ func (w *MultiWebhookSender) Send(log *logging.SimpleLogger, result ApplyResult)
 	}
 	return nil
 }
+
+// SendDrift sends the drift webhook using its Webhooks.
+func (w *MultiWebhookSender) SendDrift(log *logging.SimpleLogger, result DriftResult) error {
+	for _, w := range w.Webhooks {
+		if err := w.SendDrift(log, result); err != nil {
+			log.Warn("error sending slack webhook: %s", err)
+		}
+	}
+	return nil
+}
@@ -78,7 +78,7 @@ func TestNewWebhooksManager_UnsupportedEvent(t *testing.T) {
 	configs[0].Event = unsupportedEvent
 	_, err := webhooks.NewMultiWebhookSender(configs, client)
 	Assert(t, err != nil, "expected error")
-	Equals(t, "\"event: badevent\" not supported. Only \"event: apply\" is supported right now", err.Error())
+	Equals(t, "\"event: badevent\" not supported. Only \"event: apply\" and \"event: drift\" are supported right now", err.Error())
 }
 
 func TestNewWebhooksManager_NoKind(t *testing.T) {
@@ -186,3 +186,22 @@ func TestSend_MultipleSuccess(t *testing.T) {
 		s.VerifyWasCalledOnce().Send(logger, result)
 	}
 }
+
+func TestSendDrift_MultipleSuccess(t *testing.T) {
+	t.Log("Sending multiple drift webhooks should succeed")
+	RegisterMockTestingT(t)
+	senders := []*mocks.MockSender{
+		mocks.NewMockSender(),
+		mocks.NewMockSender(),
+	}
+	manager := webhooks.MultiWebhookSender{
+		Webhooks: []webhooks.Sender{senders[0], senders[1]},
+	}
+	logger := logging.NewNoopLogger()
+	result := webhooks.DriftResult{}
+	err := manager.SendDrift(logger, result)
+	Ok(t, err)
+	for _, s := range senders {
+		s.VerifyWasCalledOnce().SendDrift(logger, result)
+	}
+}
@@ -20,14 +20,21 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 )
 
 const workingDirPrefix = "repos"
 
+// driftDirPrefix is where repos are cloned to check them for drift. It's
+// separate from workingDirPrefix so their clones never clash with a pull
+// request's.
+const driftDirPrefix = "drift"
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_working_dir.go WorkingDir
 
 // WorkingDir handles the workspace on disk for running commands.
@@ -42,6 +49,9 @@ type WorkingDir interface {
 	// Delete deletes the workspace for this repo and pull.
 	Delete(r models.Repo, p models.PullRequest) error
 	DeleteForWorkspace(r models.Repo, p models.PullRequest, workspace string) error
+	// CloneDefaultBranch git clones the default branch of repo to check it
+	// for drift and returns the absolute path to the root of the cloned repo.
+	CloneDefaultBranch(log *logging.SimpleLogger, repo models.Repo, workspace string) (string, error)
 }
 
 // FileWorkspace implements WorkingDir with the file system.
@@ -111,9 +121,14 @@ func (w *FileWorkspace) Clone(
 func (w *FileWorkspace) forceClone(log *logging.SimpleLogger,
 	cloneDir string,
 	headRepo models.Repo,
-	p models.PullRequest) (string, error) {
+	p models.PullRequest) (dir string, err error) {
+
+	start := time.Now()
+	defer func() {
+		metrics.CloneDuration.WithLabelValues(metrics.Result(err, "")).Observe(time.Since(start).Seconds())
+	}()
 
-	err := os.RemoveAll(cloneDir)
+	err = os.RemoveAll(cloneDir)
 	if err != nil {
 		return "", errors.Wrapf(err, "deleting dir %q before cloning", cloneDir)
 	}
@@ -211,6 +226,40 @@ func (w *FileWorkspace) DeleteForWorkspace(r models.Repo, p models.PullRequest,
 	return os.RemoveAll(w.cloneDir(r, p, workspace))
 }
 
+// CloneDefaultBranch git clones the default branch of repo to check it for
+// drift and returns the absolute path to the root of the cloned repo. The
+// branch moves as pull requests are merged so it's always cloned again.
+func (w *FileWorkspace) CloneDefaultBranch(log *logging.SimpleLogger, repo models.Repo, workspace string) (dir string, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.CloneDuration.WithLabelValues(metrics.Result(err, "")).Observe(time.Since(start).Seconds())
+	}()
+
+	cloneDir := filepath.Join(w.DataDir, driftDirPrefix, repo.FullName, workspace)
+	if err := os.RemoveAll(cloneDir); err != nil {
+		return "", errors.Wrapf(err, "deleting dir %q before cloning", cloneDir)
+	}
+	log.Info("creating dir %q", cloneDir)
+	if err := os.MkdirAll(cloneDir, 0700); err != nil {
+		return "", errors.Wrap(err, "creating new workspace")
+	}
+
+	cloneURL := repo.CloneURL
+	if w.TestingOverrideBaseCloneURL != "" {
+		cloneURL = w.TestingOverrideBaseCloneURL
+	}
+	// Without --branch, git checks out the default branch.
+	cmd := exec.Command("git", "clone", "--depth=1", "--single-branch", cloneURL, cloneDir) // #nosec
+	cmd.Dir = cloneDir
+	cmdStr := w.cmdAsSanitizedStr(cmd, repo, repo)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "running %s: %s", cmdStr, string(output))
+	}
+	log.Debug("ran: %s. Output: %s", cmdStr, strings.TrimSuffix(string(output), "\n"))
+	return cloneDir, nil
+}
+
 func (w *FileWorkspace) repoPullDir(r models.Repo, p models.PullRequest) string {
 	return filepath.Join(w.DataDir, workingDirPrefix, r.FullName, strconv.Itoa(p.Num))
 }
@@ -15,12 +15,18 @@ package events
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 )
 
 //go:generate pegomock generate --use-experimental-model-gen --package mocks -o mocks/mock_working_dir_locker.go WorkingDirLocker
 
+// DriftPullNum is the pull number drift checks lock their working dirs with.
+// They're cloned separately from every pull request's, including pull 0
+// which API commands on a branch use, so they can run at the same time.
+const DriftPullNum = -1
+
 // WorkingDirLocker is used to prevent multiple commands from executing
 // at the same time for a single repo, pull, and workspace. We need to prevent
 // this from happening because a specific repo/pull/workspace has a single workspace
@@ -32,6 +38,21 @@ type WorkingDirLocker interface {
 	// an error if the workspace is already locked. The error is expected to
 	// be printed to the pull request.
 	TryLock(repoFullName string, pullNum int, workspace string) (func(), error)
+	// TryLockPath tries to acquire a lock for a single project directory,
+	// path, in this repo, workspace and pull. Different paths in the same
+	// workspace can be locked at the same time so projects can run in
+	// parallel, but not while the whole workspace or pull is locked.
+	// It returns a function that should be used to unlock the path and
+	// an error if the path is already locked. The error is expected to
+	// be printed to the pull request.
+	TryLockPath(repoFullName string, pullNum int, workspace string, path string) (func(), error)
+	// TryLockWorkspacePaths tries to acquire the lock for this repo, workspace
+	// and pull and the locks for each of paths in it at the same time.
+	// It returns a function that unlocks the workspace but leaves the paths
+	// locked, functions to unlock each of paths and an error if any of them
+	// are already locked. The error is expected to be printed to the pull
+	// request.
+	TryLockWorkspacePaths(repoFullName string, pullNum int, workspace string, paths []string) (func(), []func(), error)
 	// TryLockPull tries to acquire a lock for all the workspaces in this repo
 	// and pull.
 	// It returns a function that should be used to unlock the workspace and
@@ -81,7 +102,7 @@ func (d *DefaultWorkingDirLocker) TryLock(repoFullName string, pullNum int, work
 	pullKey := d.pullKey(repoFullName, pullNum)
 	workspaceKey := d.workspaceKey(repoFullName, pullNum, workspace)
 	for _, l := range d.locks {
-		if l == pullKey || l == workspaceKey {
+		if l == pullKey || l == workspaceKey || strings.HasPrefix(l, workspaceKey+"/") {
 			return func() {}, fmt.Errorf("the %s workspace is currently locked by another"+
 				" command that is running for this pull request–"+
 				"wait until the previous command is complete and try again", workspace)
@@ -89,17 +110,74 @@ func (d *DefaultWorkingDirLocker) TryLock(repoFullName string, pullNum int, work
 	}
 	d.locks = append(d.locks, workspaceKey)
 	return func() {
-		d.unlock(repoFullName, pullNum, workspace)
+		d.unlock(workspaceKey)
 	}, nil
 }
 
-// Unlock unlocks the workspace for this pull.
-func (d *DefaultWorkingDirLocker) unlock(repoFullName string, pullNum int, workspace string) {
+func (d *DefaultWorkingDirLocker) TryLockPath(repoFullName string, pullNum int, workspace string, path string) (func(), error) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
+	pullKey := d.pullKey(repoFullName, pullNum)
 	workspaceKey := d.workspaceKey(repoFullName, pullNum, workspace)
-	d.removeLock(workspaceKey)
+	pathKey := d.pathKey(repoFullName, pullNum, workspace, path)
+	for _, l := range d.locks {
+		if l == pullKey || l == workspaceKey || l == pathKey {
+			return func() {}, fmt.Errorf("the %s directory in the %s workspace is currently locked by another"+
+				" command that is running for this pull request–"+
+				"wait until the previous command is complete and try again", filepath.Clean(path), workspace)
+		}
+	}
+	d.locks = append(d.locks, pathKey)
+	return func() {
+		d.unlock(pathKey)
+	}, nil
+}
+
+func (d *DefaultWorkingDirLocker) TryLockWorkspacePaths(repoFullName string, pullNum int, workspace string, paths []string) (func(), []func(), error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	pullKey := d.pullKey(repoFullName, pullNum)
+	workspaceKey := d.workspaceKey(repoFullName, pullNum, workspace)
+	for _, l := range d.locks {
+		if l == pullKey || l == workspaceKey || strings.HasPrefix(l, workspaceKey+"/") {
+			return func() {}, nil, fmt.Errorf("the %s workspace is currently locked by another"+
+				" command that is running for this pull request–"+
+				"wait until the previous command is complete and try again", workspace)
+		}
+	}
+	var pathKeys []string
+	for _, path := range paths {
+		pathKey := d.pathKey(repoFullName, pullNum, workspace, path)
+		for _, k := range pathKeys {
+			if k == pathKey {
+				return func() {}, nil, fmt.Errorf("more than one project is in the %s directory in the %s workspace so they can't run at the same time", filepath.Clean(path), workspace)
+			}
+		}
+		pathKeys = append(pathKeys, pathKey)
+	}
+
+	d.locks = append(d.locks, workspaceKey)
+	d.locks = append(d.locks, pathKeys...)
+	var unlockPaths []func()
+	for _, pathKey := range pathKeys {
+		key := pathKey
+		unlockPaths = append(unlockPaths, func() {
+			d.unlock(key)
+		})
+	}
+	return func() {
+		d.unlock(workspaceKey)
+	}, unlockPaths, nil
+}
+
+// unlock removes the workspace or path lock at key.
+func (d *DefaultWorkingDirLocker) unlock(key string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.removeLock(key)
 }
 
 // Unlock unlocks all workspaces for this pull.
@@ -125,6 +203,12 @@ func (d *DefaultWorkingDirLocker) workspaceKey(repo string, pull int, workspace
 	return fmt.Sprintf("%s/%s", d.pullKey(repo, pull), workspace)
 }
 
+// pathKey is under the workspace's key. Terraform doesn't allow slashes in
+// workspace names so it can't clash with another workspace's key.
+func (d *DefaultWorkingDirLocker) pathKey(repo string, pull int, workspace string, path string) string {
+	return fmt.Sprintf("%s/%s", d.workspaceKey(repo, pull, workspace), filepath.Clean(path))
+}
+
 func (d *DefaultWorkingDirLocker) pullKey(repo string, pull int) string {
 	return fmt.Sprintf("%s/%d", repo, pull)
 }
@@ -196,3 +196,83 @@ func TestLockPull_WorkspaceFirst(t *testing.T) {
 	_, err = locker.TryLockPull("owner/repo", 1)
 	Ok(t, err)
 }
+
+// Different paths in the same workspace can be locked at the same time but
+// not while the workspace or pull is locked.
+func TestTryLockPath(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLocker()
+	unlockA, err := locker.TryLockPath(repo, 1, workspace, "a")
+	Ok(t, err)
+	unlockB, err := locker.TryLockPath(repo, 1, workspace, "b")
+	Ok(t, err)
+
+	t.Log("the same path should be locked")
+	_, err = locker.TryLockPath(repo, 1, workspace, "./a")
+	ErrEquals(t, "the a directory in the default workspace is currently locked by another command that is running for this pull request–wait until the previous command is complete and try again", err)
+
+	t.Log("the workspace and pull should be locked until all paths are unlocked")
+	_, err = locker.TryLock(repo, 1, workspace)
+	Assert(t, err != nil, "exp err")
+	_, err = locker.TryLockPull(repo, 1)
+	Assert(t, err != nil, "exp err")
+	unlockA()
+	_, err = locker.TryLock(repo, 1, workspace)
+	Assert(t, err != nil, "exp err")
+	unlockB()
+	unlockWorkspace, err := locker.TryLock(repo, 1, workspace)
+	Ok(t, err)
+
+	t.Log("paths in a locked workspace should be locked")
+	_, err = locker.TryLockPath(repo, 1, workspace, "a")
+	Assert(t, err != nil, "exp err")
+	_, err = locker.TryLockPath(repo, 1, "other-workspace", "a")
+	Ok(t, err)
+	unlockWorkspace()
+	_, err = locker.TryLockPath(repo, 1, workspace, "a")
+	Ok(t, err)
+}
+
+func TestTryLockWorkspacePaths(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLocker()
+	unlockWorkspace, unlockPaths, err := locker.TryLockWorkspacePaths(repo, 1, workspace, []string{"a", "b"})
+	Ok(t, err)
+	Equals(t, 2, len(unlockPaths))
+
+	t.Log("the workspace and its paths should be locked")
+	_, err = locker.TryLock(repo, 1, workspace)
+	Assert(t, err != nil, "exp err")
+	_, err = locker.TryLockPath(repo, 1, workspace, "c")
+	Assert(t, err != nil, "exp err")
+
+	t.Log("after the workspace is unlocked the paths should still be locked")
+	unlockWorkspace()
+	_, err = locker.TryLockPath(repo, 1, workspace, "a")
+	Assert(t, err != nil, "exp err")
+	_, err = locker.TryLock(repo, 1, workspace)
+	Assert(t, err != nil, "exp err")
+	unlockC, err := locker.TryLockPath(repo, 1, workspace, "c")
+	Ok(t, err)
+	unlockC()
+
+	unlockPaths[0]()
+	_, err = locker.TryLock(repo, 1, workspace)
+	Assert(t, err != nil, "exp err")
+	unlockPaths[1]()
+	_, err = locker.TryLock(repo, 1, workspace)
+	Ok(t, err)
+}
+
+func TestTryLockWorkspacePaths_Locked(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLocker()
+	unlockA, err := locker.TryLockPath(repo, 1, workspace, "a")
+	Ok(t, err)
+	_, _, err = locker.TryLockWorkspacePaths(repo, 1, workspace, []string{"b"})
+	ErrEquals(t, "the default workspace is currently locked by another command that is running for this pull request–wait until the previous command is complete and try again", err)
+	unlockA()
+
+	t.Log("the same path twice can't be locked")
+	_, _, err = locker.TryLockWorkspacePaths(repo, 1, workspace, []string{"a", "./a"})
+	ErrEquals(t, "more than one project is in the a directory in the default workspace so they can't run at the same time", err)
+	_, _, err = locker.TryLockWorkspacePaths(repo, 1, workspace, []string{"a"})
+	Ok(t, err)
+}
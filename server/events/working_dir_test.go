@@ -244,6 +244,30 @@ func TestClone_RecloneWrongCommit(t *testing.T) {
 	Equals(t, expCommit, actCommit)
 }
 
+// Test that the default branch is cloned into a dir separate from pull
+// requests' and that it's cloned again each time so it's up to date.
+func TestCloneDefaultBranch(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		TestingOverrideBaseCloneURL: fmt.Sprintf("file://%s", repoDir),
+	}
+	repo := models.Repo{FullName: "owner/repo"}
+	cloneDir, err := wd.CloneDefaultBranch(nil, repo, "default")
+	Ok(t, err)
+	Equals(t, filepath.Join(dataDir, "drift", "owner", "repo", "default"), cloneDir)
+	Equals(t, runCmd(t, repoDir, "git", "rev-parse", "HEAD"), runCmd(t, cloneDir, "git", "rev-parse", "HEAD"))
+
+	runCmd(t, repoDir, "git", "commit", "--allow-empty", "-m", "second commit")
+	cloneDir, err = wd.CloneDefaultBranch(nil, repo, "default")
+	Ok(t, err)
+	Equals(t, runCmd(t, repoDir, "git", "rev-parse", "HEAD"), runCmd(t, cloneDir, "git", "rev-parse", "HEAD"))
+}
+
 func initRepo(t *testing.T) (string, func()) {
 	repoDir, cleanup := TempDir(t)
 	runCmd(t, repoDir, "git", "init")
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/pkg/errors"
@@ -23,6 +24,12 @@ type ParserValidator struct{}
 // of error: os.IsNotExist(error) but it's instead preferred to check with
 // HasConfigFile.
 func (p *ParserValidator) ReadConfig(repoDir string) (valid.Config, error) {
+	return p.ReadConfigWithServerWorkflows(repoDir, nil)
+}
+
+// ReadConfigWithServerWorkflows is like ReadConfig except that projects can
+// also use the workflows defined in the server-side repo config.
+func (p *ParserValidator) ReadConfigWithServerWorkflows(repoDir string, serverWorkflows map[string]valid.Workflow) (valid.Config, error) {
 	configFile := p.configFilePath(repoDir)
 	configData, err := ioutil.ReadFile(configFile) // nolint: gosec
 
@@ -38,13 +45,61 @@ func (p *ParserValidator) ReadConfig(repoDir string) (valid.Config, error) {
 	}
 
 	// If the config file exists, parse it.
-	config, err := p.parseAndValidate(configData)
+	config, err := p.parseAndValidate(configData, serverWorkflows)
 	if err != nil {
 		return valid.Config{}, errors.Wrapf(err, "parsing %s", AtlantisYAMLFilename)
 	}
 	return config, err
 }
 
+// ParseConfig returns the parsed and validated atlantis.yaml config in
+// configData. It's for config files that weren't read from a clone of the
+// repo.
+func (p *ParserValidator) ParseConfig(configData []byte, serverWorkflows map[string]valid.Workflow) (valid.Config, error) {
+	config, err := p.parseAndValidate(configData, serverWorkflows)
+	if err != nil {
+		return valid.Config{}, errors.Wrapf(err, "parsing %s", AtlantisYAMLFilename)
+	}
+	return config, nil
+}
+
+// ReadServerConfig returns the parsed and validated server-side repo config
+// at path.
+func (p *ParserValidator) ReadServerConfig(path string) (valid.ServerConfig, error) {
+	configData, err := ioutil.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return valid.ServerConfig{}, errors.Wrapf(err, "unable to read %s", path)
+	}
+
+	var rawConfig raw.ServerConfig
+	if err := yaml.UnmarshalStrict(configData, &rawConfig); err != nil {
+		return valid.ServerConfig{}, errors.Wrapf(err, "parsing %s", path)
+	}
+
+	// Set ErrorTag to yaml so it uses the YAML field names in error messages.
+	validation.ErrorTag = "yaml"
+
+	if err := rawConfig.Validate(); err != nil {
+		return valid.ServerConfig{}, errors.Wrapf(err, "parsing %s", path)
+	}
+	for _, repo := range rawConfig.Repos {
+		if repo.Workflow == nil {
+			continue
+		}
+		if _, ok := rawConfig.Workflows[*repo.Workflow]; !ok {
+			return valid.ServerConfig{}, fmt.Errorf("parsing %s: repo %q uses workflow %q which is not defined", path, *repo.ID, *repo.Workflow)
+		}
+	}
+	config := rawConfig.ToValid()
+	// Policy set paths are relative to the config file.
+	for i, ps := range config.Policies.PolicySets {
+		if !filepath.IsAbs(ps.Path) {
+			config.Policies.PolicySets[i].Path = filepath.Join(filepath.Dir(path), ps.Path)
+		}
+	}
+	return config, nil
+}
+
 func (p *ParserValidator) HasConfigFile(repoDir string) (bool, error) {
 	_, err := os.Stat(p.configFilePath(repoDir))
 	if os.IsNotExist(err) {
@@ -60,7 +115,7 @@ func (p *ParserValidator) configFilePath(repoDir string) string {
 	return filepath.Join(repoDir, AtlantisYAMLFilename)
 }
 
-func (p *ParserValidator) parseAndValidate(configData []byte) (valid.Config, error) {
+func (p *ParserValidator) parseAndValidate(configData []byte, serverWorkflows map[string]valid.Workflow) (valid.Config, error) {
 	var rawConfig raw.Config
 	if err := yaml.UnmarshalStrict(configData, &rawConfig); err != nil {
 		return valid.Config{}, err
@@ -74,7 +129,7 @@ func (p *ParserValidator) parseAndValidate(configData []byte) (valid.Config, err
 	}
 
 	// Top level validation.
-	if err := p.validateWorkflows(rawConfig); err != nil {
+	if err := p.validateWorkflows(rawConfig, serverWorkflows); err != nil {
 		return valid.Config{}, err
 	}
 
@@ -82,6 +137,9 @@ func (p *ParserValidator) parseAndValidate(configData []byte) (valid.Config, err
 	if err := p.validateProjectNames(validConfig); err != nil {
 		return valid.Config{}, err
 	}
+	if err := p.validateProjectDependencies(validConfig); err != nil {
+		return valid.Config{}, err
+	}
 
 	return validConfig, nil
 }
@@ -123,24 +181,83 @@ func (p *ParserValidator) validateProjectNames(config valid.Config) error {
 	return nil
 }
 
-func (p *ParserValidator) validateWorkflows(config raw.Config) error {
+// validateProjectDependencies validates that each project in depends_on is
+// defined and that there are no cycles.
+func (p *ParserValidator) validateProjectDependencies(config valid.Config) error {
+	deps := make(map[string][]string)
 	for _, project := range config.Projects {
-		if err := p.validateWorkflowExists(project, config.Workflows); err != nil {
+		for _, dep := range project.DependsOn {
+			if config.FindProjectByName(dep) == nil {
+				return fmt.Errorf("project at dir: %q workspace: %q depends on project %q which is not defined", project.Dir, project.Workspace, dep)
+			}
+		}
+		if project.Name != nil {
+			deps[*project.Name] = project.DependsOn
+		}
+	}
+
+	// Depth first search from each project, keeping track of the path so far
+	// so that we can print the cycle.
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		path = append(path, name)
+		switch state[name] {
+		case visiting:
+			// Only print the projects that are part of the cycle.
+			for i, n := range path {
+				if n == name {
+					path = path[i:]
+					break
+				}
+			}
+			return fmt.Errorf("found a cycle in depends_on: %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, project := range config.Projects {
+		if project.Name == nil {
+			continue
+		}
+		if err := visit(*project.Name, nil); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (p *ParserValidator) validateWorkflowExists(project raw.Project, workflows map[string]raw.Workflow) error {
+func (p *ParserValidator) validateWorkflows(config raw.Config, serverWorkflows map[string]valid.Workflow) error {
+	for _, project := range config.Projects {
+		if err := p.validateWorkflowExists(project, config.Workflows, serverWorkflows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *ParserValidator) validateWorkflowExists(project raw.Project, workflows map[string]raw.Workflow, serverWorkflows map[string]valid.Workflow) error {
 	if project.Workflow == nil {
 		return nil
 	}
 	workflow := *project.Workflow
-	for k := range workflows {
-		if k == workflow {
-			return nil
-		}
+	if _, ok := workflows[workflow]; ok {
+		return nil
+	}
+	if _, ok := serverWorkflows[workflow]; ok {
+		return nil
 	}
 	return fmt.Errorf("workflow %q is not defined", workflow)
 }
@@ -374,6 +374,88 @@ projects:
   workspace: workspace`,
 			expErr: "found two or more projects with name \"myname\"; project names must be unique",
 		},
+		{
+			description: "depends_on project that isn't defined",
+			input: `
+version: 2
+projects:
+- dir: app
+  depends_on: [network]`,
+			expErr: "project at dir: \"app\" workspace: \"default\" depends on project \"network\" which is not defined",
+		},
+		{
+			description: "depends_on itself",
+			input: `
+version: 2
+projects:
+- name: network
+  dir: network
+  depends_on: [network]`,
+			expErr: "found a cycle in depends_on: network -> network",
+		},
+		{
+			description: "depends_on cycle",
+			input: `
+version: 2
+projects:
+- name: app
+  dir: app
+  depends_on: [cluster]
+- name: network
+  dir: network
+  depends_on: [app]
+- name: cluster
+  dir: cluster
+  depends_on: [network]`,
+			expErr: "found a cycle in depends_on: app -> cluster -> network -> app",
+		},
+		{
+			description: "depends_on without a cycle",
+			input: `
+version: 2
+projects:
+- dir: app
+  depends_on: [network, cluster]
+- name: cluster
+  dir: cluster
+  depends_on: [network]
+- name: network
+  dir: network`,
+			exp: valid.Config{
+				Version: 2,
+				Projects: []valid.Project{
+					{
+						Dir:       "app",
+						Workspace: "default",
+						Autoplan: valid.Autoplan{
+							WhenModified: []string{"**/*.tf*"},
+							Enabled:      true,
+						},
+						DependsOn: []string{"network", "cluster"},
+					},
+					{
+						Name:      String("cluster"),
+						Dir:       "cluster",
+						Workspace: "default",
+						Autoplan: valid.Autoplan{
+							WhenModified: []string{"**/*.tf*"},
+							Enabled:      true,
+						},
+						DependsOn: []string{"network"},
+					},
+					{
+						Name:      String("network"),
+						Dir:       "network",
+						Workspace: "default",
+						Autoplan: valid.Autoplan{
+							WhenModified: []string{"**/*.tf*"},
+							Enabled:      true,
+						},
+					},
+				},
+				Workflows: map[string]valid.Workflow{},
+			},
+		},
 		{
 			description: "two projects with same dir/workspace with different names",
 			input: `
@@ -692,6 +774,253 @@ workflows:
 	}
 }
 
+func TestReadConfigWithServerWorkflows(t *testing.T) {
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	err := ioutil.WriteFile(filepath.Join(tmpDir, "atlantis.yaml"), []byte(`
+version: 2
+projects:
+- dir: .
+  workflow: prod`), 0600)
+	Ok(t, err)
+
+	r := yaml.ParserValidator{}
+	_, err = r.ReadConfig(tmpDir)
+	ErrEquals(t, "parsing atlantis.yaml: workflow \"prod\" is not defined", err)
+
+	t.Log("projects should be able to use workflows defined server-side")
+	act, err := r.ReadConfigWithServerWorkflows(tmpDir, map[string]valid.Workflow{"prod": {}})
+	Ok(t, err)
+	Equals(t, "prod", *act.Projects[0].Workflow)
+}
+
+func TestReadServerConfig(t *testing.T) {
+	cases := []struct {
+		description string
+		input       string
+		exp         valid.ServerConfig
+		expErr      string
+	}{
+		{
+			description: "empty file",
+			input:       "",
+			exp: valid.ServerConfig{
+				Workflows: make(map[string]valid.Workflow),
+			},
+		},
+		{
+			description: "all keys set",
+			input: `
+repos:
+- id: github.com/owner/repo
+  apply_requirements: [approved]
+  workflow: prod
+  allowed_overrides: [workflow, apply_requirements]
+  allow_custom_workflows: true
+  allow_run_steps: false
+  permissions:
+  - command: apply
+    teams: [owner/admins]
+  - command: apply
+    projects: [prod, dir/]
+    users: [alice]
+workflows:
+  prod:
+    plan:
+      steps: [init, plan, policy_check]
+policies:
+  approvers: [alice]
+  policy_sets:
+  - name: security
+    path: /policies/security.rego`,
+			exp: valid.ServerConfig{
+				Repos: []valid.ServerRepo{
+					{
+						ID:                   "github.com/owner/repo",
+						ApplyRequirements:    []string{"approved"},
+						Workflow:             String("prod"),
+						AllowedOverrides:     []string{"workflow", "apply_requirements"},
+						AllowCustomWorkflows: Bool(true),
+						AllowRunSteps:        Bool(false),
+						Permissions: []valid.Permission{
+							{Command: "apply", Teams: []string{"owner/admins"}},
+							{Command: "apply", Projects: []string{"prod", "dir"}, Users: []string{"alice"}},
+						},
+					},
+				},
+				Workflows: map[string]valid.Workflow{
+					"prod": {
+						Plan: &valid.Stage{
+							Steps: []valid.Step{{StepName: "init"}, {StepName: "plan"}, {StepName: "policy_check"}},
+						},
+					},
+				},
+				Policies: valid.PolicySets{
+					Approvers:  []string{"alice"},
+					PolicySets: []valid.PolicySet{{Name: "security", Path: "/policies/security.rego"}},
+				},
+			},
+		},
+		{
+			description: "unknown key",
+			input: `
+repos:
+- id: github.com/owner/repo
+  automerge: true`,
+			expErr: "yaml: unmarshal errors:\n  line 3: field automerge not found in struct raw.ServerRepo",
+		},
+		{
+			description: "id is required",
+			input: `
+repos:
+- workflow: prod`,
+			expErr: "repos: (0: (id: cannot be blank.).).",
+		},
+		{
+			description: "invalid regex",
+			input: `
+repos:
+- id: /github.com/(/`,
+			expErr: "repos: (0: (id: parsing /github.com/(/: error parsing regexp: missing closing ): `github.com/(`.).).",
+		},
+		{
+			description: "invalid override",
+			input: `
+repos:
+- id: /.*/
+  allowed_overrides: [automerge]`,
+			expErr: "repos: (0: (allowed_overrides: \"automerge\" is not a valid override, only workflow and apply_requirements are supported.).).",
+		},
+		{
+			description: "permission command is required",
+			input: `
+repos:
+- id: /.*/
+  permissions:
+  - users: [alice]`,
+			expErr: "repos: (0: (permissions: (0: (command: cannot be blank.).).).).",
+		},
+		{
+			description: "invalid permission command",
+			input: `
+repos:
+- id: /.*/
+  permissions:
+  - command: destroy
+    users: [alice]`,
+			expErr: "repos: (0: (permissions: (0: (command: \"destroy\" is not a valid command, only plan, apply, unlock, approve_policies are supported.).).).).",
+		},
+		{
+			description: "empty permission team",
+			input: `
+repos:
+- id: /.*/
+  permissions:
+  - command: apply
+    teams: [""]`,
+			expErr: "repos: (0: (permissions: (0: (teams: cannot contain empty strings.).).).).",
+		},
+		{
+			description: "workflow not defined",
+			input: `
+repos:
+- id: /.*/
+  workflow: prod`,
+			expErr: "repo \"/.*/\" uses workflow \"prod\" which is not defined",
+		},
+		{
+			description: "policy set path is required",
+			input: `
+policies:
+  policy_sets:
+  - name: security`,
+			expErr: "policies: (policy_sets: (0: (path: cannot be blank.).).).",
+		},
+		{
+			description: "duplicate policy set names",
+			input: `
+policies:
+  policy_sets:
+  - name: security
+    path: a.rego
+  - name: security
+    path: b.rego`,
+			expErr: "policies: (policy_sets: policy set name \"security\" is used more than once.).",
+		},
+	}
+
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmpDir, "repos.yaml")
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := ioutil.WriteFile(path, []byte(c.input), 0600)
+			Ok(t, err)
+
+			r := yaml.ParserValidator{}
+			act, err := r.ReadServerConfig(path)
+			if c.expErr != "" {
+				ErrEquals(t, "parsing "+path+": "+c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, act)
+		})
+	}
+}
+
+func TestReadServerConfig_RegexID(t *testing.T) {
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmpDir, "repos.yaml")
+	err := ioutil.WriteFile(path, []byte(`
+repos:
+- id: /github.com/owner/.*/
+  apply_requirements: [approved]
+- id: github.com/owner/repo
+  apply_requirements: []
+  allow_run_steps: true`), 0600)
+	Ok(t, err)
+
+	r := yaml.ParserValidator{}
+	cfg, err := r.ReadServerConfig(path)
+	Ok(t, err)
+
+	_, ok := cfg.PolicyForRepo("github.com/other/repo")
+	Equals(t, false, ok)
+
+	policy, ok := cfg.PolicyForRepo("github.com/owner/another")
+	Equals(t, true, ok)
+	Equals(t, valid.RepoPolicy{ApplyRequirements: []string{"approved"}}, policy)
+
+	t.Log("later repo configs should override earlier ones")
+	policy, ok = cfg.PolicyForRepo("github.com/owner/repo")
+	Equals(t, true, ok)
+	Equals(t, valid.RepoPolicy{ApplyRequirements: []string{}, AllowRunSteps: true}, policy)
+}
+
+func TestReadServerConfig_RelativePolicyPath(t *testing.T) {
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmpDir, "repos.yaml")
+	err := ioutil.WriteFile(path, []byte(`
+policies:
+  policy_sets:
+  - name: security
+    path: policies/security`), 0600)
+	Ok(t, err)
+
+	r := yaml.ParserValidator{}
+	cfg, err := r.ReadServerConfig(path)
+	Ok(t, err)
+	Equals(t, []valid.PolicySet{{Name: "security", Path: filepath.Join(tmpDir, "policies/security")}}, cfg.Policies.PolicySets)
+}
+
 // String is a helper routine that allocates a new string value
 // to store v and returns a pointer to it.
 func String(v string) *string { return &v }
+
+// Bool is a helper routine that allocates a new bool value
+// to store v and returns a pointer to it.
+func Bool(v bool) *bool { return &v }
@@ -10,12 +10,20 @@ import (
 // DefaultAutomerge is the default setting for automerge.
 const DefaultAutomerge = false
 
+// DefaultParallelPlan is the default setting for parallel_plan.
+const DefaultParallelPlan = false
+
+// DefaultParallelApply is the default setting for parallel_apply.
+const DefaultParallelApply = false
+
 // Config is the representation for the whole config file at the top level.
 type Config struct {
-	Version   *int                `yaml:"version,omitempty"`
-	Projects  []Project           `yaml:"projects,omitempty"`
-	Workflows map[string]Workflow `yaml:"workflows,omitempty"`
-	Automerge *bool               `yaml:"automerge,omitempty"`
+	Version       *int                `yaml:"version,omitempty"`
+	Projects      []Project           `yaml:"projects,omitempty"`
+	Workflows     map[string]Workflow `yaml:"workflows,omitempty"`
+	Automerge     *bool               `yaml:"automerge,omitempty"`
+	ParallelPlan  *bool               `yaml:"parallel_plan,omitempty"`
+	ParallelApply *bool               `yaml:"parallel_apply,omitempty"`
 }
 
 func (c Config) Validate() error {
@@ -52,10 +60,21 @@ func (c Config) ToValid() valid.Config {
 		automerge = *c.Automerge
 	}
 
+	parallelPlan := DefaultParallelPlan
+	if c.ParallelPlan != nil {
+		parallelPlan = *c.ParallelPlan
+	}
+	parallelApply := DefaultParallelApply
+	if c.ParallelApply != nil {
+		parallelApply = *c.ParallelApply
+	}
+
 	return valid.Config{
-		Version:   *c.Version,
-		Projects:  validProjects,
-		Workflows: validWorkflows,
-		Automerge: automerge,
+		Version:       *c.Version,
+		Projects:      validProjects,
+		Workflows:     validWorkflows,
+		Automerge:     automerge,
+		ParallelPlan:  parallelPlan,
+		ParallelApply: parallelApply,
 	}
 }
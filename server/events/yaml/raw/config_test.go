@@ -107,6 +107,8 @@ func TestConfig_UnmarshalYAML(t *testing.T) {
 			input: `
 version: 2
 automerge: true
+parallel_plan: true
+parallel_apply: true
 projects:
 - dir: mydir
   workspace: myworkspace
@@ -123,8 +125,10 @@ workflows:
     apply:
      steps: []`,
 			exp: raw.Config{
-				Version:   Int(2),
-				Automerge: Bool(true),
+				Version:       Int(2),
+				Automerge:     Bool(true),
+				ParallelPlan:  Bool(true),
+				ParallelApply: Bool(true),
 				Projects: []raw.Project{
 					{
 						Dir:              String("mydir"),
@@ -261,6 +265,20 @@ func TestConfig_ToValid(t *testing.T) {
 				Workflows: map[string]valid.Workflow{},
 			},
 		},
+		{
+			description: "parallel plan and apply",
+			input: raw.Config{
+				Version:       Int(2),
+				ParallelPlan:  Bool(true),
+				ParallelApply: Bool(false),
+			},
+			exp: valid.Config{
+				Version:       2,
+				ParallelPlan:  true,
+				ParallelApply: false,
+				Workflows:     map[string]valid.Workflow{},
+			},
+		},
 		{
 			description: "everything set",
 			input: raw.Config{
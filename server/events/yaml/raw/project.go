@@ -3,8 +3,10 @@ package raw
 import (
 	"fmt"
 	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/hashicorp/go-version"
@@ -16,16 +18,23 @@ const (
 	DefaultWorkspace          = "default"
 	ApprovedApplyRequirement  = "approved"
 	MergeableApplyRequirement = "mergeable"
+	NoDestroyApplyRequirement = "no_destroy"
+	// ApprovedByOwnersApplyRequirement requires the project's modified files
+	// to be approved by their owners in the repo's CODEOWNERS file.
+	ApprovedByOwnersApplyRequirement = "approved_by_owners"
 )
 
 type Project struct {
-	Name              *string   `yaml:"name,omitempty"`
-	Dir               *string   `yaml:"dir,omitempty"`
-	Workspace         *string   `yaml:"workspace,omitempty"`
-	Workflow          *string   `yaml:"workflow,omitempty"`
-	TerraformVersion  *string   `yaml:"terraform_version,omitempty"`
-	Autoplan          *Autoplan `yaml:"autoplan,omitempty"`
-	ApplyRequirements []string  `yaml:"apply_requirements,omitempty"`
+	Name               *string   `yaml:"name,omitempty"`
+	Dir                *string   `yaml:"dir,omitempty"`
+	Workspace          *string   `yaml:"workspace,omitempty"`
+	Workflow           *string   `yaml:"workflow,omitempty"`
+	TerraformVersion   *string   `yaml:"terraform_version,omitempty"`
+	Autoplan           *Autoplan `yaml:"autoplan,omitempty"`
+	ApplyRequirements  []string  `yaml:"apply_requirements,omitempty"`
+	NoDestroyResources []string  `yaml:"no_destroy_resources,omitempty"`
+	LockTTL            *string   `yaml:"lock_ttl,omitempty"`
+	DependsOn          []string  `yaml:"depends_on,omitempty"`
 }
 
 func (p Project) Validate() error {
@@ -35,15 +44,6 @@ func (p Project) Validate() error {
 		}
 		return nil
 	}
-	validApplyReq := func(value interface{}) error {
-		reqs := value.([]string)
-		for _, r := range reqs {
-			if r != ApprovedApplyRequirement && r != MergeableApplyRequirement {
-				return fmt.Errorf("%q not supported, only %s and %s are supported", r, ApprovedApplyRequirement, MergeableApplyRequirement)
-			}
-		}
-		return nil
-	}
 	validTFVersion := func(value interface{}) error {
 		strPtr := value.(*string)
 		if strPtr == nil {
@@ -65,11 +65,27 @@ func (p Project) Validate() error {
 		}
 		return nil
 	}
+	validLockTTL := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		ttl, err := time.ParseDuration(*strPtr)
+		if err != nil {
+			return fmt.Errorf("%q could not be parsed as a duration, ex. 24h", *strPtr)
+		}
+		if ttl <= 0 {
+			return fmt.Errorf("%q must be greater than 0", *strPtr)
+		}
+		return nil
+	}
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Dir, validation.Required, validation.By(hasDotDot)),
-		validation.Field(&p.ApplyRequirements, validation.By(validApplyReq)),
+		validation.Field(&p.ApplyRequirements, validation.By(validApplyRequirements)),
+		validation.Field(&p.NoDestroyResources, validation.By(validResourcePatterns)),
 		validation.Field(&p.TerraformVersion, validation.By(validTFVersion)),
 		validation.Field(&p.Name, validation.By(validName)),
+		validation.Field(&p.LockTTL, validation.By(validLockTTL)),
 	)
 }
 
@@ -99,12 +115,46 @@ func (p Project) ToValid() valid.Project {
 
 	// There are no default apply requirements.
 	v.ApplyRequirements = p.ApplyRequirements
+	v.NoDestroyResources = p.NoDestroyResources
 
 	v.Name = p.Name
 
+	if p.LockTTL != nil {
+		ttl, _ := time.ParseDuration(*p.LockTTL)
+		v.LockTTL = &ttl
+	}
+
+	v.DependsOn = p.DependsOn
+
 	return v
 }
 
+// validApplyRequirements validates a list of apply_requirements.
+func validApplyRequirements(value interface{}) error {
+	reqs := value.([]string)
+	for _, r := range reqs {
+		if r != ApprovedApplyRequirement && r != MergeableApplyRequirement && r != NoDestroyApplyRequirement && r != ApprovedByOwnersApplyRequirement {
+			return fmt.Errorf("%q not supported, only %s, %s, %s and %s are supported", r, ApprovedApplyRequirement, MergeableApplyRequirement, NoDestroyApplyRequirement, ApprovedByOwnersApplyRequirement)
+		}
+	}
+	return nil
+}
+
+// validResourcePatterns validates a list of resource type patterns. They use
+// the same syntax as path.Match.
+func validResourcePatterns(value interface{}) error {
+	patterns := value.([]string)
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return errors.New("patterns cannot be empty")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%q is not a valid pattern", pattern)
+		}
+	}
+	return nil
+}
+
 // validProjectName returns true if the project name is valid.
 // Since the name might be used in URLs and definitely in files we don't
 // support any characters that must be url escaped *except* for '/' because
@@ -2,6 +2,7 @@ package raw_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/hashicorp/go-version"
@@ -42,7 +43,8 @@ autoplan:
   when_modified: []
   enabled: false
 apply_requirements:
-- mergeable`,
+- mergeable
+depends_on: [network]`,
 			exp: raw.Project{
 				Name:             String("myname"),
 				Dir:              String("mydir"),
@@ -54,6 +56,7 @@ apply_requirements:
 					Enabled:      Bool(false),
 				},
 				ApplyRequirements: []string{"mergeable"},
+				DependsOn:         []string{"network"},
 			},
 		},
 	}
@@ -101,7 +104,7 @@ func TestProject_Validate(t *testing.T) {
 				Dir:               String("."),
 				ApplyRequirements: []string{"unsupported"},
 			},
-			expErr: "apply_requirements: \"unsupported\" not supported, only approved and mergeable are supported.",
+			expErr: "apply_requirements: \"unsupported\" not supported, only approved, mergeable, no_destroy and approved_by_owners are supported.",
 		},
 		{
 			description: "apply reqs with approved requirement",
@@ -127,6 +130,31 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "apply reqs with approved_by_owners requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"approved_by_owners"},
+			},
+			expErr: "",
+		},
+		{
+			description: "apply reqs with no_destroy requirement and resource patterns",
+			input: raw.Project{
+				Dir:                String("."),
+				ApplyRequirements:  []string{"no_destroy"},
+				NoDestroyResources: []string{"aws_db_instance", "aws_rds_*"},
+			},
+			expErr: "",
+		},
+		{
+			description: "invalid no_destroy resource pattern",
+			input: raw.Project{
+				Dir:                String("."),
+				NoDestroyResources: []string{"aws_["},
+			},
+			expErr: "no_destroy_resources: \"aws_[\" is not a valid pattern.",
+		},
 		{
 			description: "empty tf version string",
 			input: raw.Project{
@@ -199,6 +227,30 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: `name: "namewith\\" is not allowed: must contain only URL safe characters.`,
 		},
+		{
+			description: "lock ttl",
+			input: raw.Project{
+				Dir:     String("."),
+				LockTTL: String("72h"),
+			},
+			expErr: "",
+		},
+		{
+			description: "lock ttl not a duration",
+			input: raw.Project{
+				Dir:     String("."),
+				LockTTL: String("3 days"),
+			},
+			expErr: "lock_ttl: \"3 days\" could not be parsed as a duration, ex. 24h.",
+		},
+		{
+			description: "lock ttl negative",
+			input: raw.Project{
+				Dir:     String("."),
+				LockTTL: String("-1h"),
+			},
+			expErr: "lock_ttl: \"-1h\" must be greater than 0.",
+		},
 	}
 	validation.ErrorTag = "yaml"
 	for _, c := range cases {
@@ -215,6 +267,7 @@ func TestProject_Validate(t *testing.T) {
 
 func TestProject_ToValid(t *testing.T) {
 	tfVersionPointEleven, _ := version.NewVersion("v0.11.0")
+	seventyTwoHours := 72 * time.Hour
 	cases := []struct {
 		description string
 		input       raw.Project
@@ -249,8 +302,11 @@ func TestProject_ToValid(t *testing.T) {
 					WhenModified: []string{"hi"},
 					Enabled:      Bool(false),
 				},
-				ApplyRequirements: []string{"approved"},
-				Name:              String("myname"),
+				ApplyRequirements:  []string{"approved", "no_destroy"},
+				NoDestroyResources: []string{"aws_db_*"},
+				Name:               String("myname"),
+				LockTTL:            String("72h"),
+				DependsOn:          []string{"network"},
 			},
 			exp: valid.Project{
 				Dir:              ".",
@@ -261,8 +317,11 @@ func TestProject_ToValid(t *testing.T) {
 					WhenModified: []string{"hi"},
 					Enabled:      false,
 				},
-				ApplyRequirements: []string{"approved"},
-				Name:              String("myname"),
+				ApplyRequirements:  []string{"approved", "no_destroy"},
+				NoDestroyResources: []string{"aws_db_*"},
+				Name:               String("myname"),
+				LockTTL:            &seventyTwoHours,
+				DependsOn:          []string{"network"},
 			},
 		},
 		{
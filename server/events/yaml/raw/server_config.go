@@ -0,0 +1,221 @@
+package raw
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+const (
+	// WorkflowKey is the atlantis.yaml project key that selects a workflow.
+	WorkflowKey = "workflow"
+	// ApplyRequirementsKey is the atlantis.yaml project key that sets the
+	// apply requirements.
+	ApplyRequirementsKey = "apply_requirements"
+)
+
+// permissionCommands are the comment commands that permissions can be set
+// for.
+var permissionCommands = []string{"plan", "apply", "unlock", "approve_policies"}
+
+// ServerConfig is the representation of the server-side repo config file
+// at the top level.
+type ServerConfig struct {
+	Repos     []ServerRepo        `yaml:"repos,omitempty"`
+	Workflows map[string]Workflow `yaml:"workflows,omitempty"`
+	Policies  Policies            `yaml:"policies,omitempty"`
+}
+
+// Policies configures the Rego policies that policy_check steps evaluate
+// plans against and who can approve plans that fail them.
+type Policies struct {
+	Approvers  []string    `yaml:"approvers,omitempty"`
+	PolicySets []PolicySet `yaml:"policy_sets,omitempty"`
+}
+
+// PolicySet is a named .rego file or directory of .rego files.
+type PolicySet struct {
+	Name string `yaml:"name,omitempty"`
+	Path string `yaml:"path,omitempty"`
+}
+
+// ServerRepo is the config for the repos that match ID.
+type ServerRepo struct {
+	// ID is either the exact ID of the repo, ex. github.com/owner/repo, or a
+	// regex surrounded by '/', ex. /github.com/owner/.*/.
+	ID                   *string  `yaml:"id,omitempty"`
+	ApplyRequirements    []string `yaml:"apply_requirements,omitempty"`
+	Workflow             *string  `yaml:"workflow,omitempty"`
+	AllowedOverrides     []string `yaml:"allowed_overrides,omitempty"`
+	AllowCustomWorkflows *bool    `yaml:"allow_custom_workflows,omitempty"`
+	AllowRunSteps        *bool    `yaml:"allow_run_steps,omitempty"`
+	// Permissions is a list since a command can have different rules for
+	// different projects.
+	Permissions []Permission `yaml:"permissions,omitempty"`
+}
+
+// Permission limits who can run a command. If Projects is empty it applies to
+// the whole repo.
+type Permission struct {
+	Command  string   `yaml:"command,omitempty"`
+	Projects []string `yaml:"projects,omitempty"`
+	Users    []string `yaml:"users,omitempty"`
+	Teams    []string `yaml:"teams,omitempty"`
+}
+
+func (s ServerConfig) Validate() error {
+	return validation.ValidateStruct(&s,
+		validation.Field(&s.Repos),
+		validation.Field(&s.Workflows),
+		validation.Field(&s.Policies),
+	)
+}
+
+func (s ServerConfig) ToValid() valid.ServerConfig {
+	var repos []valid.ServerRepo
+	for _, r := range s.Repos {
+		repos = append(repos, r.ToValid())
+	}
+	workflows := make(map[string]valid.Workflow)
+	for k, v := range s.Workflows {
+		workflows[k] = v.ToValid()
+	}
+	return valid.ServerConfig{
+		Repos:     repos,
+		Workflows: workflows,
+		Policies:  s.Policies.ToValid(),
+	}
+}
+
+func (p Policies) Validate() error {
+	uniqueNames := func(value interface{}) error {
+		seen := make(map[string]bool)
+		for _, ps := range value.([]PolicySet) {
+			if seen[ps.Name] {
+				return fmt.Errorf("policy set name %q is used more than once", ps.Name)
+			}
+			seen[ps.Name] = true
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.PolicySets, validation.By(uniqueNames)),
+	)
+}
+
+func (p Policies) ToValid() valid.PolicySets {
+	v := valid.PolicySets{
+		Approvers: p.Approvers,
+	}
+	for _, ps := range p.PolicySets {
+		v.PolicySets = append(v.PolicySets, valid.PolicySet{
+			Name: ps.Name,
+			Path: ps.Path,
+		})
+	}
+	return v
+}
+
+func (p PolicySet) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.Required),
+		validation.Field(&p.Path, validation.Required),
+	)
+}
+
+func (r ServerRepo) Validate() error {
+	validID := func(value interface{}) error {
+		id := *value.(*string)
+		if !isRegexID(id) {
+			return nil
+		}
+		_, err := regexp.Compile(id[1 : len(id)-1])
+		return errors.Wrapf(err, "parsing %s", id)
+	}
+	validOverrides := func(value interface{}) error {
+		for _, o := range value.([]string) {
+			if o != WorkflowKey && o != ApplyRequirementsKey {
+				return fmt.Errorf("%q is not a valid override, only %s and %s are supported", o, WorkflowKey, ApplyRequirementsKey)
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ID, validation.Required, validation.By(validID)),
+		validation.Field(&r.ApplyRequirements, validation.By(validApplyRequirements)),
+		validation.Field(&r.AllowedOverrides, validation.By(validOverrides)),
+		validation.Field(&r.Permissions),
+	)
+}
+
+func (r ServerRepo) ToValid() valid.ServerRepo {
+	v := valid.ServerRepo{
+		ApplyRequirements:    r.ApplyRequirements,
+		Workflow:             r.Workflow,
+		AllowedOverrides:     r.AllowedOverrides,
+		AllowCustomWorkflows: r.AllowCustomWorkflows,
+		AllowRunSteps:        r.AllowRunSteps,
+	}
+	if r.Permissions != nil {
+		v.Permissions = []valid.Permission{}
+		for _, p := range r.Permissions {
+			v.Permissions = append(v.Permissions, p.ToValid())
+		}
+	}
+	if isRegexID(*r.ID) {
+		// We ignore the error here because it should have been checked in
+		// Validate().
+		v.IDRegex, _ = regexp.Compile((*r.ID)[1 : len(*r.ID)-1])
+	} else {
+		v.ID = *r.ID
+	}
+	return v
+}
+
+func (p Permission) Validate() error {
+	validCommand := func(value interface{}) error {
+		command := value.(string)
+		for _, c := range permissionCommands {
+			if command == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not a valid command, only %s are supported", command, strings.Join(permissionCommands, ", "))
+	}
+	notEmpty := func(value interface{}) error {
+		for _, s := range value.([]string) {
+			if s == "" {
+				return errors.New("cannot contain empty strings")
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Command, validation.Required, validation.By(validCommand)),
+		validation.Field(&p.Projects, validation.By(notEmpty)),
+		validation.Field(&p.Users, validation.By(notEmpty)),
+		validation.Field(&p.Teams, validation.By(notEmpty)),
+	)
+}
+
+func (p Permission) ToValid() valid.Permission {
+	v := valid.Permission{
+		Command: p.Command,
+		Users:   p.Users,
+		Teams:   p.Teams,
+	}
+	for _, project := range p.Projects {
+		v.Projects = append(v.Projects, path.Clean(project))
+	}
+	return v
+}
+
+// isRegexID returns true if id is a regex, i.e. it's surrounded by '/'.
+func isRegexID(id string) bool {
+	return len(id) > 1 && strings.HasPrefix(id, "/") && strings.HasSuffix(id, "/")
+}
@@ -12,17 +12,19 @@ import (
 )
 
 const (
-	ExtraArgsKey  = "extra_args"
-	RunStepName   = "run"
-	PlanStepName  = "plan"
-	ApplyStepName = "apply"
-	InitStepName  = "init"
+	ExtraArgsKey        = "extra_args"
+	RunStepName         = "run"
+	PlanStepName        = "plan"
+	ApplyStepName       = "apply"
+	InitStepName        = "init"
+	PolicyCheckStepName = "policy_check"
 )
 
 // Step represents a single action/command to perform. In YAML, it can be set as
 // 1. A single string for a built-in command:
 //    - init
 //    - plan
+//    - policy_check
 // 2. A map for a built-in command and extra_args:
 //    - plan:
 //        extra_args: [-var-file=staging.tfvars]
@@ -82,7 +84,7 @@ func (s *Step) UnmarshalYAML(unmarshal func(interface{}) error) error {
 func (s Step) Validate() error {
 	validStep := func(value interface{}) error {
 		str := *value.(*string)
-		if str != InitStepName && str != PlanStepName && str != ApplyStepName {
+		if str != InitStepName && str != PlanStepName && str != ApplyStepName && str != PolicyCheckStepName {
 			return fmt.Errorf("%q is not a valid step type, maybe you omitted the 'run' key", str)
 		}
 		return nil
@@ -162,6 +162,13 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "policy_check step",
+			input: raw.Step{
+				Key: String("policy_check"),
+			},
+			expErr: "",
+		},
 		{
 			description: "init extra_args",
 			input: raw.Step{
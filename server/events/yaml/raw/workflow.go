@@ -1,6 +1,8 @@
 package raw
 
 import (
+	"fmt"
+
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
@@ -11,8 +13,22 @@ type Workflow struct {
 }
 
 func (w Workflow) Validate() error {
+	// Policies are checked against the plan so policy_check steps can only
+	// be used in the plan stage.
+	noPolicyCheck := func(value interface{}) error {
+		stage := value.(*Stage)
+		if stage == nil {
+			return nil
+		}
+		for _, step := range stage.Steps {
+			if step.Key != nil && *step.Key == PolicyCheckStepName {
+				return fmt.Errorf("%q steps can only be used in the plan stage", PolicyCheckStepName)
+			}
+		}
+		return nil
+	}
 	return validation.ValidateStruct(&w,
-		validation.Field(&w.Apply),
+		validation.Field(&w.Apply, validation.By(noPolicyCheck)),
 		validation.Field(&w.Plan),
 	)
 }
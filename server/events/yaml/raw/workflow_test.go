@@ -108,6 +108,18 @@ func TestWorkflow_Validate(t *testing.T) {
 
 	// Unset keys should validate.
 	Ok(t, (raw.Workflow{}).Validate())
+
+	// Policies can only be checked in the plan stage.
+	w = raw.Workflow{
+		Plan: &raw.Stage{
+			Steps: []raw.Step{{Key: String("plan")}, {Key: String("policy_check")}},
+		},
+	}
+	Ok(t, w.Validate())
+	w.Apply = &raw.Stage{
+		Steps: []raw.Step{{Key: String("policy_check")}, {Key: String("apply")}},
+	}
+	ErrEquals(t, "apply: \"policy_check\" steps can only be used in the plan stage.", w.Validate())
 }
 
 func TestWorkflow_ToValid(t *testing.T) {
@@ -0,0 +1,166 @@
+package valid
+
+import "regexp"
+
+// ServerConfig is the server-side repo config after it's been parsed and
+// validated. It's set by the Atlantis operator and lets them configure repos
+// without relying on each repo's atlantis.yaml file.
+type ServerConfig struct {
+	Repos []ServerRepo
+	// Workflows can be used by any repo.
+	Workflows map[string]Workflow
+	// Policies are evaluated by policy_check steps.
+	Policies PolicySets
+}
+
+// PolicySets are the Rego policies that plans are checked against.
+type PolicySets struct {
+	// Approvers are the usernames of the users that can approve plans that
+	// fail policy checks.
+	Approvers  []string
+	PolicySets []PolicySet
+}
+
+// PolicySet is a named .rego file or directory of .rego files.
+type PolicySet struct {
+	Name string
+	// Path is the absolute path to the .rego file or directory.
+	Path string
+}
+
+// ServerRepo is the config for the repos matching either ID or IDRegex.
+// Fields that weren't set in the file are nil.
+type ServerRepo struct {
+	// ID is the exact ID of the repo, ex. github.com/owner/repo. It's empty
+	// if IDRegex is set.
+	ID                   string
+	IDRegex              *regexp.Regexp
+	ApplyRequirements    []string
+	Workflow             *string
+	AllowedOverrides     []string
+	AllowCustomWorkflows *bool
+	AllowRunSteps        *bool
+	Permissions          []Permission
+}
+
+// Permission limits who can run Command. Users can run it if their username
+// is in Users or they're a member of one of Teams.
+type Permission struct {
+	// Command is the name of the comment command, ex. apply.
+	Command string
+	// Projects are the names or dirs of the projects the permission applies
+	// to. If it's empty, it applies to the whole repo.
+	Projects []string
+	Users    []string
+	// Teams are GitHub teams in the format {org}/{team slug} or the full
+	// paths of GitLab groups.
+	Teams []string
+}
+
+// RepoPolicy is the server-side config for a single repo after merging all
+// the ServerRepos that matched it.
+type RepoPolicy struct {
+	// ApplyRequirements are the default apply requirements for the repo's
+	// projects.
+	ApplyRequirements []string
+	// Workflow is the name of the default workflow for the repo's projects.
+	// It's nil if there is no default.
+	Workflow *string
+	// AllowedOverrides are the atlantis.yaml project keys that the repo can
+	// set to override the defaults above.
+	AllowedOverrides []string
+	// AllowCustomWorkflows is true if the repo's atlantis.yaml can define its
+	// own workflows.
+	AllowCustomWorkflows bool
+	// AllowRunSteps is true if the repo's own workflows can use run steps.
+	AllowRunSteps bool
+	// Permissions limit who can run commands in the repo. Commands without
+	// any permissions can be run by anyone.
+	Permissions []Permission
+}
+
+// Matches returns true if repoID matches this repo config.
+func (r ServerRepo) Matches(repoID string) bool {
+	if r.IDRegex != nil {
+		return r.IDRegex.MatchString(repoID)
+	}
+	return r.ID == repoID
+}
+
+// PolicyForRepo returns the policy for the repo with ID repoID, ex.
+// github.com/owner/repo. All the repo configs that match are merged in order,
+// so a key set by a later repo config overrides the same key from an earlier
+// one. It returns false if no repo configs matched.
+func (s ServerConfig) PolicyForRepo(repoID string) (RepoPolicy, bool) {
+	var policy RepoPolicy
+	matched := false
+	for _, r := range s.Repos {
+		if !r.Matches(repoID) {
+			continue
+		}
+		matched = true
+		if r.ApplyRequirements != nil {
+			policy.ApplyRequirements = r.ApplyRequirements
+		}
+		if r.Workflow != nil {
+			policy.Workflow = r.Workflow
+		}
+		if r.AllowedOverrides != nil {
+			policy.AllowedOverrides = r.AllowedOverrides
+		}
+		if r.AllowCustomWorkflows != nil {
+			policy.AllowCustomWorkflows = *r.AllowCustomWorkflows
+		}
+		if r.AllowRunSteps != nil {
+			policy.AllowRunSteps = *r.AllowRunSteps
+		}
+		if r.Permissions != nil {
+			policy.Permissions = r.Permissions
+		}
+	}
+	return policy, matched
+}
+
+// IsOverrideAllowed returns true if the repo's atlantis.yaml can set the
+// project key, ex. workflow.
+func (p RepoPolicy) IsOverrideAllowed(key string) bool {
+	for _, o := range p.AllowedOverrides {
+		if o == key {
+			return true
+		}
+	}
+	return false
+}
+
+// IsApprover returns true if username can approve plans that fail policy
+// checks.
+func (p PolicySets) IsApprover(username string) bool {
+	for _, a := range p.Approvers {
+		if a == username {
+			return true
+		}
+	}
+	return false
+}
+
+// HasUser returns true if username is one of the permission's users.
+func (p Permission) HasUser(username string) bool {
+	for _, u := range p.Users {
+		if u == username {
+			return true
+		}
+	}
+	return false
+}
+
+// AppliesTo returns true if the permission is specific to projects and one of
+// them is either the project named projectName or the project in repoRelDir.
+// Either can be empty if it's unknown.
+func (p Permission) AppliesTo(projectName string, repoRelDir string) bool {
+	for _, project := range p.Projects {
+		if (projectName != "" && project == projectName) || (repoRelDir != "" && project == repoRelDir) {
+			return true
+		}
+	}
+	return false
+}
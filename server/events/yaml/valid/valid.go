@@ -2,7 +2,12 @@
 // after it's been parsed and validated.
 package valid
 
-import "github.com/hashicorp/go-version"
+import (
+	"path"
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
 
 // Config is the atlantis.yaml config after it's been parsed and validated.
 type Config struct {
@@ -12,6 +17,12 @@ type Config struct {
 	Projects  []Project
 	Workflows map[string]Workflow
 	Automerge bool
+	// ParallelPlan is true if the projects in this repo should be planned
+	// concurrently.
+	ParallelPlan bool
+	// ParallelApply is true if the projects in this repo should be applied
+	// concurrently.
+	ParallelApply bool
 }
 
 func (c Config) GetPlanStage(workflowName string) *Stage {
@@ -70,6 +81,16 @@ type Project struct {
 	TerraformVersion  *version.Version
 	Autoplan          Autoplan
 	ApplyRequirements []string
+	// NoDestroyResources are patterns of the resource types that the
+	// no_destroy apply requirement protects, ex. aws_db_*. If empty, it
+	// protects all resources.
+	NoDestroyResources []string
+	// LockTTL is how long this project's lock can be held for before it's
+	// released automatically. If nil, the server's default is used.
+	LockTTL *time.Duration
+	// DependsOn are the names of the projects that must be applied before
+	// this project.
+	DependsOn []string
 }
 
 // GetName returns the name of the project or an empty string if there is no
@@ -81,6 +102,21 @@ func (p Project) GetName() string {
 	return ""
 }
 
+// IsDestroyProtected returns true if the no_destroy apply requirement
+// protects resources of resourceType, ex. aws_db_instance.
+func (p Project) IsDestroyProtected(resourceType string) bool {
+	if len(p.NoDestroyResources) == 0 {
+		return true
+	}
+	for _, pattern := range p.NoDestroyResources {
+		// The patterns were validated when the config was parsed.
+		if matched, _ := path.Match(pattern, resourceType); matched {
+			return true
+		}
+	}
+	return false
+}
+
 type Autoplan struct {
 	WhenModified []string
 	Enabled      bool
@@ -14,6 +14,7 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -24,12 +25,16 @@ import (
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/vcs/azuredevops"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/runatlantis/atlantis/server/events/vcs/gitea"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 )
 
 const githubHeader = "X-Github-Event"
+const githubRequestIDHeader = "X-Github-Delivery"
 const gitlabHeader = "X-Gitlab-Event"
 
 // bitbucketEventTypeHeader is the same in both cloud and server.
@@ -38,6 +43,15 @@ const bitbucketCloudRequestIDHeader = "X-Request-UUID"
 const bitbucketServerRequestIDHeader = "X-Request-ID"
 const bitbucketServerSignatureHeader = "X-Hub-Signature"
 
+// azuredevopsHeader is sent with every Azure DevOps service hook request. The
+// event type is only in the body.
+const azuredevopsHeader = "Request-Id"
+
+// Forgejo sends the same headers as Gitea.
+const giteaHeader = "X-Gitea-Event"
+const giteaRequestIDHeader = "X-Gitea-Delivery"
+const giteaSignatureHeader = "X-Gitea-Signature"
+
 // EventsController handles all webhook requests which signify 'events' in the
 // VCS host, ex. GitHub.
 type EventsController struct {
@@ -69,11 +83,32 @@ type EventsController struct {
 	// UI that identifies this call as coming from Bitbucket. If empty, no
 	// request validation is done.
 	BitbucketWebhookSecret []byte
+	// AzureDevopsWebhookUser and AzureDevopsWebhookPassword are the basic
+	// auth credentials added to the service hooks via the Azure DevOps UI.
+	// If AzureDevopsWebhookUser is empty, no request validation is done.
+	AzureDevopsWebhookUser     []byte
+	AzureDevopsWebhookPassword []byte
+	// GiteaWebhookSecret is the secret added to this webhook via the Gitea
+	// UI that identifies this call as coming from Gitea. If empty, no
+	// request validation is done.
+	GiteaWebhookSecret []byte
 }
 
 // Post handles POST webhook requests.
 func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
-	if r.Header.Get(githubHeader) != "" {
+	// Gitea also sends the GitHub event header so we need to check for it
+	// first.
+	if r.Header.Get(giteaHeader) != "" {
+		metrics.WebhookEvents.WithLabelValues(models.Gitea.String()).Inc()
+		if !e.supportsHost(models.Gitea) {
+			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Gitea")
+			return
+		}
+		e.Logger.Debug("handling Gitea post")
+		e.handleGiteaPost(w, r)
+		return
+	} else if r.Header.Get(githubHeader) != "" {
+		metrics.WebhookEvents.WithLabelValues(models.Github.String()).Inc()
 		if !e.supportsHost(models.Github) {
 			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitHub")
 			return
@@ -82,6 +117,7 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 		e.handleGithubPost(w, r)
 		return
 	} else if r.Header.Get(gitlabHeader) != "" {
+		metrics.WebhookEvents.WithLabelValues(models.Gitlab.String()).Inc()
 		if !e.supportsHost(models.Gitlab) {
 			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitLab")
 			return
@@ -93,6 +129,7 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 		// Bitbucket Cloud and Server use the same event type header but they
 		// use different request ID headers.
 		if r.Header.Get(bitbucketCloudRequestIDHeader) != "" {
+			metrics.WebhookEvents.WithLabelValues(models.BitbucketCloud.String()).Inc()
 			if !e.supportsHost(models.BitbucketCloud) {
 				e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket Cloud")
 				return
@@ -101,6 +138,7 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 			e.handleBitbucketCloudPost(w, r)
 			return
 		} else if r.Header.Get(bitbucketServerRequestIDHeader) != "" {
+			metrics.WebhookEvents.WithLabelValues(models.BitbucketServer.String()).Inc()
 			if !e.supportsHost(models.BitbucketServer) {
 				e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Bitbucket Server")
 				return
@@ -109,6 +147,15 @@ func (e *EventsController) Post(w http.ResponseWriter, r *http.Request) {
 			e.handleBitbucketServerPost(w, r)
 			return
 		}
+	} else if r.Header.Get(azuredevopsHeader) != "" {
+		metrics.WebhookEvents.WithLabelValues(models.AzureDevops.String()).Inc()
+		if !e.supportsHost(models.AzureDevops) {
+			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support Azure DevOps")
+			return
+		}
+		e.Logger.Debug("handling Azure DevOps post")
+		e.handleAzureDevopsPost(w, r)
+		return
 	}
 	e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request")
 }
@@ -122,7 +169,7 @@ func (e *EventsController) handleGithubPost(w http.ResponseWriter, r *http.Reque
 	}
 	e.Logger.Debug("request valid")
 
-	githubReqID := "X-Github-Delivery=" + r.Header.Get("X-Github-Delivery")
+	githubReqID := r.Header.Get(githubRequestIDHeader)
 	event, _ := github.ParseWebHook(github.WebHookType(r), payload)
 	switch event := event.(type) {
 	case *github.IssueCommentEvent:
@@ -131,8 +178,11 @@ func (e *EventsController) handleGithubPost(w http.ResponseWriter, r *http.Reque
 	case *github.PullRequestEvent:
 		e.Logger.Debug("handling as pull request event")
 		e.HandleGithubPullRequestEvent(w, event, githubReqID)
+	case *github.CheckRunEvent:
+		e.Logger.Debug("handling as check run event")
+		e.HandleGithubCheckRunEvent(w, event, githubReqID)
 	default:
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event %s", githubReqID)
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event %s=%s", githubRequestIDHeader, githubReqID)
 	}
 }
 
@@ -195,23 +245,131 @@ func (e *EventsController) handleBitbucketServerPost(w http.ResponseWriter, r *h
 	}
 }
 
+func (e *EventsController) handleAzureDevopsPost(w http.ResponseWriter, r *http.Request) {
+	reqID := r.Header.Get(azuredevopsHeader)
+	if len(e.AzureDevopsWebhookUser) > 0 {
+		if err := azuredevops.ValidateBasicAuth(r, e.AzureDevopsWebhookUser, e.AzureDevopsWebhookPassword); err != nil {
+			e.respond(w, logging.Warn, http.StatusUnauthorized, "%s", errors.Wrap(err, "request did not pass validation").Error())
+			return
+		}
+	}
+	defer r.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s=%s", err, azuredevopsHeader, reqID)
+		return
+	}
+	// Azure DevOps sends the event type in the body rather than a header.
+	var event azuredevops.Event
+	if err := json.Unmarshal(body, &event); err != nil || event.EventType == nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to determine event type %s=%s", azuredevopsHeader, reqID)
+		return
+	}
+	switch *event.EventType {
+	case azuredevops.PullCreatedEvent, azuredevops.PullUpdatedEvent:
+		e.Logger.Debug("handling as pull request state changed event")
+		e.HandleAzureDevopsPullRequestEvent(w, body, reqID)
+	case azuredevops.PullCommentCreatedEvent:
+		e.Logger.Debug("handling as comment created event")
+		e.HandleAzureDevopsCommentEvent(w, body, reqID)
+	default:
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s=%s", *event.EventType, azuredevopsHeader, reqID)
+	}
+}
+
+func (e *EventsController) handleGiteaPost(w http.ResponseWriter, r *http.Request) {
+	eventType := r.Header.Get(giteaHeader)
+	reqID := r.Header.Get(giteaRequestIDHeader)
+	sig := r.Header.Get(giteaSignatureHeader)
+	defer r.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s=%s", err, giteaRequestIDHeader, reqID)
+		return
+	}
+	if len(e.GiteaWebhookSecret) > 0 {
+		if err := gitea.ValidateSignature(body, sig, e.GiteaWebhookSecret); err != nil {
+			e.respond(w, logging.Warn, http.StatusBadRequest, "%s", errors.Wrap(err, "request did not pass validation").Error())
+			return
+		}
+	}
+	switch eventType {
+	case gitea.PullRequestHeader:
+		var event gitea.PullRequestEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			e.respond(w, logging.Error, http.StatusBadRequest, "Unable to parse body: %s %s=%s", err, giteaRequestIDHeader, reqID)
+			return
+		}
+		e.Logger.Debug("handling as pull request event")
+		e.HandleGiteaPullRequestEvent(w, event, reqID)
+	case gitea.IssueCommentHeader:
+		var event gitea.IssueCommentEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			e.respond(w, logging.Error, http.StatusBadRequest, "Unable to parse body: %s %s=%s", err, giteaRequestIDHeader, reqID)
+			return
+		}
+		e.Logger.Debug("handling as comment event")
+		e.HandleGiteaCommentEvent(w, event, reqID)
+	default:
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s=%s", eventType, giteaRequestIDHeader, reqID)
+	}
+}
+
 // HandleGithubCommentEvent handles comment events from GitHub where Atlantis
 // commands can come from. It's exported to make testing easier.
 func (e *EventsController) HandleGithubCommentEvent(w http.ResponseWriter, event *github.IssueCommentEvent, githubReqID string) {
 	if event.GetAction() != "created" {
-		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since action was not created %s", githubReqID)
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since action was not created %s=%s", githubRequestIDHeader, githubReqID)
 		return
 	}
 
 	baseRepo, user, pullNum, err := e.Parser.ParseGithubIssueCommentEvent(event)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, githubReqID)
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s=%s", err, githubRequestIDHeader, githubReqID)
 		return
 	}
 
 	// We pass in nil for maybeHeadRepo because the head repo data isn't
 	// available in the GithubIssueComment event.
-	e.handleCommentEvent(w, baseRepo, nil, nil, user, pullNum, event.Comment.GetBody(), models.Github)
+	e.handleCommentEvent(w, events.NewRequestContext(githubReqID), baseRepo, nil, nil, user, pullNum, event.Comment.GetBody(), models.Github)
+}
+
+// HandleGithubCheckRunEvent handles check run events from GitHub. When the
+// Apply button on one of our check runs is clicked, it applies the check
+// run's project the same as an apply comment would. It's exported to make
+// testing easier.
+func (e *EventsController) HandleGithubCheckRunEvent(w http.ResponseWriter, event *github.CheckRunEvent, githubReqID string) {
+	if event.GetAction() != "requested_action" || event.GetRequestedAction() == nil || event.GetRequestedAction().Identifier != events.ApplyCheckRunAction {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring check run event since it wasn't an apply action %s=%s", githubRequestIDHeader, githubReqID)
+		return
+	}
+
+	baseRepo, user, pullNum, err := e.Parser.ParseGithubCheckRunEvent(event)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s=%s", err, githubRequestIDHeader, githubReqID)
+		return
+	}
+	cmd, err := events.NewCheckRunApplyCommand(event.GetCheckRun().GetExternalID())
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s=%s", err, githubRequestIDHeader, githubReqID)
+		return
+	}
+	e.Logger.Info("parsed check run action as %s", cmd)
+
+	if !e.RepoWhitelistChecker.IsWhitelisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
+		e.commentNotWhitelisted(baseRepo, pullNum)
+		e.respond(w, logging.Warn, http.StatusForbidden, "Repo not whitelisted")
+		return
+	}
+
+	e.Logger.Debug("executing command")
+	fmt.Fprintln(w, "Processing...")
+	reqCtx := events.NewRequestContext(githubReqID)
+	if !e.TestingMode {
+		go e.CommandRunner.RunCommentCommand(reqCtx, baseRepo, nil, nil, user, pullNum, cmd)
+	} else {
+		e.CommandRunner.RunCommentCommand(reqCtx, baseRepo, nil, nil, user, pullNum, cmd)
+	}
 }
 
 // HandleBitbucketCloudCommentEvent handles comment events from Bitbucket.
@@ -221,7 +379,7 @@ func (e *EventsController) HandleBitbucketCloudCommentEvent(w http.ResponseWrite
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, &pull, user, pull.Num, comment, models.BitbucketCloud)
+	e.handleCommentEvent(w, events.NewRequestContext(reqID), baseRepo, &headRepo, &pull, user, pull.Num, comment, models.BitbucketCloud)
 }
 
 // HandleBitbucketServerCommentEvent handles comment events from Bitbucket.
@@ -231,7 +389,65 @@ func (e *EventsController) HandleBitbucketServerCommentEvent(w http.ResponseWrit
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, &pull, user, pull.Num, comment, models.BitbucketCloud)
+	e.handleCommentEvent(w, events.NewRequestContext(reqID), baseRepo, &headRepo, &pull, user, pull.Num, comment, models.BitbucketCloud)
+}
+
+// HandleAzureDevopsCommentEvent handles comment events from Azure DevOps.
+func (e *EventsController) HandleAzureDevopsCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
+	pull, baseRepo, headRepo, user, comment, err := e.Parser.ParseAzureDevopsPullCommentEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, azuredevopsHeader, reqID)
+		return
+	}
+	e.handleCommentEvent(w, events.NewRequestContext(reqID), baseRepo, &headRepo, &pull, user, pull.Num, comment, models.AzureDevops)
+}
+
+// HandleAzureDevopsPullRequestEvent handles pull request events from Azure
+// DevOps.
+func (e *EventsController) HandleAzureDevopsPullRequestEvent(w http.ResponseWriter, body []byte, reqID string) {
+	pull, pullEventType, baseRepo, headRepo, user, err := e.Parser.ParseAzureDevopsPullEvent(body)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, azuredevopsHeader, reqID)
+		return
+	}
+	e.Logger.Info("identified event as type %q", pullEventType.String())
+	e.handlePullRequestEvent(w, events.NewRequestContext(reqID), baseRepo, headRepo, pull, user, pullEventType)
+}
+
+// HandleGiteaCommentEvent handles comment events from Gitea where Atlantis
+// commands can come from. It's exported to make testing easier.
+func (e *EventsController) HandleGiteaCommentEvent(w http.ResponseWriter, event gitea.IssueCommentEvent, reqID string) {
+	if event.Action == nil || *event.Action != gitea.CommentCreatedAction {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since action was not created %s=%s", giteaRequestIDHeader, reqID)
+		return
+	}
+	// Gitea sends the same event for comments on issues and pull requests.
+	if !event.IsPull {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since it was not on a pull request %s=%s", giteaRequestIDHeader, reqID)
+		return
+	}
+
+	baseRepo, user, pullNum, err := e.Parser.ParseGiteaIssueCommentEvent(event)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s=%s", err, giteaRequestIDHeader, reqID)
+		return
+	}
+
+	// We pass in nil for maybeHeadRepo and maybePull because the pull request
+	// data isn't available in the comment event.
+	e.handleCommentEvent(w, events.NewRequestContext(reqID), baseRepo, nil, nil, user, pullNum, *event.Comment.Body, models.Gitea)
+}
+
+// HandleGiteaPullRequestEvent handles pull request events from Gitea. It's
+// exported to make testing easier.
+func (e *EventsController) HandleGiteaPullRequestEvent(w http.ResponseWriter, event gitea.PullRequestEvent, reqID string) {
+	pull, pullEventType, baseRepo, headRepo, user, err := e.Parser.ParseGiteaPullEvent(event)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, giteaRequestIDHeader, reqID)
+		return
+	}
+	e.Logger.Info("identified event as type %q", pullEventType.String())
+	e.handlePullRequestEvent(w, events.NewRequestContext(reqID), baseRepo, headRepo, pull, user, pullEventType)
 }
 
 func (e *EventsController) handleBitbucketCloudPullRequestEvent(w http.ResponseWriter, eventType string, body []byte, reqID string) {
@@ -242,7 +458,7 @@ func (e *EventsController) handleBitbucketCloudPullRequestEvent(w http.ResponseW
 	}
 	pullEventType := e.Parser.GetBitbucketCloudPullEventType(eventType)
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	e.handlePullRequestEvent(w, events.NewRequestContext(reqID), baseRepo, headRepo, pull, user, pullEventType)
 }
 
 func (e *EventsController) handleBitbucketServerPullRequestEvent(w http.ResponseWriter, eventType string, body []byte, reqID string) {
@@ -253,7 +469,7 @@ func (e *EventsController) handleBitbucketServerPullRequestEvent(w http.Response
 	}
 	pullEventType := e.Parser.GetBitbucketServerPullEventType(eventType)
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	e.handlePullRequestEvent(w, events.NewRequestContext(reqID), baseRepo, headRepo, pull, user, pullEventType)
 }
 
 // HandleGithubPullRequestEvent will delete any locks associated with the pull
@@ -262,14 +478,14 @@ func (e *EventsController) handleBitbucketServerPullRequestEvent(w http.Response
 func (e *EventsController) HandleGithubPullRequestEvent(w http.ResponseWriter, pullEvent *github.PullRequestEvent, githubReqID string) {
 	pull, pullEventType, baseRepo, headRepo, user, err := e.Parser.ParseGithubPullEvent(pullEvent)
 	if err != nil {
-		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, githubReqID)
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, githubRequestIDHeader, githubReqID)
 		return
 	}
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	e.handlePullRequestEvent(w, events.NewRequestContext(githubReqID), baseRepo, headRepo, pull, user, pullEventType)
 }
 
-func (e *EventsController) handlePullRequestEvent(w http.ResponseWriter, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User, eventType models.PullRequestEventType) {
+func (e *EventsController) handlePullRequestEvent(w http.ResponseWriter, reqCtx events.RequestContext, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User, eventType models.PullRequestEventType) {
 	if !e.RepoWhitelistChecker.IsWhitelisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
 		// If the repo isn't whitelisted and we receive an opened pull request
 		// event we comment back on the pull request that the repo isn't
@@ -293,10 +509,10 @@ func (e *EventsController) handlePullRequestEvent(w http.ResponseWriter, baseRep
 
 		e.Logger.Info("executing autoplan")
 		if !e.TestingMode {
-			go e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
+			go e.CommandRunner.RunAutoplanCommand(reqCtx, baseRepo, headRepo, pull, user)
 		} else {
 			// When testing we want to wait for everything to complete.
-			e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
+			e.CommandRunner.RunAutoplanCommand(reqCtx, baseRepo, headRepo, pull, user)
 		}
 		return
 	case models.ClosedPullEvent:
@@ -348,10 +564,11 @@ func (e *EventsController) HandleGitlabCommentEvent(w http.ResponseWriter, event
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing webhook: %s", err)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, nil, user, event.MergeRequest.IID, event.ObjectAttributes.Note, models.Gitlab)
+	// GitLab doesn't send a request ID with its webhooks.
+	e.handleCommentEvent(w, events.NewRequestContext(""), baseRepo, &headRepo, nil, user, event.MergeRequest.IID, event.ObjectAttributes.Note, models.Gitlab)
 }
 
-func (e *EventsController) handleCommentEvent(w http.ResponseWriter, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, comment string, vcsHost models.VCSHostType) {
+func (e *EventsController) handleCommentEvent(w http.ResponseWriter, reqCtx events.RequestContext, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, comment string, vcsHost models.VCSHostType) {
 	parseResult := e.CommentParser.Parse(comment, vcsHost)
 	if parseResult.Ignore {
 		truncated := comment
@@ -390,10 +607,10 @@ func (e *EventsController) handleCommentEvent(w http.ResponseWriter, baseRepo mo
 		// Respond with success and then actually execute the command asynchronously.
 		// We use a goroutine so that this function returns and the connection is
 		// closed.
-		go e.CommandRunner.RunCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
+		go e.CommandRunner.RunCommentCommand(reqCtx, baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
 	} else {
 		// When testing we want to wait for everything to complete.
-		e.CommandRunner.RunCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
+		e.CommandRunner.RunCommentCommand(reqCtx, baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
 	}
 }
 
@@ -407,7 +624,7 @@ func (e *EventsController) HandleGitlabMergeRequestEvent(w http.ResponseWriter,
 		return
 	}
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	e.handlePullRequestEvent(w, events.NewRequestContext(""), baseRepo, headRepo, pull, user, pullEventType)
 }
 
 // supportsHost returns true if h is in e.SupportedVCSHosts and false otherwise.
@@ -25,6 +25,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/runtime"
 	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/events/vcs"
 	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/yaml"
@@ -369,10 +370,9 @@ func setupE2E(t *testing.T) (server.EventsController, *vcsmocks.MockClient, *moc
 	// Real dependencies.
 	logger := logging.NewSimpleLogger("server", true, logging.Debug)
 	eventParser := &events.EventParser{
-		GithubUser:  "github-user",
-		GithubToken: "github-token",
-		GitlabUser:  "gitlab-user",
-		GitlabToken: "gitlab-token",
+		GithubCredentials: &vcs.GithubUserCredentials{User: "github-user", Token: "github-token"},
+		GitlabUser:        "gitlab-user",
+		GitlabToken:       "gitlab-token",
 	}
 	commentParser := &events.CommentParser{
 		GithubUser: "github-user",
@@ -404,9 +404,10 @@ func setupE2E(t *testing.T) (server.EventsController, *vcsmocks.MockClient, *moc
 			PlanStepRunner: &runtime.PlanStepRunner{
 				TerraformExecutor: terraformClient,
 				DefaultTFVersion:  defaultTFVersion,
+				AsyncTFExec:       terraformClient,
 			},
 			ApplyStepRunner: &runtime.ApplyStepRunner{
-				TerraformExecutor: terraformClient,
+				AsyncTFExec: terraformClient,
 			},
 			RunStepRunner: &runtime.RunStepRunner{
 				DefaultTFVersion: defaultTFVersion,
@@ -440,6 +441,7 @@ func setupE2E(t *testing.T) (server.EventsController, *vcsmocks.MockClient, *moc
 		PendingPlanFinder: &events.DefaultPendingPlanFinder{},
 		GlobalAutomerge:   false,
 		WorkingDir:        workingDir,
+		WorkingDirLocker:  locker,
 	}
 
 	repoWhitelistChecker, err := events.NewRepoWhitelistChecker("*")
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// githubManifestAccept is the Accept header required by the app manifest
+// endpoint while it's in preview.
+const githubManifestAccept = "application/vnd.github.fury-preview+json"
+
+// GithubAppController handles the pages that create a GitHub App for
+// Atlantis from a manifest.
+// See https://developer.github.com/apps/building-github-apps/creating-github-apps-from-a-manifest/.
+type GithubAppController struct {
+	AtlantisURL *url.URL
+	Logger      *logging.SimpleLogger
+	// GithubSetupComplete is true if Atlantis is already configured to run as
+	// a GitHub App in which case these pages are disabled.
+	GithubSetupComplete bool
+	// GithubHostname is the hostname of GitHub, ex. github.com.
+	GithubHostname string
+	// GithubAPIURL is the base URL of the GitHub API without a trailing
+	// slash, ex. https://api.github.com.
+	GithubAPIURL string
+	// HTTPClient is used to exchange the code for the app's credentials.
+	HTTPClient           *http.Client
+	SetupTemplate        TemplateWriter
+	ExchangeCodeTemplate TemplateWriter
+}
+
+// GithubAppSetupData holds the data for rendering the GitHub App setup page.
+type GithubAppSetupData struct {
+	// Target is the GitHub URL the manifest is posted to.
+	Target string
+	// Manifest is the JSON encoded app manifest.
+	Manifest        string
+	CleanedBasePath string
+}
+
+// GithubAppExchangeCodeData holds the data for rendering the page that shows
+// the credentials of the newly created GitHub App.
+type GithubAppExchangeCodeData struct {
+	ID              int64
+	Slug            string
+	URL             string
+	Key             string
+	WebhookSecret   string
+	CleanedBasePath string
+}
+
+// githubAppManifest is the manifest GitHub creates the app from.
+type githubAppManifest struct {
+	Name           string            `json:"name"`
+	Description    string            `json:"description"`
+	URL            string            `json:"url"`
+	RedirectURL    string            `json:"redirect_url"`
+	Public         bool              `json:"public"`
+	HookAttributes map[string]string `json:"hook_attributes"`
+	DefaultEvents  []string          `json:"default_events"`
+	DefaultPerms   map[string]string `json:"default_permissions"`
+}
+
+// Setup is the GET /github-app/setup route. It renders a form that posts an
+// app manifest to GitHub. GitHub then asks the user to confirm creating the
+// app and redirects back to ExchangeCode. If the org query param is set, the
+// app is created in that organization instead of the user's account.
+func (g *GithubAppController) Setup(w http.ResponseWriter, r *http.Request) {
+	if g.GithubSetupComplete {
+		g.respond(w, logging.Warn, http.StatusBadRequest, "Atlantis already has GitHub App credentials configured")
+		return
+	}
+
+	manifest := githubAppManifest{
+		Name:        "atlantis",
+		Description: "Terraform Pull Request Automation",
+		URL:         g.AtlantisURL.String(),
+		RedirectURL: g.AtlantisURL.String() + "/github-app/exchange-code",
+		Public:      false,
+		HookAttributes: map[string]string{
+			"url": g.AtlantisURL.String() + "/events",
+		},
+		DefaultEvents: []string{
+			"check_run",
+			"issue_comment",
+			"pull_request",
+			"pull_request_review",
+			"push",
+		},
+		DefaultPerms: map[string]string{
+			"checks":        "write",
+			"contents":      "write",
+			"issues":        "write",
+			"members":       "read",
+			"pull_requests": "write",
+			"statuses":      "write",
+		},
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		g.respond(w, logging.Error, http.StatusInternalServerError, "Failed to encode manifest: %s", err)
+		return
+	}
+
+	target := fmt.Sprintf("https://%s/settings/apps/new", g.GithubHostname)
+	if org := r.URL.Query().Get("org"); org != "" {
+		target = fmt.Sprintf("https://%s/organizations/%s/settings/apps/new", g.GithubHostname, url.PathEscape(org))
+	}
+	if err := g.SetupTemplate.Execute(w, GithubAppSetupData{
+		Target:          target,
+		Manifest:        string(manifestJSON),
+		CleanedBasePath: g.AtlantisURL.Path,
+	}); err != nil {
+		g.Logger.Err("%s", err)
+	}
+}
+
+// ExchangeCode is the GET /github-app/exchange-code route. GitHub redirects
+// here after the app is created with a code that we exchange for the app's
+// ID, private key and webhook secret. These are only shown once so the page
+// tells the user how to configure Atlantis with them.
+func (g *GithubAppController) ExchangeCode(w http.ResponseWriter, r *http.Request) {
+	if g.GithubSetupComplete {
+		g.respond(w, logging.Warn, http.StatusBadRequest, "Atlantis already has GitHub App credentials configured")
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		g.respond(w, logging.Warn, http.StatusBadRequest, "No code in request")
+		return
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/app-manifests/%s/conversions", g.GithubAPIURL, url.PathEscape(code)), nil)
+	if err != nil {
+		g.respond(w, logging.Error, http.StatusInternalServerError, "Failed to build request: %s", err)
+		return
+	}
+	req.Header.Set("Accept", githubManifestAccept)
+	httpClient := g.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		g.respond(w, logging.Error, http.StatusInternalServerError, "Failed to exchange code with GitHub: %s", err)
+		return
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		g.respond(w, logging.Error, http.StatusInternalServerError, "Failed to read response from GitHub: %s", err)
+		return
+	}
+	if resp.StatusCode != http.StatusCreated {
+		g.respond(w, logging.Error, http.StatusInternalServerError, "Failed to exchange code with GitHub: unexpected status code %d, body: %s", resp.StatusCode, string(body))
+		return
+	}
+
+	var app struct {
+		ID            int64  `json:"id"`
+		Slug          string `json:"slug"`
+		HTMLURL       string `json:"html_url"`
+		PEM           string `json:"pem"`
+		WebhookSecret string `json:"webhook_secret"`
+	}
+	if err := json.Unmarshal(body, &app); err != nil {
+		g.respond(w, logging.Error, http.StatusInternalServerError, "Failed to parse response from GitHub: %s", err)
+		return
+	}
+	g.Logger.Info("created GitHub App %q with id %d", app.Slug, app.ID)
+	if err := g.ExchangeCodeTemplate.Execute(w, GithubAppExchangeCodeData{
+		ID:              app.ID,
+		Slug:            app.Slug,
+		URL:             app.HTMLURL,
+		Key:             app.PEM,
+		WebhookSecret:   app.WebhookSecret,
+		CleanedBasePath: g.AtlantisURL.Path,
+	}); err != nil {
+		g.Logger.Err("%s", err)
+	}
+}
+
+// respond is a helper function to respond and log the response. lvl is the log
+// level to log at, code is the HTTP response code.
+func (g *GithubAppController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	g.Logger.Log(lvl, "%s", response)
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
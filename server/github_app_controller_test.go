@@ -0,0 +1,132 @@
+package server_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+)
+
+func TestGithubAppSetup_SetupComplete(t *testing.T) {
+	g := server.GithubAppController{
+		Logger:              logging.NewNoopLogger(),
+		GithubSetupComplete: true,
+	}
+	req, _ := http.NewRequest("GET", "/github-app/setup", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	g.Setup(w, req)
+	responseContains(t, w, http.StatusBadRequest, "Atlantis already has GitHub App credentials configured")
+}
+
+func TestGithubAppSetup_Renders(t *testing.T) {
+	cases := []struct {
+		query     string
+		expTarget string
+	}{
+		{
+			"",
+			"https://github.com/settings/apps/new",
+		},
+		{
+			"?org=my-org",
+			"https://github.com/organizations/my-org/settings/apps/new",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpl := sMocks.NewMockTemplateWriter()
+			atlantisURL, _ := url.Parse("https://example.com/basepath")
+			g := server.GithubAppController{
+				AtlantisURL:    atlantisURL,
+				Logger:         logging.NewNoopLogger(),
+				GithubHostname: "github.com",
+				SetupTemplate:  tmpl,
+			}
+			req, _ := http.NewRequest("GET", "/github-app/setup"+c.query, bytes.NewBuffer(nil))
+			w := httptest.NewRecorder()
+			g.Setup(w, req)
+
+			manifest := `{"name":"atlantis","description":"Terraform Pull Request Automation","url":"https://example.com/basepath",` +
+				`"redirect_url":"https://example.com/basepath/github-app/exchange-code","public":false,` +
+				`"hook_attributes":{"url":"https://example.com/basepath/events"},` +
+				`"default_events":["check_run","issue_comment","pull_request","pull_request_review","push"],` +
+				`"default_permissions":{"checks":"write","contents":"write","issues":"write","members":"read","pull_requests":"write","statuses":"write"}}`
+			tmpl.VerifyWasCalledOnce().Execute(w, server.GithubAppSetupData{
+				Target:          c.expTarget,
+				Manifest:        manifest,
+				CleanedBasePath: "/basepath",
+			})
+		})
+	}
+}
+
+func TestGithubAppExchangeCode_NoCode(t *testing.T) {
+	g := server.GithubAppController{
+		Logger: logging.NewNoopLogger(),
+	}
+	req, _ := http.NewRequest("GET", "/github-app/exchange-code", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	g.ExchangeCode(w, req)
+	responseContains(t, w, http.StatusBadRequest, "No code in request")
+}
+
+func TestGithubAppExchangeCode_Renders(t *testing.T) {
+	RegisterMockTestingT(t)
+	githubServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.RequestURI != "/app-manifests/code/conversions" {
+			t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1, "slug": "atlantis-app", "html_url": "https://github.com/apps/atlantis-app", "pem": "key", "webhook_secret": "secret"}`)) // nolint: errcheck
+	}))
+	defer githubServer.Close()
+
+	tmpl := sMocks.NewMockTemplateWriter()
+	atlantisURL, _ := url.Parse("https://example.com")
+	g := server.GithubAppController{
+		AtlantisURL:          atlantisURL,
+		Logger:               logging.NewNoopLogger(),
+		GithubHostname:       "github.com",
+		GithubAPIURL:         githubServer.URL,
+		ExchangeCodeTemplate: tmpl,
+	}
+	req, _ := http.NewRequest("GET", "/github-app/exchange-code?code=code", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	g.ExchangeCode(w, req)
+
+	tmpl.VerifyWasCalledOnce().Execute(w, server.GithubAppExchangeCodeData{
+		ID:              1,
+		Slug:            "atlantis-app",
+		URL:             "https://github.com/apps/atlantis-app",
+		Key:             "key",
+		WebhookSecret:   "secret",
+		CleanedBasePath: "",
+	})
+}
+
+func TestGithubAppExchangeCode_GithubError(t *testing.T) {
+	githubServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer githubServer.Close()
+
+	atlantisURL, _ := url.Parse("https://example.com")
+	g := server.GithubAppController{
+		AtlantisURL:  atlantisURL,
+		Logger:       logging.NewNoopLogger(),
+		GithubAPIURL: githubServer.URL,
+	}
+	req, _ := http.NewRequest("GET", "/github-app/exchange-code?code=code", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	g.ExchangeCode(w, req)
+	responseContains(t, w, http.StatusInternalServerError, "Failed to exchange code with GitHub: unexpected status code 404")
+}
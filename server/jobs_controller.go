@@ -0,0 +1,157 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events/jobs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// JobsController handles requests for the output of plans and applies.
+type JobsController struct {
+	AtlantisVersion string
+	AtlantisURL     *url.URL
+	Logger          *logging.SimpleLogger
+	Jobs            *jobs.Store
+	JobTemplate     TemplateWriter
+}
+
+// GetJob is the GET /jobs/{id} route. It renders the job detail view which
+// streams the job's output from GetJobStream.
+func (j *JobsController) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		j.respond(w, logging.Warn, http.StatusBadRequest, "No job id in request")
+		return
+	}
+	info, ok := j.Jobs.Get(id)
+	if !ok {
+		j.respond(w, logging.Info, http.StatusNotFound, "No job found at id %q", id)
+		return
+	}
+
+	viewData := JobDetailData{
+		JobID:           info.ID,
+		Command:         info.Command.String(),
+		RepoFullName:    info.Repo.FullName,
+		PullNum:         info.Pull.Num,
+		PullRequestLink: info.Pull.URL,
+		RepoRelDir:      info.RepoRelDir,
+		Workspace:       info.Workspace,
+		ProjectName:     info.ProjectName,
+		StartTime:       info.StartTime,
+		AtlantisVersion: j.AtlantisVersion,
+		CleanedBasePath: j.AtlantisURL.Path,
+	}
+	if err := j.JobTemplate.Execute(w, viewData); err != nil {
+		j.Logger.Err("%s", err)
+	}
+}
+
+// GetJobStream is the GET /jobs/{id}/stream route. It streams the job's
+// output as server-sent events. Each line is an event whose ID is its line
+// number so browsers that reconnect with the Last-Event-ID header only get
+// the lines they missed. Once the job is complete, a "complete" event is sent
+// and the response ends.
+func (j *JobsController) GetJobStream(w http.ResponseWriter, r *http.Request) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		j.respond(w, logging.Warn, http.StatusBadRequest, "No job id in request")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		j.respond(w, logging.Error, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+	next := 0
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		n, err := strconv.Atoi(lastID)
+		if err != nil {
+			j.respond(w, logging.Warn, http.StatusBadRequest, "Invalid Last-Event-ID %q", lastID)
+			return
+		}
+		next = n + 1
+	}
+
+	lines, ch, unsubscribe, ok := j.Jobs.Subscribe(id, next)
+	if !ok {
+		j.respond(w, logging.Info, http.StatusNotFound, "No job found at id %q", id)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for {
+		for _, l := range lines {
+			writeLineEvent(w, l)
+			next = l.Num + 1
+		}
+		flusher.Flush()
+
+		closed := false
+		for !closed {
+			select {
+			case l, open := <-ch:
+				if !open {
+					closed = true
+					break
+				}
+				writeLineEvent(w, l)
+				next = l.Num + 1
+				flusher.Flush()
+			case <-r.Context().Done():
+				unsubscribe()
+				return
+			}
+		}
+		unsubscribe()
+
+		// The channel is closed if the job completed, was evicted or if we
+		// fell too far behind. In the last case we subscribe again from the
+		// last line we sent.
+		info, ok := j.Jobs.Get(id)
+		if !ok {
+			return
+		}
+		if info.Complete {
+			fmt.Fprint(w, "event: complete\ndata: \n\n")
+			flusher.Flush()
+			return
+		}
+		lines, ch, unsubscribe, ok = j.Jobs.Subscribe(id, next)
+		if !ok {
+			return
+		}
+	}
+}
+
+// writeLineEvent writes l as a server-sent event. Carriage returns and
+// newlines end a field in the event stream format so each part of the line
+// between them is written as its own data field, which browsers join back
+// together with newlines.
+func writeLineEvent(w io.Writer, l jobs.Line) {
+	fmt.Fprintf(w, "id: %d\n", l.Num)
+	text := strings.Replace(l.Text, "\r\n", "\n", -1)
+	text = strings.Replace(text, "\r", "\n", -1)
+	for _, part := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "data: %s\n", part)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// respond is a helper function to respond and log the response. lvl is the log
+// level to log at, code is the HTTP response code.
+func (j *JobsController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	j.Logger.Log(lvl, "%s", response)
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
@@ -0,0 +1,209 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/jobs"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var jobCtx = models.ProjectCommandContext{
+	BaseRepo:   models.Repo{FullName: "owner/repo"},
+	Pull:       models.PullRequest{Num: 1, URL: "https://github.com/owner/repo/pull/1"},
+	RepoRelDir: "dir",
+	Workspace:  "default",
+}
+
+func TestGetJob_NoJob(t *testing.T) {
+	jc := server.JobsController{
+		Logger: logging.NewNoopLogger(),
+		Jobs:   jobs.NewStore(1, 1),
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+	w := httptest.NewRecorder()
+	jc.GetJob(w, req)
+	responseContains(t, w, http.StatusNotFound, `No job found at id "id"`)
+}
+
+func TestGetJob_Renders(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpl := sMocks.NewMockTemplateWriter()
+	store := jobs.NewStore(1, 1)
+	id := store.NewJob(jobCtx, models.PlanCommand)
+	info, _ := store.Get(id)
+	atlantisURL, _ := url.Parse("https://example.com/basepath")
+	jc := server.JobsController{
+		AtlantisVersion: "1.0.0",
+		AtlantisURL:     atlantisURL,
+		Logger:          logging.NewNoopLogger(),
+		Jobs:            store,
+		JobTemplate:     tmpl,
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	jc.GetJob(w, req)
+
+	tmpl.VerifyWasCalledOnce().Execute(w, server.JobDetailData{
+		JobID:           id,
+		Command:         "plan",
+		RepoFullName:    "owner/repo",
+		PullNum:         1,
+		PullRequestLink: "https://github.com/owner/repo/pull/1",
+		RepoRelDir:      "dir",
+		Workspace:       "default",
+		StartTime:       info.StartTime,
+		AtlantisVersion: "1.0.0",
+		CleanedBasePath: "/basepath",
+	})
+}
+
+func TestGetJobStream_NoJob(t *testing.T) {
+	jc := server.JobsController{
+		Logger: logging.NewNoopLogger(),
+		Jobs:   jobs.NewStore(1, 1),
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+	w := httptest.NewRecorder()
+	jc.GetJobStream(w, req)
+	responseContains(t, w, http.StatusNotFound, `No job found at id "id"`)
+}
+
+func TestGetJobStream_InvalidLastEventID(t *testing.T) {
+	jc := server.JobsController{
+		Logger: logging.NewNoopLogger(),
+		Jobs:   jobs.NewStore(1, 1),
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set("Last-Event-ID", "abc")
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+	w := httptest.NewRecorder()
+	jc.GetJobStream(w, req)
+	responseContains(t, w, http.StatusBadRequest, `Invalid Last-Event-ID "abc"`)
+}
+
+func TestGetJobStream_Complete(t *testing.T) {
+	store := jobs.NewStore(1, 10)
+	id := store.NewJob(jobCtx, models.PlanCommand)
+	store.WriteLine(id, "line1")
+	store.WriteLine(id, "line2")
+	store.Complete(id)
+	jc := server.JobsController{
+		Logger: logging.NewNoopLogger(),
+		Jobs:   store,
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	jc.GetJobStream(w, req)
+
+	Equals(t, http.StatusOK, w.Code)
+	Equals(t, "text/event-stream", w.Header().Get("Content-Type"))
+	Equals(t, "id: 0\ndata: line1\n\nid: 1\ndata: line2\n\nevent: complete\ndata: \n\n", w.Body.String())
+}
+
+// Test that carriage returns and newlines in a line can't end the event or
+// inject other fields into it.
+func TestGetJobStream_LineBreaks(t *testing.T) {
+	store := jobs.NewStore(1, 10)
+	id := store.NewJob(jobCtx, models.PlanCommand)
+	store.WriteLine(id, "progress\rdone\r\nid: 5\n\nevent: complete")
+	store.Complete(id)
+	jc := server.JobsController{
+		Logger: logging.NewNoopLogger(),
+		Jobs:   store,
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	jc.GetJobStream(w, req)
+
+	Equals(t, "id: 0\ndata: progress\ndata: done\ndata: id: 5\ndata: \ndata: event: complete\n\nevent: complete\ndata: \n\n", w.Body.String())
+}
+
+func TestGetJobStream_LastEventID(t *testing.T) {
+	store := jobs.NewStore(1, 10)
+	id := store.NewJob(jobCtx, models.PlanCommand)
+	store.WriteLine(id, "line1")
+	store.WriteLine(id, "line2")
+	store.Complete(id)
+	jc := server.JobsController{
+		Logger: logging.NewNoopLogger(),
+		Jobs:   store,
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set("Last-Event-ID", "0")
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	jc.GetJobStream(w, req)
+
+	Equals(t, "id: 1\ndata: line2\n\nevent: complete\ndata: \n\n", w.Body.String())
+}
+
+func TestGetJobStream_Running(t *testing.T) {
+	store := jobs.NewStore(1, 10)
+	id := store.NewJob(jobCtx, models.PlanCommand)
+	jc := server.JobsController{
+		Logger: logging.NewNoopLogger(),
+		Jobs:   store,
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		jc.GetJobStream(w, req)
+		close(done)
+	}()
+
+	// Wait for the request to subscribe before writing so the lines are
+	// streamed rather than sent as the backlog.
+	time.Sleep(50 * time.Millisecond)
+	store.WriteLine(id, "line1")
+	store.Complete(id)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for stream to end")
+	}
+	Equals(t, "id: 0\ndata: line1\n\nevent: complete\ndata: \n\n", w.Body.String())
+}
+
+func TestGetJobStream_ClientDisconnects(t *testing.T) {
+	store := jobs.NewStore(1, 10)
+	id := store.NewJob(jobCtx, models.PlanCommand)
+	jc := server.JobsController{
+		Logger: logging.NewNoopLogger(),
+		Jobs:   store,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req.WithContext(ctx), map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		jc.GetJobStream(w, req)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for stream to end")
+	}
+}
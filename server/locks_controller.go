@@ -7,6 +7,7 @@ import (
 	"net/url"
 
 	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/auth"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -24,7 +25,7 @@ type LocksController struct {
 	LockDetailTemplate TemplateWriter
 	WorkingDir         events.WorkingDir
 	WorkingDirLocker   events.WorkingDirLocker
-	DB                 *db.BoltDB
+	DB                 db.Database
 }
 
 // GetLock is the GET /locks/{id} route. It renders the lock detail view.
@@ -50,6 +51,21 @@ func (l *LocksController) GetLock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	queue, err := l.Locker.GetQueue(idUnencoded)
+	if err != nil {
+		l.respond(w, logging.Error, http.StatusInternalServerError, "Failed getting lock queue: %s", err)
+		return
+	}
+	var queueData []QueuedPullData
+	for _, q := range queue {
+		queueData = append(queueData, QueuedPullData{
+			PullNum:         q.Pull.Num,
+			PullRequestLink: q.Pull.URL,
+			QueuedBy:        q.User.Username,
+			Time:            q.Time,
+		})
+	}
+
 	owner, repo := models.SplitRepoFullName(lock.Project.RepoFullName)
 	viewData := LockDetailData{
 		LockKeyEncoded:  id,
@@ -59,6 +75,7 @@ func (l *LocksController) GetLock(w http.ResponseWriter, r *http.Request) {
 		PullRequestLink: lock.Pull.URL,
 		LockedBy:        lock.Pull.Author,
 		Workspace:       lock.Workspace,
+		Queue:           queueData,
 		AtlantisVersion: l.AtlantisVersion,
 		CleanedBasePath: l.AtlantisURL.Path,
 	}
@@ -91,6 +108,11 @@ func (l *LocksController) DeleteLock(w http.ResponseWriter, r *http.Request) {
 		l.respond(w, logging.Info, http.StatusNotFound, "No lock found at id %q", idUnencoded)
 		return
 	}
+	// username is empty if the UI doesn't require authentication.
+	username := auth.Username(r)
+	if username != "" {
+		l.Logger.Info("lock %q deleted by %s", idUnencoded, username)
+	}
 
 	// NOTE: Because BaseRepo was added to the PullRequest model later, previous
 	// installations of Atlantis will have locks in their DB that do not have
@@ -111,8 +133,12 @@ func (l *LocksController) DeleteLock(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Once the lock has been deleted, comment back on the pull request.
-		comment := fmt.Sprintf("**Warning**: The plan for dir: `%s` workspace: `%s` was **discarded** via the Atlantis UI.\n\n"+
-			"To `apply` this plan you must run `plan` again.", lock.Project.Path, lock.Workspace)
+		discardedBy := ""
+		if username != "" {
+			discardedBy = fmt.Sprintf(" by %s", username)
+		}
+		comment := fmt.Sprintf("**Warning**: The plan for dir: `%s` workspace: `%s` was **discarded** via the Atlantis UI%s.\n\n"+
+			"To `apply` this plan you must run `plan` again.", lock.Project.Path, lock.Workspace, discardedBy)
 		err = l.VCSClient.CreateComment(lock.Pull.BaseRepo, lock.Pull.Num, comment)
 		if err != nil {
 			l.respond(w, logging.Error, http.StatusInternalServerError, "Failed commenting on pull request: %s", err)
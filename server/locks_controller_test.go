@@ -13,6 +13,7 @@ import (
 	"github.com/gorilla/mux"
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/auth"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/locking/mocks"
 	mocks2 "github.com/runatlantis/atlantis/server/events/mocks"
@@ -265,3 +266,44 @@ func TestDeleteLock_CommentSuccess(t *testing.T) {
 			"To `apply` this plan you must run `plan` again.")
 	workingDir.VerifyWasCalledOnce().DeleteForWorkspace(pull.BaseRepo, pull, "workspace")
 }
+
+func TestDeleteLock_CommentsAuthenticatedUser(t *testing.T) {
+	t.Log("If the UI requires authentication, the comment should say who deleted the lock")
+	RegisterMockTestingT(t)
+
+	cp := vcsmocks.NewMockClient()
+	l := mocks.NewMockLocker()
+	pull := models.PullRequest{
+		BaseRepo: models.Repo{FullName: "owner/repo"},
+	}
+	When(l.Unlock("id")).ThenReturn(&models.ProjectLock{
+		Pull:      pull,
+		Workspace: "workspace",
+		Project: models.Project{
+			Path:         "path",
+			RepoFullName: "owner/repo",
+		},
+	}, nil)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	db, err := db.New(tmp)
+	Ok(t, err)
+	lc := server.LocksController{
+		Locker:           l,
+		Logger:           logging.NewNoopLogger(),
+		VCSClient:        cp,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		WorkingDir:       mocks2.NewMockWorkingDir(),
+		DB:               db,
+	}
+	handler := auth.Middleware(&auth.BasicAuthenticator{Username: "lkysow", Password: "pass"})(http.HandlerFunc(lc.DeleteLock))
+	req, _ := http.NewRequest("DELETE", "", bytes.NewBuffer(nil))
+	req.SetBasicAuth("lkysow", "pass")
+	req = mux.SetURLVars(req, map[string]string{"id": "id"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	responseContains(t, w, http.StatusOK, "Deleted lock id \"id\"")
+	cp.VerifyWasCalled(Once()).CreateComment(pull.BaseRepo, pull.Num,
+		"**Warning**: The plan for dir: `path` workspace: `workspace` was **discarded** via the Atlantis UI by lkysow.\n\n"+
+			"To `apply` this plan you must run `plan` again.")
+}
@@ -16,11 +16,13 @@ package logging
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
+	"sync"
 	"time"
 	"unicode"
 )
@@ -57,6 +59,16 @@ type SimpleLogger struct {
 	Logger      *log.Logger
 	KeepHistory bool
 	Level       LogLevel
+	// Format is the format log entries are written in.
+	Format LogFormat
+	// Fields are added to each log entry when writing in the JSON format.
+	Fields Fields
+	// historyMutex guards History since the same logger is used by projects
+	// that are planned or applied in parallel.
+	historyMutex sync.Mutex
+	// parent is set on loggers created by WithFields. They write their
+	// history to the parent's History so it contains all the entries.
+	parent *SimpleLogger
 }
 
 type LogLevel int
@@ -68,6 +80,32 @@ const (
 	Error
 )
 
+// LogFormat is the format that log entries are written in.
+type LogFormat int
+
+const (
+	// TextFormat writes each entry as a line of text prefixed with its source.
+	TextFormat LogFormat = iota
+	// JSONFormat writes each entry as a JSON object on its own line.
+	JSONFormat
+)
+
+// Fields are the structured data added to each log entry when writing in the
+// JSON format.
+type Fields map[string]interface{}
+
+// Keys used for the fields added to log entries.
+const (
+	RepoKey         = "repo"
+	PullKey         = "pull"
+	DirKey          = "dir"
+	WorkspaceKey    = "workspace"
+	ProjectKey      = "project"
+	CommandKey      = "command"
+	VCSRequestIDKey = "vcs_request_id"
+	JobIDKey        = "job_id"
+)
+
 // NewSimpleLogger creates a new logger.
 // source is added as a prefix to each log entry. It's useful if you want to
 // trace a log entry back to a specific context, for example a pull request id.
@@ -107,6 +145,32 @@ func (l *SimpleLogger) NewLogger(source string, keepHistory bool, lvl LogLevel)
 		Level:       lvl,
 		Logger:      l.Underlying(),
 		KeepHistory: keepHistory,
+		Format:      l.Format,
+	}
+}
+
+// WithFields returns a logger that writes the same as this one but adds
+// fields to each entry on top of this logger's fields. Its history is kept
+// in this logger's History.
+func (l *SimpleLogger) WithFields(fields Fields) *SimpleLogger {
+	if l == nil {
+		return nil
+	}
+	merged := make(Fields, len(l.Fields)+len(fields))
+	for k, v := range l.Fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &SimpleLogger{
+		Source:      l.Source,
+		Level:       l.Level,
+		Logger:      l.Logger,
+		KeepHistory: l.KeepHistory,
+		Format:      l.Format,
+		Fields:      merged,
+		parent:      l,
 	}
 }
 
@@ -117,6 +181,13 @@ func (l *SimpleLogger) SetLevel(lvl LogLevel) {
 	}
 }
 
+// SetFormat changes the format that this logger is writing in to format.
+func (l *SimpleLogger) SetFormat(format LogFormat) {
+	if l != nil {
+		l.Format = format
+	}
+}
+
 // Debug logs at debug level.
 func (l *SimpleLogger) Debug(format string, a ...interface{}) {
 	if l != nil {
@@ -153,13 +224,17 @@ func (l *SimpleLogger) Log(level LogLevel, format string, a ...interface{}) {
 
 		// Only log this message if configured to log at this level.
 		if l.Level <= level {
-			datetime := time.Now().Format("2006/01/02 15:04:05-0700")
-			var caller string
-			if l.Level <= Debug {
-				file, line := l.callSite(3)
-				caller = fmt.Sprintf(" %s:%d", file, line)
+			if l.Format == JSONFormat {
+				l.logJSON(level, msg)
+			} else {
+				datetime := time.Now().Format("2006/01/02 15:04:05-0700")
+				var caller string
+				if l.Level <= Debug {
+					file, line := l.callSite(3)
+					caller = fmt.Sprintf(" %s:%d", file, line)
+				}
+				l.Logger.Printf("%s [%s]%s %s: %s\n", datetime, levelStr, caller, l.Source, msg) // noline: errcheck
 			}
-			l.Logger.Printf("%s [%s]%s %s: %s\n", datetime, levelStr, caller, l.Source, msg) // noline: errcheck
 		}
 
 		// Keep history at all log levels.
@@ -179,8 +254,40 @@ func (l *SimpleLogger) GetLevel() LogLevel {
 	return l.Level
 }
 
+// logJSON writes msg as a JSON object containing the logger's fields.
+func (l *SimpleLogger) logJSON(level LogLevel, msg string) {
+	entry := make(map[string]interface{}, len(l.Fields)+5)
+	for k, v := range l.Fields {
+		entry[k] = v
+	}
+	file, line := l.callSite(4)
+	entry["level"] = l.levelToName(level)
+	entry["timestamp"] = time.Now().Format(time.RFC3339)
+	entry["caller"] = fmt.Sprintf("%s:%d", file, line)
+	entry["msg"] = msg
+	if l.Source != "" {
+		entry["source"] = l.Source
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		// Fields can hold any value so fall back to a message that will
+		// always marshal.
+		b, _ = json.Marshal(map[string]string{
+			"level": l.levelToName(Error),
+			"msg":   fmt.Sprintf("unable to marshal log entry %q: %s", msg, err),
+		})
+	}
+	l.Logger.Println(string(b))
+}
+
 func (l *SimpleLogger) saveToHistory(level string, msg string) {
-	l.History.WriteString(fmt.Sprintf("[%s] %s\n", level, msg))
+	h := l
+	for h.parent != nil {
+		h = h.parent
+	}
+	h.historyMutex.Lock()
+	defer h.historyMutex.Unlock()
+	h.History.WriteString(fmt.Sprintf("[%s] %s\n", level, msg))
 }
 
 func (l *SimpleLogger) capitalizeFirstLetter(s string) string {
@@ -203,6 +310,20 @@ func (l *SimpleLogger) levelToString(level LogLevel) string {
 	return "????"
 }
 
+func (l *SimpleLogger) levelToName(level LogLevel) string {
+	switch level {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	}
+	return "unknown"
+}
+
 // callSite returns the location of the caller of this function via its
 // filename and line number. skip is the number of stack frames to skip.
 func (l *SimpleLogger) callSite(skip int) (string, int) {
@@ -0,0 +1,72 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestLog_Text(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := logging.NewSimpleLogger("owner/repo#1", false, logging.Info)
+	l.Logger = log.New(buf, "", 0)
+	l.WithFields(logging.Fields{logging.DirKey: "dir"}).Info("planning")
+
+	out := buf.String()
+	Assert(t, strings.HasSuffix(out, " [INFO] owner/repo#1: Planning\n"), "unexpected output %q", out)
+}
+
+func TestLog_JSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := logging.NewSimpleLogger("owner/repo#1", false, logging.Info)
+	l.Logger = log.New(buf, "", 0)
+	l.SetFormat(logging.JSONFormat)
+	l.Fields = logging.Fields{
+		logging.RepoKey: "owner/repo",
+		logging.PullKey: 1,
+	}
+	l.WithFields(logging.Fields{logging.DirKey: "dir"}).Info("planning %s", "now")
+
+	var entry map[string]interface{}
+	Ok(t, json.Unmarshal(buf.Bytes(), &entry))
+	Equals(t, "info", entry["level"])
+	Equals(t, "Planning now", entry["msg"])
+	Equals(t, "owner/repo#1", entry["source"])
+	Equals(t, "owner/repo", entry[logging.RepoKey])
+	Equals(t, float64(1), entry[logging.PullKey])
+	Equals(t, "dir", entry[logging.DirKey])
+	Assert(t, strings.HasPrefix(entry["caller"].(string), "simple_logger_test.go:"), "unexpected caller %q", entry["caller"])
+	Assert(t, entry["timestamp"] != "", "exp timestamp to be set")
+}
+
+func TestLog_JSONBelowLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := logging.NewSimpleLogger("src", false, logging.Info)
+	l.Logger = log.New(buf, "", 0)
+	l.SetFormat(logging.JSONFormat)
+	l.Debug("hidden")
+	Equals(t, "", buf.String())
+}
+
+func TestWithFields_History(t *testing.T) {
+	l := logging.NewNoopLogger()
+	l.KeepHistory = true
+	l.Info("parent")
+	child := l.WithFields(logging.Fields{logging.DirKey: "dir"})
+	child.Warn("child")
+
+	Equals(t, "[INFO] Parent\n[WARN] Child\n", l.History.String())
+	Equals(t, logging.Fields{logging.DirKey: "dir"}, child.Fields)
+	Equals(t, 0, len(l.Fields))
+}
+
+func TestNewLogger_KeepsFormat(t *testing.T) {
+	l := logging.NewNoopLogger()
+	l.SetFormat(logging.JSONFormat)
+	Equals(t, logging.JSONFormat, l.NewLogger("src", false, logging.Info).Format)
+}
@@ -0,0 +1,124 @@
+// Package metrics defines the Prometheus metrics that Atlantis exposes on
+// its /metrics endpoint.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "atlantis"
+
+// Values for the result label.
+const (
+	// ResultSuccess means the operation succeeded.
+	ResultSuccess = "success"
+	// ResultFailure means the operation ran but didn't succeed for an
+	// expected reason, ex. the pull request wasn't approved.
+	ResultFailure = "failure"
+	// ResultError means the operation errored.
+	ResultError = "error"
+)
+
+// Values for the result label of LockAttempts.
+const (
+	// LockAcquired means the lock was acquired.
+	LockAcquired = "acquired"
+	// LockContended means the lock was held by another pull request.
+	LockContended = "contended"
+)
+
+// Registry holds all of Atlantis' metrics. We use our own registry instead of
+// the global default so that only metrics we define are exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// WebhookEvents counts the webhook requests received from each VCS host.
+	WebhookEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_events_total",
+		Help:      "Number of webhook events received by VCS host.",
+	}, []string{"vcs_host"})
+
+	// CommentCommands counts the comments parsed as Atlantis commands. The
+	// command label is the name of the command or "invalid" if Atlantis
+	// responded with help or an error instead. Comments that aren't meant
+	// for Atlantis aren't counted.
+	CommentCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "comment_commands_total",
+		Help:      "Number of comment commands parsed by command.",
+	}, []string{"command"})
+
+	// ProjectCommandDuration measures how long it takes to run plan or apply
+	// for a project.
+	ProjectCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "project_command_duration_seconds",
+		Help:      "Duration of project plans and applies by command, repo and result.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800},
+	}, []string{"command", "repo", "result"})
+
+	// LockAttempts counts attempts to lock a project.
+	LockAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lock_attempts_total",
+		Help:      "Number of attempts to lock a project by result: acquired, contended or error.",
+	}, []string{"result"})
+
+	// CloneDuration measures how long it takes to clone a repo.
+	CloneDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "clone_duration_seconds",
+		Help:      "Duration of repo clones by result.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"result"})
+
+	// VCSRequestDuration measures the latency of VCS API calls.
+	VCSRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "vcs_request_duration_seconds",
+		Help:      "Duration of VCS API calls by VCS host and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"vcs_host", "method"})
+
+	// VCSRequestErrors counts the VCS API calls that returned an error.
+	VCSRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "vcs_request_errors_total",
+		Help:      "Number of VCS API calls that errored by VCS host and method.",
+	}, []string{"vcs_host", "method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		WebhookEvents,
+		CommentCommands,
+		ProjectCommandDuration,
+		LockAttempts,
+		CloneDuration,
+		VCSRequestDuration,
+		VCSRequestErrors,
+	)
+}
+
+// Handler returns the handler for the /metrics route.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Result returns the value for the result label given the error and failure
+// reason returned by an operation.
+func Result(err error, failure string) string {
+	if err != nil {
+		return ResultError
+	}
+	if failure != "" {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
@@ -0,0 +1,33 @@
+package metrics_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/metrics"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestResult(t *testing.T) {
+	Equals(t, metrics.ResultSuccess, metrics.Result(nil, ""))
+	Equals(t, metrics.ResultFailure, metrics.Result(nil, "not approved"))
+	Equals(t, metrics.ResultError, metrics.Result(errors.New("err"), ""))
+	Equals(t, metrics.ResultError, metrics.Result(errors.New("err"), "not approved"))
+}
+
+func TestHandler(t *testing.T) {
+	metrics.LockAttempts.WithLabelValues(metrics.LockAcquired).Inc()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	metrics.Handler().ServeHTTP(w, req)
+	Equals(t, http.StatusOK, w.Code)
+	body, err := ioutil.ReadAll(w.Body)
+	Ok(t, err)
+	Assert(t, strings.Contains(string(body), `atlantis_lock_attempts_total{result="acquired"}`), "exp lock attempts metric in %s", body)
+	Assert(t, strings.Contains(string(body), "go_goroutines"), "exp go metrics in %s", body)
+}
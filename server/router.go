@@ -18,6 +18,9 @@ type Router struct {
 	// LockViewRouteIDQueryParam is the query parameter needed to construct the
 	// lock view: underlying.Get(LockViewRouteName).URL(LockViewRouteIDQueryParam, "my id").
 	LockViewRouteIDQueryParam string
+	// JobViewRouteName is the named route for the job view. The route must
+	// have an {id} path variable.
+	JobViewRouteName string
 	// AtlantisURL is the fully qualified URL that Atlantis is
 	// accessible from externally.
 	AtlantisURL *url.URL
@@ -33,3 +36,9 @@ func (r *Router) GenerateLockURL(lockID string) string {
 	// golang likes to double escape the lockURL path when using url.Parse().
 	return r.AtlantisURL.String() + lockURL.String()
 }
+
+// GenerateJobURL returns a fully qualified URL to view the job at jobID.
+func (r *Router) GenerateJobURL(jobID string) string {
+	jobURL, _ := r.Underlying.Get(r.JobViewRouteName).URL("id", jobID)
+	return r.AtlantisURL.String() + jobURL.String()
+}
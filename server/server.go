@@ -33,17 +33,23 @@ import (
 	"github.com/elazarl/go-bindata-assetfs"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/auth"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/jobs"
 	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/runtime"
 	"github.com/runatlantis/atlantis/server/events/terraform"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/vcs/azuredevops"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
+	"github.com/runatlantis/atlantis/server/events/vcs/gitea"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/metrics"
 	"github.com/runatlantis/atlantis/server/static"
 	"github.com/urfave/cli"
 	"github.com/urfave/negroni"
@@ -58,32 +64,51 @@ const (
 	// route. ex:
 	//   mux.Router.Get(LockViewRouteName).URL(LockViewRouteIDQueryParam, "my id")
 	LockViewRouteIDQueryParam = "id"
+	// JobViewRouteName is the named route in mux.Router for the job view.
+	JobViewRouteName = "job-detail"
 )
 
 // Server runs the Atlantis web server.
 type Server struct {
-	AtlantisVersion    string
-	AtlantisURL        *url.URL
-	Router             *mux.Router
-	Port               int
-	CommandRunner      *events.DefaultCommandRunner
-	Logger             *logging.SimpleLogger
-	Locker             locking.Locker
-	EventsController   *EventsController
-	LocksController    *LocksController
-	IndexTemplate      TemplateWriter
-	LockDetailTemplate TemplateWriter
-	SSLCertFile        string
-	SSLKeyFile         string
+	AtlantisVersion     string
+	AtlantisURL         *url.URL
+	Router              *mux.Router
+	Port                int
+	CommandRunner       *events.DefaultCommandRunner
+	Logger              *logging.SimpleLogger
+	Locker              locking.Locker
+	EventsController    *EventsController
+	LocksController     *LocksController
+	JobsController      *JobsController
+	GithubAppController *GithubAppController
+	DriftController     *DriftController
+	APIController       *APIController
+	// DriftDetector is nil if drift detection isn't enabled.
+	DriftDetector          *events.DriftDetector
+	DriftDetectionInterval time.Duration
+	IndexTemplate          TemplateWriter
+	LockDetailTemplate     TemplateWriter
+	LockReaper             *events.LockReaper
+	SSLCertFile            string
+	SSLKeyFile             string
+	// UIAuthenticator is nil if the UI doesn't require authentication.
+	UIAuthenticator auth.Authenticator
+	// OIDCAuthenticator is set if the UI is authenticated with OpenID Connect.
+	OIDCAuthenticator *auth.OIDCAuthenticator
 }
 
 // Config holds config for server that isn't passed in by the user.
 type Config struct {
-	AllowForkPRsFlag     string
-	AllowRepoConfigFlag  string
-	AtlantisURLFlag      string
-	AtlantisVersion      string
-	DefaultTFVersionFlag string
+	AllowForkPRsFlag           string
+	AllowRepoConfigFlag        string
+	APISecretFlag              string
+	AtlantisURLFlag            string
+	AtlantisVersion            string
+	DefaultTFVersionFlag       string
+	DriftDetectionIntervalFlag string
+	LockTTLFlag                string
+	// RepoConfig is the parsed server-side repo config.
+	RepoConfig valid.ServerConfig
 }
 
 // WebhookConfig is nested within UserConfig. It's used to configure webhooks.
@@ -106,15 +131,38 @@ type WebhookConfig struct {
 // for the server CLI command because it injects all the dependencies.
 func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	logger := logging.NewSimpleLogger("server", false, userConfig.ToLogLevel())
+	logger.SetFormat(userConfig.ToLogFormat())
 	var supportedVCSHosts []models.VCSHostType
 	var githubClient *vcs.GithubClient
 	var gitlabClient *vcs.GitlabClient
 	var bitbucketCloudClient *bitbucketcloud.Client
 	var bitbucketServerClient *bitbucketserver.Client
+	var azureDevopsClient *azuredevops.Client
+	var giteaClient *gitea.Client
+	var githubCredentials vcs.GithubCredentials
+	// githubUser is the user Atlantis comments as on GitHub. It's used to
+	// match comments that invoke Atlantis with @githubUser.
+	githubUser := userConfig.GithubUser
 	if userConfig.GithubUser != "" {
+		githubCredentials = &vcs.GithubUserCredentials{
+			User:  userConfig.GithubUser,
+			Token: userConfig.GithubToken,
+		}
+	} else if userConfig.GithubAppID != 0 {
+		appCredentials, err := vcs.NewGithubAppCredentials(int64(userConfig.GithubAppID), userConfig.GithubAppKeyFile, userConfig.GithubHostname)
+		if err != nil {
+			return nil, errors.Wrap(err, "setting up GitHub App credentials")
+		}
+		githubUser, err = appCredentials.Slug()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting GitHub App slug")
+		}
+		githubCredentials = appCredentials
+	}
+	if githubCredentials != nil {
 		supportedVCSHosts = append(supportedVCSHosts, models.Github)
 		var err error
-		githubClient, err = vcs.NewGithubClient(userConfig.GithubHostname, userConfig.GithubUser, userConfig.GithubToken)
+		githubClient, err = vcs.NewGithubClient(userConfig.GithubHostname, githubCredentials)
 		if err != nil {
 			return nil, err
 		}
@@ -149,6 +197,22 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			}
 		}
 	}
+	if userConfig.AzureDevopsUser != "" {
+		supportedVCSHosts = append(supportedVCSHosts, models.AzureDevops)
+		azureDevopsClient = azuredevops.NewClient(
+			http.DefaultClient,
+			userConfig.AzureDevopsUser,
+			userConfig.AzureDevopsToken,
+			userConfig.AzureDevopsHostname)
+	}
+	if userConfig.GiteaUser != "" {
+		supportedVCSHosts = append(supportedVCSHosts, models.Gitea)
+		var err error
+		giteaClient, err = gitea.NewClient(http.DefaultClient, userConfig.GiteaToken, userConfig.GiteaBaseURL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "setting up Gitea client")
+		}
+	}
 
 	var webhooksConfig []webhooks.Config
 	for _, c := range userConfig.Webhooks {
@@ -164,8 +228,19 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "initializing webhooks")
 	}
-	vcsClient := vcs.NewClientProxy(githubClient, gitlabClient, bitbucketCloudClient, bitbucketServerClient)
-	commitStatusUpdater := &events.DefaultCommitStatusUpdater{Client: vcsClient}
+	vcsClient := vcs.NewClientProxy(githubClient, gitlabClient, bitbucketCloudClient, bitbucketServerClient, azureDevopsClient, giteaClient)
+	var commitStatusUpdater events.CommitStatusUpdater = &events.DefaultCommitStatusUpdater{Client: vcsClient}
+	// If GitHub checks are enabled, each project's status and output is
+	// reported as a check run instead.
+	var resultUpdater events.ProjectResultUpdater
+	if userConfig.GithubChecks {
+		checksUpdater := &events.GithubChecksUpdater{
+			Client:              githubClient,
+			CommitStatusUpdater: commitStatusUpdater,
+		}
+		commitStatusUpdater = checksUpdater
+		resultUpdater = checksUpdater
+	}
 	terraformClient, err := terraform.NewClient(logger, userConfig.DataDir, userConfig.TFEToken, userConfig.DefaultTFVersion, config.DefaultTFVersionFlag, &terraform.DefaultDownloader{})
 	// The flag.Lookup call is to detect if we're running in a unit test. If we
 	// are, then we don't error out because we don't have/want terraform
@@ -176,18 +251,38 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	markdownRenderer := &events.MarkdownRenderer{
 		GitlabSupportsCommonMark: gitlabClient.SupportsCommonMark(),
 	}
-	boltdb, err := db.New(userConfig.DataDir)
+	var backend db.Database
+	switch userConfig.LockingDBType {
+	case "redis":
+		backend, err = db.NewRedis(userConfig.RedisHost, userConfig.RedisPort, userConfig.RedisPassword, userConfig.RedisDB)
+	case db.SQLiteDriver, db.PostgresDriver:
+		backend, err = db.NewSQL(userConfig.LockingDBType, userConfig.SQLDSN)
+	default:
+		backend, err = db.New(userConfig.DataDir)
+	}
 	if err != nil {
 		return nil, err
 	}
-	lockingClient := locking.NewClient(boltdb)
+	// commandRunner is declared here so the lock queue can re-run plans for
+	// pull requests that are handed a lock. It's set below.
+	var commandRunner *events.DefaultCommandRunner
+	lockingClient := locking.NewClient(backend)
+	if userConfig.EnableLockQueue {
+		lockingClient = locking.NewQueueingClient(backend, func(lock models.ProjectLock) {
+			// The pull request may have new commits since it was queued so we
+			// pass only its number and RunCommentCommand fetches it again.
+			cmd := events.NewCommentCommand(lock.Project.Path, nil, models.PlanCommand, false, lock.Workspace, "")
+			go commandRunner.RunCommentCommand(events.NewRequestContext(""), lock.Pull.BaseRepo, nil, nil, lock.User, lock.Pull.Num, cmd)
+		})
+	}
 	workingDirLocker := events.NewDefaultWorkingDirLocker()
 	workingDir := &events.FileWorkspace{
 		DataDir:       userConfig.DataDir,
 		CheckoutMerge: userConfig.CheckoutStrategy == "merge",
 	}
 	projectLocker := &events.DefaultProjectLocker{
-		Locker: lockingClient,
+		Locker:       lockingClient,
+		QueueEnabled: userConfig.EnableLockQueue,
 	}
 	parsedURL, err := ParseAtlantisURL(userConfig.AtlantisURL)
 	if err != nil {
@@ -199,41 +294,52 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		AtlantisURL:               parsedURL,
 		LockViewRouteIDQueryParam: LockViewRouteIDQueryParam,
 		LockViewRouteName:         LockViewRouteName,
+		JobViewRouteName:          JobViewRouteName,
 		Underlying:                underlyingRouter,
 	}
+	jobStore := jobs.NewStore(jobs.DefaultMaxJobs, jobs.DefaultMaxLines)
 	pullClosedExecutor := &events.PullClosedExecutor{
 		VCSClient:  vcsClient,
 		Locker:     lockingClient,
 		WorkingDir: workingDir,
 		Logger:     logger,
-		DB:         boltdb,
+		DB:         backend,
 	}
 	eventParser := &events.EventParser{
-		GithubUser:         userConfig.GithubUser,
-		GithubToken:        userConfig.GithubToken,
+		GithubCredentials:  githubCredentials,
 		GitlabUser:         userConfig.GitlabUser,
 		GitlabToken:        userConfig.GitlabToken,
 		BitbucketUser:      userConfig.BitbucketUser,
 		BitbucketToken:     userConfig.BitbucketToken,
 		BitbucketServerURL: userConfig.BitbucketBaseURL,
+		AzureDevopsUser:    userConfig.AzureDevopsUser,
+		AzureDevopsToken:   userConfig.AzureDevopsToken,
+		GiteaUser:          userConfig.GiteaUser,
+		GiteaToken:         userConfig.GiteaToken,
 	}
 	commentParser := &events.CommentParser{
-		GithubUser:    userConfig.GithubUser,
-		GitlabUser:    userConfig.GitlabUser,
-		BitbucketUser: userConfig.BitbucketUser,
+		GithubUser:      githubUser,
+		GitlabUser:      userConfig.GitlabUser,
+		BitbucketUser:   userConfig.BitbucketUser,
+		AzureDevopsUser: userConfig.AzureDevopsUser,
+		GiteaUser:       userConfig.GiteaUser,
 	}
 	defaultTfVersion := terraformClient.Version()
 	pendingPlanFinder := &events.DefaultPendingPlanFinder{}
-	commandRunner := &events.DefaultCommandRunner{
-		VCSClient:                vcsClient,
-		GithubPullGetter:         githubClient,
-		GitlabMergeRequestGetter: gitlabClient,
-		CommitStatusUpdater:      commitStatusUpdater,
-		EventParser:              eventParser,
-		MarkdownRenderer:         markdownRenderer,
-		Logger:                   logger,
-		AllowForkPRs:             userConfig.AllowForkPRs,
-		AllowForkPRsFlag:         config.AllowForkPRsFlag,
+	commandRunner = &events.DefaultCommandRunner{
+		VCSClient:                 vcsClient,
+		GithubPullGetter:          githubClient,
+		GitlabMergeRequestGetter:  gitlabClient,
+		GiteaPullGetter:           giteaClient,
+		BitbucketCloudPullGetter:  bitbucketCloudClient,
+		BitbucketServerPullGetter: bitbucketServerClient,
+		AzureDevopsPullGetter:     azureDevopsClient,
+		CommitStatusUpdater:       commitStatusUpdater,
+		EventParser:               eventParser,
+		MarkdownRenderer:          markdownRenderer,
+		Logger:                    logger,
+		AllowForkPRs:              userConfig.AllowForkPRs,
+		AllowForkPRsFlag:          config.AllowForkPRsFlag,
 		ProjectCommandBuilder: &events.DefaultProjectCommandBuilder{
 			ParserValidator:     &yaml.ParserValidator{},
 			ProjectFinder:       &events.DefaultProjectFinder{},
@@ -242,6 +348,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			WorkingDirLocker:    workingDirLocker,
 			AllowRepoConfig:     userConfig.AllowRepoConfig,
 			AllowRepoConfigFlag: config.AllowRepoConfigFlag,
+			ServerConfig:        config.RepoConfig,
 			PendingPlanFinder:   pendingPlanFinder,
 			CommentBuilder:      commentParser,
 		},
@@ -257,26 +364,100 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 				DefaultTFVersion:    defaultTfVersion,
 				CommitStatusUpdater: commitStatusUpdater,
 				AsyncTFExec:         terraformClient,
+				JobOutput:           jobStore,
+			},
+			PlanSummaryRunner: &runtime.PlanSummaryRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+			},
+			PolicyCheckStepRunner: &runtime.PolicyCheckStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+				PolicySets:        config.RepoConfig.Policies.PolicySets,
 			},
 			ApplyStepRunner: &runtime.ApplyStepRunner{
-				TerraformExecutor:   terraformClient,
 				CommitStatusUpdater: commitStatusUpdater,
 				AsyncTFExec:         terraformClient,
+				JobOutput:           jobStore,
 			},
 			RunStepRunner: &runtime.RunStepRunner{
 				DefaultTFVersion: defaultTfVersion,
 			},
 			PullApprovedChecker:      vcsClient,
+			OwnerApprovalChecker:     &events.DefaultOwnerApprovalChecker{VCSClient: vcsClient},
 			WorkingDir:               workingDir,
 			Webhooks:                 webhooksManager,
 			WorkingDirLocker:         workingDirLocker,
 			RequireApprovalOverride:  userConfig.RequireApproval,
 			RequireMergeableOverride: userConfig.RequireMergeable,
+			Jobs:                     jobStore,
+			JobURLGenerator:          router,
+			CommitStatusUpdater:      commitStatusUpdater,
+			ResultUpdater:            resultUpdater,
 		},
 		WorkingDir:        workingDir,
+		WorkingDirLocker:  workingDirLocker,
 		PendingPlanFinder: pendingPlanFinder,
-		DB:                boltdb,
+		DB:                backend,
 		GlobalAutomerge:   userConfig.Automerge,
+		ParallelPoolSize:  userConfig.ParallelPoolSize,
+		CommentMode:       userConfig.CommentMode,
+		Locker:            lockingClient,
+		CommandAuthorizer: &events.DefaultCommandAuthorizer{
+			VCSClient:    vcsClient,
+			ServerConfig: config.RepoConfig,
+		},
+	}
+	var defaultLockTTL time.Duration
+	if userConfig.LockTTL != "" {
+		defaultLockTTL, err = time.ParseDuration(userConfig.LockTTL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing --%s flag %q", config.LockTTLFlag, userConfig.LockTTL)
+		}
+	}
+	lockReaper := &events.LockReaper{
+		Locker:           lockingClient,
+		VCSClient:        vcsClient,
+		WorkingDir:       workingDir,
+		WorkingDirLocker: workingDirLocker,
+		DB:               backend,
+		Logger:           logger,
+		DefaultTTL:       defaultLockTTL,
+	}
+	// Only Bitbucket Cloud clone URLs can be built from the repo's ID.
+	var bitbucketCloudUser, bitbucketCloudToken string
+	if userConfig.BitbucketBaseURL == bitbucketcloud.BaseURL {
+		bitbucketCloudUser = userConfig.BitbucketUser
+		bitbucketCloudToken = userConfig.BitbucketToken
+	}
+	repoBuilder := &events.DefaultRepoBuilder{
+		GithubHostname:    userConfig.GithubHostname,
+		GithubCredentials: githubCredentials,
+		GitlabHostname:    userConfig.GitlabHostname,
+		GitlabUser:        userConfig.GitlabUser,
+		GitlabToken:       userConfig.GitlabToken,
+		BitbucketUser:     bitbucketCloudUser,
+		BitbucketToken:    bitbucketCloudToken,
+		GiteaBaseURL:      userConfig.GiteaBaseURL,
+		GiteaUser:         userConfig.GiteaUser,
+		GiteaToken:        userConfig.GiteaToken,
+	}
+	var driftDetector *events.DriftDetector
+	var driftInterval time.Duration
+	if userConfig.DriftDetectionInterval != "" {
+		driftInterval, err = time.ParseDuration(userConfig.DriftDetectionInterval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing --%s flag %q", config.DriftDetectionIntervalFlag, userConfig.DriftDetectionInterval)
+		}
+		driftDetector = &events.DriftDetector{
+			Repos:                 strings.Split(userConfig.DriftDetectionRepos, ","),
+			RepoBuilder:           repoBuilder,
+			ProjectCommandBuilder: commandRunner.ProjectCommandBuilder,
+			ProjectCommandRunner:  commandRunner.ProjectCommandRunner,
+			Webhooks:              webhooksManager,
+			Logger:                logger,
+			DriftURL:              parsedURL.String() + "/drift",
+		}
 	}
 	repoWhitelist, err := events.NewRepoWhitelistChecker(userConfig.RepoWhitelist)
 	if err != nil {
@@ -291,7 +472,55 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		LockDetailTemplate: lockTemplate,
 		WorkingDir:         workingDir,
 		WorkingDirLocker:   workingDirLocker,
-		DB:                 boltdb,
+		DB:                 backend,
+	}
+	jobsController := &JobsController{
+		AtlantisVersion: config.AtlantisVersion,
+		AtlantisURL:     parsedURL,
+		Logger:          logger,
+		Jobs:            jobStore,
+		JobTemplate:     jobTemplate,
+	}
+	githubAppController := &GithubAppController{
+		AtlantisURL:          parsedURL,
+		Logger:               logger,
+		GithubSetupComplete:  userConfig.GithubAppID != 0,
+		GithubHostname:       userConfig.GithubHostname,
+		GithubAPIURL:         vcs.GithubAPIURL(userConfig.GithubHostname),
+		SetupTemplate:        githubAppSetupTemplate,
+		ExchangeCodeTemplate: githubAppExchangeCodeTemplate,
+	}
+	driftController := &DriftController{
+		AtlantisVersion: config.AtlantisVersion,
+		AtlantisURL:     parsedURL,
+		Logger:          logger,
+		DriftDetector:   driftDetector,
+		DriftTemplate:   driftTemplate,
+	}
+	var uiAuthenticator auth.Authenticator
+	var oidcAuthenticator *auth.OIDCAuthenticator
+	if userConfig.WebPassword != "" {
+		uiAuthenticator = &auth.BasicAuthenticator{
+			Username: userConfig.WebUsername,
+			Password: userConfig.WebPassword,
+		}
+	} else if userConfig.OIDCIssuerURL != "" {
+		oidcAuthenticator, err = auth.NewOIDCAuthenticator(logger, userConfig.OIDCIssuerURL, userConfig.OIDCClientID, userConfig.OIDCClientSecret, userConfig.OIDCSessionSecret, parsedURL.String()+auth.OIDCCallbackPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "initializing authentication with OIDC provider %q", userConfig.OIDCIssuerURL)
+		}
+		uiAuthenticator = oidcAuthenticator
+	}
+	apiController := &APIController{
+		APISecret:            []byte(userConfig.APISecret),
+		APISecretFlag:        config.APISecretFlag,
+		Logger:               logger,
+		RepoBuilder:          repoBuilder,
+		RepoWhitelistChecker: repoWhitelist,
+		CommandRunner:        commandRunner,
+		Locker:               lockingClient,
+		LockURLGenerator:     router,
+		DB:                   backend,
 	}
 	eventsController := &EventsController{
 		CommandRunner:                commandRunner,
@@ -308,21 +537,33 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		SupportedVCSHosts:            supportedVCSHosts,
 		VCSClient:                    vcsClient,
 		BitbucketWebhookSecret:       []byte(userConfig.BitbucketWebhookSecret),
+		AzureDevopsWebhookUser:       []byte(userConfig.AzureDevopsWebhookUser),
+		AzureDevopsWebhookPassword:   []byte(userConfig.AzureDevopsWebhookPassword),
+		GiteaWebhookSecret:           []byte(userConfig.GiteaWebhookSecret),
 	}
 	return &Server{
-		AtlantisVersion:    config.AtlantisVersion,
-		AtlantisURL:        parsedURL,
-		Router:             underlyingRouter,
-		Port:               userConfig.Port,
-		CommandRunner:      commandRunner,
-		Logger:             logger,
-		Locker:             lockingClient,
-		EventsController:   eventsController,
-		LocksController:    locksController,
-		IndexTemplate:      indexTemplate,
-		LockDetailTemplate: lockTemplate,
-		SSLKeyFile:         userConfig.SSLKeyFile,
-		SSLCertFile:        userConfig.SSLCertFile,
+		AtlantisVersion:        config.AtlantisVersion,
+		AtlantisURL:            parsedURL,
+		Router:                 underlyingRouter,
+		Port:                   userConfig.Port,
+		CommandRunner:          commandRunner,
+		Logger:                 logger,
+		Locker:                 lockingClient,
+		EventsController:       eventsController,
+		LocksController:        locksController,
+		JobsController:         jobsController,
+		GithubAppController:    githubAppController,
+		DriftController:        driftController,
+		APIController:          apiController,
+		DriftDetector:          driftDetector,
+		DriftDetectionInterval: driftInterval,
+		IndexTemplate:          indexTemplate,
+		LockDetailTemplate:     lockTemplate,
+		LockReaper:             lockReaper,
+		SSLKeyFile:             userConfig.SSLKeyFile,
+		SSLCertFile:            userConfig.SSLCertFile,
+		UIAuthenticator:        uiAuthenticator,
+		OIDCAuthenticator:      oidcAuthenticator,
 	}, nil
 }
 
@@ -332,11 +573,31 @@ func (s *Server) Start() error {
 		return r.URL.Path == "/" || r.URL.Path == "/index.html"
 	})
 	s.Router.HandleFunc("/healthz", s.Healthz).Methods("GET")
+	s.Router.Handle("/metrics", metrics.Handler()).Methods("GET")
 	s.Router.PathPrefix("/static/").Handler(http.FileServer(&assetfs.AssetFS{Asset: static.Asset, AssetDir: static.AssetDir, AssetInfo: static.AssetInfo}))
 	s.Router.HandleFunc("/events", s.EventsController.Post).Methods("POST")
 	s.Router.HandleFunc("/locks", s.LocksController.DeleteLock).Methods("DELETE").Queries("id", "{id:.*}")
 	s.Router.HandleFunc("/lock", s.LocksController.GetLock).Methods("GET").
 		Queries(LockViewRouteIDQueryParam, fmt.Sprintf("{%s}", LockViewRouteIDQueryParam)).Name(LockViewRouteName)
+	s.Router.HandleFunc("/jobs/{id}", s.JobsController.GetJob).Methods("GET").Name(JobViewRouteName)
+	s.Router.HandleFunc("/jobs/{id}/stream", s.JobsController.GetJobStream).Methods("GET")
+	s.Router.HandleFunc("/drift", s.DriftController.GetDrift).Methods("GET")
+	s.Router.HandleFunc("/api/plan", s.APIController.Plan).Methods("POST")
+	s.Router.HandleFunc("/api/apply", s.APIController.Apply).Methods("POST")
+	s.Router.HandleFunc("/api/jobs/{id}", s.APIController.GetJob).Methods("GET")
+	s.Router.HandleFunc("/api/locks", s.APIController.ListLocks).Methods("GET")
+	s.Router.HandleFunc("/api/locks/{id}", s.APIController.GetLock).Methods("GET")
+	s.Router.HandleFunc("/api/pulls/{repo:.+}/{num}", s.APIController.GetPullStatus).Methods("GET")
+	if s.OIDCAuthenticator != nil {
+		s.Router.HandleFunc(auth.OIDCCallbackPath, s.OIDCAuthenticator.Callback).Methods("GET")
+	}
+	if s.UIAuthenticator != nil {
+		// Webhooks and the API have their own authentication. Health checks,
+		// metrics and static files don't need any.
+		s.Router.Use(auth.Middleware(s.UIAuthenticator, "/events", "/api/", "/healthz", "/metrics", "/static/", auth.OIDCCallbackPath))
+	}
+	s.Router.HandleFunc("/github-app/setup", s.GithubAppController.Setup).Methods("GET")
+	s.Router.HandleFunc("/github-app/exchange-code", s.GithubAppController.ExchangeCode).Methods("GET")
 	n := negroni.New(&negroni.Recovery{
 		Logger:     log.New(os.Stdout, "", log.LstdFlags),
 		PrintStack: false,
@@ -345,6 +606,14 @@ func (s *Server) Start() error {
 	}, NewRequestLogger(s.Logger))
 	n.UseHandler(s.Router)
 
+	// Release expired locks in the background until we're stopped.
+	reaperStop := make(chan struct{})
+	defer close(reaperStop)
+	go s.LockReaper.Run(events.LockReaperInterval, reaperStop)
+	if s.DriftDetector != nil {
+		go s.DriftDetector.Run(s.DriftDetectionInterval, reaperStop)
+	}
+
 	// Ensure server gracefully drains connections when stopped.
 	stop := make(chan os.Signal, 1)
 	// Stop on SIGINTs and SIGTERMs.
@@ -6,27 +6,59 @@ import "github.com/runatlantis/atlantis/server/logging"
 // The mapstructure tags correspond to flags in cmd/server.go and are used when
 // the config is parsed from a YAML file.
 type UserConfig struct {
-	AllowForkPRs           bool   `mapstructure:"allow-fork-prs"`
-	AllowRepoConfig        bool   `mapstructure:"allow-repo-config"`
-	AtlantisURL            string `mapstructure:"atlantis-url"`
-	Automerge              bool   `mapstructure:"automerge"`
-	BitbucketBaseURL       string `mapstructure:"bitbucket-base-url"`
-	BitbucketToken         string `mapstructure:"bitbucket-token"`
-	BitbucketUser          string `mapstructure:"bitbucket-user"`
-	BitbucketWebhookSecret string `mapstructure:"bitbucket-webhook-secret"`
-	CheckoutStrategy       string `mapstructure:"checkout-strategy"`
-	DataDir                string `mapstructure:"data-dir"`
-	GithubHostname         string `mapstructure:"gh-hostname"`
-	GithubToken            string `mapstructure:"gh-token"`
-	GithubUser             string `mapstructure:"gh-user"`
-	GithubWebhookSecret    string `mapstructure:"gh-webhook-secret"`
-	GitlabHostname         string `mapstructure:"gitlab-hostname"`
-	GitlabToken            string `mapstructure:"gitlab-token"`
-	GitlabUser             string `mapstructure:"gitlab-user"`
-	GitlabWebhookSecret    string `mapstructure:"gitlab-webhook-secret"`
-	LogLevel               string `mapstructure:"log-level"`
-	Port                   int    `mapstructure:"port"`
-	RepoWhitelist          string `mapstructure:"repo-whitelist"`
+	AllowForkPRs        bool   `mapstructure:"allow-fork-prs"`
+	AllowRepoConfig     bool   `mapstructure:"allow-repo-config"`
+	APISecret           string `mapstructure:"api-secret"`
+	AtlantisURL         string `mapstructure:"atlantis-url"`
+	Automerge           bool   `mapstructure:"automerge"`
+	AzureDevopsHostname string `mapstructure:"azuredevops-hostname"`
+	AzureDevopsToken    string `mapstructure:"azuredevops-token"`
+	AzureDevopsUser     string `mapstructure:"azuredevops-user"`
+	// AzureDevopsWebhookUser and AzureDevopsWebhookPassword are the basic
+	// auth credentials Azure DevOps service hooks are configured with.
+	AzureDevopsWebhookPassword string `mapstructure:"azuredevops-webhook-password"`
+	AzureDevopsWebhookUser     string `mapstructure:"azuredevops-webhook-user"`
+	BitbucketBaseURL           string `mapstructure:"bitbucket-base-url"`
+	BitbucketToken             string `mapstructure:"bitbucket-token"`
+	BitbucketUser              string `mapstructure:"bitbucket-user"`
+	BitbucketWebhookSecret     string `mapstructure:"bitbucket-webhook-secret"`
+	CheckoutStrategy           string `mapstructure:"checkout-strategy"`
+	CommentMode                string `mapstructure:"comment-mode"`
+	DataDir                    string `mapstructure:"data-dir"`
+	DriftDetectionInterval     string `mapstructure:"drift-detection-interval"`
+	DriftDetectionRepos        string `mapstructure:"drift-detection-repos"`
+	EnableLockQueue            bool   `mapstructure:"enable-lock-queue"`
+	GiteaBaseURL               string `mapstructure:"gitea-base-url"`
+	GiteaToken                 string `mapstructure:"gitea-token"`
+	GiteaUser                  string `mapstructure:"gitea-user"`
+	GiteaWebhookSecret         string `mapstructure:"gitea-webhook-secret"`
+	GithubAppID                int    `mapstructure:"gh-app-id"`
+	GithubAppKeyFile           string `mapstructure:"gh-app-key-file"`
+	GithubChecks               bool   `mapstructure:"gh-checks"`
+	GithubHostname             string `mapstructure:"gh-hostname"`
+	GithubToken                string `mapstructure:"gh-token"`
+	GithubUser                 string `mapstructure:"gh-user"`
+	GithubWebhookSecret        string `mapstructure:"gh-webhook-secret"`
+	GitlabHostname             string `mapstructure:"gitlab-hostname"`
+	GitlabToken                string `mapstructure:"gitlab-token"`
+	GitlabUser                 string `mapstructure:"gitlab-user"`
+	GitlabWebhookSecret        string `mapstructure:"gitlab-webhook-secret"`
+	LockTTL                    string `mapstructure:"lock-ttl"`
+	LockingDBType              string `mapstructure:"locking-db-type"`
+	LogFormat                  string `mapstructure:"log-format"`
+	LogLevel                   string `mapstructure:"log-level"`
+	OIDCClientID               string `mapstructure:"oidc-client-id"`
+	OIDCClientSecret           string `mapstructure:"oidc-client-secret"`
+	OIDCIssuerURL              string `mapstructure:"oidc-issuer-url"`
+	OIDCSessionSecret          string `mapstructure:"oidc-session-secret"`
+	ParallelPoolSize           int    `mapstructure:"parallel-pool-size"`
+	Port                       int    `mapstructure:"port"`
+	RedisDB                    int    `mapstructure:"redis-db"`
+	RedisHost                  string `mapstructure:"redis-host"`
+	RedisPassword              string `mapstructure:"redis-password"`
+	RedisPort                  int    `mapstructure:"redis-port"`
+	RepoConfig                 string `mapstructure:"repo-config"`
+	RepoWhitelist              string `mapstructure:"repo-whitelist"`
 	// RequireApproval is whether to require pull request approval before
 	// allowing terraform apply's to be run.
 	RequireApproval bool `mapstructure:"require-approval"`
@@ -35,10 +67,13 @@ type UserConfig struct {
 	RequireMergeable       bool            `mapstructure:"require-mergeable"`
 	SilenceWhitelistErrors bool            `mapstructure:"silence-whitelist-errors"`
 	SlackToken             string          `mapstructure:"slack-token"`
+	SQLDSN                 string          `mapstructure:"sql-dsn"`
 	SSLCertFile            string          `mapstructure:"ssl-cert-file"`
 	SSLKeyFile             string          `mapstructure:"ssl-key-file"`
 	TFEToken               string          `mapstructure:"tfe-token"`
 	DefaultTFVersion       string          `mapstructure:"default-tf-version"`
+	WebPassword            string          `mapstructure:"web-password"`
+	WebUsername            string          `mapstructure:"web-username"`
 	Webhooks               []WebhookConfig `mapstructure:"webhooks"`
 }
 
@@ -57,3 +92,12 @@ func (u UserConfig) ToLogLevel() logging.LogLevel {
 	}
 	return logging.Info
 }
+
+// ToLogFormat returns the LogFormat object corresponding to the user-passed
+// log format.
+func (u UserConfig) ToLogFormat() logging.LogFormat {
+	if u.LogFormat == "json" {
+		return logging.JSONFormat
+	}
+	return logging.TextFormat
+}
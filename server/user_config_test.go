@@ -44,3 +44,9 @@ func TestUserConfig_ToLogLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestUserConfig_ToLogFormat(t *testing.T) {
+	Equals(t, logging.TextFormat, server.UserConfig{}.ToLogFormat())
+	Equals(t, logging.TextFormat, server.UserConfig{LogFormat: "text"}.ToLogFormat())
+	Equals(t, logging.JSONFormat, server.UserConfig{LogFormat: "json"}.ToLogFormat())
+}
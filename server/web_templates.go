@@ -118,6 +118,9 @@ type LockDetailData struct {
 	LockedBy        string
 	Workspace       string
 	Time            time.Time
+	// Queue is the pull requests waiting for this lock, in the order they'll
+	// acquire it.
+	Queue           []QueuedPullData
 	AtlantisVersion string
 	// CleanedBasePath is the path Atlantis is accessible at externally. If
 	// not using a path-based proxy, this will be an empty string. Never ends
@@ -125,6 +128,159 @@ type LockDetailData struct {
 	CleanedBasePath string
 }
 
+// QueuedPullData holds the fields needed to display a pull request waiting
+// in a lock's queue.
+type QueuedPullData struct {
+	PullNum         int
+	PullRequestLink string
+	QueuedBy        string
+	Time            time.Time
+}
+
+// JobDetailData holds the fields needed to display the job detail view.
+type JobDetailData struct {
+	JobID           string
+	Command         string
+	RepoFullName    string
+	PullNum         int
+	PullRequestLink string
+	RepoRelDir      string
+	Workspace       string
+	ProjectName     string
+	StartTime       time.Time
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+var jobTemplate = template.Must(template.New("job.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img src="{{ .CleanedBasePath }}/static/images/atlantis-icon.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>{{.RepoFullName}} #{{.PullNum}}</strong> <code>{{.Command}}</code> <code id="jobStatus">Running</code></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+      <h6><code>Pull Request Link</code>: <a href="{{.PullRequestLink}}" target="_blank"><strong>{{.PullRequestLink}}</strong></a></h6>
+      {{ if .ProjectName }}<h6><code>Project</code>: <strong>{{.ProjectName}}</strong></h6>{{ end }}
+      <h6><code>Dir</code>: <strong>{{.RepoRelDir}}</strong></h6>
+      <h6><code>Workspace</code>: <strong>{{.Workspace}}</strong></h6>
+      <h6><code>Started</code>: <strong>{{.StartTime.Format "2006-01-02 15:04:05 MST"}}</strong></h6>
+      <pre><code id="jobOutput"></code></pre>
+    </section>
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+<script>
+  var output = document.getElementById("jobOutput");
+  var status = document.getElementById("jobStatus");
+  // EventSource reconnects on its own and sends the ID of the last line it
+  // got so we only receive the lines we're missing.
+  var source = new EventSource("{{ .CleanedBasePath }}/jobs/{{ .JobID }}/stream");
+  source.onmessage = function(event) {
+    output.appendChild(document.createTextNode(event.data + "\n"));
+  };
+  source.addEventListener("complete", function() {
+    status.textContent = "Complete";
+    source.close();
+  });
+</script>
+</body>
+</html>
+`))
+
+// DriftProjectData holds the fields needed to display the drift status of a
+// project.
+type DriftProjectData struct {
+	RepoID      string
+	RepoRelDir  string
+	Workspace   string
+	ProjectName string
+	CheckedAt   time.Time
+	Status      string
+	Summary     string
+	Error       string
+}
+
+// DriftData holds the data for rendering the drift page.
+type DriftData struct {
+	// Enabled is false if drift detection isn't enabled.
+	Enabled         bool
+	Projects        []DriftProjectData
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+var driftTemplate = template.Must(template.New("drift.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+<div class="container">
+  <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img src="{{ .CleanedBasePath }}/static/images/atlantis-icon.png"/></a>
+    <p class="title-heading">atlantis</p>
+  </section>
+  <div class="navbar-spacer"></div>
+  <br>
+  <section>
+    <p class="title-heading small"><strong>Drift</strong></p>
+    {{ if not .Enabled }}
+    <p class="placeholder">Drift detection is not enabled.</p>
+    {{ else if .Projects }}
+    {{ range .Projects }}
+      <div class="twelve columns button content lock-row">
+      <div class="list-title">{{.RepoID}}{{ if .ProjectName }} - <span class="heading-font-size">{{.ProjectName}}</span>{{ else if .RepoRelDir }} - <span class="heading-font-size">{{.RepoRelDir}}/{{.Workspace}}</span>{{ end }}</div>
+      <div class="list-status"><code>{{.Status}}</code></div>
+      <div class="list-timestamp"><span class="heading-font-size">{{.CheckedAt.Format "2006-01-02 15:04:05 MST"}}</span></div>
+      {{ if .Summary }}<div class="list-title"><span class="heading-font-size">{{.Summary}}</span></div>{{ end }}
+      {{ if .Error }}<pre><code>{{.Error}}</code></pre>{{ end }}
+      </div>
+    {{ end }}
+    {{ else }}
+    <p class="placeholder">No projects have been checked for drift yet.</p>
+    {{ end }}
+  </section>
+</div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+</body>
+</html>
+`))
+
 var lockTemplate = template.Must(template.New("lock.html.tmpl").Parse(`
 <!DOCTYPE html>
 <html lang="en">
@@ -156,6 +312,14 @@ var lockTemplate = template.Must(template.New("lock.html.tmpl").Parse(`
         <h6><code>Pull Request Link</code>: <a href="{{.PullRequestLink}}" target="_blank"><strong>{{.PullRequestLink}}</strong></a></h6>
         <h6><code>Locked By</code>: <strong>{{.LockedBy}}</strong></h6>
         <h6><code>Workspace</code>: <strong>{{.Workspace}}</strong></h6>
+        {{ if .Queue }}
+        <h6><code>Queue</code>:</h6>
+        <ol>
+        {{ range .Queue }}
+          <li><a href="{{.PullRequestLink}}" target="_blank"><strong>#{{.PullNum}}</strong></a> queued by <strong>{{.QueuedBy}}</strong></li>
+        {{ end }}
+        </ol>
+        {{ end }}
         <br>
       </div>
       <div class="four columns">
@@ -226,3 +390,71 @@ v{{ .AtlantisVersion }}
 </body>
 </html>
 `))
+
+var githubAppSetupTemplate = template.Must(template.New("github-app-setup.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img src="{{ .CleanedBasePath }}/static/images/atlantis-icon.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>Create a GitHub App</strong></p>
+    </section>
+    <section>
+      <p>Redirecting to GitHub...</p>
+      <form id="manifestForm" action="{{ .Target }}" method="post">
+        <input type="hidden" name="manifest" value="{{ .Manifest }}">
+        <button type="submit">Create App on GitHub</button>
+      </form>
+    </section>
+  </div>
+<script>
+  document.getElementById("manifestForm").submit();
+</script>
+</body>
+</html>
+`))
+
+var githubAppExchangeCodeTemplate = template.Must(template.New("github-app-exchange-code.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img src="{{ .CleanedBasePath }}/static/images/atlantis-icon.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>GitHub App created</strong></p>
+    </section>
+    <section>
+      <p>Your app <a href="{{ .URL }}" target="_blank"><strong>{{ .Slug }}</strong></a> was created.
+        These credentials are only shown once so save them now.
+        Then install the app on your repos and restart Atlantis with:</p>
+      <pre><code>--gh-app-id {{ .ID }} --gh-app-key-file /path/to/key.pem --gh-webhook-secret {{ .WebhookSecret }}</code></pre>
+      <h6><code>App ID</code>: <strong>{{ .ID }}</strong></h6>
+      <h6><code>Webhook Secret</code>: <strong>{{ .WebhookSecret }}</strong></h6>
+      <h6><code>Private Key</code> (save to /path/to/key.pem):</h6>
+      <pre><code>{{ .Key }}</code></pre>
+    </section>
+  </div>
+</body>
+</html>
+`))